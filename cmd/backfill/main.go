@@ -0,0 +1,206 @@
+// Command backfill writes historical bid/ask bars from the Saxo chart service through the
+// recorder sinks, so a new deployment has history from before its start date
+//
+//	go run ./cmd/backfill -from 20251101 -to 20251117 -horizon 1 -ticker EURUSD,USDJPY
+//
+// Every bar becomes one row at the bar's end with its closing bid/ask, flagged as backfill (16).
+// Rows are appended to the same hourly files the collector writes, so backfill days the
+// collector hasn't recorded
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/saxoref"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+	"github.com/joho/godotenv"
+)
+
+// horizons are the bar sizes (minutes) the chart service supports
+var horizons = []int{1, 5, 10, 15, 30, 60, 120, 240, 360, 480, 1440}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Backfill error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	from := flag.String("from", "", "First day to backfill (YYYYMMDD, UTC)")
+	to := flag.String("to", "", "Last day to backfill (YYYYMMDD, UTC; default yesterday)")
+	horizon := flag.Int("horizon", 1, fmt.Sprintf("Bar size in minutes %v", horizons))
+	tickers := flag.String("ticker", "", "Comma-separated tickers to backfill (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file")
+	recorders := flag.String("recorders", toolconfig.GetEnv("SPREAD_RECORDERS", "csv"), "Sinks to write to, as SPREAD_RECORDERS")
+	flag.Parse()
+
+	start, end, err := parseRange(*from, *to, time.Now())
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(horizons, *horizon) {
+		return fmt.Errorf("unsupported -horizon %d (supported: %v)", *horizon, horizons)
+	}
+
+	instruments, err := toolconfig.ReadInstruments(*instrumentsPath, *tickers)
+	if err != nil {
+		return err
+	}
+
+	logger := log.New(os.Stderr, "[FX-BACKFILL] ", log.LstdFlags|log.Lmsgprefix)
+	ctx := context.Background()
+	client, baseURL, err := brokerClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	recorder, err := toolconfig.CreateRecorders(*recorders, tenant.SinkDefaults(tenantName))
+	if err != nil {
+		return err
+	}
+	b := &backfiller{
+		logger:   logger,
+		charts:   saxoref.NewCharts(client, baseURL),
+		details:  saxoref.NewInstrumentDetails(client, baseURL),
+		recorder: recorder,
+		horizon:  *horizon,
+	}
+
+	logger.Printf("Backfilling %d instruments, %s to %s, %d-minute bars",
+		len(instruments), start.Format(time.DateOnly), end.Add(-time.Second).Format(time.DateOnly), *horizon)
+	for _, inst := range instruments {
+		if err = b.backfill(ctx, inst, start, end); err != nil {
+			break
+		}
+	}
+
+	// Close even after a failure: the Arrow sink only becomes readable once closed
+	if closeErr := recorder.Close(ctx); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close recorders: %w", closeErr)
+	}
+	return err
+}
+
+// backfiller writes the bars of one instrument at a time through the recorders
+type backfiller struct {
+	logger   *log.Logger
+	charts   *saxoref.Charts
+	details  *saxoref.InstrumentDetails
+	recorder *storage.MultiRecorder
+	horizon  int // Minutes
+}
+
+// backfill writes the bars of inst in [start, end)
+func (b *backfiller) backfill(ctx context.Context, inst toolconfig.Instrument, start, end time.Time) error {
+	if inst.Decimals == 0 {
+		decimals, err := b.details.Decimals(ctx, inst.Uic, inst.AssetType)
+		if err != nil {
+			return fmt.Errorf("failed to look up decimals for %s: %w", inst.Ticker, err)
+		}
+		inst.Decimals = decimals
+	}
+
+	step := time.Duration(b.horizon) * time.Minute
+	count := 0
+	err := b.charts.Bars(ctx, inst.Uic, inst.AssetType, b.horizon, start, end, func(bars []saxoref.Bar) error {
+		ticks := barTicks(inst, bars, step)
+		count += len(ticks)
+		return b.recorder.RecordBatch(ctx, ticks)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to backfill %s: %w", inst.Ticker, err)
+	}
+	if err := b.recorder.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush %s: %w", inst.Ticker, err)
+	}
+
+	b.logger.Printf("%s: %d bars", inst.Ticker, count)
+	return nil
+}
+
+// barTicks converts bars into rows at each bar's end with its closing quote
+// Bars without a two-sided close are skipped
+func barTicks(inst toolconfig.Instrument, bars []saxoref.Bar, step time.Duration) []*domain.PriceData {
+	ticks := make([]*domain.PriceData, 0, len(bars))
+	for _, bar := range bars {
+		if bar.CloseBid <= 0 || bar.CloseAsk <= 0 {
+			continue
+		}
+		tick := &domain.PriceData{
+			Timestamp:  bar.Time.Add(step).UTC(),
+			Uic:        inst.Uic,
+			Ticker:     inst.Ticker,
+			AssetType:  inst.AssetType,
+			Bid:        bar.CloseBid,
+			Ask:        bar.CloseAsk,
+			Decimals:   inst.Decimals,
+			Flags:      domain.FlagBackfill,
+			SpreadUnit: domain.SpreadUnitPrice,
+		}
+		tick.CalculateSpread()
+		ticks = append(ticks, tick)
+	}
+	return ticks
+}
+
+// parseRange returns [start of from, end of to) in UTC, ending no later than now
+func parseRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	if from == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from is required")
+	}
+	start, err := time.Parse("20060102", from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid -from '%s': %w", from, err)
+	}
+
+	end := now.UTC().Truncate(24 * time.Hour) // Through yesterday
+	if to != "" {
+		last, err := time.Parse("20060102", to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to '%s': %w", to, err)
+		}
+		if end = last.AddDate(0, 0, 1); end.After(now) {
+			end = now.UTC()
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("empty range %s to %s", start.Format(time.DateOnly), end.Format(time.DateOnly))
+	}
+	return start, end, nil
+}
+
+// brokerClient logs in and returns an authenticated HTTP client and the OpenAPI base URL
+func brokerClient(ctx context.Context, logger *log.Logger) (*http.Client, string, error) {
+	authClient, err := saxo.CreateSaxoAuthClient(logger)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create auth client: %w", err)
+	}
+	if !authClient.IsAuthenticated() {
+		if err := authClient.Login(ctx); err != nil {
+			return nil, "", fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	client, err := authClient.GetHTTPClient(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get HTTP client: %w", err)
+	}
+	return client, authClient.GetBaseURL(), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/saxoref"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestBarTicks(t *testing.T) {
+	inst := toolconfig.Instrument{Ticker: "EURUSD", Uic: 21, AssetType: "FxSpot", Decimals: 5}
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	bars := []saxoref.Bar{
+		{Time: start, CloseBid: 1.1, CloseAsk: 1.1002},
+		{Time: start.Add(time.Minute), CloseBid: 1.1}, // One-sided close
+	}
+
+	ticks := barTicks(inst, bars, time.Minute)
+	if len(ticks) != 1 {
+		t.Fatalf("Expected the one-sided bar to be skipped, got %d ticks", len(ticks))
+	}
+	tick := ticks[0]
+	if !tick.Timestamp.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the tick at the bar's end, got %v", tick.Timestamp)
+	}
+	if tick.Ticker != "EURUSD" || tick.Flags&domain.FlagBackfill == 0 {
+		t.Errorf("Expected a backfill tick of EURUSD, got %s with flags %v", tick.Ticker, tick.Flags)
+	}
+	if tick.Spread <= 0 {
+		t.Errorf("Expected the spread to be calculated, got %v", tick.Spread)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2025, 11, d, 0, 0, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		from, to   string
+		start, end time.Time
+	}{
+		{"20251110", "", day(10), day(18)},         // Through yesterday
+		{"20251110", "20251112", day(10), day(13)}, // Through the end of -to
+		{"20251110", "20251118", day(10), now},     // Not past now
+	} {
+		start, end, err := parseRange(tc.from, tc.to, now)
+		if err != nil {
+			t.Errorf("%s-%s: unexpected error: %v", tc.from, tc.to, err)
+			continue
+		}
+		if !start.Equal(tc.start) || !end.Equal(tc.end) {
+			t.Errorf("%s-%s: expected %v to %v, got %v to %v", tc.from, tc.to, tc.start, tc.end, start, end)
+		}
+	}
+
+	for _, tc := range [][2]string{{"", ""}, {"2025-11-10", ""}, {"20251118", ""}, {"20251112", "20251110"}} {
+		if _, _, err := parseRange(tc[0], tc[1], now); err == nil {
+			t.Errorf("%s-%s: expected an error", tc[0], tc[1])
+		}
+	}
+}
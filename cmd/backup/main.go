@@ -0,0 +1,175 @@
+// Command backup copies the finalized spread files that are new or changed since the last run
+//
+//	go run ./cmd/backup -target s3://fx-backup/spreads
+//	go run ./cmd/backup -target az://fxbackups/spreads
+//	go run ./cmd/backup -target sftp://backup@nas.local/volume1/fx
+//	go run ./cmd/backup -target /mnt/usb/fx
+//
+// The size and modification time of every copied file are kept in the collector's state database
+// (STATE_DB) or a state file, so a nightly run only stats the archive and uploads the files
+// finalized since then.
+// Files still being written (*.partial) are left for the next run. The target gets the same
+// YYYYMMDD/TICKER_HH.csv layout, which cmd/restore reads back
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/objectstore"
+	"github.com/bjoelf/fx-collector/internal/adapters/remotefs"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Backup error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	partialFiles, err := strconv.ParseBool(toolconfig.GetEnv("CSV_PARTIAL_FILES", "false"))
+	if err != nil {
+		return fmt.Errorf("invalid CSV_PARTIAL_FILES: %w", err)
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "Spread archive directory")
+	targetURL := flag.String("target", toolconfig.GetEnv("BACKUP_TARGET", ""), "Backup target: local path, sftp://user@host/path, s3://bucket/prefix, gs://bucket/prefix or az://account/container/prefix")
+	statePath := flag.String("state", toolconfig.GetEnv("BACKUP_STATE", ""), "State file (default the collector's state database, else <dir>/.backup_state.json)")
+	stateDB := flag.String("state-db", tenant.Path(tenantName, toolconfig.GetEnv("STATE_DB", "")), "Collector state database keeping the progress (STATE_DB)")
+	adminAddr := flag.String("admin", toolconfig.GetEnv("ADMIN_ADDR", ""), "Collector admin API, used while the collector holds the state database")
+	adminToken := flag.String("token", toolconfig.GetEnv("ADMIN_TOKEN", ""), "Admin API token")
+	dryRun := flag.Bool("dry-run", false, "Only list the files that would be copied")
+	partial := flag.Bool("partial", partialFiles, "The collector writes *.partial files (CSV_PARTIAL_FILES), so other files of the current hour are finished")
+	stores := objectstore.ConfigFromEnv(toolconfig.GetEnv)
+	flag.StringVar(&stores.AWS.CLIPath, "aws", stores.AWS.CLIPath, "Path to the AWS CLI (s3:// targets)")
+	flag.StringVar(&stores.GCS.CLIPath, "gcloud", stores.GCS.CLIPath, "Path to the gcloud CLI (gs:// targets)")
+	flag.StringVar(&stores.Azure.CLIPath, "az", stores.Azure.CLIPath, "Path to the Azure CLI (az:// targets)")
+	sftpPath := flag.String("sftp", toolconfig.GetEnv("SFTP_PATH", "sftp"), "Path to the sftp client (sftp:// targets)")
+	flag.Parse()
+
+	if *targetURL == "" {
+		return fmt.Errorf("no target given (set -target or BACKUP_TARGET)")
+	}
+
+	dest, err := remotefs.NewTarget(*targetURL, stores, *sftpPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	legacy := fileProgress{path: filepath.Join(*dir, ".backup_state.json")}
+	var progress ports.BackupProgress = legacy
+	inDatabase := *statePath == "" && *stateDB != ""
+	switch {
+	case *statePath != "":
+		progress = fileProgress{path: *statePath}
+	case inDatabase:
+		database := databaseProgress{path: *stateDB}
+		if *adminAddr != "" {
+			database.admin = newAdminProgress(*adminAddr, *adminToken)
+		}
+		progress = database
+	}
+
+	backedUp, err := progress.BackedUp(ctx, *targetURL)
+	if err != nil {
+		return err
+	}
+	// Continue from the state file of an earlier version instead of copying everything again
+	if len(backedUp) == 0 && inDatabase {
+		if backedUp, err = legacy.BackedUp(ctx, *targetURL); err != nil {
+			return err
+		}
+		if len(backedUp) > 0 {
+			log.Printf("Continuing from %s (%d files)", legacy.path, len(backedUp))
+		}
+	}
+
+	files, err := storage.ListSpreadFiles(*dir, storage.ArchiveFilter{})
+	if err != nil {
+		return err
+	}
+
+	// Group the changed files by day, keeping the records of unchanged ones
+	// Files no longer in the archive (e.g. pruned by retention) drop out of the state
+	next := make(map[string]domain.BackedUpFile)
+	changed := make(map[string][]string)
+	pending := make(map[string]domain.BackedUpFile) // Stat before copying, so a file reopened meanwhile is copied again next run
+	var days []string
+	currentHour := time.Now().UTC().Truncate(time.Hour)
+	for _, file := range files {
+		if strings.HasSuffix(file, storage.PartialSuffix) {
+			continue
+		}
+		// Without partial files the hour being written can't be told from a finished one
+		if hour, ok := storage.SpreadFileHour(file); !*partial && ok && !hour.Before(currentHour) {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		day := filepath.Base(filepath.Dir(file))
+		key := day + "/" + filepath.Base(file)
+		record := domain.BackedUpFile{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		if previous, ok := backedUp[key]; ok && previous.Size == record.Size && previous.ModTime.Equal(record.ModTime) {
+			next[key] = record
+			continue
+		}
+		if _, ok := changed[day]; !ok {
+			days = append(days, day)
+		}
+		changed[day] = append(changed[day], file)
+		pending[key] = record
+	}
+
+	copied := 0
+	for _, day := range days {
+		if *dryRun {
+			for _, file := range changed[day] {
+				log.Printf("Would copy %s", file)
+			}
+			continue
+		}
+		if err := dest.Upload(ctx, day, changed[day]); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", day, err)
+		}
+		for _, file := range changed[day] {
+			key := day + "/" + filepath.Base(file)
+			next[key] = pending[key]
+		}
+		copied += len(changed[day])
+		// Saved per day, so an interrupted backup resumes where it stopped
+		if err := progress.SaveBackedUp(ctx, *targetURL, next); err != nil {
+			return err
+		}
+		log.Printf("%s: copied %d files", day, len(changed[day]))
+	}
+	if *dryRun {
+		return nil
+	}
+	if err := progress.SaveBackedUp(ctx, *targetURL, next); err != nil {
+		return err
+	}
+	log.Printf("Backup complete: %d files copied, %d unchanged", copied, len(next)-copied)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// fileProgress keeps the backup progress in a JSON state file, for one target at a time
+type fileProgress struct {
+	path string
+}
+
+// backupState is the layout of the state file
+type backupState struct {
+	Target string                         `json:"target"`
+	Files  map[string]domain.BackedUpFile `json:"files"` // By path relative to the archive (YYYYMMDD/TICKER_HH.csv)
+}
+
+// BackedUp reads the state file; a missing file or one of another target means nothing was copied yet
+func (p fileProgress) BackedUp(ctx context.Context, target string) (map[string]domain.BackedUpFile, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]domain.BackedUpFile), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s: %w", p.path, err)
+	}
+	var state backupState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", p.path, err)
+	}
+	if state.Target != target || state.Files == nil {
+		if state.Target != "" && state.Target != target {
+			log.Printf("Target changed from %s, copying the whole archive", state.Target)
+		}
+		return make(map[string]domain.BackedUpFile), nil
+	}
+	return state.Files, nil
+}
+
+// SaveBackedUp writes the state file atomically
+func (p fileProgress) SaveBackedUp(ctx context.Context, target string, files map[string]domain.BackedUpFile) error {
+	data, err := json.MarshalIndent(backupState{Target: target, Files: files}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := os.WriteFile(p.path+".tmp", append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", p.path, err)
+	}
+	if err := os.Rename(p.path+".tmp", p.path); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", p.path, err)
+	}
+	return nil
+}
+
+// databaseProgress keeps the backup progress in the collector's state database, opened for each
+// read and write only so a collector starting meanwhile isn't locked out. While the collector
+// holds the database, the progress goes through its admin API
+type databaseProgress struct {
+	path  string
+	admin *adminProgress // nil without an admin API address
+}
+
+// BackedUp reads the files copied to target
+func (p databaseProgress) BackedUp(ctx context.Context, target string) (map[string]domain.BackedUpFile, error) {
+	var files map[string]domain.BackedUpFile
+	err := p.with(func(progress ports.BackupProgress) (err error) {
+		files, err = progress.BackedUp(ctx, target)
+		return err
+	})
+	return files, err
+}
+
+// SaveBackedUp replaces the files copied to target
+func (p databaseProgress) SaveBackedUp(ctx context.Context, target string, files map[string]domain.BackedUpFile) error {
+	return p.with(func(progress ports.BackupProgress) error {
+		return progress.SaveBackedUp(ctx, target, files)
+	})
+}
+
+// with runs fn on the opened database, or on the admin API while the collector holds it
+func (p databaseProgress) with(fn func(progress ports.BackupProgress) error) error {
+	store, err := storage.OpenBoltStateStore(p.path, "")
+	if errors.Is(err, storage.ErrStateStoreInUse) {
+		if p.admin == nil {
+			return fmt.Errorf("%w; set ADMIN_ADDR (-admin) to keep the progress through the running collector", err)
+		}
+		return fn(p.admin)
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// adminProgress keeps the backup progress in the state database of a running collector, which
+// holds the database's lock, through its admin API
+type adminProgress struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func newAdminProgress(addr, token string) *adminProgress {
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return &adminProgress{client: &http.Client{Timeout: 30 * time.Second}, baseURL: "http://" + addr + "/admin/backup", token: token}
+}
+
+// BackedUp asks the collector for the files copied to target
+func (p adminProgress) BackedUp(ctx context.Context, target string) (map[string]domain.BackedUpFile, error) {
+	var files map[string]domain.BackedUpFile
+	if err := p.do(ctx, http.MethodGet, target, nil, &files); err != nil {
+		return nil, fmt.Errorf("failed to read backup progress from the collector: %w", err)
+	}
+	if files == nil {
+		files = make(map[string]domain.BackedUpFile)
+	}
+	return files, nil
+}
+
+// SaveBackedUp hands the files copied to target to the collector
+func (p adminProgress) SaveBackedUp(ctx context.Context, target string, files map[string]domain.BackedUpFile) error {
+	body, err := json.Marshal(files)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup progress: %w", err)
+	}
+	if err := p.do(ctx, http.MethodPut, target, body, nil); err != nil {
+		return fmt.Errorf("failed to save backup progress with the collector: %w", err)
+	}
+	return nil
+}
+
+// do sends one request for target and decodes the response into out, if any
+func (p adminProgress) do(ctx context.Context, method, target string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+"?target="+url.QueryEscape(target), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("the collector at %s runs without a state database (STATE_DB)", req.URL.Host)
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
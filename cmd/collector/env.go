@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/joho/godotenv"
+)
+
+// Settings owned by saxo-adapter, which reads them itself
+var (
+	adapterEnvPrefixes = []string{"SAXO_", "BROKER_"}
+	adapterEnvKeys     = []string{"AUTH_URL", "TOKEN_URL", "PROVIDER"}
+)
+
+// envLookups records every setting read through getEnv, so the list of known settings
+// can't drift from what loadConfig actually reads
+var envLookups = make(map[string]bool)
+
+// envValues records the effective value of every setting read through getEnv, for the run journal
+var envValues = make(map[string]string)
+
+// secretSettingMarkers mark settings whose values are redacted in effectiveSettings
+var secretSettingMarkers = []string{"TOKEN", "PASSWORD", "SECRET", "WEBHOOK_URL", "HEADERS", "API_KEY"}
+
+// getEnv gets a setting from FXC_<key> or <key> with default value
+func getEnv(key, defaultValue string) string {
+	envLookups[key] = true
+	value := toolconfig.GetEnv(key, defaultValue)
+	envValues[key] = value
+	return value
+}
+
+// effectiveSettings returns the value of every setting read so far, with secrets redacted
+func effectiveSettings() map[string]string {
+	settings := make(map[string]string, len(envValues))
+	for key, value := range envValues {
+		if value != "" && slices.ContainsFunc(secretSettingMarkers, func(marker string) bool {
+			return strings.Contains(key, marker)
+		}) {
+			value = "<redacted>"
+		}
+		settings[key] = value
+	}
+	return settings
+}
+
+// checkEnv reports settings that are set but never read, after loadConfig has run
+// Checked are all FXC_ variables, every key of the loaded .env file and unprefixed variables
+// that are one or two edits away from a known setting (SPREAD_FLUSH_INTERVALL)
+func checkEnv(envFile string) []string {
+	var problems []string
+	unknown := func(name, key, origin string) {
+		problem := fmt.Sprintf("unknown setting %s (%s)", name, origin)
+		if suggestion := closestSetting(key); suggestion != "" {
+			problem += fmt.Sprintf(", did you mean %s?", suggestion)
+		}
+		problems = append(problems, problem)
+	}
+
+	var fileValues map[string]string
+	if envFile != "" {
+		var err error
+		if fileValues, err = godotenv.Read(envFile); err != nil {
+			problems = append(problems, fmt.Sprintf("failed to re-read %s: %v", envFile, err))
+		}
+	}
+	for name := range fileValues {
+		if key := strings.TrimPrefix(name, toolconfig.EnvPrefix); !envLookups[key] && !adapterSetting(key) {
+			unknown(name, key, envFile)
+		}
+	}
+
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if _, fromFile := fileValues[name]; fromFile {
+			continue // Checked above
+		}
+		key, prefixed := strings.CutPrefix(name, toolconfig.EnvPrefix)
+
+		switch {
+		case envLookups[key]:
+			if plain := os.Getenv(key); prefixed && plain != "" && plain != value {
+				problems = append(problems, fmt.Sprintf("%s and %s are both set, using %s", name, key, name))
+			}
+		case prefixed:
+			unknown(name, key, "environment")
+		case !adapterSetting(key) && closestSetting(key) != "":
+			unknown(name, key, "environment")
+		}
+	}
+
+	slices.Sort(problems)
+	return problems
+}
+
+// adapterSetting reports whether key is read by saxo-adapter rather than the collector
+func adapterSetting(key string) bool {
+	for _, prefix := range adapterEnvPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return slices.Contains(adapterEnvKeys, key)
+}
+
+// closestSetting returns the known setting within two edits of key, if any
+func closestSetting(key string) string {
+	if len(key) < 6 {
+		return "" // Too short to tell a typo from an unrelated variable
+	}
+
+	best, bestDistance := "", 3
+	for known := range envLookups {
+		distance := editDistance(key, known)
+		if distance > 0 && (distance < bestDistance || distance == bestDistance && known < best) {
+			best, bestDistance = known, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
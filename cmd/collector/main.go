@@ -1,38 +1,261 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"log"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // FX market hours are defined in New York time
 
+	"github.com/bjoelf/fx-collector/internal/adapters/admin"
+	"github.com/bjoelf/fx-collector/internal/adapters/api"
+	"github.com/bjoelf/fx-collector/internal/adapters/bigquery"
+	"github.com/bjoelf/fx-collector/internal/adapters/calendar"
+	"github.com/bjoelf/fx-collector/internal/adapters/clock"
+	"github.com/bjoelf/fx-collector/internal/adapters/fix"
+	"github.com/bjoelf/fx-collector/internal/adapters/kafka"
+	"github.com/bjoelf/fx-collector/internal/adapters/lease"
+	"github.com/bjoelf/fx-collector/internal/adapters/metrics"
+	"github.com/bjoelf/fx-collector/internal/adapters/mqtt"
+	"github.com/bjoelf/fx-collector/internal/adapters/notify"
+	"github.com/bjoelf/fx-collector/internal/adapters/objectstore"
+	"github.com/bjoelf/fx-collector/internal/adapters/processor"
+	"github.com/bjoelf/fx-collector/internal/adapters/questdb"
+	"github.com/bjoelf/fx-collector/internal/adapters/remotefs"
+	"github.com/bjoelf/fx-collector/internal/adapters/remotewrite"
 	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/adapters/tracing"
+	"github.com/bjoelf/fx-collector/internal/buildinfo"
 	"github.com/bjoelf/fx-collector/internal/services"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
 	saxo "github.com/bjoelf/saxo-adapter/adapter"
 	"github.com/joho/godotenv"
 )
 
+// shutdownGrace is how long past SHUTDOWN_TIMEOUT the collector waits for shutdown steps that
+// ignore the deadline before it exits anyway
+const shutdownGrace = 5 * time.Second
+
 // Config holds all application configuration
+// Every setting is read by loadConfig from FXC_<NAME> or <NAME> (see Configuration Reference in README.md)
 type Config struct {
-	InstrumentsPath string
-	SpreadDir       string
-	FlushInterval   time.Duration
-	Instruments     map[string]services.Instrument
+	EnvFile         string                 // .env file loaded at start, re-read on reload ("" if none)
+	InstrumentsPath string                 // INSTRUMENTS_PATH
+	Tenant          string                 // Account or environment separating the output of this collector ("" for none)
+	Environment     string                 // Trading environment (SAXO_ENVIRONMENT): sim or live
+	ExplicitEnv     bool                   // SAXO_ENVIRONMENT was set rather than defaulted to sim
+	Recorders       []storage.RecorderSpec // Enabled sinks, e.g. csv, arrow?dir=/mnt/ticks
+	SpreadDir       string                 // CSV sink output directory (default dir of csv)
+	NDJSONOutput    string                 // "-" for stdout, or a file / named pipe path (default output of ndjson)
+	ArrowDir        string                 // Arrow sink output directory (default dir of arrow)
+	PriceFormat     domain.PriceFormat     // float or decimal for the ndjson, mqtt and arrow sinks
+	FlushInterval   time.Duration          // How often all sinks are flushed
+	ShutdownTimeout time.Duration          // Deadline for the final flush and closing the sinks on shutdown
+	SyncPolicy      storage.SyncPolicy     // When the csv and arrow sinks fsync
+	CSVBuffer       string                 // Write buffer per CSV file: auto or a byte count
+	CSVMaxOpenFiles int                    // Open CSV files per csv sink at most (0 = no limit)
+	CSVPartialFiles bool                   // Write hourly CSV files as .partial until their hour is over
+
+	// Announcements of finalized CSV files (both empty disables)
+	FinalizedOutboxDir  string
+	FinalizedWebhookURL string
+	FinalizedCopyTarget string                         // Local/mounted path, sftp:// or object store URL receiving each finalized file
+	FinalizedPendingDir string                         // Announcements and copies waiting for a retry
+	ObjectStores        objectstore.Config             // CLI paths and credentials of s3://, gs:// and az:// copy targets
+	SFTPPath            string                         // sftp client of sftp:// copy targets
+	Instruments         map[string]services.Instrument // Loaded from InstrumentsPath
+	InstrumentSinks     map[string][]string            // Sink names of instruments written to only some sinks
+	Groups              domain.InstrumentGroups        // Group tags of the instruments
+	MQTT                mqtt.PublisherConfig
+	FIX                 fix.AcceptorConfig
+	QuestDB             questdb.SenderConfig
+	QuestDBDedup        bool // Create the questdb sink's table deduplicated on the tick key
+	RemoteWrite         remotewrite.Config
+	Kafka               kafka.Config
+	BigQuery            bigquery.Config // Defaults of the bigquery sink (Interval 0: per mode)
+
+	// Notifications
+	WebhookURL       string
+	WebhookFormat    string
+	WebhookTemplate  string // Payload template file, replaces WebhookFormat
+	NotifyCooldown   time.Duration
+	DataGapThreshold time.Duration
+	DataGapCritical  time.Duration         // DATA_GAP_CRITICAL_AFTER
+	SMTP             notify.SMTPConfig     // Email alerts, enabled with SMTP_ADDR
+	Incidents        notify.IncidentConfig // PagerDuty/Opsgenie, enabled with INCIDENT_PROVIDER
+
+	// Disk space monitoring
+	DiskMonitor services.DiskMonitorConfig
+
+	// Broker updates as received, before mapping ("" disables)
+	RawCaptureDir       string
+	RawCaptureKeepHours int
+
+	// Pruning of sinks with a retain= parameter and of the stores below (0 keeps their data)
+	Retention       services.RetentionConfig
+	CopyRetain      time.Duration // FINALIZED_COPY_RETAIN
+	SnapshotRetain  time.Duration // SNAPSHOT_RETAIN
+	AlignedRetain   time.Duration // ALIGNED_RETAIN
+	HistogramRetain time.Duration // HISTOGRAM_RETAIN
+
+	// Clock drift against NTP and the broker (0 interval disables)
+	ClockCheckInterval  time.Duration
+	ClockDriftThreshold time.Duration
+	ClockNTPServer      string // "none" disables the NTP check
+	ClockBrokerCheck    bool
+
+	// Startup check of instrument decimals against broker metadata (off, warn or strict)
+	DecimalsCheck services.DecimalsCheck
+
+	// Spread outlier filter (off, flag or reject)
+	OutlierAction services.OutlierAction
+
+	// Spread recorded for crossed quotes (keep, clamp or invalid)
+	CrossedQuotes domain.CrossedQuotePolicy
+
+	// Ticks repeating a quote unchanged for longer are flagged stale (0 disables)
+	StaleQuoteAfter time.Duration
+
+	// Locked/crossed market log (empty disables)
+	AnomalyDir string
+
+	// Prometheus metrics endpoint (empty disables)
+	MetricsAddr string
+
+	// Admin API (empty address disables; token optional)
+	AdminAddr  string
+	AdminToken string
+
+	// Read API over the CSV archive (empty address disables; token optional)
+	APIAddr  string
+	APIToken string
+
+	// Recurring windows without recording
+	PauseSchedule domain.PauseSchedule
+
+	// Sampled recording with full capture bursts (0 sample interval disables)
+	Burst services.BurstConfig
+
+	// Effective spread for a notional from market depth (0 disables)
+	EffectiveSpreadNotional float64
+
+	// Latest quote per instrument and window (0 disables)
+	ConflationInterval time.Duration
+
+	// Trailing window of the spread_z column (0 disables)
+	SpreadZScoreWindow time.Duration
+
+	// Custom processing stages, e.g. filter?exclude=USDTRY, exec?cmd=./enrich (empty disables)
+	Processors []processor.Spec
+
+	// Daily tick and byte quotas (all limits 0 disables)
+	Quota services.QuotaConfig
+
+	// OpenTelemetry tracing of the tick pipeline (empty endpoint disables)
+	Tracing         tracing.Config
+	TraceSampleRate int
+
+	// Ops log for connection quality
+	OpsLogDir         string
+	HeartbeatInterval time.Duration
+
+	// Primary/standby mode
+	HAMode       string
+	HALockFile   string
+	HALeaseTTL   time.Duration
+	HAInstanceID string
+
+	// Subscription watchdog (0 disables)
+	SubscriptionStaleAfter time.Duration
+
+	// Restarts of failed background goroutines
+	RestartPolicy services.RestartPolicy
+
+	// Start/stop records of every run
+	RunJournal string
+
+	// How often errors per component and per-sink write statistics are logged (0 disables)
+	ErrorBudgetInterval time.Duration
+	SinkSummaryInterval time.Duration
+
+	// Regular-grid snapshots (0 disables)
+	SnapshotInterval time.Duration
+	SnapshotMaxAge   time.Duration // Carry a quote forward at most this long (0 = no limit)
+	SnapshotDir      string
+
+	// Clock-aligned quotes of all instruments (0 disables)
+	AlignedInterval time.Duration
+	AlignedDir      string
+
+	// Hourly spread histograms (no bounds disables)
+	HistogramBounds []float64
+	HistogramDir    string
+
+	// Trading sessions for tick labels
+	Sessions []domain.Session
+
+	// Rollover / triple-swap tick flags (nil disables)
+	Rollover *domain.RolloverCalendar
+
+	// Market holidays (nil means FX market hours only)
+	Holidays *domain.HolidayCalendar
+
+	// Economic calendar annotation (empty source disables)
+	CalendarSource    string
+	CalendarDir       string
+	CalendarWindow    time.Duration
+	CalendarMinImpact domain.Impact
+	CalendarRefresh   time.Duration
+
+	// Sequence number persistence
+	SequenceStateFile string
+	StateDB           string // bbolt database for sequences, last recorded ticks and gaps (empty: SequenceStateFile only)
+
+	// Storage write retry and dead-lettering
+	StorageRetry  storage.RetryConfig
+	DeadLetterDir string
+	FallbackProbe time.Duration // How often a failed sink with a fallback ("mqtt|csv") is retried
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelftest(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-version" || os.Args[1] == "version") {
+		fmt.Println(buildinfo.Get())
+		return
+	}
+
 	if err := run(); err != nil {
 		log.Fatalf("Application error: %v", err)
 	}
 }
 
-func run() error {
+func run() (runErr error) {
 	logger := log.New(os.Stdout, "[FX-COLLECTOR] ", log.LstdFlags|log.Lmsgprefix)
-	logger.Println("=== FX Collector Starting ===")
+	logger.Printf("=== FX Collector Starting (%s) ===", buildinfo.Get().Short())
 
 	// Load configuration from .env file and environment
 	config, err := loadConfig(logger)
@@ -40,6 +263,22 @@ func run() error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// Journal this run; the stop record tells a clean shutdown from a failed start
+	stopReason := "error"
+	if config.RunJournal != "" {
+		runs := startRun(config.RunJournal, config, logger)
+		defer func() { runs.stop(stopReason, runErr) }()
+	}
+
+	// A component the supervisor gives up on ends the run like a signal, through the normal shutdown
+	componentFailed := make(chan error, 1)
+	config.RestartPolicy.OnGiveUp = func(component string, err error) {
+		select {
+		case componentFailed <- fmt.Errorf("%s: %w", component, err):
+		default:
+		}
+	}
+
 	// Create Saxo auth client (handles OAuth automatically)
 	logger.Println("Creating Saxo authentication client...")
 	authClient, err := saxo.CreateSaxoAuthClient(logger)
@@ -58,8 +297,308 @@ func run() error {
 		return fmt.Errorf("failed to create broker services: %w", err)
 	}
 
-	// Create spread recorder
-	spreadRecorder := storage.NewCSVSpreadRecorder(config.SpreadDir)
+	// NDJSON or protobuf on stdout is data - move log output out of the way
+	for _, chain := range config.Recorders {
+		for spec := &chain; spec != nil; spec = spec.Fallback {
+			if (spec.Name == "ndjson" && spec.Param("output", config.NDJSONOutput) == "-") ||
+				(spec.Name == "proto" && spec.Param("output", "-") == "-") {
+				logger.SetOutput(os.Stderr)
+				logger.Printf("%s recorder writes to stdout - logging to stderr", spec.Name)
+			}
+		}
+	}
+
+	// The registry exists before the sinks, which register their write metrics in it
+	var registry *metrics.Registry
+	var sinkMetrics storage.SinkMetrics
+	if config.MetricsAddr != "" {
+		registry = metrics.NewRegistry()
+		if config.Tenant != "" {
+			registry.ConstLabel("tenant", config.Tenant)
+		}
+		sinkMetrics = storage.NewSinkMetrics(registry)
+	}
+
+	// Refuse to mix sim and live data before any sink opens a file
+	dirs := datasetDirs(config)
+	for _, dir := range dirs {
+		if err := storage.TagEnvironment(dir, config.Environment, config.ExplicitEnv); err != nil {
+			return err
+		}
+	}
+	logger.Printf("Environment %s (%d data directories checked)", config.Environment, len(dirs))
+
+	// Create spread recorders; sinks with a fallback report failover through adapterEvents
+	adapterEvents := make(chan domain.Event, 16)
+	spreadRecorder, err := createRecorders(config, adapterEvents, sinkMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to create spread recorder: %w", err)
+	}
+	if purger, ok := storage.As[ports.DayPurger](spreadRecorder); ok {
+		config.DiskMonitor.Purger = purger
+	}
+	var meteredSinks []ports.MeteredSink
+	for _, sink := range storage.All[*storage.MeteredRecorder](spreadRecorder) {
+		meteredSinks = append(meteredSinks, sink)
+	}
+	// Sockets, logs and the other sinks need descriptors beyond the CSV files
+	if limit, err := storage.OpenFileLimit(); err == nil && config.CSVMaxOpenFiles > 0 && uint64(config.CSVMaxOpenFiles)+64 > limit {
+		logger.Printf("⚠️ CSV_MAX_OPEN_FILES=%d leaves little room below the open file limit (ulimit -n %d); lower it or raise the limit",
+			config.CSVMaxOpenFiles, limit)
+	}
+
+	// Every sink with retain= is pruned by the retention job, as are the stores with a *_RETAIN period
+	var retained []services.RetainedStore
+	for _, sink := range storage.All[*storage.RetentionRecorder](spreadRecorder) {
+		retained = append(retained, services.RetainedStore{Name: sink.Name(), Retain: sink.Retention(), Pruner: sink})
+	}
+
+	// Announce finalized CSV files to downstream jobs; closed after the service, which finalizes the last ones
+	var finalized *storage.FinalizedFiles
+	if config.FinalizedOutboxDir != "" || config.FinalizedWebhookURL != "" || config.FinalizedCopyTarget != "" {
+		var target remotefs.Target
+		if finalized, target, err = createFinalizedFiles(config, spreadRecorder); err != nil {
+			return err
+		}
+		if target != nil && config.CopyRetain > 0 {
+			retained = append(retained, services.RetainedStore{Name: "copy target", Retain: config.CopyRetain, Pruner: target})
+		}
+		if !config.CSVPartialFiles {
+			logger.Printf("⚠️ Finalized file announcements and copies need CSV_PARTIAL_FILES=true (or partial=true per csv sink)")
+		}
+	}
+
+	// Create optional webhook notifier
+	serviceOpts := []services.Option{
+		services.WithAdapterEvents(adapterEvents),
+		services.WithDataGapThreshold(config.DataGapThreshold),
+		services.WithDataGapCriticalAfter(config.DataGapCritical),
+		services.WithDiskMonitor(config.DiskMonitor),
+		services.WithClockDrift(clockDriftConfig(config, authClient)),
+		services.WithSubscriptionWatchdog(config.SubscriptionStaleAfter),
+		services.WithRestartPolicy(config.RestartPolicy),
+		services.WithErrorBudget(config.ErrorBudgetInterval),
+		services.WithSinkSummary(config.SinkSummaryInterval, meteredSinks...),
+		services.WithFlushGroups(storage.FlushGroups(spreadRecorder)),
+		services.WithSequenceStore(storage.NewJSONSequenceStore(config.SequenceStateFile)),
+		services.WithOpsLog(storage.NewCSVOpsLog(config.OpsLogDir), config.HeartbeatInterval),
+		services.WithUnmappedDeadLetter(storage.NewNDJSONDeadLetterQueue(config.DeadLetterDir, "unmapped")),
+		services.WithSessions(config.Sessions),
+		services.WithRolloverFlags(config.Rollover),
+		services.WithHolidays(config.Holidays),
+		services.WithPauseSchedule(config.PauseSchedule),
+		services.WithDecimalsCheck(config.DecimalsCheck),
+		services.WithInstrumentMetadata(&brokerMetadata{authClient: authClient}),
+		services.WithOutlierFilter(services.OutlierFilterConfig{
+			Action:  config.OutlierAction,
+			Rejects: storage.NewNDJSONDeadLetterQueue(config.DeadLetterDir, "rejected"),
+		}),
+		services.WithCrossedQuotes(config.CrossedQuotes),
+		services.WithStaleQuotes(config.StaleQuoteAfter),
+	}
+	if config.RawCaptureDir != "" {
+		serviceOpts = append(serviceOpts, services.WithRawCapture(storage.NewNDJSONRawCapture(config.RawCaptureDir, config.RawCaptureKeepHours)))
+		logger.Printf("Raw capture enabled (%s, keeping %d hours)", config.RawCaptureDir, config.RawCaptureKeepHours)
+	}
+	var throttled *notify.ThrottledNotifier
+	var notifiers []ports.Notifier
+	if config.WebhookURL != "" {
+		var webhookOpts []notify.WebhookOption
+		format := config.WebhookFormat
+		if config.WebhookTemplate != "" {
+			tmpl, err := notify.LoadPayloadTemplate(config.WebhookTemplate)
+			if err != nil {
+				return err
+			}
+			webhookOpts = append(webhookOpts, notify.WithPayloadTemplate(tmpl))
+			format = "template " + config.WebhookTemplate
+		}
+		webhook, err := notify.NewWebhookNotifier(config.WebhookURL, notify.WebhookFormat(config.WebhookFormat), webhookOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook notifier: %w", err)
+		}
+		throttled = notify.NewThrottledNotifier(webhook, config.NotifyCooldown)
+		notifiers = append(notifiers, throttled)
+		logger.Printf("Webhook notifications enabled (format=%s)", format)
+	}
+	var mailer *notify.SMTPNotifier
+	if config.SMTP.Addr != "" {
+		if mailer, err = notify.NewSMTPNotifier(config.SMTP); err != nil {
+			return fmt.Errorf("failed to create SMTP notifier: %w", err)
+		}
+		notifiers = append(notifiers, mailer)
+		logger.Printf("Email notifications enabled (%s to %s, %s and above, batched per %v)",
+			config.SMTP.Addr, strings.Join(config.SMTP.To, ","), config.SMTP.MinSeverity, config.SMTP.BatchWindow)
+	}
+	if config.Incidents.Provider != "" {
+		incidents, err := notify.NewIncidentNotifier(config.Incidents)
+		if err != nil {
+			return fmt.Errorf("failed to create incident notifier: %w", err)
+		}
+		notifiers = append(notifiers, incidents)
+		logger.Printf("Incident alerts enabled (%s, %s and above)", config.Incidents.Provider, config.Incidents.MinSeverity)
+	}
+	switch len(notifiers) {
+	case 0:
+	case 1:
+		serviceOpts = append(serviceOpts, services.WithNotifier(notifiers[0]))
+	default:
+		serviceOpts = append(serviceOpts, services.WithNotifier(notify.NewMultiNotifier(notifiers...)))
+	}
+
+	if config.AnomalyDir != "" {
+		serviceOpts = append(serviceOpts, services.WithAnomalyLog(storage.NewNDJSONDeadLetterQueue(config.AnomalyDir, "anomalies")))
+		logger.Printf("Locked/crossed market log enabled (%s)", config.AnomalyDir)
+	}
+
+	if registry != nil {
+		serviceOpts = append(serviceOpts, services.WithMetrics(registry.Port()))
+		if csvRecorder, ok := storage.As[*storage.CSVSpreadRecorder](spreadRecorder); ok {
+			bufferSizes := registry.Gauge("fx_collector_csv_buffer_bytes",
+				"Write buffer size of each instrument's current CSV file", "ticker")
+			csvRecorder.OnBufferSize(func(ticker string, size int) { bufferSizes.Set(float64(size), ticker) })
+		}
+
+		metricsServer := &http.Server{Addr: config.MetricsAddr, Handler: metricsMux(registry, time.Now())}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Printf("Metrics server error: %v", err)
+			}
+		}()
+		defer metricsServer.Close()
+		logger.Printf("Metrics enabled (http://%s/metrics, health at /health)", config.MetricsAddr)
+	}
+
+	if config.Tracing.Endpoint != "" {
+		tracer, err := tracing.NewTracer(config.Tracing, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create tracer: %w", err)
+		}
+		defer tracer.Close()
+		serviceOpts = append(serviceOpts, services.WithTracing(tracer, config.TraceSampleRate))
+		logger.Printf("Tracing enabled (1 in %d ticks -> %s)", config.TraceSampleRate, config.Tracing.Endpoint)
+	}
+
+	if config.SnapshotInterval > 0 {
+		snapshots := storage.NewCSVSnapshotRecorder(config.SnapshotDir)
+		if config.SnapshotRetain > 0 {
+			retained = append(retained, services.RetainedStore{Name: "snapshots", Retain: config.SnapshotRetain, Pruner: snapshots})
+		}
+		snapshotWriters := []ports.SnapshotWriter{snapshots}
+		for _, sink := range storage.All[*bigquery.Sink](spreadRecorder) {
+			if bars := sink.Bars(); bars != nil {
+				snapshotWriters = append(snapshotWriters, bars)
+			}
+		}
+		serviceOpts = append(serviceOpts,
+			services.WithSnapshots(config.SnapshotInterval, storage.NewMultiSnapshotWriter(snapshotWriters...)),
+			services.WithSnapshotMaxAge(config.SnapshotMaxAge))
+		logger.Printf("Snapshots enabled (every %v -> %s)", config.SnapshotInterval, config.SnapshotDir)
+	}
+
+	if config.AlignedInterval > 0 {
+		aligned := storage.NewCSVAlignedRecorder(config.AlignedDir)
+		if config.AlignedRetain > 0 {
+			retained = append(retained, services.RetainedStore{Name: "aligned quotes", Retain: config.AlignedRetain, Pruner: aligned})
+		}
+		serviceOpts = append(serviceOpts, services.WithAlignedQuotes(config.AlignedInterval, aligned))
+		logger.Printf("Aligned quotes enabled (every %v -> %s)", config.AlignedInterval, config.AlignedDir)
+	}
+	if len(config.HistogramBounds) > 0 {
+		histograms := storage.NewCSVHistogramRecorder(config.HistogramDir)
+		if config.HistogramRetain > 0 {
+			retained = append(retained, services.RetainedStore{Name: "histograms", Retain: config.HistogramRetain, Pruner: histograms})
+		}
+		serviceOpts = append(serviceOpts, services.WithSpreadHistograms(config.HistogramBounds, histograms))
+		logger.Printf("Spread histograms enabled (buckets %v pips -> %s)", config.HistogramBounds, config.HistogramDir)
+	}
+	if len(retained) > 0 {
+		serviceOpts = append(serviceOpts, services.WithRetention(config.Retention, retained...))
+	}
+
+	if config.EffectiveSpreadNotional > 0 {
+		serviceOpts = append(serviceOpts, services.WithEffectiveSpread(config.EffectiveSpreadNotional))
+	}
+
+	if len(config.Processors) > 0 {
+		pipeline, err := processor.NewPipeline(config.Processors)
+		if err != nil {
+			return fmt.Errorf("failed to create processors: %w", err)
+		}
+		defer pipeline.Close()
+		serviceOpts = append(serviceOpts, services.WithProcessor(pipeline))
+	}
+
+	if config.ConflationInterval > 0 {
+		serviceOpts = append(serviceOpts, services.WithConflation(config.ConflationInterval))
+		logger.Printf("Conflation enabled (last quote per instrument every %v)", config.ConflationInterval)
+	}
+
+	if config.SpreadZScoreWindow > 0 {
+		serviceOpts = append(serviceOpts, services.WithSpreadZScore(config.SpreadZScoreWindow))
+		logger.Printf("Spread z-scores enabled (trailing %v window)", config.SpreadZScoreWindow)
+	}
+
+	if config.Quota.Enabled() {
+		serviceOpts = append(serviceOpts, services.WithTickQuota(config.Quota))
+		logger.Printf("Daily quotas enabled (action=%s)", config.Quota.Action)
+	}
+
+	// Always installed so a reload can switch sampling on
+	serviceOpts = append(serviceOpts, services.WithBurstMode(config.Burst))
+	if config.Burst.SampleInterval > 0 || slices.ContainsFunc(slices.Collect(maps.Values(config.Instruments)),
+		func(instrument services.Instrument) bool { return instrument.SampleInterval > 0 }) {
+		interval := "instrument's sampleInterval"
+		if config.Burst.SampleInterval > 0 {
+			interval = config.Burst.SampleInterval.String()
+		}
+		logger.Printf("Burst mode enabled (one tick per %s, full capture around calendar events and for %v after anomalies)",
+			interval, config.Burst.AnomalyWindow)
+		if config.CalendarSource == "" {
+			logger.Println("Burst mode: no CALENDAR_SOURCE, bursts are triggered by anomalies only")
+		}
+	}
+
+	if config.CalendarSource != "" {
+		var source ports.CalendarSource = calendar.NewCSVCalendar(config.CalendarSource)
+		if strings.HasPrefix(config.CalendarSource, "http://") || strings.HasPrefix(config.CalendarSource, "https://") {
+			source = calendar.NewHTTPCalendar(config.CalendarSource)
+		}
+		serviceOpts = append(serviceOpts, services.WithEconomicCalendar(services.CalendarConfig{
+			Source:          source,
+			Writer:          storage.NewCSVCalendarWriter(config.CalendarDir),
+			Window:          config.CalendarWindow,
+			MinImpact:       config.CalendarMinImpact,
+			RefreshInterval: config.CalendarRefresh,
+		}))
+		logger.Printf("Economic calendar annotation enabled (source=%s)", config.CalendarSource)
+	}
+
+	// Collector state in a database instead of the sequence file, which is imported on first use
+	var stateStore ports.StateStore
+	if config.StateDB != "" {
+		boltStore, err := storage.OpenBoltStateStore(config.StateDB, config.SequenceStateFile)
+		if err != nil {
+			return err
+		}
+		defer boltStore.Close()
+		stateStore = boltStore
+		serviceOpts = append(serviceOpts, services.WithStateStore(stateStore))
+		logger.Printf("Collector state in %s", config.StateDB)
+	}
+
+	// Primary/standby coordination: only the lease holder records
+	switch config.HAMode {
+	case "off":
+	case "file":
+		serviceOpts = append(serviceOpts, services.WithLeaderElection(
+			lease.NewFileLease(config.HALockFile, config.HAInstanceID, config.HALeaseTTL),
+			config.HALeaseTTL,
+		))
+		logger.Printf("Primary/standby mode enabled (instance=%s, lease=%s)", config.HAInstanceID, config.HALockFile)
+	default:
+		return fmt.Errorf("unsupported HA_MODE: %s", config.HAMode)
+	}
 
 	// Create collector service
 	collectorService, err := services.NewCollectorService(
@@ -69,11 +608,56 @@ func run() error {
 		spreadRecorder,
 		config.FlushInterval,
 		logger,
+		serviceOpts...,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create collector service: %w", err)
 	}
 
+	// SIGHUP and POST /admin/reload apply changed runtime settings without reconnecting
+	reloader := &configReloader{
+		envFile:        config.EnvFile,
+		service:        collectorService,
+		notifier:       throttled,
+		notifyCooldown: config.NotifyCooldown,
+		logger:         logger,
+	}
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	defer signal.Stop(hupChan)
+	go reloader.reloadOnSignal(hupChan)
+
+	if config.AdminAddr != "" {
+		adminOpts := []admin.Option{admin.WithConfigReloader(reloader), admin.WithGroups(config.Groups), admin.WithStatus(collectorService)}
+		if stateStore != nil {
+			adminOpts = append(adminOpts, admin.WithStateStore(stateStore))
+		}
+		adminServer := &http.Server{Addr: config.AdminAddr, Handler: admin.NewHandler(collectorService, config.AdminToken, adminOpts...)}
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Printf("Admin server error: %v", err)
+			}
+		}()
+		defer adminServer.Close()
+		logger.Printf("Admin API enabled (http://%s/admin/recording, /admin/status, POST /admin/reload)", config.AdminAddr)
+	}
+
+	if config.APIAddr != "" {
+		archiveDir, err := csvArchiveDir(config)
+		if err != nil {
+			return err
+		}
+		handler := api.NewHandler(storage.NewCSVArchive(archiveDir), slices.Collect(maps.Keys(config.Instruments)), config.APIToken)
+		apiServer := &http.Server{Addr: config.APIAddr, Handler: handler}
+		go func() {
+			if err := apiServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Printf("API server error: %v", err)
+			}
+		}()
+		defer apiServer.Close()
+		logger.Printf("Read API enabled (http://%s/api/v1/spreads, archive %s)", config.APIAddr, archiveDir)
+	}
+
 	// Start collector service
 	if err := collectorService.Start(); err != nil {
 		return fmt.Errorf("failed to start collector service: %w", err)
@@ -84,34 +668,57 @@ func run() error {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	logger.Println("=== FX Collector Running (press Ctrl+C to stop) ===")
-	<-sigChan
-	logger.Println("\n=== Shutdown Signal Received ===")
+	var failure error
+	select {
+	case sig := <-sigChan:
+		stopReason = "signal: " + sig.String()
+		logger.Println("\n=== Shutdown Signal Received ===")
+	case failure = <-componentFailed:
+		stopReason = "component_failed"
+		logger.Printf("=== Component Failed, Shutting Down: %v ===", failure)
+	}
 
-	// Graceful shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Graceful shutdown; every component gives up on slow work at the deadline
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
 	defer cancel()
 
-	shutdownComplete := make(chan error, 1)
+	// Not every step honours the context (a sink stuck in a write, a mail server that never
+	// answers), so the run ends at the deadline plus a grace period whatever is still pending
+	shutdownDone := make(chan error, 1)
 	go func() {
-		shutdownComplete <- collectorService.Stop()
+		err := collectorService.Stop(shutdownCtx)
+		if finalized != nil {
+			if closeErr := finalized.Close(shutdownCtx); closeErr != nil {
+				logger.Printf("Failed to announce finalized files: %v", closeErr)
+			}
+		}
+		if mailer != nil {
+			// Mail the events still waiting for their batch window, e.g. the failure that ended the run
+			if mailErr := mailer.Close(shutdownCtx); mailErr != nil {
+				logger.Printf("Failed to send queued alert mail: %v", mailErr)
+			}
+		}
+		shutdownDone <- err
 	}()
 
+	forceExit := time.NewTimer(config.ShutdownTimeout + shutdownGrace)
+	defer forceExit.Stop()
 	select {
-	case err := <-shutdownComplete:
-		if err != nil {
-			logger.Printf("Shutdown completed with errors: %v", err)
-			return err
-		}
-		logger.Println("=== Shutdown Complete ===")
-		return nil
-	case <-shutdownCtx.Done():
+	case err = <-shutdownDone:
+	case <-forceExit.C:
 		logger.Println("=== Shutdown Timeout - Forcing Exit ===")
-		return fmt.Errorf("shutdown timeout exceeded")
+		return errors.Join(failure, fmt.Errorf("shutdown did not complete within %v", config.ShutdownTimeout+shutdownGrace))
+	}
+	if err != nil {
+		logger.Printf("=== Shutdown Completed With Errors ===")
+		return errors.Join(failure, err)
 	}
+	logger.Println("=== Shutdown Complete ===")
+	return failure
 }
 
-// loadConfig loads all configuration from .env file and environment variables
-func loadConfig(logger *log.Logger) (*Config, error) {
+// loadEnvFile loads the first .env file found into the environment and returns its path
+func loadEnvFile(logger *log.Logger) string {
 	// Load .env file following pivot-web2 pattern (supports debug run from cmd/collector/ and run from root)
 	envPaths := []string{
 		".env",       // Current directory (root)
@@ -119,21 +726,34 @@ func loadConfig(logger *log.Logger) (*Config, error) {
 		"../.env",    // From cmd/ to project root
 	}
 
-	loaded := false
+	// The process environment wins over the file, also when the file is re-read on reload
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		processEnv[name] = true
+	}
+
 	for _, envPath := range envPaths {
 		if _, err := os.Stat(envPath); err == nil {
 			if err := godotenv.Load(envPath); err == nil {
-				loaded = true
+				if values, err := godotenv.Read(envPath); err == nil {
+					for name := range values {
+						if !processEnv[name] {
+							envFileKeys = append(envFileKeys, name)
+						}
+					}
+				}
 				logger.Printf("Loaded .env from: %s", envPath)
-				break
+				return envPath
 			}
 		}
 	}
 
-	if !loaded {
-		logger.Println("Warning: .env file not found in any expected location, using system environment variables")
-	}
+	logger.Println("Warning: .env file not found in any expected location, using system environment variables")
+	return ""
+}
 
+// findInstrumentsFile resolves INSTRUMENTS_PATH against the expected run directories
+func findInstrumentsFile(logger *log.Logger) (string, error) {
 	// Read configuration values from environment with multiple relative path support for instruments
 	instrumentsPaths := []string{
 		getEnv("INSTRUMENTS_PATH", "data/instruments.json"), // Default from env or "data/instruments.json"
@@ -152,79 +772,1247 @@ func loadConfig(logger *log.Logger) (*Config, error) {
 	}
 
 	if instrumentsPath == "" {
-		return nil, fmt.Errorf("instruments file not found in any expected location: %v", instrumentsPaths)
+		return "", fmt.Errorf("instruments file not found in any expected location: %v", instrumentsPaths)
+	}
+
+	return instrumentsPath, nil
+}
+
+// loadConfig loads all configuration from .env file and environment variables
+func loadConfig(logger *log.Logger) (*Config, error) {
+	envFile := loadEnvFile(logger)
+
+	instrumentsPath, err := findInstrumentsFile(logger)
+	if err != nil {
+		return nil, err
 	}
 
 	spreadDir := getEnv("SPREAD_RECORDING_DIR", "data/spreads")
-	flushIntervalStr := getEnv("SPREAD_FLUSH_INTERVAL", "30s")
 
-	// Parse flush interval
-	flushInterval, err := time.ParseDuration(flushIntervalStr)
+	// Settings that can also be changed at runtime
+	tunables, err := loadTunables()
 	if err != nil {
-		return nil, fmt.Errorf("invalid SPREAD_FLUSH_INTERVAL '%s': %w", flushIntervalStr, err)
+		return nil, err
 	}
 
-	// Load instruments from JSON file
-	logger.Printf("Loading instruments from: %s", instrumentsPath)
-	instruments, err := loadInstruments(instrumentsPath)
+	var bigQueryInterval time.Duration
+	if value := getEnv("BIGQUERY_INTERVAL", ""); value != "" {
+		if bigQueryInterval, err = time.ParseDuration(value); err != nil || bigQueryInterval <= 0 {
+			return nil, fmt.Errorf("invalid BIGQUERY_INTERVAL '%s': must be a positive duration", value)
+		}
+	}
+
+	remoteWriteInterval, err := time.ParseDuration(getEnv("REMOTE_WRITE_INTERVAL", "15s"))
+	if err != nil || remoteWriteInterval < time.Second {
+		return nil, fmt.Errorf("invalid REMOTE_WRITE_INTERVAL '%s': must be at least 1s", getEnv("REMOTE_WRITE_INTERVAL", ""))
+	}
+
+	dataGapCritical, err := time.ParseDuration(getEnv("DATA_GAP_CRITICAL_AFTER", "15m"))
+	if err != nil || dataGapCritical < 0 {
+		return nil, fmt.Errorf("invalid DATA_GAP_CRITICAL_AFTER '%s': must be a duration (0 disables)", getEnv("DATA_GAP_CRITICAL_AFTER", ""))
+	}
+
+	smtpConfig, err := loadSMTPConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load instruments: %w", err)
+		return nil, err
 	}
-	logger.Printf("Loaded %d instruments", len(instruments))
 
-	return &Config{
-		InstrumentsPath: instrumentsPath,
-		SpreadDir:       spreadDir,
-		FlushInterval:   flushInterval,
-		Instruments:     instruments,
-	}, nil
-}
+	incidentConfig := notify.IncidentConfig{
+		Provider: notify.IncidentProvider(getEnv("INCIDENT_PROVIDER", "")),
+		Key:      getEnv("INCIDENT_API_KEY", ""),
+		URL:      getEnv("INCIDENT_API_URL", ""),
+	}
+	if incidentConfig.MinSeverity, err = domain.ParseSeverity(getEnv("INCIDENT_MIN_SEVERITY", "critical")); err != nil {
+		return nil, fmt.Errorf("invalid INCIDENT_MIN_SEVERITY: %w", err)
+	}
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	shutdownTimeout, err := time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "10s"))
+	if err != nil || shutdownTimeout <= 0 {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT '%s': must be a positive duration", getEnv("SHUTDOWN_TIMEOUT", ""))
 	}
-	return defaultValue
-}
 
-// instrument represents a trading instrument from JSON
-type instrument struct {
-	Ticker    string `json:"ticker"`
-	Uic       int    `json:"uic"`
-	AssetType string `json:"assetType"`
-	Decimals  int    `json:"decimals"`
-}
+	diskMinFreeStr := getEnv("DISK_MIN_FREE_MB", "1024")
+	diskMinFreeMB, err := strconv.ParseUint(diskMinFreeStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DISK_MIN_FREE_MB '%s': %w", diskMinFreeStr, err)
+	}
 
-// loadInstruments loads trading instruments from a JSON file
-func loadInstruments(filepath string) (map[string]services.Instrument, error) {
-	data, err := os.ReadFile(filepath)
+	diskCheckIntervalStr := getEnv("DISK_CHECK_INTERVAL", "1m")
+	diskCheckInterval, err := time.ParseDuration(diskCheckIntervalStr)
+	if err != nil || diskCheckInterval <= 0 {
+		return nil, fmt.Errorf("invalid DISK_CHECK_INTERVAL '%s': must be a positive duration", diskCheckIntervalStr)
+	}
+
+	retentionIntervalStr := getEnv("RETENTION_CHECK_INTERVAL", "1h")
+	retentionInterval, err := time.ParseDuration(retentionIntervalStr)
+	if err != nil || retentionInterval <= 0 {
+		return nil, fmt.Errorf("invalid RETENTION_CHECK_INTERVAL '%s': must be a positive duration", retentionIntervalStr)
+	}
+	retentionDryRun, err := strconv.ParseBool(getEnv("RETENTION_DRY_RUN", "false"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("invalid RETENTION_DRY_RUN: %w", err)
+	}
+	retainPeriods := make(map[string]time.Duration)
+	for _, key := range []string{"FINALIZED_COPY_RETAIN", "SNAPSHOT_RETAIN", "ALIGNED_RETAIN", "HISTOGRAM_RETAIN"} {
+		if value := getEnv(key, ""); value != "" {
+			if retainPeriods[key], err = storage.ParseRetention(value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		}
 	}
 
-	var config struct {
-		Instruments []instrument `json:"instruments"`
+	rawCaptureKeepStr := getEnv("RAW_CAPTURE_KEEP_HOURS", "24")
+	rawCaptureKeep, err := strconv.Atoi(rawCaptureKeepStr)
+	if err != nil || rawCaptureKeep < 0 {
+		return nil, fmt.Errorf("invalid RAW_CAPTURE_KEEP_HOURS '%s': must be a number >= 0", rawCaptureKeepStr)
 	}
 
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	diskSampleRateStr := getEnv("DISK_SAMPLE_RATE", "10")
+	diskSampleRate, err := strconv.Atoi(diskSampleRateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DISK_SAMPLE_RATE '%s': %w", diskSampleRateStr, err)
 	}
 
-	if len(config.Instruments) == 0 {
-		return nil, fmt.Errorf("no instruments found")
+	clockCheckIntervalStr := getEnv("CLOCK_CHECK_INTERVAL", "15m")
+	clockCheckInterval, err := time.ParseDuration(clockCheckIntervalStr)
+	if err != nil || clockCheckInterval < 0 {
+		return nil, fmt.Errorf("invalid CLOCK_CHECK_INTERVAL '%s': must be a duration (0 disables)", clockCheckIntervalStr)
+	}
+	clockDriftThresholdStr := getEnv("CLOCK_DRIFT_THRESHOLD", "500ms")
+	clockDriftThreshold, err := time.ParseDuration(clockDriftThresholdStr)
+	if err != nil || clockDriftThreshold <= 0 {
+		return nil, fmt.Errorf("invalid CLOCK_DRIFT_THRESHOLD '%s': must be a positive duration", clockDriftThresholdStr)
+	}
+	clockBrokerCheck, err := strconv.ParseBool(getEnv("CLOCK_BROKER_CHECK", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLOCK_BROKER_CHECK: %w", err)
 	}
 
-	// Convert to map for easy lookup
-	instruments := make(map[string]services.Instrument)
-	for _, inst := range config.Instruments {
-		instruments[inst.Ticker] = services.Instrument{
-			Ticker:    inst.Ticker,
-			Uic:       inst.Uic,
-			AssetType: inst.AssetType,
-			Decimals:  inst.Decimals,
+	heartbeatIntervalStr := getEnv("OPS_HEARTBEAT_INTERVAL", "1m")
+	heartbeatInterval, err := time.ParseDuration(heartbeatIntervalStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPS_HEARTBEAT_INTERVAL '%s': %w", heartbeatIntervalStr, err)
+	}
+
+	haLeaseTTLStr := getEnv("HA_LEASE_TTL", "15s")
+	haLeaseTTL, err := time.ParseDuration(haLeaseTTLStr)
+	if err != nil || haLeaseTTL < time.Second {
+		return nil, fmt.Errorf("invalid HA_LEASE_TTL '%s': must be at least 1s", haLeaseTTLStr)
+	}
+
+	hostname, _ := os.Hostname()
+	defaultInstanceID := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	restartPolicy := services.DefaultRestartPolicy()
+	restartMaxStr := getEnv("RESTART_MAX", strconv.Itoa(restartPolicy.MaxRestarts))
+	if restartPolicy.MaxRestarts, err = strconv.Atoi(restartMaxStr); err != nil {
+		return nil, fmt.Errorf("invalid RESTART_MAX '%s': %w", restartMaxStr, err)
+	}
+	restartWindowStr := getEnv("RESTART_WINDOW", restartPolicy.Window.String())
+	if restartPolicy.Window, err = time.ParseDuration(restartWindowStr); err != nil {
+		return nil, fmt.Errorf("invalid RESTART_WINDOW '%s': %w", restartWindowStr, err)
+	}
+	restartBackoffStr := getEnv("RESTART_BACKOFF", restartPolicy.InitialBackoff.String())
+	if restartPolicy.InitialBackoff, err = time.ParseDuration(restartBackoffStr); err != nil {
+		return nil, fmt.Errorf("invalid RESTART_BACKOFF '%s': %w", restartBackoffStr, err)
+	}
+	restartMaxBackoffStr := getEnv("RESTART_MAX_BACKOFF", restartPolicy.MaxBackoff.String())
+	if restartPolicy.MaxBackoff, err = time.ParseDuration(restartMaxBackoffStr); err != nil {
+		return nil, fmt.Errorf("invalid RESTART_MAX_BACKOFF '%s': %w", restartMaxBackoffStr, err)
+	}
+	if err := restartPolicy.Validate(); err != nil {
+		return nil, err
+	}
+
+	errorBudgetIntervalStr := getEnv("ERROR_BUDGET_INTERVAL", "1h")
+	errorBudgetInterval, err := time.ParseDuration(errorBudgetIntervalStr)
+	if err != nil || errorBudgetInterval < 0 {
+		return nil, fmt.Errorf("invalid ERROR_BUDGET_INTERVAL '%s': must be a duration >= 0", errorBudgetIntervalStr)
+	}
+	sinkSummaryIntervalStr := getEnv("SINK_SUMMARY_INTERVAL", "15m")
+	sinkSummaryInterval, err := time.ParseDuration(sinkSummaryIntervalStr)
+	if err != nil || sinkSummaryInterval < 0 {
+		return nil, fmt.Errorf("invalid SINK_SUMMARY_INTERVAL '%s': must be a duration >= 0", sinkSummaryIntervalStr)
+	}
+
+	retryAttemptsStr := getEnv("STORAGE_RETRY_ATTEMPTS", "3")
+	retryAttempts, err := strconv.Atoi(retryAttemptsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_RETRY_ATTEMPTS '%s': %w", retryAttemptsStr, err)
+	}
+
+	retryBackoffStr := getEnv("STORAGE_RETRY_BACKOFF", "100ms")
+	retryBackoff, err := time.ParseDuration(retryBackoffStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_RETRY_BACKOFF '%s': %w", retryBackoffStr, err)
+	}
+
+	retryMaxBackoffStr := getEnv("STORAGE_RETRY_MAX_BACKOFF", "2s")
+	retryMaxBackoff, err := time.ParseDuration(retryMaxBackoffStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_RETRY_MAX_BACKOFF '%s': %w", retryMaxBackoffStr, err)
+	}
+
+	fallbackProbeStr := getEnv("FALLBACK_PROBE_INTERVAL", "30s")
+	fallbackProbe, err := time.ParseDuration(fallbackProbeStr)
+	if err != nil || fallbackProbe <= 0 {
+		return nil, fmt.Errorf("invalid FALLBACK_PROBE_INTERVAL '%s': must be a positive duration", fallbackProbeStr)
+	}
+
+	mqttQoSStr := getEnv("MQTT_QOS", "0")
+	mqttQoS, err := strconv.ParseUint(mqttQoSStr, 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MQTT_QOS '%s': %w", mqttQoSStr, err)
+	}
+
+	mqttRetainedStr := getEnv("MQTT_RETAINED", "false")
+	mqttRetained, err := strconv.ParseBool(mqttRetainedStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MQTT_RETAINED '%s': %w", mqttRetainedStr, err)
+	}
+
+	snapshotIntervalStr := getEnv("SNAPSHOT_INTERVAL", "1s")
+	snapshotInterval, err := time.ParseDuration(snapshotIntervalStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNAPSHOT_INTERVAL '%s': %w", snapshotIntervalStr, err)
+	}
+	snapshotMaxAgeStr := getEnv("SNAPSHOT_MAX_AGE", "5m")
+	snapshotMaxAge, err := time.ParseDuration(snapshotMaxAgeStr)
+	if err != nil || snapshotMaxAge < 0 {
+		return nil, fmt.Errorf("invalid SNAPSHOT_MAX_AGE '%s': must be a duration >= 0", snapshotMaxAgeStr)
+	}
+
+	alignedIntervalStr := getEnv("ALIGNED_INTERVAL", "0")
+	alignedInterval, err := time.ParseDuration(alignedIntervalStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALIGNED_INTERVAL '%s': %w", alignedIntervalStr, err)
+	}
+
+	var histogramBounds []float64
+	if bounds := getEnv("HISTOGRAM_BUCKETS", ""); bounds != "" {
+		if histogramBounds, err = domain.ParseHistogramBounds(bounds); err != nil {
+			return nil, fmt.Errorf("invalid HISTOGRAM_BUCKETS: %w", err)
 		}
 	}
 
-	return instruments, nil
+	syncPolicy, err := storage.ParseSyncPolicy(getEnv("FSYNC_POLICY", "never"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FSYNC_POLICY: %w", err)
+	}
+	csvBuffer := getEnv("CSV_BUFFER", "auto")
+	if _, err := storage.ParseBufferSizer(csvBuffer, time.Second); err != nil {
+		return nil, fmt.Errorf("invalid CSV_BUFFER: %w", err)
+	}
+	csvMaxOpenFilesStr := getEnv("CSV_MAX_OPEN_FILES", "512")
+	csvMaxOpenFiles, err := strconv.Atoi(csvMaxOpenFilesStr)
+	if err != nil || csvMaxOpenFiles < 0 {
+		return nil, fmt.Errorf("invalid CSV_MAX_OPEN_FILES '%s': must be a number >= 0", csvMaxOpenFilesStr)
+	}
+	csvPartialFiles, err := strconv.ParseBool(getEnv("CSV_PARTIAL_FILES", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV_PARTIAL_FILES: %w", err)
+	}
+	questDBDedup, err := strconv.ParseBool(getEnv("QUESTDB_DEDUP", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUESTDB_DEDUP: %w", err)
+	}
+
+	priceFormat, err := domain.ParsePriceFormat(getEnv("PRICE_FORMAT", "float"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PRICE_FORMAT: %w", err)
+	}
+
+	var sessions []domain.Session
+	if spec := getEnv("SESSIONS", domain.DefaultSessions); spec != "none" {
+		if sessions, err = domain.ParseSessions(spec); err != nil {
+			return nil, fmt.Errorf("invalid SESSIONS: %w", err)
+		}
+	}
+
+	var rollover *domain.RolloverCalendar
+	tripleSwapDay := getEnv("TRIPLE_SWAP_DAY", "wednesday")
+	if window := getEnv("ROLLOVER_WINDOW", domain.DefaultRolloverWindow); window != "none" {
+		if rollover, err = domain.ParseRolloverCalendar(window, tripleSwapDay); err != nil {
+			return nil, fmt.Errorf("invalid ROLLOVER_WINDOW/TRIPLE_SWAP_DAY: %w", err)
+		}
+	}
+
+	pauseSchedule, err := domain.ParsePauseSchedule(getEnv("PAUSE_SCHEDULE", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PAUSE_SCHEDULE: %w", err)
+	}
+
+	var holidays *domain.HolidayCalendar
+	if path := getEnv("HOLIDAYS_FILE", ""); path != "" {
+		if holidays, err = calendar.LoadHolidays(path); err != nil {
+			return nil, err
+		}
+	}
+
+	calendarWindowStr := getEnv("CALENDAR_WINDOW", "15m")
+	calendarWindow, err := time.ParseDuration(calendarWindowStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CALENDAR_WINDOW '%s': %w", calendarWindowStr, err)
+	}
+
+	calendarMinImpact, err := domain.ParseImpact(getEnv("CALENDAR_MIN_IMPACT", "high"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CALENDAR_MIN_IMPACT: %w", err)
+	}
+
+	calendarRefreshStr := getEnv("CALENDAR_REFRESH", "1h")
+	calendarRefresh, err := time.ParseDuration(calendarRefreshStr)
+	if err != nil || calendarRefresh <= 0 {
+		return nil, fmt.Errorf("invalid CALENDAR_REFRESH '%s': must be a positive duration", calendarRefreshStr)
+	}
+
+	effectiveNotionalStr := getEnv("EFFECTIVE_SPREAD_NOTIONAL", "0")
+	effectiveNotional, err := strconv.ParseFloat(effectiveNotionalStr, 64)
+	if err != nil || effectiveNotional < 0 {
+		return nil, fmt.Errorf("invalid EFFECTIVE_SPREAD_NOTIONAL '%s': must be a non-negative amount", effectiveNotionalStr)
+	}
+
+	conflationIntervalStr := getEnv("CONFLATION_INTERVAL", "0")
+	conflationInterval, err := time.ParseDuration(conflationIntervalStr)
+	if err != nil || conflationInterval < 0 {
+		return nil, fmt.Errorf("invalid CONFLATION_INTERVAL '%s': must be a non-negative duration", conflationIntervalStr)
+	}
+
+	zscoreWindowStr := getEnv("SPREAD_ZSCORE_WINDOW", "0")
+	zscoreWindow, err := time.ParseDuration(zscoreWindowStr)
+	if err != nil || zscoreWindow < 0 {
+		return nil, fmt.Errorf("invalid SPREAD_ZSCORE_WINDOW '%s': must be a non-negative duration", zscoreWindowStr)
+	}
+
+	processors, err := processor.ParseSpecs(getEnv("PROCESSORS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROCESSORS: %w", err)
+	}
+
+	var quotaLimits [4]int64
+	for i, key := range []string{"QUOTA_TICKS_PER_INSTRUMENT", "QUOTA_MB_PER_INSTRUMENT", "QUOTA_TICKS_TOTAL", "QUOTA_MB_TOTAL"} {
+		value := getEnv(key, "0")
+		if quotaLimits[i], err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %w", key, value, err)
+		}
+	}
+
+	quotaSampleRateStr := getEnv("QUOTA_SAMPLE_RATE", "10")
+	quotaSampleRate, err := strconv.Atoi(quotaSampleRateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUOTA_SAMPLE_RATE '%s': %w", quotaSampleRateStr, err)
+	}
+
+	traceHeaders, err := tracing.ParseHeaders(getEnv("OTEL_EXPORTER_OTLP_HEADERS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+	}
+
+	spreadUnit, err := domain.ParseSpreadUnit(getEnv("SPREAD_UNIT", "price"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SPREAD_UNIT: %w", err)
+	}
+	crossedQuotes, err := domain.ParseCrossedQuotePolicy(getEnv("CROSSED_QUOTES", "keep"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CROSSED_QUOTES: %w", err)
+	}
+	staleQuoteAfterStr := getEnv("STALE_QUOTE_AFTER", "1m")
+	staleQuoteAfter, err := time.ParseDuration(staleQuoteAfterStr)
+	if err != nil || staleQuoteAfter < 0 {
+		return nil, fmt.Errorf("invalid STALE_QUOTE_AFTER '%s': must be a non-negative duration", staleQuoteAfterStr)
+	}
+
+	// Load instruments from JSON file
+	logger.Printf("Loading instruments from: %s", instrumentsPath)
+	instruments, err := loadInstruments(instrumentsPath, spreadUnit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instruments: %w", err)
+	}
+	logger.Printf("Loaded %d instruments", len(instruments.Instruments))
+	for _, group := range instruments.Groups.Names() {
+		logger.Printf("Group %s: %s", group, strings.Join(instruments.Groups[group], ", "))
+	}
+
+	var recorders []storage.RecorderSpec
+	for _, definition := range strings.Split(getEnv("SPREAD_RECORDERS", "csv"), ",") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		spec, err := storage.ParseRecorderSpec(definition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPREAD_RECORDERS: %w", err)
+		}
+		recorders = append(recorders, spec)
+	}
+
+	tenantName := getEnv("TENANT", "")
+	if err := tenant.Validate(tenantName); err != nil {
+		return nil, err
+	}
+
+	// Read by saxo-adapter as well, hence not prefixed
+	environment, explicitEnv, err := storage.ParseEnvironment(os.Getenv("SAXO_ENVIRONMENT"))
+	if err != nil {
+		return nil, err
+	}
+
+	configStrict, err := strconv.ParseBool(getEnv("CONFIG_STRICT", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONFIG_STRICT: %w", err)
+	}
+
+	config := &Config{
+		EnvFile:         envFile,
+		InstrumentsPath: instrumentsPath,
+		Tenant:          tenantName,
+		Environment:     environment,
+		ExplicitEnv:     explicitEnv,
+		Recorders:       recorders,
+		SpreadDir:       spreadDir,
+		NDJSONOutput:    getEnv("NDJSON_OUTPUT", "-"),
+		ArrowDir:        getEnv("ARROW_DIR", "data/arrow"),
+		PriceFormat:     priceFormat,
+		FlushInterval:   tunables.FlushInterval,
+		ShutdownTimeout: shutdownTimeout,
+		SyncPolicy:      syncPolicy,
+		CSVBuffer:       csvBuffer,
+		CSVMaxOpenFiles: csvMaxOpenFiles,
+		CSVPartialFiles: csvPartialFiles,
+
+		FinalizedOutboxDir:  getEnv("FINALIZED_OUTBOX_DIR", ""),
+		FinalizedWebhookURL: getEnv("FINALIZED_WEBHOOK_URL", ""),
+		FinalizedCopyTarget: getEnv("FINALIZED_COPY_TARGET", ""),
+		FinalizedPendingDir: getEnv("FINALIZED_PENDING_DIR", "data/state/finalized"),
+		ObjectStores:        objectstore.ConfigFromEnv(getEnv),
+		SFTPPath:            getEnv("SFTP_PATH", "sftp"),
+		Instruments:         instruments.Instruments,
+		InstrumentSinks:     instruments.Sinks,
+		Groups:              instruments.Groups,
+		MQTT: mqtt.PublisherConfig{
+			BrokerURL:     getEnv("MQTT_BROKER", "tcp://localhost:1883"),
+			ClientID:      getEnv("MQTT_CLIENT_ID", "fx-collector-"+defaultInstanceID),
+			Username:      getEnv("MQTT_USERNAME", ""),
+			Password:      getEnv("MQTT_PASSWORD", ""),
+			TopicTemplate: getEnv("MQTT_TOPIC", "fx/spread/{ticker}"),
+			QoS:           byte(mqttQoS),
+			Retained:      mqttRetained,
+			PriceFormat:   priceFormat,
+		},
+		FIX: fix.AcceptorConfig{
+			Addr:         getEnv("FIX_ADDR", "localhost:9878"),
+			SenderCompID: getEnv("FIX_SENDER_COMP_ID", "FXCOLLECTOR"),
+		},
+		QuestDB: questdb.SenderConfig{
+			Addr:     getEnv("QUESTDB_ADDR", "localhost:9009"),
+			Table:    getEnv("QUESTDB_TABLE", "fx_ticks"),
+			HTTPAddr: getEnv("QUESTDB_HTTP_ADDR", ""),
+		},
+		QuestDBDedup: questDBDedup,
+		RemoteWrite: remotewrite.Config{
+			URL:      getEnv("REMOTE_WRITE_URL", ""),
+			Token:    getEnv("REMOTE_WRITE_TOKEN", ""),
+			Interval: remoteWriteInterval,
+			Job:      getEnv("REMOTE_WRITE_JOB", "fx-collector"),
+			Instance: getEnv("REMOTE_WRITE_INSTANCE", hostname),
+		},
+		Kafka: kafka.Config{
+			RESTURL:       getEnv("KAFKA_REST_URL", ""),
+			Topic:         getEnv("KAFKA_TOPIC", "fx.ticks"),
+			Format:        getEnv("KAFKA_FORMAT", "avro"),
+			RegistryURL:   getEnv("SCHEMA_REGISTRY_URL", ""),
+			Compatibility: getEnv("SCHEMA_COMPATIBILITY", ""),
+		},
+		BigQuery: bigquery.Config{
+			Project:      getEnv("BIGQUERY_PROJECT", ""),
+			Dataset:      getEnv("BIGQUERY_DATASET", ""),
+			Table:        getEnv("BIGQUERY_TABLE", "ticks"),
+			BarsTable:    getEnv("BIGQUERY_BARS_TABLE", ""),
+			Mode:         getEnv("BIGQUERY_MODE", bigquery.ModeLoad),
+			Interval:     bigQueryInterval,
+			Partitioning: getEnv("BIGQUERY_PARTITION", "DAY"),
+			StagingDir:   getEnv("BIGQUERY_STAGING_DIR", "data/bigquery"),
+			BQPath:       getEnv("BQ_PATH", "bq"),
+			GcloudPath:   getEnv("GCLOUD_PATH", "gcloud"),
+		},
+
+		WebhookURL:       getEnv("WEBHOOK_URL", ""),
+		WebhookFormat:    getEnv("WEBHOOK_FORMAT", "generic"),
+		WebhookTemplate:  getEnv("WEBHOOK_TEMPLATE", ""),
+		NotifyCooldown:   tunables.NotifyCooldown,
+		DataGapThreshold: tunables.DataGapThreshold,
+		DataGapCritical:  dataGapCritical,
+		SMTP:             smtpConfig,
+		Incidents:        incidentConfig,
+
+		DiskMonitor: services.DiskMonitorConfig{
+			Path:          spreadDir,
+			MinFreeBytes:  diskMinFreeMB * 1024 * 1024,
+			CheckInterval: diskCheckInterval,
+			Action:        services.DiskAction(getEnv("DISK_EMERGENCY_ACTION", "none")),
+			SampleRate:    diskSampleRate,
+			Usage:         storage.DiskUsage,
+		},
+		Retention:       services.RetentionConfig{CheckInterval: retentionInterval, DryRun: retentionDryRun},
+		CopyRetain:      retainPeriods["FINALIZED_COPY_RETAIN"],
+		SnapshotRetain:  retainPeriods["SNAPSHOT_RETAIN"],
+		AlignedRetain:   retainPeriods["ALIGNED_RETAIN"],
+		HistogramRetain: retainPeriods["HISTOGRAM_RETAIN"],
+
+		RawCaptureDir:       getEnv("RAW_CAPTURE_DIR", ""),
+		RawCaptureKeepHours: rawCaptureKeep,
+
+		ClockCheckInterval:  clockCheckInterval,
+		ClockDriftThreshold: clockDriftThreshold,
+		ClockNTPServer:      getEnv("CLOCK_NTP_SERVER", "pool.ntp.org"),
+		ClockBrokerCheck:    clockBrokerCheck,
+
+		DecimalsCheck: services.DecimalsCheck(getEnv("DECIMALS_CHECK", "warn")),
+		OutlierAction: services.OutlierAction(getEnv("OUTLIER_FILTER", "off")),
+		CrossedQuotes: crossedQuotes,
+		AnomalyDir:    getEnv("ANOMALY_DIR", ""),
+		MetricsAddr:   getEnv("METRICS_ADDR", ""),
+		AdminAddr:     getEnv("ADMIN_ADDR", ""),
+		AdminToken:    getEnv("ADMIN_TOKEN", ""),
+		APIAddr:       getEnv("API_ADDR", ""),
+		APIToken:      getEnv("API_TOKEN", ""),
+
+		StaleQuoteAfter: staleQuoteAfter,
+
+		PauseSchedule: pauseSchedule,
+
+		Burst: services.BurstConfig{
+			SampleInterval: tunables.SampleInterval,
+			AnomalyWindow:  tunables.BurstAnomalyWindow,
+		},
+
+		EffectiveSpreadNotional: effectiveNotional,
+		ConflationInterval:      conflationInterval,
+		SpreadZScoreWindow:      zscoreWindow,
+		Processors:              processors,
+
+		Quota: services.QuotaConfig{
+			InstrumentTicks: quotaLimits[0],
+			InstrumentBytes: quotaLimits[1] * 1024 * 1024,
+			TotalTicks:      quotaLimits[2],
+			TotalBytes:      quotaLimits[3] * 1024 * 1024,
+			Action:          services.QuotaAction(getEnv("QUOTA_ACTION", "alert")),
+			SampleRate:      quotaSampleRate,
+			RowSize:         storage.CSVRowSize,
+		},
+
+		Tracing: tracing.Config{
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			Headers:     traceHeaders,
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "fx-collector"),
+		},
+		TraceSampleRate: tunables.TraceSampleRate,
+
+		OpsLogDir:         getEnv("OPS_LOG_DIR", "data/ops"),
+		HeartbeatInterval: heartbeatInterval,
+
+		HAMode:       getEnv("HA_MODE", "off"),
+		HALockFile:   getEnv("HA_LOCK_FILE", "data/collector.lease"),
+		HALeaseTTL:   haLeaseTTL,
+		HAInstanceID: getEnv("HA_INSTANCE_ID", defaultInstanceID),
+
+		SubscriptionStaleAfter: tunables.SubscriptionStaleAfter,
+		RestartPolicy:          restartPolicy,
+		RunJournal:             getEnv("RUN_JOURNAL", "data/runs.jsonl"),
+		ErrorBudgetInterval:    errorBudgetInterval,
+		SinkSummaryInterval:    sinkSummaryInterval,
+
+		SnapshotInterval: snapshotInterval,
+		SnapshotMaxAge:   snapshotMaxAge,
+		SnapshotDir:      getEnv("SNAPSHOT_DIR", "data/snapshots"),
+
+		AlignedInterval: alignedInterval,
+		AlignedDir:      getEnv("ALIGNED_DIR", "data/aligned"),
+
+		HistogramBounds: histogramBounds,
+		HistogramDir:    getEnv("HISTOGRAM_DIR", "data/histograms"),
+
+		Sessions: sessions,
+		Rollover: rollover,
+		Holidays: holidays,
+
+		CalendarSource:    getEnv("CALENDAR_SOURCE", ""),
+		CalendarDir:       getEnv("CALENDAR_DIR", "data/calendar"),
+		CalendarWindow:    calendarWindow,
+		CalendarMinImpact: calendarMinImpact,
+		CalendarRefresh:   calendarRefresh,
+
+		SequenceStateFile: getEnv("SEQUENCE_STATE_FILE", "data/state/sequences.json"),
+		StateDB:           getEnv("STATE_DB", ""),
+
+		StorageRetry: storage.RetryConfig{
+			MaxAttempts:    retryAttempts,
+			InitialBackoff: retryBackoff,
+			MaxBackoff:     retryMaxBackoff,
+		},
+		DeadLetterDir: getEnv("DEAD_LETTER_DIR", "data/deadletter"),
+		FallbackProbe: fallbackProbe,
+	}
+
+	if tenantName != "" {
+		applyTenant(config)
+	}
+
+	// Every setting has been read by now - anything left over is a typo or obsolete
+	if problems := checkEnv(envFile); len(problems) > 0 {
+		if configStrict {
+			return nil, fmt.Errorf("CONFIG_STRICT: %s", strings.Join(problems, "; "))
+		}
+		for _, problem := range problems {
+			logger.Printf("Warning: %s", problem)
+		}
+	}
+
+	return config, nil
+}
+
+// loadSMTPConfig reads the email alert settings; SMTP_ADDR empty disables email
+func loadSMTPConfig() (notify.SMTPConfig, error) {
+	cfg := notify.SMTPConfig{
+		Addr:     getEnv("SMTP_ADDR", ""),
+		Username: getEnv("SMTP_USERNAME", ""),
+		Password: getEnv("SMTP_PASSWORD", ""),
+		From:     getEnv("SMTP_FROM", ""),
+	}
+	for _, to := range strings.Split(getEnv("SMTP_TO", ""), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			cfg.To = append(cfg.To, to)
+		}
+	}
+
+	var err error
+	if cfg.MinSeverity, err = domain.ParseSeverity(getEnv("SMTP_MIN_SEVERITY", "critical")); err != nil {
+		return cfg, fmt.Errorf("invalid SMTP_MIN_SEVERITY: %w", err)
+	}
+	batchWindowStr := getEnv("SMTP_BATCH_WINDOW", "5m")
+	if cfg.BatchWindow, err = time.ParseDuration(batchWindowStr); err != nil {
+		return cfg, fmt.Errorf("invalid SMTP_BATCH_WINDOW '%s': %w", batchWindowStr, err)
+	}
+	if cfg.Addr != "" {
+		if err := cfg.Validate(); err != nil {
+			return cfg, fmt.Errorf("invalid SMTP settings: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// applyTenant separates the output of a tenant: its directory is inserted before the last element
+// of every output path (data/spreads -> data/acme/spreads), its name prefixes the MQTT topic and
+// suffixes the QuestDB table and BigQuery dataset (fx_ticks -> fx_ticks_acme) and the Kafka topic
+// (fx.ticks -> fx.ticks.acme)
+// Paths and names given as sink parameters (dir=, output=, table=) are taken as they are
+func applyTenant(config *Config) {
+	for _, path := range []*string{
+		&config.SpreadDir, &config.NDJSONOutput, &config.ArrowDir, &config.FinalizedOutboxDir, &config.FinalizedPendingDir,
+		&config.DiskMonitor.Path, &config.AnomalyDir, &config.OpsLogDir, &config.HALockFile,
+		&config.RunJournal, &config.SnapshotDir, &config.AlignedDir, &config.HistogramDir,
+		&config.CalendarDir, &config.SequenceStateFile, &config.StateDB, &config.DeadLetterDir,
+		&config.RawCaptureDir, &config.BigQuery.StagingDir,
+	} {
+		*path = tenant.Path(config.Tenant, *path)
+	}
+	config.QuestDB.Table = tenant.Object(config.Tenant, config.QuestDB.Table)
+	config.BigQuery.Dataset = tenant.Object(config.Tenant, config.BigQuery.Dataset)
+	config.MQTT.TopicTemplate = config.Tenant + "/" + config.MQTT.TopicTemplate
+	if config.Tenant != "" {
+		config.Kafka.Topic += "." + config.Tenant
+	}
+}
+
+// datasetDirs returns the directories recorded tick data goes to, fallback sinks included
+func datasetDirs(config *Config) []string {
+	var dirs []string
+	for _, spec := range config.Recorders {
+		for s := &spec; s != nil; s = s.Fallback {
+			switch s.Name {
+			case "csv":
+				dirs = append(dirs, s.Param("dir", config.SpreadDir))
+			case "arrow":
+				dirs = append(dirs, s.Param("dir", config.ArrowDir))
+			}
+		}
+	}
+	if config.SnapshotInterval > 0 {
+		dirs = append(dirs, config.SnapshotDir)
+	}
+	if config.AlignedInterval > 0 {
+		dirs = append(dirs, config.AlignedDir)
+	}
+	if len(config.HistogramBounds) > 0 {
+		dirs = append(dirs, config.HistogramDir)
+	}
+	return dirs
+}
+
+// csvArchiveDir returns the directory of the configured CSV sink, which the read API serves
+func csvArchiveDir(config *Config) (string, error) {
+	for _, spec := range config.Recorders {
+		if spec.Name == "csv" {
+			return spec.Param("dir", config.SpreadDir), nil
+		}
+	}
+	return "", fmt.Errorf("API_ADDR needs the csv recorder in SPREAD_RECORDERS")
+}
+
+// createRecorders builds the configured sinks, each with its own retry/dead-letter wrapper
+// Wrapping per sink keeps a healthy sink from receiving duplicates when another one is retried
+func createRecorders(config *Config, events chan<- domain.Event, sinkMetrics storage.SinkMetrics) (ports.SpreadRecorder, error) {
+	deadLetter := storage.NewNDJSONDeadLetterQueue(config.DeadLetterDir, "failed_ticks")
+	defaults := recorderDefaults(config)
+
+	// Every backend is metered on its own, labelled by name ("csv", then "csv#2" for a second one)
+	seen := make(map[string]int)
+	meter := func(sink ports.TickWriter, name string) ports.TickWriter {
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s#%d", name, n)
+		}
+		return storage.NewMeteredRecorder(sink, name, sinkMetrics)
+	}
+
+	spools := make(map[string]string) // Spool path -> primary, so no two chains share one
+	var sinks []ports.TickWriter
+	for _, spec := range config.Recorders {
+		sink, err := createSinkChain(config, spec, defaults, deadLetter, events, meter, spools)
+		if err != nil {
+			return nil, err
+		}
+
+		// Instruments routed to other sinks ("sinks" in instruments.json) are kept away from this one
+		var skip []string
+		for ticker, names := range config.InstrumentSinks {
+			if !slices.Contains(names, spec.Name) {
+				skip = append(skip, ticker)
+			}
+		}
+		if len(skip) > 0 {
+			sink = storage.NewTickerFilterRecorder(sink, skip)
+		}
+
+		// flush=5s gives the sink its own flush ticker instead of SPREAD_FLUSH_INTERVAL
+		if value := spec.Param("flush", ""); value != "" {
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("invalid flush interval %q for recorder %s", value, spec.Name)
+			}
+			sink = storage.NewIntervalFlushRecorder(sink, interval)
+		}
+
+		// retain=30d lets the central retention job prune what the sink recorded before that
+		if value := spec.Param("retain", ""); value != "" {
+			retain, err := storage.ParseRetention(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retain parameter for recorder %s: %w", spec.Name, err)
+			}
+			if sink, err = storage.NewRetentionRecorder(sink, spec.Name, retain); err != nil {
+				return nil, err
+			}
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("no recorders configured")
+	}
+	for ticker, names := range config.InstrumentSinks {
+		for _, name := range names {
+			if !slices.ContainsFunc(config.Recorders, func(spec storage.RecorderSpec) bool { return spec.Name == name }) {
+				return nil, fmt.Errorf("instrument %s writes to sink %s, which is not in SPREAD_RECORDERS", ticker, name)
+			}
+		}
+	}
+	return storage.NewMultiRecorder(sinks...), nil
+}
+
+// createFinalizedFiles announces the files finalized by every csv sink to the outbox and/or webhook,
+// and copies them to FINALIZED_COPY_TARGET
+// The copy target is returned for retention (nil without FINALIZED_COPY_TARGET)
+func createFinalizedFiles(config *Config, recorder ports.TickWriter) (*storage.FinalizedFiles, remotefs.Target, error) {
+	var publishers []storage.NamedPublisher
+	if config.FinalizedOutboxDir != "" {
+		outbox, err := storage.NewOutboxPublisher(config.FinalizedOutboxDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		publishers = append(publishers, storage.NamedPublisher{Name: "outbox", FilePublisher: outbox})
+	}
+	if config.FinalizedWebhookURL != "" {
+		publishers = append(publishers, storage.NamedPublisher{Name: "webhook", FilePublisher: notify.NewFileWebhook(config.FinalizedWebhookURL)})
+	}
+	var target remotefs.Target
+	if config.FinalizedCopyTarget != "" {
+		var err error
+		target, err = remotefs.NewTarget(config.FinalizedCopyTarget, config.ObjectStores, config.SFTPPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid FINALIZED_COPY_TARGET: %w", err)
+		}
+		publishers = append(publishers, storage.NamedPublisher{Name: "copy", FilePublisher: remotefs.NewPublisher(target)})
+	}
+
+	// Announcements that fail are kept there and retried, also after a restart
+	finalized, err := storage.NewFinalizedFiles(config.FinalizedPendingDir, publishers...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, csvRecorder := range storage.All[*storage.CSVSpreadRecorder](recorder) {
+		csvRecorder.OnFinalize(finalized.Add)
+	}
+	return finalized, target, nil
+}
+
+// createSinkChain builds a sink with its fallbacks ("mqtt|csv"): a failing sink hands its writes
+// to the next one and gets the missed ticks later from a spool (spool=path, default
+// DEAD_LETTER_DIR/fallback_<sink>_<hash of its parameters>.ndjson). Only the last sink of a chain dead-letters
+func createSinkChain(config *Config, spec storage.RecorderSpec, defaults map[string]url.Values,
+	deadLetter ports.DeadLetterQueue, events chan<- domain.Event, meter func(ports.TickWriter, string) ports.TickWriter,
+	spools map[string]string) (ports.TickWriter, error) {
+	sink, err := storage.NewRecorder(spec.WithDefaults(defaults[spec.Name]))
+	if err != nil {
+		return nil, err
+	}
+	sink = meter(sink, spec.Name)
+	if spec.Fallback == nil {
+		return storage.NewRetryingRecorder(sink, config.StorageRetry, deadLetter), nil
+	}
+
+	// The parameters tell two chains with the same kind of primary apart ("mqtt?broker=a|csv,mqtt?broker=b|csv")
+	primary := storage.RecorderSpec{Name: spec.Name, Params: spec.Params}
+	spool := spec.Param("spool", filepath.Join(config.DeadLetterDir,
+		fmt.Sprintf("fallback_%s_%08x.ndjson", spec.Name, crc32.ChecksumIEEE([]byte(primary.String())))))
+	if other, ok := spools[spool]; ok {
+		return nil, fmt.Errorf("recorders %s and %s share the fallback spool %s: set spool= on one of them", other, primary, spool)
+	}
+	spools[spool] = primary.String()
+
+	fallback, err := createSinkChain(config, *spec.Fallback, defaults, deadLetter, events, meter, spools)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewFallbackRecorder(storage.NewRetryingRecorder(sink, config.StorageRetry, nil), fallback, storage.FallbackConfig{
+		Name:          spec.Name,
+		SpoolPath:     spool,
+		ProbeInterval: config.FallbackProbe,
+		Events:        events,
+	})
+}
+
+// recorderDefaults maps the sink settings from the environment to recorder parameters
+// Parameters given in SPREAD_RECORDERS take precedence
+func recorderDefaults(config *Config) map[string]url.Values {
+	format := string(config.PriceFormat)
+	fsync := config.SyncPolicy.String()
+	return map[string]url.Values{
+		"csv": {
+			"dir":            {config.SpreadDir},
+			"fsync":          {fsync},
+			"buffer":         {config.CSVBuffer},
+			"buffer_window":  {config.FlushInterval.String()},
+			"max_open_files": {strconv.Itoa(config.CSVMaxOpenFiles)},
+			"partial":        {strconv.FormatBool(config.CSVPartialFiles)},
+		},
+		"ndjson": {"output": {config.NDJSONOutput}, "format": {format}},
+		"arrow":  {"dir": {config.ArrowDir}, "format": {format}, "fsync": {fsync}},
+		"mqtt": {
+			"broker":    {config.MQTT.BrokerURL},
+			"client_id": {config.MQTT.ClientID},
+			"username":  {config.MQTT.Username},
+			"password":  {config.MQTT.Password},
+			"topic":     {config.MQTT.TopicTemplate},
+			"qos":       {strconv.Itoa(int(config.MQTT.QoS))},
+			"retained":  {strconv.FormatBool(config.MQTT.Retained)},
+			"format":    {format},
+		},
+		"fix": {"addr": {config.FIX.Addr}, "sender": {config.FIX.SenderCompID}, "symbols": {strings.Join(sinkTickers(config, "fix"), ",")}},
+		"questdb": {
+			"addr":  {config.QuestDB.Addr},
+			"table": {config.QuestDB.Table},
+			"http":  {config.QuestDB.HTTPAddr},
+			"dedup": {strconv.FormatBool(config.QuestDBDedup)},
+		},
+		"remotewrite": {
+			"url":      {config.RemoteWrite.URL},
+			"token":    {config.RemoteWrite.Token},
+			"interval": {config.RemoteWrite.Interval.String()},
+			"job":      {config.RemoteWrite.Job},
+			"instance": {config.RemoteWrite.Instance},
+			"tenant":   {config.Tenant},
+		},
+		"kafka": {
+			"rest":          {config.Kafka.RESTURL},
+			"topic":         {config.Kafka.Topic},
+			"format":        {config.Kafka.Format},
+			"registry":      {config.Kafka.RegistryURL},
+			"compatibility": {config.Kafka.Compatibility},
+		},
+		"bigquery": {
+			"project":    {config.BigQuery.Project},
+			"dataset":    {config.BigQuery.Dataset},
+			"table":      {config.BigQuery.Table},
+			"bars_table": {config.BigQuery.BarsTable},
+			"mode":       {config.BigQuery.Mode},
+			"interval":   {bigQueryInterval(config.BigQuery.Interval)},
+			"partition":  {config.BigQuery.Partitioning},
+			"staging":    {config.BigQuery.StagingDir},
+			"bq":         {config.BigQuery.BQPath},
+			"gcloud":     {config.BigQuery.GcloudPath},
+		},
+	}
+}
+
+// sinkTickers returns the instruments written to the sink of that name, sorted
+func sinkTickers(config *Config, sink string) []string {
+	var tickers []string
+	for ticker := range config.Instruments {
+		if names, routed := config.InstrumentSinks[ticker]; !routed || slices.Contains(names, sink) {
+			tickers = append(tickers, ticker)
+		}
+	}
+	slices.Sort(tickers)
+	return tickers
+}
+
+// bigQueryInterval returns the interval parameter default, empty to leave it to the sink's mode
+func bigQueryInterval(interval time.Duration) string {
+	if interval == 0 {
+		return ""
+	}
+	return interval.String()
+}
+
+// instrument represents a trading instrument from JSON
+type instrument struct {
+	Ticker    string `json:"ticker"`
+	Uic       int    `json:"uic"`
+	AssetType string `json:"assetType"`
+	Decimals  int    `json:"decimals"` // 0 = take from broker metadata (DECIMALS_CHECK)
+
+	PipSize     float64 `json:"pipSize,omitempty"`     // Default 0.01 for JPY pairs, 0.0001 otherwise
+	PipDecimals int     `json:"pipDecimals,omitempty"` // Decimal place of a pip, e.g. 4 for EURUSD quoted at 5 decimals (sets pipSize)
+
+	SpreadDecimals int `json:"spreadDecimals,omitempty"` // Decimals of price-unit spreads (0 = decimals)
+
+	Groups []string `json:"groups,omitempty"` // Group tags such as "majors"; settings of the first group win
+
+	instrumentOverrides
+}
+
+// instrumentOverrides are the settings the "defaults" block of instruments.json sets for all
+// instruments, the "groups" block for the instruments of a group, and each instrument can override;
+// unset ones fall back to the environment
+// The limits are pointers so that an explicit 0 overrides a default rather than counting as unset
+type instrumentOverrides struct {
+	MaxSpread   *float64 `json:"maxSpread,omitempty"`   // Outlier filter bound (0 = none)
+	MaxTickRate *float64 `json:"maxTickRate,omitempty"` // Recorded ticks per second (0 = unlimited)
+	SpreadUnit  string   `json:"spreadUnit,omitempty"`  // Overrides SPREAD_UNIT
+
+	SampleInterval string   `json:"sampleInterval,omitempty"` // Overrides SAMPLE_INTERVAL ("0" records every tick)
+	StaleAfter     string   `json:"staleAfter,omitempty"`     // Overrides SUBSCRIPTION_STALE_AFTER ("0" disables)
+	Sessions       string   `json:"sessions,omitempty"`       // Overrides SESSIONS ("none" disables labels)
+	Sinks          []string `json:"sinks,omitempty"`          // Sinks of SPREAD_RECORDERS to write to, by name (default all)
+}
+
+// withDefaults fills the settings o leaves unset from defaults
+func (o instrumentOverrides) withDefaults(defaults instrumentOverrides) instrumentOverrides {
+	if o.MaxSpread == nil {
+		o.MaxSpread = defaults.MaxSpread
+	}
+	if o.MaxTickRate == nil {
+		o.MaxTickRate = defaults.MaxTickRate
+	}
+	if o.SpreadUnit == "" {
+		o.SpreadUnit = defaults.SpreadUnit
+	}
+	if o.SampleInterval == "" {
+		o.SampleInterval = defaults.SampleInterval
+	}
+	if o.StaleAfter == "" {
+		o.StaleAfter = defaults.StaleAfter
+	}
+	if o.Sessions == "" {
+		o.Sessions = defaults.Sessions
+	}
+	if o.Sinks == nil {
+		o.Sinks = defaults.Sinks
+	}
+	return o
+}
+
+// instrumentsFile is the layout of instruments.json
+type instrumentsFile struct {
+	Defaults    instrumentOverrides            `json:"defaults"`
+	Groups      map[string]instrumentOverrides `json:"groups"`
+	Instruments []instrument                   `json:"instruments"`
+}
+
+// readInstruments parses an instruments file
+// strict rejects unknown fields so typos such as "maxSpead" are reported instead of ignored
+func readInstruments(path string, strict bool) ([]instrument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var config instrumentsFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	if len(config.Instruments) == 0 {
+		return nil, fmt.Errorf("no instruments found")
+	}
+	groups := instrumentGroups(config.Instruments)
+	for name := range config.Groups {
+		if _, ok := groups[name]; !ok {
+			return nil, fmt.Errorf("group %s has settings but no instruments", name)
+		}
+	}
+	for i := range config.Instruments {
+		inst := &config.Instruments[i]
+		for _, group := range inst.Groups {
+			inst.instrumentOverrides = inst.withDefaults(config.Groups[group])
+		}
+		inst.instrumentOverrides = inst.withDefaults(config.Defaults)
+	}
+	return config.Instruments, nil
+}
+
+// instrumentGroups collects the group tags of instruments
+func instrumentGroups(instruments []instrument) domain.InstrumentGroups {
+	groups := make(domain.InstrumentGroups)
+	for _, inst := range instruments {
+		for _, group := range inst.Groups {
+			groups[group] = append(groups[group], inst.Ticker)
+		}
+	}
+	return groups
+}
+
+// checkInstruments returns every problem in the instrument list, in file order
+func checkInstruments(instruments []instrument) []string {
+	var problems []string
+	tickers := make(map[string]int)
+	uics := make(map[string]int)
+
+	for i, inst := range instruments {
+		name := inst.Ticker
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			problems = append(problems, fmt.Sprintf("instrument %s: missing ticker", name))
+		}
+		report := func(format string, args ...any) {
+			problems = append(problems, fmt.Sprintf("instrument %s: ", name)+fmt.Sprintf(format, args...))
+		}
+
+		if inst.Uic <= 0 {
+			report("invalid uic %d", inst.Uic)
+		}
+		if inst.AssetType == "" {
+			report("missing assetType")
+		}
+		if inst.Decimals < 0 || inst.Decimals > 10 {
+			report("invalid decimals %d", inst.Decimals)
+		}
+		if inst.SpreadDecimals < 0 || inst.SpreadDecimals > 10 {
+			report("invalid spreadDecimals %d", inst.SpreadDecimals)
+		} else if inst.SpreadDecimals > 0 && inst.SpreadDecimals < inst.Decimals {
+			report("spreadDecimals %d below decimals %d", inst.SpreadDecimals, inst.Decimals)
+		}
+		if inst.MaxSpread != nil && *inst.MaxSpread < 0 {
+			report("negative maxSpread %v", *inst.MaxSpread)
+		}
+		if inst.MaxTickRate != nil && *inst.MaxTickRate < 0 {
+			report("negative maxTickRate %v", *inst.MaxTickRate)
+		}
+		if inst.PipSize < 0 {
+			report("negative pipSize %v", inst.PipSize)
+		}
+		switch {
+		case inst.PipDecimals < 0 || inst.PipDecimals > 10:
+			report("invalid pipDecimals %d", inst.PipDecimals)
+		case inst.PipDecimals > 0 && inst.Decimals > 0 && inst.PipDecimals > inst.Decimals:
+			report("pipDecimals %d above decimals %d", inst.PipDecimals, inst.Decimals)
+		case inst.PipDecimals > 0 && inst.PipSize > 0 && inst.PipSize != domain.PipSizeForDecimals(inst.PipDecimals):
+			report("pipSize %v contradicts pipDecimals %d", inst.PipSize, inst.PipDecimals)
+		}
+		if inst.SpreadUnit != "" {
+			if _, err := domain.ParseSpreadUnit(inst.SpreadUnit); err != nil {
+				report("%v", err)
+			}
+		}
+		if _, err := parseOverrideDuration(inst.SampleInterval); err != nil {
+			report("invalid sampleInterval %q", inst.SampleInterval)
+		}
+		if _, err := parseOverrideDuration(inst.StaleAfter); err != nil {
+			report("invalid staleAfter %q", inst.StaleAfter)
+		}
+		if _, err := parseInstrumentSessions(inst.Sessions); err != nil {
+			report("invalid sessions: %v", err)
+		}
+		for _, sink := range inst.Sinks {
+			if !slices.Contains(storage.RecorderNames(), sink) {
+				report("unknown sink %q (known: %s)", sink, strings.Join(storage.RecorderNames(), ", "))
+			}
+		}
+
+		if inst.Ticker != "" {
+			if first, ok := tickers[inst.Ticker]; ok {
+				report("duplicate ticker (also instrument #%d)", first)
+			} else {
+				tickers[inst.Ticker] = i + 1
+			}
+		}
+		// The same UIC may be listed under different asset types (e.g. FxSpot and FxForwards)
+		if inst.Uic > 0 {
+			key := fmt.Sprintf("%d/%s", inst.Uic, inst.AssetType)
+			if first, ok := uics[key]; ok {
+				report("duplicate uic %d (%s) (also instrument #%d)", inst.Uic, inst.AssetType, first)
+			} else {
+				uics[key] = i + 1
+			}
+		}
+	}
+	// Report tools and the admin API accept group names where they take tickers
+	for _, group := range instrumentGroups(instruments).Names() {
+		if _, ok := tickers[group]; ok {
+			problems = append(problems, fmt.Sprintf("group %s: same name as a ticker", group))
+		}
+	}
+	return problems
+}
+
+// instrumentSet is a loaded instruments file
+type instrumentSet struct {
+	Instruments map[string]services.Instrument
+	Sinks       map[string][]string // Sink names of instruments written to only some sinks
+	Groups      domain.InstrumentGroups
+}
+
+// loadInstruments loads trading instruments from a JSON file
+// defaultUnit applies to instruments without their own spreadUnit
+func loadInstruments(filepath string, defaultUnit domain.SpreadUnit) (instrumentSet, error) {
+	list, err := readInstruments(filepath, false)
+	if err != nil {
+		return instrumentSet{}, err
+	}
+	if problems := checkInstruments(list); len(problems) > 0 {
+		return instrumentSet{}, fmt.Errorf("%s (run 'collector validate' for the full list)", problems[0])
+	}
+
+	// Convert to map for easy lookup
+	instruments := make(map[string]services.Instrument)
+	sinks := make(map[string][]string)
+	for _, inst := range list {
+		unit := defaultUnit
+		if inst.SpreadUnit != "" {
+			unit, _ = domain.ParseSpreadUnit(inst.SpreadUnit) // Checked above
+		}
+		pipSize := inst.PipSize
+		if inst.PipDecimals > 0 {
+			pipSize = domain.PipSizeForDecimals(inst.PipDecimals)
+		} else if pipSize == 0 {
+			pipSize = domain.DefaultPipSize(inst.Ticker)
+		}
+		// Checked above
+		sampleInterval, _ := parseOverrideDuration(inst.SampleInterval)
+		staleAfter, _ := parseOverrideDuration(inst.StaleAfter)
+		sessions, _ := parseInstrumentSessions(inst.Sessions)
+
+		instruments[inst.Ticker] = services.Instrument{
+			Ticker:    inst.Ticker,
+			Uic:       inst.Uic,
+			AssetType: inst.AssetType,
+			Decimals:  inst.Decimals,
+			MaxSpread: limit(inst.MaxSpread),
+
+			MaxTickRate: limit(inst.MaxTickRate),
+			PipSize:     pipSize,
+			SpreadUnit:  unit,
+
+			SpreadDecimals: inst.SpreadDecimals,
+
+			SampleInterval: sampleInterval,
+			StaleAfter:     staleAfter,
+			Sessions:       sessions,
+		}
+		if len(inst.Sinks) > 0 {
+			sinks[inst.Ticker] = inst.Sinks
+		}
+	}
+
+	return instrumentSet{Instruments: instruments, Sinks: sinks, Groups: instrumentGroups(list)}, nil
+}
+
+// limit returns an instrument limit, 0 (none) if unset
+func limit(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// parseOverrideDuration parses a per-instrument duration: "" keeps the global setting (0),
+// and "0" switches the feature off for the instrument (-1, see services.Instrument)
+func parseOverrideDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if d == 0 {
+		return -1, nil
+	}
+	return d, nil
+}
+
+// parseInstrumentSessions parses a per-instrument SESSIONS override: nil keeps the global sessions
+func parseInstrumentSessions(spec string) ([]domain.Session, error) {
+	switch spec {
+	case "":
+		return nil, nil
+	case "none":
+		return []domain.Session{}, nil
+	}
+	return domain.ParseSessions(spec)
+}
+
+// clockDriftConfig returns the reference clocks to check the local clock against
+// The broker is asked through its gateway's Date header, so no API call or token is needed
+func clockDriftConfig(config *Config, authClient saxo.AuthClient) services.ClockDriftConfig {
+	cfg := services.ClockDriftConfig{Interval: config.ClockCheckInterval, Threshold: config.ClockDriftThreshold}
+	if config.ClockCheckInterval == 0 {
+		return cfg
+	}
+	if config.ClockNTPServer != "none" {
+		cfg.Sources = append(cfg.Sources, clock.NewNTPSource(config.ClockNTPServer))
+	}
+	if config.ClockBrokerCheck {
+		cfg.Sources = append(cfg.Sources, clock.NewHTTPDateSource("broker", authClient.GetBaseURL()))
+	}
+	return cfg
+}
+
+// metricsMux serves the registry at /metrics and the build and uptime at /health
+func metricsMux(registry *metrics.Registry, startedAt time.Time) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Status    string         `json:"status"`
+			Build     buildinfo.Info `json:"build"`
+			StartedAt time.Time      `json:"started_at"`
+			Uptime    string         `json:"uptime"`
+		}{"ok", buildinfo.Get(), startedAt.UTC(), time.Since(startedAt).Round(time.Second).String()})
+	})
+	return mux
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadInstruments_ZeroOverridesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instruments.json")
+	err := os.WriteFile(path, []byte(`{
+		"defaults": {"maxSpread": 0.002, "maxTickRate": 5},
+		"groups": {"exotics": {"maxSpread": 0.05}},
+		"instruments": [
+			{"ticker": "EURUSD", "uic": 21, "assetType": "FxSpot", "decimals": 5},
+			{"ticker": "USDTRY", "uic": 1790, "assetType": "FxSpot", "decimals": 4, "groups": ["exotics"], "maxTickRate": 0},
+			{"ticker": "USDZAR", "uic": 1791, "assetType": "FxSpot", "decimals": 4, "groups": ["exotics"], "maxSpread": 0}
+		]
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := readInstruments(path, true)
+	if err != nil {
+		t.Fatalf("readInstruments failed: %v", err)
+	}
+	for i, want := range []struct {
+		maxSpread, maxTickRate float64
+	}{
+		{0.002, 5}, // Defaults
+		{0.05, 0},  // Group bound, own rate limit switched off
+		{0, 5},     // Own bound switched off over the group's
+	} {
+		inst := list[i]
+		if got := limit(inst.MaxSpread); got != want.maxSpread {
+			t.Errorf("%s: expected maxSpread %v, got %v", inst.Ticker, want.maxSpread, got)
+		}
+		if got := limit(inst.MaxTickRate); got != want.maxTickRate {
+			t.Errorf("%s: expected maxTickRate %v, got %v", inst.Ticker, want.maxTickRate, got)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/notify"
+	"github.com/bjoelf/fx-collector/internal/services"
+	"github.com/joho/godotenv"
+)
+
+// processEnv holds the variables set before the .env file was loaded; the file never overrides them
+var processEnv = make(map[string]bool)
+
+// envFileKeys lists the variables set from the .env file, at start or by the last reload
+var envFileKeys []string
+
+// tunableSettings are the settings SIGHUP and POST /admin/reload apply without a restart
+type tunableSettings struct {
+	services.Tunables
+	NotifyCooldown time.Duration
+}
+
+// loadTunables reads the settings that can be changed at runtime
+func loadTunables() (tunableSettings, error) {
+	var t tunableSettings
+	var err error
+
+	flushIntervalStr := getEnv("SPREAD_FLUSH_INTERVAL", "30s")
+	if t.FlushInterval, err = time.ParseDuration(flushIntervalStr); err != nil {
+		return t, fmt.Errorf("invalid SPREAD_FLUSH_INTERVAL '%s': %w", flushIntervalStr, err)
+	}
+
+	traceSampleRateStr := getEnv("TRACE_SAMPLE_RATE", "100")
+	if t.TraceSampleRate, err = strconv.Atoi(traceSampleRateStr); err != nil || t.TraceSampleRate < 1 {
+		return t, fmt.Errorf("invalid TRACE_SAMPLE_RATE '%s': must be a positive integer", traceSampleRateStr)
+	}
+
+	dataGapThresholdStr := getEnv("DATA_GAP_THRESHOLD", "5m")
+	if t.DataGapThreshold, err = time.ParseDuration(dataGapThresholdStr); err != nil {
+		return t, fmt.Errorf("invalid DATA_GAP_THRESHOLD '%s': %w", dataGapThresholdStr, err)
+	}
+
+	staleAfterStr := getEnv("SUBSCRIPTION_STALE_AFTER", "2m")
+	if t.SubscriptionStaleAfter, err = time.ParseDuration(staleAfterStr); err != nil {
+		return t, fmt.Errorf("invalid SUBSCRIPTION_STALE_AFTER '%s': %w", staleAfterStr, err)
+	}
+
+	sampleIntervalStr := getEnv("SAMPLE_INTERVAL", "0")
+	if t.SampleInterval, err = time.ParseDuration(sampleIntervalStr); err != nil || t.SampleInterval < 0 {
+		return t, fmt.Errorf("invalid SAMPLE_INTERVAL '%s': must be a duration of 0 or more", sampleIntervalStr)
+	}
+
+	burstAnomalyWindowStr := getEnv("BURST_ANOMALY_WINDOW", "5m")
+	if t.BurstAnomalyWindow, err = time.ParseDuration(burstAnomalyWindowStr); err != nil || t.BurstAnomalyWindow < 0 {
+		return t, fmt.Errorf("invalid BURST_ANOMALY_WINDOW '%s': must be a duration of 0 or more", burstAnomalyWindowStr)
+	}
+
+	notifyCooldownStr := getEnv("NOTIFY_COOLDOWN", "15m")
+	if t.NotifyCooldown, err = time.ParseDuration(notifyCooldownStr); err != nil {
+		return t, fmt.Errorf("invalid NOTIFY_COOLDOWN '%s': %w", notifyCooldownStr, err)
+	}
+
+	return t, nil
+}
+
+// configReloader re-reads the .env file and applies the tunable settings to the running collector
+type configReloader struct {
+	envFile        string
+	service        *services.CollectorService
+	notifier       *notify.ThrottledNotifier // nil without WEBHOOK_URL
+	notifyCooldown time.Duration
+	logger         *log.Logger
+
+	mu sync.Mutex
+}
+
+// ReloadConfig implements ports.ConfigReloader
+func (r *configReloader) ReloadConfig() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reloadEnvFile(); err != nil {
+		return nil, err
+	}
+	settings, err := loadTunables()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := r.service.Reload(settings.Tunables)
+	if err != nil {
+		return nil, err
+	}
+	if r.notifier != nil && settings.NotifyCooldown != r.notifyCooldown {
+		r.notifier.SetCooldown(settings.NotifyCooldown)
+		change := fmt.Sprintf("notify cooldown %v -> %v", r.notifyCooldown, settings.NotifyCooldown)
+		r.logger.Printf("Settings reloaded: [%s]", change)
+		changes = append(changes, change)
+		r.notifyCooldown = settings.NotifyCooldown
+	}
+	return changes, nil
+}
+
+// reloadEnvFile sets the variables of the .env file again, unless they came from the process environment
+// Variables removed from the file since the last load are unset
+func (r *configReloader) reloadEnvFile() error {
+	if r.envFile == "" {
+		return nil
+	}
+	values, err := godotenv.Read(r.envFile)
+	if err != nil {
+		return fmt.Errorf("failed to re-read %s: %w", r.envFile, err)
+	}
+
+	for _, name := range envFileKeys {
+		if _, ok := values[name]; !ok {
+			os.Unsetenv(name)
+		}
+	}
+
+	envFileKeys = envFileKeys[:0]
+	for name, value := range values {
+		if processEnv[name] {
+			continue
+		}
+		os.Setenv(name, value)
+		envFileKeys = append(envFileKeys, name)
+	}
+	return nil
+}
+
+// reloadOnSignal reloads the configuration on every signal until signals is closed
+func (r *configReloader) reloadOnSignal(signals <-chan os.Signal) {
+	for range signals {
+		r.logger.Println("SIGHUP received - reloading settings")
+		changes, err := r.ReloadConfig()
+		switch {
+		case err != nil:
+			r.logger.Printf("Reload failed, settings unchanged: %v", err)
+		case len(changes) == 0:
+			r.logger.Println("Reload: no runtime setting changed")
+		}
+	}
+}
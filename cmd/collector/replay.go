@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/replay"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/services"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// replayAuth stands in for the Saxo auth client during a replay: always logged in, never called
+// Embedding the interface keeps it complete; the methods the service needs are overridden
+type replayAuth struct {
+	saxo.AuthClient
+}
+
+func (replayAuth) IsAuthenticated() bool           { return true }
+func (replayAuth) Login(ctx context.Context) error { return nil }
+func (replayAuth) GetBaseURL() string              { return "" }
+func (replayAuth) GetWebSocketURL() string         { return "replay" }
+
+// runReplay implements "collector replay": feed raw capture files (RAW_CAPTURE_DIR) through the
+// mapper and record the result, without a broker connection
+// Returns the process exit code: 0 replayed, 1 replay failed, 2 usage or setup error
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	sinks := fs.String("sinks", "ndjson", "sinks for the replayed ticks, same syntax as SPREAD_RECORDERS")
+	speed := fs.Float64("speed", 0, "replay speed relative to the capture, e.g. 1 for real time (0: as fast as possible)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: collector replay [-sinks specs] [-speed x] capture-file-or-dir...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// Ticks may go to stdout (ndjson), so logging goes to stderr
+	logger := log.New(os.Stderr, "[FX-COLLECTOR] ", log.LstdFlags|log.Lmsgprefix)
+
+	var files []string
+	for _, arg := range fs.Args() {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			captures, err := storage.ListRawCaptures(arg)
+			if err != nil {
+				logger.Printf("❌ %v", err)
+				return 2
+			}
+			files = append(files, captures...)
+			continue
+		}
+		files = append(files, arg)
+	}
+
+	config, err := loadConfig(logger)
+	if err != nil {
+		logger.Printf("❌ %v", err)
+		return 2
+	}
+	config.Recorders = nil
+	for _, definition := range strings.Split(*sinks, ",") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		spec, err := storage.ParseRecorderSpec(definition)
+		if err != nil {
+			logger.Printf("❌ invalid -sinks: %v", err)
+			return 2
+		}
+		config.Recorders = append(config.Recorders, spec)
+	}
+
+	events := make(chan domain.Event, 16)
+	go func() {
+		for event := range events {
+			logger.Printf("%s: %s", event.Type, event.Message)
+		}
+	}()
+	recorder, err := createRecorders(config, events, storage.SinkMetrics{})
+	if err != nil {
+		logger.Printf("❌ failed to create spread recorder: %v", err)
+		return 2
+	}
+
+	// Only the options that shape the mapped ticks; monitoring and side outputs stay off
+	client := replay.NewClient(files, *speed)
+	opts := []services.Option{
+		services.WithWebSocketClient(client),
+		services.WithSessions(config.Sessions),
+		services.WithRolloverFlags(config.Rollover),
+		services.WithHolidays(config.Holidays),
+		services.WithCrossedQuotes(config.CrossedQuotes),
+		services.WithStaleQuotes(config.StaleQuoteAfter),
+	}
+	if config.SpreadZScoreWindow > 0 {
+		opts = append(opts, services.WithSpreadZScore(config.SpreadZScoreWindow))
+	}
+	service, err := services.NewCollectorService(replayAuth{}, nil, config.Instruments, recorder, config.FlushInterval, logger, opts...)
+	if err != nil {
+		logger.Printf("❌ %v", err)
+		return 2
+	}
+
+	logger.Printf("Replaying %d capture files", len(files))
+	startedAt := time.Now()
+	if err := service.Start(); err != nil {
+		logger.Printf("❌ %v", err)
+		return 1
+	}
+	<-client.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	stopErr := service.Stop(ctx)
+
+	count, err := client.Result()
+	if err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		logger.Printf("❌ Replay failed after %d updates: %v", count, err)
+		return 1
+	}
+	logger.Printf("Replayed %d updates in %v", count, time.Since(startedAt).Round(time.Millisecond))
+	return 0
+}
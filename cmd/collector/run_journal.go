@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/buildinfo"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// runJournal writes the start and stop record of this run to RUN_JOURNAL
+type runJournal struct {
+	journal *storage.RunJournal
+	logger  *log.Logger
+	start   domain.RunRecord
+	once    sync.Once
+}
+
+// startRun appends the start record: effective settings, config hash, instruments and build
+// A journal that can't be written is logged, not fatal - the ticks matter more than the metadata
+func startRun(path string, config *Config, logger *log.Logger) *runJournal {
+	now := time.Now().UTC()
+	hostname, _ := os.Hostname()
+	settings := effectiveSettings()
+	instruments := slices.Sorted(maps.Keys(config.Instruments))
+
+	record := domain.RunRecord{
+		RunID:       newRunID(now),
+		Event:       domain.RunStarted,
+		Timestamp:   now,
+		StartedAt:   now,
+		Host:        hostname,
+		PID:         os.Getpid(),
+		ConfigHash:  configHash(settings, instruments),
+		Settings:    settings,
+		Instruments: instruments,
+	}
+	build := buildinfo.Get()
+	record.Version, record.Revision, record.Modified = build.Version, build.Commit, build.Modified
+	record.BuildDate, record.GoVersion = build.Date, build.GoVersion
+
+	runs := &runJournal{journal: storage.NewRunJournal(path), logger: logger, start: record}
+	if err := runs.journal.Append(record); err != nil {
+		logger.Printf("Warning: failed to write run journal: %v", err)
+	} else {
+		logger.Printf("Run %s started (config %s, journal %s)", record.RunID, record.ConfigHash[:12], path)
+	}
+	return runs
+}
+
+// stop appends the stop record; only the first call per run is written
+func (r *runJournal) stop(reason string, err error) {
+	r.once.Do(func() {
+		record := domain.RunRecord{
+			RunID:      r.start.RunID,
+			Event:      domain.RunStopped,
+			Timestamp:  time.Now().UTC(),
+			StartedAt:  r.start.StartedAt,
+			Host:       r.start.Host,
+			PID:        r.start.PID,
+			Version:    r.start.Version,
+			Revision:   r.start.Revision,
+			Modified:   r.start.Modified,
+			BuildDate:  r.start.BuildDate,
+			GoVersion:  r.start.GoVersion,
+			ConfigHash: r.start.ConfigHash,
+			Reason:     reason,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if err := r.journal.Append(record); err != nil {
+			r.logger.Printf("Warning: failed to write run journal: %v", err)
+		}
+	})
+}
+
+// newRunID returns a sortable, unique run ID like 20250310T080000Z-1a2b3c4d
+func newRunID(now time.Time) string {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	return now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// configHash hashes the settings (sorted by key) and instruments, so equally configured runs share a hash
+func configHash(settings map[string]string, instruments []string) string {
+	hash := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		fmt.Fprintf(hash, "%s=%s\n", key, settings[key])
+	}
+	for _, ticker := range instruments {
+		fmt.Fprintf(hash, "instrument=%s\n", ticker)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/clock"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+	"github.com/bjoelf/saxo-adapter/adapter/websocket"
+)
+
+// errSkipped marks a self-test check that could not run because an earlier one failed
+var errSkipped = errors.New("skipped")
+
+// selftest runs the checks of "collector selftest" and prints one line per check
+type selftest struct {
+	out    io.Writer
+	failed int
+}
+
+// check runs fn and prints its result; fn returns what it verified
+func (s *selftest) check(name string, fn func() (string, error)) bool {
+	detail, err := fn()
+	switch {
+	case errors.Is(err, errSkipped):
+		fmt.Fprintf(s.out, "⏭️  %-12s %s\n", name, detail)
+	case err != nil:
+		fmt.Fprintf(s.out, "❌ %-12s %v\n", name, err)
+		s.failed++
+	default:
+		fmt.Fprintf(s.out, "✅ %-12s %s\n", name, detail)
+	}
+	return err == nil
+}
+
+// runSelftest implements "collector selftest": check everything a session depends on (config,
+// credentials, WebSocket, instruments, storage, disk space, clock) without collecting
+// Returns the process exit code: 0 all checks passed, 1 a check failed, 2 usage error
+func runSelftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 2*time.Minute, "timeout for login, broker lookups and clock queries")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: collector selftest [-timeout d]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Adapter and config logging goes to stderr, the report to stdout
+	logger := log.New(os.Stderr, "[FX-COLLECTOR] ", log.LstdFlags|log.Lmsgprefix)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	s := &selftest{out: os.Stdout}
+	var config *Config
+	configOK := s.check("config", func() (string, error) {
+		var err error
+		if config, err = loadConfig(logger); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d instruments from %s, %s environment", len(config.Instruments), config.InstrumentsPath, config.Environment), nil
+	})
+
+	var authClient saxo.AuthClient
+	s.check("credentials", func() (string, error) {
+		var err error
+		if authClient, err = brokerLogin(ctx, logger); err != nil {
+			return "", err
+		}
+		return "logged in to " + authClient.GetBaseURL(), nil
+	})
+
+	s.check("websocket", func() (string, error) {
+		if authClient == nil {
+			return "needs credentials", errSkipped
+		}
+		wsClient := websocket.NewSaxoWebSocketClient(authClient, authClient.GetBaseURL(), authClient.GetWebSocketURL(), logger)
+		if err := wsClient.Connect(ctx); err != nil {
+			return "", fmt.Errorf("failed to connect to %s: %w", authClient.GetWebSocketURL(), err)
+		}
+		_ = wsClient.Close()
+		return "connected to " + authClient.GetWebSocketURL(), nil
+	})
+
+	s.check("instruments", func() (string, error) {
+		if !configOK || authClient == nil {
+			return "needs config and credentials", errSkipped
+		}
+		instruments, err := readInstruments(config.InstrumentsPath, true)
+		if err != nil {
+			return "", err
+		}
+		source, err := brokerReference(ctx, authClient)
+		if err != nil {
+			return "", err
+		}
+		if problems := resolveInstruments(ctx, source, instruments, io.Discard); len(problems) > 0 {
+			return "", fmt.Errorf("%d of %d failed: %s", len(problems), len(instruments), problems[0])
+		}
+		return fmt.Sprintf("%d resolved at the broker", len(instruments)), nil
+	})
+
+	if !configOK {
+		s.check("storage", func() (string, error) { return "needs config", errSkipped })
+		s.check("disk space", func() (string, error) { return "needs config", errSkipped })
+		s.check("clock", func() (string, error) { return "needs config", errSkipped })
+	} else {
+		for _, dir := range selftestDirs(config) {
+			s.check("storage", func() (string, error) { return checkWritable(dir, config.Environment, config.ExplicitEnv) })
+		}
+		s.check("disk space", func() (string, error) { return checkDiskSpace(config) })
+		s.check("clock", func() (string, error) { return checkClock(ctx, config, authClient) })
+	}
+
+	if s.failed > 0 {
+		fmt.Fprintf(s.out, "%d check(s) failed\n", s.failed)
+		return 1
+	}
+	fmt.Fprintln(s.out, "All checks passed")
+	return 0
+}
+
+// selftestDirs returns the directories the collector writes to: the datasets and its state
+func selftestDirs(config *Config) []string {
+	dirs := datasetDirs(config)
+	for _, dir := range []string{filepath.Dir(config.SequenceStateFile), config.DeadLetterDir, config.OpsLogDir} {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if config.StateDB != "" {
+		dirs = append(dirs, filepath.Dir(config.StateDB))
+	}
+	return dirs
+}
+
+// checkWritable creates dir if needed and writes and removes a probe file in it
+// Dataset directories are also checked against the environment, without tagging them
+func checkWritable(dir, env string, explicitEnv bool) (string, error) {
+	if err := storage.CheckEnvironment(dir, env, explicitEnv); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	probe, err := os.CreateTemp(dir, ".selftest-*")
+	if err != nil {
+		return "", fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	_, err = probe.WriteString("selftest\n")
+	probe.Close()
+	os.Remove(probe.Name())
+	if err != nil {
+		return "", fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	return dir + " writable", nil
+}
+
+// checkDiskSpace compares the free space under the CSV directory with DISK_MIN_FREE_MB
+func checkDiskSpace(config *Config) (string, error) {
+	free, _, err := storage.DiskUsage(config.DiskMonitor.Path)
+	if err != nil {
+		return "", err
+	}
+	detail := fmt.Sprintf("%d MB free at %s", free/(1024*1024), config.DiskMonitor.Path)
+	if free < config.DiskMonitor.MinFreeBytes {
+		return "", fmt.Errorf("%s, DISK_MIN_FREE_MB is %d", detail, config.DiskMonitor.MinFreeBytes/(1024*1024))
+	}
+	return detail, nil
+}
+
+// checkClock measures the local clock against NTP and the broker (when logged in) like the drift check
+func checkClock(ctx context.Context, config *Config, authClient saxo.AuthClient) (string, error) {
+	var sources []ports.ClockSource
+	if config.ClockNTPServer != "none" {
+		sources = append(sources, clock.NewNTPSource(config.ClockNTPServer))
+	}
+	if config.ClockBrokerCheck && authClient != nil {
+		sources = append(sources, clock.NewHTTPDateSource("broker", authClient.GetBaseURL()))
+	}
+	if len(sources) == 0 {
+		return "no reference clock (CLOCK_NTP_SERVER=none)", errSkipped
+	}
+
+	detail := ""
+	for _, source := range sources {
+		offset, uncertainty, err := source.ClockOffset(ctx)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", source.Name(), err)
+		}
+		if detail != "" {
+			detail += ", "
+		}
+		detail += fmt.Sprintf("%s offset %v ±%v", source.Name(), offset.Round(time.Millisecond), uncertainty.Round(time.Millisecond))
+		if drift := max(offset.Abs()-uncertainty, 0); drift > config.ClockDriftThreshold {
+			return "", fmt.Errorf("%s exceeds CLOCK_DRIFT_THRESHOLD %v", detail, config.ClockDriftThreshold)
+		}
+	}
+	return detail, nil
+}
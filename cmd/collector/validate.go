@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/saxoref"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// runValidate implements "collector validate": check the instruments file and resolve every
+// instrument against the broker before a long-running session is started
+// Returns the process exit code: 0 valid, 1 problems found, 2 usage or setup error
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	instrumentsPath := fs.String("instruments", "", "instruments file (default: INSTRUMENTS_PATH lookup)")
+	offline := fs.Bool("offline", false, "skip broker lookups (schema and uniqueness checks only)")
+	timeout := fs.Duration("timeout", 2*time.Minute, "timeout for login and broker lookups")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: collector validate [-instruments path] [-offline] [-timeout d]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := log.New(os.Stderr, "[FX-COLLECTOR] ", log.LstdFlags|log.Lmsgprefix)
+	loadEnvFile(logger)
+
+	path := *instrumentsPath
+	if path == "" {
+		var err error
+		if path, err = findInstrumentsFile(logger); err != nil {
+			logger.Printf("❌ %v", err)
+			return 2
+		}
+	}
+
+	instruments, err := readInstruments(path, true)
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		return 1
+	}
+	problems := checkInstruments(instruments)
+
+	if !*offline {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+
+		authClient, err := brokerLogin(ctx, logger)
+		if err != nil {
+			logger.Printf("❌ %v (use -offline to skip broker lookups)", err)
+			return 2
+		}
+		source, err := brokerReference(ctx, authClient)
+		if err != nil {
+			logger.Printf("❌ %v (use -offline to skip broker lookups)", err)
+			return 2
+		}
+		problems = append(problems, resolveInstruments(ctx, source, instruments, os.Stdout)...)
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d problem(s)\n", path, len(problems))
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return 1
+	}
+
+	fmt.Printf("%s: ✅ %d instruments valid\n", path, len(instruments))
+	return 0
+}
+
+// brokerLogin creates a Saxo auth client and logs in unless a stored token is still valid
+func brokerLogin(ctx context.Context, logger *log.Logger) (saxo.AuthClient, error) {
+	authClient, err := saxo.CreateSaxoAuthClient(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth client: %w", err)
+	}
+	if !authClient.IsAuthenticated() {
+		if err := authClient.Login(ctx); err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+	return authClient, nil
+}
+
+// brokerReference returns a Saxo reference data client for a logged in auth client
+func brokerReference(ctx context.Context, authClient saxo.AuthClient) (*saxoref.InstrumentDetails, error) {
+	saxoAuth, ok := authClient.(interface {
+		GetHTTPClient(ctx context.Context) (*http.Client, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("auth client provides no HTTP client")
+	}
+	client, err := saxoAuth.GetHTTPClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HTTP client: %w", err)
+	}
+	return saxoref.NewInstrumentDetails(client, authClient.GetBaseURL()), nil
+}
+
+// brokerMetadata provides the decimals check with Saxo reference data, creating the client on
+// first use: the check runs after login, when the auth client can hand out an HTTP client
+type brokerMetadata struct {
+	authClient saxo.AuthClient
+	source     *saxoref.InstrumentDetails
+}
+
+// Decimals implements ports.InstrumentMetadata
+func (m *brokerMetadata) Decimals(ctx context.Context, uic int, assetType string) (int, error) {
+	if m.source == nil {
+		source, err := brokerReference(ctx, m.authClient)
+		if err != nil {
+			return 0, err
+		}
+		m.source = source
+	}
+	return m.source.Decimals(ctx, uic, assetType)
+}
+
+// resolveInstruments looks every instrument up by UIC and compares symbol and decimals
+// A symbol that differs from the ticker is only a warning: tickers are local names
+func resolveInstruments(ctx context.Context, source *saxoref.InstrumentDetails, instruments []instrument, out io.Writer) []string {
+	var problems []string
+	for _, inst := range instruments {
+		if inst.Uic <= 0 || inst.AssetType == "" {
+			continue // Already reported
+		}
+
+		ref, err := source.Lookup(ctx, inst.Uic, inst.AssetType)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("instrument %s: %v", inst.Ticker, err))
+			continue
+		}
+
+		if !strings.EqualFold(ref.Symbol, inst.Ticker) {
+			fmt.Fprintf(out, "⚠️  %s: uic %d is %s (%s) at the broker\n", inst.Ticker, inst.Uic, ref.Symbol, ref.Description)
+		}
+		switch {
+		case inst.Decimals == 0:
+			fmt.Fprintf(out, "ℹ️  %s: no decimals configured, broker quotes %d\n", inst.Ticker, ref.Decimals)
+		case inst.Decimals != ref.Decimals:
+			problems = append(problems, fmt.Sprintf("instrument %s: decimals %d, broker quotes %d", inst.Ticker, inst.Decimals, ref.Decimals))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/saxoref"
+)
+
+func TestCheckInstruments(t *testing.T) {
+	negative := -1.0
+	instruments := []instrument{
+		{Ticker: "EURUSD", Uic: 21, AssetType: "FxSpot", Decimals: 5},
+		{Ticker: "EURUSD", Uic: 21, AssetType: "FxSpot", Decimals: 5},         // Duplicate ticker and uic
+		{Ticker: "EURUSD_FWD", Uic: 21, AssetType: "FxForwards", Decimals: 5}, // Same uic, other asset type
+		{Uic: 0, Decimals: 12},
+		{Ticker: "USDJPY", Uic: 42, AssetType: "FxSpot", Decimals: 3, PipDecimals: 2, PipSize: 0.1},
+		{Ticker: "GBPUSD", Uic: 31, AssetType: "FxSpot", instrumentOverrides: instrumentOverrides{MaxSpread: &negative, Sinks: []string{"nope"}}},
+	}
+
+	problems := strings.Join(checkInstruments(instruments), "\n")
+	for _, want := range []string{
+		"instrument EURUSD: duplicate ticker (also instrument #1)",
+		"instrument EURUSD: duplicate uic 21 (FxSpot) (also instrument #1)",
+		"instrument #4: missing ticker",
+		"instrument #4: invalid uic 0",
+		"instrument #4: missing assetType",
+		"instrument #4: invalid decimals 12",
+		"instrument USDJPY: pipSize 0.1 contradicts pipDecimals 2",
+		"instrument GBPUSD: negative maxSpread -1",
+		`instrument GBPUSD: unknown sink "nope"`,
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+	if strings.Contains(problems, "EURUSD_FWD") {
+		t.Errorf("Expected the same uic under another asset type to be fine, got:\n%s", problems)
+	}
+}
+
+func TestResolveInstruments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi/ref/v1/instruments/details/21/FxSpot":
+			w.Write([]byte(`{"Uic":21,"Symbol":"EURUSD","Format":{"Decimals":4,"Format":"AllowDecimalPips"}}`))
+		case "/openapi/ref/v1/instruments/details/42/FxSpot":
+			w.Write([]byte(`{"Uic":42,"Symbol":"USDJPY","Description":"US Dollar/Japanese Yen","Format":{"Decimals":2,"Format":"AllowDecimalPips"}}`))
+		default:
+			http.Error(w, `{"ErrorCode":"NotFound"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	source := saxoref.NewInstrumentDetails(server.Client(), server.URL+"/openapi/")
+
+	instruments := []instrument{
+		{Ticker: "EURUSD", Uic: 21, AssetType: "FxSpot", Decimals: 4}, // Broker quotes 5 (decimal pips)
+		{Ticker: "YEN", Uic: 42, AssetType: "FxSpot"},                 // Local name, decimals from the broker
+		{Ticker: "GONE", Uic: 7, AssetType: "FxSpot", Decimals: 5},
+		{Ticker: "BROKEN", AssetType: "FxSpot"}, // Reported by checkInstruments, not looked up
+	}
+	var out bytes.Buffer
+	problems := resolveInstruments(context.Background(), source, instruments, &out)
+
+	if len(problems) != 2 || !strings.Contains(problems[0], "EURUSD: decimals 4, broker quotes 5") ||
+		!strings.Contains(problems[1], "instrument GONE:") {
+		t.Errorf("Expected the decimals mismatch and the unknown uic, got %q", problems)
+	}
+	if !strings.Contains(out.String(), "YEN: uic 42 is USDJPY") || !strings.Contains(out.String(), "YEN: no decimals configured, broker quotes 3") {
+		t.Errorf("Expected warnings for YEN, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,132 @@
+// Command correlation reports rolling correlation matrices of spread widening across instruments
+//
+//	go run ./cmd/correlation -from 20251101 -to 20251130 -resolution 1m -window 4h -step 1h -format csv
+//
+// Reads the regular-grid snapshot files (SNAPSHOT_DIR), resamples each instrument's widest
+// spread to -resolution and correlates the series pairwise over the -window before every -step
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/calendar"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/analysis"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Correlation error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SNAPSHOT_DIR", "data/snapshots")), "Snapshot directory")
+	from := flag.String("from", "", "First day to include (YYYYMMDD, default 7 days ago)")
+	to := flag.String("to", "", "Last day to include (YYYYMMDD, default today)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	resolution := flag.Duration("resolution", time.Minute, "Resampling interval for the spread series")
+	window := flag.Duration("window", 4*time.Hour, "Rolling window each matrix correlates (24h with -step 24h: one matrix per UTC day)")
+	step := flag.Duration("step", time.Hour, "Time between the ends of consecutive windows")
+	format := flag.String("format", "csv", "Output format: csv (one row per pair) or json (matrices)")
+	holidaysFile := flag.String("holidays", toolconfig.GetEnv("HOLIDAYS_FILE", ""), "Holiday calendar CSV; data on an instrument's holidays is excluded")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: csv, json)", *format)
+	}
+	if *resolution <= 0 {
+		return fmt.Errorf("invalid -resolution %v", *resolution)
+	}
+	if *window < analysis.MinCorrelationWindow(*resolution) || *window%*resolution != 0 {
+		return fmt.Errorf("invalid -window %v: must be a multiple of -resolution and at least %v",
+			*window, analysis.MinCorrelationWindow(*resolution))
+	}
+	if *step <= 0 || *step%*resolution != 0 {
+		return fmt.Errorf("invalid -step %v: must be a positive multiple of -resolution", *step)
+	}
+
+	today := time.Now().UTC()
+	filter := storage.ArchiveFilter{From: today.AddDate(0, 0, -6), To: today}
+	if *from != "" {
+		if filter.From, err = time.Parse("20060102", *from); err != nil {
+			return fmt.Errorf("invalid -from '%s': %w", *from, err)
+		}
+	}
+	if *to != "" {
+		if filter.To, err = time.Parse("20060102", *to); err != nil {
+			return fmt.Errorf("invalid -to '%s': %w", *to, err)
+		}
+	}
+	var holidays *domain.HolidayCalendar
+	if *holidaysFile != "" {
+		if holidays, err = calendar.LoadHolidays(*holidaysFile); err != nil {
+			return err
+		}
+	}
+
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter.Tickers = groups.Expand(*tickers)
+
+	files, err := storage.ListSpreadFiles(*dir, filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no snapshot files in %s match the filter", *dir)
+	}
+	log.Printf("Reading %d files...", len(files))
+
+	builder := analysis.NewCorrelationBuilder(*resolution, *window, *step)
+	for _, file := range files {
+		err := storage.ReadSnapshotFile(file, func(s *domain.Snapshot) error {
+			if _, closed := holidays.Holiday(s.Ticker, s.Timestamp); closed {
+				return nil
+			}
+			builder.Add(s)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	matrices := builder.Build()
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(matrices)
+	}
+	return analysis.WriteCorrelationCSV(out, matrices)
+}
@@ -0,0 +1,147 @@
+// Command costs estimates what a strategy pays in spread per instrument and day of week
+//
+//	go run ./cmd/costs -notional 100000 -hours "mon-fri 08:00-17:00 tz=Europe/London" -trades-per-hour 2 > costs.csv
+//
+// Reads the hourly CSV files of the lookback period from the spread archive and prices
+// -trades-per-hour round trips of -notional through the -hours windows at the time-weighted
+// spread recorded in them
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	_ "time/tzdata" // Embedded zoneinfo for -tz and -hours on hosts without it
+
+	"github.com/bjoelf/fx-collector/internal/adapters/calendar"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/analysis"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Costs error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "Spread archive directory")
+	days := flag.Int("days", 30, "Lookback in days, ending today")
+	notional := flag.Float64("notional", 0, "Trade size in base currency units (required)")
+	hours := flag.String("hours", "", "Trading windows, same syntax as PAUSE_SCHEDULE (required)")
+	tradesPerHour := flag.Float64("trades-per-hour", 1, "Round trips per hour inside the trading windows")
+	tz := flag.String("tz", "UTC", "Time zone for the day of week")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	format := flag.String("format", "csv", "Output format: csv or json")
+	holidaysFile := flag.String("holidays", toolconfig.GetEnv("HOLIDAYS_FILE", ""), "Holiday calendar CSV; data on an instrument's holidays is excluded")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: csv, json)", *format)
+	}
+	if *days < 1 {
+		return fmt.Errorf("invalid -days %d", *days)
+	}
+	if *notional <= 0 {
+		return fmt.Errorf("-notional must be positive")
+	}
+	if *tradesPerHour <= 0 {
+		return fmt.Errorf("invalid -trades-per-hour %v", *tradesPerHour)
+	}
+
+	location, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid -tz '%s': %w", *tz, err)
+	}
+	schedule, err := domain.ParsePauseSchedule(*hours)
+	if err != nil {
+		return fmt.Errorf("invalid -hours '%s': %w", *hours, err)
+	}
+	if len(schedule) == 0 {
+		return fmt.Errorf("-hours is required (e.g. \"mon-fri 08:00-17:00 tz=Europe/London\")")
+	}
+
+	var holidays *domain.HolidayCalendar
+	if *holidaysFile != "" {
+		if holidays, err = calendar.LoadHolidays(*holidaysFile); err != nil {
+			return err
+		}
+	}
+
+	// Day directories are named by UTC date
+	today := time.Now().UTC()
+	filter := storage.ArchiveFilter{From: today.AddDate(0, 0, -(*days - 1)), To: today}
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter.Tickers = groups.Expand(*tickers)
+	pipSizes, err := storage.ReadInstrumentPipSizes(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := storage.ListSpreadFiles(*dir, filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no spread files in %s for the last %d days", *dir, *days)
+	}
+	log.Printf("Reading %d files...", len(files))
+
+	profile := analysis.TradingProfile{Hours: schedule, TradesPerHour: *tradesPerHour, Notional: *notional}
+	estimator := analysis.NewSpreadCostEstimator(profile, location)
+	estimator.SetPipSizes(pipSizes)
+	ticks, skipped := 0, 0
+	for _, file := range files {
+		err := storage.ReadSpreadFile(file, func(p *domain.PriceData) error {
+			if _, closed := holidays.Holiday(p.Ticker, p.Timestamp); closed {
+				skipped++
+				return nil
+			}
+			estimator.Add(p)
+			ticks++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Aggregated %d ticks (%d on holidays skipped)", ticks, skipped)
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	costs := estimator.Build()
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(costs)
+	}
+	return analysis.WriteSpreadCostCSV(out, costs)
+}
@@ -0,0 +1,146 @@
+// Command export writes the spread archive as tick files for backtesting tools
+//
+//	go run ./cmd/export -from 20251101 -to 20251130 -ticker EURUSD -format mt5 -o export
+//
+// Writes one file per instrument (<TICKER>_<format>.csv) in the -format layout:
+// mt5 for MetaTrader 5 custom symbol tick import, dukascopy for tools reading Dukascopy tick CSVs
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Export error: %v", err)
+	}
+}
+
+// exportFile is the open output of one instrument
+type exportFile struct {
+	file     *os.File
+	buffer   *bufio.Writer
+	exporter *storage.TickExporter
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "Spread archive directory")
+	from := flag.String("from", "", "First day to include (YYYYMMDD, default 7 days ago)")
+	to := flag.String("to", "", "Last day to include (YYYYMMDD, default today)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	format := flag.String("format", storage.TickExportMT5, "Tick file layout: mt5 or dukascopy")
+	outliers := flag.Bool("outliers", false, "Include ticks flagged as outliers")
+	backfill := flag.Bool("backfill", false, "Include backfilled bar closes")
+	output := flag.String("o", "export", "Output directory")
+	flag.Parse()
+
+	if *format != storage.TickExportMT5 && *format != storage.TickExportDukascopy {
+		return fmt.Errorf("unknown format %q (supported: %s, %s)", *format, storage.TickExportMT5, storage.TickExportDukascopy)
+	}
+
+	today := time.Now().UTC()
+	filter := storage.ArchiveFilter{From: today.AddDate(0, 0, -6), To: today}
+	if *from != "" {
+		if filter.From, err = time.Parse("20060102", *from); err != nil {
+			return fmt.Errorf("invalid -from '%s': %w", *from, err)
+		}
+	}
+	if *to != "" {
+		if filter.To, err = time.Parse("20060102", *to); err != nil {
+			return fmt.Errorf("invalid -to '%s': %w", *to, err)
+		}
+	}
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter.Tickers = groups.Expand(*tickers)
+
+	files, err := storage.ListSpreadFiles(*dir, filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no spread files in %s match the filter", *dir)
+	}
+	if err := os.MkdirAll(*output, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", *output, err)
+	}
+	log.Printf("Reading %d files...", len(files))
+
+	outputs := make(map[string]*exportFile)
+	defer func() {
+		for _, out := range outputs {
+			out.file.Close()
+		}
+	}()
+
+	ticks, skipped := 0, 0
+	for _, file := range files {
+		err := storage.ReadSpreadFile(file, func(p *domain.PriceData) error {
+			if (!*outliers && p.Flags.Has(domain.FlagOutlier)) || (!*backfill && p.Flags.Has(domain.FlagBackfill)) {
+				skipped++
+				return nil
+			}
+
+			out, ok := outputs[p.Ticker]
+			if !ok {
+				var err error
+				if out, err = createExportFile(filepath.Join(*output, p.Ticker+"_"+*format+".csv"), *format); err != nil {
+					return err
+				}
+				outputs[p.Ticker] = out
+			}
+			ticks++
+			return out.exporter.Write(p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for ticker, out := range outputs {
+		if err := out.exporter.Flush(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", ticker, err)
+		}
+		if err := out.buffer.Flush(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", ticker, err)
+		}
+	}
+	log.Printf("Exported %d ticks of %d instruments to %s (%d flagged ticks skipped)", ticks, len(outputs), *output, skipped)
+	return nil
+}
+
+// createExportFile creates path and writes the header of format
+func createExportFile(path, format string) (*exportFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	buffer := bufio.NewWriter(file)
+	exporter, err := storage.NewTickExporter(buffer, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &exportFile{file: file, buffer: buffer, exporter: exporter}, nil
+}
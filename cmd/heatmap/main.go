@@ -0,0 +1,123 @@
+// Command heatmap reports median and p95 spread per instrument by hour of day and day of week
+//
+//	go run ./cmd/heatmap -days 30 -tz America/New_York -format csv > heatmap.csv
+//
+// Reads the hourly CSV files of the lookback period from the spread archive
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	_ "time/tzdata" // Embedded zoneinfo for -tz on hosts without it
+
+	"github.com/bjoelf/fx-collector/internal/adapters/calendar"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/analysis"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Heatmap error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "Spread archive directory")
+	days := flag.Int("days", 30, "Lookback in days, ending today")
+	tz := flag.String("tz", "UTC", "Time zone for hour of day and weekday (e.g. America/New_York)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	format := flag.String("format", "csv", "Output format: csv or json")
+	holidaysFile := flag.String("holidays", toolconfig.GetEnv("HOLIDAYS_FILE", ""), "Holiday calendar CSV; data on an instrument's holidays is excluded")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: csv, json)", *format)
+	}
+	if *days < 1 {
+		return fmt.Errorf("invalid -days %d", *days)
+	}
+
+	location, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid -tz '%s': %w", *tz, err)
+	}
+
+	var holidays *domain.HolidayCalendar
+	if *holidaysFile != "" {
+		if holidays, err = calendar.LoadHolidays(*holidaysFile); err != nil {
+			return err
+		}
+	}
+
+	// Day directories are named by UTC date
+	today := time.Now().UTC()
+	filter := storage.ArchiveFilter{From: today.AddDate(0, 0, -(*days - 1)), To: today}
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter.Tickers = groups.Expand(*tickers)
+
+	files, err := storage.ListSpreadFiles(*dir, filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no spread files in %s for the last %d days", *dir, *days)
+	}
+	log.Printf("Reading %d files...", len(files))
+
+	builder := analysis.NewHeatmapBuilder(location)
+	ticks, skipped := 0, 0
+	for _, file := range files {
+		err := storage.ReadSpreadFile(file, func(p *domain.PriceData) error {
+			if _, closed := holidays.Holiday(p.Ticker, p.Timestamp); closed {
+				skipped++
+				return nil
+			}
+			builder.Add(p)
+			ticks++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Aggregated %d ticks (%d on holidays skipped)", ticks, skipped)
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	heatmaps := builder.Build()
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(heatmaps)
+	}
+	return analysis.WriteHeatmapCSV(out, heatmaps)
+}
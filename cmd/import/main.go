@@ -0,0 +1,206 @@
+// Command import loads third-party tick CSVs into the collector's storage through the recorder sinks
+//
+//	go run ./cmd/import -source dukascopy -columns "timestamp=Gmt time,bid=Bid,ask=Ask" \
+//	  -time-format "02.01.2006 15:04:05.000" -ticker EURUSD EURUSD_Ticks_2025.11.csv
+//
+// Every row is flagged as imported (64) and carries -source in the source column, so bought
+// history lives in the same hourly files as live captures and can still be told apart
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "time/tzdata" // Embedded zoneinfo for -tz on hosts without it
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+// batchSize is the number of rows handed to the sinks at once
+const batchSize = 1000
+
+// tickerNormalizer turns third-party symbols like EUR/USD or eur_usd into collector tickers
+var tickerNormalizer = strings.NewReplacer("/", "", "_", "", "-", "", ".", "")
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Import error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	source := flag.String("source", "", "Name of the data vendor, written to the source column (required)")
+	columns := flag.String("columns", "timestamp=timestamp,bid=bid,ask=ask,ticker=ticker", "Column mapping: field=header name (or 1-based number with -header=false) for timestamp, bid, ask and optionally ticker")
+	header := flag.Bool("header", true, "The first row of each file names the columns")
+	delimiter := flag.String("delimiter", ",", "Field separator (one character, or \"tab\")")
+	timeFormat := flag.String("time-format", "rfc3339", "Timestamp format: rfc3339, unix, unix_ms, unix_us or a Go layout")
+	tz := flag.String("tz", "UTC", "Time zone of timestamps without one")
+	ticker := flag.String("ticker", "", "Ticker of all rows, for files without a ticker column")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file")
+	recorders := flag.String("recorders", toolconfig.GetEnv("SPREAD_RECORDERS", "csv"), "Sinks to write to, as SPREAD_RECORDERS")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: import [flags] FILE...\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *source == "" {
+		return fmt.Errorf("-source is required")
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return fmt.Errorf("no files given")
+	}
+
+	mapping, err := storage.ParseColumnMapping(*columns)
+	if err != nil {
+		return fmt.Errorf("invalid -columns: %w", err)
+	}
+	if _, ok := mapping["ticker"]; ok && *ticker != "" {
+		delete(mapping, "ticker") // -ticker wins over the default mapping
+	}
+	if _, ok := mapping["ticker"]; !ok && *ticker == "" {
+		return fmt.Errorf("-ticker is required when -columns has no ticker column")
+	}
+	comma, err := parseDelimiter(*delimiter)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid -tz '%s': %w", *tz, err)
+	}
+	format := storage.TickCSVFormat{Columns: mapping, Header: *header, Comma: comma, TimeLayout: *timeFormat, Location: location}
+
+	list, err := toolconfig.ReadInstruments(*instrumentsPath, "")
+	if err != nil {
+		return err
+	}
+	instruments := make(map[string]toolconfig.Instrument, len(list))
+	for _, inst := range list {
+		instruments[strings.ToUpper(inst.Ticker)] = inst
+	}
+	recorder, err := toolconfig.CreateRecorders(*recorders, tenant.SinkDefaults(tenantName))
+	if err != nil {
+		return err
+	}
+
+	im := &importer{
+		instruments: instruments,
+		recorder:    recorder,
+		source:      *source,
+		ticker:      *ticker,
+		counts:      make(map[string]int),
+	}
+	for _, path := range flag.Args() {
+		if err = im.importFile(context.Background(), path, format); err != nil {
+			break
+		}
+	}
+
+	// Close even after a failure: the Arrow sink only becomes readable once closed
+	if closeErr := recorder.Close(context.Background()); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close recorders: %w", closeErr)
+	}
+	for t, count := range im.counts {
+		log.Printf("%s: %d ticks imported from %s", t, count, *source)
+	}
+	return err
+}
+
+// importer converts rows of all files and writes them in batches
+type importer struct {
+	instruments map[string]toolconfig.Instrument
+	recorder    *storage.MultiRecorder
+	source      string
+	ticker      string         // Fixed ticker, or "" for a ticker column
+	counts      map[string]int // Imported rows per ticker
+}
+
+// importFile writes the rows of one file
+func (im *importer) importFile(ctx context.Context, path string, format storage.TickCSVFormat) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	batch := make([]*domain.PriceData, 0, batchSize)
+	err = storage.ReadTickCSV(file, format, func(p *domain.PriceData) error {
+		if err := im.prepare(p); err != nil {
+			return err
+		}
+		batch = append(batch, p)
+		if len(batch) < batchSize {
+			return nil
+		}
+		err := im.recorder.RecordBatch(ctx, batch)
+		batch = make([]*domain.PriceData, 0, batchSize) // Sinks may keep the slice
+		return err
+	})
+	if err == nil && len(batch) > 0 {
+		err = im.recorder.RecordBatch(ctx, batch)
+	}
+	if err == nil {
+		err = im.recorder.Flush(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", path, err)
+	}
+	return nil
+}
+
+// prepare fills in the instrument details and the import tags of one row
+func (im *importer) prepare(p *domain.PriceData) error {
+	if im.ticker != "" {
+		p.Ticker = im.ticker
+	}
+	p.Ticker = strings.ToUpper(tickerNormalizer.Replace(p.Ticker))
+	inst, ok := im.instruments[p.Ticker]
+	if !ok {
+		return fmt.Errorf("unknown ticker %q (not in instruments file)", p.Ticker)
+	}
+
+	p.Uic = inst.Uic
+	p.AssetType = inst.AssetType
+	if inst.Decimals > 0 {
+		p.Decimals = inst.Decimals
+		p.CalculateSpread()
+	}
+	p.Flags = domain.FlagImported
+	if p.SpreadOutlier(inst.MaxSpread) != "" {
+		p.Flags |= domain.FlagOutlier
+	}
+	p.SpreadUnit = domain.SpreadUnitPrice
+	p.Source = im.source
+	im.counts[p.Ticker]++
+	return nil
+}
+
+// parseDelimiter returns the single-character field separator
+func parseDelimiter(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) {
+		return 0, fmt.Errorf("invalid -delimiter %q (one character or \"tab\")", s)
+	}
+	return r, nil
+}
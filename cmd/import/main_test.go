@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestImporter_Prepare(t *testing.T) {
+	im := &importer{
+		instruments: map[string]toolconfig.Instrument{
+			"EURUSD": {Ticker: "EURUSD", Uic: 21, AssetType: "FxSpot", Decimals: 5, MaxSpread: 0.001},
+		},
+		source: "dukascopy",
+		counts: make(map[string]int),
+	}
+
+	p := &domain.PriceData{Ticker: "eur/usd", Bid: 1.1, Ask: 1.102}
+	if err := im.prepare(p); err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	if p.Ticker != "EURUSD" || p.Uic != 21 || p.Decimals != 5 || p.Source != "dukascopy" {
+		t.Errorf("Expected the EURUSD details and source, got %+v", p)
+	}
+	if !p.Flags.Has(domain.FlagImported) || !p.Flags.Has(domain.FlagOutlier) {
+		t.Errorf("Expected an imported outlier, got flags %v", p.Flags)
+	}
+	if im.counts["EURUSD"] != 1 {
+		t.Errorf("Expected 1 row counted, got %d", im.counts["EURUSD"])
+	}
+
+	if err := im.prepare(&domain.PriceData{Ticker: "USDJPY"}); err == nil {
+		t.Error("Expected an error for a ticker not in the instruments file")
+	}
+
+	im.ticker = "EUR_USD" // -ticker overrides the column
+	p = &domain.PriceData{Ticker: "ignored", Bid: 1.1, Ask: 1.1001}
+	if err := im.prepare(p); err != nil || p.Ticker != "EURUSD" {
+		t.Errorf("Expected the fixed ticker, got %s (%v)", p.Ticker, err)
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for input, want := range map[string]rune{",": ',', ";": ';', "tab": '\t', `\t`: '\t', "|": '|'} {
+		if got, err := parseDelimiter(input); err != nil || got != want {
+			t.Errorf("%q: expected %q, got %q (%v)", input, want, got, err)
+		}
+	}
+	for _, input := range []string{"", ",,", "tabs"} {
+		if _, err := parseDelimiter(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
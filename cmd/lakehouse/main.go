@@ -0,0 +1,254 @@
+// Command lakehouse publishes the snapshot bars as a Delta Lake table
+//
+//	go run ./cmd/lakehouse -table /mnt/lake/fx/snapshots
+//
+// Each day of SNAPSHOT_DIR becomes one Parquet file (written by the DuckDB CLI) in the table's
+// date=YYYY-MM-DD partition. A day is rewritten only when its CSV files changed since it was
+// published, replacing the previous file in the same commit, so an hourly run keeps the table
+// current and Spark, Trino, Databricks or DuckDB query it without an ETL step
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/delta"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/joho/godotenv"
+)
+
+// sourceTag is the add-action tag holding the fingerprint of the CSV files a data file was built from
+const sourceTag = "fxc_source"
+
+// partitionColumn partitions the table by day
+const partitionColumn = "date"
+
+// barSchema is the table schema; the partition column is only in the file paths, not the Parquet files
+var barSchema = []delta.Field{
+	{Name: "timestamp", Type: "timestamp"},
+	{Name: "uic", Type: "integer", Nullable: true},
+	{Name: "ticker", Type: "string"},
+	{Name: "asset_type", Type: "string", Nullable: true},
+	{Name: "bid", Type: "double", Nullable: true},
+	{Name: "ask", Type: "double", Nullable: true},
+	{Name: "min_spread", Type: "double", Nullable: true},
+	{Name: "max_spread", Type: "double", Nullable: true},
+	{Name: "ticks", Type: "integer", Nullable: true},
+	{Name: partitionColumn, Type: "date"},
+}
+
+// barSelect converts the CSV columns (read as text) to the table types
+const barSelect = `CAST("timestamp" AS TIMESTAMPTZ) AS "timestamp", CAST(uic AS INTEGER) AS uic, ticker, asset_type,
+CAST(bid AS DOUBLE) AS bid, CAST(ask AS DOUBLE) AS ask, CAST(min_spread AS DOUBLE) AS min_spread,
+CAST(max_spread AS DOUBLE) AS max_spread, CAST(ticks AS INTEGER) AS ticks`
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Lakehouse error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SNAPSHOT_DIR", "data/snapshots")), "Snapshot directory")
+	tableDir := flag.String("table", tenant.Path(tenantName, toolconfig.GetEnv("DELTA_TABLE_DIR", "data/delta/snapshots")), "Delta table directory")
+	from := flag.String("from", "", "First day to publish (YYYYMMDD, default all)")
+	to := flag.String("to", "", "Last day to publish (YYYYMMDD)")
+	retain := flag.Duration("retain", 7*24*time.Hour, "Keep replaced Parquet files this long for time travel (0 deletes them at once)")
+	dryRun := flag.Bool("dry-run", false, "Only list the days that would be written")
+	duckdb := flag.String("duckdb", toolconfig.GetEnv("DUCKDB_PATH", "duckdb"), "Path to the DuckDB CLI")
+	flag.Parse()
+
+	var filter storage.ArchiveFilter
+	if *from != "" {
+		if filter.From, err = time.Parse("20060102", *from); err != nil {
+			return fmt.Errorf("invalid -from '%s': %w", *from, err)
+		}
+	}
+	if *to != "" {
+		if filter.To, err = time.Parse("20060102", *to); err != nil {
+			return fmt.Errorf("invalid -to '%s': %w", *to, err)
+		}
+	}
+
+	cliPath, err := exec.LookPath(*duckdb)
+	if err != nil {
+		return fmt.Errorf("DuckDB CLI not found (install it or set DUCKDB_PATH): %w", err)
+	}
+
+	files, err := storage.ListSpreadFiles(*dir, filter)
+	if err != nil {
+		return err
+	}
+	var days []string
+	byDay := make(map[string][]string)
+	for _, file := range files {
+		day := filepath.Base(filepath.Dir(file))
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], file)
+	}
+
+	table := delta.Open(*tableDir)
+	snapshot, err := table.Load()
+	if err != nil {
+		return err
+	}
+
+	written, unchanged := 0, 0
+	for _, day := range days {
+		date := day[:4] + "-" + day[4:6] + "-" + day[6:]
+		fingerprint, rows, err := describeDay(byDay[day])
+		if err != nil {
+			return err
+		}
+
+		var previous []string
+		current := true
+		for path, file := range snapshot.Files {
+			if file.PartitionValues[partitionColumn] == date {
+				previous = append(previous, path)
+				current = current && file.Tags[sourceTag] == fingerprint
+			}
+		}
+		if len(previous) > 0 && current {
+			unchanged++
+			continue
+		}
+		if *dryRun {
+			log.Printf("Would write %s (%d bars from %d files, replacing %d)", date, rows, len(byDay[day]), len(previous))
+			continue
+		}
+
+		file, err := writeDay(cliPath, table, date, byDay[day])
+		if err != nil {
+			return err
+		}
+		file.Stats = fmt.Sprintf(`{"numRecords":%d}`, rows)
+		file.Tags = map[string]string{sourceTag: fingerprint}
+		if err := table.Commit(snapshot, barSchema, []string{partitionColumn}, []delta.File{file}, previous, "WRITE"); err != nil {
+			os.Remove(filepath.Join(table.Dir(), filepath.FromSlash(file.Path)))
+			return err
+		}
+		if snapshot, err = table.Load(); err != nil {
+			return err
+		}
+		log.Printf("%s: %d bars -> version %d", date, rows, snapshot.Version)
+		written++
+	}
+
+	if !*dryRun {
+		deleted, err := table.Vacuum(snapshot, *retain)
+		if err != nil {
+			return err
+		}
+		if len(deleted) > 0 {
+			log.Printf("Vacuumed %d replaced files", len(deleted))
+		}
+	}
+	log.Printf("Lakehouse complete: %d days written, %d unchanged (table %s, version %d)", written, unchanged, *tableDir, snapshot.Version)
+	return nil
+}
+
+// describeDay returns a fingerprint of a day's CSV files (names, sizes, modification times) and their row count
+func describeDay(files []string) (string, int, error) {
+	hash := sha256.New()
+	rows := 0
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		fmt.Fprintf(hash, "%s %d %d\n", filepath.Base(file), info.Size(), info.ModTime().UnixNano())
+
+		lines, err := countLines(file)
+		if err != nil {
+			return "", 0, err
+		}
+		rows += max(lines-1, 0) // Header line
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], rows, nil
+}
+
+// countLines counts the lines of a file
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	lines := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
+
+// writeDay converts a day's CSV files to a Parquet file in the table's partition for date
+func writeDay(cliPath string, table *delta.Table, date string, files []string) (delta.File, error) {
+	partition := map[string]string{partitionColumn: date}
+	path := delta.NewFilePath(partition, []string{partitionColumn})
+	full := filepath.Join(table.Dir(), filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return delta.File{}, fmt.Errorf("failed to create %s: %w", filepath.Dir(full), err)
+	}
+
+	quoted := make([]string, len(files))
+	for i, file := range files {
+		quoted[i] = sqlString(file)
+	}
+	query := fmt.Sprintf(`SET TimeZone = 'UTC';
+COPY (SELECT %s FROM read_csv([%s], header = true, union_by_name = true, all_varchar = true) ORDER BY ticker, "timestamp")
+TO %s (FORMAT parquet, COMPRESSION snappy);`, barSelect, strings.Join(quoted, ", "), sqlString(full))
+
+	cmd := exec.Command(cliPath, "-c", query)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(full)
+		return delta.File{}, fmt.Errorf("duckdb failed for %s: %w: %s", date, err, strings.TrimSpace(stderr.String()))
+	}
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return delta.File{}, fmt.Errorf("duckdb wrote no file for %s: %w", date, err)
+	}
+	return delta.File{
+		Path:             path,
+		PartitionValues:  partition,
+		Size:             info.Size(),
+		ModificationTime: info.ModTime().UnixMilli(),
+		DataChange:       true,
+	}, nil
+}
+
+// sqlString quotes a string literal for DuckDB
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,205 @@
+// Command monitor shows a live table of the instruments a running collector receives
+//
+//	go run ./cmd/monitor -addr localhost:9091
+//
+// Polls the collector's GET /admin/status (ADMIN_ADDR must be set on the collector) and redraws the
+// terminal with the last bid/ask, spread, tick rate and quote age of every instrument, plus the
+// broker connection state. Instruments without a tick for -stale are highlighted
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+// Terminal escape sequences
+const (
+	altScreenOn  = "\033[?1049h\033[?25l" // Alternate screen, cursor hidden: the shell is restored on exit
+	altScreenOff = "\033[?25h\033[?1049l"
+	clearScreen  = "\033[H\033[2J"
+	colorRed     = "\033[31m"
+	colorYellow  = "\033[33m"
+	colorDim     = "\033[2m"
+	colorReset   = "\033[0m"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Monitor error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+
+	addr := flag.String("addr", toolconfig.GetEnv("ADMIN_ADDR", "localhost:9091"), "Collector admin API address")
+	token := flag.String("token", toolconfig.GetEnv("ADMIN_TOKEN", ""), "Admin API token")
+	interval := flag.Duration("interval", time.Second, "Refresh interval")
+	stale := flag.Duration("stale", 10*time.Second, "Quote age from which an instrument is highlighted")
+	flag.Parse()
+
+	if *interval <= 0 || *stale <= 0 {
+		return fmt.Errorf("-interval and -stale must be positive")
+	}
+	url := "http://" + *addr + "/admin/status"
+	if strings.HasPrefix(*addr, ":") {
+		url = "http://localhost" + *addr + "/admin/status"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := &http.Client{Timeout: *interval + 2*time.Second}
+	fmt.Print(altScreenOn)
+	defer fmt.Print(altScreenOff)
+
+	var previous *domain.CollectorStatus
+	refresh := time.NewTicker(*interval)
+	defer refresh.Stop()
+	for {
+		status, err := fetchStatus(ctx, client, url, *token)
+		var screen string
+		if err != nil {
+			screen = fmt.Sprintf("%sCollector unreachable at %s: %v%s\n", colorRed, *addr, err, colorReset)
+		} else {
+			screen = render(status, previous, *stale)
+			previous = status
+		}
+		fmt.Print(clearScreen + screen)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-refresh.C:
+		}
+	}
+}
+
+// fetchStatus reads the collector's live state from the admin API
+func fetchStatus(ctx context.Context, client *http.Client, url, token string) (*domain.CollectorStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var status domain.CollectorStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid status response: %w", err)
+	}
+	return &status, nil
+}
+
+// render draws the status as a header line and a table; tick rates come from the previous status
+func render(status, previous *domain.CollectorStatus, stale time.Duration) string {
+	var sb strings.Builder
+	connection := colorRed + "DISCONNECTED" + colorReset
+	if status.Connected {
+		connection = "connected"
+	}
+	lastTick := "never"
+	if status.LastTickAt != nil {
+		lastTick = age(status.Timestamp.Sub(*status.LastTickAt)) + " ago"
+	}
+	fmt.Fprintf(&sb, "FX Collector  broker %s  last tick %s  %s UTC  (Ctrl+C to quit)\n\n",
+		connection, lastTick, status.Timestamp.Format("15:04:05"))
+
+	previousTicks := make(map[string]uint64)
+	var elapsed float64
+	if previous != nil {
+		elapsed = status.Timestamp.Sub(previous.Timestamp).Seconds()
+		for _, instrument := range previous.Instruments {
+			previousTicks[instrument.Ticker] = instrument.Ticks
+		}
+	}
+
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TICKER\tBID\tASK\tSPREAD\tPIPS\tTICKS/S\tAGE\t")
+	colors := []string{""}
+	for _, instrument := range status.Instruments {
+		rate := "-"
+		if before, ok := previousTicks[instrument.Ticker]; ok && elapsed > 0 && instrument.Ticks >= before {
+			rate = strconv.FormatFloat(float64(instrument.Ticks-before)/elapsed, 'f', 1, 64)
+		}
+		if instrument.QuoteTime == nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t%s\t-\t\n", instrument.Ticker, rate)
+			colors = append(colors, colorYellow)
+			continue
+		}
+
+		quoteAge := status.Timestamp.Sub(*instrument.QuoteTime)
+		pips := "-"
+		if instrument.SpreadPips > 0 {
+			pips = strconv.FormatFloat(instrument.SpreadPips, 'f', 1, 64)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", instrument.Ticker,
+			price(instrument.Bid, instrument.Decimals), price(instrument.Ask, instrument.Decimals),
+			price(instrument.Spread, instrument.Decimals+1), pips, rate, age(quoteAge))
+
+		switch {
+		case instrument.Paused:
+			colors = append(colors, colorDim)
+		case quoteAge >= stale:
+			colors = append(colors, colorYellow)
+		default:
+			colors = append(colors, "")
+		}
+	}
+	tw.Flush()
+
+	// Colored after alignment, escape sequences would count as text for tabwriter
+	for i, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		if i < len(colors) && colors[i] != "" {
+			line = colors[i] + line + colorReset
+		}
+		sb.WriteString(line + "\n")
+	}
+	fmt.Fprintf(&sb, "\n%sdim: paused  %syellow: no tick for %s%s\n", colorDim, colorReset+colorYellow, stale, colorReset)
+	return sb.String()
+}
+
+// price formats a price with the instrument's decimals
+func price(v float64, decimals int) string {
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// age formats a duration compactly (850ms, 12.3s, 4m5s)
+func age(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestFetchStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(domain.CollectorStatus{Connected: true, Instruments: []domain.InstrumentStatus{{Ticker: "EURUSD"}}})
+	}))
+	defer server.Close()
+
+	status, err := fetchStatus(context.Background(), server.Client(), server.URL, "secret")
+	if err != nil {
+		t.Fatalf("fetchStatus failed: %v", err)
+	}
+	if !status.Connected || len(status.Instruments) != 1 {
+		t.Errorf("Expected a connected status with one instrument, got %+v", status)
+	}
+
+	if _, err := fetchStatus(context.Background(), server.Client(), server.URL, "wrong"); err == nil || !strings.Contains(err.Error(), "status 401: unauthorized") {
+		t.Errorf("Expected the status code and body in the error, got %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	fresh, old := now.Add(-500*time.Millisecond), now.Add(-2*time.Minute)
+	previous := &domain.CollectorStatus{Timestamp: now.Add(-2 * time.Second), Instruments: []domain.InstrumentStatus{{Ticker: "EURUSD", Ticks: 10}}}
+	status := &domain.CollectorStatus{
+		Timestamp:  now,
+		Connected:  true,
+		LastTickAt: &fresh,
+		Instruments: []domain.InstrumentStatus{
+			{Ticker: "EURUSD", Bid: 1.1, Ask: 1.10012, Spread: 0.00012, SpreadPips: 1.2, Decimals: 5, QuoteTime: &fresh, Ticks: 15},
+			{Ticker: "USDJPY", Bid: 150.1, Ask: 150.12, Spread: 0.02, Decimals: 3, QuoteTime: &old},
+			{Ticker: "GBPUSD"},
+		},
+	}
+
+	lines := strings.Split(render(status, previous, time.Minute), "\n")
+	if !strings.Contains(lines[0], "broker connected") || !strings.Contains(lines[0], "last tick 500ms ago") {
+		t.Errorf("Expected the connection and last tick in the header, got %q", lines[0])
+	}
+	for _, want := range []struct {
+		line  int
+		parts []string
+	}{
+		{3, []string{"EURUSD", "1.10000", "1.10012", "0.000120", "1.2", "2.5", "500ms"}}, // 5 ticks in 2s
+		{4, []string{colorYellow, "USDJPY", "150.120", "2m0s"}},                          // Stale
+		{5, []string{colorYellow, "GBPUSD"}},                                             // No quote yet
+	} {
+		for _, part := range want.parts {
+			if !strings.Contains(lines[want.line], part) {
+				t.Errorf("Expected %q in line %d, got %q", part, want.line, lines[want.line])
+			}
+		}
+	}
+	if strings.Contains(lines[3], colorYellow) {
+		t.Errorf("Expected EURUSD not to be highlighted, got %q", lines[3])
+	}
+}
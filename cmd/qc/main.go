@@ -0,0 +1,108 @@
+// Command qc reports the data quality of the spread archive per instrument and UTC day
+//
+//	go run ./cmd/qc -from 20251101 -to 20251130 -format csv
+//
+// Counts each instrument's ticks per day that are clean and that carry each quality flag
+// (outlier, crossed, clamped, snapshot, stale, indicative)
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/analysis"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("QC error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "Spread archive directory")
+	from := flag.String("from", "", "First day to include (YYYYMMDD, default yesterday)")
+	to := flag.String("to", "", "Last day to include (YYYYMMDD, default today)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	format := flag.String("format", "csv", "Output format: csv or json")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: csv, json)", *format)
+	}
+
+	today := time.Now().UTC()
+	filter := storage.ArchiveFilter{From: today.AddDate(0, 0, -1), To: today}
+	if *from != "" {
+		if filter.From, err = time.Parse("20060102", *from); err != nil {
+			return fmt.Errorf("invalid -from '%s': %w", *from, err)
+		}
+	}
+	if *to != "" {
+		if filter.To, err = time.Parse("20060102", *to); err != nil {
+			return fmt.Errorf("invalid -to '%s': %w", *to, err)
+		}
+	}
+
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter.Tickers = groups.Expand(*tickers)
+
+	files, err := storage.ListSpreadFiles(*dir, filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no spread files in %s match the filter", *dir)
+	}
+	log.Printf("Reading %d files...", len(files))
+
+	builder := analysis.NewQualityBuilder()
+	for _, file := range files {
+		err := storage.ReadSpreadFile(file, func(p *domain.PriceData) error {
+			builder.Add(p)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	summaries := builder.Build()
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	}
+	return analysis.WriteQualityCSV(out, summaries)
+}
@@ -0,0 +1,288 @@
+// Command query runs SQL over the spread archive using the DuckDB CLI
+//
+//	go run ./cmd/query -from 20251118 -ticker EURUSD,USDJPY -format json \
+//	  "SELECT ticker, avg(spread) AS avg_spread FROM spreads GROUP BY ticker"
+//	go run ./cmd/query -from 20251101 -format parquet -o november.parquet "SELECT * FROM spreads"
+//	go run ./cmd/query -source arrow -from 20251118 "SELECT count(*) FROM spreads"
+//
+// The archive is exposed as the view `spreads`, read by DuckDB through a glob. Day and ticker
+// filters become predicates of the view: on the file paths of the CSV archive (DuckDB skips the
+// files they rule out), on the timestamp and ticker columns of Arrow and Parquet files. The SQL
+// goes to DuckDB on stdin, so its length isn't bound by the command line limit
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Query error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	source := flag.String("source", "csv", "Archive to query: csv, arrow or parquet")
+	dir := flag.String("dir", "", "Archive directory (default SPREAD_RECORDING_DIR for csv, ARROW_DIR for arrow; required for parquet)")
+	from := flag.String("from", "", "First day to include (YYYYMMDD)")
+	to := flag.String("to", "", "Last day to include (YYYYMMDD)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers to include (default all)")
+	format := flag.String("format", "csv", "Output format: csv, json or parquet (needs -o)")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	duckdb := flag.String("duckdb", toolconfig.GetEnv("DUCKDB_PATH", "duckdb"), "Path to the DuckDB CLI")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: query [flags] SQL (or SQL on stdin)\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	query := strings.Join(flag.Args(), " ")
+	if query == "" {
+		stdin, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read query from stdin: %w", err)
+		}
+		query = string(stdin)
+	}
+	if strings.TrimSpace(query) == "" {
+		flag.Usage()
+		return fmt.Errorf("no query given")
+	}
+
+	switch *format {
+	case "csv", "json":
+	case "parquet":
+		if *output == "-" {
+			return fmt.Errorf("-format parquet needs an output file (-o)")
+		}
+	default:
+		return fmt.Errorf("unknown format %q (supported: csv, json, parquet)", *format)
+	}
+
+	if *dir == "" {
+		switch *source {
+		case "csv":
+			*dir = tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads"))
+		case "arrow":
+			*dir = tenant.Path(tenantName, toolconfig.GetEnv("ARROW_DIR", "data/arrow"))
+		case "parquet":
+			return fmt.Errorf("-source parquet needs the directory of the Parquet files (-dir)")
+		}
+	}
+
+	filter, err := parseFilter(*from, *to, *tickers)
+	if err != nil {
+		return err
+	}
+
+	found, err := archiveHasFiles(*source, *dir, filter)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no %s files in %s match the filter", *source, *dir)
+	}
+	view, err := spreadsView(*source, *dir, filter)
+	if err != nil {
+		return err
+	}
+
+	cliPath, err := exec.LookPath(*duckdb)
+	if err != nil {
+		return fmt.Errorf("DuckDB CLI not found (install it or set DUCKDB_PATH): %w", err)
+	}
+
+	var cmd *exec.Cmd
+	if *format == "parquet" {
+		// DuckDB writes the file itself; -bail stops at the first failing statement, as -c does
+		cmd = exec.Command(cliPath, "-bail")
+		cmd.Stdin = strings.NewReader(view + "\n" + parquetCopy(query, *output))
+	} else {
+		cmd = exec.Command(cliPath, "-bail", "-"+*format)
+		cmd.Stdin = strings.NewReader(view + "\n" + query)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if *format != "parquet" && *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		cmd.Stdout = file
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("duckdb failed: %w", err)
+	}
+	return nil
+}
+
+// parseFilter converts the command line filters into an archive filter
+func parseFilter(from, to, tickers string) (storage.ArchiveFilter, error) {
+	var filter storage.ArchiveFilter
+	var err error
+
+	if from != "" {
+		if filter.From, err = time.Parse("20060102", from); err != nil {
+			return filter, fmt.Errorf("invalid -from '%s': %w", from, err)
+		}
+	}
+	if to != "" {
+		if filter.To, err = time.Parse("20060102", to); err != nil {
+			return filter, fmt.Errorf("invalid -to '%s': %w", to, err)
+		}
+	}
+	for _, ticker := range strings.Split(tickers, ",") {
+		if ticker = strings.TrimSpace(ticker); ticker != "" {
+			filter.Tickers = append(filter.Tickers, ticker)
+		}
+	}
+	return filter, nil
+}
+
+// archiveHasFiles reports whether the archive holds a file the filter doesn't rule out by its path
+func archiveHasFiles(source, dir string, filter storage.ArchiveFilter) (bool, error) {
+	switch source {
+	case "csv":
+		files, err := storage.ListSpreadFiles(dir, filter)
+		return len(files) > 0, err
+	case "arrow":
+		files, err := storage.ListArrowFiles(dir, filter)
+		return len(files) > 0, err
+	case "parquet":
+		found := false
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".parquet") {
+				found = true
+				return fs.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		return found, nil
+	}
+	return false, fmt.Errorf("unknown source %q (supported: csv, arrow, parquet)", source)
+}
+
+// spreadsView returns the SQL creating the `spreads` view over the archive in dir, restricted to filter
+// union_by_name tolerates files written before a column (e.g. seq) was added
+func spreadsView(source, dir string, filter storage.ArchiveFilter) (string, error) {
+	dayGlob := filepath.ToSlash(dir) + "/[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]/"
+	setup := "SET TimeZone = 'UTC';\n"
+	var from, where string
+	switch source {
+	case "csv":
+		// The day and ticker are taken from the path (YYYYMMDD/TICKER_HH[-N].csv[.partial]), so DuckDB
+		// skips the files they rule out without reading them
+		from = fmt.Sprintf("read_csv(%s, header = true, union_by_name = true, filename = true)", sqlString(dayGlob+"*.csv*"))
+		where = joinPredicates(
+			`regexp_matches(filename, '_[0-9]{2}(-[0-9]+)?\.csv(\.partial)?$')`,
+			dayPredicates(filter, `regexp_extract(filename, '/([0-9]{8})/[^/]*$', 1)`),
+			tickerPredicate(filter, `regexp_extract(filename, '/([^/]+)_[0-9]{2}(-[0-9]+)?\.csv(\.partial)?$', 1)`))
+	case "arrow":
+		// Arrow IPC files are read by the nanoarrow community extension
+		setup += "INSTALL nanoarrow FROM community;\nLOAD nanoarrow;\n"
+		from = fmt.Sprintf("read_arrow(%s)", sqlString(dayGlob+"spreads_*.arrow"))
+		where = joinPredicates(timestampPredicates(filter), tickerPredicate(filter, "ticker"))
+	case "parquet":
+		// Row group statistics let DuckDB skip what the timestamp and ticker predicates rule out
+		from = fmt.Sprintf("read_parquet(%s, union_by_name = true)", sqlString(filepath.ToSlash(dir)+"/**/*.parquet"))
+		where = joinPredicates(timestampPredicates(filter), tickerPredicate(filter, "ticker"))
+	default:
+		return "", fmt.Errorf("unknown source %q (supported: csv, arrow, parquet)", source)
+	}
+
+	columns := "*"
+	if source == "csv" {
+		columns = "* EXCLUDE (filename)"
+	}
+	view := fmt.Sprintf("%sCREATE VIEW spreads AS SELECT %s FROM %s", setup, columns, from)
+	if where != "" {
+		view += " WHERE " + where
+	}
+	return view + ";", nil
+}
+
+// dayPredicates returns the SQL conditions of filter's days on a YYYYMMDD expression
+func dayPredicates(filter storage.ArchiveFilter, day string) string {
+	var predicates []string
+	if !filter.From.IsZero() {
+		predicates = append(predicates, fmt.Sprintf("%s >= %s", day, sqlString(filter.From.Format("20060102"))))
+	}
+	if !filter.To.IsZero() {
+		predicates = append(predicates, fmt.Sprintf("%s <= %s", day, sqlString(filter.To.Format("20060102"))))
+	}
+	return joinPredicates(predicates...)
+}
+
+// timestampPredicates returns the SQL conditions of filter's days on the timestamp column
+// Comparing the column itself (not a day derived from it) lets DuckDB use file statistics
+func timestampPredicates(filter storage.ArchiveFilter) string {
+	var predicates []string
+	if !filter.From.IsZero() {
+		predicates = append(predicates, fmt.Sprintf("timestamp >= TIMESTAMPTZ %s", sqlString(filter.From.Format("2006-01-02"))))
+	}
+	if !filter.To.IsZero() {
+		predicates = append(predicates, fmt.Sprintf("timestamp < TIMESTAMPTZ %s", sqlString(filter.To.AddDate(0, 0, 1).Format("2006-01-02"))))
+	}
+	return joinPredicates(predicates...)
+}
+
+// tickerPredicate returns the SQL condition of filter's tickers on a ticker expression ("" for all tickers)
+func tickerPredicate(filter storage.ArchiveFilter, ticker string) string {
+	if len(filter.Tickers) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(filter.Tickers))
+	for i, t := range filter.Tickers {
+		quoted[i] = sqlString(t)
+	}
+	return fmt.Sprintf("%s IN (%s)", ticker, strings.Join(quoted, ", "))
+}
+
+// joinPredicates combines the non-empty conditions with AND
+func joinPredicates(predicates ...string) string {
+	var nonEmpty []string
+	for _, p := range predicates {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, " AND ")
+}
+
+// parquetCopy returns the SQL writing the result of a single SELECT query to a Parquet file
+func parquetCopy(query, path string) string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	return fmt.Sprintf("COPY (%s) TO %s (FORMAT parquet);", query, sqlString(path))
+}
+
+// sqlString quotes s as an SQL string literal
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
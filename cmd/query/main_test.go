@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+)
+
+func TestParquetCopy(t *testing.T) {
+	got := parquetCopy(" SELECT * FROM spreads;\n", "out/o'brien.parquet")
+	want := "COPY (SELECT * FROM spreads) TO 'out/o''brien.parquet' (FORMAT parquet);"
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestSpreadsView(t *testing.T) {
+	filter := storage.ArchiveFilter{
+		From:    time.Date(2025, 11, 18, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2025, 11, 20, 0, 0, 0, 0, time.UTC),
+		Tickers: []string{"EURUSD", "USDJPY"},
+	}
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"csv", "SET TimeZone = 'UTC';\n" +
+			"CREATE VIEW spreads AS SELECT * EXCLUDE (filename) FROM read_csv('data/spreads/[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]/*.csv*', header = true, union_by_name = true, filename = true)" +
+			" WHERE regexp_matches(filename, '_[0-9]{2}(-[0-9]+)?\\.csv(\\.partial)?$')" +
+			" AND regexp_extract(filename, '/([0-9]{8})/[^/]*$', 1) >= '20251118'" +
+			" AND regexp_extract(filename, '/([0-9]{8})/[^/]*$', 1) <= '20251120'" +
+			" AND regexp_extract(filename, '/([^/]+)_[0-9]{2}(-[0-9]+)?\\.csv(\\.partial)?$', 1) IN ('EURUSD', 'USDJPY');"},
+		{"arrow", "SET TimeZone = 'UTC';\nINSTALL nanoarrow FROM community;\nLOAD nanoarrow;\n" +
+			"CREATE VIEW spreads AS SELECT * FROM read_arrow('data/spreads/[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]/spreads_*.arrow')" +
+			" WHERE timestamp >= TIMESTAMPTZ '2025-11-18' AND timestamp < TIMESTAMPTZ '2025-11-21' AND ticker IN ('EURUSD', 'USDJPY');"},
+		{"parquet", "SET TimeZone = 'UTC';\n" +
+			"CREATE VIEW spreads AS SELECT * FROM read_parquet('data/spreads/**/*.parquet', union_by_name = true)" +
+			" WHERE timestamp >= TIMESTAMPTZ '2025-11-18' AND timestamp < TIMESTAMPTZ '2025-11-21' AND ticker IN ('EURUSD', 'USDJPY');"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := spreadsView(tt.source, "data/spreads", filter)
+			if err != nil {
+				t.Fatalf("Failed to build the view: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSpreadsView_Unfiltered(t *testing.T) {
+	got, err := spreadsView("parquet", "archive", storage.ArchiveFilter{})
+	if err != nil {
+		t.Fatalf("Failed to build the view: %v", err)
+	}
+	if strings.Contains(got, "WHERE") {
+		t.Errorf("Expected no conditions without a filter, got %s", got)
+	}
+	if _, err := spreadsView("ndjson", "archive", storage.ArchiveFilter{}); err == nil {
+		t.Error("Expected an unknown source to fail")
+	}
+}
@@ -0,0 +1,321 @@
+// Command reconcile compares the ticks two sinks recorded and optionally repairs the target
+//
+//	go run ./cmd/reconcile -source csv -target "arrow?dir=/mnt/arrow" -from 20251101 -to 20251130
+//
+// Reports per instrument the ticks missing from the target, the ticks only the target has and the
+// duplicates on either side. With -repair the missing ticks are written to the target sink, except
+// for the hours a running collector may still have open
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	"github.com/joho/godotenv"
+)
+
+// batchSize is the number of missing ticks handed to the target at once
+const batchSize = 1000
+
+// openGrace is how long after its hour a running collector may keep writing to it, as for the
+// partial files of the CSV sink
+const openGrace = time.Minute
+
+// sinkTicks counts the ticks of one sink by key
+type sinkTicks struct {
+	counts map[domain.TickKey]int
+	ticks  map[domain.TickKey]*domain.PriceData // First copy of each tick, kept for the source only
+	latest map[string]time.Time                 // Latest tick of each instrument
+}
+
+// instrumentReport is the result for one instrument
+type instrumentReport struct {
+	Ticker          string `json:"ticker"`
+	Source          int    `json:"source"`            // Ticks in the source, duplicates included
+	Target          int    `json:"target"`            // Ticks in the target, duplicates included
+	Missing         int    `json:"missing"`           // Source ticks the target lacks
+	Extra           int    `json:"extra"`             // Target ticks the source lacks
+	SourceDuplicate int    `json:"source_duplicates"` // Copies beyond the first in the source
+	TargetDuplicate int    `json:"target_duplicates"` // Copies beyond the first in the target
+	Repaired        int    `json:"repaired"`          // Missing ticks written to the target (-repair)
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Reconcile error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	sourceDef := flag.String("source", "csv", "Sink holding the reference data, as in SPREAD_RECORDERS (csv, arrow or ndjson)")
+	targetDef := flag.String("target", "", "Sink to check against the source, as in SPREAD_RECORDERS (required)")
+	from := flag.String("from", "", "First day to include (YYYYMMDD, default 7 days ago)")
+	to := flag.String("to", "", "Last day to include (YYYYMMDD, default today)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	format := flag.String("format", "csv", "Output format: csv or json")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	repair := flag.Bool("repair", false, "Write the ticks missing from the target to it")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: csv, json)", *format)
+	}
+	if *targetDef == "" {
+		return fmt.Errorf("-target is required")
+	}
+	source, err := parseSink("-source", *sourceDef, tenantName)
+	if err != nil {
+		return err
+	}
+	target, err := parseSink("-target", *targetDef, tenantName)
+	if err != nil {
+		return err
+	}
+	if source.String() == target.String() {
+		return fmt.Errorf("-source and -target are the same sink (%s)", source)
+	}
+
+	// Day directories are named by UTC date
+	today := time.Now().UTC()
+	filter := storage.ArchiveFilter{From: today.AddDate(0, 0, -6), To: today}
+	if *from != "" {
+		if filter.From, err = time.Parse("20060102", *from); err != nil {
+			return fmt.Errorf("invalid -from '%s': %w", *from, err)
+		}
+	}
+	if *to != "" {
+		if filter.To, err = time.Parse("20060102", *to); err != nil {
+			return fmt.Errorf("invalid -to '%s': %w", *to, err)
+		}
+	}
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter.Tickers = groups.Expand(*tickers)
+
+	sourceTicks, err := readSink(source, filter, true)
+	if err != nil {
+		return fmt.Errorf("failed to read source %s: %w", source, err)
+	}
+	targetTicks, err := readSink(target, filter, false)
+	if err != nil {
+		return fmt.Errorf("failed to read target %s: %w", target, err)
+	}
+
+	reports := compare(sourceTicks, targetTicks)
+	missing := missingTicks(sourceTicks, targetTicks)
+	if *repair {
+		var open int
+		missing, open = closedHours(missing, targetTicks, time.Now())
+		if open > 0 {
+			log.Printf("Skipping %d missing ticks of hours a running collector may still be writing; repair them once it moved on", open)
+		}
+	}
+	if *repair && len(missing) > 0 {
+		repaired, err := repairTarget(context.Background(), target, missing)
+		for _, p := range missing[:repaired] {
+			reports[p.Ticker].Repaired++
+		}
+		log.Printf("Wrote %d of %d missing ticks to %s", repaired, len(missing), target)
+		if err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	rows := make([]*instrumentReport, 0, len(reports))
+	for _, ticker := range slices.Sorted(maps.Keys(reports)) {
+		rows = append(rows, reports[ticker])
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	}
+	return writeReportCSV(out, rows)
+}
+
+// parseSink parses a sink definition and fills in the collector's default locations for the tenant
+func parseSink(flagName, definition, tenantName string) (storage.RecorderSpec, error) {
+	spec, err := storage.ParseRecorderSpec(definition)
+	if err != nil {
+		return storage.RecorderSpec{}, fmt.Errorf("invalid %s: %w", flagName, err)
+	}
+	if spec.Fallback != nil {
+		return storage.RecorderSpec{}, fmt.Errorf("invalid %s: compare the sinks of a fallback chain one by one", flagName)
+	}
+	defaults := tenant.SinkDefaults(tenantName)
+	return spec.WithDefaults(defaults[spec.Name]), nil
+}
+
+// readSink counts the ticks a sink recorded within filter; keep holds on to the ticks for repairs
+func readSink(spec storage.RecorderSpec, filter storage.ArchiveFilter, keep bool) (*sinkTicks, error) {
+	result := &sinkTicks{counts: make(map[domain.TickKey]int), latest: make(map[string]time.Time)}
+	if keep {
+		result.ticks = make(map[domain.TickKey]*domain.PriceData)
+	}
+
+	total := 0
+	err := storage.ReadRecorded(spec, filter, func(p *domain.PriceData) error {
+		key := p.Key()
+		result.counts[key]++
+		if p.Timestamp.After(result.latest[p.Ticker]) {
+			result.latest[p.Ticker] = p.Timestamp
+		}
+		if keep && result.counts[key] == 1 {
+			result.ticks[key] = p
+		}
+		total++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Read %d ticks from %s", total, spec)
+	return result, nil
+}
+
+// compare builds the report of each instrument in either sink
+func compare(source, target *sinkTicks) map[string]*instrumentReport {
+	reports := make(map[string]*instrumentReport)
+	report := func(ticker string) *instrumentReport {
+		if reports[ticker] == nil {
+			reports[ticker] = &instrumentReport{Ticker: ticker}
+		}
+		return reports[ticker]
+	}
+
+	for key, count := range source.counts {
+		r := report(key.Ticker)
+		r.Source += count
+		r.SourceDuplicate += count - 1
+		if target.counts[key] == 0 {
+			r.Missing++
+		}
+	}
+	for key, count := range target.counts {
+		r := report(key.Ticker)
+		r.Target += count
+		r.TargetDuplicate += count - 1
+		if source.counts[key] == 0 {
+			r.Extra++
+		}
+	}
+	return reports
+}
+
+// missingTicks returns the source ticks the target lacks, oldest first
+func missingTicks(source, target *sinkTicks) []*domain.PriceData {
+	var missing []*domain.PriceData
+	for key, p := range source.ticks {
+		if target.counts[key] == 0 {
+			missing = append(missing, p)
+		}
+	}
+	sort.SliceStable(missing, func(i, j int) bool {
+		if !missing[i].Timestamp.Equal(missing[j].Timestamp) {
+			return missing[i].Timestamp.Before(missing[j].Timestamp)
+		}
+		return missing[i].Ticker < missing[j].Ticker
+	})
+	return missing
+}
+
+// closedHours drops the ticks of hours a running collector may still be writing to and returns the
+// rest with the number dropped. Those are the hours that ended less than openGrace before now and,
+// as the collector keeps an instrument's file open until its next tick in a later hour, the hour of
+// the latest tick the target has of each instrument
+func closedHours(ticks []*domain.PriceData, target *sinkTicks, now time.Time) ([]*domain.PriceData, int) {
+	closed := make([]*domain.PriceData, 0, len(ticks))
+	for _, p := range ticks {
+		hour := p.Timestamp.UTC().Truncate(time.Hour)
+		if now.Sub(hour) <= time.Hour+openGrace {
+			continue
+		}
+		if latest, ok := target.latest[p.Ticker]; ok && !hour.Before(latest.UTC().Truncate(time.Hour)) {
+			continue
+		}
+		closed = append(closed, p)
+	}
+	return closed, len(ticks) - len(closed)
+}
+
+// repairTarget writes ticks to the target sink in batches and returns how many were written
+// Duplicates are left alone: removing rows would mean rewriting the target's files
+func repairTarget(ctx context.Context, spec storage.RecorderSpec, ticks []*domain.PriceData) (int, error) {
+	sink, err := storage.NewRecorder(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for written < len(ticks) {
+		batch := ticks[written:min(written+batchSize, len(ticks))]
+		if err = sink.RecordBatch(ctx, batch); err != nil {
+			err = fmt.Errorf("failed to write to %s: %w", spec, err)
+			break
+		}
+		written += len(batch)
+	}
+
+	// Close even after a failure: the Arrow sink only becomes readable once closed
+	if closer, ok := sink.(ports.Closer); ok {
+		if closeErr := closer.Close(ctx); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", spec, closeErr)
+		}
+	}
+	return written, err
+}
+
+// writeReportCSV writes one row per instrument
+func writeReportCSV(w io.Writer, rows []*instrumentReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"ticker", "source", "target", "missing", "extra", "source_duplicates", "target_duplicates", "repaired"})
+	for _, r := range rows {
+		writer.Write([]string{
+			r.Ticker,
+			strconv.Itoa(r.Source),
+			strconv.Itoa(r.Target),
+			strconv.Itoa(r.Missing),
+			strconv.Itoa(r.Extra),
+			strconv.Itoa(r.SourceDuplicate),
+			strconv.Itoa(r.TargetDuplicate),
+			strconv.Itoa(r.Repaired),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
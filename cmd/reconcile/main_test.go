@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// newSinkTicks counts ticks as readSink does, keeping the first copy of each
+func newSinkTicks(ticks ...*domain.PriceData) *sinkTicks {
+	s := &sinkTicks{counts: make(map[domain.TickKey]int), ticks: make(map[domain.TickKey]*domain.PriceData), latest: make(map[string]time.Time)}
+	for _, p := range ticks {
+		key := p.Key()
+		if s.counts[key]++; s.counts[key] == 1 {
+			s.ticks[key] = p
+		}
+		if p.Timestamp.After(s.latest[p.Ticker]) {
+			s.latest[p.Ticker] = p.Timestamp
+		}
+	}
+	return s
+}
+
+func TestCompare(t *testing.T) {
+	start := time.Date(2025, 11, 18, 10, 0, 0, 0, time.UTC)
+	tick := func(ticker string, offset time.Duration) *domain.PriceData {
+		return &domain.PriceData{Ticker: ticker, Timestamp: start.Add(offset), Bid: 1.1, Ask: 1.1001}
+	}
+	a, b, c := tick("EURUSD", 0), tick("EURUSD", time.Second), tick("EURUSD", 2*time.Second)
+	source := newSinkTicks(a, a, b, c, tick("USDJPY", time.Hour))
+	target := newSinkTicks(a, c, c, tick("EURUSD", 3*time.Second))
+
+	reports := compare(source, target)
+	eur := reports["EURUSD"]
+	if eur == nil {
+		t.Fatal("Expected a report for EURUSD")
+	}
+	want := instrumentReport{Ticker: "EURUSD", Source: 4, Target: 4, Missing: 1, Extra: 1, SourceDuplicate: 1, TargetDuplicate: 1}
+	if *eur != want {
+		t.Errorf("Expected %+v, got %+v", want, *eur)
+	}
+	if jpy := reports["USDJPY"]; jpy == nil || jpy.Missing != 1 || jpy.Target != 0 {
+		t.Errorf("Expected USDJPY missing from the target, got %+v", jpy)
+	}
+
+	missing := missingTicks(source, target)
+	if len(missing) != 2 || missing[0] != b || missing[1].Ticker != "USDJPY" {
+		t.Errorf("Expected b and the USDJPY tick oldest first, got %v", missing)
+	}
+}
+
+func TestClosedHours(t *testing.T) {
+	now := time.Date(2025, 11, 18, 12, 30, 0, 0, time.UTC)
+	tick := func(ticker string, at time.Time) *domain.PriceData {
+		return &domain.PriceData{Ticker: ticker, Timestamp: at, Bid: 1.1, Ask: 1.1001}
+	}
+	target := newSinkTicks(tick("USDJPY", now.Add(-2*time.Hour))) // The target's file of 10:00 may still be open
+
+	ticks := []*domain.PriceData{
+		tick("EURUSD", now.Add(-3*time.Hour)),    // 09:30, closed
+		tick("EURUSD", now.Add(-20*time.Minute)), // 12:10, the hour still being written
+		tick("USDJPY", now.Add(-3*time.Hour)),    // Before the target's latest hour
+		tick("USDJPY", now.Add(-2*time.Hour)),    // In the target's latest hour
+	}
+	closed, dropped := closedHours(ticks, target, now)
+	if dropped != 2 || len(closed) != 2 || closed[0] != ticks[0] || closed[1] != ticks[2] {
+		t.Errorf("Expected the 09:30 ticks kept and 2 dropped, got %v and %d", closed, dropped)
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	var out bytes.Buffer
+	rows := []*instrumentReport{{Ticker: "EURUSD", Source: 4, Target: 3, Missing: 1, Repaired: 1}}
+	if err := writeReportCSV(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "ticker,source,target,missing,extra,source_duplicates,target_duplicates,repaired\nEURUSD,4,3,1,0,0,0,1\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
@@ -0,0 +1,115 @@
+// Command restore copies archived days from S3, GCS or Azure Blob Storage back into the local spread archive
+//
+//	go run ./cmd/restore -remote s3://fx-archive/spreads -from 20250301 -to 20250331 -ticker EURUSD
+//
+// The remote holds the same layout as SPREAD_RECORDING_DIR (YYYYMMDD/TICKER_HH.csv). Each day is
+// synced with the provider's CLI (see objectstore), so files already restored are skipped and the
+// query, export and analysis tools can read the days as if they had never been archived
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/objectstore"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Restore error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	remote := flag.String("remote", toolconfig.GetEnv("ARCHIVE_URL", ""), "Archive to restore from (s3://bucket/prefix, gs://bucket/prefix or az://account/container/prefix)")
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "Local spread archive directory")
+	from := flag.String("from", "", "First day to restore (YYYYMMDD)")
+	to := flag.String("to", "", "Last day to restore (YYYYMMDD, default -from)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers to restore (default all)")
+	dryRun := flag.Bool("dry-run", false, "Only list what would be copied")
+	stores := objectstore.ConfigFromEnv(toolconfig.GetEnv)
+	flag.StringVar(&stores.AWS.CLIPath, "aws", stores.AWS.CLIPath, "Path to the AWS CLI (s3:// archives)")
+	flag.StringVar(&stores.GCS.CLIPath, "gcloud", stores.GCS.CLIPath, "Path to the gcloud CLI (gs:// archives)")
+	flag.StringVar(&stores.Azure.CLIPath, "az", stores.Azure.CLIPath, "Path to the Azure CLI (az:// archives)")
+	flag.Parse()
+
+	if *remote == "" {
+		return fmt.Errorf("no archive given (set -remote or ARCHIVE_URL)")
+	}
+	if *from == "" {
+		return fmt.Errorf("-from is required")
+	}
+	if *to == "" {
+		*to = *from
+	}
+	first, err := time.Parse("20060102", *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from '%s': %w", *from, err)
+	}
+	last, err := time.Parse("20060102", *to)
+	if err != nil {
+		return fmt.Errorf("invalid -to '%s': %w", *to, err)
+	}
+	if last.Before(first) {
+		return fmt.Errorf("-to %s is before -from %s", *to, *from)
+	}
+
+	var tickerList []string
+	for _, ticker := range strings.Split(*tickers, ",") {
+		if ticker = strings.TrimSpace(ticker); ticker != "" {
+			tickerList = append(tickerList, ticker)
+		}
+	}
+
+	provider, err := objectstore.New(*remote, stores)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		key := day.Format("20060102")
+		dst := filepath.Join(*dir, key)
+		if err := os.MkdirAll(dst, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dst, err)
+		}
+
+		err := provider.Download(context.Background(), key, dst, tickerList, *dryRun)
+		_ = os.Remove(dst) // Only succeeds if the archive had nothing for the day
+		if err != nil {
+			log.Printf("%s: %v", key, err)
+			failed++
+			continue
+		}
+		if *dryRun {
+			continue
+		}
+
+		files, err := storage.ListSpreadFiles(*dir, storage.ArchiveFilter{From: day, To: day, Tickers: tickerList})
+		if err != nil {
+			return err
+		}
+		log.Printf("%s: %d files in %s", key, len(files), dst)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d days failed to restore", failed)
+	}
+	return nil
+}
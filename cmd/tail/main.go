@@ -0,0 +1,130 @@
+// Command tail follows the ticks of one instrument as the collector records them
+//
+//	go run ./cmd/tail -ticker EURUSD
+//
+// Prints the last ticks of the current hourly CSV file, then every new tick as it is flushed.
+// At the hour boundary it finishes the old file and continues with the new one, which tail -F
+// can't do since every hour has its own file
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Tail error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	dir := flag.String("dir", tenant.Path(tenantName, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads")), "CSV spread directory")
+	ticker := flag.String("ticker", "", "Instrument to follow (required)")
+	lines := flag.Int("n", 10, "Ticks of the current file to print before following")
+	interval := flag.Duration("interval", 500*time.Millisecond, "How often to check for new ticks")
+	format := flag.String("format", "text", "Output format: text or json (one object per line)")
+	flag.Parse()
+
+	if *ticker == "" {
+		return fmt.Errorf("-ticker is required")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: text, json)", *format)
+	}
+	if *interval <= 0 || *lines < 0 {
+		return fmt.Errorf("-interval must be positive and -n at least 0")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	show := printer(os.Stdout, *format)
+	tail := storage.NewSpreadTail(*dir, *ticker)
+	defer tail.Close()
+
+	// The first poll reads the whole current file; only its last ticks are shown
+	var history []*domain.PriceData
+	if err := tail.Poll(func(p *domain.PriceData) error {
+		history = append(history, p)
+		if len(history) > *lines {
+			history = history[1:]
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, p := range history {
+		if err := show(p); err != nil {
+			return err
+		}
+	}
+	if tail.Path() == "" {
+		log.Printf("No file for %s yet in %s, waiting", *ticker, *dir)
+	} else {
+		log.Printf("Following %s", tail.Path())
+	}
+
+	poll := time.NewTicker(*interval)
+	defer poll.Stop()
+	path := tail.Path()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		}
+		if err := tail.Poll(show); err != nil {
+			return err
+		}
+		if tail.Path() != path {
+			path = tail.Path()
+			log.Printf("Following %s", path)
+		}
+	}
+}
+
+// printer returns a function writing ticks to out as aligned text lines or JSON objects
+func printer(out io.Writer, format string) func(*domain.PriceData) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		return func(p *domain.PriceData) error {
+			return encoder.Encode(p)
+		}
+	}
+	return func(p *domain.PriceData) error {
+		line := fmt.Sprintf("%s  %-8s  %s / %s  spread %s",
+			p.Timestamp.Format("2006-01-02 15:04:05.000"), p.Ticker,
+			strconv.FormatFloat(p.Bid, 'f', p.Decimals, 64), strconv.FormatFloat(p.Ask, 'f', p.Decimals, 64),
+			strconv.FormatFloat(p.Spread, 'f', p.Decimals, 64))
+		if p.Sequence > 0 {
+			line += fmt.Sprintf("  seq %d", p.Sequence)
+		}
+		if p.Flags != 0 {
+			line += "  [" + p.Flags.String() + "]"
+		}
+		_, err := fmt.Fprintln(out, line)
+		return err
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestPrinter(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 250_000_000, time.UTC),
+		Ticker:    "EURUSD",
+		Bid:       1.1,
+		Ask:       1.10012,
+		Spread:    0.00012,
+		Decimals:  5,
+		Sequence:  42,
+		Flags:     domain.FlagRollover | domain.FlagOutlier,
+	}
+
+	var out bytes.Buffer
+	if err := printer(&out, "text")(tick); err != nil {
+		t.Fatal(err)
+	}
+	want := "2025-11-18 12:00:00.250  EURUSD    1.10000 / 1.10012  spread 0.00012  seq 42  [rollover|outlier]\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := printer(&out, "json")(tick); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("{")) || !bytes.HasSuffix(out.Bytes(), []byte("}\n")) {
+		t.Errorf("Expected one JSON object per line, got %q", out.String())
+	}
+}
@@ -0,0 +1,153 @@
+// Command verify rebuilds the snapshot grid from the raw ticks and compares it with the stored snapshots
+//
+//	go run ./cmd/verify -from 20251117 -to 20251121 -ticker EURUSD
+//
+// Reports every stored snapshot whose recorded tick count, closing quote or spread range differs
+// from the one rebuilt from the ticks, and every interval with ticks but no stored snapshot. Exits
+// with an error when discrepancies are found
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/internal/analysis"
+	"github.com/bjoelf/fx-collector/internal/tenant"
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Verify error: %v", err)
+	}
+}
+
+func run() error {
+	_ = godotenv.Load() // Optional, same .env as the collector
+	tenantName, err := tenant.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	snapshotDir := flag.String("snapshots", tenant.Path(tenantName, toolconfig.GetEnv("SNAPSHOT_DIR", "data/snapshots")), "Snapshot directory")
+	sourceDef := flag.String("source", "csv", "Sink holding the raw ticks, as in SPREAD_RECORDERS (csv, arrow or ndjson)")
+	intervalStr := flag.String("interval", toolconfig.GetEnv("SNAPSHOT_INTERVAL", "1s"), "Snapshot interval the collector ran with")
+	from := flag.String("from", "", "First day to include (YYYYMMDD, default 7 days ago)")
+	to := flag.String("to", "", "Last day to include (YYYYMMDD, default today)")
+	tickers := flag.String("ticker", "", "Comma-separated tickers or instrument groups to include (default all)")
+	instrumentsPath := flag.String("instruments", toolconfig.GetEnv("INSTRUMENTS_PATH", "data/instruments.json"), "Instruments file defining the groups")
+	format := flag.String("format", "csv", "Output format: csv or json")
+	output := flag.String("o", "-", "Output file (- for stdout)")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q (supported: csv, json)", *format)
+	}
+	interval, err := time.ParseDuration(*intervalStr)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid -interval '%s'", *intervalStr)
+	}
+	source, err := storage.ParseRecorderSpec(*sourceDef)
+	if err != nil {
+		return fmt.Errorf("invalid -source: %w", err)
+	}
+	defaults := tenant.SinkDefaults(tenantName)
+	source = source.WithDefaults(defaults[source.Name])
+
+	// Day directories are named by UTC date
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	first, last := today.AddDate(0, 0, -6), today
+	if *from != "" {
+		if first, err = time.Parse("20060102", *from); err != nil {
+			return fmt.Errorf("invalid -from '%s': %w", *from, err)
+		}
+	}
+	if *to != "" {
+		if last, err = time.Parse("20060102", *to); err != nil {
+			return fmt.Errorf("invalid -to '%s': %w", *to, err)
+		}
+	}
+	groups, err := storage.ReadInstrumentGroups(*instrumentsPath)
+	if err != nil {
+		return err
+	}
+	filter := storage.ArchiveFilter{Tickers: groups.Expand(*tickers)}
+
+	// One day at a time keeps only a day's rebuilt snapshots in memory
+	var discrepancies []analysis.SnapshotDiscrepancy
+	snapshots := 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		filter.From, filter.To = day, day
+		found, checked, err := verifyDay(source, *snapshotDir, interval, filter)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", day.Format("20060102"), err)
+		}
+		discrepancies = append(discrepancies, found...)
+		snapshots += checked
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(discrepancies)
+	} else {
+		err = analysis.WriteSnapshotDiscrepanciesCSV(out, discrepancies)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Checked %d snapshots, %d discrepancies", snapshots, len(discrepancies))
+	if len(discrepancies) > 0 {
+		return fmt.Errorf("snapshots don't match the ticks (%d discrepancies)", len(discrepancies))
+	}
+	return nil
+}
+
+// verifyDay compares one day's stored snapshots with the ones rebuilt from its ticks
+func verifyDay(source storage.RecorderSpec, snapshotDir string, interval time.Duration, filter storage.ArchiveFilter) ([]analysis.SnapshotDiscrepancy, int, error) {
+	checker := analysis.NewSnapshotChecker(interval)
+	err := storage.ReadRecorded(source, filter, func(p *domain.PriceData) error {
+		checker.AddTick(p)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read ticks from %s: %w", source, err)
+	}
+
+	// Snapshot files share the tick archive's layout
+	files, err := storage.ListSpreadFiles(snapshotDir, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	var found []analysis.SnapshotDiscrepancy
+	checked := 0
+	for _, path := range files {
+		err := storage.ReadSnapshotFile(path, func(s *domain.Snapshot) error {
+			found = append(found, checker.Check(s)...)
+			checked++
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return append(found, checker.Missing()...), checked, nil
+}
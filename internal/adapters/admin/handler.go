@@ -0,0 +1,272 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// Handler serves the admin API:
+//
+//	GET  /admin/recording                current pauses
+//	POST /admin/pause[/{ticker}]         pause all instruments or one
+//	POST /admin/resume[/{ticker}]        resume all instruments or one
+//	POST /admin/pause/group/{group}      pause the instruments of a group (with WithGroups)
+//	POST /admin/resume/group/{group}     resume the instruments of a group
+//	GET  /admin/groups                   groups with their instruments and which are paused
+//	POST /admin/reload                   re-read the configuration (with WithConfigReloader)
+//	GET  /admin/status                   connection state and latest quote per instrument (with WithStatus)
+//	GET  /admin/gaps[?from=&to=]         recorded gaps, RFC 3339 bounds, default the last 7 days (with WithStateStore)
+//	GET  /admin/backup?target=           files cmd/backup copied to target (with WithStateStore)
+//	PUT  /admin/backup?target=           replace them, while the collector holds the state database
+//
+// Every pause and resume response is the recording state as JSON
+type Handler struct {
+	mux      *http.ServeMux
+	control  ports.RecordingControl
+	reloader ports.ConfigReloader
+	status   ports.StatusProvider
+	groups   domain.InstrumentGroups
+	store    ports.StateStore
+	token    string
+}
+
+// Option configures optional admin endpoints
+type Option func(*Handler)
+
+// WithConfigReloader serves POST /admin/reload, which answers {"changes": [...]} or 400 with the error
+func WithConfigReloader(reloader ports.ConfigReloader) Option {
+	return func(h *Handler) {
+		h.reloader = reloader
+	}
+}
+
+// WithStatus serves GET /admin/status, the live state cmd/monitor displays
+func WithStatus(status ports.StatusProvider) Option {
+	return func(h *Handler) {
+		h.status = status
+	}
+}
+
+// WithGroups serves the group endpoints for groups
+func WithGroups(groups domain.InstrumentGroups) Option {
+	return func(h *Handler) {
+		h.groups = groups
+	}
+}
+
+// WithStateStore serves the recorded gaps and the backup progress of the state database
+func WithStateStore(store ports.StateStore) Option {
+	return func(h *Handler) {
+		h.store = store
+	}
+}
+
+// NewHandler creates the admin API; a non-empty token is required as "Authorization: Bearer <token>"
+func NewHandler(control ports.RecordingControl, token string, opts ...Option) *Handler {
+	h := &Handler{mux: http.NewServeMux(), control: control, token: token}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /admin/recording", h.state)
+	h.mux.HandleFunc("POST /admin/pause", h.pause)
+	h.mux.HandleFunc("POST /admin/pause/{ticker}", h.pause)
+	h.mux.HandleFunc("POST /admin/resume", h.resume)
+	h.mux.HandleFunc("POST /admin/resume/{ticker}", h.resume)
+	if h.reloader != nil {
+		h.mux.HandleFunc("POST /admin/reload", h.reload)
+	}
+	if h.status != nil {
+		h.mux.HandleFunc("GET /admin/status", h.collectorStatus)
+	}
+	if h.groups != nil {
+		h.mux.HandleFunc("GET /admin/groups", h.groupStates)
+		h.mux.HandleFunc("POST /admin/pause/group/{group}", h.pauseGroup)
+		h.mux.HandleFunc("POST /admin/resume/group/{group}", h.resumeGroup)
+	}
+	if h.store != nil {
+		h.mux.HandleFunc("GET /admin/gaps", h.gaps)
+		h.mux.HandleFunc("GET /admin/backup", h.backedUp)
+		h.mux.HandleFunc("PUT /admin/backup", h.saveBackedUp)
+	}
+	return h
+}
+
+// ServeHTTP checks the token and dispatches the request
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" {
+		want := "Bearer " + h.token
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) state(w http.ResponseWriter, r *http.Request) {
+	h.writeState(w)
+}
+
+func (h *Handler) pause(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, h.control.PauseRecording(r.PathValue("ticker")))
+}
+
+func (h *Handler) resume(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, h.control.ResumeRecording(r.PathValue("ticker")))
+}
+
+func (h *Handler) pauseGroup(w http.ResponseWriter, r *http.Request) {
+	h.applyGroup(w, r.PathValue("group"), h.control.PauseRecording)
+}
+
+func (h *Handler) resumeGroup(w http.ResponseWriter, r *http.Request) {
+	h.applyGroup(w, r.PathValue("group"), h.control.ResumeRecording)
+}
+
+// applyGroup pauses or resumes every instrument of group
+func (h *Handler) applyGroup(w http.ResponseWriter, group string, apply func(ticker string) error) {
+	tickers, ok := h.groups[group]
+	if !ok {
+		http.Error(w, "unknown group "+group, http.StatusNotFound)
+		return
+	}
+	var errs []error
+	for _, ticker := range tickers {
+		if err := apply(ticker); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	h.apply(w, errors.Join(errs...))
+}
+
+// groupState is one group in the GET /admin/groups response
+type groupState struct {
+	Tickers []string `json:"tickers"`
+	Paused  []string `json:"paused"` // Manually or by schedule
+}
+
+func (h *Handler) groupStates(w http.ResponseWriter, r *http.Request) {
+	state := h.control.RecordingState()
+	groups := make(map[string]groupState, len(h.groups))
+	for name, tickers := range h.groups {
+		group := groupState{Tickers: tickers, Paused: []string{}}
+		for _, ticker := range tickers {
+			if state.Paused || slices.Contains(state.Tickers, ticker) || slices.Contains(state.Scheduled, ticker) {
+				group.Paused = append(group.Paused, ticker)
+			}
+		}
+		groups[name] = group
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		log.Printf("Admin: Failed to write response: %v", err)
+	}
+}
+
+func (h *Handler) collectorStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.status.CollectorStatus()); err != nil {
+		log.Printf("Admin: Failed to write response: %v", err)
+	}
+}
+
+func (h *Handler) reload(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.reloader.ReloadConfig()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string][]string{"changes": append([]string{}, changes...)}); err != nil {
+		log.Printf("Admin: Failed to write response: %v", err)
+	}
+}
+
+func (h *Handler) gaps(w http.ResponseWriter, r *http.Request) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -7)
+	for name, bound := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := r.URL.Query().Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid "+name+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			*bound = parsed
+		}
+	}
+	gaps, err := h.store.Gaps(r.Context(), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, append([]domain.RecordingGap{}, gaps...))
+}
+
+func (h *Handler) backedUp(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "no target given", http.StatusBadRequest)
+		return
+	}
+	files, err := h.store.BackedUp(r.Context(), target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, files)
+}
+
+func (h *Handler) saveBackedUp(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "no target given", http.StatusBadRequest)
+		return
+	}
+	var files map[string]domain.BackedUpFile
+	if err := json.NewDecoder(r.Body).Decode(&files); err != nil {
+		http.Error(w, "invalid backup progress: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.store.SaveBackedUp(r.Context(), target, files); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes v as the JSON response
+func (h *Handler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Admin: Failed to write response: %v", err)
+	}
+}
+
+// apply reports the result of a pause or resume
+func (h *Handler) apply(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInstrumentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		h.writeState(w)
+	}
+}
+
+func (h *Handler) writeState(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.control.RecordingState()); err != nil {
+		log.Printf("Admin: Failed to write response: %v", err)
+	}
+}
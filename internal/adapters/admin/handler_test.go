@@ -0,0 +1,270 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// fakeControl records pauses like the collector service
+type fakeControl struct {
+	all     bool
+	tickers []string
+}
+
+func (f *fakeControl) PauseRecording(ticker string) error {
+	switch {
+	case ticker == "":
+		f.all = true
+	case ticker != "EURUSD":
+		return domain.ErrInstrumentNotFound
+	default:
+		f.tickers = append(f.tickers, ticker)
+	}
+	return nil
+}
+
+func (f *fakeControl) ResumeRecording(ticker string) error {
+	if ticker == "" {
+		f.all, f.tickers = false, nil
+	}
+	f.tickers = slices.DeleteFunc(f.tickers, func(t string) bool { return t == ticker })
+	return nil
+}
+
+func (f *fakeControl) RecordingState() domain.RecordingState {
+	return domain.RecordingState{Paused: f.all, Tickers: f.tickers}
+}
+
+func TestHandler_PauseResume(t *testing.T) {
+	control := &fakeControl{}
+	handler := NewHandler(control, "")
+
+	do := func(method, path string) (int, domain.RecordingState) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var state domain.RecordingState
+		json.Unmarshal(rec.Body.Bytes(), &state)
+		return rec.Code, state
+	}
+
+	if code, state := do(http.MethodPost, "/admin/pause/EURUSD"); code != http.StatusOK || !slices.Equal(state.Tickers, []string{"EURUSD"}) {
+		t.Errorf("Pause EURUSD: %d %+v", code, state)
+	}
+	if code, state := do(http.MethodPost, "/admin/pause"); code != http.StatusOK || !state.Paused {
+		t.Errorf("Pause all: %d %+v", code, state)
+	}
+	if code, state := do(http.MethodPost, "/admin/resume"); code != http.StatusOK || state.Paused || len(state.Tickers) != 0 {
+		t.Errorf("Resume all: %d %+v", code, state)
+	}
+	if code, _ := do(http.MethodPost, "/admin/pause/XAUUSD"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown ticker, got %d", code)
+	}
+	if code, _ := do(http.MethodGet, "/admin/pause"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET /admin/pause, got %d", code)
+	}
+	if code, _ := do(http.MethodGet, "/admin/recording"); code != http.StatusOK {
+		t.Errorf("Expected 200 for state, got %d", code)
+	}
+}
+
+func TestHandler_Token(t *testing.T) {
+	handler := NewHandler(&fakeControl{}, "secret")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/pause", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/pause", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with token, got %d", rec.Code)
+	}
+}
+
+// fakeReloader fails once err is set
+type fakeReloader struct {
+	err error
+}
+
+func (f *fakeReloader) ReloadConfig() ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []string{"flush interval 30s -> 10s"}, nil
+}
+
+func TestHandler_Reload(t *testing.T) {
+	reloader := &fakeReloader{}
+	handler := NewHandler(&fakeControl{}, "", WithConfigReloader(reloader))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	var body struct{ Changes []string }
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || !slices.Equal(body.Changes, []string{"flush interval 30s -> 10s"}) {
+		t.Errorf("Reload: %d %s", rec.Code, rec.Body)
+	}
+
+	reloader.err = errors.New("flush interval must be positive, got 0s")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a failed reload, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	NewHandler(&fakeControl{}, "").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a reloader, got %d", rec.Code)
+	}
+}
+
+func TestHandler_Groups(t *testing.T) {
+	control := &fakeControl{}
+	groups := domain.InstrumentGroups{"majors": {"EURUSD"}, "exotics": {"USDTRY"}}
+	handler := NewHandler(control, "", WithGroups(groups))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/admin/pause/group/majors"); rec.Code != http.StatusOK || !slices.Equal(control.tickers, []string{"EURUSD"}) {
+		t.Errorf("Pause group: %d %v", rec.Code, control.tickers)
+	}
+
+	var states map[string]struct{ Tickers, Paused []string }
+	json.Unmarshal(do(http.MethodGet, "/admin/groups").Body.Bytes(), &states)
+	if !slices.Equal(states["majors"].Paused, []string{"EURUSD"}) || len(states["exotics"].Paused) != 0 {
+		t.Errorf("Unexpected group states %+v", states)
+	}
+
+	if rec := do(http.MethodPost, "/admin/resume/group/majors"); rec.Code != http.StatusOK || len(control.tickers) != 0 {
+		t.Errorf("Resume group: %d %v", rec.Code, control.tickers)
+	}
+	if rec := do(http.MethodPost, "/admin/pause/group/metals"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown group, got %d", rec.Code)
+	}
+	// The fake control only knows EURUSD
+	if rec := do(http.MethodPost, "/admin/pause/group/exotics"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a group with an unknown ticker, got %d", rec.Code)
+	}
+}
+
+// fakeStatus reports a fixed collector status
+type fakeStatus struct{ status domain.CollectorStatus }
+
+func (f fakeStatus) CollectorStatus() domain.CollectorStatus { return f.status }
+
+func TestHandler_Status(t *testing.T) {
+	status := domain.CollectorStatus{
+		Connected:   true,
+		Instruments: []domain.InstrumentStatus{{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4, Ticks: 42}},
+	}
+
+	rec := httptest.NewRecorder()
+	NewHandler(&fakeControl{}, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without WithStatus, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	NewHandler(&fakeControl{}, "", WithStatus(fakeStatus{status})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+	var got domain.CollectorStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+	}
+	if !got.Connected || len(got.Instruments) != 1 || got.Instruments[0].Ticks != 42 || got.Instruments[0].QuoteTime != nil {
+		t.Errorf("Unexpected status %+v", got)
+	}
+}
+
+// fakeState keeps gaps and backup progress in memory
+type fakeState struct {
+	ports.StateStore
+	gaps    []domain.RecordingGap
+	backups map[string]map[string]domain.BackedUpFile
+}
+
+func (f *fakeState) Gaps(ctx context.Context, from, to time.Time) ([]domain.RecordingGap, error) {
+	var gaps []domain.RecordingGap
+	for _, gap := range f.gaps {
+		if !gap.Start.Before(from) && gap.Start.Before(to) {
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps, nil
+}
+
+func (f *fakeState) BackedUp(ctx context.Context, target string) (map[string]domain.BackedUpFile, error) {
+	return f.backups[target], nil
+}
+
+func (f *fakeState) SaveBackedUp(ctx context.Context, target string, files map[string]domain.BackedUpFile) error {
+	f.backups[target] = files
+	return nil
+}
+
+func TestHandler_Gaps(t *testing.T) {
+	start := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	state := &fakeState{gaps: []domain.RecordingGap{
+		{Start: start, End: start.Add(time.Minute), Reason: domain.GapNoData},
+		{Start: start.AddDate(0, 0, 1), End: start.AddDate(0, 0, 1).Add(time.Hour), Reason: domain.GapDowntime},
+	}}
+	handler := NewHandler(&fakeControl{}, "", WithStateStore(state))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/gaps?from=2025-03-03T00:00:00Z&to=2025-03-04T00:00:00Z", nil))
+	var gaps []domain.RecordingGap
+	if err := json.Unmarshal(rec.Body.Bytes(), &gaps); err != nil {
+		t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+	}
+	if len(gaps) != 1 || gaps[0].Reason != domain.GapNoData {
+		t.Errorf("Expected the gap of March 3, got %+v", gaps)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/gaps?from=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid bound, got %d", rec.Code)
+	}
+}
+
+func TestHandler_BackupProgress(t *testing.T) {
+	state := &fakeState{backups: make(map[string]map[string]domain.BackedUpFile)}
+	handler := NewHandler(&fakeControl{}, "", WithStateStore(state))
+	path := "/admin/backup?target=" + url.QueryEscape("sftp://nas/fx")
+
+	rec := httptest.NewRecorder()
+	body := `{"20250303/EURUSD_10.csv": {"size": 42, "mod_time": "2025-03-03T11:00:00Z"}}`
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var files map[string]domain.BackedUpFile
+	if err := json.Unmarshal(rec.Body.Bytes(), &files); err != nil {
+		t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+	}
+	if files["20250303/EURUSD_10.csv"].Size != 42 {
+		t.Errorf("Expected the saved progress of the target, got %+v", files)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// grafanaSeries is a value a Grafana target can chart, as TICKER.name (e.g. EURUSD.max_spread)
+type grafanaSeries struct {
+	name  string
+	label string
+	value func(s *domain.Snapshot, interval time.Duration) float64
+}
+
+var grafanaSeriesList = []grafanaSeries{
+	{"spread", "spread", func(s *domain.Snapshot, _ time.Duration) float64 { return s.Ask - s.Bid }},
+	{"min_spread", "min spread", func(s *domain.Snapshot, _ time.Duration) float64 { return s.MinSpread }},
+	{"max_spread", "max spread", func(s *domain.Snapshot, _ time.Duration) float64 { return s.MaxSpread }},
+	{"tick_rate", "ticks/s", func(s *domain.Snapshot, interval time.Duration) float64 { return float64(s.Ticks) / interval.Seconds() }},
+}
+
+// grafanaMetric is one entry of the /metrics response
+type grafanaMetric struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// grafanaQueryRequest is the part of a /query request we use
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64 `json:"intervalMs"`
+	MaxDataPoints int64 `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+// grafanaTimeSeries is one series of the /query response; datapoints are [value, unix ms]
+type grafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// grafanaHealth answers the datasource's connection test
+func (h *Handler) grafanaHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// grafanaMetrics lists the chartable series
+func (h *Handler) grafanaMetrics(w http.ResponseWriter, r *http.Request) {
+	var metrics []grafanaMetric
+	for _, ticker := range h.tickers {
+		for _, series := range grafanaSeriesList {
+			metrics = append(metrics, grafanaMetric{Label: ticker + " " + series.label, Value: ticker + "." + series.name})
+		}
+	}
+	writeJSON(w, metrics)
+}
+
+// grafanaSearch lists the series matching the typed text (older datasource versions)
+func (h *Handler) grafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	json.NewDecoder(r.Body).Decode(&req) // An empty body lists everything
+
+	targets := []string{}
+	for _, ticker := range h.tickers {
+		for _, series := range grafanaSeriesList {
+			if target := ticker + "." + series.name; strings.Contains(strings.ToUpper(target), strings.ToUpper(req.Target)) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	writeJSON(w, targets)
+}
+
+// grafanaQuery returns one time series per target, aggregated to Grafana's interval
+func (h *Handler) grafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	from, to := req.Range.From, req.Range.To
+	if !from.Before(to) {
+		http.Error(w, "range from must be before to", http.StatusBadRequest)
+		return
+	}
+	interval := grafanaInterval(to.Sub(from), req.IntervalMs, req.MaxDataPoints)
+
+	result := []grafanaTimeSeries{}
+	for _, target := range req.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		ticker, name, _ := strings.Cut(target.Target, ".")
+		i := slices.IndexFunc(grafanaSeriesList, func(s grafanaSeries) bool { return s.name == name })
+		if i < 0 || !slices.Contains(h.tickers, ticker) {
+			http.Error(w, fmt.Sprintf("unknown target %q", target.Target), http.StatusBadRequest)
+			return
+		}
+
+		series := grafanaTimeSeries{Target: target.Target, Datapoints: [][2]float64{}}
+		err := readIntervals(r.Context(), h.reader, ticker, from, to, interval, func(s *domain.Snapshot) error {
+			value := grafanaSeriesList[i].value(s, interval)
+			series.Datapoints = append(series.Datapoints, [2]float64{value, float64(s.Timestamp.UnixMilli())})
+			return nil
+		})
+		if err != nil {
+			log.Printf("API: Failed to read %s for Grafana: %v", ticker, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, series)
+	}
+	writeJSON(w, result)
+}
+
+// grafanaInterval picks the aggregation interval: Grafana's, widened so the range fits
+// maxDataPoints, rounded up to whole seconds
+func grafanaInterval(span time.Duration, intervalMs, maxDataPoints int64) time.Duration {
+	interval := time.Duration(intervalMs) * time.Millisecond
+	if maxDataPoints > 0 {
+		interval = max(interval, span/time.Duration(maxDataPoints))
+	}
+	return max((interval + time.Second - 1).Truncate(time.Second), time.Second)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("API: Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, h http.Handler, url, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, url, strings.NewReader(body)))
+	return rec
+}
+
+func TestGrafana_Search(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"USDJPY", "EURUSD"}, "")
+
+	if rec := get(t, h, "/grafana/"); rec.Code != http.StatusOK {
+		t.Errorf("Expected health check 200, got %d", rec.Code)
+	}
+
+	var targets []string
+	rec := post(t, h, "/grafana/search", `{"target":"eurusd.max"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &targets); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rec.Body)
+	}
+	if len(targets) != 1 || targets[0] != "EURUSD.max_spread" {
+		t.Errorf("Unexpected search result: %v", targets)
+	}
+
+	var metrics []grafanaMetric
+	rec = post(t, h, "/grafana/metrics", `{}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rec.Body)
+	}
+	if len(metrics) != 8 || metrics[0].Value != "EURUSD.spread" {
+		t.Errorf("Unexpected metrics: %v", metrics)
+	}
+}
+
+func TestGrafana_Query(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"EURUSD"}, "")
+
+	rec := post(t, h, "/grafana/query", `{
+		"range": {"from": "2025-11-18T12:00:00Z", "to": "2025-11-18T13:00:00Z"},
+		"intervalMs": 60000,
+		"maxDataPoints": 6,
+		"targets": [{"target": "EURUSD.max_spread"}, {"target": "EURUSD.tick_rate"}, {"target": "EURUSD.spread", "hide": true}]
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var series []grafanaTimeSeries
+	if err := json.Unmarshal(rec.Body.Bytes(), &series); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rec.Body)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(series))
+	}
+
+	// maxDataPoints widens the 1m interval to 10m: 6 points of 30 ticks
+	maxSpread, tickRate := series[0].Datapoints, series[1].Datapoints
+	if len(maxSpread) != 6 || len(tickRate) != 6 {
+		t.Fatalf("Expected 6 datapoints per series, got %d and %d", len(maxSpread), len(tickRate))
+	}
+	start := time.Date(2025, 11, 18, 12, 10, 0, 0, time.UTC)
+	if maxSpread[1][1] != float64(start.UnixMilli()) {
+		t.Errorf("Expected second point at %v, got %v", start, maxSpread[1][1])
+	}
+	if maxSpread[0][0] < 0.0000299 || maxSpread[0][0] > 0.0000301 {
+		t.Errorf("Expected max spread 0.00003, got %v", maxSpread[0][0])
+	}
+	if tickRate[0][0] != 0.05 {
+		t.Errorf("Expected 0.05 ticks/s, got %v", tickRate[0][0])
+	}
+}
+
+func TestGrafana_QueryErrors(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"EURUSD"}, "")
+
+	for _, body := range []string{
+		`not json`,
+		`{"range": {"from": "2025-11-18T13:00:00Z", "to": "2025-11-18T12:00:00Z"}}`,
+		`{"range": {"from": "2025-11-18T12:00:00Z", "to": "2025-11-18T13:00:00Z"}, "targets": [{"target": "GBPUSD.spread"}]}`,
+		`{"range": {"from": "2025-11-18T12:00:00Z", "to": "2025-11-18T13:00:00Z"}, "targets": [{"target": "EURUSD.mid"}]}`,
+	} {
+		if rec := post(t, h, "/grafana/query", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestGrafanaInterval(t *testing.T) {
+	tests := []struct {
+		span          time.Duration
+		intervalMs    int64
+		maxDataPoints int64
+		want          time.Duration
+	}{
+		{time.Hour, 60_000, 1000, time.Minute},
+		{24 * time.Hour, 20_000, 1000, 87 * time.Second}, // 86.4s rounded up
+		{time.Minute, 100, 0, time.Second},
+	}
+	for _, tt := range tests {
+		if got := grafanaInterval(tt.span, tt.intervalMs, tt.maxDataPoints); got != tt.want {
+			t.Errorf("grafanaInterval(%v, %d, %d) = %v, want %v", tt.span, tt.intervalMs, tt.maxDataPoints, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+const (
+	defaultLimit = 100_000   // Rows per response unless ?limit= is given
+	maxLimit     = 1_000_000 // Upper bound for ?limit=
+)
+
+// errLimitReached stops reading once a response has its maximum number of rows
+var errLimitReached = errors.New("row limit reached")
+
+// Handler serves recorded spreads read back from storage:
+//
+//	GET /api/v1/spreads           recorded instruments
+//	GET /api/v1/spreads/{ticker}  ticks or aggregated intervals
+//	/grafana/...                  Grafana JSON datasource (see grafana.go)
+//
+// The ticker endpoint takes from and to (RFC3339 or YYYY-MM-DD, default the last hour),
+// resolution (raw or an interval like 1m), format (json or csv) and limit (rows)
+type Handler struct {
+	mux     *http.ServeMux
+	reader  ports.SpreadReader
+	tickers []string
+	token   string
+	now     func() time.Time
+}
+
+// NewHandler creates the read API for tickers; a non-empty token is required as "Authorization: Bearer <token>"
+func NewHandler(reader ports.SpreadReader, tickers []string, token string) *Handler {
+	h := &Handler{
+		mux:     http.NewServeMux(),
+		reader:  reader,
+		tickers: slices.Sorted(slices.Values(tickers)),
+		token:   token,
+		now:     time.Now,
+	}
+	h.mux.HandleFunc("GET /api/v1/spreads", h.instruments)
+	h.mux.HandleFunc("GET /api/v1/spreads/{ticker}", h.spreads)
+	h.mux.HandleFunc("GET /grafana/{$}", h.grafanaHealth)
+	h.mux.HandleFunc("POST /grafana/metrics", h.grafanaMetrics)
+	h.mux.HandleFunc("POST /grafana/search", h.grafanaSearch)
+	h.mux.HandleFunc("POST /grafana/query", h.grafanaQuery)
+	return h
+}
+
+// ServeHTTP checks the token and dispatches the request
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" {
+		want := "Bearer " + h.token
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) instruments(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string][]string{"tickers": h.tickers})
+}
+
+// spreadQuery is a parsed /api/v1/spreads/{ticker} request
+type spreadQuery struct {
+	ticker     string
+	from, to   time.Time
+	resolution time.Duration // 0 = raw ticks
+	format     string
+	limit      int
+}
+
+func (h *Handler) spreads(w http.ResponseWriter, r *http.Request) {
+	query, err := h.parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(h.tickers, query.ticker) {
+		http.Error(w, fmt.Sprintf("%v: %s", domain.ErrInstrumentNotFound, query.ticker), http.StatusNotFound)
+		return
+	}
+
+	var out rowWriter
+	if query.format == "csv" {
+		out = newCSVRows(w, query.resolution > 0)
+	} else {
+		out = newJSONRows(w, query)
+	}
+
+	rows := 0
+	emit := func(row any) error {
+		if rows == query.limit {
+			return errLimitReached
+		}
+		rows++
+		return out.write(row)
+	}
+
+	if query.resolution == 0 {
+		err = h.reader.ReadSpreads(r.Context(), query.ticker, query.from, query.to, func(data *domain.PriceData) error {
+			return emit(data)
+		})
+	} else {
+		err = readIntervals(r.Context(), h.reader, query.ticker, query.from, query.to, query.resolution, func(s *domain.Snapshot) error {
+			return emit(s)
+		})
+	}
+
+	truncated := errors.Is(err, errLimitReached)
+	if err != nil && !truncated {
+		// Headers are usually sent already; an incomplete body is all that can signal the failure
+		log.Printf("API: Failed to read %s: %v", query.ticker, err)
+		if !out.started() {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := out.close(truncated); err != nil {
+		log.Printf("API: Failed to write response: %v", err)
+	}
+}
+
+// readIntervals aggregates the ticks of ticker into intervals of resolution, skipping intervals without ticks
+func readIntervals(ctx context.Context, reader ports.SpreadReader, ticker string, from, to time.Time, resolution time.Duration, fn func(*domain.Snapshot) error) error {
+	var bucket *domain.Snapshot
+	err := reader.ReadSpreads(ctx, ticker, from, to, func(data *domain.PriceData) error {
+		start := data.Timestamp.UTC().Truncate(resolution)
+		if bucket != nil && bucket.Timestamp.Equal(start) {
+			bucket.Add(data)
+			return nil
+		}
+		if bucket != nil {
+			if err := fn(bucket); err != nil {
+				return err
+			}
+		}
+		bucket = domain.NewSnapshot(start, data)
+		return nil
+	})
+	if err != nil || bucket == nil {
+		return err
+	}
+	return fn(bucket)
+}
+
+// parseQuery reads the ticker and query parameters
+func (h *Handler) parseQuery(r *http.Request) (spreadQuery, error) {
+	params := r.URL.Query()
+	query := spreadQuery{
+		ticker: r.PathValue("ticker"),
+		format: params.Get("format"),
+		limit:  defaultLimit,
+	}
+
+	var err error
+	if query.to, err = parseTime(params.Get("to"), h.now()); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+	if query.from, err = parseTime(params.Get("from"), query.to.Add(-time.Hour)); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if !query.from.Before(query.to) {
+		return query, fmt.Errorf("from must be before to")
+	}
+
+	switch resolution := params.Get("resolution"); resolution {
+	case "", "raw":
+	default:
+		if query.resolution, err = time.ParseDuration(resolution); err != nil {
+			return query, fmt.Errorf("invalid resolution: %w", err)
+		}
+		if query.resolution < time.Second {
+			return query, fmt.Errorf("resolution must be at least 1s, got %v", query.resolution)
+		}
+	}
+
+	switch query.format {
+	case "":
+		query.format = "json"
+	case "json", "csv":
+	default:
+		return query, fmt.Errorf("unknown format %q (supported: json, csv)", query.format)
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		if query.limit, err = strconv.Atoi(limit); err != nil || query.limit < 1 || query.limit > maxLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+	return query, nil
+}
+
+// parseTime parses an RFC3339 timestamp or a YYYY-MM-DD day (UTC); empty returns def
+func parseTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// rowWriter streams ticks (*domain.PriceData) or intervals (*domain.Snapshot) as they are read
+type rowWriter interface {
+	write(row any) error
+	started() bool
+	close(truncated bool) error
+}
+
+// jsonRows writes {"ticker":..., "rows":[...], "truncated":...} one row at a time
+type jsonRows struct {
+	w      http.ResponseWriter
+	header []byte
+	rows   int
+}
+
+func newJSONRows(w http.ResponseWriter, query spreadQuery) *jsonRows {
+	resolution := "raw"
+	if query.resolution > 0 {
+		resolution = query.resolution.String()
+	}
+	header, _ := json.Marshal(map[string]any{
+		"ticker":     query.ticker,
+		"from":       query.from.UTC(),
+		"to":         query.to.UTC(),
+		"resolution": resolution,
+	})
+	// Reopen the object to append the rows array
+	return &jsonRows{w: w, header: append(header[:len(header)-1], `,"rows":[`...)}
+}
+
+func (j *jsonRows) begin() error {
+	if j.header == nil {
+		return nil
+	}
+	j.w.Header().Set("Content-Type", "application/json")
+	_, err := j.w.Write(j.header)
+	j.header = nil
+	return err
+}
+
+func (j *jsonRows) write(row any) error {
+	if err := j.begin(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if j.rows > 0 {
+		data = append([]byte{','}, data...)
+	}
+	j.rows++
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonRows) started() bool {
+	return j.header == nil
+}
+
+func (j *jsonRows) close(truncated bool) error {
+	if err := j.begin(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(j.w, "],\"truncated\":%t}\n", truncated)
+	return err
+}
+
+// csvRows writes a header and one line per row; truncation is reported in the X-Truncated trailer
+type csvRows struct {
+	w         http.ResponseWriter
+	writer    *csv.Writer
+	intervals bool
+	begun     bool
+}
+
+func newCSVRows(w http.ResponseWriter, intervals bool) *csvRows {
+	return &csvRows{w: w, writer: csv.NewWriter(w), intervals: intervals}
+}
+
+func (c *csvRows) begin() error {
+	if c.begun {
+		return nil
+	}
+	c.begun = true
+	c.w.Header().Set("Content-Type", "text/csv")
+	c.w.Header().Set("Trailer", "X-Truncated")
+
+	header := []string{"timestamp", "bid", "ask", "spread", "seq", "flags"}
+	if c.intervals {
+		header = []string{"timestamp", "bid", "ask", "min_spread", "max_spread", "ticks"}
+	}
+	return c.writer.Write(header)
+}
+
+func (c *csvRows) write(row any) error {
+	if err := c.begin(); err != nil {
+		return err
+	}
+
+	var record []string
+	switch row := row.(type) {
+	case *domain.PriceData:
+		record = []string{
+			row.Timestamp.UTC().Format(time.RFC3339Nano),
+			formatPrice(row.Bid, row.Decimals),
+			formatPrice(row.Ask, row.Decimals),
+			formatPrice(row.Spread, row.Decimals),
+			strconv.FormatUint(row.Sequence, 10),
+			strconv.FormatUint(uint64(row.Flags), 10),
+		}
+	case *domain.Snapshot:
+		record = []string{
+			row.Timestamp.Format(time.RFC3339Nano),
+			formatPrice(row.Bid, row.Decimals),
+			formatPrice(row.Ask, row.Decimals),
+			formatPrice(row.MinSpread, row.Decimals),
+			formatPrice(row.MaxSpread, row.Decimals),
+			strconv.Itoa(row.Ticks),
+		}
+	default:
+		return fmt.Errorf("unexpected row type %T", row)
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvRows) started() bool {
+	return c.begun
+}
+
+func (c *csvRows) close(truncated bool) error {
+	if err := c.begin(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	c.w.Header().Set("X-Truncated", strconv.FormatBool(truncated))
+	return c.writer.Error()
+}
+
+// formatPrice writes a price with the precision it was recorded with
+func formatPrice(value float64, decimals int) string {
+	if decimals <= 0 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
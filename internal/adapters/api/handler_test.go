@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// fakeReader serves one EURUSD tick every 20 seconds from 12:00 to 12:59:40
+type fakeReader struct{}
+
+func (fakeReader) ReadSpreads(ctx context.Context, ticker string, from, to time.Time, fn func(*domain.PriceData) error) error {
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	for i := range 180 {
+		data := &domain.PriceData{
+			Timestamp: start.Add(time.Duration(i) * 20 * time.Second),
+			Ticker:    ticker,
+			Bid:       1.1,
+			Ask:       1.1 + float64(i%3+1)*0.00001,
+			Decimals:  5,
+			Sequence:  uint64(i + 1),
+		}
+		data.CalculateSpread()
+		if data.Timestamp.Before(from) || !data.Timestamp.Before(to) {
+			continue
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spreadResponse is the JSON envelope with the rows left undecoded
+type spreadResponse struct {
+	Ticker     string            `json:"ticker"`
+	Resolution string            `json:"resolution"`
+	Rows       []json.RawMessage `json:"rows"`
+	Truncated  bool              `json:"truncated"`
+}
+
+func get(t *testing.T, h http.Handler, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	return rec
+}
+
+func TestHandler_RawTicks(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"EURUSD"}, "")
+
+	rec := get(t, h, "/api/v1/spreads/EURUSD?from=2025-11-18T12:00:00Z&to=2025-11-18T12:01:00Z")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp spreadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rec.Body)
+	}
+	if resp.Ticker != "EURUSD" || resp.Resolution != "raw" || len(resp.Rows) != 3 || resp.Truncated {
+		t.Errorf("Unexpected response: %s", rec.Body)
+	}
+
+	var tick domain.PriceData
+	if err := json.Unmarshal(resp.Rows[1], &tick); err != nil || tick.Sequence != 2 {
+		t.Errorf("Unexpected second row %s (%v)", resp.Rows[1], err)
+	}
+}
+
+func TestHandler_Resolution(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"EURUSD"}, "")
+
+	rec := get(t, h, "/api/v1/spreads/EURUSD?from=2025-11-18&to=2025-11-19&resolution=10m&format=csv")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected header + 6 intervals, got:\n%s", rec.Body)
+	}
+	if lines[0] != "timestamp,bid,ask,min_spread,max_spread,ticks" {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if lines[1] != "2025-11-18T12:00:00Z,1.10000,1.10003,0.00001,0.00003,30" {
+		t.Errorf("Unexpected first interval: %s", lines[1])
+	}
+	if got := rec.Result().Trailer.Get("X-Truncated"); got != "false" {
+		t.Errorf("Expected X-Truncated trailer false, got %q", got)
+	}
+}
+
+func TestHandler_Limit(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"EURUSD"}, "")
+
+	rec := get(t, h, "/api/v1/spreads/EURUSD?from=2025-11-18&to=2025-11-19&limit=5")
+	var resp spreadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rec.Body)
+	}
+	if len(resp.Rows) != 5 || !resp.Truncated {
+		t.Errorf("Expected 5 rows and truncated, got %d rows, truncated=%v", len(resp.Rows), resp.Truncated)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	h := NewHandler(fakeReader{}, []string{"EURUSD"}, "secret")
+
+	tests := []struct {
+		url  string
+		auth bool
+		want int
+	}{
+		{"/api/v1/spreads", false, http.StatusUnauthorized},
+		{"/api/v1/spreads", true, http.StatusOK},
+		{"/api/v1/spreads/GBPUSD", true, http.StatusNotFound},
+		{"/api/v1/spreads/EURUSD?from=yesterday", true, http.StatusBadRequest},
+		{"/api/v1/spreads/EURUSD?from=2025-11-19&to=2025-11-18", true, http.StatusBadRequest},
+		{"/api/v1/spreads/EURUSD?resolution=100ms", true, http.StatusBadRequest},
+		{"/api/v1/spreads/EURUSD?format=xml", true, http.StatusBadRequest},
+		{"/api/v1/spreads/EURUSD?limit=0", true, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.auth {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.url, tt.want, rec.Code)
+		}
+	}
+}
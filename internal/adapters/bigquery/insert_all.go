@@ -0,0 +1,182 @@
+package bigquery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// insertAllEndpoint is the BigQuery REST API; streaming inserts go to tabledata.insertAll
+const insertAllEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+// insertBatchSize is the number of rows per insertAll request (BigQuery recommends about 500)
+const insertBatchSize = 500
+
+// tokenLifetime is how long an access token from gcloud is reused; they are valid for an hour
+const tokenLifetime = 30 * time.Minute
+
+// streamer sends staged rows with tabledata.insertAll, each with an insertId derived from the
+// row's natural key: BigQuery drops rows whose insertId it has seen in the last minutes, so a
+// file sent again after a crash between the insert and its removal doesn't duplicate rows
+// The bq CLI can't pass insertIds, so the request is made directly with a token from gcloud
+type streamer struct {
+	endpoint string // REST API base URL
+	project  string
+	gcloud   string // Path to the gcloud CLI
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newStreamer resolves the project (the gcloud default when empty) and checks that gcloud can issue tokens
+func newStreamer(project, gcloudPath string) (*streamer, error) {
+	gcloud, err := exec.LookPath(gcloudPath)
+	if err != nil {
+		return nil, fmt.Errorf("gcloud CLI not found (needed for streaming inserts, set gcloud=): %w", err)
+	}
+	if project == "" {
+		out, err := exec.Command(gcloud, "config", "get-value", "project").Output()
+		if project = strings.TrimSpace(string(out)); err != nil || project == "" {
+			return nil, fmt.Errorf("BigQuery project is required for streaming inserts (no gcloud default project)")
+		}
+	}
+	s := &streamer{endpoint: insertAllEndpoint, project: project, gcloud: gcloud, client: &http.Client{Timeout: time.Minute}}
+	if _, err := s.accessToken(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// accessToken returns a cached token, asking gcloud for a new one when it is due
+func (s *streamer) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+	out, err := exec.CommandContext(ctx, s.gcloud, "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get an access token from gcloud: %w", err)
+	}
+	s.token, s.expires = strings.TrimSpace(string(out)), time.Now().Add(tokenLifetime)
+	return s.token, nil
+}
+
+// insertRow is one row of an insertAll request
+type insertRow struct {
+	InsertID string          `json:"insertId"`
+	JSON     json.RawMessage `json:"json"`
+}
+
+// insertFile streams the rows of a staging file into dataset.table in batches
+func (s *streamer) insertFile(ctx context.Context, dataset, table, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open staging file: %w", err)
+	}
+	defer f.Close()
+
+	var rows []insertRow
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := bytes.Clone(scanner.Bytes())
+		rows = append(rows, insertRow{InsertID: insertID(line), JSON: line})
+		if len(rows) == insertBatchSize {
+			if err := s.insert(ctx, dataset, table, rows); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read staging file: %w", err)
+	}
+	if len(rows) > 0 {
+		return s.insert(ctx, dataset, table, rows)
+	}
+	return nil
+}
+
+// insert sends one insertAll request; any rejected row fails it
+func (s *streamer) insert(ctx context.Context, dataset, table string, rows []insertRow) error {
+	body, err := json.Marshal(map[string]any{"rows": rows})
+	if err != nil {
+		return fmt.Errorf("failed to encode rows: %w", err)
+	}
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll",
+		s.endpoint, url.PathEscape(s.project), url.PathEscape(dataset), url.PathEscape(table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("insertAll request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		InsertErrors []json.RawMessage `json:"insertErrors"`
+		Error        struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized {
+			s.mu.Lock()
+			s.token = ""
+			s.mu.Unlock()
+		}
+		return fmt.Errorf("insertAll returned %s: %s", resp.Status, result.Error.Message)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("invalid insertAll response: %w", decodeErr)
+	}
+	if len(result.InsertErrors) > 0 {
+		return fmt.Errorf("insertAll rejected %d of %d rows, first: %s", len(result.InsertErrors), len(rows), result.InsertErrors[0])
+	}
+	return nil
+}
+
+// insertID derives a row's insertId from its natural key (see domain.TickKey): ticker, timestamp
+// and sequence number, or bid and ask for rows without one (imports, backfill and bars)
+func insertID(row []byte) string {
+	var key struct {
+		Ticker    string          `json:"ticker"`
+		Timestamp string          `json:"timestamp"`
+		Seq       uint64          `json:"seq"`
+		Bid       json.RawMessage `json:"bid"`
+		Ask       json.RawMessage `json:"ask"`
+	}
+	if err := json.Unmarshal(row, &key); err != nil {
+		sum := sha256.Sum256(row)
+		return hex.EncodeToString(sum[:16])
+	}
+	id := fmt.Sprintf("%s|%s|%d", key.Ticker, key.Timestamp, key.Seq)
+	if key.Seq == 0 {
+		id += "|" + string(key.Bid) + "|" + string(key.Ask)
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
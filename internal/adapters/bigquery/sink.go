@@ -0,0 +1,615 @@
+// Package bigquery implements a sink loading ticks (and snapshot bars) into BigQuery tables
+// It shells out to the bq CLI of the Google Cloud SDK, which brings its own authentication
+// (gcloud auth, service account keys, workload identity), so the collector needs no client library
+package bigquery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// Load modes
+const (
+	ModeLoad   = "load"   // Load jobs (bq load): free, but limited to 1,500 per table and day
+	ModeStream = "stream" // Streaming inserts (insertAll): rows queryable within seconds, billed per GB
+)
+
+// tickSchema and barSchema are the table schemas in bq's inline form
+const (
+	tickSchema = "timestamp:TIMESTAMP,uic:INTEGER,ticker:STRING,asset_type:STRING,bid:FLOAT,ask:FLOAT,spread:FLOAT," +
+		"decimals:INTEGER,seq:INTEGER,session:STRING,flags:INTEGER,effective_spread:FLOAT,spread_z:FLOAT,source:STRING"
+	barSchema = "timestamp:TIMESTAMP,uic:INTEGER,ticker:STRING,asset_type:STRING,bid:FLOAT,ask:FLOAT," +
+		"min_spread:FLOAT,max_spread:FLOAT,ticks:INTEGER,decimals:INTEGER"
+)
+
+// bigQueryTime is the timestamp layout of the JSON rows; BigQuery keeps microseconds
+const bigQueryTime = "2006-01-02T15:04:05.000000Z"
+
+// tableRef matches [project:]dataset.table
+var tableRef = regexp.MustCompile(`^([a-z][a-z0-9-]*:)?\w+\.\w+$`)
+
+// Config holds the BigQuery sink settings
+type Config struct {
+	Project      string        // Project of the tables (empty: the bq default project)
+	Dataset      string        // Dataset of the tables
+	Table        string        // Tick table, e.g. ticks
+	BarsTable    string        // Snapshot bar table (empty: bars are not loaded)
+	Mode         string        // load or stream
+	Interval     time.Duration // How often staged rows are loaded
+	Partitioning string        // Time partitioning of new tables: HOUR, DAY, MONTH or YEAR
+	StagingDir   string        // Where rows wait for the next load
+	BQPath       string        // Path to the bq CLI
+	GcloudPath   string        // Path to the gcloud CLI, which issues the tokens of streaming inserts
+}
+
+// Validate checks the sink configuration
+func (c Config) Validate() error {
+	if c.Dataset == "" || c.Table == "" {
+		return fmt.Errorf("BigQuery dataset and table are required")
+	}
+	for _, table := range []string{c.Table, c.BarsTable} {
+		if table != "" && !tableRef.MatchString(c.ref(table)) {
+			return fmt.Errorf("invalid BigQuery table %q", c.ref(table))
+		}
+	}
+	if c.Mode != ModeLoad && c.Mode != ModeStream {
+		return fmt.Errorf("invalid BigQuery mode %q (must be load or stream)", c.Mode)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("BigQuery load interval must be positive")
+	}
+	if !slices.Contains([]string{"HOUR", "DAY", "MONTH", "YEAR"}, c.Partitioning) {
+		return fmt.Errorf("invalid BigQuery partitioning %q (must be HOUR, DAY, MONTH or YEAR)", c.Partitioning)
+	}
+	return nil
+}
+
+// ref returns the bq reference of a table: [project:]dataset.table
+func (c Config) ref(table string) string {
+	ref := c.Dataset + "." + table
+	if c.Project != "" {
+		ref = c.Project + ":" + ref
+	}
+	return ref
+}
+
+// ConfigFromSpec reads a sink configuration from recorder parameters:
+// bigquery?project=..&dataset=fx&table=ticks&bars_table=bars&mode=load&interval=5m&partition=DAY&staging=data/bigquery&bq=bq&gcloud=gcloud
+func ConfigFromSpec(spec storage.RecorderSpec) (Config, error) {
+	mode := strings.ToLower(spec.Param("mode", ModeLoad))
+	defaultInterval := "5m"
+	if mode == ModeStream {
+		defaultInterval = "10s"
+	}
+	interval, err := time.ParseDuration(spec.Param("interval", defaultInterval))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BigQuery interval: %w", err)
+	}
+
+	return Config{
+		Project:      spec.Param("project", ""),
+		Dataset:      spec.Param("dataset", ""),
+		Table:        spec.Param("table", "ticks"),
+		BarsTable:    spec.Param("bars_table", ""),
+		Mode:         mode,
+		Interval:     interval,
+		Partitioning: strings.ToUpper(spec.Param("partition", "DAY")),
+		StagingDir:   spec.Param("staging", "data/bigquery"),
+		BQPath:       spec.Param("bq", "bq"),
+		GcloudPath:   spec.Param("gcloud", "gcloud"),
+	}, nil
+}
+
+func init() {
+	storage.RegisterRecorder("bigquery", func(spec storage.RecorderSpec) (ports.TickWriter, error) {
+		config, err := ConfigFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewSink(config)
+	})
+}
+
+// Sink implements TickWriter by staging ticks as JSON rows in local files and loading them into
+// a time-partitioned table every interval
+// Staged files survive failed loads and restarts, so rows are only lost with the staging directory
+type Sink struct {
+	config   Config
+	bq       string
+	streamer *streamer // Streaming inserts; nil in load mode
+	ticks    *table
+	bars     *table // nil without a bars table
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSink creates the tables if needed, loads rows left staged by a previous run and starts the load loop
+func NewSink(config Config) (*Sink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	bq, err := exec.LookPath(config.BQPath)
+	if err != nil {
+		return nil, fmt.Errorf("bq CLI not found (install the Google Cloud SDK or set bq=): %w", err)
+	}
+
+	s := &Sink{config: config, bq: bq, stop: make(chan struct{}), done: make(chan struct{})}
+	if config.Mode == ModeStream {
+		if s.streamer, err = newStreamer(config.Project, config.GcloudPath); err != nil {
+			return nil, err
+		}
+	}
+	s.ticks, err = s.newTable(config.Table, tickSchema)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.newUniqueView(config.Table); err != nil {
+		return nil, err
+	}
+	if config.BarsTable != "" {
+		if s.bars, err = s.newTable(config.BarsTable, barSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	go s.loadLoop()
+	return s, nil
+}
+
+// newTable creates a table (partitioned by timestamp, clustered by ticker) unless it exists
+func (s *Sink) newTable(name, schema string) (*table, error) {
+	ref := s.config.ref(name)
+	if err := exec.Command(s.bq, "show", "--format=none", ref).Run(); err != nil {
+		out, err := exec.Command(s.bq, "mk", "--table",
+			"--time_partitioning_field=timestamp",
+			"--time_partitioning_type="+s.config.Partitioning,
+			"--clustering_fields=ticker",
+			ref, schema).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create BigQuery table %s: %w: %s", ref, err, strings.TrimSpace(string(out)))
+		}
+		log.Printf("BigQuery: Created table %s", ref)
+	}
+
+	dir := filepath.Join(s.config.StagingDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory %s: %w", dir, err)
+	}
+	return &table{name: name, ref: ref, dir: dir}, nil
+}
+
+// newUniqueView creates the view <table>_unique unless it exists: the table's rows with one row
+// per natural key (see domain.TickKey), since neither load jobs nor insertIds across more than a
+// few minutes stop a tick replayed by a fallback or reconcile -repair from being appended twice
+func (s *Sink) newUniqueView(table string) error {
+	ref := s.config.ref(table + "_unique")
+	if err := exec.Command(s.bq, "show", "--format=none", ref).Run(); err == nil {
+		return nil
+	}
+	source := "`" + strings.Replace(s.config.ref(table), ":", ".", 1) + "`"
+	query := "SELECT * EXCEPT (row_number) FROM (SELECT *, ROW_NUMBER() OVER (" +
+		"PARTITION BY ticker, timestamp, seq, IF(seq = 0, FORMAT('%t/%t', bid, ask), '')) AS row_number " +
+		"FROM " + source + ") WHERE row_number = 1"
+	out, err := exec.Command(s.bq, "mk", "--use_legacy_sql=false", "--view", query, ref).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create BigQuery view %s: %w: %s", ref, err, strings.TrimSpace(string(out)))
+	}
+	log.Printf("BigQuery: Created view %s", ref)
+	return nil
+}
+
+// Record saves a single price data point
+func (s *Sink) Record(ctx context.Context, data *domain.PriceData) error {
+	return s.RecordBatch(ctx, []*domain.PriceData{data})
+}
+
+// RecordBatch stages price data points for the next load
+func (s *Sink) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	rows := make([]any, len(data))
+	for i, tick := range data {
+		rows[i] = newTickRow(tick)
+	}
+	return s.ticks.stage(rows)
+}
+
+// Bars returns the writer staging snapshot bars, or nil without a bars table
+// Its rows are loaded with the ticks; closing the sink loads them a last time
+func (s *Sink) Bars() ports.SnapshotWriter {
+	if s.bars == nil {
+		return nil
+	}
+	return barWriter{s.bars}
+}
+
+// Flush writes the staged rows to disk; loading happens on the sink's own interval
+func (s *Sink) Flush(ctx context.Context) error {
+	err := s.ticks.flush()
+	if s.bars != nil && err == nil {
+		err = s.bars.flush()
+	}
+	return err
+}
+
+// Close stops the load loop and loads the remaining rows
+// Rows that can't be loaded before ctx expires stay staged for the next start
+func (s *Sink) Close(ctx context.Context) error {
+	close(s.stop)
+	<-s.done
+	return s.loadAll(ctx)
+}
+
+// Prune deletes the rows of the tick and bar tables from the UTC days that ended before cutoff
+// (see ports.Pruner) with a DML DELETE; the dry run only counts them
+// Returns one entry per table with rows to delete, e.g. "fx.ticks: 41873 rows before 2025-11-02"
+func (s *Sink) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	day := cutoff.UTC().Truncate(24 * time.Hour)
+	tables := []*table{s.ticks}
+	if s.bars != nil {
+		tables = append(tables, s.bars)
+	}
+
+	var pruned []string
+	for _, t := range tables {
+		source := "`" + strings.Replace(t.ref, ":", ".", 1) + "`"
+		where := fmt.Sprintf(" WHERE timestamp < TIMESTAMP('%s')", day.Format(time.DateOnly))
+		out, err := s.query(ctx, "--format=json", "SELECT COUNT(*) AS n FROM "+source+where)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to count expired rows of %s: %w", t.ref, err)
+		}
+		var counts []struct {
+			N json.Number `json:"n"`
+		}
+		if err := json.Unmarshal(out, &counts); err != nil || len(counts) != 1 {
+			return pruned, fmt.Errorf("unexpected count of expired rows of %s: %s", t.ref, bytes.TrimSpace(out))
+		}
+		if counts[0].N.String() == "0" {
+			continue
+		}
+		if !dryRun {
+			if _, err := s.query(ctx, "--format=none", "DELETE FROM "+source+where); err != nil {
+				return pruned, fmt.Errorf("failed to delete expired rows of %s: %w", t.ref, err)
+			}
+		}
+		pruned = append(pruned, fmt.Sprintf("%s: %s rows before %s", t.ref, counts[0].N, day.Format(time.DateOnly)))
+	}
+	return pruned, nil
+}
+
+// query runs a standard SQL statement with bq and returns its output
+func (s *Sink) query(ctx context.Context, format, statement string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, s.bq, "query", "--use_legacy_sql=false", "--quiet", format, statement)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(append(out, exitErr.Stderr...)))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// loadLoop loads the staged rows every interval
+func (s *Sink) loadLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.loadAll(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("BigQuery: %v (rows stay staged)", err)
+			}
+		}
+	}
+}
+
+// loadAll seals the current staging files and loads every sealed file
+func (s *Sink) loadAll(ctx context.Context) error {
+	if err := s.load(ctx, s.ticks); err != nil {
+		return err
+	}
+	if s.bars != nil {
+		return s.load(ctx, s.bars)
+	}
+	return nil
+}
+
+// load loads the sealed files of a table in order, removing each one once it is in BigQuery
+func (s *Sink) load(ctx context.Context, t *table) error {
+	files, err := t.seal()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if s.streamer != nil {
+			if err := s.streamer.insertFile(ctx, s.config.Dataset, t.name, file); err != nil {
+				return fmt.Errorf("failed to insert %s into %s: %w", file, t.ref, err)
+			}
+		} else if err := s.loadFile(ctx, t, file); err != nil {
+			return err
+		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("failed to remove loaded file %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// maxLoadAttempts caps the load jobs submitted for one staging file whose earlier jobs failed
+const maxLoadAttempts = 5
+
+// loadFile loads one staging file with a load job whose ID derives from the file, so a file
+// loaded before but not removed (a crash, a failed Remove, ctx cancelled after the submit) isn't
+// loaded twice: BigQuery refuses the job ID as "Already Exists", and the earlier job's outcome
+// decides whether the file is loaded or needs a job with the next ID
+func (s *Sink) loadFile(ctx context.Context, t *table, file string) error {
+	base := loadJobID(t, file)
+	for attempt := range maxLoadAttempts {
+		jobID := base
+		if attempt > 0 {
+			jobID = fmt.Sprintf("%s_%d", base, attempt)
+		}
+		cmd := exec.CommandContext(ctx, s.bq, "--job_id="+jobID, "load", "--source_format=NEWLINE_DELIMITED_JSON", t.ref, file)
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		if !strings.Contains(string(out), "Already Exists") {
+			return fmt.Errorf("failed to load %s into %s: %w: %s", file, t.ref, err, strings.TrimSpace(string(out)))
+		}
+
+		failed, err := s.jobFailed(ctx, jobID)
+		if err != nil {
+			return fmt.Errorf("failed to check earlier load of %s into %s: %w", file, t.ref, err)
+		}
+		if !failed {
+			log.Printf("BigQuery: %s was already loaded into %s (job %s)", file, t.ref, jobID)
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to load %s into %s: %d load jobs failed", file, t.ref, maxLoadAttempts)
+}
+
+// jobFailed waits for a job to finish and reports whether it failed
+func (s *Sink) jobFailed(ctx context.Context, jobID string) (bool, error) {
+	out, err := exec.CommandContext(ctx, s.bq, "--format=json", "wait", "--fail_on_error=false", jobID).Output()
+	if err != nil {
+		return false, err
+	}
+	var job struct {
+		Status struct {
+			ErrorResult *struct {
+				Message string `json:"message"`
+			} `json:"errorResult"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &job); err != nil {
+		return false, fmt.Errorf("unexpected job status: %s", bytes.TrimSpace(out))
+	}
+	if job.Status.ErrorResult != nil {
+		log.Printf("BigQuery: earlier load job %s failed: %s", jobID, job.Status.ErrorResult.Message)
+		return true, nil
+	}
+	return false, nil
+}
+
+// loadJobID names the load job of a staging file, e.g. fxcollector_fx_ticks_20251118T110000_000000000_1a2b3c4d
+// The hash of the file's absolute path keeps collectors that stage into other directories apart
+func loadJobID(t *table, file string) string {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		path = file
+	}
+	sum := sha256.Sum256([]byte(path))
+	stem := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return jobIDChars.ReplaceAllString("fxcollector_"+t.ref+"_"+stem, "_") + "_" + hex.EncodeToString(sum[:4])
+}
+
+// jobIDChars matches what a BigQuery job ID can't contain
+var jobIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// table stages the rows of one BigQuery table in NDJSON files
+// Rows go to an open file; seal closes it, so the next row opens a new one
+type table struct {
+	name string
+	ref  string
+	dir  string
+
+	mu     sync.Mutex
+	file   *os.File
+	buffer *bufio.Writer
+}
+
+// stage appends rows to the open staging file
+func (t *table) stage(rows []any) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		name := filepath.Join(t.dir, time.Now().UTC().Format("20060102T150405.000000000")+".ndjson")
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create staging file: %w", err)
+		}
+		t.file, t.buffer = file, bufio.NewWriter(file)
+	}
+
+	encoder := json.NewEncoder(t.buffer)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to stage row for %s: %w", t.ref, err)
+		}
+	}
+	return nil
+}
+
+// flush writes the buffered rows to the open staging file
+func (t *table) flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.buffer == nil {
+		return nil
+	}
+	if err := t.buffer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush staging file %s: %w", t.file.Name(), err)
+	}
+	return nil
+}
+
+// seal closes the open staging file and returns all complete staging files, oldest first
+// A file left by a crash may end in a partial row, which is cut off before loading
+func (t *table) seal() ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file != nil {
+		err := t.buffer.Flush()
+		if closeErr := t.file.Close(); err == nil {
+			err = closeErr
+		}
+		t.file, t.buffer = nil, nil
+		if err != nil {
+			return nil, fmt.Errorf("failed to close staging file: %w", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(t.dir, "*.ndjson"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		if err := trimPartialRow(file); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// trimPartialRow truncates a file after its last complete line
+func trimPartialRow(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read staging file: %w", err)
+	}
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+	end := strings.LastIndexByte(string(data), '\n') + 1
+	if err := os.Truncate(path, int64(end)); err != nil {
+		return fmt.Errorf("failed to repair staging file: %w", err)
+	}
+	return nil
+}
+
+// barWriter implements SnapshotWriter by staging bars in the bars table
+type barWriter struct {
+	table *table
+}
+
+// RecordSnapshots stages the snapshots of one or more completed intervals
+func (w barWriter) RecordSnapshots(ctx context.Context, snapshots []*domain.Snapshot) error {
+	rows := make([]any, len(snapshots))
+	for i, snapshot := range snapshots {
+		rows[i] = newBarRow(snapshot)
+	}
+	return w.table.stage(rows)
+}
+
+// tickRow is a tick in the columns of the tick table; spread is always in price units
+type tickRow struct {
+	Timestamp       string  `json:"timestamp"`
+	Uic             int     `json:"uic"`
+	Ticker          string  `json:"ticker"`
+	AssetType       string  `json:"asset_type"`
+	Bid             float64 `json:"bid"`
+	Ask             float64 `json:"ask"`
+	Spread          float64 `json:"spread"`
+	Decimals        int     `json:"decimals"`
+	Seq             uint64  `json:"seq"`
+	Session         string  `json:"session,omitempty"`
+	Flags           uint32  `json:"flags"`
+	EffectiveSpread float64 `json:"effective_spread,omitempty"`
+	SpreadZ         float64 `json:"spread_z,omitempty"`
+	Source          string  `json:"source,omitempty"`
+}
+
+func newTickRow(p *domain.PriceData) tickRow {
+	return tickRow{
+		Timestamp:       p.Timestamp.UTC().Format(bigQueryTime),
+		Uic:             p.Uic,
+		Ticker:          p.Ticker,
+		AssetType:       p.AssetType,
+		Bid:             p.Bid,
+		Ask:             p.Ask,
+		Spread:          p.Spread,
+		Decimals:        p.Decimals,
+		Seq:             p.Sequence,
+		Session:         p.SessionLabel,
+		Flags:           uint32(p.Flags),
+		EffectiveSpread: p.EffectiveSpread,
+		SpreadZ:         p.SpreadZ,
+		Source:          p.Source,
+	}
+}
+
+// barRow is a snapshot in the columns of the bars table
+type barRow struct {
+	Timestamp string  `json:"timestamp"`
+	Uic       int     `json:"uic"`
+	Ticker    string  `json:"ticker"`
+	AssetType string  `json:"asset_type"`
+	Bid       float64 `json:"bid"`
+	Ask       float64 `json:"ask"`
+	MinSpread float64 `json:"min_spread"`
+	MaxSpread float64 `json:"max_spread"`
+	Ticks     int     `json:"ticks"`
+	Decimals  int     `json:"decimals"`
+}
+
+func newBarRow(s *domain.Snapshot) barRow {
+	return barRow{
+		Timestamp: s.Timestamp.UTC().Format(bigQueryTime),
+		Uic:       s.Uic,
+		Ticker:    s.Ticker,
+		AssetType: s.AssetType,
+		Bid:       s.Bid,
+		Ask:       s.Ask,
+		MinSpread: s.MinSpread,
+		MaxSpread: s.MaxSpread,
+		Ticks:     s.Ticks,
+		Decimals:  s.Decimals,
+	}
+}
@@ -0,0 +1,252 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// fakeBQ writes a bq stand-in that logs its arguments and appends loaded files to <dir>/<table>.rows
+// "show" fails for tables and views that haven't been made yet; a load job ID is used only once
+func fakeBQ(t *testing.T, dir string) string {
+	t.Helper()
+	script := `#!/bin/sh
+echo "$@" >> "` + dir + `/calls"
+eval last=\${$#}
+case "$1" in
+--job_id=*)
+	job="` + dir + `/${1#--job_id=}.job"
+	if [ -f "$job" ]; then echo "BigQuery error in load operation: Already Exists: Job"; exit 1; fi
+	touch "$job"; shift ;;
+esac
+case "$1" in
+show) test -f "` + dir + `/$last.made" ;;
+mk) if [ "$2" = --table ]; then eval last=\${$(($# - 1))}; fi; touch "` + dir + `/$last.made" ;;
+load) cat "$4" >> "` + dir + `/$3.rows" ;;
+query) case "$last" in SELECT*) echo '[{"n":"42"}]' ;; esac ;;
+--format=json) echo '{"status":{"state":"DONE"}}' ;;
+esac
+`
+	path := filepath.Join(dir, "bq")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSink_StagesAndLoadsTicksAndBars(t *testing.T) {
+	dir := t.TempDir()
+	spec, err := storage.ParseRecorderSpec("bigquery?dataset=fx&bars_table=bars&interval=1h&staging=" + filepath.Join(dir, "staging") + "&bq=" + fakeBQ(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ConfigFromSpec(spec)
+	if err != nil {
+		t.Fatalf("ConfigFromSpec failed: %v", err)
+	}
+	sink, err := NewSink(config)
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Date(2025, 11, 18, 12, 0, 0, 123456789, time.UTC)
+	tick := &domain.PriceData{Timestamp: now, Uic: 21, Ticker: "EURUSD", AssetType: "FxSpot", Bid: 1.1, Ask: 1.1002, Spread: 0.0002, Sequence: 7}
+	if err := sink.Record(ctx, tick); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := sink.Bars().RecordSnapshots(ctx, []*domain.Snapshot{domain.NewSnapshot(now.Truncate(time.Minute), tick)}); err != nil {
+		t.Fatalf("RecordSnapshots failed: %v", err)
+	}
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	if !strings.Contains(string(calls), "mk --table --time_partitioning_field=timestamp --time_partitioning_type=DAY --clustering_fields=ticker fx.ticks timestamp:TIMESTAMP") {
+		t.Errorf("Tick table not created as expected:\n%s", calls)
+	}
+	if !strings.Contains(string(calls), "mk --use_legacy_sql=false --view SELECT * EXCEPT (row_number) FROM (") ||
+		!strings.Contains(string(calls), "FROM `fx.ticks`) WHERE row_number = 1 fx.ticks_unique") {
+		t.Errorf("Unique view not created as expected:\n%s", calls)
+	}
+	if !strings.Contains(string(calls), "load --source_format=NEWLINE_DELIMITED_JSON fx.bars ") {
+		t.Errorf("Bars not loaded:\n%s", calls)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "fx.ticks.rows"))
+	if err != nil {
+		t.Fatalf("No ticks loaded: %v", err)
+	}
+	var row map[string]any
+	if err := json.Unmarshal(data, &row); err != nil {
+		t.Fatalf("Row is not JSON: %v", err)
+	}
+	if row["timestamp"] != "2025-11-18T12:00:00.123456Z" || row["ticker"] != "EURUSD" || row["seq"] != 7.0 {
+		t.Errorf("Unexpected row: %v", row)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fx.bars.rows")); err != nil {
+		t.Errorf("No bars loaded: %v", err)
+	}
+
+	// Loaded files are removed from staging
+	staged, _ := filepath.Glob(filepath.Join(dir, "staging", "*", "*.ndjson"))
+	if len(staged) != 0 {
+		t.Errorf("Expected empty staging, got %v", staged)
+	}
+}
+
+func TestSink_DoesNotLoadAFileTwice(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging")
+	config := Config{Dataset: "fx", Table: "ticks", Mode: ModeLoad, Interval: time.Hour, Partitioning: "DAY", StagingDir: staging, BQPath: fakeBQ(t, dir)}
+	sink, err := NewSink(config)
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+	file := filepath.Join(staging, "ticks", "20251118T110000.000000000.ndjson")
+	if err := os.WriteFile(file, []byte("{\"ticker\":\"EURUSD\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first load succeeded but the file wasn't removed (a crash); it is staged again on restart
+	if err := sink.loadFile(context.Background(), sink.ticks, file); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "fx.ticks.rows"))
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Errorf("Expected the row loaded once, got %d rows", n)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Expected the already loaded file removed, got %v", err)
+	}
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	if !strings.Contains(string(calls), "--job_id=fxcollector_fx_ticks_20251118T110000_000000000_") {
+		t.Errorf("Expected a job ID from the file name:\n%s", calls)
+	}
+}
+
+func TestSink_KeepsRowsWhenLoadFails(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging")
+	// A crash left a staged file ending in a partial row
+	if err := os.MkdirAll(filepath.Join(staging, "ticks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(staging, "ticks", "20251118T110000.000000000.ndjson")
+	if err := os.WriteFile(leftover, []byte("{\"ticker\":\"EURUSD\"}\n{\"tick"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := filepath.Join(dir, "bq-failing")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\ncase \"$2\" in load) echo quota exceeded; exit 1 ;; esac\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{Dataset: "fx", Table: "ticks", Mode: ModeLoad, Interval: time.Hour, Partitioning: "DAY", StagingDir: staging, BQPath: failing}
+	sink, err := NewSink(config)
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+	if err := sink.Record(context.Background(), &domain.PriceData{Ticker: "USDJPY"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := sink.Close(context.Background()); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected the load error, got %v", err)
+	}
+
+	staged, _ := filepath.Glob(filepath.Join(staging, "ticks", "*.ndjson"))
+	if len(staged) != 2 {
+		t.Fatalf("Expected 2 staged files, got %v", staged)
+	}
+	data, _ := os.ReadFile(leftover)
+	if string(data) != "{\"ticker\":\"EURUSD\"}\n" {
+		t.Errorf("Partial row not cut off: %q", data)
+	}
+}
+
+func TestSink_StreamsWithInsertIDs(t *testing.T) {
+	dir := t.TempDir()
+	gcloud := filepath.Join(dir, "gcloud")
+	if err := os.WriteFile(gcloud, []byte("#!/bin/sh\ncase \"$1\" in config) echo my-project ;; auth) echo token-1 ;; esac\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var requests []map[string][]insertRow
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/my-project/datasets/fx/tables/ticks/insertAll" || r.Header.Get("Authorization") != "Bearer token-1" {
+			t.Errorf("Unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body map[string][]insertRow
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	config := Config{Dataset: "fx", Table: "ticks", Mode: ModeStream, Interval: time.Hour, Partitioning: "DAY",
+		StagingDir: filepath.Join(dir, "staging"), BQPath: fakeBQ(t, dir), GcloudPath: gcloud}
+	sink, err := NewSink(config)
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+	sink.streamer.endpoint = server.URL
+
+	ctx := context.Background()
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	ticks := []*domain.PriceData{
+		{Timestamp: now, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Sequence: 7},
+		{Timestamp: now, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Sequence: 7}, // Replayed
+		{Timestamp: now, Ticker: "EURUSD", Bid: 1.1001, Ask: 1.1003},           // Imported, no sequence
+	}
+	if err := sink.RecordBatch(ctx, ticks); err != nil {
+		t.Fatalf("RecordBatch failed: %v", err)
+	}
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(requests) != 1 || len(requests[0]["rows"]) != 3 {
+		t.Fatalf("Expected one request with 3 rows, got %+v", requests)
+	}
+	rows := requests[0]["rows"]
+	if rows[0].InsertID != rows[1].InsertID || rows[0].InsertID == rows[2].InsertID || rows[0].InsertID == "" {
+		t.Errorf("Expected equal insertIds for the same tick only, got %q %q %q", rows[0].InsertID, rows[1].InsertID, rows[2].InsertID)
+	}
+}
+
+func TestSink_PruneDeletesRowsOfExpiredDays(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{Project: "p", Dataset: "fx", Table: "ticks", Mode: ModeLoad, Interval: time.Hour, Partitioning: "DAY",
+		StagingDir: filepath.Join(dir, "staging"), BQPath: fakeBQ(t, dir)}
+	sink, err := NewSink(config)
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+	defer sink.Close(context.Background())
+
+	cutoff := time.Date(2025, 11, 2, 15, 0, 0, 0, time.UTC)
+	pruned, err := sink.Prune(context.Background(), cutoff, true)
+	if err != nil || len(pruned) != 1 || pruned[0] != "p:fx.ticks: 42 rows before 2025-11-02" {
+		t.Fatalf("Dry run: unexpected %v, %v", pruned, err)
+	}
+	if _, err := sink.Prune(context.Background(), cutoff, false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	if deletes := strings.Count(string(calls), "DELETE FROM `p.fx.ticks` WHERE timestamp < TIMESTAMP('2025-11-02')"); deletes != 1 {
+		t.Errorf("Expected one DELETE after the dry run, got %d in:\n%s", deletes, calls)
+	}
+}
@@ -0,0 +1,87 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+var (
+	testFrom = time.Date(2025, 11, 17, 0, 0, 0, 0, time.UTC)
+	testTo   = time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC)
+)
+
+func TestCSVCalendar_Events(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.csv")
+	content := "time,currency,impact,title\n" +
+		"2025-11-21T13:30:00Z,usd,High,Non-Farm Employment Change\n" +
+		"2025-11-19T09:00:00+01:00,EUR,medium,German ZEW Economic Sentiment\n" +
+		"2025-12-05T13:30:00Z,USD,high,Non-Farm Employment Change\n" // Outside range
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write calendar: %v", err)
+	}
+
+	events, err := NewCSVCalendar(path).Events(context.Background(), testFrom, testTo)
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Currency != "USD" || events[0].Impact != domain.ImpactHigh {
+		t.Errorf("Unexpected event: %+v", events[0])
+	}
+	if !events[1].Time.Equal(time.Date(2025, 11, 19, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected time converted to UTC, got %v", events[1].Time)
+	}
+}
+
+func TestHTTPCalendar_Events(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"title":"CPI m/m","country":"USD","date":"2025-11-18T08:30:00-05:00","impact":"High","forecast":"0.3%","previous":"0.4%"},
+			{"title":"Bank Holiday","country":"JPY","date":"2025-11-24T00:00:00+09:00","impact":"Holiday"}
+		]`))
+	}))
+	defer server.Close()
+
+	events, err := NewHTTPCalendar(server.URL).Events(context.Background(), testFrom, testTo)
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d: %+v", len(events), events)
+	}
+	if !events[0].Time.Equal(time.Date(2025, 11, 18, 13, 30, 0, 0, time.UTC)) || events[0].Impact != domain.ImpactHigh {
+		t.Errorf("Unexpected event: %+v", events[0])
+	}
+}
+
+func TestLoadHolidays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.csv")
+	content := "date,scope,name\n" +
+		"2025-12-25,*,Christmas Day\n" +
+		"2025-11-24,jpy,Labour Thanksgiving (observed)\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write holidays: %v", err)
+	}
+
+	holidays, err := LoadHolidays(path)
+	if err != nil {
+		t.Fatalf("LoadHolidays failed: %v", err)
+	}
+
+	h, ok := holidays.Holiday("USDJPY", time.Date(2025, 11, 24, 3, 0, 0, 0, time.UTC))
+	if !ok || h.Name != "Labour Thanksgiving (observed)" {
+		t.Errorf("Expected JPY holiday, got %+v (%v)", h, ok)
+	}
+	if holidays.IsOpen("EURUSD", time.Date(2025, 12, 25, 12, 0, 0, 0, time.UTC)) {
+		t.Error("Expected market closed on Christmas Day")
+	}
+}
@@ -0,0 +1,67 @@
+package calendar
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// CSVCalendar implements CalendarSource from a manually maintained or exported CSV file
+// Columns: time,currency,impact,title (time in RFC3339, impact low/medium/high)
+// The file is re-read on every call so edits are picked up without a restart
+type CSVCalendar struct {
+	path string
+}
+
+// NewCSVCalendar creates a calendar source reading path
+func NewCSVCalendar(path string) *CSVCalendar {
+	return &CSVCalendar{path: path}
+}
+
+// Events returns the events scheduled in [from, to)
+func (c *CSVCalendar) Events(ctx context.Context, from, to time.Time) ([]domain.CalendarEvent, error) {
+	file, err := os.Open(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open calendar %s: %w", c.path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar %s: %w", c.path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if strings.Join(records[0], ",") != "time,currency,impact,title" {
+		return nil, fmt.Errorf("calendar %s: expected header time,currency,impact,title", c.path)
+	}
+
+	var events []domain.CalendarEvent
+	for i, record := range records[1:] {
+		at, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s:%d: invalid time: %w", c.path, i+2, err)
+		}
+		impact, err := domain.ParseImpact(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s:%d: %w", c.path, i+2, err)
+		}
+		if at.Before(from) || !at.Before(to) {
+			continue
+		}
+
+		events = append(events, domain.CalendarEvent{
+			Time:     at.UTC(),
+			Currency: strings.ToUpper(strings.TrimSpace(record[1])),
+			Impact:   impact,
+			Title:    record[3],
+		})
+	}
+	return events, nil
+}
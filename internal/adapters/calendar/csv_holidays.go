@@ -0,0 +1,47 @@
+package calendar
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// LoadHolidays reads a holiday calendar CSV
+// Columns: date,scope,name (date as YYYY-MM-DD trading day; scope "*", a currency or a ticker)
+func LoadHolidays(path string) (*domain.HolidayCalendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open holidays %s: %w", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holidays %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return domain.NewHolidayCalendar(nil), nil
+	}
+	if strings.Join(records[0], ",") != "date,scope,name" {
+		return nil, fmt.Errorf("holidays %s: expected header date,scope,name", path)
+	}
+
+	var holidays []domain.Holiday
+	for i, record := range records[1:] {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("holidays %s:%d: invalid date: %w", path, i+2, err)
+		}
+		scope := strings.ToUpper(strings.TrimSpace(record[1]))
+		if scope == "" {
+			return nil, fmt.Errorf("holidays %s:%d: empty scope", path, i+2)
+		}
+
+		holidays = append(holidays, domain.Holiday{Date: date, Scope: scope, Name: record[2]})
+	}
+	return domain.NewHolidayCalendar(holidays), nil
+}
@@ -0,0 +1,84 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// ffEvent is one entry of a Forex Factory style weekly JSON feed
+type ffEvent struct {
+	Title   string `json:"title"`
+	Country string `json:"country"` // Currency code, e.g. USD
+	Date    string `json:"date"`    // RFC3339 with offset
+	Impact  string `json:"impact"`  // Low, Medium, High, Holiday
+}
+
+// HTTPCalendar implements CalendarSource from a JSON feed in the Forex Factory weekly export format
+// e.g. https://nfs.faireconomy.media/ff_calendar_thisweek.json
+type HTTPCalendar struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPCalendar creates a calendar source fetching url
+func NewHTTPCalendar(url string) *HTTPCalendar {
+	return &HTTPCalendar{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Events returns the events scheduled in [from, to)
+// Entries without a known impact (holidays, speeches without rating) are skipped
+func (c *HTTPCalendar) Events(ctx context.Context, from, to time.Time) ([]domain.CalendarEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("calendar returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var feed []ffEvent
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode calendar: %w", err)
+	}
+
+	var events []domain.CalendarEvent
+	for _, entry := range feed {
+		impact, err := domain.ParseImpact(entry.Impact)
+		if err != nil {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q for %s: %w", entry.Date, entry.Title, err)
+		}
+		if at.Before(from) || !at.Before(to) {
+			continue
+		}
+
+		events = append(events, domain.CalendarEvent{
+			Time:     at.UTC(),
+			Currency: strings.ToUpper(entry.Country),
+			Impact:   impact,
+			Title:    entry.Title,
+		})
+	}
+	return events, nil
+}
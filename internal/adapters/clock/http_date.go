@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPDateSource implements ClockSource with the Date header of an HTTP server, e.g. the broker's gateway
+// The header has one-second resolution, so measurements are uncertain by at least half a second
+type HTTPDateSource struct {
+	name   string
+	url    string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewHTTPDateSource creates a clock source reading the Date header of HEAD requests to url
+// Any status will do, so the URL needs no authentication
+func NewHTTPDateSource(name, url string) *HTTPDateSource {
+	return &HTTPDateSource{name: name, url: url, client: &http.Client{Timeout: 10 * time.Second}, now: time.Now}
+}
+
+// Name returns the source name given to NewHTTPDateSource
+func (s *HTTPDateSource) Name() string {
+	return s.name
+}
+
+// ClockOffset compares the Date header with the middle of the request
+// The server time is taken as the middle of the second the header names
+func (s *HTTPDateSource) ClockOffset(ctx context.Context) (time.Duration, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.url, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	sent := s.now()
+	resp, err := s.client.Do(req)
+	received := s.now()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to reach %s: %w", s.name, err)
+	}
+	resp.Body.Close()
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s sent no valid Date header: %w", s.name, err)
+	}
+	roundTrip := received.Sub(sent)
+	local := sent.Add(roundTrip / 2)
+	offset := date.Add(500 * time.Millisecond).Sub(local)
+	return offset, 500*time.Millisecond + roundTrip/2, nil
+}
@@ -0,0 +1,43 @@
+package clock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPDateSource_ClockOffset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected HEAD, got %s", r.Method)
+		}
+		w.Header().Set("Date", time.Now().Add(-10*time.Second).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusUnauthorized) // Any status carries the date
+	}))
+	defer server.Close()
+
+	source := NewHTTPDateSource("broker", server.URL)
+	offset, uncertainty, err := source.ClockOffset(context.Background())
+	if err != nil {
+		t.Fatalf("ClockOffset failed: %v", err)
+	}
+	if (offset + 10*time.Second).Abs() > uncertainty {
+		t.Errorf("Expected about -10s, got %v ± %v", offset, uncertainty)
+	}
+	if uncertainty < 500*time.Millisecond {
+		t.Errorf("Expected at least half a second of uncertainty, got %v", uncertainty)
+	}
+}
+
+func TestHTTPDateSource_NoDate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil // Suppress the header net/http adds
+	}))
+	defer server.Close()
+
+	if _, _, err := NewHTTPDateSource("broker", server.URL).ClockOffset(context.Background()); err == nil {
+		t.Error("Expected an error without a Date header")
+	}
+}
@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and the Unix epoch (1970)
+const ntpEpochOffset = 2208988800
+
+// NTPSource implements ClockSource with a single SNTP (RFC 4330) query per measurement
+type NTPSource struct {
+	server  string // host:port
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewNTPSource creates a clock source for server (host or host:port, default port 123)
+func NewNTPSource(server string) *NTPSource {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	return &NTPSource{server: server, timeout: 5 * time.Second, now: time.Now}
+}
+
+// Name returns "ntp"
+func (s *NTPSource) Name() string {
+	return "ntp"
+}
+
+// ClockOffset queries the server; the uncertainty is half the round trip spent outside the server
+func (s *NTPSource) ClockOffset(ctx context.Context) (time.Duration, time.Duration, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", s.server)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to reach NTP server %s: %w", s.server, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// Client request: LI 0, version 4, mode 3; the transmit time comes back as the origin time
+	request := make([]byte, 48)
+	request[0] = 0x23
+	sent := s.now()
+	putNTPTime(request[40:], sent)
+	if _, err := conn.Write(request); err != nil {
+		return 0, 0, fmt.Errorf("failed to query NTP server %s: %w", s.server, err)
+	}
+
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := s.now()
+	if err != nil {
+		return 0, 0, fmt.Errorf("no answer from NTP server %s: %w", s.server, err)
+	}
+	if n < 48 || response[0]&0x07 != 4 {
+		return 0, 0, fmt.Errorf("invalid answer from NTP server %s", s.server)
+	}
+	if response[1] == 0 {
+		return 0, 0, fmt.Errorf("NTP server %s refused the query (kiss code %q)", s.server, response[12:16])
+	}
+	if binary.BigEndian.Uint64(response[24:32]) != binary.BigEndian.Uint64(request[40:48]) {
+		return 0, 0, fmt.Errorf("NTP server %s answered another request", s.server)
+	}
+
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	offset := (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	delay := received.Sub(sent) - serverSent.Sub(serverReceived)
+	return offset, max(delay, 0) / 2, nil
+}
+
+// putNTPTime writes t as a 64-bit NTP timestamp
+func putNTPTime(b []byte, t time.Time) {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / 1e9
+	binary.BigEndian.PutUint64(b, seconds<<32|fraction)
+}
+
+// ntpTime reads a 64-bit NTP timestamp
+func ntpTime(b []byte) time.Time {
+	v := binary.BigEndian.Uint64(b)
+	seconds := int64(v>>32) - ntpEpochOffset
+	nanos := int64((v & 0xffffffff) * 1e9 >> 32)
+	return time.Unix(seconds, nanos)
+}
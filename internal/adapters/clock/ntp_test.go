@@ -0,0 +1,70 @@
+package clock
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveNTP answers one SNTP query from a clock running ahead by skew
+func serveNTP(t *testing.T, skew time.Duration, stratum byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		request := make([]byte, 48)
+		_, addr, err := conn.ReadFrom(request)
+		if err != nil {
+			return
+		}
+		response := make([]byte, 48)
+		response[0] = 0x24 // Version 4, server mode
+		response[1] = stratum
+		copy(response[24:32], request[40:48])
+		putNTPTime(response[32:], time.Now().Add(skew))
+		putNTPTime(response[40:], time.Now().Add(skew))
+		conn.WriteTo(response, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNTPSource_ClockOffset(t *testing.T) {
+	source := NewNTPSource(serveNTP(t, 2*time.Second, 2))
+	offset, uncertainty, err := source.ClockOffset(context.Background())
+	if err != nil {
+		t.Fatalf("ClockOffset failed: %v", err)
+	}
+	if offset < 1950*time.Millisecond || offset > 2050*time.Millisecond {
+		t.Errorf("Expected an offset of about 2s, got %v", offset)
+	}
+	if uncertainty < 0 || uncertainty > 50*time.Millisecond {
+		t.Errorf("Unexpected uncertainty %v", uncertainty)
+	}
+}
+
+func TestNTPSource_KissOfDeath(t *testing.T) {
+	source := NewNTPSource(serveNTP(t, 0, 0))
+	if _, _, err := source.ClockOffset(context.Background()); err == nil {
+		t.Error("Expected an error for stratum 0")
+	}
+}
+
+func TestNTPTime_RoundTrip(t *testing.T) {
+	want := time.Date(2025, 11, 18, 12, 0, 0, 123456789, time.UTC)
+	b := make([]byte, 8)
+	putNTPTime(b, want)
+	if got := ntpTime(b); got.Sub(want).Abs() > time.Nanosecond {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestNewNTPSource_DefaultPort(t *testing.T) {
+	if got := NewNTPSource("pool.ntp.org").server; got != "pool.ntp.org:123" {
+		t.Errorf("Expected the default port, got %s", got)
+	}
+}
@@ -0,0 +1,295 @@
+// Package delta maintains the transaction log of a Delta Lake table on a local or mounted filesystem
+// The data files are Parquet, written by the caller (cmd/lakehouse uses DuckDB); this package records
+// which of them make up each table version, so Spark, Trino, DuckDB, Databricks and other lakehouse
+// engines read the directory as a table. Only what an append/replace-partition writer needs is
+// implemented: protocol 1/2, JSON commits without checkpoints, and vacuum of removed files
+//
+// The package must be the table's only writer. Other engines may read it, but a table another
+// engine committed to (e.g. an OPTIMIZE) or checkpointed is refused with ErrForeignWriter rather
+// than replayed from an incomplete log
+package delta
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogDir is the directory of the transaction log inside the table
+const LogDir = "_delta_log"
+
+// ErrConflict is returned when another writer committed the version first
+var ErrConflict = errors.New("concurrent commit")
+
+// ErrForeignWriter is returned by Load for a table another engine has written to or checkpointed
+var ErrForeignWriter = errors.New("the table was written by another engine; it must only be written by fx-collector")
+
+// engineInfo identifies the commits written by this package
+const engineInfo = "fx-collector"
+
+// Field is a column of the table schema; Type is a Delta primitive (string, integer, long, double, timestamp, date)
+type Field struct {
+	Name     string
+	Type     string
+	Nullable bool
+}
+
+// File is a data file of the table (an add action)
+type File struct {
+	Path             string            `json:"path"` // Relative to the table directory
+	PartitionValues  map[string]string `json:"partitionValues"`
+	Size             int64             `json:"size"`
+	ModificationTime int64             `json:"modificationTime"` // Unix milliseconds
+	DataChange       bool              `json:"dataChange"`
+	Stats            string            `json:"stats,omitempty"` // JSON, e.g. {"numRecords":1440}
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+// removal is a remove action
+type removal struct {
+	Path              string `json:"path"`
+	DeletionTimestamp int64  `json:"deletionTimestamp"`
+	DataChange        bool   `json:"dataChange"`
+}
+
+// Snapshot is the state of the table at its latest version
+type Snapshot struct {
+	Version int64           // -1 for a table without commits
+	Files   map[string]File // Active data files by path
+	removed map[string]int64
+}
+
+// Table is a Delta table rooted at a directory
+type Table struct {
+	dir string
+}
+
+// Open returns the table at dir; it is created by the first Commit
+func Open(dir string) *Table {
+	return &Table{dir: dir}
+}
+
+// Dir returns the table directory
+func (t *Table) Dir() string {
+	return t.dir
+}
+
+// Load replays the transaction log and returns the current snapshot
+func (t *Table) Load() (*Snapshot, error) {
+	snapshot := &Snapshot{Version: -1, Files: make(map[string]File), removed: make(map[string]int64)}
+	entries, err := os.ReadDir(filepath.Join(t.dir, LogDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Delta log: %w", err)
+	}
+
+	var versions []int64
+	for _, entry := range entries {
+		if entry.Name() == "_last_checkpoint" || strings.Contains(entry.Name(), ".checkpoint.") {
+			return nil, fmt.Errorf("%w (checkpoint %s)", ErrForeignWriter, entry.Name())
+		}
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || len(name) != 20 {
+			continue
+		}
+		version, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+
+	for i, version := range versions {
+		if int64(i) != version {
+			return nil, fmt.Errorf("Delta log is missing version %d, removed by another engine's log cleanup?", i)
+		}
+		if err := snapshot.apply(t.commitPath(version)); err != nil {
+			return nil, fmt.Errorf("version %d: %w", version, err)
+		}
+		snapshot.Version = version
+	}
+	return snapshot, nil
+}
+
+// apply replays the actions of one commit file
+func (s *Snapshot) apply(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read Delta commit: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var action struct {
+			CommitInfo *struct {
+				EngineInfo string `json:"engineInfo"`
+			} `json:"commitInfo"`
+			Add    *File    `json:"add"`
+			Remove *removal `json:"remove"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			return fmt.Errorf("invalid action in %s: %w", path, err)
+		}
+		switch {
+		case action.CommitInfo != nil && action.CommitInfo.EngineInfo != engineInfo:
+			return fmt.Errorf("%w (committed by %q)", ErrForeignWriter, action.CommitInfo.EngineInfo)
+		case action.Add != nil:
+			s.Files[action.Add.Path] = *action.Add
+			delete(s.removed, action.Add.Path)
+		case action.Remove != nil:
+			delete(s.Files, action.Remove.Path)
+			s.removed[action.Remove.Path] = action.Remove.DeletionTimestamp
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read Delta commit %s: %w", path, err)
+	}
+	return nil
+}
+
+// Commit writes the next version after snapshot, adding and removing data files
+// The first commit of a table also needs the schema and partition columns
+// Returns ErrConflict when another writer took the version; reload and retry
+func (t *Table) Commit(snapshot *Snapshot, schema []Field, partitionColumns []string, add []File, remove []string, operation string) error {
+	now := time.Now().UnixMilli()
+	var lines []any
+	lines = append(lines, map[string]any{"commitInfo": map[string]any{
+		"timestamp": now, "operation": operation, "engineInfo": engineInfo,
+	}})
+
+	if snapshot.Version < 0 {
+		if len(schema) == 0 {
+			return fmt.Errorf("the first commit of a Delta table needs a schema")
+		}
+		schemaString, err := schemaJSON(schema)
+		if err != nil {
+			return err
+		}
+		lines = append(lines,
+			map[string]any{"protocol": map[string]any{"minReaderVersion": 1, "minWriterVersion": 2}},
+			map[string]any{"metaData": map[string]any{
+				"id":               newID(),
+				"format":           map[string]any{"provider": "parquet", "options": map[string]string{}},
+				"schemaString":     schemaString,
+				"partitionColumns": partitionColumns,
+				"configuration":    map[string]string{},
+				"createdTime":      now,
+			}})
+	}
+	for _, path := range remove {
+		lines = append(lines, map[string]any{"remove": removal{Path: path, DeletionTimestamp: now, DataChange: true}})
+	}
+	for _, file := range add {
+		lines = append(lines, map[string]any{"add": file})
+	}
+
+	var data []byte
+	for _, line := range lines {
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			return fmt.Errorf("failed to encode Delta action: %w", err)
+		}
+		data = append(append(data, encoded...), '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Join(t.dir, LogDir), 0755); err != nil {
+		return fmt.Errorf("failed to create Delta log: %w", err)
+	}
+	// Write the commit under a temporary name, then link it into place: the link fails if the
+	// version exists, which is the mutual exclusion Delta expects from the filesystem
+	version := snapshot.Version + 1
+	tmp := filepath.Join(t.dir, LogDir, "."+newID()+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write Delta commit: %w", err)
+	}
+	defer os.Remove(tmp)
+	if err := os.Link(tmp, t.commitPath(version)); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w: version %d already exists", ErrConflict, version)
+		}
+		return fmt.Errorf("failed to commit Delta version %d: %w", version, err)
+	}
+	return nil
+}
+
+// Vacuum deletes the data files removed from the table more than retain ago, as Delta's VACUUM does
+// Readers of versions older than retain lose those files (time travel ends there)
+func (t *Table) Vacuum(snapshot *Snapshot, retain time.Duration) ([]string, error) {
+	cutoff := time.Now().Add(-retain).UnixMilli()
+	var deleted []string
+	for path, removedAt := range snapshot.removed {
+		if removedAt > cutoff {
+			continue
+		}
+		err := os.Remove(filepath.Join(t.dir, filepath.FromSlash(path)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to vacuum %s: %w", path, err)
+		}
+		if err == nil {
+			deleted = append(deleted, path)
+		}
+	}
+	slices.Sort(deleted)
+	return deleted, nil
+}
+
+// NewFilePath returns a new data file path in the partition, named like Spark's Delta writer
+func NewFilePath(partition map[string]string, columns []string) string {
+	var dirs []string
+	for _, column := range columns {
+		dirs = append(dirs, column+"="+partition[column])
+	}
+	return strings.Join(append(dirs, "part-00000-"+newID()+"-c000.snappy.parquet"), "/")
+}
+
+// commitPath returns the path of a version's commit file
+func (t *Table) commitPath(version int64) string {
+	return filepath.Join(t.dir, LogDir, fmt.Sprintf("%020d.json", version))
+}
+
+// schemaJSON encodes the schema as a Spark struct type
+func schemaJSON(fields []Field) (string, error) {
+	type structField struct {
+		Name     string            `json:"name"`
+		Type     string            `json:"type"`
+		Nullable bool              `json:"nullable"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	encoded := struct {
+		Type   string        `json:"type"`
+		Fields []structField `json:"fields"`
+	}{Type: "struct"}
+	for _, field := range fields {
+		encoded.Fields = append(encoded.Fields, structField{Name: field.Name, Type: field.Type, Nullable: field.Nullable, Metadata: map[string]string{}})
+	}
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Delta schema: %w", err)
+	}
+	return string(data), nil
+}
+
+// newID returns a random UUID (version 4)
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
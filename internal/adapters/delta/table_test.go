@@ -0,0 +1,182 @@
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchema = []Field{
+	{Name: "timestamp", Type: "timestamp"},
+	{Name: "bid", Type: "double", Nullable: true},
+	{Name: "date", Type: "date"},
+}
+
+// addFile creates an empty data file in the table and returns its add action
+func addFile(t *testing.T, table *Table, date string) File {
+	t.Helper()
+	partition := map[string]string{"date": date}
+	path := NewFilePath(partition, []string{"date"})
+	full := filepath.Join(table.Dir(), filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte("PAR1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return File{Path: path, PartitionValues: partition, Size: 4, DataChange: true, Stats: `{"numRecords":1}`}
+}
+
+func TestCommit_FirstVersionHasProtocolAndMetadata(t *testing.T) {
+	table := Open(t.TempDir())
+	snapshot, err := table.Load()
+	if err != nil || snapshot.Version != -1 {
+		t.Fatalf("Expected an empty table, got %+v, %v", snapshot, err)
+	}
+
+	file := addFile(t, table, "2025-11-18")
+	if err := table.Commit(snapshot, testSchema, []string{"date"}, []File{file}, nil, "WRITE"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(table.Dir(), LogDir, "00000000000000000000.json"))
+	if err != nil {
+		t.Fatalf("Commit file missing: %v", err)
+	}
+	var actions []string
+	var schemaString string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var action map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &action); err != nil {
+			t.Fatalf("Invalid action %q: %v", line, err)
+		}
+		for name, value := range action {
+			actions = append(actions, name)
+			if name == "metaData" {
+				var metadata struct{ SchemaString string }
+				json.Unmarshal(value, &metadata)
+				schemaString = metadata.SchemaString
+			}
+		}
+	}
+	if strings.Join(actions, ",") != "commitInfo,protocol,metaData,add" {
+		t.Errorf("Unexpected actions %v", actions)
+	}
+	if !strings.Contains(schemaString, `{"name":"bid","type":"double","nullable":true,"metadata":{}}`) {
+		t.Errorf("Unexpected schema %s", schemaString)
+	}
+
+	snapshot, err = table.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if snapshot.Version != 0 || len(snapshot.Files) != 1 || snapshot.Files[file.Path].Stats != file.Stats {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+}
+
+func TestCommit_ReplacesPartitionAndDetectsConflicts(t *testing.T) {
+	table := Open(t.TempDir())
+	empty, _ := table.Load()
+	first := addFile(t, table, "2025-11-18")
+	if err := table.Commit(empty, testSchema, []string{"date"}, []File{first}, nil, "WRITE"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	snapshot, _ := table.Load()
+	second := addFile(t, table, "2025-11-18")
+	if err := table.Commit(snapshot, nil, nil, []File{second}, []string{first.Path}, "WRITE"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// A writer still holding version 0 must not overwrite version 1
+	err := table.Commit(snapshot, nil, nil, []File{addFile(t, table, "2025-11-19")}, nil, "WRITE")
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+
+	snapshot, err = table.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if snapshot.Version != 1 || len(snapshot.Files) != 1 {
+		t.Fatalf("Expected version 1 with one file, got %+v", snapshot)
+	}
+	if _, ok := snapshot.Files[second.Path]; !ok {
+		t.Errorf("Expected %s to be active, got %v", second.Path, snapshot.Files)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(table.Dir(), LogDir, ".*.tmp")); len(leftovers) > 0 {
+		t.Errorf("Temporary commit files left: %v", leftovers)
+	}
+}
+
+func TestVacuum_DeletesRemovedFilesPastRetention(t *testing.T) {
+	table := Open(t.TempDir())
+	empty, _ := table.Load()
+	first := addFile(t, table, "2025-11-18")
+	if err := table.Commit(empty, testSchema, []string{"date"}, []File{first}, nil, "WRITE"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ := table.Load()
+	second := addFile(t, table, "2025-11-18")
+	if err := table.Commit(snapshot, nil, nil, []File{second}, []string{first.Path}, "WRITE"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ = table.Load()
+
+	if deleted, err := table.Vacuum(snapshot, 24*time.Hour); err != nil || len(deleted) != 0 {
+		t.Errorf("Expected nothing vacuumed within retention, got %v, %v", deleted, err)
+	}
+	deleted, err := table.Vacuum(snapshot, 0)
+	if err != nil || len(deleted) != 1 || deleted[0] != first.Path {
+		t.Fatalf("Expected %s vacuumed, got %v, %v", first.Path, deleted, err)
+	}
+	if _, err := os.Stat(filepath.Join(table.Dir(), filepath.FromSlash(first.Path))); !os.IsNotExist(err) {
+		t.Errorf("Removed file still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(table.Dir(), filepath.FromSlash(second.Path))); err != nil {
+		t.Errorf("Active file deleted: %v", err)
+	}
+}
+
+func TestLoad_FailsOnMissingVersion(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, LogDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LogDir, "00000000000000000001.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir).Load(); err == nil {
+		t.Error("Expected an error for a log without version 0")
+	}
+}
+
+func TestLoad_RefusesForeignWriters(t *testing.T) {
+	table := Open(t.TempDir())
+	file := addFile(t, table, "2025-11-18")
+	if err := table.Commit(&Snapshot{Version: -1}, testSchema, []string{"date"}, []File{file}, nil, "WRITE"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// An OPTIMIZE by Spark commits version 1
+	optimize := `{"commitInfo":{"timestamp":1763500000000,"operation":"OPTIMIZE","engineInfo":"Apache-Spark/3.5.1 Delta-Lake/3.2.0"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(table.Dir(), LogDir, "00000000000000000001.json"), []byte(optimize), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Load(); !errors.Is(err, ErrForeignWriter) {
+		t.Errorf("Expected ErrForeignWriter for a Spark commit, got %v", err)
+	}
+
+	os.Remove(filepath.Join(table.Dir(), LogDir, "00000000000000000001.json"))
+	if err := os.WriteFile(filepath.Join(table.Dir(), LogDir, "_last_checkpoint"), []byte(`{"version":0,"size":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Load(); !errors.Is(err, ErrForeignWriter) {
+		t.Errorf("Expected ErrForeignWriter for a checkpointed table, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package fix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// AcceptorConfig holds FIX acceptor settings
+type AcceptorConfig struct {
+	Addr         string   // Listen address, e.g. localhost:9878
+	SenderCompID string   // Our CompID; logons addressed to another TargetCompID are refused
+	Symbols      []string // Tickers served; requests for others are rejected (empty: those recorded so far)
+}
+
+// Validate checks the acceptor configuration
+func (c AcceptorConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("FIX listen address is required")
+	}
+	if c.SenderCompID == "" {
+		return fmt.Errorf("FIX SenderCompID is required")
+	}
+	return nil
+}
+
+// ConfigFromSpec reads an acceptor configuration from recorder parameters:
+// fix?addr=localhost:9878&sender=FXCOLLECTOR&symbols=EURUSD,USDJPY
+func ConfigFromSpec(spec storage.RecorderSpec) AcceptorConfig {
+	var symbols []string
+	for _, symbol := range strings.Split(spec.Param("symbols", ""), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return AcceptorConfig{
+		Addr:         spec.Param("addr", "localhost:9878"),
+		SenderCompID: spec.Param("sender", "FXCOLLECTOR"),
+		Symbols:      symbols,
+	}
+}
+
+func init() {
+	storage.RegisterRecorder("fix", func(spec storage.RecorderSpec) (ports.TickWriter, error) {
+		return NewAcceptor(ConfigFromSpec(spec))
+	})
+}
+
+// Acceptor implements TickWriter by serving the recorded ticks as FIX 4.4 market data
+// Counterparties log on and send MarketDataRequest (V) for tickers; they get a
+// MarketDataSnapshotFullRefresh (W) of the latest quote and, when subscribed, a
+// MarketDataIncrementalRefresh (X) per tick. Sessions are not recoverable: both sides
+// start at sequence number 1 on every logon and resend requests are answered with a sequence reset
+type Acceptor struct {
+	config   AcceptorConfig
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	sessions map[*session]bool
+	last     map[string]*domain.PriceData // Latest quote per ticker, for snapshots
+	closed   bool
+}
+
+// NewAcceptor starts listening for FIX connections
+func NewAcceptor(config AcceptorConfig) (*Acceptor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", config.Addr, err)
+	}
+	log.Printf("FIXAcceptor: ✅ Listening on %s as %s", listener.Addr(), config.SenderCompID)
+
+	a := &Acceptor{
+		config:   config,
+		listener: listener,
+		sessions: make(map[*session]bool),
+		last:     make(map[string]*domain.PriceData),
+	}
+	a.wg.Add(1)
+	go a.accept()
+	return a, nil
+}
+
+// Addr returns the listen address (useful with port 0)
+func (a *Acceptor) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+// accept serves connections until the listener is closed
+func (a *Acceptor) accept() {
+	defer a.wg.Done()
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("FIXAcceptor: Accept failed: %v", err)
+			}
+			return
+		}
+
+		s := newSession(a, conn)
+		a.mu.Lock()
+		if a.closed {
+			a.mu.Unlock()
+			conn.Close()
+			return
+		}
+		a.sessions[s] = true
+		a.mu.Unlock()
+
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			s.run()
+
+			a.mu.Lock()
+			delete(a.sessions, s)
+			a.mu.Unlock()
+		}()
+	}
+}
+
+// known reports whether ticker is served: configured, or recorded so far
+func (a *Acceptor) known(ticker string) bool {
+	if slices.Contains(a.config.Symbols, ticker) {
+		return true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.last[ticker]
+	return ok
+}
+
+// snapshot returns the latest quote of ticker, or nil
+func (a *Acceptor) snapshot(ticker string) *domain.PriceData {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last[ticker]
+}
+
+// Record sends a tick to the sessions subscribed to its ticker
+// Sessions that can't keep up are disconnected instead of slowing the pipeline down
+func (a *Acceptor) Record(ctx context.Context, data *domain.PriceData) error {
+	quote := *data
+
+	a.mu.Lock()
+	a.last[quote.Ticker] = &quote
+	sessions := make([]*session, 0, len(a.sessions))
+	for s := range a.sessions {
+		sessions = append(sessions, s)
+	}
+	a.mu.Unlock()
+
+	for _, s := range sessions {
+		s.quote(&quote)
+	}
+	return nil
+}
+
+// RecordBatch sends multiple ticks
+func (a *Acceptor) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	for i, d := range data {
+		if err := a.Record(ctx, d); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+	}
+	return nil
+}
+
+// Close stops listening and logs out all sessions
+func (a *Acceptor) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	for s := range a.sessions {
+		s.logout("Collector shutting down")
+	}
+	a.mu.Unlock()
+
+	err := a.listener.Close()
+	a.wg.Wait()
+	return err
+}
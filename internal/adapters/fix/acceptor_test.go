@@ -0,0 +1,186 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestMessage_RoundTrip(t *testing.T) {
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	raw := encode(newMessage(msgTestRequest).add(tagTestReqID, "ping"), "FXCOLLECTOR", "CLIENT", 7, now)
+
+	want := "8=FIX.4.4\x019=69\x0135=1\x0149=FXCOLLECTOR\x0156=CLIENT\x0134=7\x0152=20251118-12:00:00.000\x01112=ping\x0110="
+	if !bytes.HasPrefix(raw, []byte(want)) {
+		t.Fatalf("Unexpected encoding: %q", raw)
+	}
+
+	m, err := readMessage(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if m.msgType() != msgTestRequest || m.get(tagTestReqID) != "ping" || m.get(tagMsgSeqNum) != "7" {
+		t.Errorf("Unexpected message: %v", m)
+	}
+
+	raw[len(raw)-3]++ // Corrupt the checksum
+	if _, err := readMessage(bufio.NewReader(bytes.NewReader(raw))); err == nil {
+		t.Error("Expected a checksum error")
+	}
+}
+
+func TestAcceptor_MarketData(t *testing.T) {
+	acceptor, err := NewAcceptor(AcceptorConfig{Addr: "127.0.0.1:0", SenderCompID: "FXCOLLECTOR"})
+	if err != nil {
+		t.Fatalf("NewAcceptor failed: %v", err)
+	}
+	defer acceptor.Close()
+
+	ctx := context.Background()
+	tick := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Ticker:    "EURUSD",
+		Bid:       1.0845,
+		Ask:       1.08452,
+		Decimals:  5,
+	}
+	acceptor.Record(ctx, tick)
+
+	conn, err := net.Dial("tcp", acceptor.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	seq := 0
+	send := func(m message) {
+		seq++
+		if _, err := conn.Write(encode(m, "CLIENT", "FXCOLLECTOR", seq, time.Now())); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	expect := func(msgType string) message {
+		m, err := readMessage(reader)
+		if err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+		if m.msgType() != msgType {
+			t.Fatalf("Expected MsgType %s, got %v", msgType, m)
+		}
+		return m
+	}
+
+	send(newMessage(msgLogon).add(tagEncryptMethod, "0").add(tagHeartBtInt, "30"))
+	expect(msgLogon)
+
+	send(newMessage(msgMarketDataRequest).
+		add(tagMDReqID, "req1").
+		add(tagSubscriptionRequestType, subscriptionSnapshotUpdates).
+		add(tagNoRelatedSym, "1").
+		add(tagSymbol, "EURUSD"))
+	snapshot := expect(msgMarketDataSnapshot)
+	if prices := snapshot.getAll(tagMDEntryPx); len(prices) != 2 || prices[0] != "1.08450" || prices[1] != "1.08452" {
+		t.Errorf("Expected bid 1.08450 and offer 1.08452, got %v", prices)
+	}
+
+	next := *tick
+	next.Bid = 1.08451
+	acceptor.Record(ctx, &next)
+	acceptor.Record(ctx, &domain.PriceData{Ticker: "USDJPY", Bid: 155.1, Ask: 155.11, Decimals: 3})
+
+	update := expect(msgMarketDataIncremental)
+	if update.get(tagMDReqID) != "req1" || update.get(tagMDEntryPx) != "1.08451" || update.get(tagSymbol) != "EURUSD" {
+		t.Errorf("Unexpected incremental refresh: %v", update)
+	}
+
+	send(newMessage(msgLogout))
+	expect(msgLogout)
+}
+
+func TestAcceptor_RejectsUnknownSymbol(t *testing.T) {
+	acceptor, err := NewAcceptor(AcceptorConfig{Addr: "127.0.0.1:0", SenderCompID: "FXCOLLECTOR", Symbols: []string{"EURUSD", "USDJPY"}})
+	if err != nil {
+		t.Fatalf("NewAcceptor failed: %v", err)
+	}
+	defer acceptor.Close()
+
+	conn, err := net.Dial("tcp", acceptor.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	seq := 0
+	send := func(m message) {
+		seq++
+		if _, err := conn.Write(encode(m, "CLIENT", "FXCOLLECTOR", seq, time.Now())); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	expect := func(msgType string) message {
+		m, err := readMessage(reader)
+		if err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+		if m.msgType() != msgType {
+			t.Fatalf("Expected MsgType %s, got %v", msgType, m)
+		}
+		return m
+	}
+	request := func(reqID, symbol string) {
+		send(newMessage(msgMarketDataRequest).
+			add(tagMDReqID, reqID).
+			add(tagSubscriptionRequestType, subscriptionSnapshotUpdates).
+			add(tagNoRelatedSym, "1").
+			add(tagSymbol, symbol))
+	}
+
+	send(newMessage(msgLogon).add(tagEncryptMethod, "0").add(tagHeartBtInt, "30"))
+	expect(msgLogon)
+
+	request("req1", "GBPUSD")
+	reject := expect(msgMarketDataRequestReject)
+	if reject.get(tagMDReqID) != "req1" || reject.get(tagMDReqRejReason) != rejectUnknownSymbol {
+		t.Errorf("Unexpected reject: %v", reject)
+	}
+
+	// Configured but not quoted yet: subscribed, the first tick follows as a snapshot or an
+	// update, depending on whether it is recorded before the request is handled
+	request("req2", "USDJPY")
+	acceptor.Record(context.Background(), &domain.PriceData{Ticker: "USDJPY", Bid: 155.1, Ask: 155.11, Decimals: 3})
+	m, err := readMessage(reader)
+	if err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if m.msgType() == msgMarketDataRequestReject || m.get(tagMDReqID) != "req2" || m.get(tagSymbol) != "USDJPY" {
+		t.Errorf("Expected USDJPY market data, got %v", m)
+	}
+}
+
+func TestAcceptor_RefusesWrongTarget(t *testing.T) {
+	acceptor, err := NewAcceptor(AcceptorConfig{Addr: "127.0.0.1:0", SenderCompID: "FXCOLLECTOR"})
+	if err != nil {
+		t.Fatalf("NewAcceptor failed: %v", err)
+	}
+	defer acceptor.Close()
+
+	conn, err := net.Dial("tcp", acceptor.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write(encode(newMessage(msgLogon).add(tagHeartBtInt, "30"), "CLIENT", "SOMEONE", 1, time.Now()))
+	if _, err := readMessage(bufio.NewReader(conn)); err == nil {
+		t.Error("Expected the connection to be closed without a logon reply")
+	}
+}
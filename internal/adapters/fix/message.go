@@ -0,0 +1,237 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// beginString is the only FIX version the acceptor speaks
+const beginString = "FIX.4.4"
+
+// soh separates fields on the wire
+const soh = '\x01'
+
+// maxBodyLength bounds the messages read from a counterparty
+const maxBodyLength = 64 << 10
+
+// sendingTimeLayout is the UTCTimestamp format with milliseconds
+const sendingTimeLayout = "20060102-15:04:05.000"
+
+// FIX tags used by the acceptor
+const (
+	tagBeginString             = 8
+	tagBodyLength              = 9
+	tagCheckSum                = 10
+	tagMsgSeqNum               = 34
+	tagMsgType                 = 35
+	tagNewSeqNo                = 36
+	tagRefSeqNum               = 45
+	tagSenderCompID            = 49
+	tagSendingTime             = 52
+	tagSymbol                  = 55
+	tagTargetCompID            = 56
+	tagText                    = 58
+	tagEncryptMethod           = 98
+	tagHeartBtInt              = 108
+	tagTestReqID               = 112
+	tagResetSeqNumFlag         = 141
+	tagNoRelatedSym            = 146
+	tagMDReqID                 = 262
+	tagSubscriptionRequestType = 263
+	tagMDUpdateType            = 265
+	tagNoMDEntries             = 268
+	tagMDEntryType             = 269
+	tagMDEntryPx               = 270
+	tagMDEntryDate             = 272
+	tagMDEntryTime             = 273
+	tagMDUpdateAction          = 279
+	tagMDReqRejReason          = 281
+	tagRefMsgType              = 372
+	tagSessionRejectReason     = 373
+)
+
+// Message types
+const (
+	msgHeartbeat               = "0"
+	msgTestRequest             = "1"
+	msgResendRequest           = "2"
+	msgReject                  = "3"
+	msgSequenceReset           = "4"
+	msgLogout                  = "5"
+	msgLogon                   = "A"
+	msgMarketDataRequest       = "V"
+	msgMarketDataSnapshot      = "W"
+	msgMarketDataIncremental   = "X"
+	msgMarketDataRequestReject = "Y"
+)
+
+// SubscriptionRequestType values
+const (
+	subscriptionSnapshot        = "0"
+	subscriptionSnapshotUpdates = "1"
+	subscriptionUnsubscribe     = "2"
+)
+
+// field is one tag=value pair
+type field struct {
+	tag   int
+	value string
+}
+
+// message is the body of a FIX message in wire order, starting with MsgType
+// The standard header fields besides MsgType and the trailer are added by encode
+type message []field
+
+// newMessage starts a message of msgType
+func newMessage(msgType string) message {
+	return message{{tagMsgType, msgType}}
+}
+
+// add appends a field
+func (m message) add(tag int, value string) message {
+	return append(m, field{tag, value})
+}
+
+// msgType returns the MsgType
+func (m message) msgType() string {
+	return m.get(tagMsgType)
+}
+
+// get returns the first value of tag, or ""
+func (m message) get(tag int) string {
+	for _, f := range m {
+		if f.tag == tag {
+			return f.value
+		}
+	}
+	return ""
+}
+
+// getAll returns every value of tag in order (repeating group members)
+func (m message) getAll(tag int) []string {
+	var values []string
+	for _, f := range m {
+		if f.tag == tag {
+			values = append(values, f.value)
+		}
+	}
+	return values
+}
+
+// encode frames m with the standard header (sender, target, seq, sending time) and the checksum trailer
+func encode(m message, sender, target string, seq int, now time.Time) []byte {
+	var body bytes.Buffer
+	writeField := func(tag int, value string) {
+		body.WriteString(strconv.Itoa(tag))
+		body.WriteByte('=')
+		body.WriteString(value)
+		body.WriteByte(soh)
+	}
+	writeField(tagMsgType, m.msgType())
+	writeField(tagSenderCompID, sender)
+	writeField(tagTargetCompID, target)
+	writeField(tagMsgSeqNum, strconv.Itoa(seq))
+	writeField(tagSendingTime, now.UTC().Format(sendingTimeLayout))
+	for _, f := range m[1:] {
+		writeField(f.tag, f.value)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%d=%s%c%d=%d%c", tagBeginString, beginString, soh, tagBodyLength, body.Len(), soh)
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "%d=%03d%c", tagCheckSum, checksum(out.Bytes()), soh)
+	return out.Bytes()
+}
+
+// checksum is the byte sum modulo 256 of everything before the CheckSum field
+func checksum(b []byte) int {
+	sum := 0
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+// readMessage reads one message, checking BeginString, BodyLength and CheckSum
+// The returned message holds the body fields (MsgType first), without BeginString, BodyLength and CheckSum
+func readMessage(r *bufio.Reader) (message, error) {
+	var raw bytes.Buffer
+
+	begin, err := readField(r, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if begin.tag != tagBeginString || begin.value != beginString {
+		return nil, fmt.Errorf("expected %d=%s, got %d=%s", tagBeginString, beginString, begin.tag, begin.value)
+	}
+	length, err := readField(r, &raw)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(length.value)
+	if length.tag != tagBodyLength || err != nil || n <= 0 || n > maxBodyLength {
+		return nil, fmt.Errorf("invalid body length %d=%s", length.tag, length.value)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	raw.Write(body)
+	trailer, err := readField(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sum, err := strconv.Atoi(trailer.value); trailer.tag != tagCheckSum || err != nil || sum != checksum(raw.Bytes()) {
+		return nil, fmt.Errorf("invalid checksum %d=%s", trailer.tag, trailer.value)
+	}
+
+	m, err := parseFields(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 || m[0].tag != tagMsgType {
+		return nil, fmt.Errorf("message does not start with MsgType")
+	}
+	return m, nil
+}
+
+// readField reads one tag=value<SOH> field, copying its bytes to raw if not nil
+// Fields longer than the reader's buffer fail with bufio.ErrBufferFull
+func readField(r *bufio.Reader, raw *bytes.Buffer) (field, error) {
+	b, err := r.ReadSlice(soh)
+	if err != nil {
+		return field{}, err
+	}
+	if raw != nil {
+		raw.Write(b)
+	}
+	fields, err := parseFields(b)
+	if err != nil {
+		return field{}, err
+	}
+	return fields[0], nil
+}
+
+// parseFields splits SOH-terminated tag=value pairs
+func parseFields(b []byte) (message, error) {
+	if len(b) == 0 || b[len(b)-1] != soh {
+		return nil, fmt.Errorf("field not terminated by SOH")
+	}
+
+	var m message
+	for _, pair := range strings.Split(string(b[:len(b)-1]), string(soh)) {
+		tag, value, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(tag)
+		if !ok || err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid field %q", pair)
+		}
+		m = append(m, field{n, value})
+	}
+	return m, nil
+}
@@ -0,0 +1,308 @@
+package fix
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+const (
+	logonTimeout     = 10 * time.Second // Wait for the counterparty's Logon after connecting
+	writeTimeout     = 5 * time.Second  // Bound on one write to the counterparty
+	defaultHeartbeat = 30 * time.Second // Used when the Logon has no valid HeartBtInt
+	sendQueueSize    = 1024             // Messages that may wait for a slow counterparty
+)
+
+// Values of the market data fields
+const (
+	entryBid            = "0"
+	entryOffer          = "1"
+	updateActionChange  = "1"
+	updateTypeFull      = "0"
+	rejectUnknownSymbol = "0"
+	rejectSubscription  = "4" // Unsupported SubscriptionRequestType
+	rejectInvalidType   = "11"
+)
+
+// subscription is a counterparty's market data request for one ticker
+type subscription struct {
+	reqID       string
+	fullRefresh bool // Send every tick as a snapshot (MDUpdateType=0) instead of an incremental refresh
+}
+
+// session is one counterparty connection
+type session struct {
+	acceptor *Acceptor
+	conn     net.Conn
+	out      chan message
+	done     chan struct{}
+	once     sync.Once
+	loggedOn atomic.Bool
+
+	// Set from the Logon before the writer starts
+	target    string
+	heartbeat time.Duration
+
+	mu            sync.Mutex
+	subscriptions map[string]subscription // By ticker
+}
+
+// newSession wraps an accepted connection
+func newSession(a *Acceptor, conn net.Conn) *session {
+	return &session{
+		acceptor:      a,
+		conn:          conn,
+		out:           make(chan message, sendQueueSize),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]subscription),
+	}
+}
+
+// run waits for the Logon, then handles messages until either side logs out or the connection fails
+func (s *session) run() {
+	defer s.close()
+	remote := s.conn.RemoteAddr()
+	reader := bufio.NewReader(s.conn)
+
+	s.conn.SetReadDeadline(time.Now().Add(logonTimeout))
+	logon, err := readMessage(reader)
+	if err != nil {
+		log.Printf("FIXAcceptor: No logon from %s: %v", remote, err)
+		return
+	}
+	if logon.msgType() != msgLogon {
+		log.Printf("FIXAcceptor: Expected logon from %s, got MsgType %s", remote, logon.msgType())
+		return
+	}
+	if target := logon.get(tagTargetCompID); target != s.acceptor.config.SenderCompID {
+		log.Printf("FIXAcceptor: Refused logon from %s for TargetCompID %q", remote, target)
+		return
+	}
+
+	s.target = logon.get(tagSenderCompID)
+	s.heartbeat = defaultHeartbeat
+	if seconds, err := strconv.Atoi(logon.get(tagHeartBtInt)); err == nil && seconds > 0 {
+		s.heartbeat = time.Duration(seconds) * time.Second
+	}
+	writerDone := make(chan struct{})
+	go s.write(writerDone)
+	defer func() { <-writerDone }()
+
+	s.loggedOn.Store(true)
+	s.send(newMessage(msgLogon).
+		add(tagEncryptMethod, "0").
+		add(tagHeartBtInt, strconv.Itoa(int(s.heartbeat/time.Second))).
+		add(tagResetSeqNumFlag, "Y"))
+	log.Printf("FIXAcceptor: %s logged on from %s", s.target, remote)
+
+	for {
+		// Two missed heartbeats (plus transmission slack) mean the counterparty is gone
+		s.conn.SetReadDeadline(time.Now().Add(2*s.heartbeat + s.heartbeat/5))
+		m, err := readMessage(reader)
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				log.Printf("FIXAcceptor: Session %s ended: %v", s.target, err)
+			}
+			return
+		}
+		s.handle(m)
+	}
+}
+
+// handle answers one message from a logged-on counterparty
+func (s *session) handle(m message) {
+	switch m.msgType() {
+	case msgHeartbeat:
+	case msgTestRequest:
+		s.send(newMessage(msgHeartbeat).add(tagTestReqID, m.get(tagTestReqID)))
+	case msgResendRequest:
+		// Market data is stale by the time it could be resent; skip ahead instead (NewSeqNo is set by write)
+		s.send(newMessage(msgSequenceReset))
+	case msgLogout:
+		log.Printf("FIXAcceptor: %s logged out", s.target)
+		s.send(newMessage(msgLogout))
+	case msgMarketDataRequest:
+		s.marketDataRequest(m)
+	default:
+		s.send(newMessage(msgReject).
+			add(tagRefSeqNum, m.get(tagMsgSeqNum)).
+			add(tagText, "Unsupported message type").
+			add(tagRefMsgType, m.msgType()).
+			add(tagSessionRejectReason, rejectInvalidType))
+	}
+}
+
+// marketDataRequest subscribes, unsubscribes or sends snapshots; a request naming an unknown
+// symbol is rejected as a whole
+// Snapshots are sent under the subscription lock, so an update never overtakes its snapshot
+func (s *session) marketDataRequest(m message) {
+	reqID := m.get(tagMDReqID)
+	symbols := m.getAll(tagSymbol)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requestType := m.get(tagSubscriptionRequestType)
+	switch {
+	case requestType == subscriptionUnsubscribe:
+		for ticker, sub := range s.subscriptions {
+			if sub.reqID == reqID {
+				delete(s.subscriptions, ticker)
+			}
+		}
+		return
+	case requestType != subscriptionSnapshot && requestType != subscriptionSnapshotUpdates:
+		s.rejectRequest(reqID, rejectSubscription, "Unsupported SubscriptionRequestType "+requestType)
+		return
+	case len(symbols) == 0:
+		s.rejectRequest(reqID, rejectUnknownSymbol, "No symbols requested")
+		return
+	}
+	for _, ticker := range symbols {
+		if !s.acceptor.known(ticker) {
+			s.rejectRequest(reqID, rejectUnknownSymbol, "Unknown symbol "+ticker)
+			return
+		}
+	}
+
+	for _, ticker := range symbols {
+		if requestType == subscriptionSnapshotUpdates {
+			s.subscriptions[ticker] = subscription{reqID: reqID, fullRefresh: m.get(tagMDUpdateType) == updateTypeFull}
+		}
+		if quote := s.acceptor.snapshot(ticker); quote != nil {
+			s.send(snapshotMessage(reqID, quote))
+		}
+	}
+}
+
+// rejectRequest sends a MarketDataRequestReject
+func (s *session) rejectRequest(reqID, reason, text string) {
+	s.send(newMessage(msgMarketDataRequestReject).
+		add(tagMDReqID, reqID).
+		add(tagMDReqRejReason, reason).
+		add(tagText, text))
+}
+
+// quote sends a tick if the counterparty subscribed to its ticker
+func (s *session) quote(p *domain.PriceData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[p.Ticker]
+	switch {
+	case !ok:
+	case sub.fullRefresh:
+		s.send(snapshotMessage(sub.reqID, p))
+	default:
+		s.send(incrementalMessage(sub.reqID, p))
+	}
+}
+
+// send queues a message; a counterparty that lets the queue fill up is disconnected
+func (s *session) send(m message) {
+	select {
+	case s.out <- m:
+	default:
+		log.Printf("FIXAcceptor: %s is not keeping up, disconnecting", s.target)
+		s.close()
+	}
+}
+
+// logout ends the session with a Logout (or just closes it before logon)
+func (s *session) logout(text string) {
+	if !s.loggedOn.Load() {
+		s.close()
+		return
+	}
+	s.send(newMessage(msgLogout).add(tagText, text))
+}
+
+// close drops the connection
+func (s *session) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+// write sends queued messages and heartbeats, numbering them from 1
+// The connection is closed after a Logout has been written
+func (s *session) write(writerDone chan struct{}) {
+	defer close(writerDone)
+
+	ticker := time.NewTicker(s.heartbeat / 2)
+	defer ticker.Stop()
+
+	seq := 0
+	lastSent := time.Now()
+	for {
+		var m message
+		select {
+		case <-s.done:
+			return
+		case m = <-s.out:
+		case now := <-ticker.C:
+			if now.Sub(lastSent) < s.heartbeat/2 {
+				continue
+			}
+			m = newMessage(msgHeartbeat)
+		}
+
+		seq++
+		if m.msgType() == msgSequenceReset {
+			m = m.add(tagNewSeqNo, strconv.Itoa(seq+1))
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := s.conn.Write(encode(m, s.acceptor.config.SenderCompID, s.target, seq, time.Now())); err != nil {
+			log.Printf("FIXAcceptor: Failed to write to %s: %v", s.target, err)
+			s.close()
+			return
+		}
+		lastSent = time.Now()
+
+		if m.msgType() == msgLogout {
+			s.close()
+			return
+		}
+	}
+}
+
+// snapshotMessage is a MarketDataSnapshotFullRefresh with the bid and offer of p
+func snapshotMessage(reqID string, p *domain.PriceData) message {
+	m := newMessage(msgMarketDataSnapshot).
+		add(tagMDReqID, reqID).
+		add(tagSymbol, p.Ticker).
+		add(tagNoMDEntries, "2")
+	m = appendEntry(m, entryBid, p.FixedBid().Format(p.Decimals), p, false)
+	return appendEntry(m, entryOffer, p.FixedAsk().Format(p.Decimals), p, false)
+}
+
+// incrementalMessage is a MarketDataIncrementalRefresh changing the bid and offer of p
+func incrementalMessage(reqID string, p *domain.PriceData) message {
+	m := newMessage(msgMarketDataIncremental).
+		add(tagMDReqID, reqID).
+		add(tagNoMDEntries, "2")
+	m = appendEntry(m.add(tagMDUpdateAction, updateActionChange), entryBid, p.FixedBid().Format(p.Decimals), p, true)
+	return appendEntry(m.add(tagMDUpdateAction, updateActionChange), entryOffer, p.FixedAsk().Format(p.Decimals), p, true)
+}
+
+// appendEntry appends one MDEntry group member with the quote time; withSymbol adds Symbol after MDEntryType
+func appendEntry(m message, entryType, price string, p *domain.PriceData, withSymbol bool) message {
+	m = m.add(tagMDEntryType, entryType)
+	if withSymbol {
+		m = m.add(tagSymbol, p.Ticker)
+	}
+	t := p.Timestamp.UTC()
+	return m.
+		add(tagMDEntryPx, price).
+		add(tagMDEntryDate, t.Format("20060102")).
+		add(tagMDEntryTime, t.Format("15:04:05.000"))
+}
@@ -0,0 +1,215 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	"github.com/bjoelf/fx-collector/pkg/tickavro"
+	"github.com/bjoelf/fx-collector/pkg/tickpb"
+)
+
+// Formats lists the supported record value formats
+var Formats = []string{"avro", "proto"}
+
+// registryTimeout bounds registering the schema at startup
+const registryTimeout = 10 * time.Second
+
+// Config holds the Kafka sink settings
+type Config struct {
+	RESTURL       string // REST Proxy, e.g. http://rest-proxy:8082
+	Topic         string
+	Format        string // avro (registered with the Schema Registry) or proto (tickpb.PriceData)
+	RegistryURL   string // Schema Registry, e.g. http://schema-registry:8081 (avro)
+	Compatibility string // Set on the topic's value subject before registering (empty: the registry's)
+}
+
+// Validate checks the sink configuration
+func (c Config) Validate() error {
+	if c.RESTURL == "" {
+		return fmt.Errorf("Kafka REST Proxy URL is required")
+	}
+	if c.Topic == "" {
+		return fmt.Errorf("Kafka topic is required")
+	}
+	if !slices.Contains(Formats, c.Format) {
+		return fmt.Errorf("invalid Kafka format %q (supported: %s)", c.Format, strings.Join(Formats, ", "))
+	}
+	if c.Format == "avro" && c.RegistryURL == "" {
+		return fmt.Errorf("schema registry URL is required for Avro")
+	}
+	if c.Compatibility != "" && !slices.Contains(tickavro.Compatibilities, strings.ToUpper(c.Compatibility)) {
+		return fmt.Errorf("invalid compatibility %q (supported: %s)", c.Compatibility, strings.Join(tickavro.Compatibilities, ", "))
+	}
+	return nil
+}
+
+// ConfigFromSpec reads a sink configuration from recorder parameters:
+// kafka?rest=http://rest-proxy:8082&topic=fx.ticks&format=avro&registry=http://schema-registry:8081&compatibility=FULL_TRANSITIVE
+func ConfigFromSpec(spec storage.RecorderSpec) (Config, error) {
+	return Config{
+		RESTURL:       spec.Param("rest", ""),
+		Topic:         spec.Param("topic", "fx.ticks"),
+		Format:        spec.Param("format", "avro"),
+		RegistryURL:   spec.Param("registry", ""),
+		Compatibility: spec.Param("compatibility", ""),
+	}, nil
+}
+
+func init() {
+	storage.RegisterRecorder("kafka", func(spec storage.RecorderSpec) (ports.TickWriter, error) {
+		config, err := ConfigFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewSink(config)
+	})
+}
+
+// batchSize is the number of records per produce request, and the pending records that trigger
+// one without waiting for Flush
+// maxPending bounds the records kept while the REST Proxy is unreachable; beyond it writes fail,
+// so the ticks go to a fallback sink instead of filling memory
+const (
+	batchSize  = 500
+	maxPending = 100000
+)
+
+// Sink implements TickWriter by buffering ticks as Kafka records and producing them on every
+// flush (or once a batch is pending)
+// Records are keyed by ticker, so an instrument's ticks stay in order on one partition
+// Avro values carry the Confluent wire format header with the schema ID registered at startup
+// Records that were not acknowledged stay pending and are produced again on the next flush;
+// acknowledged records of a partly rejected request are not sent twice
+type Sink struct {
+	config   Config
+	producer *Producer
+	schemaID int // avro
+
+	mu      sync.Mutex
+	pending []Record
+}
+
+// NewSink registers the Avro schema (see Register) and returns a sink producing to the topic
+func NewSink(config Config) (*Sink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	producer, err := NewProducer(config.RESTURL)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{config: config, producer: producer}
+	if config.Format == "avro" {
+		ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+		defer cancel()
+		registry, err := tickavro.NewRegistry(config.RegistryURL)
+		if err != nil {
+			return nil, err
+		}
+		if s.schemaID, err = Register(ctx, registry, config.Topic, config.Compatibility); err != nil {
+			return nil, err
+		}
+		log.Printf("KafkaSink: ✅ Producing to %s via %s (Avro schema ID %d)", config.Topic, producer.baseURL, s.schemaID)
+	} else {
+		log.Printf("KafkaSink: ✅ Producing to %s via %s (%s)", config.Topic, producer.baseURL, config.Format)
+	}
+	return s, nil
+}
+
+// Register sets compatibility (unless empty) on the topic's value subject, checks tickavro.Schema
+// against the latest version and registers it, returning its ID
+// An incompatible schema fails with tickavro.ErrIncompatible and the registry's reasons
+func Register(ctx context.Context, registry *tickavro.Registry, topic, compatibility string) (int, error) {
+	subject := tickavro.ValueSubject(topic)
+	if compatibility != "" {
+		if err := registry.SetCompatibility(ctx, subject, compatibility); err != nil {
+			return 0, err
+		}
+	}
+	if err := registry.CheckCompatibility(ctx, subject, tickavro.Schema); err != nil {
+		return 0, err
+	}
+	return registry.Register(ctx, subject, tickavro.Schema)
+}
+
+// Record buffers a single price data point
+func (s *Sink) Record(ctx context.Context, data *domain.PriceData) error {
+	return s.RecordBatch(ctx, []*domain.PriceData{data})
+}
+
+// RecordBatch buffers multiple price data points, producing them once a batch is pending
+func (s *Sink) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) >= maxPending {
+		return fmt.Errorf("Kafka REST Proxy unreachable with %d records pending for %s", len(s.pending), s.config.Topic)
+	}
+	for _, priceData := range data {
+		s.pending = append(s.pending, Record{Key: []byte(priceData.Ticker), Value: s.value(priceData)})
+	}
+	if len(s.pending) < batchSize {
+		return nil
+	}
+	// The ticks are buffered either way; a failed request is reported by the next Flush
+	if err := s.send(ctx); err != nil {
+		log.Printf("KafkaSink: %v", err)
+	}
+	return nil
+}
+
+// value encodes a tick in the configured format
+func (s *Sink) value(data *domain.PriceData) []byte {
+	if s.config.Format == "avro" {
+		return tickavro.AppendPriceData(tickavro.AppendHeader(nil, s.schemaID), data)
+	}
+	return tickpb.AppendPriceData(nil, data)
+}
+
+// Flush produces the pending records
+func (s *Sink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.send(ctx)
+}
+
+// send produces the pending records in batches, keeping those that were not acknowledged; the
+// caller holds mu
+// A request that fails as a whole stops the flush, so an unreachable proxy costs one timeout
+func (s *Sink) send(ctx context.Context) error {
+	var kept []Record
+	var firstErr error
+	for start := 0; start < len(s.pending); start += batchSize {
+		batch := s.pending[start:min(start+batchSize, len(s.pending))]
+		failed, err := s.producer.Produce(ctx, s.config.Topic, batch)
+		if err == nil {
+			continue
+		}
+		if failed == nil {
+			kept = append(kept, s.pending[start:]...)
+			s.pending = kept
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		for _, i := range failed {
+			kept = append(kept, batch[i])
+		}
+	}
+	s.pending = kept
+	return firstErr
+}
+
+// Close produces the pending records until ctx is done
+func (s *Sink) Close(ctx context.Context) error {
+	return s.Flush(ctx)
+}
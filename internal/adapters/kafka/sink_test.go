@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/tickavro"
+)
+
+// fakeConfluent serves the Schema Registry and REST Proxy endpoints the sink uses
+type fakeConfluent struct {
+	t *testing.T
+
+	mu            sync.Mutex
+	compatibility string
+	schemas       []string
+	produced      []Record
+	reject        map[string]bool // Values rejected by the brokers
+	down          bool
+}
+
+func (f *fakeConfluent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == "/config/fx.ticks-value":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		f.compatibility = body["compatibility"]
+		json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodPost && r.URL.Path == "/compatibility/subjects/fx.ticks-value/versions/latest":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/subjects/fx.ticks-value/versions":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		f.schemas = append(f.schemas, body["schema"])
+		w.Write([]byte(`{"id":7}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/topics/fx.ticks":
+		if f.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != binaryContentType {
+			f.t.Errorf("Unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		var request struct {
+			Records []produceRecord `json:"records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			f.t.Errorf("Invalid produce request: %v", err)
+		}
+		var offsets []string
+		for _, record := range request.Records {
+			if f.reject[string(record.Key)] {
+				offsets = append(offsets, `{"partition":0,"offset":null,"error_code":2,"error":"leader not available"}`)
+				continue
+			}
+			f.produced = append(f.produced, Record(record))
+			offsets = append(offsets, `{"partition":0,"offset":1,"error_code":null,"error":null}`)
+		}
+		w.Write([]byte(`{"offsets":[` + strings.Join(offsets, ",") + `]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSink_ProducesRegisteredAvroRecords(t *testing.T) {
+	fake := &fakeConfluent{t: t, reject: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	sink, err := NewSink(Config{RESTURL: server.URL, Topic: "fx.ticks", Format: "avro", RegistryURL: server.URL, Compatibility: "FULL_TRANSITIVE"})
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+	if fake.compatibility != "FULL_TRANSITIVE" || len(fake.schemas) != 1 || fake.schemas[0] != tickavro.Schema {
+		t.Fatalf("Expected the schema registered under FULL_TRANSITIVE, got %q with %d schemas", fake.compatibility, len(fake.schemas))
+	}
+
+	ctx := context.Background()
+	tick := &domain.PriceData{Timestamp: time.Date(2025, 11, 26, 14, 30, 0, 0, time.UTC), Uic: 21, Ticker: "EURUSD", AssetType: "FxSpot", Bid: 1.15123, Ask: 1.15131}
+	if err := sink.Record(ctx, tick); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if len(fake.produced) != 0 {
+		t.Fatal("Expected records to wait for Flush")
+	}
+	if err := sink.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(fake.produced) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(fake.produced))
+	}
+	record := fake.produced[0]
+	if string(record.Key) != "EURUSD" {
+		t.Errorf("Expected key EURUSD, got %q", record.Key)
+	}
+	schemaID, payload, err := tickavro.ParseHeader(record.Value)
+	if err != nil || schemaID != 7 {
+		t.Fatalf("Expected schema ID 7, got %d (%v)", schemaID, err)
+	}
+	decoded, err := tickavro.UnmarshalPriceData(payload)
+	if err != nil {
+		t.Fatalf("UnmarshalPriceData failed: %v", err)
+	}
+	if decoded.Ticker != "EURUSD" || decoded.Bid != tick.Bid || !decoded.Timestamp.Equal(tick.Timestamp) {
+		t.Errorf("Expected %+v, got %+v", tick, decoded)
+	}
+}
+
+func TestSink_KeepsRecordsThatWereNotAcknowledged(t *testing.T) {
+	fake := &fakeConfluent{t: t, reject: map[string]bool{"USDJPY": true}, down: true}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	sink, err := NewSink(Config{RESTURL: server.URL, Topic: "fx.ticks", Format: "proto"})
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+	ctx := context.Background()
+	ticks := []*domain.PriceData{{Ticker: "EURUSD"}, {Ticker: "USDJPY"}, {Ticker: "GBPUSD"}}
+	if err := sink.RecordBatch(ctx, ticks); err != nil {
+		t.Fatalf("RecordBatch failed: %v", err)
+	}
+
+	// An unreachable proxy keeps every record
+	if err := sink.Flush(ctx); err == nil {
+		t.Fatal("Expected Flush to fail while the proxy is down")
+	}
+	if len(sink.pending) != 3 {
+		t.Fatalf("Expected 3 pending records, got %d", len(sink.pending))
+	}
+
+	// A partly rejected request keeps only the rejected record
+	fake.down = false
+	if err := sink.Flush(ctx); err == nil || !strings.Contains(err.Error(), "leader not available") {
+		t.Fatalf("Expected the rejection, got %v", err)
+	}
+	if len(fake.produced) != 2 || len(sink.pending) != 1 || string(sink.pending[0].Key) != "USDJPY" {
+		t.Fatalf("Expected 2 produced and USDJPY pending, got %d produced and %d pending", len(fake.produced), len(sink.pending))
+	}
+
+	fake.reject = nil
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(fake.produced) != 3 || len(sink.pending) != 0 {
+		t.Errorf("Expected every record produced once, got %d produced and %d pending", len(fake.produced), len(sink.pending))
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{RESTURL: "http://rest-proxy:8082", Topic: "fx.ticks", Format: "avro", RegistryURL: "http://schema-registry:8081"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid configuration, got %v", err)
+	}
+	for name, config := range map[string]Config{
+		"no proxy":      {Topic: "fx.ticks", Format: "proto"},
+		"no topic":      {RESTURL: "http://rest-proxy:8082", Format: "proto"},
+		"format":        {RESTURL: "http://rest-proxy:8082", Topic: "fx.ticks", Format: "json"},
+		"no registry":   {RESTURL: "http://rest-proxy:8082", Topic: "fx.ticks", Format: "avro"},
+		"compatibility": {RESTURL: "http://rest-proxy:8082", Topic: "fx.ticks", Format: "proto", Compatibility: "SOMETIMES"},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
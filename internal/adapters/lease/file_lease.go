@@ -0,0 +1,149 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// leaseRecord is the JSON content of the lease file
+type leaseRecord struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileLease implements LeaseLock using a lease file on a filesystem shared by all instances
+// Updates are serialized with an exclusive <path>.lock guard file and published by atomic rename
+type FileLease struct {
+	path  string
+	owner string
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// NewFileLease creates a lease stored at path, held by owner for ttl after each renewal
+func NewFileLease(path, owner string, ttl time.Duration) *FileLease {
+	return &FileLease{
+		path:  path,
+		owner: owner,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// TryAcquire acquires the lease, or renews it when already held, and returns when the lease this
+// instance holds expires (the zero time if it doesn't hold it)
+func (l *FileLease) TryAcquire(ctx context.Context) (time.Time, error) {
+	var expiresAt time.Time
+	err := l.withGuard(func() error {
+		current, err := l.read()
+		if err != nil {
+			return err
+		}
+
+		now := l.now()
+		if current != nil && current.Owner != l.owner && now.Before(current.ExpiresAt) {
+			return nil // Held by another live instance
+		}
+
+		record := leaseRecord{Owner: l.owner, ExpiresAt: now.Add(l.ttl)}
+		if err := l.write(record); err != nil {
+			return err
+		}
+		expiresAt = record.ExpiresAt
+		return nil
+	})
+	if errors.Is(err, errGuardBusy) {
+		// Another instance is updating the lease right now - report current ownership, which this
+		// attempt didn't renew
+		current, readErr := l.read()
+		if readErr != nil {
+			return time.Time{}, readErr
+		}
+		if current != nil && current.Owner == l.owner && l.now().Before(current.ExpiresAt) {
+			return current.ExpiresAt, nil
+		}
+		return time.Time{}, nil
+	}
+	return expiresAt, err
+}
+
+// Release gives up the lease (if held) so a standby can take over immediately
+func (l *FileLease) Release(ctx context.Context) error {
+	return l.withGuard(func() error {
+		current, err := l.read()
+		if err != nil || current == nil || current.Owner != l.owner {
+			return err
+		}
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove lease file: %w", err)
+		}
+		return nil
+	})
+}
+
+// errGuardBusy is returned when another instance holds the guard file
+var errGuardBusy = errors.New("lease guard busy")
+
+// withGuard runs fn while holding the exclusive guard file
+// A guard older than the TTL is assumed to be left over from a crashed instance and removed
+func (l *FileLease) withGuard(fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create lease directory: %w", err)
+	}
+
+	guardPath := l.path + ".lock"
+	guard, err := os.OpenFile(guardPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if !os.IsExist(err) {
+			return fmt.Errorf("failed to create lease guard: %w", err)
+		}
+		if info, statErr := os.Stat(guardPath); statErr == nil && l.now().Sub(info.ModTime()) > l.ttl {
+			os.Remove(guardPath)
+		}
+		return errGuardBusy
+	}
+	guard.Close()
+	defer os.Remove(guardPath)
+
+	return fn()
+}
+
+// read returns the current lease, or nil if there is none
+func (l *FileLease) read() (*leaseRecord, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read lease file: %w", err)
+	}
+
+	var record leaseRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		// A corrupt lease can't be trusted by anyone - treat it as free
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// write atomically replaces the lease file
+func (l *FileLease) write(record leaseRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode lease: %w", err)
+	}
+
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lease file: %w", err)
+	}
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		return fmt.Errorf("failed to publish lease file: %w", err)
+	}
+	return nil
+}
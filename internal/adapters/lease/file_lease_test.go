@@ -0,0 +1,98 @@
+package lease
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLease_SingleLeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.lease")
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	primary := NewFileLease(path, "primary", 15*time.Second)
+	standby := NewFileLease(path, "standby", 15*time.Second)
+	primary.now, standby.now = clock, clock
+	ctx := context.Background()
+
+	if until, err := primary.TryAcquire(ctx); err != nil || until.IsZero() {
+		t.Fatalf("Primary should acquire free lease: until=%v err=%v", until, err)
+	}
+	if until, err := standby.TryAcquire(ctx); err != nil || !until.IsZero() {
+		t.Fatalf("Standby must not acquire held lease: until=%v err=%v", until, err)
+	}
+
+	// Renewal keeps the lease alive past the original expiry
+	now = now.Add(10 * time.Second)
+	if until, _ := primary.TryAcquire(ctx); until.IsZero() {
+		t.Fatal("Primary should renew its own lease")
+	}
+	now = now.Add(10 * time.Second)
+	if until, _ := standby.TryAcquire(ctx); !until.IsZero() {
+		t.Fatal("Standby must not acquire renewed lease")
+	}
+
+	// Primary stops renewing - standby takes over after expiry
+	now = now.Add(6 * time.Second)
+	if until, _ := standby.TryAcquire(ctx); until.IsZero() {
+		t.Fatal("Standby should take over expired lease")
+	}
+	if until, _ := primary.TryAcquire(ctx); !until.IsZero() {
+		t.Fatal("Former primary must not reclaim a lease held by standby")
+	}
+}
+
+func TestFileLease_ReleaseHandsOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.lease")
+	primary := NewFileLease(path, "primary", time.Minute)
+	standby := NewFileLease(path, "standby", time.Minute)
+	ctx := context.Background()
+
+	if until, _ := primary.TryAcquire(ctx); until.IsZero() {
+		t.Fatal("Primary should acquire free lease")
+	}
+
+	// Release by a non-owner is a no-op
+	if err := standby.Release(ctx); err != nil {
+		t.Fatalf("Release by non-owner failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("Non-owner release must not remove the lease")
+	}
+
+	if err := primary.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if until, _ := standby.TryAcquire(ctx); until.IsZero() {
+		t.Fatal("Standby should acquire released lease immediately")
+	}
+}
+
+func TestFileLease_BusyGuardReportsStoredExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.lease")
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	now := start
+	primary := NewFileLease(path, "primary", 15*time.Second)
+	primary.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if until, err := primary.TryAcquire(ctx); err != nil || !until.Equal(start.Add(15*time.Second)) {
+		t.Fatalf("Expected the lease until 12:00:15, got %v (%v)", until, err)
+	}
+
+	// Another instance holds the guard: the renewal doesn't extend the lease
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	now = start.Add(10 * time.Second)
+	if until, err := primary.TryAcquire(ctx); err != nil || !until.Equal(start.Add(15*time.Second)) {
+		t.Errorf("Expected the stored expiry 12:00:15, got %v (%v)", until, err)
+	}
+	now = start.Add(16 * time.Second)
+	if until, _ := primary.TryAcquire(ctx); !until.IsZero() {
+		t.Errorf("Expected the expired lease not to be held, got %v", until)
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GaugeVec is a value that can go up and down, partitioned by label values
+// A nil GaugeVec ignores all updates, like a nil CounterVec
+type GaugeVec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64 // Keyed by label values joined with \xff
+}
+
+// Gauge registers a gauge with the given label names
+func (r *Registry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+	}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge for the given label values
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	g.values[strings.Join(labelValues, "\xff")] = value
+	g.mu.Unlock()
+}
+
+// Value returns the current value for the given label values
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[strings.Join(labelValues, "\xff")]
+}
+
+// writeText writes the gauge family with series sorted by label values
+func (g *GaugeVec) writeText(w io.Writer, constNames, constValues []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range slices.Sorted(maps.Keys(g.values)) {
+		var labelValues []string
+		if len(g.labelNames) > 0 {
+			labelValues = strings.Split(key, "\xff")
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels(slices.Concat(constNames, g.labelNames), slices.Concat(constValues, labelValues)), strconv.FormatFloat(g.values[key], 'g', -1, 64))
+	}
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets are upper bounds in seconds for pipeline stage durations, 10µs to 5s
+var DurationBuckets = []float64{0.00001, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// HistogramVec is a histogram partitioned by label values
+// A nil HistogramVec ignores all observations, like a nil CounterVec
+type HistogramVec struct {
+	name       string
+	help       string
+	buckets    []float64
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*histogram // Keyed by label values joined with \xff
+}
+
+// histogram is one labelled series; counts are per bucket, not cumulative
+type histogram struct {
+	counts []uint64 // len(buckets)+1, the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Observe adds value to the histogram for the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogram{counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = v
+	}
+	i, _ := slices.BinarySearch(h.buckets, value) // First bucket with bound >= value
+	v.counts[i]++
+	v.sum += value
+	v.count++
+}
+
+// Count returns the number of observations for the given label values
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v, ok := h.values[strings.Join(labelValues, "\xff")]; ok {
+		return v.count
+	}
+	return 0
+}
+
+// writeText writes the histogram family with series sorted by label values
+func (h *HistogramVec) writeText(w io.Writer, constNames, constValues []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range slices.Sorted(maps.Keys(h.values)) {
+		v := h.values[key]
+		labelValues := slices.Clone(constValues)
+		if len(h.labelNames) > 0 {
+			labelValues = append(labelValues, strings.Split(key, "\xff")...)
+		}
+		labelNames := slices.Concat(constNames, h.labelNames)
+		bucketLabels := append(slices.Clone(labelNames), "le")
+
+		cumulative := uint64(0)
+		for i, count := range v.counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+			}
+			labels := formatLabels(bucketLabels, append(slices.Clone(labelValues), le))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, cumulative)
+		}
+		labels := formatLabels(labelNames, labelValues)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, strconv.FormatFloat(v.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, v.count)
+	}
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import "github.com/bjoelf/fx-collector/pkg/ports"
+
+// Port returns the registry as the services see it (ports.MetricsRegistry)
+func (r *Registry) Port() ports.MetricsRegistry {
+	return portRegistry{r}
+}
+
+// portRegistry adapts the registry's concrete metric types to the ports interfaces
+type portRegistry struct {
+	r *Registry
+}
+
+func (p portRegistry) Counter(name, help string, labelNames ...string) ports.Counter {
+	return p.r.Counter(name, help, labelNames...)
+}
+
+func (p portRegistry) Gauge(name, help string, labelNames ...string) ports.Gauge {
+	return p.r.Gauge(name, help, labelNames...)
+}
+
+func (p portRegistry) DurationHistogram(name, help string, labelNames ...string) ports.Histogram {
+	return p.r.Histogram(name, help, DurationBuckets, labelNames...)
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds the collector's metrics and serves them in the Prometheus text format
+// Only what the collector needs is implemented: labelled counters, gauges and histograms, no client library required
+type Registry struct {
+	mu          sync.Mutex
+	families    []family
+	constNames  []string // Labels added to every series (ConstLabel)
+	constValues []string
+}
+
+// family is a registered metric that writes itself in the text format, every series
+// starting with the given constant labels
+type family interface {
+	writeText(w io.Writer, constNames, constValues []string)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers a monotonically increasing counter with the given label names
+func (r *Registry) Counter(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     make(map[string]*atomic.Uint64),
+	}
+
+	r.register(c)
+	return c
+}
+
+// Histogram registers a histogram with the given upper bucket bounds (ascending) and label names
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:       name,
+		help:       help,
+		buckets:    buckets,
+		labelNames: labelNames,
+		values:     make(map[string]*histogram),
+	}
+	r.register(h)
+	return h
+}
+
+// ConstLabel adds a label with a fixed value to every series, e.g. tenant="acme"
+func (r *Registry) ConstLabel(name, value string) {
+	r.mu.Lock()
+	r.constNames = append(r.constNames, name)
+	r.constValues = append(r.constValues, value)
+	r.mu.Unlock()
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	r.families = append(r.families, f)
+	r.mu.Unlock()
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.WriteText(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	constNames, constValues := slices.Clone(r.constNames), slices.Clone(r.constValues)
+	r.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+	for _, f := range families {
+		f.writeText(out, constNames, constValues)
+	}
+	return out.Flush()
+}
+
+// CounterVec is a counter partitioned by label values
+// A nil CounterVec ignores all updates, so callers need no metrics-enabled checks
+type CounterVec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.RWMutex
+	values map[string]*atomic.Uint64 // Keyed by label values joined with \xff
+}
+
+// Inc adds one to the counter for the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds n to the counter for the given label values
+func (c *CounterVec) Add(n uint64, labelValues ...string) {
+	if c == nil {
+		return
+	}
+	c.value(labelValues).Add(n)
+}
+
+// Value returns the current count for the given label values
+func (c *CounterVec) Value(labelValues ...string) uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.value(labelValues).Load()
+}
+
+// value returns the counter cell for labelValues, creating it on first use
+func (c *CounterVec) value(labelValues []string) *atomic.Uint64 {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.RLock()
+	v, ok := c.values[key]
+	c.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok = c.values[key]; !ok {
+		v = &atomic.Uint64{}
+		c.values[key] = v
+	}
+	return v
+}
+
+// writeText writes the counter family with series sorted by label values
+func (c *CounterVec) writeText(w io.Writer, constNames, constValues []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+
+	c.mu.RLock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	c.mu.RUnlock()
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		c.mu.RLock()
+		value := c.values[key].Load()
+		c.mu.RUnlock()
+		fmt.Fprintf(w, "%s%s %d\n", c.name, formatLabels(slices.Concat(constNames, c.labelNames), slices.Concat(constValues, strings.Split(key, "\xff"))), value)
+	}
+}
+
+// formatLabels renders {name="value",...} with Prometheus escaping
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		fmt.Fprintf(&b, "%s=%q", name, value) // %q escapes backslash, quote and newline like Prometheus
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	registry := NewRegistry()
+	crossed := registry.Counter("fx_crossed_ticks_total", "Ticks with bid >= ask", "ticker", "kind")
+
+	crossed.Inc("USDJPY", "locked")
+	crossed.Inc("EURUSD", "crossed")
+	crossed.Add(2, "EURUSD", "crossed")
+
+	if got := crossed.Value("EURUSD", "crossed"); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := "# HELP fx_crossed_ticks_total Ticks with bid >= ask\n" +
+		"# TYPE fx_crossed_ticks_total counter\n" +
+		"fx_crossed_ticks_total{ticker=\"EURUSD\",kind=\"crossed\"} 3\n" +
+		"fx_crossed_ticks_total{ticker=\"USDJPY\",kind=\"locked\"} 1\n"
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+}
+
+func TestCounterVec_Nil(t *testing.T) {
+	var counter *CounterVec
+	counter.Inc("EURUSD") // Must not panic
+	if counter.Value("EURUSD") != 0 {
+		t.Error("Nil counter should report 0")
+	}
+}
+
+func TestHistogramVec_WriteText(t *testing.T) {
+	registry := NewRegistry()
+	stages := registry.Histogram("fx_stage_seconds", "Stage duration", []float64{0.001, 0.01}, "stage")
+
+	stages.Observe(0.0005, "record")
+	stages.Observe(0.001, "record") // Bounds are inclusive
+	stages.Observe(0.5, "record")
+
+	if got := stages.Count("record"); got != 3 {
+		t.Errorf("Expected 3 observations, got %d", got)
+	}
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP fx_stage_seconds Stage duration\n" +
+		"# TYPE fx_stage_seconds histogram\n" +
+		"fx_stage_seconds_bucket{stage=\"record\",le=\"0.001\"} 2\n" +
+		"fx_stage_seconds_bucket{stage=\"record\",le=\"0.01\"} 2\n" +
+		"fx_stage_seconds_bucket{stage=\"record\",le=\"+Inf\"} 3\n" +
+		"fx_stage_seconds_sum{stage=\"record\"} 0.5015\n" +
+		"fx_stage_seconds_count{stage=\"record\"} 3\n"
+	if got := out.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	var nilHistogram *HistogramVec
+	nilHistogram.Observe(1, "record") // Must not panic
+}
+
+func TestGaugeVec_WriteText(t *testing.T) {
+	registry := NewRegistry()
+	offset := registry.Gauge("fx_clock_offset_seconds", "Clock offset", "source")
+
+	offset.Set(0.25, "ntp")
+	offset.Set(-1.5, "broker")
+	offset.Set(-0.125, "broker")
+	if got := offset.Value("broker"); got != -0.125 {
+		t.Errorf("Expected the last value, got %v", got)
+	}
+
+	var b strings.Builder
+	if err := registry.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP fx_clock_offset_seconds Clock offset\n" +
+		"# TYPE fx_clock_offset_seconds gauge\n" +
+		"fx_clock_offset_seconds{source=\"broker\"} -0.125\n" +
+		"fx_clock_offset_seconds{source=\"ntp\"} 0.25\n"
+	if got := b.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	var nilGauge *GaugeVec
+	nilGauge.Set(1, "ntp") // Must not panic
+}
+
+func TestRegistry_ConstLabel(t *testing.T) {
+	registry := NewRegistry()
+	registry.ConstLabel("tenant", "acme")
+	ticks := registry.Counter("fx_ticks_total", "Ticks recorded", "ticker")
+	uptime := registry.Gauge("fx_uptime_seconds", "Uptime")
+	stages := registry.Histogram("fx_stage_seconds", "Stage duration", []float64{0.01}, "stage")
+
+	ticks.Inc("EURUSD")
+	uptime.Set(5)
+	stages.Observe(0.001, "record")
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"fx_ticks_total{tenant=\"acme\",ticker=\"EURUSD\"} 1\n",
+		"fx_uptime_seconds{tenant=\"acme\"} 5\n",
+		"fx_stage_seconds_bucket{tenant=\"acme\",stage=\"record\",le=\"0.01\"} 1\n",
+		"fx_stage_seconds_count{tenant=\"acme\",stage=\"record\"} 1\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Missing %q in:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	pahomqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// publishTimeout bounds how long a QoS 1/2 publish may wait for the broker's acknowledgement
+const publishTimeout = 5 * time.Second
+
+// PublisherConfig holds MQTT connection and publishing settings
+type PublisherConfig struct {
+	BrokerURL     string // e.g. tcp://localhost:1883 or ssl://broker:8883
+	ClientID      string
+	Username      string
+	Password      string
+	TopicTemplate string // {ticker} is replaced with the instrument ticker, e.g. fx/spread/{ticker}
+	QoS           byte   // 0, 1 or 2
+	Retained      bool   // Keep the last value per topic on the broker for late subscribers
+
+	PriceFormat domain.PriceFormat // float or decimal (exact bid/ask/spread in the JSON payload)
+}
+
+// Validate checks the publisher configuration
+func (c PublisherConfig) Validate() error {
+	if c.BrokerURL == "" {
+		return fmt.Errorf("MQTT broker URL is required")
+	}
+	if c.QoS > 2 {
+		return fmt.Errorf("invalid MQTT QoS %d (must be 0, 1 or 2)", c.QoS)
+	}
+	if !strings.Contains(c.TopicTemplate, "{ticker}") {
+		return fmt.Errorf("MQTT topic template %q must contain {ticker}", c.TopicTemplate)
+	}
+	return nil
+}
+
+// ConfigFromSpec reads a publisher configuration from recorder parameters:
+// mqtt?broker=tcp://host:1883&client_id=..&username=..&password=..&topic=fx/spread/{ticker}&qos=0&retained=false&format=float
+func ConfigFromSpec(spec storage.RecorderSpec) (PublisherConfig, error) {
+	qos, err := strconv.ParseUint(spec.Param("qos", "0"), 10, 8)
+	if err != nil {
+		return PublisherConfig{}, fmt.Errorf("invalid MQTT qos: %w", err)
+	}
+	retained, err := strconv.ParseBool(spec.Param("retained", "false"))
+	if err != nil {
+		return PublisherConfig{}, fmt.Errorf("invalid MQTT retained: %w", err)
+	}
+	format, err := spec.PriceFormat()
+	if err != nil {
+		return PublisherConfig{}, err
+	}
+
+	return PublisherConfig{
+		BrokerURL:     spec.Param("broker", "tcp://localhost:1883"),
+		ClientID:      spec.Param("client_id", "fx-collector"),
+		Username:      spec.Param("username", ""),
+		Password:      spec.Param("password", ""),
+		TopicTemplate: spec.Param("topic", "fx/spread/{ticker}"),
+		QoS:           byte(qos),
+		Retained:      retained,
+		PriceFormat:   format,
+	}, nil
+}
+
+func init() {
+	storage.RegisterRecorder("mqtt", func(spec storage.RecorderSpec) (ports.TickWriter, error) {
+		config, err := ConfigFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublisher(config)
+	})
+}
+
+// Publisher implements TickWriter by publishing each tick as JSON to an MQTT topic per instrument
+// MQTT has no buffering of its own to flush, so only Close is implemented besides writing
+type Publisher struct {
+	client pahomqtt.Client
+	config PublisherConfig
+}
+
+// NewPublisher connects to the broker and returns a publisher
+// The client reconnects automatically; publishes during an outage fail and are retried by the caller
+func NewPublisher(config PublisherConfig) (*Publisher, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := pahomqtt.NewClientOptions().
+		AddBroker(config.BrokerURL).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(10 * time.Second).
+		SetConnectionLostHandler(func(_ pahomqtt.Client, err error) {
+			log.Printf("MQTTPublisher: Connection lost: %v", err)
+		}).
+		SetOnConnectHandler(func(_ pahomqtt.Client) {
+			log.Printf("MQTTPublisher: ✅ Connected to %s", config.BrokerURL)
+		})
+
+	client := pahomqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(15 * time.Second) {
+		return nil, fmt.Errorf("timeout connecting to MQTT broker %s", config.BrokerURL)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %w", config.BrokerURL, err)
+	}
+
+	return &Publisher{
+		client: client,
+		config: config,
+	}, nil
+}
+
+// Record publishes a single price data point
+func (p *Publisher) Record(ctx context.Context, data *domain.PriceData) error {
+	payload, err := data.MarshalJSONFormat(p.config.PriceFormat)
+	if err != nil {
+		return fmt.Errorf("failed to encode tick for %s: %w", data.Ticker, err)
+	}
+
+	token := p.client.Publish(p.topicFor(data.Ticker), p.config.QoS, p.config.Retained, payload)
+
+	// QoS 0 is fire-and-forget; only wait for acknowledged deliveries
+	if p.config.QoS == 0 {
+		return nil
+	}
+
+	select {
+	case <-token.Done():
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(publishTimeout):
+		return fmt.Errorf("timeout publishing tick for %s", data.Ticker)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to publish tick for %s: %w", data.Ticker, err)
+	}
+	return nil
+}
+
+// RecordBatch publishes multiple price data points
+func (p *Publisher) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	for i, priceData := range data {
+		if err := p.Record(ctx, priceData); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+	}
+	return nil
+}
+
+// disconnectQuiesce is how long in-flight messages get to complete on Close
+const disconnectQuiesce = 250 * time.Millisecond
+
+// Close disconnects from the broker, allowing in-flight messages to complete until ctx's deadline
+func (p *Publisher) Close(ctx context.Context) error {
+	quiesce := disconnectQuiesce
+	if deadline, ok := ctx.Deadline(); ok {
+		quiesce = max(min(quiesce, time.Until(deadline)), 0)
+	}
+	p.client.Disconnect(uint(quiesce.Milliseconds()))
+	return nil
+}
+
+// topicFor renders the topic template for a ticker
+func (p *Publisher) topicFor(ticker string) string {
+	return strings.ReplaceAll(p.config.TopicTemplate, "{ticker}", ticker)
+}
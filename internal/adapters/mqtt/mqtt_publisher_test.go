@@ -0,0 +1,224 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	pahomqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// fakeToken completes when done is closed, with err
+type fakeToken struct {
+	done chan struct{}
+	err  error
+}
+
+func (t *fakeToken) Wait() bool                       { <-t.done; return true }
+func (t *fakeToken) WaitTimeout(d time.Duration) bool { return true }
+func (t *fakeToken) Done() <-chan struct{}            { return t.done }
+func (t *fakeToken) Error() error                     { return t.err }
+
+// publishedMessage is one Publish call of fakeClient
+type publishedMessage struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+}
+
+// fakeClient records publishes; acks decides each publish's token (nil: completed without error)
+type fakeClient struct {
+	pahomqtt.Client
+	published    []publishedMessage
+	acks         func(n int) *fakeToken
+	disconnected bool
+}
+
+func (c *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) pahomqtt.Token {
+	c.published = append(c.published, publishedMessage{topic, qos, retained, payload.([]byte)})
+	if c.acks != nil {
+		if token := c.acks(len(c.published)); token != nil {
+			return token
+		}
+	}
+	return completedToken(nil)
+}
+
+func (c *fakeClient) Disconnect(quiesce uint) {
+	c.disconnected = true
+}
+
+// completedToken returns a token that has completed with err
+func completedToken(err error) *fakeToken {
+	token := &fakeToken{done: make(chan struct{}), err: err}
+	close(token.done)
+	return token
+}
+
+func TestPublisherConfig_Validate(t *testing.T) {
+	valid := PublisherConfig{BrokerURL: "tcp://localhost:1883", TopicTemplate: "fx/spread/{ticker}", QoS: 1}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*PublisherConfig)
+	}{
+		{"missing broker", func(c *PublisherConfig) { c.BrokerURL = "" }},
+		{"invalid QoS", func(c *PublisherConfig) { c.QoS = 3 }},
+		{"topic without ticker", func(c *PublisherConfig) { c.TopicTemplate = "fx/spread" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			if err := config.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestPublisher_TopicFor(t *testing.T) {
+	p := &Publisher{config: PublisherConfig{TopicTemplate: "home/fx/{ticker}/spread"}}
+	if got := p.topicFor("EURUSD"); got != "home/fx/EURUSD/spread" {
+		t.Errorf("Unexpected topic: %s", got)
+	}
+}
+
+func TestConfigFromSpec(t *testing.T) {
+	spec, err := storage.ParseRecorderSpec("mqtt?broker=ssl://broker:8883&topic=ticks/{ticker}&qos=1&retained=true&format=decimal")
+	if err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+
+	config, err := ConfigFromSpec(spec)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	want := PublisherConfig{
+		BrokerURL:     "ssl://broker:8883",
+		ClientID:      "fx-collector",
+		TopicTemplate: "ticks/{ticker}",
+		QoS:           1,
+		Retained:      true,
+		PriceFormat:   domain.PriceFormatDecimal,
+	}
+	if config != want {
+		t.Errorf("Expected %+v, got %+v", want, config)
+	}
+
+	if _, err := ConfigFromSpec(storage.RecorderSpec{Name: "mqtt", Params: map[string][]string{"qos": {"x"}}}); err == nil {
+		t.Error("Expected error for invalid qos")
+	}
+}
+
+func TestPublisher_Record(t *testing.T) {
+	client := &fakeClient{}
+	p := &Publisher{client: client, config: PublisherConfig{TopicTemplate: "fx/spread/{ticker}", QoS: 1, Retained: true}}
+
+	tick := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC),
+		Ticker:    "EURUSD",
+		Bid:       1.08451,
+		Ask:       1.08471,
+		Decimals:  5,
+	}
+	tick.CalculateSpread()
+	if err := p.Record(context.Background(), tick); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+
+	if len(client.published) != 1 {
+		t.Fatalf("Expected 1 publish, got %d", len(client.published))
+	}
+	msg := client.published[0]
+	if msg.topic != "fx/spread/EURUSD" || msg.qos != 1 || !msg.retained {
+		t.Errorf("Expected fx/spread/EURUSD with QoS 1 retained, got %s with QoS %d retained %v", msg.topic, msg.qos, msg.retained)
+	}
+	var payload struct {
+		Ticker string  `json:"ticker"`
+		Bid    float64 `json:"bid"`
+		Ask    float64 `json:"ask"`
+	}
+	if err := json.Unmarshal(msg.payload, &payload); err != nil {
+		t.Fatalf("Expected a JSON payload: %v", err)
+	}
+	if payload.Ticker != "EURUSD" || payload.Bid != 1.08451 || payload.Ask != 1.08471 {
+		t.Errorf("Unexpected payload: %s", msg.payload)
+	}
+}
+
+func TestPublisher_RecordWaitsForAcknowledgement(t *testing.T) {
+	brokerErr := errors.New("not authorized")
+	client := &fakeClient{acks: func(int) *fakeToken { return completedToken(brokerErr) }}
+	p := &Publisher{client: client, config: PublisherConfig{TopicTemplate: "fx/{ticker}", QoS: 1}}
+	tick := &domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+
+	if err := p.Record(context.Background(), tick); !errors.Is(err, brokerErr) {
+		t.Errorf("Expected the broker's error for QoS 1, got %v", err)
+	}
+
+	// QoS 0 is fire-and-forget: the token isn't looked at
+	p.config.QoS = 0
+	if err := p.Record(context.Background(), tick); err != nil {
+		t.Errorf("Expected no error for QoS 0, got %v", err)
+	}
+
+	// An acknowledgement that doesn't come is given up with ctx
+	p.config.QoS = 2
+	client.acks = func(int) *fakeToken { return &fakeToken{done: make(chan struct{})} }
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Record(ctx, tick); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+}
+
+func TestPublisher_RecordBatchReportsPartialWrite(t *testing.T) {
+	brokerErr := errors.New("connection lost")
+	client := &fakeClient{acks: func(n int) *fakeToken {
+		if n == 3 {
+			return completedToken(brokerErr)
+		}
+		return nil
+	}}
+	p := &Publisher{client: client, config: PublisherConfig{TopicTemplate: "fx/{ticker}", QoS: 1}}
+
+	batch := make([]*domain.PriceData, 4)
+	for i := range batch {
+		batch[i] = &domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+	}
+	err := p.RecordBatch(context.Background(), batch)
+
+	var partial *ports.PartialWriteError
+	if !errors.As(err, &partial) || partial.Written != 2 {
+		t.Fatalf("Expected a partial write after 2 ticks, got %v", err)
+	}
+	if !errors.Is(err, brokerErr) {
+		t.Errorf("Expected the broker's error, got %v", err)
+	}
+	if len(client.published) != 3 {
+		t.Errorf("Expected publishing to stop at the failed tick, got %d publishes", len(client.published))
+	}
+}
+
+func TestPublisher_Close(t *testing.T) {
+	client := &fakeClient{}
+	p := &Publisher{client: client}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if !client.disconnected {
+		t.Error("Expected the client to disconnect")
+	}
+}
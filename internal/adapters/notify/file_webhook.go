@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// FileWebhook implements FilePublisher by POSTing each finalized file's description as JSON
+type FileWebhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewFileWebhook creates a publisher posting to url
+func NewFileWebhook(url string) *FileWebhook {
+	return &FileWebhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// PublishFile sends a single finalized file
+func (w *FileWebhook) PublishFile(ctx context.Context, file domain.FinalizedFile) error {
+	body, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode file webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create file webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send file webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("file webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
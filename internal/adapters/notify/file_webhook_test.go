@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestFileWebhook_PublishFile(t *testing.T) {
+	var received domain.FinalizedFile
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	file := domain.FinalizedFile{Path: "data/spreads/20251118/EURUSD_14.csv", Dataset: "spreads", Ticker: "EURUSD", Rows: 42, SHA256: "abc"}
+	if err := NewFileWebhook(server.URL).PublishFile(context.Background(), file); err != nil {
+		t.Fatalf("PublishFile failed: %v", err)
+	}
+	if received.Path != file.Path || received.Rows != 42 || received.SHA256 != "abc" {
+		t.Errorf("Unexpected payload: %+v", received)
+	}
+}
+
+func TestFileWebhook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := NewFileWebhook(server.URL).PublishFile(context.Background(), domain.FinalizedFile{}); err == nil {
+		t.Error("Expected an error for status 503")
+	}
+}
@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// IncidentProvider selects the incident management API
+type IncidentProvider string
+
+const (
+	ProviderPagerDuty IncidentProvider = "pagerduty" // PagerDuty Events API v2
+	ProviderOpsgenie  IncidentProvider = "opsgenie"  // Opsgenie Alert API v2
+)
+
+// Default API endpoints; Opsgenie accounts in the EU use https://api.eu.opsgenie.com
+const (
+	pagerDutyURL = "https://events.pagerduty.com"
+	opsgenieURL  = "https://api.opsgenie.com"
+)
+
+// IncidentConfig configures the incident notifier
+type IncidentConfig struct {
+	Provider    IncidentProvider
+	Key         string          // PagerDuty integration (routing) key or Opsgenie API key
+	URL         string          // API base URL (default: the provider's public endpoint)
+	MinSeverity domain.Severity // Events below this severity don't open incidents (default critical)
+}
+
+// IncidentNotifier implements Notifier by opening incidents in PagerDuty or Opsgenie
+// Each incident has a deduplication key per collector, event type and instrument, so repeats
+// of a failure update one incident; an event that ends the condition (see EventType.Resolves)
+// resolves it. The key doesn't depend on the process, so the resolve is always sent: an incident
+// opened before a restart is resolved too, and resolving a key with no open incident does nothing
+type IncidentNotifier struct {
+	cfg    IncidentConfig
+	source string
+	client *http.Client
+}
+
+// NewIncidentNotifier creates an incident notifier
+func NewIncidentNotifier(cfg IncidentConfig) (*IncidentNotifier, error) {
+	switch cfg.Provider {
+	case ProviderPagerDuty:
+		if cfg.URL == "" {
+			cfg.URL = pagerDutyURL
+		}
+	case ProviderOpsgenie:
+		if cfg.URL == "" {
+			cfg.URL = opsgenieURL
+		}
+	default:
+		return nil, fmt.Errorf("unsupported incident provider: %s (pagerduty or opsgenie)", cfg.Provider)
+	}
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("%s needs an API key", cfg.Provider)
+	}
+	if cfg.MinSeverity == "" {
+		cfg.MinSeverity = domain.SeverityCritical
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+
+	source, err := os.Hostname()
+	if err != nil {
+		source = "unknown"
+	}
+	return &IncidentNotifier{
+		cfg:    cfg,
+		source: source,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Notify opens (or updates) the incident of a severe event, or resolves the incident an event ends
+func (n *IncidentNotifier) Notify(ctx context.Context, event domain.Event) error {
+	if raised, ok := event.Type.Resolves(); ok {
+		return n.resolve(ctx, n.dedupKey(raised, event.Ticker), event)
+	}
+
+	if !event.Severity.AtLeast(n.cfg.MinSeverity) {
+		return nil
+	}
+	return n.trigger(ctx, n.dedupKey(event.Type, event.Ticker), event)
+}
+
+// dedupKey identifies one condition of one collector, e.g. fx-collector/fx1/data_gap or
+// fx-collector/fx1/storage_error/EURUSD
+func (n *IncidentNotifier) dedupKey(eventType domain.EventType, ticker string) string {
+	key := "fx-collector/" + n.source + "/" + string(eventType)
+	if ticker != "" {
+		key += "/" + ticker
+	}
+	return key
+}
+
+// trigger opens an incident, or adds the event to the open incident with the same key
+func (n *IncidentNotifier) trigger(ctx context.Context, key string, event domain.Event) error {
+	details := map[string]string{"event": string(event.Type)}
+	if event.Ticker != "" {
+		details["ticker"] = event.Ticker
+	}
+	for k, v := range event.Fields {
+		details[k] = v
+	}
+
+	switch n.cfg.Provider {
+	case ProviderPagerDuty:
+		return n.post(ctx, n.cfg.URL+"/v2/enqueue", map[string]any{
+			"routing_key":  n.cfg.Key,
+			"event_action": "trigger",
+			"dedup_key":    key,
+			"payload": map[string]any{
+				"summary":        eventLine(n.source, event),
+				"source":         n.source,
+				"severity":       string(event.Severity), // critical, warning and info are PagerDuty severities too
+				"timestamp":      event.Timestamp.Format(time.RFC3339),
+				"component":      "fx-collector",
+				"class":          string(event.Type),
+				"custom_details": details,
+			},
+		})
+	default:
+		return n.post(ctx, n.cfg.URL+"/v2/alerts", map[string]any{
+			"message":     truncate(eventLine(n.source, event), 130), // Opsgenie's limit
+			"alias":       key,
+			"description": event.Message,
+			"priority":    opsgeniePriority(event.Severity),
+			"source":      n.source,
+			"tags":        []string{"fx-collector", string(event.Type)},
+			"details":     details,
+		})
+	}
+}
+
+// resolve closes the incident with key, if there is one
+func (n *IncidentNotifier) resolve(ctx context.Context, key string, event domain.Event) error {
+	switch n.cfg.Provider {
+	case ProviderPagerDuty:
+		return n.post(ctx, n.cfg.URL+"/v2/enqueue", map[string]any{
+			"routing_key":  n.cfg.Key,
+			"event_action": "resolve",
+			"dedup_key":    key,
+		})
+	default:
+		err := n.post(ctx, n.cfg.URL+"/v2/alerts/"+url.PathEscape(key)+"/close?identifierType=alias", map[string]any{
+			"source": n.source,
+			"note":   event.Message,
+		})
+		if errors.Is(err, errNotFound) { // No alert with this alias: nothing to close
+			return nil
+		}
+		return err
+	}
+}
+
+// errNotFound is wrapped by post for a 404 response
+var errNotFound = errors.New("not found")
+
+// post sends one API request
+func (n *IncidentNotifier) post(ctx context.Context, endpoint string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", n.cfg.Provider, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", n.cfg.Provider, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.cfg.Provider == ProviderOpsgenie {
+		req.Header.Set("Authorization", "GenieKey "+n.cfg.Key)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", n.cfg.Provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s returned status %d: %w", n.cfg.Provider, resp.StatusCode, errNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", n.cfg.Provider, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// opsgeniePriority maps a severity to an Opsgenie priority (P1 highest)
+func opsgeniePriority(severity domain.Severity) string {
+	switch severity {
+	case domain.SeverityCritical:
+		return "P1"
+	case domain.SeverityWarning:
+		return "P3"
+	}
+	return "P5"
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
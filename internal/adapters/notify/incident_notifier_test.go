@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// incidentRequest is one request received by the fake incident API
+type incidentRequest struct {
+	path string
+	auth string
+	body map[string]any
+}
+
+func newIncidentServer(t *testing.T) (*httptest.Server, func() []incidentRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []incidentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, incidentRequest{path: r.URL.RequestURI(), auth: r.Header.Get("Authorization"), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []incidentRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]incidentRequest{}, requests...)
+	}
+}
+
+func TestIncidentNotifier_PagerDuty(t *testing.T) {
+	server, requests := newIncidentServer(t)
+	notifier, err := NewIncidentNotifier(IncidentConfig{Provider: ProviderPagerDuty, Key: "routing-key", URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewIncidentNotifier failed: %v", err)
+	}
+	ctx := context.Background()
+
+	send := func(eventType domain.EventType, severity domain.Severity) {
+		t.Helper()
+		if err := notifier.Notify(ctx, domain.NewEvent(eventType, severity, "test")); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+	send(domain.EventDataGap, domain.SeverityWarning)  // Below critical
+	send(domain.EventDataGap, domain.SeverityCritical) // Trigger
+	send(domain.EventDataGap, domain.SeverityCritical) // Same dedup key
+	send(domain.EventDataResumed, domain.SeverityInfo) // Resolve
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 requests, got %d: %+v", len(got), got)
+	}
+	key := got[0].body["dedup_key"].(string)
+	if !strings.HasSuffix(key, "/data_gap") || got[1].body["dedup_key"] != key || got[2].body["dedup_key"] != key {
+		t.Errorf("Expected one dedup key, got %v, %v, %v", key, got[1].body["dedup_key"], got[2].body["dedup_key"])
+	}
+	actions := []any{got[0].body["event_action"], got[1].body["event_action"], got[2].body["event_action"]}
+	if actions[0] != "trigger" || actions[1] != "trigger" || actions[2] != "resolve" {
+		t.Errorf("Unexpected actions %v", actions)
+	}
+	if got[0].path != "/v2/enqueue" || got[0].body["routing_key"] != "routing-key" {
+		t.Errorf("Unexpected request %+v", got[0])
+	}
+	payload := got[0].body["payload"].(map[string]any)
+	if payload["severity"] != "critical" || payload["class"] != "data_gap" {
+		t.Errorf("Unexpected payload %v", payload)
+	}
+}
+
+func TestIncidentNotifier_ResolvesIncidentsOfAnEarlierProcess(t *testing.T) {
+	server, requests := newIncidentServer(t)
+	cfg := IncidentConfig{Provider: ProviderPagerDuty, Key: "routing-key", URL: server.URL}
+	before, _ := NewIncidentNotifier(cfg)
+	ctx := context.Background()
+	if err := before.Notify(ctx, domain.NewEvent(domain.EventDataGap, domain.SeverityCritical, "gap")); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	// The collector restarted: a new notifier resolves the incident the old one opened
+	after, _ := NewIncidentNotifier(cfg)
+	if err := after.Notify(ctx, domain.NewEvent(domain.EventDataResumed, domain.SeverityInfo, "resumed")); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 || got[1].body["event_action"] != "resolve" || got[1].body["dedup_key"] != got[0].body["dedup_key"] {
+		t.Errorf("Expected the restarted notifier to resolve the open incident, got %+v", got)
+	}
+}
+
+func TestIncidentNotifier_OpsgenieCloseWithoutAlert(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Alert does not exist"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+	notifier, _ := NewIncidentNotifier(IncidentConfig{Provider: ProviderOpsgenie, Key: "api-key", URL: server.URL})
+
+	event := domain.NewEvent(domain.EventDiskSpaceRecovered, domain.SeverityInfo, "Disk space recovered")
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Errorf("Expected closing a missing alert to succeed, got %v", err)
+	}
+}
+
+func TestIncidentNotifier_OpsgenieKeysPerTicker(t *testing.T) {
+	server, requests := newIncidentServer(t)
+	notifier, err := NewIncidentNotifier(IncidentConfig{Provider: ProviderOpsgenie, Key: "api-key", URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewIncidentNotifier failed: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, ticker := range []string{"EURUSD", "USDJPY"} {
+		event := domain.NewEvent(domain.EventStorageError, domain.SeverityCritical, "Failed to record price")
+		event.Ticker = ticker
+		notifier.Notify(ctx, event)
+	}
+	notifier.Notify(ctx, domain.NewEvent(domain.EventDiskSpaceLow, domain.SeverityCritical, "Low disk space"))
+	notifier.Notify(ctx, domain.NewEvent(domain.EventDiskSpaceRecovered, domain.SeverityInfo, "Disk space recovered"))
+
+	got := requests()
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 requests, got %d: %+v", len(got), got)
+	}
+	if got[0].body["alias"] == got[1].body["alias"] {
+		t.Errorf("Expected a dedup key per instrument, got %v twice", got[0].body["alias"])
+	}
+	if got[0].auth != "GenieKey api-key" || got[0].path != "/v2/alerts" || got[0].body["priority"] != "P1" {
+		t.Errorf("Unexpected create request %+v", got[0])
+	}
+	if !strings.HasPrefix(got[3].path, "/v2/alerts/") || !strings.HasSuffix(got[3].path, "/close?identifierType=alias") {
+		t.Errorf("Unexpected close request %s", got[3].path)
+	}
+}
+
+func TestNewIncidentNotifier_Invalid(t *testing.T) {
+	if _, err := NewIncidentNotifier(IncidentConfig{Provider: "victorops", Key: "key"}); err == nil {
+		t.Error("Expected error for unsupported provider")
+	}
+	if _, err := NewIncidentNotifier(IncidentConfig{Provider: ProviderPagerDuty}); err == nil {
+		t.Error("Expected error without a key")
+	}
+}
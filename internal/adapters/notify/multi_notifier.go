@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// MultiNotifier sends every event to several channels (e.g. webhook and email)
+type MultiNotifier struct {
+	notifiers []ports.Notifier
+}
+
+// NewMultiNotifier creates a notifier fanning out to notifiers, in order
+func NewMultiNotifier(notifiers ...ports.Notifier) *MultiNotifier {
+	return &MultiNotifier{notifiers: notifiers}
+}
+
+// Notify sends the event to every channel; a failing channel doesn't keep it from the others
+func (m *MultiNotifier) Notify(ctx context.Context, event domain.Event) error {
+	var errs []error
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// TemplateData is what a payload template sees: the event's fields (.Type, .Severity, .Timestamp,
+// .Ticker, .Message, .Fields) plus the sending collector and the default chat line
+// Events of an instrument carry its latest quote in .Fields (bid, ask, spread, spread_pips,
+// hour_spread_min/avg/max...); a missing field renders as an empty string
+type TemplateData struct {
+	domain.Event
+	Source string // Hostname of the collector
+	Text   string // The line the slack and discord formats send
+}
+
+// PayloadTemplate renders webhook request bodies with Go's text/template, for receivers that
+// expect their own payload (Slack blocks, PagerDuty or Opsgenie events...)
+type PayloadTemplate struct {
+	tmpl *template.Template
+}
+
+// templateFuncs are available in payload templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	// json encodes a value, e.g. "text": {{json .Message}} gives a quoted and escaped string
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// default returns fallback if value is empty: {{default "-" (index .Fields "spread_pips")}}
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// ParsePayloadTemplate parses a payload template and renders a sample event with it, so
+// mistakes such as unknown fields are reported at startup rather than with the first alert
+func ParsePayloadTemplate(text string) (*PayloadTemplate, error) {
+	tmpl, err := template.New("payload").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse payload template: %w", err)
+	}
+	t := &PayloadTemplate{tmpl: tmpl}
+
+	sample := domain.NewEvent(domain.EventDataGap, domain.SeverityWarning, "No price updates for 5m0s")
+	sample.Ticker = "EURUSD"
+	sample.Fields = map[string]string{"bid": "1.084210", "ask": "1.084230", "spread": "0.000020", "spread_pips": "0.2"}
+	body, _, err := t.render(TemplateData{Event: sample, Source: "sample", Text: "sample"})
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("payload template renders an empty body")
+	}
+	return t, nil
+}
+
+// LoadPayloadTemplate reads and parses a payload template file
+func LoadPayloadTemplate(path string) (*PayloadTemplate, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload template: %w", err)
+	}
+	t, err := ParsePayloadTemplate(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// render executes the template; the content type is JSON when the body is valid JSON, plain text otherwise
+func (t *PayloadTemplate) render(data TemplateData) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return nil, "", fmt.Errorf("failed to render payload template: %w", err)
+	}
+	body := bytes.TrimSpace(buf.Bytes())
+	if json.Valid(body) {
+		return body, "application/json", nil
+	}
+	return body, "text/plain; charset=utf-8", nil
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestWebhookNotifier_PayloadTemplate(t *testing.T) {
+	tmpl, err := ParsePayloadTemplate(`{
+  "summary": {{json (printf "%s %s: %s" (upper (printf "%s" .Severity)) .Ticker .Message)}},
+  "severity": {{if eq .Severity "critical"}}"critical"{{else}}"warning"{{end}},
+  "spread_pips": {{json (default "n/a" (index .Fields "spread_pips"))}},
+  "source": {{json .Source}}
+}`)
+	if err != nil {
+		t.Fatalf("ParsePayloadTemplate failed: %v", err)
+	}
+
+	var received map[string]string
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, FormatGeneric, WithPayloadTemplate(tmpl))
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	event := domain.NewEvent(domain.EventStorageError, domain.SeverityCritical, `Failed to record "price"`)
+	event.Ticker = "EURUSD"
+	event.Fields = map[string]string{"spread_pips": "0.3"}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Unexpected content type %s", contentType)
+	}
+	if received["summary"] != `CRITICAL EURUSD: Failed to record "price"` || received["severity"] != "critical" || received["spread_pips"] != "0.3" {
+		t.Errorf("Unexpected payload %v", received)
+	}
+
+	// Events without a quote render the fallback
+	if err := notifier.Notify(context.Background(), domain.NewEvent(domain.EventDisconnected, domain.SeverityWarning, "Disconnected")); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if received["spread_pips"] != "n/a" || received["severity"] != "warning" {
+		t.Errorf("Unexpected payload %v", received)
+	}
+}
+
+func TestWebhookNotifier_PlainTextTemplate(t *testing.T) {
+	tmpl, err := ParsePayloadTemplate(`{{.Type}} {{.Ticker}} {{.Fields.spread}}`)
+	if err != nil {
+		t.Fatalf("ParsePayloadTemplate failed: %v", err)
+	}
+
+	var body, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	notifier, _ := NewWebhookNotifier(server.URL, FormatGeneric, WithPayloadTemplate(tmpl))
+	event := domain.NewEvent(domain.EventDataGap, domain.SeverityWarning, "No price updates")
+	event.Ticker = "USDJPY"
+	event.Fields = map[string]string{"spread": "0.0150"}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if body != "data_gap USDJPY 0.0150" || contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Unexpected body %q (%s)", body, contentType)
+	}
+}
+
+func TestParsePayloadTemplate_Invalid(t *testing.T) {
+	for _, text := range []string{
+		`{{.Message`,       // Syntax error
+		`{{.Instrument}}`,  // No such field
+		`{{json .Message}`, // Unclosed action
+		`   `,              // Empty body
+	} {
+		if _, err := ParsePayloadTemplate(text); err == nil {
+			t.Errorf("Expected error for %q", text)
+		}
+	}
+}
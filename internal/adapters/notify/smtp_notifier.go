@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"maps"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// maxEventsPerMail caps the events listed in one mail; the rest are counted
+const maxEventsPerMail = 100
+
+const (
+	smtpTimeout       = 30 * time.Second // Bounds connecting to and talking with the mail server
+	smtpRetryInterval = time.Minute      // Wait before retrying a mail that failed
+	maxPendingEvents  = 1000             // Events kept for a retry while the mail server is down; the oldest go first
+)
+
+// SMTPConfig configures the email notifier
+type SMTPConfig struct {
+	Addr     string   // Mail server host:port; STARTTLS is used when the server offers it
+	Username string   // PLAIN auth (optional, needs TLS unless the server is on localhost)
+	Password string   // PLAIN auth password
+	From     string   // Sender address
+	To       []string // Recipients
+
+	MinSeverity domain.Severity // Events below this severity are not mailed (default critical)
+	BatchWindow time.Duration   // Events within this window after the first go out in one mail (0 = one mail each)
+}
+
+// Validate checks the configuration
+func (c SMTPConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", c.Addr, err)
+	}
+	if c.From == "" {
+		return fmt.Errorf("SMTP sender address is required")
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("at least one SMTP recipient is required")
+	}
+	if c.BatchWindow < 0 {
+		return fmt.Errorf("SMTP batch window must not be negative, got %v", c.BatchWindow)
+	}
+	return nil
+}
+
+// SMTPNotifier implements Notifier by sending email
+// Events are batched: the first event opens a window, and everything arriving within it is sent as
+// one mail, so a flapping connection or a failing disk causes one mail per window instead of a storm
+// A mail that can't be sent keeps its events queued; they go out with the next mail, retried
+// every smtpRetryInterval
+type SMTPNotifier struct {
+	cfg    SMTPConfig
+	source string
+	send   func(ctx context.Context, msg []byte) error
+
+	mu      sync.Mutex
+	pending []domain.Event
+	timer   *time.Timer
+}
+
+// NewSMTPNotifier creates an email notifier
+func NewSMTPNotifier(cfg SMTPConfig) (*SMTPNotifier, error) {
+	if cfg.MinSeverity == "" {
+		cfg.MinSeverity = domain.SeverityCritical
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	source, err := os.Hostname()
+	if err != nil {
+		source = "unknown"
+	}
+	n := &SMTPNotifier{cfg: cfg, source: source}
+	n.send = n.sendMail
+	return n, nil
+}
+
+// Notify queues an event for the next mail; without a batch window it is sent right away
+func (n *SMTPNotifier) Notify(ctx context.Context, event domain.Event) error {
+	if !event.Severity.AtLeast(n.cfg.MinSeverity) {
+		return nil
+	}
+
+	n.mu.Lock()
+	n.pending = append(n.pending, event)
+	if n.cfg.BatchWindow <= 0 {
+		n.mu.Unlock()
+		return n.Flush(ctx)
+	}
+	n.schedule(n.cfg.BatchWindow)
+	n.mu.Unlock()
+	return nil
+}
+
+// Flush sends the queued events now; if that fails they stay queued and are retried later
+func (n *SMTPNotifier) Flush(ctx context.Context) error {
+	n.mu.Lock()
+	events := n.pending
+	n.pending = nil
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	n.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	err := n.deliver(ctx, events)
+	if err != nil {
+		n.mu.Lock()
+		n.pending = append(events, n.pending...)
+		if dropped := len(n.pending) - maxPendingEvents; dropped > 0 {
+			log.Printf("SMTP notifier: dropped %d events queued while the mail server is unreachable", dropped)
+			n.pending = slices.Delete(n.pending, 0, dropped)
+		}
+		n.schedule(smtpRetryInterval)
+		n.mu.Unlock()
+	}
+	return err
+}
+
+// schedule flushes the queue after delay unless a flush is already scheduled; called with mu held
+func (n *SMTPNotifier) schedule(delay time.Duration) {
+	if n.timer != nil {
+		return
+	}
+	n.timer = time.AfterFunc(delay, func() {
+		if err := n.Flush(context.Background()); err != nil {
+			log.Printf("SMTP notifier: %v", err)
+		}
+	})
+}
+
+// Close sends the queued events so a shutdown doesn't swallow the alert that caused it
+// Events that still can't be sent are lost
+func (n *SMTPNotifier) Close(ctx context.Context) error {
+	err := n.Flush(ctx)
+	n.mu.Lock()
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	n.mu.Unlock()
+	return err
+}
+
+// deliver sends events as one mail
+func (n *SMTPNotifier) deliver(ctx context.Context, events []domain.Event) error {
+	if err := n.send(ctx, n.message(events, time.Now())); err != nil {
+		return fmt.Errorf("failed to send mail with %d events: %w", len(events), err)
+	}
+	return nil
+}
+
+// sendMail delivers one message, giving up after smtpTimeout or when ctx is done
+// Like smtp.SendMail it upgrades to TLS when the server offers STARTTLS
+func (n *SMTPNotifier) sendMail(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() }) // Unblocks the exchange when ctx is cancelled
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(n.cfg.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range n.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the mail: a subject naming the most severe event, and one line per event
+func (n *SMTPNotifier) message(events []domain.Event, now time.Time) []byte {
+	worst := events[0]
+	for _, event := range events[1:] {
+		if !worst.Severity.AtLeast(event.Severity) {
+			worst = event
+		}
+	}
+	subject := fmt.Sprintf("[fx-collector@%s] %s: %s", n.source, strings.ToUpper(string(worst.Severity)), worst.Message)
+	if len(events) > 1 {
+		subject += fmt.Sprintf(" (+%d more)", len(events)-1)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	for i, event := range events {
+		if i == maxEventsPerMail {
+			fmt.Fprintf(&msg, "... and %d more events (see the ops log)\r\n", len(events)-i)
+			break
+		}
+		msg.WriteString(eventLine(n.source, event))
+		msg.WriteString("\r\n")
+		for _, key := range slices.Sorted(maps.Keys(event.Fields)) {
+			fmt.Fprintf(&msg, "    %s=%s\r\n", key, event.Fields[key])
+		}
+	}
+	return msg.Bytes()
+}
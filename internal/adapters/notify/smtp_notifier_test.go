@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// mailbox records the mails an SMTPNotifier sends; while down, sending fails
+type mailbox struct {
+	mu    sync.Mutex
+	mails []string
+	down  bool
+}
+
+func (m *mailbox) send(ctx context.Context, msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return errors.New("connection refused")
+	}
+	m.mails = append(m.mails, string(msg))
+	return nil
+}
+
+func (m *mailbox) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.mails)
+}
+
+func newTestSMTPNotifier(t *testing.T, window time.Duration) (*SMTPNotifier, *mailbox) {
+	t.Helper()
+	notifier, err := NewSMTPNotifier(SMTPConfig{
+		Addr:        "mail.example.com:587",
+		From:        "fx@example.com",
+		To:          []string{"ops@example.com"},
+		BatchWindow: window,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier failed: %v", err)
+	}
+	box := &mailbox{}
+	notifier.send = box.send
+	return notifier, box
+}
+
+func TestSMTPNotifier_Batches(t *testing.T) {
+	notifier, box := newTestSMTPNotifier(t, time.Hour)
+	ctx := context.Background()
+
+	storage := domain.NewEvent(domain.EventStorageError, domain.SeverityCritical, "Failed to record price: disk full")
+	storage.Ticker = "EURUSD"
+	storage.Fields = map[string]string{"spread_pips": "0.2"}
+	for _, event := range []domain.Event{
+		storage,
+		domain.NewEvent(domain.EventDisconnected, domain.SeverityWarning, "WebSocket disconnected"), // Below critical
+		domain.NewEvent(domain.EventAuthFailure, domain.SeverityCritical, "Authentication failed"),
+	} {
+		if err := notifier.Notify(ctx, event); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+	if box.count() != 0 {
+		t.Fatalf("Expected no mail before the batch window ends, got %d", box.count())
+	}
+
+	if err := notifier.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if box.count() != 1 {
+		t.Fatalf("Expected one mail, got %d", box.count())
+	}
+	mail := box.mails[0]
+	for _, want := range []string{"Subject: ", "(+1 more)", "disk full", "spread_pips=0.2", "Authentication failed", "To: ops@example.com"} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("Mail is missing %q:\n%s", want, mail)
+		}
+	}
+	if strings.Contains(mail, "WebSocket disconnected") {
+		t.Errorf("Warning should not be mailed:\n%s", mail)
+	}
+
+	// Nothing queued, nothing sent
+	notifier.Flush(ctx)
+	if box.count() != 1 {
+		t.Errorf("Expected no mail for an empty batch, got %d", box.count())
+	}
+}
+
+func TestSMTPNotifier_WindowSendsMail(t *testing.T) {
+	notifier, box := newTestSMTPNotifier(t, 20*time.Millisecond)
+	notifier.Notify(context.Background(), domain.NewEvent(domain.EventDataGap, domain.SeverityCritical, "No price updates for 15m0s"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for box.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if box.count() != 1 {
+		t.Fatalf("Expected the batch window to send one mail, got %d", box.count())
+	}
+}
+
+func TestSMTPNotifier_KeepsFailedMailForRetry(t *testing.T) {
+	notifier, box := newTestSMTPNotifier(t, 0)
+	ctx := context.Background()
+
+	box.down = true
+	if err := notifier.Notify(ctx, domain.NewEvent(domain.EventDiskSpaceLow, domain.SeverityCritical, "Low disk space")); err == nil {
+		t.Fatal("Expected an error while the mail server is down")
+	}
+	box.down = false
+	if err := notifier.Notify(ctx, domain.NewEvent(domain.EventAuthFailure, domain.SeverityCritical, "Authentication failed")); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if box.count() != 1 {
+		t.Fatalf("Expected one mail, got %d", box.count())
+	}
+	for _, want := range []string{"Low disk space", "Authentication failed"} {
+		if !strings.Contains(box.mails[0], want) {
+			t.Errorf("Mail is missing the retried event %q:\n%s", want, box.mails[0])
+		}
+	}
+	notifier.Close(ctx)
+}
+
+func TestSMTPNotifier_GivesUpOnAStalledServer(t *testing.T) {
+	// Accepts connections but never sends its greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	notifier, err := NewSMTPNotifier(SMTPConfig{Addr: listener.Addr().String(), From: "fx@example.com", To: []string{"ops@example.com"}})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := notifier.Notify(ctx, domain.NewEvent(domain.EventDataGap, domain.SeverityCritical, "gap")); err == nil {
+		t.Fatal("Expected an error from a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the send to stop with the context, took %v", elapsed)
+	}
+	notifier.Close(ctx) // Drops the retry
+}
+
+func TestSMTPConfig_Validate(t *testing.T) {
+	valid := SMTPConfig{Addr: "localhost:25", From: "fx@example.com", To: []string{"ops@example.com"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+
+	noPort, noFrom, noTo := valid, valid, valid
+	noPort.Addr = "localhost"
+	noFrom.From = ""
+	noTo.To = nil
+	for _, cfg := range []SMTPConfig{noPort, noFrom, noTo} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// ThrottledNotifier suppresses repeats of the same event (type + ticker + severity) within a cooldown
+// Prevents a failing disk or flapping connection from flooding the chat channel
+type ThrottledNotifier struct {
+	next     ports.Notifier
+	cooldown time.Duration
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// NewThrottledNotifier wraps next so each distinct event is sent at most once per cooldown
+func NewThrottledNotifier(next ports.Notifier, cooldown time.Duration) *ThrottledNotifier {
+	return &ThrottledNotifier{
+		next:     next,
+		cooldown: cooldown,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Notify forwards the event unless an identical one was sent within the cooldown
+func (n *ThrottledNotifier) Notify(ctx context.Context, event domain.Event) error {
+	// Severity is part of the key so an escalation (a data gap turning critical) isn't suppressed
+	key := string(event.Type) + "|" + event.Ticker + "|" + string(event.Severity)
+
+	n.mu.Lock()
+	if last, ok := n.lastSent[key]; ok && event.Timestamp.Sub(last) < n.cooldown {
+		n.mu.Unlock()
+		return nil
+	}
+	n.lastSent[key] = event.Timestamp
+	n.mu.Unlock()
+
+	return n.next.Notify(ctx, event)
+}
+
+// SetCooldown changes the cooldown at runtime; it applies to events already sent as well
+func (n *ThrottledNotifier) SetCooldown(cooldown time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cooldown = cooldown
+}
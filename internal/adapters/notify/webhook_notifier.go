@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// WebhookFormat selects the JSON payload shape expected by the receiving service
+type WebhookFormat string
+
+const (
+	FormatGeneric WebhookFormat = "generic" // Raw event JSON
+	FormatSlack   WebhookFormat = "slack"   // Slack incoming webhook: {"text": "..."}
+	FormatDiscord WebhookFormat = "discord" // Discord webhook: {"content": "..."}
+)
+
+// WebhookNotifier implements Notifier by POSTing events to a webhook URL
+type WebhookNotifier struct {
+	url      string
+	format   WebhookFormat
+	template *PayloadTemplate // Replaces the format's payload if set
+	source   string
+	client   *http.Client
+}
+
+// WebhookOption configures optional WebhookNotifier behaviour
+type WebhookOption func(*WebhookNotifier)
+
+// WithPayloadTemplate renders the request body with t instead of the built-in format
+func WithPayloadTemplate(t *PayloadTemplate) WebhookOption {
+	return func(n *WebhookNotifier) {
+		n.template = t
+	}
+}
+
+// NewWebhookNotifier creates a notifier posting to url using the given payload format
+func NewWebhookNotifier(url string, format WebhookFormat, opts ...WebhookOption) (*WebhookNotifier, error) {
+	switch format {
+	case FormatGeneric, FormatSlack, FormatDiscord:
+	default:
+		return nil, fmt.Errorf("unsupported webhook format: %s", format)
+	}
+
+	// Hostname identifies which collector sent the event when several run headless
+	source, err := os.Hostname()
+	if err != nil {
+		source = "unknown"
+	}
+
+	n := &WebhookNotifier{
+		url:    url,
+		format: format,
+		source: source,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n, nil
+}
+
+// Notify sends a single event
+func (n *WebhookNotifier) Notify(ctx context.Context, event domain.Event) error {
+	body, contentType, err := n.payload(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// payload builds the request body and its content type for the configured format or template
+func (n *WebhookNotifier) payload(event domain.Event) ([]byte, string, error) {
+	if n.template != nil {
+		return n.template.render(TemplateData{Event: event, Source: n.source, Text: n.text(event)})
+	}
+
+	var body []byte
+	var err error
+	switch n.format {
+	case FormatSlack:
+		body, err = json.Marshal(map[string]string{"text": n.text(event)})
+	case FormatDiscord:
+		body, err = json.Marshal(map[string]string{"content": n.text(event)})
+	default:
+		body, err = json.Marshal(struct {
+			Source string `json:"source"`
+			domain.Event
+		}{Source: n.source, Event: event})
+	}
+	return body, "application/json", err
+}
+
+// text renders an event as a single human-readable chat line
+func (n *WebhookNotifier) text(event domain.Event) string {
+	return eventLine(n.source, event)
+}
+
+// eventLine renders an event as a single human-readable line, for chat messages and mail bodies
+func eventLine(source string, event domain.Event) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] fx-collector@%s: %s", strings.ToUpper(string(event.Severity)), source, event.Message)
+	if event.Ticker != "" {
+		fmt.Fprintf(&sb, " (ticker=%s)", event.Ticker)
+	}
+	fmt.Fprintf(&sb, " [%s at %s]", event.Type, event.Timestamp.Format(time.RFC3339))
+	return sb.String()
+}
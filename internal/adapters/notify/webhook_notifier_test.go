@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestWebhookNotifier_Formats(t *testing.T) {
+	event := domain.NewEvent(domain.EventDataGap, domain.SeverityWarning, "No price updates for 5m0s")
+	event.Ticker = "EURUSD"
+
+	tests := []struct {
+		format WebhookFormat
+		key    string
+	}{
+		{FormatSlack, "text"},
+		{FormatDiscord, "content"},
+		{FormatGeneric, "message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var received map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Unexpected content type: %s", ct)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("Failed to decode body: %v", err)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			notifier, err := NewWebhookNotifier(server.URL, tt.format)
+			if err != nil {
+				t.Fatalf("Failed to create notifier: %v", err)
+			}
+
+			if err := notifier.Notify(context.Background(), event); err != nil {
+				t.Fatalf("Notify failed: %v", err)
+			}
+
+			value, ok := received[tt.key].(string)
+			if !ok {
+				t.Fatalf("Payload missing %q: %v", tt.key, received)
+			}
+			if !strings.Contains(value, "No price updates") {
+				t.Errorf("Payload %q does not contain message: %s", tt.key, value)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, FormatSlack)
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	event := domain.NewEvent(domain.EventAuthFailure, domain.SeverityCritical, "Authentication failed")
+	if err := notifier.Notify(context.Background(), event); err == nil {
+		t.Fatal("Expected error for non-2xx status")
+	}
+}
+
+func TestNewWebhookNotifier_InvalidFormat(t *testing.T) {
+	if _, err := NewWebhookNotifier("http://localhost", "teams"); err == nil {
+		t.Fatal("Expected error for unsupported format")
+	}
+}
+
+type countingNotifier struct {
+	events []domain.Event
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, event domain.Event) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestThrottledNotifier_Cooldown(t *testing.T) {
+	inner := &countingNotifier{}
+	notifier := NewThrottledNotifier(inner, time.Minute)
+	ctx := context.Background()
+
+	base := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	send := func(eventType domain.EventType, ticker string, offset time.Duration) {
+		event := domain.Event{Type: eventType, Ticker: ticker, Timestamp: base.Add(offset)}
+		if err := notifier.Notify(ctx, event); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+
+	send(domain.EventStorageError, "EURUSD", 0)
+	send(domain.EventStorageError, "EURUSD", 10*time.Second) // Suppressed
+	send(domain.EventStorageError, "USDJPY", 10*time.Second) // Different ticker
+	send(domain.EventDataGap, "", 20*time.Second)            // Different type
+	send(domain.EventStorageError, "EURUSD", 2*time.Minute)  // Cooldown expired
+
+	if len(inner.events) != 4 {
+		t.Fatalf("Expected 4 forwarded events, got %d", len(inner.events))
+	}
+}
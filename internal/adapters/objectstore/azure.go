@@ -0,0 +1,149 @@
+package objectstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureProvider copies with the Azure CLI (az storage blob upload / download)
+// download-batch would recreate the blob prefix under the destination, so a day is listed and each
+// missing or different blob downloaded on its own, like a sync
+type azureProvider struct {
+	cli       string
+	account   string
+	container string
+	prefix    string // Blob name prefix of the archive, without trailing slash
+	config    AzureConfig
+}
+
+// env passes the credentials in the variables az reads, keeping them off the command line
+func (p *azureProvider) env() []string {
+	env := setEnv(nil, "AZURE_STORAGE_AUTH_MODE", p.config.AuthMode)
+	env = setEnv(env, "AZURE_STORAGE_KEY", p.config.AccountKey)
+	env = setEnv(env, "AZURE_STORAGE_SAS_TOKEN", p.config.SASToken)
+	return setEnv(env, "AZURE_STORAGE_CONNECTION_STRING", p.config.ConnectionString)
+}
+
+// blobName returns the name of a blob in the archive
+func (p *azureProvider) blobName(day, file string) string {
+	return path.Join(p.prefix, day, file)
+}
+
+func (p *azureProvider) Upload(ctx context.Context, day string, files []string) error {
+	for _, file := range files {
+		name := p.blobName(day, filepath.Base(file))
+		err := run(ctx, p.cli, p.env(), "storage", "blob", "upload", "--only-show-errors", "--overwrite",
+			"--account-name", p.account, "--container-name", p.container, "--name", name, "--file", file)
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (p *azureProvider) Download(ctx context.Context, day, dir string, tickers []string, dryRun bool) error {
+	blobs, err := p.list(ctx, p.blobName(day, "")+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, blob := range blobs {
+		file := path.Base(blob.name)
+		if !matchesTicker(file, tickers) {
+			continue
+		}
+		dst := filepath.Join(dir, file)
+		if info, err := os.Stat(dst); err == nil && info.Size() == blob.size {
+			continue
+		}
+		if dryRun {
+			log.Printf("(dryrun) download: az://%s/%s/%s to %s", p.account, p.container, blob.name, dst)
+			continue
+		}
+		err := run(ctx, p.cli, p.env(), "storage", "blob", "download", "--only-show-errors", "--overwrite",
+			"--account-name", p.account, "--container-name", p.container, "--name", blob.name, "--file", dst)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", blob.name, err)
+		}
+	}
+	return nil
+}
+
+// azureBlob is a listed blob
+type azureBlob struct {
+	name string
+	size int64
+}
+
+// list returns the blobs under prefix
+func (p *azureProvider) list(ctx context.Context, prefix string) ([]azureBlob, error) {
+	cmd := exec.CommandContext(ctx, p.cli, "storage", "blob", "list", "--only-show-errors",
+		"--account-name", p.account, "--container-name", p.container, "--prefix", prefix,
+		"--num-results", "*", "--query", "[].[name, properties.contentLength]", "--output", "tsv")
+	cmd.Env = append(os.Environ(), p.env()...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	var blobs []azureBlob
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		name, sizeText, _ := strings.Cut(scanner.Text(), "\t")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeText), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected blob listing line %q", scanner.Text())
+		}
+		blobs = append(blobs, azureBlob{name: name, size: size})
+	}
+	return blobs, nil
+}
+
+// matchesTicker reports whether a TICKER_HH.csv file belongs to one of the tickers (all if empty)
+func matchesTicker(file string, tickers []string) bool {
+	if len(tickers) == 0 {
+		return true
+	}
+	for _, ticker := range tickers {
+		if strings.HasPrefix(file, ticker+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *azureProvider) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	prefix := ""
+	if p.prefix != "" {
+		prefix = p.prefix + "/"
+	}
+	// With a delimiter, the day directories are listed as virtual directories "prefix/20251101/"
+	out, err := output(ctx, p.cli, p.env(), "storage", "blob", "list", "--only-show-errors",
+		"--account-name", p.account, "--container-name", p.container, "--prefix", prefix, "--delimiter", "/",
+		"--num-results", "*", "--query", "[].name", "--output", "tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	var days []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasSuffix(line, "/") {
+			days = append(days, lastSegment(line))
+		}
+	}
+	base := "az://" + path.Join(p.account, p.container, p.prefix)
+	return prune(ctx, base, days, cutoff, dryRun, func(day string) error {
+		return run(ctx, p.cli, p.env(), "storage", "blob", "delete-batch", "--only-show-errors",
+			"--account-name", p.account, "--source", p.container, "--pattern", p.blobName(day, "*"))
+	})
+}
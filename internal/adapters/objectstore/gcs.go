@@ -0,0 +1,70 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gcsProvider copies with the gcloud CLI (gcloud storage cp / gcloud storage rsync)
+type gcsProvider struct {
+	cli    string
+	base   string // gs://bucket/prefix
+	config GCSConfig
+}
+
+// env points gcloud at the service account key and billing project instead of its own configuration
+func (p *gcsProvider) env() []string {
+	env := setEnv(nil, "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE", p.config.CredentialsFile)
+	return setEnv(env, "CLOUDSDK_BILLING_QUOTA_PROJECT", p.config.Project)
+}
+
+func (p *gcsProvider) Upload(ctx context.Context, day string, files []string) error {
+	for _, file := range files {
+		dst := p.base + "/" + day + "/" + filepath.Base(file)
+		if err := run(ctx, p.cli, p.env(), "storage", "cp", file, dst); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (p *gcsProvider) Download(ctx context.Context, day, dir string, tickers []string, dryRun bool) error {
+	src := p.base + "/" + day + "/"
+	if err := run(ctx, p.cli, p.env(), gcsSyncArgs(src, dir, tickers, dryRun)...); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", src, err)
+	}
+	return nil
+}
+
+// gcsSyncArgs returns the gcloud CLI arguments syncing the day at src into dst
+// gcloud only takes exclude patterns, so every file not starting with a requested ticker is excluded
+func gcsSyncArgs(src, dst string, tickers []string, dryRun bool) []string {
+	args := []string{"storage", "rsync", src, dst}
+	if len(tickers) > 0 {
+		args = append(args, "--exclude", "^(?!("+strings.Join(tickers, "|")+")_)")
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	return args
+}
+
+func (p *gcsProvider) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	out, err := output(ctx, p.cli, p.env(), "storage", "ls", p.base+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", p.base, err)
+	}
+	// Directories are listed as gs://bucket/prefix/20251101/
+	var days []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasSuffix(line, "/") {
+			days = append(days, lastSegment(line))
+		}
+	}
+	return prune(ctx, p.base, days, cutoff, dryRun, func(day string) error {
+		return run(ctx, p.cli, p.env(), "storage", "rm", "--recursive", p.base+"/"+day)
+	})
+}
@@ -0,0 +1,158 @@
+// Package objectstore copies archive days to and from object storage: S3, Google Cloud Storage
+// and Azure Blob Storage
+// Each provider drives the vendor's CLI (aws, gcloud, az), which handles authentication, retries and
+// multipart transfers; Config only passes the provider-specific credentials on to it
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Provider is an object store holding the archive layout (YYYYMMDD/TICKER_HH.csv) under a base URL
+type Provider interface {
+	// Upload copies local files into the day directory of the archive, replacing existing objects
+	Upload(ctx context.Context, day string, files []string) error
+
+	// Download copies the objects of a day missing or different locally into dir
+	// tickers limits the copy to the files of those tickers (all if empty)
+	Download(ctx context.Context, day, dir string, tickers []string, dryRun bool) error
+
+	// Prune deletes the day directories recorded entirely before cutoff, except the most recent one,
+	// and returns their URLs; with dryRun it only lists them (see ports.Pruner)
+	Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error)
+}
+
+// Schemes lists the supported URL schemes
+var Schemes = []string{"s3", "gs", "az"}
+
+// Config holds the CLI paths and credentials of every provider; only the one matching the URL is used
+type Config struct {
+	AWS   AWSConfig
+	GCS   GCSConfig
+	Azure AzureConfig
+}
+
+// AWSConfig configures s3:// URLs
+type AWSConfig struct {
+	CLIPath     string // AWS_CLI_PATH
+	Profile     string // AWS_PROFILE: named profile of ~/.aws/credentials
+	Region      string // AWS_REGION
+	EndpointURL string // S3_ENDPOINT_URL: S3-compatible stores such as MinIO or Cloudflare R2
+}
+
+// GCSConfig configures gs:// URLs
+type GCSConfig struct {
+	CLIPath         string // GCLOUD_PATH
+	CredentialsFile string // GCS_CREDENTIALS_FILE: service account key instead of the gcloud login
+	Project         string // GCS_PROJECT: billing project (requester-pays buckets)
+}
+
+// AzureConfig configures az://account/container/prefix URLs
+type AzureConfig struct {
+	CLIPath          string // AZ_PATH
+	AuthMode         string // AZURE_STORAGE_AUTH_MODE: login (Entra ID) or key
+	AccountKey       string // AZURE_STORAGE_KEY
+	SASToken         string // AZURE_STORAGE_SAS_TOKEN
+	ConnectionString string // AZURE_STORAGE_CONNECTION_STRING
+}
+
+// ConfigFromEnv reads the provider settings with getEnv, the calling command's environment lookup
+func ConfigFromEnv(getEnv func(key, defaultValue string) string) Config {
+	return Config{
+		AWS: AWSConfig{
+			CLIPath:     getEnv("AWS_CLI_PATH", "aws"),
+			Profile:     getEnv("AWS_PROFILE", ""),
+			Region:      getEnv("AWS_REGION", ""),
+			EndpointURL: getEnv("S3_ENDPOINT_URL", ""),
+		},
+		GCS: GCSConfig{
+			CLIPath:         getEnv("GCLOUD_PATH", "gcloud"),
+			CredentialsFile: getEnv("GCS_CREDENTIALS_FILE", ""),
+			Project:         getEnv("GCS_PROJECT", ""),
+		},
+		Azure: AzureConfig{
+			CLIPath:          getEnv("AZ_PATH", "az"),
+			AuthMode:         getEnv("AZURE_STORAGE_AUTH_MODE", ""),
+			AccountKey:       getEnv("AZURE_STORAGE_KEY", ""),
+			SASToken:         getEnv("AZURE_STORAGE_SAS_TOKEN", ""),
+			ConnectionString: getEnv("AZURE_STORAGE_CONNECTION_STRING", ""),
+		},
+	}
+}
+
+// IsURL reports whether rawURL names an object store, as opposed to a local path or another transport
+func IsURL(rawURL string) bool {
+	scheme, _, found := strings.Cut(rawURL, "://")
+	return found && slices.Contains(Schemes, scheme)
+}
+
+// New returns the provider for an s3://, gs:// or az:// URL
+func New(rawURL string, config Config) (Provider, error) {
+	u, err := url.Parse(strings.TrimSuffix(rawURL, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid archive URL %q", rawURL)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		cli, err := exec.LookPath(config.AWS.CLIPath)
+		if err != nil {
+			return nil, fmt.Errorf("AWS CLI not found (install it or set AWS_CLI_PATH): %w", err)
+		}
+		return &s3Provider{cli: cli, base: u.String(), config: config.AWS}, nil
+	case "gs":
+		cli, err := exec.LookPath(config.GCS.CLIPath)
+		if err != nil {
+			return nil, fmt.Errorf("gcloud CLI not found (install it or set GCLOUD_PATH): %w", err)
+		}
+		return &gcsProvider{cli: cli, base: u.String(), config: config.GCS}, nil
+	case "az":
+		cli, err := exec.LookPath(config.Azure.CLIPath)
+		if err != nil {
+			return nil, fmt.Errorf("Azure CLI not found (install it or set AZ_PATH): %w", err)
+		}
+		container, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		if container == "" {
+			return nil, fmt.Errorf("invalid archive URL %q: expected az://account/container[/prefix]", rawURL)
+		}
+		return &azureProvider{cli: cli, account: u.Host, container: container, prefix: prefix, config: config.Azure}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive %q (supported: %s)", rawURL, strings.Join(Schemes, "://, ")+"://")
+	}
+}
+
+// run runs a CLI with extra environment variables
+// Its output is logged (or returned with the error) instead of passed through, as the collector's
+// stdout may carry the ndjson sink
+func run(ctx context.Context, cli string, env []string, args ...string) error {
+	cmd := exec.CommandContext(ctx, cli, args...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", filepath.Base(cli), err, bytes.TrimSpace(out))
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			log.Printf("%s: %s", filepath.Base(cli), line)
+		}
+	}
+	return nil
+}
+
+// setEnv appends name=value to env unless value is empty
+func setEnv(env []string, name, value string) []string {
+	if value == "" {
+		return env
+	}
+	return append(env, name+"="+value)
+}
@@ -0,0 +1,145 @@
+package objectstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCLI writes a stand-in CLI that logs its arguments and AZURE_/AWS_ environment to <dir>/calls
+// and prints listing when called to list ("blob list", "s3 ls" or "storage ls")
+func fakeCLI(t *testing.T, dir, listing string) string {
+	t.Helper()
+	script := `#!/bin/sh
+echo "$@" >> "` + dir + `/calls"
+env | grep -E '^(AZURE_STORAGE|AWS_PROFILE|CLOUDSDK_)' | sort >> "` + dir + `/calls"
+if [ "$2 $3" = "blob list" ] || [ "$1 $2" = "s3 ls" ] || [ "$1 $2" = "storage ls" ]; then printf '` + listing + `'; fi
+`
+	path := filepath.Join(dir, "cli")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNew_ParsesProviderURLs(t *testing.T) {
+	cli := fakeCLI(t, t.TempDir(), "")
+	config := Config{AWS: AWSConfig{CLIPath: cli}, GCS: GCSConfig{CLIPath: cli}, Azure: AzureConfig{CLIPath: cli}}
+
+	azure, err := New("az://fxbackups/archive/spreads/", config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	p := azure.(*azureProvider)
+	if p.account != "fxbackups" || p.container != "archive" || p.prefix != "spreads" {
+		t.Errorf("Unexpected Azure location: %+v", p)
+	}
+	if _, err := New("az://fxbackups", config); err == nil {
+		t.Error("Expected an error for an Azure URL without container")
+	}
+	if s3, err := New("s3://bucket/prefix/", config); err != nil || s3.(*s3Provider).base != "s3://bucket/prefix" {
+		t.Errorf("Unexpected S3 provider: %+v, %v", s3, err)
+	}
+	if _, err := New("ftp://host/dir", config); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+	if !IsURL("gs://bucket") || IsURL("/mnt/backup") || IsURL("sftp://host/dir") {
+		t.Error("IsURL misclassifies URLs")
+	}
+}
+
+func TestAzure_DownloadsMissingBlobsWithCredentialsInEnv(t *testing.T) {
+	dir := t.TempDir()
+	listing := `spreads/20250301/EURUSD_14.csv\t12\nspreads/20250301/EURUSD_15.csv\t5\nspreads/20250301/USDJPY_14.csv\t7\n`
+	config := Config{Azure: AzureConfig{CLIPath: fakeCLI(t, dir, listing), AuthMode: "key", AccountKey: "secret"}}
+	provider, err := New("az://fxbackups/archive/spreads", config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// EURUSD_15 is already restored with the same size
+	local := filepath.Join(dir, "20250301")
+	if err := os.MkdirAll(local, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(local, "EURUSD_15.csv"), []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.Download(context.Background(), "20250301", local, []string{"EURUSD"}, false); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	calls := string(data)
+	if !strings.Contains(calls, "--prefix spreads/20250301/") {
+		t.Errorf("Day not listed under the prefix:\n%s", calls)
+	}
+	if !strings.Contains(calls, "--name spreads/20250301/EURUSD_14.csv --file "+filepath.Join(local, "EURUSD_14.csv")) {
+		t.Errorf("EURUSD_14 not downloaded:\n%s", calls)
+	}
+	if strings.Contains(calls, "EURUSD_15.csv --file") || strings.Contains(calls, "USDJPY_14.csv --file") {
+		t.Errorf("Unexpected downloads:\n%s", calls)
+	}
+	if !strings.Contains(calls, "AZURE_STORAGE_KEY=secret") || strings.Contains(calls, "--account-key") {
+		t.Errorf("Account key not passed through the environment:\n%s", calls)
+	}
+}
+
+func TestS3_UploadsWithProfileAndEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{AWS: AWSConfig{CLIPath: fakeCLI(t, dir, ""), Profile: "backup", EndpointURL: "https://minio.local:9000"}}
+	provider, err := New("s3://fx-backup/spreads", config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := provider.Upload(context.Background(), "20250301", []string{"/data/spreads/20250301/EURUSD_14.csv"}); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	want := "--endpoint-url https://minio.local:9000 s3 cp --only-show-errors /data/spreads/20250301/EURUSD_14.csv s3://fx-backup/spreads/20250301/EURUSD_14.csv"
+	if !strings.Contains(string(data), want) || !strings.Contains(string(data), "AWS_PROFILE=backup") {
+		t.Errorf("Unexpected upload:\n%s", data)
+	}
+}
+
+func TestS3_PrunesExpiredDaysExceptTheNewest(t *testing.T) {
+	dir := t.TempDir()
+	listing := `                           PRE 20251101/\n                           PRE 20251102/\n                           PRE 20251103/\n                           PRE tmp/\n2025-11-03 10:00:00  12 README\n`
+	provider, err := New("s3://fx-backup/spreads", Config{AWS: AWSConfig{CLIPath: fakeCLI(t, dir, listing)}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cutoff := time.Date(2025, 11, 5, 0, 0, 0, 0, time.UTC)
+
+	removed, err := provider.Prune(context.Background(), cutoff, true)
+	if err != nil || strings.Join(removed, " ") != "s3://fx-backup/spreads/20251101 s3://fx-backup/spreads/20251102" {
+		t.Fatalf("Unexpected dry run: %v, %v", removed, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "calls")); strings.Contains(string(data), " rm ") {
+		t.Errorf("Dry run removed objects:\n%s", data)
+	}
+
+	if _, err := provider.Prune(context.Background(), cutoff, false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	calls := string(data)
+	if !strings.Contains(calls, "s3 rm --recursive --only-show-errors s3://fx-backup/spreads/20251102/") || strings.Contains(calls, "20251103/") {
+		t.Errorf("Unexpected removals:\n%s", calls)
+	}
+}
+
+func TestExpiredDays(t *testing.T) {
+	days := []string{"20251103", "20251101", "notaday", "20251102"}
+	if got := ExpiredDays(days, time.Date(2025, 11, 2, 12, 0, 0, 0, time.UTC)); len(got) != 1 || got[0] != "20251101" {
+		t.Errorf("Expected only 20251101 expired, got %v", got)
+	}
+	if got := ExpiredDays([]string{"20251101"}, time.Now()); len(got) != 0 {
+		t.Errorf("Expected the only day to be kept, got %v", got)
+	}
+}
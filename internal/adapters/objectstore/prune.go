@@ -0,0 +1,71 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExpiredDays returns the YYYYMMDD names among days whose whole UTC day is before cutoff, oldest
+// first; the most recent day is kept like in local pruning, and other names are ignored
+func ExpiredDays(days []string, cutoff time.Time) []string {
+	var valid []string
+	for _, day := range days {
+		if _, err := time.Parse("20060102", day); err == nil {
+			valid = append(valid, day)
+		}
+	}
+	sort.Strings(valid)
+
+	var expired []string
+	for _, day := range valid[:max(len(valid)-1, 0)] {
+		start, _ := time.Parse("20060102", day)
+		if start.AddDate(0, 0, 1).After(cutoff) {
+			break
+		}
+		expired = append(expired, day)
+	}
+	return expired
+}
+
+// output runs a CLI with extra environment variables and returns its standard output
+func output(ctx context.Context, cli string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, cli, args...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(cli), err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", filepath.Base(cli), err)
+	}
+	return out, nil
+}
+
+// prune removes the expired days with remove (unless dryRun) and returns their URLs
+func prune(ctx context.Context, base string, days []string, cutoff time.Time, dryRun bool, remove func(day string) error) ([]string, error) {
+	var removed []string
+	for _, day := range ExpiredDays(days, cutoff) {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if !dryRun {
+			if err := remove(day); err != nil {
+				return removed, fmt.Errorf("failed to remove %s/%s: %w", base, day, err)
+			}
+		}
+		removed = append(removed, base+"/"+day)
+	}
+	return removed, nil
+}
+
+// lastSegment returns the last element of a listed directory, "s3://b/p/20251101/" -> "20251101"
+func lastSegment(name string) string {
+	return path.Base(strings.TrimSuffix(strings.TrimSpace(name), "/"))
+}
@@ -0,0 +1,81 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// s3Provider copies with the AWS CLI (aws s3 cp / aws s3 sync)
+type s3Provider struct {
+	cli    string
+	base   string // s3://bucket/prefix
+	config AWSConfig
+}
+
+// env passes the profile and region the way the AWS CLI reads them
+func (p *s3Provider) env() []string {
+	env := setEnv(nil, "AWS_PROFILE", p.config.Profile)
+	return setEnv(env, "AWS_REGION", p.config.Region)
+}
+
+// args prepends the global options to a command
+func (p *s3Provider) args(args ...string) []string {
+	if p.config.EndpointURL != "" {
+		args = append([]string{"--endpoint-url", p.config.EndpointURL}, args...)
+	}
+	return args
+}
+
+func (p *s3Provider) Upload(ctx context.Context, day string, files []string) error {
+	for _, file := range files {
+		dst := p.base + "/" + day + "/" + filepath.Base(file)
+		if err := run(ctx, p.cli, p.env(), p.args("s3", "cp", "--only-show-errors", file, dst)...); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (p *s3Provider) Download(ctx context.Context, day, dir string, tickers []string, dryRun bool) error {
+	src := p.base + "/" + day + "/"
+	if err := run(ctx, p.cli, p.env(), p.args(s3SyncArgs(src, dir, tickers, dryRun)...)...); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", src, err)
+	}
+	return nil
+}
+
+// s3SyncArgs returns the AWS CLI arguments syncing the day at src into dst
+// Include patterns are applied after the exclude, so only the requested tickers are copied
+func s3SyncArgs(src, dst string, tickers []string, dryRun bool) []string {
+	args := []string{"s3", "sync", src, dst, "--only-show-errors"}
+	if len(tickers) > 0 {
+		args = append(args, "--exclude", "*")
+		for _, ticker := range tickers {
+			args = append(args, "--include", ticker+"_*")
+		}
+	}
+	if dryRun {
+		args = append(args, "--dryrun")
+	}
+	return args
+}
+
+func (p *s3Provider) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	out, err := output(ctx, p.cli, p.env(), p.args("s3", "ls", p.base+"/")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", p.base, err)
+	}
+	// Directories are listed as "PRE 20251101/"
+	var days []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "PRE" {
+			days = append(days, lastSegment(fields[1]))
+		}
+	}
+	return prune(ctx, p.base, days, cutoff, dryRun, func(day string) error {
+		return run(ctx, p.cli, p.env(), p.args("s3", "rm", "--recursive", "--only-show-errors", p.base+"/"+day+"/")...)
+	})
+}
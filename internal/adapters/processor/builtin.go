@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+func init() {
+	// filter?exclude=USDTRY&drop_flags=outlier
+	Register("filter", func(spec Spec) (ports.TickProcessor, error) {
+		return NewFilter(spec.Params["include"], spec.Params["exclude"], spec.Params["drop_flags"])
+	})
+	// shift?offset=-150ms&tickers=USDJPY
+	Register("shift", func(spec Spec) (ports.TickProcessor, error) {
+		offset, err := time.ParseDuration(spec.Param("offset", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset: %w", err)
+		}
+		return &Shift{Offset: offset, Tickers: upper(spec.Params["tickers"])}, nil
+	})
+}
+
+// Filter drops ticks by instrument or flag
+type Filter struct {
+	Include   []string         // Only these tickers pass (empty = all)
+	Exclude   []string         // These tickers never pass
+	DropFlags domain.TickFlags // Ticks with any of these flags are dropped
+}
+
+// NewFilter creates a filter from ticker lists and flag names (e.g. "outlier", "sampled")
+func NewFilter(include, exclude, dropFlags []string) (*Filter, error) {
+	f := &Filter{Include: upper(include), Exclude: upper(exclude)}
+	for _, name := range dropFlags {
+		flag, err := domain.ParseTickFlag(name)
+		if err != nil {
+			return nil, err
+		}
+		f.DropFlags |= flag
+	}
+	return f, nil
+}
+
+// Process drops excluded ticks
+func (f *Filter) Process(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error) {
+	if len(f.Include) > 0 && !slices.Contains(f.Include, data.Ticker) {
+		return nil, nil
+	}
+	if slices.Contains(f.Exclude, data.Ticker) || data.Flags&f.DropFlags != 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// Shift moves tick timestamps by a fixed offset, e.g. to correct a feed's known clock skew
+type Shift struct {
+	Offset  time.Duration
+	Tickers []string // Only these tickers are shifted (empty = all)
+}
+
+// Process shifts the timestamp
+func (s *Shift) Process(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error) {
+	if len(s.Tickers) == 0 || slices.Contains(s.Tickers, data.Ticker) {
+		data.Timestamp = data.Timestamp.Add(s.Offset)
+	}
+	return data, nil
+}
+
+// upper uppercases tickers
+func upper(tickers []string) []string {
+	out := make([]string, 0, len(tickers))
+	for _, t := range tickers {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
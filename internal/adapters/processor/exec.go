@@ -0,0 +1,261 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// Restart delays of a failed program: doubled on every failure in a row, up to the maximum
+const (
+	execRestartDelay    = time.Second
+	execMaxRestartDelay = time.Minute
+)
+
+func init() {
+	// exec?cmd=python3+enrich.py&timeout=50ms&on_error=pass
+	Register("exec", func(spec Spec) (ports.TickProcessor, error) {
+		timeout, err := time.ParseDuration(spec.Param("timeout", "100ms"))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q: must be a positive duration", spec.Param("timeout", ""))
+		}
+		onError := spec.Param("on_error", "pass")
+		if onError != "pass" && onError != "drop" {
+			return nil, fmt.Errorf("invalid on_error %q (supported: pass, drop)", onError)
+		}
+		return NewExec(strings.Fields(spec.Param("cmd", "")), timeout, onError == "drop")
+	})
+}
+
+// Exec implements TickProcessor by exchanging ticks with an external program over stdin/stdout
+// Each tick is written as one JSON line (the NDJSON fields, spread in price units); the program
+// answers every line with one line: a JSON object whose fields replace the tick's (fields it
+// leaves out are kept), or null to drop the tick. The program runs for the life of the collector
+// and its stderr goes to the collector log.
+// Process runs on the price goroutine, so the stage fails fast: a program that fails, exits or
+// misses the timeout is bypassed at once - ticks pass unchanged, or are dropped with dropOnError -
+// and stopped and started again in the background, after a delay that grows while it keeps failing
+type Exec struct {
+	command      []string
+	timeout      time.Duration
+	dropOnError  bool
+	restartDelay time.Duration // After the first failure in a row
+	done         chan struct{} // Closed by Close, ending a restart in progress
+
+	mu       sync.Mutex
+	program  *execProgram // nil while bypassed
+	failures int          // Failures in a row, for the restart delay
+	closed   bool
+	restarts sync.WaitGroup
+}
+
+// execProgram is a running program with its pipes
+type execProgram struct {
+	cmd       *exec.Cmd
+	stdin     *os.File
+	responses chan []byte
+}
+
+// NewExec starts command for exchanging ticks
+func NewExec(command []string, timeout time.Duration, dropOnError bool) (*Exec, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("cmd is required")
+	}
+	e := &Exec{command: command, timeout: timeout, dropOnError: dropOnError, restartDelay: execRestartDelay, done: make(chan struct{})}
+	program, err := e.start()
+	if err != nil {
+		return nil, err
+	}
+	e.program = program
+	return e, nil
+}
+
+// start launches the program with pipes for ticks and responses
+func (e *Exec) start() (*execProgram, error) {
+	stdinReader, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		stdin.Close()
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+
+	cmd := exec.Command(e.command[0], e.command[1:]...)
+	cmd.Stdin = stdinReader
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, fmt.Errorf("failed to start %s: %w", e.command[0], err)
+	}
+
+	responses := make(chan []byte)
+	go func() {
+		defer close(responses)
+		defer stdout.Close()
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			responses <- bytes.Clone(scanner.Bytes())
+		}
+	}()
+
+	log.Printf("Processor: ✅ Started %s (pid %d)", strings.Join(e.command, " "), cmd.Process.Pid)
+	return &execProgram{cmd: cmd, stdin: stdin, responses: responses}, nil
+}
+
+// stop kills the program and waits for it
+func (p *execProgram) stop() {
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+	for range p.responses {
+		// Drain so the reader goroutine ends
+	}
+}
+
+// Process sends the tick to the program and applies its answer
+// While the program is being restarted, the tick bypasses the stage without waiting
+func (e *Exec) Process(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, fmt.Errorf("processor closed")
+	}
+	if e.program == nil {
+		return e.fallback(data), nil
+	}
+
+	result, err := e.exchange(ctx, data)
+	if err != nil {
+		e.failures++
+		delay := min(e.restartDelay<<min(e.failures-1, 6), execMaxRestartDelay)
+		log.Printf("Processor: ❌ %s: %v - bypassed, restarting in %v", e.command[0], err, delay)
+		failed := e.program
+		e.program = nil
+		e.restarts.Add(1)
+		go e.restart(failed, delay)
+		return e.fallback(data), nil
+	}
+	e.failures = 0
+	return result, nil
+}
+
+// restart stops a failed program and starts it again after delay, off the price goroutine
+func (e *Exec) restart(failed *execProgram, delay time.Duration) {
+	defer e.restarts.Done()
+	failed.stop()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-e.done:
+			return
+		}
+
+		program, err := e.start()
+		if err == nil {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if e.closed {
+				program.stop()
+				return
+			}
+			e.program = program
+			return
+		}
+		delay = min(2*delay, execMaxRestartDelay)
+		log.Printf("Processor: ❌ %v - retrying in %v", err, delay)
+		timer.Reset(delay)
+	}
+}
+
+// exchange writes one tick and reads the answer; must be called with mu held
+func (e *Exec) exchange(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error) {
+	program := e.program
+	line, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tick: %w", err)
+	}
+
+	deadline := time.Now().Add(e.timeout)
+	program.stdin.SetWriteDeadline(deadline)
+	if _, err := program.stdin.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write tick: %w", err)
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	var response []byte
+	select {
+	case r, ok := <-program.responses:
+		if !ok {
+			return nil, errors.New("program exited")
+		}
+		response = r
+	case <-timer.C:
+		return nil, fmt.Errorf("no answer within %v", e.timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	response = bytes.TrimSpace(response)
+	if bytes.Equal(response, []byte("null")) {
+		return nil, nil
+	}
+	result := *data
+	if err := json.Unmarshal(response, &result); err != nil {
+		return nil, fmt.Errorf("invalid answer %q: %w", response, err)
+	}
+	if result.Bid != data.Bid || result.Ask != data.Ask {
+		result.CalculateSpread()
+	}
+	return &result, nil
+}
+
+// fallback is the result for a tick the program couldn't process
+func (e *Exec) fallback(data *domain.PriceData) *domain.PriceData {
+	if e.dropOnError {
+		return nil
+	}
+	return data
+}
+
+// Close stops the program, waiting for a restart in progress
+func (e *Exec) Close() error {
+	e.mu.Lock()
+	if !e.closed {
+		close(e.done)
+	}
+	e.closed = true
+	if e.program != nil {
+		e.program.stop()
+		e.program = nil
+	}
+	e.mu.Unlock()
+	e.restarts.Wait()
+	return nil
+}
@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// TestHelperProcess is the external program of the exec tests, run as a subprocess
+// It tags EURUSD ticks, drops USDTRY, hangs on USDJPY and exits on GBPUSD
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("FXC_PROCESSOR_HELPER") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var tick domain.PriceData
+		if err := json.Unmarshal(scanner.Bytes(), &tick); err != nil {
+			os.Exit(2)
+		}
+		switch tick.Ticker {
+		case "USDTRY":
+			fmt.Println("null")
+		case "USDJPY":
+			time.Sleep(time.Minute)
+		case "GBPUSD":
+			os.Exit(1)
+		default:
+			fmt.Printf(`{"source":"enriched","ask":%v}`+"\n", tick.Ask+0.0001)
+		}
+	}
+	os.Exit(0)
+}
+
+func newHelperExec(t *testing.T, dropOnError bool) *Exec {
+	t.Helper()
+	t.Setenv("FXC_PROCESSOR_HELPER", "1")
+	e, err := NewExec([]string{os.Args[0], "-test.run=TestHelperProcess"}, 2*time.Second, dropOnError)
+	if err != nil {
+		t.Fatalf("NewExec failed: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestExec(t *testing.T) {
+	e := newHelperExec(t, false)
+	ctx := context.Background()
+
+	tick := &domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, PipSize: 0.0001}
+	got, err := e.Process(ctx, tick)
+	if err != nil || got == nil {
+		t.Fatalf("Process failed: %v, %v", got, err)
+	}
+	if got.Source != "enriched" || got.Ticker != "EURUSD" || got.PipSize != 0.0001 {
+		t.Errorf("Expected the answer merged into the tick, got %+v", got)
+	}
+	if got.Ask != 1.1003 || fmt.Sprintf("%.4f", got.Spread) != "0.0003" {
+		t.Errorf("Expected ask 1.1003 and a recomputed spread, got %v / %v", got.Ask, got.Spread)
+	}
+
+	if got, err := e.Process(ctx, &domain.PriceData{Ticker: "USDTRY"}); err != nil || got != nil {
+		t.Errorf("Expected USDTRY to be dropped, got %v, %v", got, err)
+	}
+}
+
+func TestExec_Failures(t *testing.T) {
+	ctx := context.Background()
+
+	// A program that exits passes the tick unchanged and is restarted after the delay
+	e := newHelperExec(t, false)
+	e.restartDelay = 10 * time.Millisecond
+	tick := &domain.PriceData{Ticker: "GBPUSD"}
+	if got, err := e.Process(ctx, tick); err != nil || got != tick {
+		t.Errorf("Expected the tick to pass unchanged, got %v, %v", got, err)
+	}
+	if got, _ := e.Process(ctx, &domain.PriceData{Ticker: "EURUSD"}); got == nil || got.Source != "" {
+		t.Errorf("Expected ticks to pass unchanged while stopped, got %+v", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		e.mu.Lock()
+		restarted := e.program != nil
+		e.mu.Unlock()
+		if restarted || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got, _ := e.Process(ctx, &domain.PriceData{Ticker: "EURUSD"}); got == nil || got.Source != "enriched" {
+		t.Errorf("Expected the program to be restarted, got %+v", got)
+	}
+
+	// A timeout drops the tick with dropOnError
+	e = newHelperExec(t, true)
+	e.timeout = 50 * time.Millisecond
+	if got, err := e.Process(ctx, &domain.PriceData{Ticker: "USDJPY"}); err != nil || got != nil {
+		t.Errorf("Expected the tick to be dropped, got %v, %v", got, err)
+	}
+}
+
+func TestExec_BypassesWhileRestarting(t *testing.T) {
+	ctx := context.Background()
+	e := newHelperExec(t, false)
+	e.timeout = 50 * time.Millisecond
+
+	if got, _ := e.Process(ctx, &domain.PriceData{Ticker: "USDJPY"}); got == nil {
+		t.Fatal("Expected the tick that timed out to pass unchanged")
+	}
+	// The hung program is stopped in the background; the next ticks don't wait for it
+	start := time.Now()
+	for range 100 {
+		if got, _ := e.Process(ctx, &domain.PriceData{Ticker: "EURUSD"}); got == nil || got.Source != "" {
+			t.Fatalf("Expected ticks to bypass the stage, got %+v", got)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > e.timeout {
+		t.Errorf("Expected bypassed ticks not to wait, took %v", elapsed)
+	}
+
+	// Close ends the restart that is still waiting for its delay
+	closed := make(chan struct{})
+	go func() {
+		e.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Expected Close not to wait for the restart delay")
+	}
+}
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Rule conditions and assigned values are Lua expressions, evaluated with gopher-lua:
+//
+//	spread_pips > 5 and hour >= 22
+//	(ticker == "USDTRY" or ticker == "USDZAR") and has_flag("rollover")
+//	not (session == "" or math.abs(bid - ask) > 0.01)
+//
+// Identifiers are tick variables (see ruleVariables) or Lua's base, string, math and table
+// functions; any other name is an error rather than nil
+
+// compileLua compiles expression s into a chunk returning its value
+func compileLua(s, name string) (*lua.FunctionProto, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty %s", name)
+	}
+	chunk, err := parse.Parse(strings.NewReader("return ("+s+")"), name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, strings.TrimSpace(s), err)
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, strings.TrimSpace(s), err)
+	}
+	return proto, nil
+}
+
+// ruleState is a Lua state evaluating compiled expressions against one tick at a time
+// It is not safe for concurrent use
+type ruleState struct {
+	L         *lua.LState
+	env       *ruleEnv                              // Tick being evaluated
+	functions map[*lua.FunctionProto]*lua.LFunction // Compiled expressions loaded into L
+}
+
+// newRuleState creates a Lua state with the base, string, math and table libraries, without
+// file access, and the rule variables and functions
+func newRuleState() *ruleState {
+	s := &ruleState{
+		L:         lua.NewState(lua.Options{SkipOpenLibs: true}),
+		functions: make(map[*lua.FunctionProto]*lua.LFunction),
+	}
+	for name, open := range map[string]lua.LGFunction{
+		lua.BaseLibName:   lua.OpenBase,
+		lua.StringLibName: lua.OpenString,
+		lua.MathLibName:   lua.OpenMath,
+		lua.TabLibName:    lua.OpenTable,
+	} {
+		s.L.Push(s.L.NewFunction(open))
+		s.L.Push(lua.LString(name))
+		s.L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module"} {
+		s.L.SetGlobal(name, lua.LNil)
+	}
+
+	s.L.SetGlobal("has_flag", s.L.NewFunction(s.hasFlag))
+	s.L.SetGlobal("round", s.L.NewFunction(round))
+
+	// Tick variables are looked up when used, so a rule pays only for the ones it reads
+	globals := s.L.NewTable()
+	globals.RawSetString("__index", s.L.NewFunction(s.variable))
+	s.L.SetMetatable(s.L.G.Global, globals)
+	return s
+}
+
+// Close releases the Lua state
+func (s *ruleState) Close() {
+	s.L.Close()
+}
+
+// eval evaluates a compiled expression against the current tick
+func (s *ruleState) eval(proto *lua.FunctionProto) (lua.LValue, error) {
+	fn, ok := s.functions[proto]
+	if !ok {
+		fn = s.L.NewFunctionFromProto(proto)
+		s.functions[proto] = fn
+	}
+	s.L.Push(fn)
+	if err := s.L.PCall(0, 1, nil); err != nil {
+		return nil, err
+	}
+	value := s.L.Get(-1)
+	s.L.Pop(1)
+	return value, nil
+}
+
+// variable is the __index of the globals: the tick variable of that name, or an error
+func (s *ruleState) variable(L *lua.LState) int {
+	name := L.CheckString(2)
+	variable, ok := ruleVariables[name]
+	if !ok {
+		L.RaiseError("unknown variable %q", name)
+	}
+	L.Push(variable(s.env))
+	return 1
+}
+
+// hasFlag implements has_flag("outlier")
+func (s *ruleState) hasFlag(L *lua.LState) int {
+	flag, err := domain.ParseTickFlag(L.CheckString(1))
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	L.Push(lua.LBool(s.env.tick.Flags.Has(flag)))
+	return 1
+}
+
+// round implements round(x, decimals), rounding half away from zero
+func round(L *lua.LState) int {
+	scale := math.Pow(10, float64(L.OptInt(2, 0)))
+	L.Push(lua.LNumber(math.Round(float64(L.CheckNumber(1))*scale) / scale))
+	return 1
+}
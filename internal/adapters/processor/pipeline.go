@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// Pipeline implements TickProcessor by running stages in order
+// A stage dropping a tick (nil) ends the pipeline for it
+type Pipeline struct {
+	stages []ports.TickProcessor
+	names  []string
+}
+
+// NewPipeline creates the stages of specs, in order
+func NewPipeline(specs []Spec) (*Pipeline, error) {
+	p := &Pipeline{}
+	for _, spec := range specs {
+		stage, err := New(spec)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.stages = append(p.stages, stage)
+		p.names = append(p.names, spec.Name)
+	}
+	if len(specs) > 0 {
+		log.Printf("Processor: ✅ Pipeline %v", p.names)
+	}
+	return p, nil
+}
+
+// Len returns the number of stages
+func (p *Pipeline) Len() int {
+	return len(p.stages)
+}
+
+// Process passes data through every stage
+func (p *Pipeline) Process(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error) {
+	for i, stage := range p.stages {
+		var err error
+		if data, err = stage.Process(ctx, data); err != nil {
+			return nil, fmt.Errorf("processor %s: %w", p.names[i], err)
+		}
+		if data == nil {
+			return nil, nil
+		}
+	}
+	return data, nil
+}
+
+// Close releases stages holding resources (e.g. subprocesses)
+func (p *Pipeline) Close() error {
+	var errs []error
+	for _, stage := range p.stages {
+		if closer, ok := stage.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs("filter?exclude=USDTRY&exclude=usdzar, shift?offset=-150ms,")
+	if err != nil {
+		t.Fatalf("ParseSpecs failed: %v", err)
+	}
+	if len(specs) != 2 || specs[0].Name != "filter" || specs[1].Param("offset", "") != "-150ms" {
+		t.Fatalf("Unexpected specs: %v", specs)
+	}
+	if got := specs[0].Params["exclude"]; len(got) != 2 {
+		t.Errorf("Expected two excluded tickers, got %v", got)
+	}
+
+	if _, err := NewPipeline([]Spec{{Name: "enrich"}}); err == nil {
+		t.Error("Expected an error for an unknown processor")
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	specs, err := ParseSpecs("filter?exclude=usdtry&drop_flags=outlier,shift?offset=-150ms&tickers=USDJPY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := NewPipeline(specs)
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+	defer pipeline.Close()
+
+	base := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		tick   domain.PriceData
+		passes bool
+		want   time.Time
+	}{
+		{"passes", domain.PriceData{Ticker: "EURUSD", Timestamp: base}, true, base},
+		{"shifted", domain.PriceData{Ticker: "USDJPY", Timestamp: base}, true, base.Add(-150 * time.Millisecond)},
+		{"excluded ticker", domain.PriceData{Ticker: "USDTRY", Timestamp: base}, false, time.Time{}},
+		{"dropped flag", domain.PriceData{Ticker: "EURUSD", Timestamp: base, Flags: domain.FlagOutlier | domain.FlagRollover}, false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tick := tt.tick
+			got, err := pipeline.Process(context.Background(), &tick)
+			if err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+			if (got != nil) != tt.passes {
+				t.Fatalf("Expected passes=%v, got %v", tt.passes, got)
+			}
+			if got != nil && !got.Timestamp.Equal(tt.want) {
+				t.Errorf("Expected timestamp %v, got %v", tt.want, got.Timestamp)
+			}
+		})
+	}
+}
+
+func TestFilter_Include(t *testing.T) {
+	filter, err := NewFilter([]string{"eurusd"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if got, _ := filter.Process(ctx, &domain.PriceData{Ticker: "EURUSD"}); got == nil {
+		t.Error("Expected EURUSD to pass")
+	}
+	if got, _ := filter.Process(ctx, &domain.PriceData{Ticker: "GBPUSD"}); got != nil {
+		t.Error("Expected GBPUSD to be dropped")
+	}
+
+	if _, err := NewFilter(nil, nil, []string{"halted"}); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+}
@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// Spec is a parsed stage definition: name[?key=value&...], e.g. "filter?exclude=USDTRY"
+// Parameters taking several values repeat the key: filter?exclude=USDTRY&exclude=USDZAR
+type Spec struct {
+	Name   string
+	Params url.Values
+}
+
+// ParseSpec parses a stage definition; parameter values are URL query encoded
+func ParseSpec(s string) (Spec, error) {
+	name, query, _ := strings.Cut(strings.TrimSpace(s), "?")
+	if name == "" {
+		return Spec{}, fmt.Errorf("processor %q has no name", s)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return Spec{}, fmt.Errorf("invalid parameters for processor %s: %w", name, err)
+	}
+	return Spec{Name: strings.ToLower(name), Params: params}, nil
+}
+
+// ParseSpecs parses a comma-separated list of stage definitions, skipping empty entries
+func ParseSpecs(s string) ([]Spec, error) {
+	var specs []Spec
+	for _, definition := range strings.Split(s, ",") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		spec, err := ParseSpec(definition)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// String returns the spec in its config form
+func (s Spec) String() string {
+	if len(s.Params) == 0 {
+		return s.Name
+	}
+	return s.Name + "?" + s.Params.Encode()
+}
+
+// Param returns a parameter or defaultValue if it isn't set
+func (s Spec) Param(key, defaultValue string) string {
+	if value := s.Params.Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Factory creates a stage from its spec
+type Factory func(spec Spec) (ports.TickProcessor, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a stage available by name, typically from an init function
+// Registering the same name twice panics, as with database/sql drivers
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("processor: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("processor: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Names returns the registered stage names, sorted
+func Names() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	return slices.Sorted(maps.Keys(factories))
+}
+
+// New creates the stage registered under spec.Name
+func New(spec Spec) (ports.TickProcessor, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[spec.Name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown processor %q (supported: %s)", spec.Name, strings.Join(Names(), ", "))
+	}
+
+	p, err := factory(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s processor: %w", spec.Name, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,354 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func init() {
+	// rules?file=rules.txt&tz=America/New_York, or inline: rules?rule=spread_pips+>+5+and+hour+>=+22+->+drop
+	Register("rules", func(spec Spec) (ports.TickProcessor, error) {
+		location, err := time.LoadLocation(spec.Param("tz", "UTC"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tz: %w", err)
+		}
+
+		var rules []Rule
+		if path := spec.Param("file", ""); path != "" {
+			if rules, err = LoadRules(path); err != nil {
+				return nil, err
+			}
+		}
+		for i, line := range spec.Params["rule"] {
+			rule, err := ParseRule(line)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			rules = append(rules, rule)
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("file or rule is required")
+		}
+		return &Rules{Rules: rules, Location: location}, nil
+	})
+}
+
+// ruleEnv is the tick a rule is evaluated against
+type ruleEnv struct {
+	tick  *domain.PriceData
+	local time.Time // Tick time in the stage's time zone
+}
+
+// ruleVariables lists the variables available to rule expressions
+var ruleVariables = map[string]func(env *ruleEnv) lua.LValue{
+	"ticker":           func(e *ruleEnv) lua.LValue { return lua.LString(e.tick.Ticker) },
+	"uic":              func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.Uic) },
+	"asset_type":       func(e *ruleEnv) lua.LValue { return lua.LString(e.tick.AssetType) },
+	"bid":              func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.Bid) },
+	"ask":              func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.Ask) },
+	"mid":              func(e *ruleEnv) lua.LValue { return lua.LNumber((e.tick.Bid + e.tick.Ask) / 2) },
+	"spread":           func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.Spread) },
+	"spread_pips":      func(e *ruleEnv) lua.LValue { return lua.LNumber(unitSpread(e.tick, domain.SpreadUnitPips)) },
+	"spread_points":    func(e *ruleEnv) lua.LValue { return lua.LNumber(unitSpread(e.tick, domain.SpreadUnitPoints)) },
+	"spread_bps":       func(e *ruleEnv) lua.LValue { return lua.LNumber(unitSpread(e.tick, domain.SpreadUnitBps)) },
+	"effective_spread": func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.EffectiveSpread) },
+	"decimals":         func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.Decimals) },
+	"seq":              func(e *ruleEnv) lua.LValue { return lua.LNumber(e.tick.Sequence) },
+	"session":          func(e *ruleEnv) lua.LValue { return lua.LString(e.tick.SessionLabel) },
+	"flags":            func(e *ruleEnv) lua.LValue { return lua.LString(e.tick.Flags.String()) },
+	"source":           func(e *ruleEnv) lua.LValue { return lua.LString(e.tick.Source) },
+	"hour":             func(e *ruleEnv) lua.LValue { return lua.LNumber(e.local.Hour()) },
+	"minute":           func(e *ruleEnv) lua.LValue { return lua.LNumber(e.local.Minute()) },
+	"weekday":          func(e *ruleEnv) lua.LValue { return lua.LNumber((int(e.local.Weekday())+6)%7 + 1) }, // 1 = Monday … 7 = Sunday
+}
+
+// unitSpread returns the tick's spread in unit, with the conventional pip size if the tick has none
+func unitSpread(tick *domain.PriceData, unit domain.SpreadUnit) float64 {
+	p := *tick
+	p.SpreadUnit = unit
+	if p.PipSize <= 0 {
+		p.PipSize = domain.DefaultPipSize(p.Ticker)
+	}
+	spread, _ := p.UnitSpread()
+	return spread
+}
+
+// ruleFields lists the fields a set action can assign, with their types
+var ruleFields = map[string]lua.LValueType{
+	"bid":              lua.LTNumber,
+	"ask":              lua.LTNumber,
+	"effective_spread": lua.LTNumber,
+	"session":          lua.LTString,
+	"source":           lua.LTString,
+}
+
+// RuleAction is what a matching rule does to the tick
+type RuleAction struct {
+	Drop  bool
+	Flag  domain.TickFlags // Flag to set, if not 0
+	Field string           // Field to assign, if not ""
+	value *lua.FunctionProto
+}
+
+// Rule is a compiled "condition -> action[, action...]" line
+type Rule struct {
+	Text      string
+	condition *lua.FunctionProto
+	Actions   []RuleAction
+}
+
+// ruleSample is the tick new rules are tried on, so that type errors and unknown names show at startup
+var ruleSample = domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001, Spread: 0.0001, Decimals: 5}
+
+// ParseRule compiles a rule such as `spread_pips > 5 and hour >= 22 -> drop`
+// Actions are drop, flag <name> and set <field> = <expression>, separated by commas
+func ParseRule(s string) (Rule, error) {
+	parts := splitOutside(s, "->", 2)
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("expected condition -> action")
+	}
+	condition, err := compileLua(parts[0], "condition")
+	if err != nil {
+		return Rule{}, err
+	}
+
+	rule := Rule{Text: strings.TrimSpace(s), condition: condition}
+	for _, text := range splitOutside(parts[1], ",", -1) {
+		action, err := parseAction(strings.TrimSpace(text))
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.Actions = append(rule.Actions, action)
+	}
+
+	state := newRuleState()
+	defer state.Close()
+	sample := ruleSample
+	if err := rule.check(state, &ruleEnv{tick: &sample, local: sample.Timestamp}); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// parseAction parses one action after "->"
+func parseAction(s string) (RuleAction, error) {
+	name, rest, _ := strings.Cut(s, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch name {
+	case "drop":
+		if rest != "" {
+			return RuleAction{}, fmt.Errorf("unexpected %q after drop", rest)
+		}
+		return RuleAction{Drop: true}, nil
+
+	case "flag":
+		flag, err := domain.ParseTickFlag(strings.Trim(rest, `"'`))
+		if err != nil {
+			return RuleAction{}, err
+		}
+		return RuleAction{Flag: flag}, nil
+
+	case "set":
+		field, expr, ok := strings.Cut(rest, "=")
+		field = strings.TrimSpace(field)
+		if _, known := ruleFields[field]; !known {
+			return RuleAction{}, fmt.Errorf("can't set %q (supported: bid, ask, effective_spread, session, source)", field)
+		}
+		if !ok {
+			return RuleAction{}, fmt.Errorf("expected set %s = <expression>", field)
+		}
+		value, err := compileLua(expr, field)
+		if err != nil {
+			return RuleAction{}, err
+		}
+		return RuleAction{Field: field, value: value}, nil
+	}
+	return RuleAction{}, fmt.Errorf("unknown action %q (supported: drop, flag, set)", s)
+}
+
+// check evaluates the rule's condition and assigned values against env without changing the tick
+func (r Rule) check(state *ruleState, env *ruleEnv) error {
+	state.env = env
+	if _, err := r.matches(state); err != nil {
+		return err
+	}
+	for _, action := range r.Actions {
+		if action.value != nil {
+			if _, err := action.evalValue(state); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matches evaluates the condition, which must be a bool
+func (r Rule) matches(state *ruleState) (bool, error) {
+	value, err := state.eval(r.condition)
+	if err != nil {
+		return false, err
+	}
+	match, ok := value.(lua.LBool)
+	if !ok {
+		return false, fmt.Errorf("condition must be a boolean, got %s", value.Type())
+	}
+	return bool(match), nil
+}
+
+// evalValue evaluates a set action's value, which must have the field's type
+func (a RuleAction) evalValue(state *ruleState) (lua.LValue, error) {
+	value, err := state.eval(a.value)
+	if err != nil {
+		return nil, err
+	}
+	if value.Type() != ruleFields[a.Field] {
+		return nil, fmt.Errorf("%s must be set to a %s, got %s", a.Field, ruleFields[a.Field], value.Type())
+	}
+	return value, nil
+}
+
+// splitOutside splits s around sep, outside string literals and brackets, into at most n parts (n < 0: all)
+func splitOutside(s, sep string, n int) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], sep) && (n < 0 || len(parts) < n-1):
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// LoadRules reads rules from a file, one per line; blank lines and lines starting with # are skipped
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rules: %w", err)
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Rules implements TickProcessor with per-tick rules evaluated in order
+// Every matching rule applies its actions to the tick as changed by earlier rules; drop ends evaluation
+// A rule failing on a tick fails the stage for that tick
+type Rules struct {
+	Rules    []Rule
+	Location *time.Location // Time zone of hour, minute and weekday (nil = UTC)
+
+	mu    sync.Mutex
+	state *ruleState // Created on the first tick
+}
+
+// Process applies the matching rules
+func (r *Rules) Process(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error) {
+	location := r.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == nil {
+		r.state = newRuleState()
+	}
+	r.state.env = &ruleEnv{tick: data, local: data.Timestamp.In(location)}
+
+	for _, rule := range r.Rules {
+		match, err := rule.matches(r.state)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Text, err)
+		}
+		if !match {
+			continue
+		}
+		for _, action := range rule.Actions {
+			switch {
+			case action.Drop:
+				return nil, nil
+			case action.Flag != 0:
+				data.Flags |= action.Flag
+			default:
+				value, err := action.evalValue(r.state)
+				if err != nil {
+					return nil, fmt.Errorf("rule %q: %w", rule.Text, err)
+				}
+				applyField(data, action.Field, value)
+			}
+		}
+	}
+	return data, nil
+}
+
+// Close releases the Lua state
+func (r *Rules) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != nil {
+		r.state.Close()
+		r.state = nil
+	}
+	return nil
+}
+
+// applyField assigns a set action's value, already checked against the field's type
+func applyField(data *domain.PriceData, field string, value lua.LValue) {
+	switch field {
+	case "bid":
+		data.Bid = float64(value.(lua.LNumber))
+		data.CalculateSpread()
+	case "ask":
+		data.Ask = float64(value.(lua.LNumber))
+		data.CalculateSpread()
+	case "effective_spread":
+		data.EffectiveSpread = float64(value.(lua.LNumber))
+	case "session":
+		data.SessionLabel = string(value.(lua.LString))
+	case "source":
+		data.Source = string(value.(lua.LString))
+	}
+}
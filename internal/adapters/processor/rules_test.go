@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestRuleExpressions(t *testing.T) {
+	// Wednesday 22:30 UTC
+	tick := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 19, 22, 30, 0, 0, time.UTC),
+		Ticker:    "USDJPY", Bid: 155.100, Ask: 155.160, Spread: 0.06, Decimals: 3,
+		SessionLabel: "new_york", Flags: domain.FlagRollover,
+	}
+	state := newRuleState()
+	defer state.Close()
+	state.env = &ruleEnv{tick: tick, local: tick.Timestamp}
+
+	tests := []struct {
+		expr string
+		want lua.LValue
+	}{
+		{"spread_pips > 5 and hour >= 22", lua.LTrue},
+		{"spread_pips", lua.LNumber(6)},
+		{"spread_points == 60", lua.LTrue},
+		{"hour < 22 or weekday ~= 3", lua.LFalse},
+		{`ticker == "EURUSD" or ticker == 'USDJPY'`, lua.LTrue},
+		{`has_flag("rollover") and not has_flag("outlier")`, lua.LTrue},
+		{`session:find("york") ~= nil and ticker:lower():sub(1, 3) == "usd"`, lua.LTrue},
+		{"round(mid, 2)", lua.LNumber(155.13)},
+		{"math.abs(-(2 + 3) * 2 % 4)", lua.LNumber(2)},
+		{`source == "" and flags == "rollover"`, lua.LTrue},
+		{`not (minute >= 30)`, lua.LFalse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			proto, err := compileLua(tt.expr, "condition")
+			if err != nil {
+				t.Fatalf("compileLua failed: %v", err)
+			}
+			got, err := state.eval(proto)
+			if err != nil {
+				t.Fatalf("eval failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRuleExpressions_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"spread_pips > ",
+		"spreads > 5",
+		`ticker > 5`,
+		`has_flag("halted")`,
+		`dofile("/etc/passwd")`,
+		`"unterminated`,
+		"bid @ ask",
+	} {
+		if _, err := ParseRule(expr + " -> drop"); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.txt")
+	content := strings.Join([]string{
+		"# Wide spreads around the rollover are noise",
+		"spread_pips > 5 and hour >= 22 -> drop",
+		"",
+		`ticker == "EURUSD" and spread_pips > 2 -> flag outlier, set source = string.format("%s", "wide")`,
+		`ticker == "USDJPY" -> set ask = ask + 0.01`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseSpec("rules?file=" + path + "&rule=session+==+\"\"+->+set+session+=+\"none\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage, err := New(spec)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	at := func(hour int) time.Time { return time.Date(2025, 11, 18, hour, 0, 0, 0, time.UTC) }
+	if got, _ := stage.Process(ctx, &domain.PriceData{Timestamp: at(22), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1006, Spread: 0.0006}); got != nil {
+		t.Errorf("Expected the wide rollover tick to be dropped, got %+v", got)
+	}
+
+	got, _ := stage.Process(ctx, &domain.PriceData{Timestamp: at(12), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1003, Spread: 0.0003})
+	if got == nil || !got.Flags.Has(domain.FlagOutlier) || got.Source != "wide" || got.SessionLabel != "none" {
+		t.Errorf("Expected a flagged tick with source and session set, got %+v", got)
+	}
+
+	got, _ = stage.Process(ctx, &domain.PriceData{Timestamp: at(12), Ticker: "USDJPY", Bid: 155.1, Ask: 155.11, SessionLabel: "tokyo"})
+	if got == nil || got.Ask != 155.12 || got.Spread < 0.0199 || got.Spread > 0.0201 || got.SessionLabel != "tokyo" {
+		t.Errorf("Expected ask raised and spread recomputed, got %+v", got)
+	}
+}
+
+func TestParseRule_Errors(t *testing.T) {
+	for _, rule := range []string{
+		"spread_pips > 5",
+		"spread_pips -> drop",
+		"true -> explode",
+		"true -> flag halted",
+		"true -> set ticker = \"X\"",
+		"true -> set bid = \"1\"",
+		"true -> drop,",
+	} {
+		if _, err := ParseRule(rule); err == nil {
+			t.Errorf("Expected an error for %q", rule)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	os.WriteFile(path, []byte("true -> drop\nhour > -> drop\n"), 0o644)
+	if _, err := LoadRules(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("Expected an error naming line 2, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package questdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dedupKeys is the natural key of a tick (see domain.TickKey) as QuestDB upsert keys
+// bid and ask only tell apart ticks without a sequence number; replays of a tick carry the same ones
+const dedupKeys = "timestamp, ticker, seq, bid, ask"
+
+// EnsureTable creates the tick table as a WAL table deduplicated on the natural key of a tick,
+// or turns deduplication on for a table QuestDB already created from ILP lines
+// With deduplication, a tick written again (a fallback replay, reconcile -repair, a resent
+// buffer) replaces its row instead of adding one
+func EnsureTable(ctx context.Context, httpAddr, table string) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (ticker SYMBOL, asset_type SYMBOL, session SYMBOL, source SYMBOL, `+
+			`uic LONG, bid DOUBLE, ask DOUBLE, spread DOUBLE, decimals LONG, seq LONG, flags LONG, `+
+			`effective_spread DOUBLE, spread_z DOUBLE, timestamp TIMESTAMP) `+
+			`TIMESTAMP(timestamp) PARTITION BY DAY WAL DEDUP UPSERT KEYS(%s)`, table, dedupKeys),
+		fmt.Sprintf(`ALTER TABLE "%s" DEDUP ENABLE UPSERT KEYS(%s)`, table, dedupKeys),
+	}
+	for _, statement := range statements {
+		if err := exec(ctx, httpAddr, statement); err != nil {
+			return fmt.Errorf("failed to set up QuestDB table %s (dedup=false skips this): %w", table, err)
+		}
+	}
+	return nil
+}
+
+// exec runs a statement with QuestDB's /exec endpoint
+func exec(ctx context.Context, httpAddr, statement string) error {
+	_, err := query(ctx, httpAddr, statement)
+	return err
+}
+
+// query runs a statement with QuestDB's /exec endpoint and returns the rows of its result
+func query(ctx context.Context, httpAddr, statement string) ([][]any, error) {
+	endpoint := "http://" + httpAddr + "/exec?query=" + url.QueryEscape(statement)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Dataset [][]any `json:"dataset"`
+		Error   string  `json:"error"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("QuestDB returned %s: %s", resp.Status, result.Error)
+	}
+	if decodeErr != nil && !errors.Is(decodeErr, io.EOF) {
+		return nil, fmt.Errorf("invalid QuestDB response: %w", decodeErr)
+	}
+	return result.Dataset, nil
+}
+
+// Prune drops the day partitions of the table that ended before cutoff (see ports.Pruner),
+// keeping the newest partition like the file sinks keep their newest day
+// Returns the dropped partitions as table/YYYY-MM-DD
+func (s *Sender) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	rows, err := query(ctx, s.config.HTTPAddr, fmt.Sprintf(`SELECT name FROM table_partitions('%s') ORDER BY minTimestamp`, s.config.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", s.config.Table, err)
+	}
+
+	var expired, quoted, names []string
+	for _, row := range rows[:max(len(rows)-1, 0)] {
+		name, _ := row[0].(string)
+		day, err := time.Parse(time.DateOnly, name)
+		if err != nil {
+			continue // Not a day partition
+		}
+		if day.AddDate(0, 0, 1).After(cutoff) {
+			break
+		}
+		names = append(names, name)
+		quoted = append(quoted, "'"+name+"'")
+		expired = append(expired, s.config.Table+"/"+name)
+	}
+	if len(expired) == 0 || dryRun {
+		return expired, nil
+	}
+	statement := fmt.Sprintf(`ALTER TABLE "%s" DROP PARTITION LIST %s`, s.config.Table, strings.Join(quoted, ","))
+	if err := exec(ctx, s.config.HTTPAddr, statement); err != nil {
+		return nil, fmt.Errorf("failed to drop partitions %v of %s: %w", names, s.config.Table, err)
+	}
+	return expired, nil
+}
@@ -0,0 +1,286 @@
+// Package questdb writes ticks to QuestDB over the InfluxDB line protocol (ILP) on TCP
+package questdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// dialTimeout and writeTimeout bound connecting to and writing to QuestDB
+const (
+	dialTimeout  = 10 * time.Second
+	writeTimeout = 5 * time.Second
+)
+
+// SenderConfig holds QuestDB ILP settings
+type SenderConfig struct {
+	Addr     string // ILP TCP address, e.g. localhost:9009
+	Table    string // Created with deduplication (see EnsureTable), or by QuestDB on the first line
+	HTTPAddr string // QuestDB HTTP address for EnsureTable and Prune, e.g. localhost:9000
+	Dedup    bool   // Create the table with deduplication on HTTPAddr (see EnsureTable)
+}
+
+// Validate checks the sender configuration
+func (c SenderConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("QuestDB address is required")
+	}
+	if c.Table == "" || strings.ContainsAny(c.Table, ".\"'/\\\n\r") {
+		return fmt.Errorf("invalid QuestDB table name %q", c.Table)
+	}
+	return nil
+}
+
+// ConfigFromSpec reads a sender configuration from recorder parameters:
+// questdb?addr=localhost:9009&table=fx_ticks&http=localhost:9000&dedup=true
+// http defaults to port 9000 on the host of addr; dedup=false skips the DDL
+func ConfigFromSpec(spec storage.RecorderSpec) (SenderConfig, error) {
+	config := SenderConfig{
+		Addr:  spec.Param("addr", "localhost:9009"),
+		Table: spec.Param("table", "fx_ticks"),
+	}
+	dedup, err := strconv.ParseBool(spec.Param("dedup", "true"))
+	if err != nil {
+		return SenderConfig{}, fmt.Errorf("invalid QuestDB dedup %q: %w", spec.Param("dedup", ""), err)
+	}
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return SenderConfig{}, fmt.Errorf("invalid QuestDB address %q: %w", config.Addr, err)
+	}
+	config.HTTPAddr = spec.Param("http", net.JoinHostPort(host, "9000"))
+	config.Dedup = dedup
+	return config, nil
+}
+
+func init() {
+	storage.RegisterRecorder("questdb", func(spec storage.RecorderSpec) (ports.TickWriter, error) {
+		config, err := ConfigFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewSender(config)
+	})
+}
+
+// flushThreshold is the pending size that triggers a write without waiting for Flush
+// maxPending bounds the lines kept while QuestDB is unreachable; beyond it writes fail, so the
+// ticks go to a fallback sink instead of filling memory
+const (
+	flushThreshold = 64 << 10
+	maxPending     = 4 << 20
+)
+
+// Sender implements TickWriter by buffering ticks as ILP lines and sending them over one TCP
+// connection on every flush (or once 64 KiB are pending)
+// ticker, asset_type, session and source are SYMBOL columns (QuestDB interns them, so filtering
+// by instrument is cheap); the tick timestamp is the designated timestamp
+// ILP over TCP has no acknowledgements, so only a broken connection is noticed: the pending lines
+// are kept and sent again after reconnecting on the next flush
+type Sender struct {
+	config SenderConfig
+
+	mu      sync.Mutex
+	conn    net.Conn
+	pending []byte
+}
+
+// NewSender creates the table with deduplication if Dedup is set, connects to QuestDB and returns a sender
+func NewSender(config SenderConfig) (*Sender, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Dedup {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		defer cancel()
+		if err := EnsureTable(ctx, config.HTTPAddr, config.Table); err != nil {
+			return nil, err
+		}
+	}
+	s := &Sender{config: config}
+	if err := s.connect(context.Background()); err != nil {
+		return nil, err
+	}
+	log.Printf("QuestDBSender: ✅ Connected to %s (table %s)", config.Addr, config.Table)
+	return s, nil
+}
+
+// connect dials QuestDB; the caller holds mu (or owns s exclusively)
+func (s *Sender) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to QuestDB %s: %w", s.config.Addr, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Record buffers a single price data point
+func (s *Sender) Record(ctx context.Context, data *domain.PriceData) error {
+	return s.RecordBatch(ctx, []*domain.PriceData{data})
+}
+
+// RecordBatch buffers multiple price data points, sending them once enough are pending
+func (s *Sender) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) >= maxPending {
+		return fmt.Errorf("QuestDB %s unreachable with %d bytes pending", s.config.Addr, len(s.pending))
+	}
+	for _, priceData := range data {
+		s.pending = AppendLine(s.pending, s.config.Table, priceData)
+	}
+	if len(s.pending) < flushThreshold {
+		return nil
+	}
+	// The ticks are buffered either way; a failed send is reported by the next Flush
+	if err := s.send(ctx); err != nil {
+		log.Printf("QuestDBSender: %v", err)
+	}
+	return nil
+}
+
+// Flush sends the pending lines, reconnecting first if the connection was lost
+func (s *Sender) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.send(ctx)
+}
+
+// send writes the pending lines; the caller holds mu
+func (s *Sender) send(ctx context.Context) error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+		log.Printf("QuestDBSender: Reconnected to %s", s.config.Addr)
+	}
+
+	deadline := time.Now().Add(writeTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	s.conn.SetWriteDeadline(deadline)
+	n, err := s.conn.Write(s.pending)
+	if err == nil {
+		s.pending = s.pending[:0]
+		return nil
+	}
+
+	s.conn.Close()
+	s.conn = nil
+	// Drop what was written, up to the end of a partly written line: resending its tail on a new
+	// connection would be a malformed line, which makes QuestDB close the connection
+	if n > 0 {
+		if end := bytes.IndexByte(s.pending[n-1:], '\n'); end >= 0 {
+			n += end
+		} else {
+			n = len(s.pending)
+		}
+		s.pending = s.pending[:copy(s.pending, s.pending[n:])]
+	}
+	return fmt.Errorf("failed to send ticks to QuestDB %s: %w", s.config.Addr, err)
+}
+
+// Close sends the pending lines until ctx is done and closes the connection
+func (s *Sender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.send(ctx)
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// AppendLine appends a tick as an ILP line:
+// table,ticker=EURUSD,asset_type=FxSpot uic=21i,bid=1.15432,ask=1.15441,spread=0.00009,... <unix nanos>
+// Optional values (session, source, effective_spread, spread_z) are left out when unset, which QuestDB stores as NULL
+func AppendLine(b []byte, table string, data *domain.PriceData) []byte {
+	b = appendEscaped(b, table, " ,")
+	b = appendSymbol(b, "ticker", data.Ticker)
+	b = appendSymbol(b, "asset_type", data.AssetType)
+	b = appendSymbol(b, "session", data.SessionLabel)
+	b = appendSymbol(b, "source", data.Source)
+
+	b = append(b, " uic="...)
+	b = strconv.AppendInt(b, int64(data.Uic), 10)
+	b = append(b, 'i')
+	b = appendFloat(b, "bid", data.Bid)
+	b = appendFloat(b, "ask", data.Ask)
+	b = appendFloat(b, "spread", data.Spread)
+	b = append(b, ",decimals="...)
+	b = strconv.AppendInt(b, int64(data.Decimals), 10)
+	b = append(b, "i,seq="...)
+	b = strconv.AppendInt(b, int64(data.Sequence), 10)
+	b = append(b, "i,flags="...)
+	b = strconv.AppendInt(b, int64(data.Flags), 10)
+	b = append(b, 'i')
+	if data.EffectiveSpread != 0 {
+		b = appendFloat(b, "effective_spread", data.EffectiveSpread)
+	}
+	if data.SpreadZ != 0 {
+		b = appendFloat(b, "spread_z", data.SpreadZ)
+	}
+
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, data.Timestamp.UnixNano(), 10)
+	return append(b, '\n')
+}
+
+// appendSymbol appends a tag (a QuestDB SYMBOL), skipping empty values
+func appendSymbol(b []byte, name, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = append(b, ',')
+	b = append(b, name...)
+	b = append(b, '=')
+	return appendEscaped(b, value, " ,=")
+}
+
+// appendFloat appends a double field; NaN and infinities, which ILP can't carry, are left out
+func appendFloat(b []byte, name string, value float64) []byte {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return b
+	}
+	b = append(b, ',')
+	b = append(b, name...)
+	b = append(b, '=')
+	return strconv.AppendFloat(b, value, 'f', -1, 64)
+}
+
+// appendEscaped appends s with backslashes before the characters special in its position
+// Newlines can't be escaped in ILP and become escaped spaces
+func appendEscaped(b []byte, s, special string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n' || c == '\r':
+			b = append(b, '\\', ' ')
+			continue
+		case c == '\\' || strings.IndexByte(special, c) >= 0:
+			b = append(b, '\\')
+		}
+		b = append(b, c)
+	}
+	return b
+}
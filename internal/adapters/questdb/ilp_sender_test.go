@@ -0,0 +1,170 @@
+package questdb
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestAppendLine(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp:    time.Unix(1763467200, 123456789),
+		Uic:          21,
+		Ticker:       "EURUSD",
+		AssetType:    "FxSpot",
+		Bid:          1.15432,
+		Ask:          1.15441,
+		Spread:       0.00009,
+		Decimals:     5,
+		Sequence:     42,
+		SessionLabel: "london+new_york",
+		SpreadZ:      -1.5,
+	}
+	want := "fx_ticks,ticker=EURUSD,asset_type=FxSpot,session=london+new_york " +
+		"uic=21i,bid=1.15432,ask=1.15441,spread=0.00009,decimals=5i,seq=42i,flags=0i,spread_z=-1.5 1763467200123456789\n"
+	if got := string(AppendLine(nil, "fx_ticks", tick)); got != want {
+		t.Errorf("Expected\n%s got\n%s", want, got)
+	}
+}
+
+func TestAppendLine_EscapesSymbols(t *testing.T) {
+	tick := &domain.PriceData{Timestamp: time.Unix(0, 1), Ticker: "EUR USD,x=1", Source: "a\\b\nc"}
+	want := `t,ticker=EUR\ USD\,x\=1,source=a\\b\ c uic=0i,bid=0,ask=0,spread=0,decimals=0i,seq=0i,flags=0i 1` + "\n"
+	if got := string(AppendLine(nil, "t", tick)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestConfigFromSpec(t *testing.T) {
+	spec, err := storage.ParseRecorderSpec("questdb?addr=nas:9009&table=ticks")
+	if err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+	if config, err := ConfigFromSpec(spec); err != nil || config != (SenderConfig{Addr: "nas:9009", Table: "ticks", HTTPAddr: "nas:9000", Dedup: true}) {
+		t.Errorf("Unexpected config %+v, %v", config, err)
+	}
+	spec, _ = storage.ParseRecorderSpec("questdb?addr=nas:9009&dedup=false")
+	if config, err := ConfigFromSpec(spec); err != nil || config.Dedup {
+		t.Errorf("Expected no deduplication, got %+v, %v", config, err)
+	}
+	if err := (SenderConfig{Addr: "nas:9009", Table: "fx.ticks"}).Validate(); err == nil {
+		t.Error("Expected an error for a table name with a dot")
+	}
+}
+
+func TestSender_SendsBufferedLinesOnFlushAndReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	ctx := context.Background()
+	sender, err := NewSender(SenderConfig{Addr: listener.Addr().String(), Table: "fx_ticks"})
+	if err != nil {
+		t.Fatalf("NewSender failed: %v", err)
+	}
+	defer sender.Close(ctx)
+	first := <-conns
+
+	tick := &domain.PriceData{Timestamp: time.Unix(1, 0), Ticker: "EURUSD", Bid: 1, Ask: 2, Spread: 1}
+	if err := sender.Record(ctx, tick); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := sender.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	want := string(AppendLine(nil, "fx_ticks", tick))
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := bufio.NewReader(first).ReadString('\n'); err != nil || line != want {
+		t.Fatalf("Expected %q, got %q, %v", want, line, err)
+	}
+
+	// A lost connection is replaced on the next flush
+	sender.mu.Lock()
+	sender.conn.Close()
+	sender.conn = nil
+	sender.mu.Unlock()
+	first.Close()
+
+	if err := sender.Record(ctx, tick); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := sender.Flush(ctx); err != nil {
+		t.Fatalf("Flush after reconnect failed: %v", err)
+	}
+	second := <-conns
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := bufio.NewReader(second).ReadString('\n'); err != nil || line != want {
+		t.Errorf("Expected %q after reconnect, got %q, %v", want, line, err)
+	}
+}
+
+func TestEnsureTable_DeduplicatesOnTheTickKey(t *testing.T) {
+	var statements []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statements = append(statements, r.URL.Query().Get("query"))
+		w.Write([]byte(`{"ddl":"OK"}`))
+	}))
+	defer server.Close()
+
+	if err := EnsureTable(context.Background(), strings.TrimPrefix(server.URL, "http://"), "fx_ticks"); err != nil {
+		t.Fatalf("EnsureTable failed: %v", err)
+	}
+	if len(statements) != 2 ||
+		!strings.HasPrefix(statements[0], `CREATE TABLE IF NOT EXISTS "fx_ticks" (ticker SYMBOL,`) ||
+		!strings.HasSuffix(statements[0], "WAL DEDUP UPSERT KEYS(timestamp, ticker, seq, bid, ask)") ||
+		statements[1] != `ALTER TABLE "fx_ticks" DEDUP ENABLE UPSERT KEYS(timestamp, ticker, seq, bid, ask)` {
+		t.Errorf("Unexpected statements %q", statements)
+	}
+}
+
+func TestSender_PruneDropsExpiredDayPartitions(t *testing.T) {
+	var statements []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statement := r.URL.Query().Get("query")
+		statements = append(statements, statement)
+		if strings.HasPrefix(statement, "SELECT") {
+			w.Write([]byte(`{"columns":[{"name":"name","type":"STRING"}],"dataset":[["2025-11-01"],["2025-11-02"],["2025-11-03"]]}`))
+			return
+		}
+		w.Write([]byte(`{"ddl":"OK"}`))
+	}))
+	defer server.Close()
+
+	sender := &Sender{config: SenderConfig{Table: "fx_ticks", HTTPAddr: strings.TrimPrefix(server.URL, "http://")}}
+	ctx := context.Background()
+	cutoff := time.Date(2025, 11, 3, 12, 0, 0, 0, time.UTC)
+
+	dropped, err := sender.Prune(ctx, cutoff, true)
+	if err != nil || !slices.Equal(dropped, []string{"fx_ticks/2025-11-01", "fx_ticks/2025-11-02"}) || len(statements) != 1 {
+		t.Fatalf("Dry run: unexpected %v, %v after %q", dropped, err, statements)
+	}
+	// The newest partition is kept whatever the cutoff
+	if _, err := sender.Prune(ctx, cutoff.AddDate(1, 0, 0), false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if want := `ALTER TABLE "fx_ticks" DROP PARTITION LIST '2025-11-01','2025-11-02'`; statements[len(statements)-1] != want {
+		t.Errorf("Expected %q, got %q", want, statements[len(statements)-1])
+	}
+}
@@ -0,0 +1,35 @@
+package remotefs
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// uploadTimeout bounds copying one hourly file; busy hours of liquid pairs reach tens of megabytes,
+// which take minutes over a slow uplink
+const uploadTimeout = 15 * time.Minute
+
+// Publisher implements FilePublisher by copying each finalized file to a target as it is finalized
+// A failed copy is not retried; cmd/backup picks up whatever the target is missing
+type Publisher struct {
+	target Target
+}
+
+// NewPublisher creates a publisher copying to target
+func NewPublisher(target Target) *Publisher {
+	return &Publisher{target: target}
+}
+
+// PublishFile copies the file into its day directory on the target
+func (p *Publisher) PublishFile(ctx context.Context, file domain.FinalizedFile) error {
+	day := filepath.Base(filepath.Dir(file.Path))
+	return p.target.Upload(ctx, day, []string{file.Path})
+}
+
+// PublishTimeout replaces the announcement timeout of FinalizedFiles, which is meant for messages
+func (p *Publisher) PublishTimeout() time.Duration {
+	return uploadTimeout
+}
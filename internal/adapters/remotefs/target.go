@@ -0,0 +1,240 @@
+// Package remotefs copies finalized spread files to where they are kept off the collector host:
+// a local or mounted path (NAS over NFS/SMB), an SFTP server, or an object store
+// Targets keep the archive layout, YYYYMMDD/TICKER_HH.csv under the target's base path
+package remotefs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/objectstore"
+)
+
+// Target receives the files of archive days
+type Target interface {
+	// Upload copies local files into the day directory of the target, replacing existing ones
+	Upload(ctx context.Context, day string, files []string) error
+
+	// Prune deletes the day directories recorded entirely before cutoff, except the most recent one
+	// (see ports.Pruner)
+	Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error)
+}
+
+// NewTarget creates the target for a local path, an sftp://user@host[:port]/path URL or an object
+// store URL (s3://, gs://, az://); sftpPath is the sftp client to run
+func NewTarget(rawURL string, stores objectstore.Config, sftpPath string) (Target, error) {
+	scheme, _, found := strings.Cut(rawURL, "://")
+	if !found {
+		return LocalTarget{Dir: rawURL}, nil
+	}
+	if objectstore.IsURL(rawURL) {
+		return objectstore.New(rawURL, stores)
+	}
+
+	switch scheme {
+	case "sftp":
+		cli, err := exec.LookPath(sftpPath)
+		if err != nil {
+			return nil, fmt.Errorf("sftp client not found (install it or set SFTP_PATH): %w", err)
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid target %q", rawURL)
+		}
+		return &SFTPTarget{cli: cli, u: u}, nil
+	default:
+		return nil, fmt.Errorf("unsupported target %q (supported: local path, sftp://, s3://, gs://, az://)", rawURL)
+	}
+}
+
+// LocalTarget copies files into a directory, e.g. a mounted disk or network share
+type LocalTarget struct {
+	Dir string
+}
+
+func (t LocalTarget) Upload(ctx context.Context, day string, files []string) error {
+	dayDir := filepath.Join(t.Dir, day)
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dayDir, err)
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := copyFile(file, filepath.Join(dayDir, filepath.Base(file))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t LocalTarget) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	entries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", t.Dir, err)
+	}
+	var days []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			days = append(days, entry.Name())
+		}
+	}
+
+	var removed []string
+	for _, day := range objectstore.ExpiredDays(days, cutoff) {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		dayDir := filepath.Join(t.Dir, day)
+		if !dryRun {
+			if err := os.RemoveAll(dayDir); err != nil {
+				return removed, fmt.Errorf("failed to remove %s: %w", dayDir, err)
+			}
+		}
+		removed = append(removed, dayDir)
+	}
+	return removed, nil
+}
+
+// copyFile writes src to dst through a temporary file, so dst is never seen half written
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst + ".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := os.Rename(dst+".tmp", dst); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return nil
+}
+
+// SFTPTarget uploads a day's files in one sftp batch session (key-based authentication)
+// Files are put under a temporary name and renamed, so readers on the server never see half a file
+type SFTPTarget struct {
+	cli string
+	u   *url.URL
+}
+
+func (t *SFTPTarget) Upload(ctx context.Context, day string, files []string) error {
+	out, err := t.run(ctx, t.batch(day, files))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			log.Printf("SFTPTarget: %s", line)
+		}
+	}
+	return nil
+}
+
+// Prune lists the base directory in one session and removes the expired days in a second one
+func (t *SFTPTarget) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	base := t.base()
+	out, err := t.run(ctx, "ls -1 "+quoteSFTP(base)+"\n")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", base, err)
+	}
+	// Batch mode echoes each command as "sftp> ..."; the rest are the listed paths
+	var days []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "sftp>") {
+			days = append(days, path.Base(line))
+		}
+	}
+
+	var batch strings.Builder
+	var removed []string
+	for _, day := range objectstore.ExpiredDays(days, cutoff) {
+		dayDir := path.Join(base, day)
+		fmt.Fprintf(&batch, "-rm %s\nrmdir %s\n", quoteSFTP(dayDir+"/*"), quoteSFTP(dayDir))
+		removed = append(removed, t.u.Scheme+"://"+t.u.Host+"/"+dayDir)
+	}
+	if dryRun || len(removed) == 0 {
+		return removed, nil
+	}
+	if _, err := t.run(ctx, batch.String()); err != nil {
+		return nil, fmt.Errorf("failed to remove expired days: %w", err)
+	}
+	return removed, nil
+}
+
+// run runs an sftp batch and returns its output
+// Captured rather than passed through: the collector's stdout may carry the ndjson sink
+func (t *SFTPTarget) run(ctx context.Context, batch string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, t.cli, t.args()...)
+	cmd.Stdin = strings.NewReader(batch)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("sftp failed: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// base returns the target directory relative to the login directory
+func (t *SFTPTarget) base() string {
+	if base := strings.TrimPrefix(t.u.Path, "/"); base != "" {
+		return base
+	}
+	return "."
+}
+
+// args returns the sftp arguments reading the batch from stdin
+func (t *SFTPTarget) args() []string {
+	args := []string{"-q", "-b", "-"}
+	if port := t.u.Port(); port != "" {
+		args = append(args, "-P", port)
+	}
+	host := t.u.Hostname()
+	if t.u.User != nil {
+		host = t.u.User.Username() + "@" + host
+	}
+	return append(args, host)
+}
+
+// batch returns the sftp commands uploading the files of a day
+// A leading "-" lets the batch continue when a directory already exists or there is nothing to remove
+func (t *SFTPTarget) batch(day string, files []string) string {
+	base := t.base()
+	dayDir := path.Join(base, day)
+	var batch strings.Builder
+	fmt.Fprintf(&batch, "-mkdir %s\n-mkdir %s\n", quoteSFTP(base), quoteSFTP(dayDir))
+	for _, file := range files {
+		dst := path.Join(dayDir, filepath.Base(file))
+		// rename fails on an existing file with most servers, so the old version goes first
+		fmt.Fprintf(&batch, "put %s %s\n-rm %s\nrename %s %s\n",
+			quoteSFTP(file), quoteSFTP(dst+".tmp"), quoteSFTP(dst), quoteSFTP(dst+".tmp"), quoteSFTP(dst))
+	}
+	return batch.String()
+}
+
+// quoteSFTP quotes a path for an sftp batch file
+func quoteSFTP(p string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p) + `"`
+}
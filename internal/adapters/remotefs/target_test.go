@@ -0,0 +1,141 @@
+package remotefs
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/objectstore"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestPublisher_CopiesFinalizedFileIntoDayDirectory(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "20251118")
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(archive, "EURUSD_14.csv")
+	if err := os.WriteFile(src, []byte("timestamp,bid,ask\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nas := t.TempDir()
+	target, err := NewTarget(nas, objectstore.Config{}, "sftp")
+	if err != nil {
+		t.Fatalf("NewTarget failed: %v", err)
+	}
+	if err := NewPublisher(target).PublishFile(context.Background(), domain.FinalizedFile{Path: src}); err != nil {
+		t.Fatalf("PublishFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(nas, "20251118", "EURUSD_14.csv"))
+	if err != nil || string(data) != "timestamp,bid,ask\n" {
+		t.Errorf("File not copied: %q, %v", data, err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(nas, "20251118", "*.tmp")); len(leftovers) > 0 {
+		t.Errorf("Temporary files left: %v", leftovers)
+	}
+}
+
+func TestSFTPTarget_BatchUploadsUnderTemporaryNames(t *testing.T) {
+	u, _ := url.Parse("sftp://backup@nas.local:2222/volume1/fx")
+	target := &SFTPTarget{cli: "sftp", u: u}
+
+	if args := strings.Join(target.args(), " "); args != "-q -b - -P 2222 backup@nas.local" {
+		t.Errorf("Unexpected arguments: %s", args)
+	}
+	want := `-mkdir "volume1/fx"
+-mkdir "volume1/fx/20251118"
+put "/data/20251118/EURUSD_14.csv" "volume1/fx/20251118/EURUSD_14.csv.tmp"
+-rm "volume1/fx/20251118/EURUSD_14.csv"
+rename "volume1/fx/20251118/EURUSD_14.csv.tmp" "volume1/fx/20251118/EURUSD_14.csv"
+`
+	if got := target.batch("20251118", []string{"/data/20251118/EURUSD_14.csv"}); got != want {
+		t.Errorf("Unexpected batch:\n%s", got)
+	}
+
+	if _, err := NewTarget("ftp://nas.local/fx", objectstore.Config{}, "sftp"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+func TestSFTPTarget_KeepsOutputOffStdout(t *testing.T) {
+	cli := filepath.Join(t.TempDir(), "sftp")
+	script := "#!/bin/sh\ncat >/dev/null\necho 'sftp> put EURUSD_14.csv'\n[ \"$FAIL\" = \"\" ] || { echo 'Permission denied' >&2; exit 1; }\n"
+	if err := os.WriteFile(cli, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("sftp://backup@nas.local/fx")
+	target := &SFTPTarget{cli: cli, u: u}
+
+	// Stdout may be the ndjson sink's stream
+	stdout := os.Stdout
+	captured, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = captured
+	defer func() { os.Stdout = stdout }()
+
+	if err := target.Upload(context.Background(), "20251118", []string{"/data/20251118/EURUSD_14.csv"}); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	t.Setenv("FAIL", "1")
+	err = target.Upload(context.Background(), "20251118", []string{"/data/20251118/EURUSD_14.csv"})
+	if err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("Expected the sftp output in the error, got %v", err)
+	}
+
+	os.Stdout = stdout
+	if info, _ := captured.Stat(); info.Size() != 0 {
+		t.Errorf("Expected nothing written to stdout, got %d bytes", info.Size())
+	}
+}
+
+func TestLocalTarget_PrunesExpiredDays(t *testing.T) {
+	dir := t.TempDir()
+	for _, day := range []string{"20251101", "20251102", "20251103"} {
+		if err := os.MkdirAll(filepath.Join(dir, day), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := LocalTarget{Dir: dir}
+	removed, err := target.Prune(context.Background(), time.Date(2025, 11, 9, 0, 0, 0, 0, time.UTC), false)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("Expected two days removed, got %v, %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20251103")); err != nil {
+		t.Errorf("Most recent day removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20251101")); !os.IsNotExist(err) {
+		t.Errorf("Expired day kept: %v", err)
+	}
+}
+
+func TestSFTPTarget_PrunesExpiredDays(t *testing.T) {
+	dir := t.TempDir()
+	cli := filepath.Join(dir, "sftp")
+	script := "#!/bin/sh\ncat >> " + dir + "/batches\necho 'sftp> ls -1 \"fx\"'\nprintf 'fx/20251101\\nfx/20251102\\nfx/incoming\\n'\n"
+	if err := os.WriteFile(cli, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("sftp://backup@nas.local/fx")
+	target := &SFTPTarget{cli: cli, u: u}
+
+	removed, err := target.Prune(context.Background(), time.Date(2025, 11, 9, 0, 0, 0, 0, time.UTC), false)
+	if err != nil || len(removed) != 1 || removed[0] != "sftp://nas.local/fx/20251101" {
+		t.Fatalf("Unexpected removals: %v, %v", removed, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "batches"))
+	want := `ls -1 "fx"
+-rm "fx/20251101/*"
+rmdir "fx/20251101"
+`
+	if string(data) != want {
+		t.Errorf("Unexpected batches:\n%s", data)
+	}
+}
@@ -0,0 +1,65 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fields splits a protobuf message into its length-delimited and fixed64 fields
+func fields(t *testing.T, b []byte) map[int][][]byte {
+	t.Helper()
+	out := make(map[int][][]byte)
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		switch key & 7 {
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			out[int(key>>3)] = append(out[int(key>>3)], b[n:n+int(size)])
+			b = b[n+int(size):]
+		case wireFixed64:
+			out[int(key>>3)] = append(out[int(key>>3)], b[:8])
+			b = b[8:]
+		case wireVarint:
+			_, n := binary.Uvarint(b)
+			out[int(key>>3)] = append(out[int(key>>3)], b[:n])
+			b = b[n:]
+		default:
+			t.Fatalf("Unexpected wire type %d", key&7)
+		}
+	}
+	return out
+}
+
+// decodeWriteRequest returns each series as "name{label=value,...}" with its first sample value
+func decodeWriteRequest(t *testing.T, b []byte) map[string]float64 {
+	t.Helper()
+	out := make(map[string]float64)
+	for _, ts := range fields(t, b)[fieldTimeseries] {
+		f := fields(t, ts)
+		var name string
+		var labels []string
+		for _, label := range f[fieldLabels] {
+			l := fields(t, label)
+			n, v := string(l[fieldLabelName][0]), string(l[fieldLabelValue][0])
+			if n == "__name__" {
+				name = v
+			} else {
+				labels = append(labels, n+"="+v)
+			}
+		}
+		sample := fields(t, f[fieldSamples][0])
+		out[name+"{"+strings.Join(labels, ",")+"}"] = math.Float64frombits(binary.LittleEndian.Uint64(sample[fieldSampleValue][0]))
+	}
+	return out
+}
+
+func TestNewClient_RejectsInvalidURL(t *testing.T) {
+	for _, url := range []string{"", "victoria:8428/api/v1/write", "ftp://host/write"} {
+		if _, err := NewClient(url, ""); err == nil {
+			t.Errorf("Expected an error for %q", url)
+		}
+	}
+}
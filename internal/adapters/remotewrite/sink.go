@@ -0,0 +1,226 @@
+package remotewrite
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// Config holds the remote-write sink settings
+type Config struct {
+	URL      string        // Remote-write endpoint, e.g. http://victoria:8428/api/v1/write
+	Token    string        // Bearer token (empty: none, or basic auth from the URL)
+	Interval time.Duration // Resolution of the pushed series
+	Job      string        // job label
+	Instance string        // instance label
+	Tenant   string        // tenant label (empty: none)
+}
+
+// Validate checks the sink configuration
+func (c Config) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("remote-write URL is required")
+	}
+	if c.Interval < time.Second {
+		return fmt.Errorf("remote-write interval must be at least 1s, got %v", c.Interval)
+	}
+	return nil
+}
+
+// ConfigFromSpec reads a sink configuration from recorder parameters:
+// remotewrite?url=http://victoria:8428/api/v1/write&interval=15s&token=..&job=fx-collector&instance=..&tenant=..
+func ConfigFromSpec(spec storage.RecorderSpec) (Config, error) {
+	interval, err := time.ParseDuration(spec.Param("interval", "15s"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid remote-write interval: %w", err)
+	}
+	hostname, _ := os.Hostname()
+	return Config{
+		URL:      spec.Param("url", ""),
+		Token:    spec.Param("token", ""),
+		Interval: interval,
+		Job:      spec.Param("job", "fx-collector"),
+		Instance: spec.Param("instance", hostname),
+		Tenant:   spec.Param("tenant", ""),
+	}, nil
+}
+
+func init() {
+	storage.RegisterRecorder("remotewrite", func(spec storage.RecorderSpec) (ports.TickWriter, error) {
+		config, err := ConfigFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewSink(config)
+	})
+}
+
+// spreadStats aggregates an instrument's ticks over one interval
+type spreadStats struct {
+	assetType      string
+	last, min, max float64
+	sum            float64
+	bid, ask       float64
+	ticks          int
+}
+
+// Sink implements TickWriter by downsampling the ticks of each instrument to one sample per
+// interval and pushing them with remote-write, so existing dashboards chart live spreads
+// Series (spread in SPREAD_UNIT): fx_spread (last), fx_spread_min, fx_spread_max, fx_spread_avg,
+// fx_bid, fx_ask and fx_ticks, labelled ticker and asset_type; instruments without ticks in an
+// interval get no sample, so gaps stay visible
+// A failed push is logged and its interval skipped: the CSV archive stays the record
+type Sink struct {
+	config Config
+	client *Client
+	labels []Label // job, instance and tenant
+
+	mu    sync.Mutex
+	stats map[string]*spreadStats // By ticker
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSink starts pushing every interval
+func NewSink(config Config) (*Sink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(config.URL, config.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []Label{{"job", config.Job}, {"instance", config.Instance}}
+	if config.Tenant != "" {
+		labels = append(labels, Label{"tenant", config.Tenant})
+	}
+	s := &Sink{
+		config: config,
+		client: client,
+		labels: labels,
+		stats:  make(map[string]*spreadStats),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	log.Printf("RemoteWriteSink: ✅ Pushing spreads to %s every %v", client.url, config.Interval)
+	return s, nil
+}
+
+// Record adds a price data point to its instrument's interval
+func (s *Sink) Record(ctx context.Context, data *domain.PriceData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(data)
+	return nil
+}
+
+// RecordBatch adds multiple price data points
+func (s *Sink) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, priceData := range data {
+		s.add(priceData)
+	}
+	return nil
+}
+
+// add updates the ticker's statistics; the caller holds mu
+func (s *Sink) add(data *domain.PriceData) {
+	spread, _ := data.UnitSpread()
+	stats := s.stats[data.Ticker]
+	if stats == nil {
+		stats = &spreadStats{min: math.Inf(1), max: math.Inf(-1)}
+		s.stats[data.Ticker] = stats
+	}
+	stats.assetType = data.AssetType
+	stats.last = spread
+	stats.min = min(stats.min, spread)
+	stats.max = max(stats.max, spread)
+	stats.sum += spread
+	stats.bid = data.Bid
+	stats.ask = data.Ask
+	stats.ticks++
+}
+
+// run pushes the finished interval on every tick of the interval timer
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.config.Interval)
+			if err := s.push(ctx, now); err != nil {
+				log.Printf("RemoteWriteSink: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// push sends the statistics gathered since the last push, stamped now, and starts a new interval
+func (s *Sink) push(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	stats := s.stats
+	s.stats = make(map[string]*spreadStats, len(stats))
+	s.mu.Unlock()
+
+	return s.client.Push(ctx, s.series(stats, now))
+}
+
+// series converts the statistics of an interval into one sample per series
+func (s *Sink) series(stats map[string]*spreadStats, now time.Time) []Series {
+	tickers := make([]string, 0, len(stats))
+	for ticker := range stats {
+		tickers = append(tickers, ticker)
+	}
+	slices.Sort(tickers)
+
+	var series []Series
+	for _, ticker := range tickers {
+		st := stats[ticker]
+		values := []struct {
+			name  string
+			value float64
+		}{
+			{"fx_spread", st.last},
+			{"fx_spread_min", st.min},
+			{"fx_spread_max", st.max},
+			{"fx_spread_avg", st.sum / float64(st.ticks)},
+			{"fx_bid", st.bid},
+			{"fx_ask", st.ask},
+			{"fx_ticks", float64(st.ticks)},
+		}
+		for _, v := range values {
+			labels := append([]Label{{"__name__", v.name}, {"ticker", ticker}}, s.labels...)
+			if st.assetType != "" { // An empty label value means no label to Prometheus
+				labels = append(labels, Label{"asset_type", st.assetType})
+			}
+			series = append(series, Series{Labels: labels, Samples: []Sample{{Value: v.value, Timestamp: now}}})
+		}
+	}
+	return series
+}
+
+// Close stops the timer and pushes the last, partial interval until ctx is done
+func (s *Sink) Close(ctx context.Context) error {
+	close(s.stop)
+	<-s.done
+	return s.push(ctx, time.Now())
+}
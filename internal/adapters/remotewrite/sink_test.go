@@ -0,0 +1,71 @@
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/golang/snappy"
+)
+
+func TestSink_PushesDownsampledSpreads(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		compressed, _ := io.ReadAll(r.Body)
+		var err error
+		if body, err = snappy.Decode(nil, compressed); err != nil {
+			t.Errorf("Invalid snappy body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	url := strings.Replace(server.URL, "http://", "http://user:secret@", 1) + "/api/v1/write"
+	sink, err := NewSink(Config{URL: url, Interval: time.Hour, Job: "fx", Instance: "nas", Tenant: "acme"})
+	if err != nil {
+		t.Fatalf("NewSink failed: %v", err)
+	}
+
+	ctx := context.Background()
+	sink.RecordBatch(ctx, []*domain.PriceData{
+		{Ticker: "EURUSD", AssetType: "FxSpot", Bid: 1.1, Ask: 1.1002, Spread: 0.0002},
+		{Ticker: "EURUSD", AssetType: "FxSpot", Bid: 1.1, Ask: 1.1004, Spread: 0.0004},
+		{Ticker: "USDJPY", Bid: 150, Ask: 150.01, Spread: 0.01},
+	})
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if header.Get("Content-Encoding") != "snappy" || header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("Missing remote-write headers: %v", header)
+	}
+	if user, pass, ok := (&http.Request{Header: header}).BasicAuth(); !ok || user != "user" || pass != "secret" {
+		t.Errorf("Expected basic auth from the URL, got %q %q %v", user, pass, ok)
+	}
+
+	got := decodeWriteRequest(t, body)
+	eur := "{asset_type=FxSpot,instance=nas,job=fx,tenant=acme,ticker=EURUSD}"
+	want := map[string]float64{
+		"fx_spread" + eur:     0.0004,
+		"fx_spread_min" + eur: 0.0002,
+		"fx_spread_max" + eur: 0.0004,
+		"fx_ticks" + eur:      2,
+		"fx_ticks{instance=nas,job=fx,tenant=acme,ticker=USDJPY}": 1,
+	}
+	for series, value := range want {
+		if math.Abs(got[series]-value) > 1e-12 {
+			t.Errorf("Expected %s %v, got %v", series, value, got[series])
+		}
+	}
+	if len(got) != 14 {
+		t.Errorf("Expected 7 series per instrument, got %d: %v", len(got), got)
+	}
+}
@@ -0,0 +1,177 @@
+// Package replay feeds captured broker updates back to the collector in place of the Saxo WebSocket
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// Client implements saxo.WebSocketClient over raw capture files (see storage.NDJSONRawCapture)
+// Captured frames are decoded again (see frameUpdates), captured updates delivered as they are.
+// The updates go through the same mapping and recording as live ones, which makes mapping bugs
+// reproducible against the broker quirks that triggered them
+type Client struct {
+	files []string
+	speed float64 // Replay speed relative to the capture (0: as fast as possible)
+
+	updates chan saxo.PriceUpdate
+	state   chan<- bool
+	once    sync.Once
+	done    chan struct{}
+	cancel  context.CancelFunc
+
+	mu      sync.Mutex
+	tickers map[int]string // UIC to ticker, for captured frames (see RegisterInstruments)
+	count   int
+	err     error
+}
+
+// NewClient creates a client replaying files in order; speed 1 keeps the captured pacing, 2 doubles it
+func NewClient(files []string, speed float64) *Client {
+	return &Client{
+		files:   files,
+		speed:   speed,
+		updates: make(chan saxo.PriceUpdate),
+		done:    make(chan struct{}),
+		cancel:  func() {},
+	}
+}
+
+// Connect reports the connection as up; there is nothing to connect to
+func (c *Client) Connect(ctx context.Context) error {
+	if c.state != nil {
+		select {
+		case c.state <- true:
+		default:
+		}
+	}
+	return nil
+}
+
+// SubscribeToPrices starts the replay on the first call; resubscribing doesn't restart it
+// Every captured update is delivered, also those of tickers not subscribed (they fail to map as they did live)
+func (c *Client) SubscribeToPrices(ctx context.Context, tickers []string) error {
+	c.once.Do(func() {
+		ctx, c.cancel = context.WithCancel(ctx)
+		go c.run(ctx)
+	})
+	return nil
+}
+
+// RegisterInstruments maps UICs to tickers for decoding captured frames, like the Saxo client does
+func (c *Client) RegisterInstruments(instruments []*saxo.Instrument) {
+	tickers := make(map[int]string, len(instruments))
+	for _, instrument := range instruments {
+		tickers[instrument.Identifier] = instrument.Ticker
+	}
+	c.mu.Lock()
+	c.tickers = tickers
+	c.mu.Unlock()
+}
+
+// SubscribeToOrders does nothing; captures hold price updates only
+func (c *Client) SubscribeToOrders(ctx context.Context) error { return nil }
+
+// SubscribeToPortfolio does nothing; captures hold price updates only
+func (c *Client) SubscribeToPortfolio(ctx context.Context) error { return nil }
+
+// SubscribeToSessionEvents does nothing; captures hold price updates only
+func (c *Client) SubscribeToSessionEvents(ctx context.Context) error { return nil }
+
+// GetOrderUpdateChannel returns a channel that never delivers
+func (c *Client) GetOrderUpdateChannel() <-chan saxo.OrderUpdate { return nil }
+
+// GetPortfolioUpdateChannel returns a channel that never delivers
+func (c *Client) GetPortfolioUpdateChannel() <-chan saxo.PortfolioUpdate { return nil }
+
+// GetPriceUpdateChannel returns the channel the captured updates are delivered on
+func (c *Client) GetPriceUpdateChannel() <-chan saxo.PriceUpdate {
+	return c.updates
+}
+
+// SetStateChannels keeps the state channel to report the connection; context IDs are not used
+func (c *Client) SetStateChannels(stateChannel chan<- bool, contextIDChannel chan<- string) {
+	c.state = stateChannel
+}
+
+// Close stops the replay
+func (c *Client) Close() error {
+	c.cancel()
+	return nil
+}
+
+// Done is closed once every update was delivered, the replay failed or was stopped
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Result returns the number of updates delivered and the error that ended the replay, if any
+func (c *Client) Result() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count, c.err
+}
+
+// run delivers the updates of all files until they are exhausted or ctx is done
+func (c *Client) run(ctx context.Context) {
+	defer close(c.done)
+
+	var previous time.Time
+	for _, file := range c.files {
+		err := storage.ReadRawCapture(file, func(record storage.RawCaptureRecord) error {
+			updates, err := c.decode(record)
+			if err != nil {
+				return fmt.Errorf("%s: invalid record at %v: %w", file, record.ReceivedAt, err)
+			}
+			if c.speed > 0 && !previous.IsZero() {
+				if wait := time.Duration(float64(record.ReceivedAt.Sub(previous)) / c.speed); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+			previous = record.ReceivedAt
+
+			for _, update := range updates {
+				select {
+				case c.updates <- update:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				c.mu.Lock()
+				c.count++
+				c.mu.Unlock()
+			}
+			return nil
+		})
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return
+		}
+	}
+}
+
+// decode returns the updates of a record: those of a captured frame, or the captured decoded update
+func (c *Client) decode(record storage.RawCaptureRecord) ([]saxo.PriceUpdate, error) {
+	if record.Frame != nil {
+		c.mu.Lock()
+		tickers := c.tickers
+		c.mu.Unlock()
+		return frameUpdates(record.Frame, record.ReceivedAt, tickers)
+	}
+	var update saxo.PriceUpdate
+	if err := json.Unmarshal(record.Payload, &update); err != nil {
+		return nil, err
+	}
+	return []saxo.PriceUpdate{update}, nil
+}
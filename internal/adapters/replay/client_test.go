@@ -0,0 +1,129 @@
+package replay
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+func TestClient_ReplaysCapture(t *testing.T) {
+	dir := t.TempDir()
+	capture := storage.NewNDJSONRawCapture(dir, 0)
+	base := time.Date(2025, 11, 18, 14, 0, 0, 0, time.UTC)
+	captured := []saxo.PriceUpdate{
+		{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Timestamp: base},
+		{Ticker: "USDJPY", Bid: 155.1, Ask: 155.12, Timestamp: base.Add(time.Second)},
+	}
+	for i, update := range captured {
+		if err := capture.Capture(base.Add(time.Duration(i)*time.Hour), update); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := capture.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	files, err := storage.ListRawCaptures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(files, 0)
+	state := make(chan bool, 1)
+	client.SetStateChannels(state, nil)
+	ctx := context.Background()
+	if err := client.Connect(ctx); err != nil || !<-state {
+		t.Fatalf("Expected a connected state, got %v", err)
+	}
+	if err := client.SubscribeToPrices(ctx, []string{"EURUSD"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range captured {
+		got := <-client.GetPriceUpdateChannel()
+		if got.Ticker != want.Ticker || got.Bid != want.Bid || !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("Update %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	<-client.Done()
+	if count, err := client.Result(); count != 2 || err != nil {
+		t.Errorf("Expected 2 updates and no error, got %d, %v", count, err)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw_20251118_14.ndjson")
+	capture := storage.NewNDJSONRawCapture(filepath.Dir(path), 0)
+	if err := capture.Capture(time.Date(2025, 11, 18, 14, 0, 0, 0, time.UTC), saxo.PriceUpdate{Ticker: "EURUSD"}); err != nil {
+		t.Fatal(err)
+	}
+	capture.Close(context.Background())
+
+	client := NewClient([]string{path}, 0)
+	client.SubscribeToPrices(context.Background(), nil)
+	client.Close() // Nobody reads the update
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Replay didn't stop on Close")
+	}
+	if _, err := client.Result(); err == nil {
+		t.Error("Expected the replay to report it was stopped")
+	}
+}
+
+// saxoFrame builds a streaming frame of messages, each a reference ID and a JSON payload
+func saxoFrame(messages ...[2]string) []byte {
+	var frame []byte
+	for i, message := range messages {
+		header := make([]byte, 11)
+		binary.LittleEndian.PutUint64(header, uint64(i+1))
+		header[10] = byte(len(message[0]))
+		frame = append(frame, header...)
+		frame = append(frame, message[0]...)
+		frame = append(frame, 0)
+		frame = binary.LittleEndian.AppendUint32(frame, uint32(len(message[1])))
+		frame = append(frame, message[1]...)
+	}
+	return frame
+}
+
+func TestClient_ReplaysCapturedFrames(t *testing.T) {
+	dir := t.TempDir()
+	capture := storage.NewNDJSONRawCapture(dir, 0)
+	receivedAt := time.Date(2025, 11, 18, 14, 0, 0, 0, time.UTC)
+	frame := saxoFrame(
+		[2]string{"_heartbeat", `[{"ReferenceId":"_heartbeat"}]`},
+		[2]string{"prices-20251118-140000", `[{"Uic":21,"Quote":{"Bid":1.1,"Ask":1.1002,"Mid":1.1001}},{"Uic":99,"Quote":{"Bid":1}}]`},
+	)
+	if err := capture.CaptureFrame(receivedAt, frame); err != nil {
+		t.Fatal(err)
+	}
+	capture.Close(context.Background())
+	files, _ := storage.ListRawCaptures(dir)
+
+	client := NewClient(files, 0)
+	client.RegisterInstruments([]*saxo.Instrument{{Ticker: "EURUSD", Identifier: 21}})
+	client.SubscribeToPrices(context.Background(), nil)
+
+	got := <-client.GetPriceUpdateChannel()
+	if got.Ticker != "EURUSD" || got.Bid != 1.1 || got.Ask != 1.1002 || !got.Timestamp.Equal(receivedAt) {
+		t.Errorf("Unexpected update %+v", got)
+	}
+	<-client.Done()
+	if count, err := client.Result(); count != 1 || err != nil {
+		t.Errorf("Expected 1 update (unknown UIC skipped) and no error, got %d, %v", count, err)
+	}
+}
+
+func TestParseFrame_Truncated(t *testing.T) {
+	frame := saxoFrame([2]string{"prices-1", `[{"Uic":21}]`})
+	if _, err := parseFrame(frame[:len(frame)-3]); err == nil {
+		t.Error("Expected an error for a truncated payload")
+	}
+}
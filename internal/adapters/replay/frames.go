@@ -0,0 +1,81 @@
+package replay
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// frameMessage is one message of a Saxo streaming frame
+type frameMessage struct {
+	referenceID string
+	payload     []byte
+}
+
+// parseFrame splits a Saxo streaming frame into its messages; a frame can carry several, each laid
+// out as message ID (8 bytes), reserved (2), reference ID size (1), reference ID, payload format
+// (1, 0 = JSON), payload size (4, little-endian) and payload
+func parseFrame(frame []byte) ([]frameMessage, error) {
+	var messages []frameMessage
+	for len(frame) > 0 {
+		if len(frame) < 11 {
+			return messages, fmt.Errorf("truncated message header (%d bytes)", len(frame))
+		}
+		refEnd := 11 + int(frame[10])
+		if len(frame) < refEnd+5 {
+			return messages, fmt.Errorf("truncated message header (%d bytes)", len(frame))
+		}
+		size := int(binary.LittleEndian.Uint32(frame[refEnd+1 : refEnd+5]))
+		end := refEnd + 5 + size
+		if len(frame) < end {
+			return messages, fmt.Errorf("truncated payload: %d of %d bytes", len(frame)-refEnd-5, size)
+		}
+		messages = append(messages, frameMessage{referenceID: string(frame[11:refEnd]), payload: frame[refEnd+5 : end]})
+		frame = frame[end:]
+	}
+	return messages, nil
+}
+
+// streamingPrice is one entry of a price subscription message
+type streamingPrice struct {
+	Uic   int `json:"Uic"`
+	Quote struct {
+		Bid float64 `json:"Bid"`
+		Ask float64 `json:"Ask"`
+		Mid float64 `json:"Mid"`
+	} `json:"Quote"`
+}
+
+// frameUpdates decodes the price updates of a frame like the Saxo WebSocket client does: entries
+// of "prices-" subscriptions, mapped from UIC to ticker and stamped with the time of receipt
+// Entries of unregistered UICs are skipped, as the client skips them. Unlike saxo-adapter v0.4.1,
+// which parses the first message of a frame only, every message is decoded
+func frameUpdates(frame []byte, receivedAt time.Time, tickers map[int]string) ([]saxo.PriceUpdate, error) {
+	messages, err := parseFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+	var updates []saxo.PriceUpdate
+	for _, message := range messages {
+		if !strings.HasPrefix(message.referenceID, "prices-") {
+			continue
+		}
+		var prices []streamingPrice
+		if err := json.Unmarshal(message.payload, &prices); err != nil {
+			return nil, fmt.Errorf("invalid price message %s: %w", message.referenceID, err)
+		}
+		for _, price := range prices {
+			ticker, ok := tickers[price.Uic]
+			if !ok {
+				continue
+			}
+			updates = append(updates, saxo.PriceUpdate{Ticker: ticker, Bid: price.Quote.Bid, Ask: price.Quote.Ask,
+				Mid: price.Quote.Mid, Timestamp: receivedAt})
+		}
+	}
+	return updates, nil
+}
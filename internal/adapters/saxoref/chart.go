@@ -0,0 +1,139 @@
+package saxoref
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxChartCount is the most bars the chart service returns per request
+const maxChartCount = 1200
+
+// chartRetries is how often a throttled (429) chart request is retried
+const chartRetries = 5
+
+// Bar is one bid/ask OHLC bar from the chart service
+type Bar struct {
+	Time     time.Time `json:"Time"` // Bar start (UTC)
+	OpenBid  float64   `json:"OpenBid"`
+	HighBid  float64   `json:"HighBid"`
+	LowBid   float64   `json:"LowBid"`
+	CloseBid float64   `json:"CloseBid"`
+	OpenAsk  float64   `json:"OpenAsk"`
+	HighAsk  float64   `json:"HighAsk"`
+	LowAsk   float64   `json:"LowAsk"`
+	CloseAsk float64   `json:"CloseAsk"`
+}
+
+// chartResponse is the part of /chart/v3/charts we use
+type chartResponse struct {
+	Data []Bar `json:"Data"`
+}
+
+// Charts fetches historical bid/ask bars using the Saxo OpenAPI chart service
+type Charts struct {
+	baseURL string
+	client  *http.Client // Must add the OAuth bearer token (e.g. from the auth client)
+}
+
+// NewCharts creates a chart client for baseURL (e.g. https://gateway.saxobank.com/sim/openapi)
+func NewCharts(client *http.Client, baseURL string) *Charts {
+	return &Charts{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Bars calls fn with the bars of horizon minutes in [from, to), one page at a time and in time order
+// Saxo keeps a limited history per horizon; bars before it are silently missing
+func (c *Charts) Bars(ctx context.Context, uic int, assetType string, horizon int, from, to time.Time, fn func([]Bar) error) error {
+	step := time.Duration(horizon) * time.Minute
+	for next := from; next.Before(to); {
+		page, err := c.page(ctx, uic, assetType, horizon, next)
+		if err != nil {
+			return err
+		}
+
+		var bars []Bar
+		for _, bar := range page {
+			if !bar.Time.Before(next) && bar.Time.Before(to) {
+				bars = append(bars, bar)
+			}
+		}
+		if len(bars) == 0 {
+			return nil // No more data in range
+		}
+		if err := fn(bars); err != nil {
+			return err
+		}
+		next = bars[len(bars)-1].Time.Add(step)
+	}
+	return nil
+}
+
+// page fetches up to maxChartCount bars starting at from, retrying while throttled
+func (c *Charts) page(ctx context.Context, uic int, assetType string, horizon int, from time.Time) ([]Bar, error) {
+	query := url.Values{
+		"Uic":       {strconv.Itoa(uic)},
+		"AssetType": {assetType},
+		"Horizon":   {strconv.Itoa(horizon)},
+		"Mode":      {"From"},
+		"Time":      {from.UTC().Format(time.RFC3339)},
+		"Count":     {strconv.Itoa(maxChartCount)},
+	}
+	u := c.baseURL + "/chart/v3/charts?" + query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch chart for %d: %w", uic, err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < chartRetries {
+			resp.Body.Close()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryAfter(resp.Header.Get("Retry-After"))):
+			}
+			continue
+		}
+
+		bars, err := decodeChart(resp, uic)
+		resp.Body.Close()
+		return bars, err
+	}
+}
+
+// decodeChart reads the bars of a chart response
+func decodeChart(resp *http.Response, uic int) ([]Bar, error) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("chart for %d returned status %d: %s", uic, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var chart chartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chart); err != nil {
+		return nil, fmt.Errorf("failed to decode chart for %d: %w", uic, err)
+	}
+	return chart.Data, nil
+}
+
+// retryAfter parses a Retry-After header in seconds (default 5s)
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Second
+}
@@ -0,0 +1,90 @@
+package saxoref
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCharts_BarsPages(t *testing.T) {
+	start := time.Date(2025, 11, 18, 0, 0, 0, 0, time.UTC)
+	throttled := false
+	requests := 0
+
+	// Serves 1-minute bars for 3000 minutes from start, up to maxChartCount per request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chart/v3/charts" || r.URL.Query().Get("Uic") != "21" || r.URL.Query().Get("Mode") != "From" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		if !throttled {
+			throttled = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		requests++
+
+		from, err := time.Parse(time.RFC3339, r.URL.Query().Get("Time"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var response chartResponse
+		for t := from; t.Before(start.Add(3000*time.Minute)) && len(response.Data) < maxChartCount; t = t.Add(time.Minute) {
+			response.Data = append(response.Data, Bar{Time: t, CloseBid: 1.1, CloseAsk: 1.10002})
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	var bars []Bar
+	err := NewCharts(server.Client(), server.URL).Bars(context.Background(), 21, "FxSpot", 1,
+		start, start.Add(72*time.Hour), func(page []Bar) error {
+			bars = append(bars, page...)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Bars failed: %v", err)
+	}
+
+	if len(bars) != 3000 {
+		t.Fatalf("Expected 3000 bars, got %d", len(bars))
+	}
+	for i, bar := range bars {
+		if want := start.Add(time.Duration(i) * time.Minute); !bar.Time.Equal(want) {
+			t.Fatalf("Bar %d at %v, want %v", i, bar.Time, want)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("Expected 4 page requests (3 full/partial + 1 empty), got %d", requests)
+	}
+}
+
+func TestCharts_BarsStopsAtEnd(t *testing.T) {
+	start := time.Date(2025, 11, 18, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response chartResponse
+		for i := range 10 {
+			response.Data = append(response.Data, Bar{Time: start.Add(time.Duration(i) * time.Hour)})
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	var bars []Bar
+	err := NewCharts(server.Client(), server.URL).Bars(context.Background(), 21, "FxSpot", 60,
+		start, start.Add(5*time.Hour), func(page []Bar) error {
+			bars = append(bars, page...)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Bars failed: %v", err)
+	}
+	if len(bars) != 5 {
+		t.Errorf("Expected 5 bars before the end, got %d", len(bars))
+	}
+}
@@ -0,0 +1,92 @@
+package saxoref
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// instrumentDetails is the part of /ref/v1/instruments/details we use
+type instrumentDetails struct {
+	Uic         int    `json:"Uic"`
+	Symbol      string `json:"Symbol"`
+	AssetType   string `json:"AssetType"`
+	Description string `json:"Description"`
+	Format      struct {
+		Decimals int    `json:"Decimals"`
+		Format   string `json:"Format"` // e.g. "AllowDecimalPips" for FX quoted with fractional pips
+	} `json:"Format"`
+}
+
+// Instrument is the broker's reference data for one instrument
+type Instrument struct {
+	Uic         int
+	Symbol      string // e.g. "EURUSD"
+	AssetType   string
+	Description string
+	Decimals    int // Streamed decimals, including a decimal pip
+}
+
+// InstrumentDetails implements InstrumentMetadata using the Saxo OpenAPI reference data service
+type InstrumentDetails struct {
+	baseURL string
+	client  *http.Client // Must add the OAuth bearer token (e.g. from the auth client)
+}
+
+// NewInstrumentDetails creates a reference data client for baseURL (e.g. https://gateway.saxobank.com/sim/openapi)
+func NewInstrumentDetails(client *http.Client, baseURL string) *InstrumentDetails {
+	return &InstrumentDetails{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Decimals returns the number of decimals Saxo quotes the instrument with
+// Instruments formatted with AllowDecimalPips are streamed with one extra decimal (EURUSD 1.08451)
+func (d *InstrumentDetails) Decimals(ctx context.Context, uic int, assetType string) (int, error) {
+	instrument, err := d.Lookup(ctx, uic, assetType)
+	if err != nil {
+		return 0, err
+	}
+	return instrument.Decimals, nil
+}
+
+// Lookup fetches the reference data for a UIC and asset type
+func (d *InstrumentDetails) Lookup(ctx context.Context, uic int, assetType string) (Instrument, error) {
+	url := fmt.Sprintf("%s/ref/v1/instruments/details/%d/%s", d.baseURL, uic, assetType)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Instrument{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return Instrument{}, fmt.Errorf("failed to fetch instrument details for %d: %w", uic, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Instrument{}, fmt.Errorf("instrument details for %d returned status %d: %s", uic, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var details instrumentDetails
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return Instrument{}, fmt.Errorf("failed to decode instrument details for %d: %w", uic, err)
+	}
+
+	decimals := details.Format.Decimals
+	if strings.Contains(details.Format.Format, "AllowDecimalPips") {
+		decimals++
+	}
+	return Instrument{
+		Uic:         uic,
+		Symbol:      details.Symbol,
+		AssetType:   assetType,
+		Description: details.Description,
+		Decimals:    decimals,
+	}, nil
+}
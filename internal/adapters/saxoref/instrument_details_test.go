@@ -0,0 +1,50 @@
+package saxoref
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInstrumentDetails_Decimals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi/ref/v1/instruments/details/21/FxSpot":
+			w.Write([]byte(`{"Uic":21,"Symbol":"EURUSD","Format":{"Decimals":4,"Format":"AllowDecimalPips","OrderDecimals":4}}`))
+		case "/openapi/ref/v1/instruments/details/4912/Stock":
+			w.Write([]byte(`{"Uic":4912,"Format":{"Decimals":2}}`))
+		default:
+			http.Error(w, `{"ErrorCode":"NotFound"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	details := NewInstrumentDetails(server.Client(), server.URL+"/openapi/")
+	ctx := context.Background()
+
+	if decimals, err := details.Decimals(ctx, 21, "FxSpot"); err != nil || decimals != 5 {
+		t.Errorf("Expected 5 decimals for EURUSD, got %d (%v)", decimals, err)
+	}
+	if decimals, err := details.Decimals(ctx, 4912, "Stock"); err != nil || decimals != 2 {
+		t.Errorf("Expected 2 decimals, got %d (%v)", decimals, err)
+	}
+	if _, err := details.Decimals(ctx, 1, "FxSpot"); err == nil {
+		t.Error("Expected error for unknown instrument")
+	}
+}
+
+func TestInstrumentDetails_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Uic":42,"Symbol":"USDJPY","Description":"US Dollar/Japanese Yen","Format":{"Decimals":2,"Format":"AllowDecimalPips"}}`))
+	}))
+	defer server.Close()
+
+	instrument, err := NewInstrumentDetails(server.Client(), server.URL).Lookup(context.Background(), 42, "FxSpot")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if instrument.Symbol != "USDJPY" || instrument.Decimals != 3 || instrument.AssetType != "FxSpot" {
+		t.Errorf("Unexpected instrument: %+v", instrument)
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveFilter narrows the spread archive down to the files a query needs
+// Zero values mean unbounded / all instruments
+type ArchiveFilter struct {
+	From    time.Time // First day included
+	To      time.Time // Last day included
+	Tickers []string
+}
+
+// ListSpreadFiles returns the hourly CSV files under baseDir matching filter, oldest day first
+// Filtering on the directory layout (YYYYMMDD/TICKER_HH.csv) avoids opening files a query can't match
+func ListSpreadFiles(baseDir string, filter ArchiveFilter) ([]string, error) {
+	return listArchive(baseDir, filter, spreadFileTicker)
+}
+
+// ListArrowFiles returns the hourly Arrow files under baseDir within the filter's days, oldest day first
+// Arrow files hold all instruments, so filter.Tickers is left to the reader
+func ListArrowFiles(baseDir string, filter ArchiveFilter) ([]string, error) {
+	return listArchive(baseDir, ArchiveFilter{From: filter.From, To: filter.To}, func(name string) (string, bool) {
+		return "", strings.HasPrefix(name, "spreads_") && strings.HasSuffix(name, ".arrow")
+	})
+}
+
+// listArchive returns the files of the day directories under baseDir accepted by match and filter
+// match returns the ticker a file holds ("" for all instruments) and whether it belongs to the archive
+func listArchive(baseDir string, filter ArchiveFilter, match func(name string) (string, bool)) ([]string, error) {
+	days, err := dayDirs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", baseDir, err)
+	}
+
+	from := dayKey(filter.From)
+	to := dayKey(filter.To)
+
+	var files []string
+	for _, day := range days {
+		if (from != "" && day < from) || (to != "" && day > to) {
+			continue
+		}
+
+		dirPath := filepath.Join(baseDir, day)
+		entries, err := os.ReadDir(dirPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", dirPath, err)
+		}
+
+		for _, entry := range entries {
+			ticker, ok := match(entry.Name())
+			if entry.IsDir() || !ok {
+				continue
+			}
+			if ticker != "" && len(filter.Tickers) > 0 && !slices.Contains(filter.Tickers, ticker) {
+				continue
+			}
+			files = append(files, filepath.Join(dirPath, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// spreadFileTicker extracts the ticker from an hourly file name (TICKER_HH.csv or TICKER_HH-N.csv,
+// either with .partial while being written)
+func spreadFileTicker(name string) (string, bool) {
+	base, ok := strings.CutSuffix(strings.TrimSuffix(name, PartialSuffix), ".csv")
+	if !ok {
+		return "", false
+	}
+	if i := strings.LastIndex(base, "-"); i > 0 {
+		if _, err := strconv.Atoi(base[i+1:]); err == nil {
+			base = base[:i]
+		}
+	}
+	i := strings.LastIndex(base, "_")
+	if i <= 0 || len(base)-i-1 != 2 {
+		return "", false
+	}
+	return base[:i], true
+}
+
+// SpreadFileHour returns the UTC hour an hourly file of the archive (YYYYMMDD/TICKER_HH.csv) holds
+func SpreadFileHour(path string) (time.Time, bool) {
+	name := filepath.Base(path)
+	ticker, ok := spreadFileTicker(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	day, err := time.Parse("20060102", filepath.Base(filepath.Dir(path)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	hour, err := strconv.Atoi(name[len(ticker)+1 : len(ticker)+3])
+	if err != nil || hour > 23 {
+		return time.Time{}, false
+	}
+	return day.Add(time.Duration(hour) * time.Hour), true
+}
+
+// dayKey formats t as a YYYYMMDD directory name ("" for the zero time)
+func dayKey(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("20060102")
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListSpreadFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, file := range []string{
+		"20251117/EURUSD_23.csv",
+		"20251118/EURUSD_00.csv",
+		"20251118/USDJPY_00.csv",
+		"20251118/USDJPY_00-2.csv",
+		"20251118/GBP_USD_01.csv",
+		"20251119/EURUSD_00.csv",
+		"20251118/notes.txt",
+	} {
+		path := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	day := func(d int) time.Time { return time.Date(2025, 11, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		filter   ArchiveFilter
+		expected int
+	}{
+		{"all", ArchiveFilter{}, 6},
+		{"single day", ArchiveFilter{From: day(18), To: day(18)}, 4},
+		{"from", ArchiveFilter{From: day(18)}, 5},
+		{"ticker", ArchiveFilter{Tickers: []string{"EURUSD"}}, 3},
+		{"numbered file", ArchiveFilter{Tickers: []string{"USDJPY"}}, 2},
+		{"ticker with underscore", ArchiveFilter{Tickers: []string{"GBP_USD"}}, 1},
+		{"day and ticker", ArchiveFilter{To: day(17), Tickers: []string{"EURUSD"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ListSpreadFiles(tempDir, tt.filter)
+			if err != nil {
+				t.Fatalf("ListSpreadFiles failed: %v", err)
+			}
+			if len(files) != tt.expected {
+				t.Errorf("Expected %d files, got %d: %v", tt.expected, len(files), files)
+			}
+		})
+	}
+}
+
+func TestSpreadFileHour(t *testing.T) {
+	tests := []struct {
+		path string
+		want time.Time
+		ok   bool
+	}{
+		{"data/spreads/20251118/EURUSD_14.csv", time.Date(2025, 11, 18, 14, 0, 0, 0, time.UTC), true},
+		{"data/spreads/20251118/GBP_USD_09-2.csv", time.Date(2025, 11, 18, 9, 0, 0, 0, time.UTC), true},
+		{"data/spreads/20251118/EURUSD_23.csv.partial", time.Date(2025, 11, 18, 23, 0, 0, 0, time.UTC), true},
+		{"data/spreads/20251118/notes.txt", time.Time{}, false},
+		{"data/spreads/latest/EURUSD_14.csv", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := SpreadFileHour(tt.path)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("SpreadFileHour(%s) = %v, %v; want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
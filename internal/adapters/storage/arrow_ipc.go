@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+
+	"github.com/bjoelf/fx-collector/internal/buildinfo"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// Minimal Apache Arrow IPC file encoder for the fixed tick schema
+// Format reference: https://arrow.apache.org/docs/format/Columnar.html#ipc-file-format
+// Only what the spread schema needs is implemented: non-nullable Timestamp, Int64, UInt64,
+// Float64 and Utf8 columns, no dictionaries and no compression
+
+const (
+	arrowMagic = "ARROW1"
+
+	arrowMetadataV5 = 4 // MetadataVersion.V5
+
+	arrowHeaderSchema      = 1 // MessageHeader.Schema
+	arrowHeaderRecordBatch = 3 // MessageHeader.RecordBatch
+
+	arrowTypeInt           = 2  // Type.Int
+	arrowTypeFloatingPoint = 3  // Type.FloatingPoint
+	arrowTypeUtf8          = 5  // Type.Utf8
+	arrowTypeTimestamp     = 10 // Type.Timestamp
+
+	arrowPrecisionDouble    = 2 // Precision.DOUBLE
+	arrowTimeUnitNanosecond = 3 // TimeUnit.NANOSECOND
+)
+
+// arrowKind is the physical type of a column
+type arrowKind int
+
+const (
+	arrowTimestampNanos arrowKind = iota
+	arrowInt64
+	arrowUint64
+	arrowFloat64
+	arrowUtf8
+)
+
+// arrowField describes one column of the schema
+type arrowField struct {
+	name string
+	kind arrowKind
+}
+
+// arrowColumn holds the values of one column for a record batch
+// Exactly one of the slices is used, depending on the field kind
+type arrowColumn struct {
+	ints    []int64
+	uints   []uint64
+	floats  []float64
+	strings []string
+}
+
+// arrowBlock locates a record batch inside the file for the footer
+type arrowBlock struct {
+	offset         int64
+	metadataLength int32
+	bodyLength     int64
+}
+
+// arrowFileHeader returns the leading magic bytes, padded to 8 bytes
+func arrowFileHeader() []byte {
+	return append([]byte(arrowMagic), 0, 0)
+}
+
+// arrowSchemaMessage encodes the schema as an encapsulated IPC message
+func arrowSchemaMessage(fields []arrowField) []byte {
+	b := flatbuffers.NewBuilder(1024)
+	schema := buildArrowSchema(b, fields)
+	return encapsulateArrowMessage(finishArrowMessage(b, arrowHeaderSchema, schema, 0), nil)
+}
+
+// arrowRecordBatchMessage encodes one record batch as an encapsulated IPC message
+// It returns the message bytes and the metadata length (prefix included) for the footer block
+func arrowRecordBatchMessage(fields []arrowField, columns []arrowColumn, rows int) ([]byte, int32, int64) {
+	type buffer struct{ offset, length int64 }
+
+	var (
+		body    []byte
+		buffers []buffer
+	)
+	appendBuffer := func(data []byte) {
+		buffers = append(buffers, buffer{offset: int64(len(body)), length: int64(len(data))})
+		body = append(body, data...)
+		body = append(body, make([]byte, padding8(len(body)))...)
+	}
+
+	for i, field := range fields {
+		column := columns[i]
+		appendBuffer(nil) // Validity bitmap may be omitted when there are no nulls
+
+		switch field.kind {
+		case arrowTimestampNanos, arrowInt64:
+			values := make([]byte, 8*len(column.ints))
+			for j, v := range column.ints {
+				binary.LittleEndian.PutUint64(values[8*j:], uint64(v))
+			}
+			appendBuffer(values)
+		case arrowUint64:
+			values := make([]byte, 8*len(column.uints))
+			for j, v := range column.uints {
+				binary.LittleEndian.PutUint64(values[8*j:], v)
+			}
+			appendBuffer(values)
+		case arrowFloat64:
+			values := make([]byte, 8*len(column.floats))
+			for j, v := range column.floats {
+				binary.LittleEndian.PutUint64(values[8*j:], math.Float64bits(v))
+			}
+			appendBuffer(values)
+		case arrowUtf8:
+			offsets := make([]byte, 4*(len(column.strings)+1))
+			var data []byte
+			for j, s := range column.strings {
+				data = append(data, s...)
+				binary.LittleEndian.PutUint32(offsets[4*(j+1):], uint32(len(data)))
+			}
+			appendBuffer(offsets)
+			appendBuffer(data)
+		}
+	}
+
+	b := flatbuffers.NewBuilder(1024)
+
+	b.StartVector(16, len(buffers), 8)
+	for i := len(buffers) - 1; i >= 0; i-- {
+		b.Prep(8, 16)
+		b.PrependInt64(buffers[i].length)
+		b.PrependInt64(buffers[i].offset)
+	}
+	buffersVector := b.EndVector(len(buffers))
+
+	b.StartVector(16, len(fields), 8)
+	for range fields {
+		b.Prep(8, 16)
+		b.PrependInt64(0) // null_count
+		b.PrependInt64(int64(rows))
+	}
+	nodesVector := b.EndVector(len(fields))
+
+	b.StartObject(5)
+	b.PrependInt64Slot(0, int64(rows), 0)
+	b.PrependUOffsetTSlot(1, nodesVector, 0)
+	b.PrependUOffsetTSlot(2, buffersVector, 0)
+	recordBatch := b.EndObject()
+
+	metadata := finishArrowMessage(b, arrowHeaderRecordBatch, recordBatch, int64(len(body)))
+	message := encapsulateArrowMessage(metadata, body)
+	return message, int32(len(message) - len(body)), int64(len(body))
+}
+
+// arrowEndOfStream is the end-of-stream marker written before the footer
+func arrowEndOfStream() []byte {
+	return []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}
+}
+
+// arrowFileFooter encodes the footer, its length and the trailing magic
+func arrowFileFooter(fields []arrowField, blocks []arrowBlock) []byte {
+	b := flatbuffers.NewBuilder(1024)
+	schema := buildArrowSchema(b, fields)
+
+	b.StartVector(24, len(blocks), 8)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b.Prep(8, 24)
+		b.PrependInt64(blocks[i].bodyLength)
+		b.Pad(4)
+		b.PrependInt32(blocks[i].metadataLength)
+		b.PrependInt64(blocks[i].offset)
+	}
+	recordBatches := b.EndVector(len(blocks))
+
+	b.StartVector(24, 0, 8)
+	dictionaries := b.EndVector(0)
+
+	b.StartObject(5)
+	b.PrependInt16Slot(0, arrowMetadataV5, 0)
+	b.PrependUOffsetTSlot(1, schema, 0)
+	b.PrependUOffsetTSlot(2, dictionaries, 0)
+	b.PrependUOffsetTSlot(3, recordBatches, 0)
+	b.Finish(b.EndObject())
+
+	footer := b.FinishedBytes()
+	out := make([]byte, 0, len(footer)+4+len(arrowMagic))
+	out = append(out, footer...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(footer)))
+	return append(out, arrowMagic...)
+}
+
+// buildArrowSchema writes a Schema table into b and returns its offset
+func buildArrowSchema(b *flatbuffers.Builder, fields []arrowField) flatbuffers.UOffsetT {
+	offsets := make([]flatbuffers.UOffsetT, len(fields))
+	for i, field := range fields {
+		name := b.CreateString(field.name)
+		typeType, typ := buildArrowType(b, field.kind)
+
+		// Readers expect a children vector even for primitive types
+		b.StartVector(4, 0, 4)
+		children := b.EndVector(0)
+
+		b.StartObject(7)
+		b.PrependUOffsetTSlot(0, name, 0)
+		b.PrependBoolSlot(1, false, false) // nullable
+		b.PrependByteSlot(2, typeType, 0)
+		b.PrependUOffsetTSlot(3, typ, 0)
+		b.PrependUOffsetTSlot(5, children, 0)
+		offsets[i] = b.EndObject()
+	}
+
+	b.StartVector(4, len(offsets), 4)
+	for i := len(offsets) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(offsets[i])
+	}
+	fieldsVector := b.EndVector(len(offsets))
+
+	metadata := buildArrowMetadata(b, []string{
+		"fx_collector.dataset", ArrowSpreadSchema.Dataset,
+		"fx_collector.schema_version", strconv.Itoa(ArrowSpreadSchema.Version),
+		"fx_collector.written_by", buildinfo.Get().Short(),
+	})
+
+	b.StartObject(4)
+	b.PrependUOffsetTSlot(1, fieldsVector, 0) // endianness defaults to Little
+	b.PrependUOffsetTSlot(2, metadata, 0)
+	return b.EndObject()
+}
+
+// buildArrowMetadata writes a custom_metadata vector of KeyValue tables from key, value pairs
+func buildArrowMetadata(b *flatbuffers.Builder, pairs []string) flatbuffers.UOffsetT {
+	offsets := make([]flatbuffers.UOffsetT, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key := b.CreateString(pairs[i])
+		value := b.CreateString(pairs[i+1])
+		b.StartObject(2)
+		b.PrependUOffsetTSlot(0, key, 0)
+		b.PrependUOffsetTSlot(1, value, 0)
+		offsets = append(offsets, b.EndObject())
+	}
+
+	b.StartVector(4, len(offsets), 4)
+	for i := len(offsets) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(offsets[i])
+	}
+	return b.EndVector(len(offsets))
+}
+
+// buildArrowType writes the type table for a column kind and returns the union type and offset
+func buildArrowType(b *flatbuffers.Builder, kind arrowKind) (byte, flatbuffers.UOffsetT) {
+	switch kind {
+	case arrowTimestampNanos:
+		timezone := b.CreateString("UTC")
+		b.StartObject(2)
+		b.PrependInt16Slot(0, arrowTimeUnitNanosecond, 0)
+		b.PrependUOffsetTSlot(1, timezone, 0)
+		return arrowTypeTimestamp, b.EndObject()
+	case arrowInt64, arrowUint64:
+		b.StartObject(2)
+		b.PrependInt32Slot(0, 64, 0)
+		b.PrependBoolSlot(1, kind == arrowInt64, false)
+		return arrowTypeInt, b.EndObject()
+	case arrowFloat64:
+		b.StartObject(1)
+		b.PrependInt16Slot(0, arrowPrecisionDouble, 0)
+		return arrowTypeFloatingPoint, b.EndObject()
+	default:
+		b.StartObject(0)
+		return arrowTypeUtf8, b.EndObject()
+	}
+}
+
+// finishArrowMessage wraps a header table in a Message table and returns the flatbuffer bytes
+func finishArrowMessage(b *flatbuffers.Builder, headerType byte, header flatbuffers.UOffsetT, bodyLength int64) []byte {
+	b.StartObject(5)
+	b.PrependInt16Slot(0, arrowMetadataV5, 0)
+	b.PrependByteSlot(1, headerType, 0)
+	b.PrependUOffsetTSlot(2, header, 0)
+	b.PrependInt64Slot(3, bodyLength, 0)
+	b.Finish(b.EndObject())
+	return b.FinishedBytes()
+}
+
+// encapsulateArrowMessage frames metadata and body as
+// <0xFFFFFFFF><int32 metadata size><metadata><padding to 8 bytes><body>
+func encapsulateArrowMessage(metadata, body []byte) []byte {
+	pad := padding8(8 + len(metadata))
+
+	out := make([]byte, 0, 8+len(metadata)+pad+len(body))
+	out = binary.LittleEndian.AppendUint32(out, 0xFFFFFFFF)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(metadata)+pad))
+	out = append(out, metadata...)
+	out = append(out, make([]byte, pad)...)
+	return append(out, body...)
+}
+
+// padding8 returns how many bytes are needed to align n to 8
+func padding8(n int) int {
+	return (8 - n%8) % 8
+}
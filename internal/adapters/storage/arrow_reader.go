@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// ReadArrowFile streams the ticks of one hourly Arrow file written by ArrowRecorder to fn
+// The file is read as a stream of messages rather than through the footer, so the file of the
+// current hour can be read up to its last complete record batch before it is finalized.
+// Spread is recomputed in price units; float files don't store Decimals, so it is inferred
+// from the shortest text of bid and ask
+func ReadArrowFile(path string, fn func(*domain.PriceData) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 1<<16)
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic[:6]) != arrowMagic {
+		return fmt.Errorf("%s is not an Arrow file", path)
+	}
+
+	var fields []arrowField
+	for {
+		headerType, header, body, err := readArrowMessage(reader)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil // End of stream, or a batch still being written
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		switch headerType {
+		case arrowHeaderSchema:
+			if fields, err = parseArrowSchema(header); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		case arrowHeaderRecordBatch:
+			if fields == nil {
+				return fmt.Errorf("%s: record batch before schema", path)
+			}
+			if err := readArrowRecordBatch(fields, header, body, fn); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+}
+
+// readArrowMessage reads one encapsulated message and returns its header table and body
+// io.EOF marks the end-of-stream marker
+func readArrowMessage(r io.Reader) (byte, *flatbuffers.Table, []byte, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return 0, nil, nil, err
+	}
+	if binary.LittleEndian.Uint32(prefix) != 0xFFFFFFFF {
+		return 0, nil, nil, fmt.Errorf("missing message continuation marker")
+	}
+	size := binary.LittleEndian.Uint32(prefix[4:])
+	if size == 0 {
+		return 0, nil, nil, io.EOF
+	}
+
+	metadata := make([]byte, size)
+	if _, err := io.ReadFull(r, metadata); err != nil {
+		return 0, nil, nil, err
+	}
+	message := &flatbuffers.Table{Bytes: metadata, Pos: flatbuffers.GetUOffsetT(metadata)}
+	headerType := message.GetByteSlot(6, 0)
+	header := arrowTableField(message, 2)
+	bodyLength := message.GetInt64Slot(10, 0)
+	if header == nil || bodyLength < 0 {
+		return 0, nil, nil, fmt.Errorf("invalid message metadata")
+	}
+
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, nil, err
+	}
+	return headerType, header, body, nil
+}
+
+// arrowTableField returns the table in field of t, or nil if it is absent
+func arrowTableField(t *flatbuffers.Table, field int) *flatbuffers.Table {
+	o := flatbuffers.UOffsetT(t.Offset(flatbuffers.VOffsetT(4 + 2*field)))
+	if o == 0 {
+		return nil
+	}
+	return &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(t.Pos + o)}
+}
+
+// parseArrowSchema reads the column layout of a Schema table
+// Only the types ArrowRecorder writes are accepted
+func parseArrowSchema(schema *flatbuffers.Table) ([]arrowField, error) {
+	o := flatbuffers.UOffsetT(schema.Offset(6))
+	if o == 0 {
+		return nil, fmt.Errorf("schema has no fields")
+	}
+	vector, n := schema.Vector(o), schema.VectorLen(o)
+
+	fields := make([]arrowField, n)
+	for i := range fields {
+		field := &flatbuffers.Table{Bytes: schema.Bytes, Pos: schema.Indirect(vector + flatbuffers.UOffsetT(4*i))}
+		if o := flatbuffers.UOffsetT(field.Offset(4)); o != 0 {
+			fields[i].name = field.String(field.Pos + o)
+		}
+
+		typ := arrowTableField(field, 3)
+		switch typeType := field.GetByteSlot(8, 0); {
+		case typeType == arrowTypeTimestamp && typ != nil && typ.GetInt16Slot(4, 0) == arrowTimeUnitNanosecond:
+			fields[i].kind = arrowTimestampNanos
+		case typeType == arrowTypeInt && typ != nil && typ.GetInt32Slot(4, 0) == 64:
+			fields[i].kind = arrowUint64
+			if typ.GetBoolSlot(6, false) {
+				fields[i].kind = arrowInt64
+			}
+		case typeType == arrowTypeFloatingPoint && typ != nil && typ.GetInt16Slot(4, 0) == arrowPrecisionDouble:
+			fields[i].kind = arrowFloat64
+		case typeType == arrowTypeUtf8:
+			fields[i].kind = arrowUtf8
+		default:
+			return nil, fmt.Errorf("unsupported type %d of column %q", typeType, fields[i].name)
+		}
+	}
+	return fields, nil
+}
+
+// readArrowRecordBatch decodes the columns of one record batch and calls fn per row
+func readArrowRecordBatch(fields []arrowField, batch *flatbuffers.Table, body []byte, fn func(*domain.PriceData) error) error {
+	rows := int(batch.GetInt64Slot(4, 0))
+	o := flatbuffers.UOffsetT(batch.Offset(8))
+	if o == 0 {
+		return fmt.Errorf("record batch has no buffers")
+	}
+	buffers, nBuffers := batch.Vector(o), batch.VectorLen(o)
+
+	next := 0
+	buffer := func() ([]byte, error) {
+		if next >= nBuffers {
+			return nil, fmt.Errorf("record batch has too few buffers")
+		}
+		at := buffers + flatbuffers.UOffsetT(16*next)
+		offset, length := batch.GetInt64(at), batch.GetInt64(at+8)
+		next++
+		if offset < 0 || length < 0 || offset+length > int64(len(body)) {
+			return nil, fmt.Errorf("buffer outside the record batch body")
+		}
+		return body[offset : offset+length], nil
+	}
+
+	columns := make(map[string]arrowColumn, len(fields))
+	for _, field := range fields {
+		if _, err := buffer(); err != nil { // Validity bitmap; ArrowRecorder writes no nulls
+			return err
+		}
+		values, err := buffer()
+		if err != nil {
+			return err
+		}
+
+		var column arrowColumn
+		switch field.kind {
+		case arrowUtf8:
+			data, err := buffer()
+			if err != nil {
+				return err
+			}
+			if len(values) < 4*(rows+1) {
+				return fmt.Errorf("column %q is shorter than %d rows", field.name, rows)
+			}
+			column.strings = make([]string, rows)
+			for j := range column.strings {
+				start, end := binary.LittleEndian.Uint32(values[4*j:]), binary.LittleEndian.Uint32(values[4*(j+1):])
+				if start > end || int(end) > len(data) {
+					return fmt.Errorf("invalid string offsets in column %q", field.name)
+				}
+				column.strings[j] = string(data[start:end])
+			}
+		default:
+			if len(values) < 8*rows {
+				return fmt.Errorf("column %q is shorter than %d rows", field.name, rows)
+			}
+			column.uints = make([]uint64, rows)
+			for j := range column.uints {
+				column.uints[j] = binary.LittleEndian.Uint64(values[8*j:])
+			}
+		}
+		columns[field.name] = column
+	}
+
+	kinds := make(map[string]arrowKind, len(fields))
+	for _, field := range fields {
+		kinds[field.name] = field.kind
+	}
+	for j := 0; j < rows; j++ {
+		data, err := arrowRow(columns, kinds, j)
+		if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// arrowRow converts row j of the decoded columns into price data
+func arrowRow(columns map[string]arrowColumn, kinds map[string]arrowKind, j int) (*domain.PriceData, error) {
+	word := func(name string) (uint64, bool) {
+		if c, ok := columns[name]; ok && c.uints != nil {
+			return c.uints[j], true
+		}
+		return 0, false
+	}
+	float := func(name string) float64 {
+		if c, ok := columns[name]; ok && c.uints != nil && kinds[name] == arrowFloat64 {
+			return math.Float64frombits(c.uints[j])
+		}
+		return 0
+	}
+	text := func(name string) string {
+		if c, ok := columns[name]; ok && c.strings != nil {
+			return c.strings[j]
+		}
+		return ""
+	}
+
+	nanos, ok := word("timestamp")
+	if !ok {
+		return nil, fmt.Errorf("missing column %q", "timestamp")
+	}
+	data := &domain.PriceData{
+		Timestamp:    time.Unix(0, int64(nanos)).UTC(),
+		Ticker:       text("ticker"),
+		AssetType:    text("asset_type"),
+		SessionLabel: text("session"),
+		Source:       text("source"),
+	}
+	// Written since v8; 0 (not computed) in older files
+	data.EffectiveSpread = float("effective_spread")
+	data.SpreadZ = float("spread_z")
+	if uic, ok := word("uic"); ok {
+		data.Uic = int(int64(uic))
+	}
+	if seq, ok := word("seq"); ok {
+		data.Sequence = seq
+	}
+	if flags, ok := word("flags"); ok {
+		data.Flags = domain.TickFlags(flags)
+	}
+
+	bid, okBid := word("bid")
+	ask, okAsk := word("ask")
+	if !okBid || !okAsk {
+		return nil, fmt.Errorf("missing bid or ask column")
+	}
+	if decimals, ok := word("decimals"); ok && kinds["bid"] == arrowInt64 {
+		// PriceFormatDecimal: scaled integers
+		data.Decimals = int(decimals)
+		data.Bid = domain.FixedPrice(int64(bid)).Float64(data.Decimals)
+		data.Ask = domain.FixedPrice(int64(ask)).Float64(data.Decimals)
+	} else {
+		data.Bid = math.Float64frombits(bid)
+		data.Ask = math.Float64frombits(ask)
+		data.Decimals = max(floatDecimals(data.Bid), floatDecimals(data.Ask))
+	}
+
+	// Recompute rather than convert: the stored spread may be in pips or points
+	data.Spread = roundPrice(data.Ask-data.Bid, data.Decimals)
+	if data.Flags.Has(domain.FlagClamped) {
+		data.Spread = 0
+	}
+	return data, nil
+}
+
+// floatDecimals returns the number of decimals in the shortest text of v
+func floatDecimals(v float64) int {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
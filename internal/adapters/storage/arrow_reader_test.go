@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestReadArrowFile_RoundTrip(t *testing.T) {
+	for _, format := range []domain.PriceFormat{domain.PriceFormatFloat, domain.PriceFormatDecimal} {
+		t.Run(string(format), func(t *testing.T) {
+			tempDir := t.TempDir()
+			recorder := NewArrowRecorder(tempDir, format)
+
+			written := &domain.PriceData{
+				Timestamp:    time.Date(2025, 11, 18, 14, 0, 0, 123456789, time.UTC),
+				Uic:          42,
+				Ticker:       "USDJPY",
+				AssetType:    "FxSpot",
+				Bid:          155.123,
+				Ask:          155.137,
+				Decimals:     3,
+				Sequence:     7,
+				SessionLabel: "tokyo",
+				Flags:        domain.FlagRollover,
+				SpreadUnit:   domain.SpreadUnitPips,
+				Source:       "dukascopy",
+			}
+			written.CalculateSpread()
+			written.EffectiveSpread = 0.0125
+			written.SpreadZ = 1.5
+			if err := recorder.Record(context.Background(), written); err != nil {
+				t.Fatalf("Failed to record: %v", err)
+			}
+			if err := recorder.Close(context.Background()); err != nil {
+				t.Fatalf("Failed to close: %v", err)
+			}
+
+			var got []*domain.PriceData
+			path := filepath.Join(tempDir, "20251118", "spreads_14.arrow")
+			if err := ReadArrowFile(path, func(p *domain.PriceData) error { got = append(got, p); return nil }); err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("Expected 1 tick, got %d", len(got))
+			}
+
+			tick := got[0]
+			if !tick.Timestamp.Equal(written.Timestamp) || tick.Uic != 42 || tick.Ticker != "USDJPY" || tick.AssetType != "FxSpot" {
+				t.Errorf("Unexpected identity fields: %+v", tick)
+			}
+			if tick.Bid != 155.123 || tick.Ask != 155.137 || tick.Decimals != 3 || tick.Spread != 0.014 {
+				t.Errorf("Unexpected prices: bid=%v ask=%v decimals=%d spread=%v", tick.Bid, tick.Ask, tick.Decimals, tick.Spread)
+			}
+			if tick.Sequence != 7 || tick.SessionLabel != "tokyo" || tick.Flags != domain.FlagRollover || tick.Source != "dukascopy" {
+				t.Errorf("Unexpected metadata: %+v", tick)
+			}
+			if tick.EffectiveSpread != 0.0125 || tick.SpreadZ != 1.5 {
+				t.Errorf("Expected effective spread 0.0125 and z 1.5, got %v and %v", tick.EffectiveSpread, tick.SpreadZ)
+			}
+		})
+	}
+}
+
+func TestReadArrowFile_Unfinalized(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	ctx := context.Background()
+
+	for i := range 3 {
+		data := &domain.PriceData{
+			Timestamp: time.Date(2025, 11, 18, 14, 0, i, 0, time.UTC),
+			Ticker:    "EURUSD",
+			Bid:       1.1,
+			Ask:       1.1002,
+			Decimals:  5,
+		}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+		if i == 1 {
+			if err := recorder.Flush(ctx); err != nil {
+				t.Fatalf("Failed to flush: %v", err)
+			}
+		}
+	}
+
+	// Only the flushed batch is on disk while the hour is still open
+	var count int
+	path := filepath.Join(tempDir, "20251118", "spreads_14.arrow")
+	if err := ReadArrowFile(path, func(*domain.PriceData) error { count++; return nil }); err != nil {
+		t.Fatalf("Failed to read open file: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 ticks before finalizing, got %d", count)
+	}
+
+	// A partially written batch is ignored: cut the file short of the end of the batch,
+	// which the end-of-stream marker and footer follow
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailer := 8 + int(binary.LittleEndian.Uint32(content[len(content)-10:])) + 10
+	if err := os.WriteFile(path, content[:len(content)-trailer-8], 0644); err != nil {
+		t.Fatal(err)
+	}
+	count = 0
+	if err := ReadArrowFile(path, func(*domain.PriceData) error { count++; return nil }); err != nil {
+		t.Fatalf("Failed to read truncated file: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the truncated batch to be skipped, got %d ticks", count)
+	}
+
+	recorder.Close(context.Background())
+}
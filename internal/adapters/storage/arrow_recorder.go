@@ -0,0 +1,446 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// arrowMaxBatchRows bounds memory use between flushes
+const arrowMaxBatchRows = 50_000
+
+// arrowMaxDecimals caps the decimals taken from the price text of a tick without Decimals,
+// so float artifacts can't overflow the int64 units
+const arrowMaxDecimals = 10
+
+// arrowSpreadFields is the column layout of the Arrow files, the columns of SpreadSchema
+// A column added to the CSV files is added here too, so the two schema versions stay the same
+var arrowSpreadFields = []arrowField{
+	{"timestamp", arrowTimestampNanos},
+	{"uic", arrowInt64},
+	{"ticker", arrowUtf8},
+	{"asset_type", arrowUtf8},
+	{"bid", arrowFloat64},
+	{"ask", arrowFloat64},
+	{"spread", arrowFloat64},
+	{"seq", arrowUint64},
+	{"session", arrowUtf8},
+	{"flags", arrowUint64},
+	{"spread_unit", arrowUtf8},
+	{"effective_spread", arrowFloat64},
+	{"source", arrowUtf8},
+	{"spread_z", arrowFloat64},
+}
+
+// arrowFields returns the column layout for a price format
+// PriceFormatDecimal stores bid, ask and spread as int64 units of the instrument's smallest
+// increment and adds a decimals column (value = units / 10^decimals); spread_unit does not apply
+func arrowFields(format domain.PriceFormat) []arrowField {
+	fields := slices.Clone(arrowSpreadFields)
+	if format == domain.PriceFormatDecimal {
+		fields[4].kind = arrowInt64
+		fields[5].kind = arrowInt64
+		fields[6].kind = arrowInt64
+		fields = append(fields, arrowField{"decimals", arrowInt64})
+	}
+	return fields
+}
+
+// ArrowRecorder implements SpreadRecorder writing hourly Apache Arrow IPC (Feather v2) files
+// File format: data/arrow/YYYYMMDD/spreads_HH.arrow (all instruments in one file per hour)
+// Each Flush appends a record batch and rewrites the end-of-stream marker and footer after it,
+// so the file of the current hour is readable with pyarrow.ipc.open_file / pandas.read_feather
+// up to its last flush; it is finalized when the hour rolls over or on Close
+type ArrowRecorder struct {
+	baseDir string
+	format  domain.PriceFormat
+	fields  []arrowField
+	mu      sync.Mutex
+
+	hourKey string
+	hour    time.Time // Hour of the current file, to reopen one that was abandoned
+	file    arrowFile // nil after an abandoned file until the next batch opens another one
+	offset  int64     // End of the last record batch; the trailer is written from here
+	blocks  []arrowBlock
+	trailed int // Blocks listed in the trailer on disk, -1 if it is missing or stale
+
+	columns []arrowColumn
+	rows    int
+
+	syncPolicy SyncPolicy
+	unsynced   int // Rows written since the last fsync (SyncEvery)
+
+	written atomic.Uint64 // Bytes written to files
+}
+
+// arrowFile is the part of *os.File the recorder writes with
+type arrowFile interface {
+	io.Writer
+	io.WriterAt
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+	Name() string
+}
+
+func init() {
+	// arrow?dir=data/arrow&format=float&fsync=never
+	RegisterRecorder("arrow", func(spec RecorderSpec) (ports.TickWriter, error) {
+		format, err := spec.PriceFormat()
+		if err != nil {
+			return nil, err
+		}
+		policy, err := ParseSyncPolicy(spec.Param("fsync", ""))
+		if err != nil {
+			return nil, err
+		}
+		recorder := NewArrowRecorder(spec.Param("dir", "data/arrow"), format)
+		recorder.SetSyncPolicy(policy)
+		return recorder, nil
+	})
+}
+
+// NewArrowRecorder creates a new Arrow IPC recorder
+func NewArrowRecorder(baseDir string, format domain.PriceFormat) *ArrowRecorder {
+	r := &ArrowRecorder{baseDir: baseDir, format: format, fields: arrowFields(format)}
+	r.resetColumns()
+	return r
+}
+
+// SetSyncPolicy sets when files are fsynced (default never)
+// SyncEvery writes a record batch per fsync, so small N means many small batches
+func (r *ArrowRecorder) SetSyncPolicy(policy SyncPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncPolicy = policy
+}
+
+// Record saves a single price data point
+func (r *ArrowRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.rotate(ctx, data.Timestamp); err != nil {
+		return err
+	}
+	return ports.PartialWrite(1, r.appendRow(data)) // The row stays buffered if writing fails
+}
+
+// RecordBatch saves multiple price data points efficiently
+func (r *ArrowRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, priceData := range data {
+		if err := r.rotate(ctx, priceData.Timestamp); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+		if err := r.appendRow(priceData); err != nil {
+			return ports.PartialWrite(i+1, err) // The row stays buffered for the next batch
+		}
+	}
+	return nil
+}
+
+// keepsUnwritten marks the recorder as keeping the rows of a batch it failed to write for the next
+// flush (unwrittenKeeper)
+func (r *ArrowRecorder) keepsUnwritten() {}
+
+// Flush writes buffered rows to the current hourly file as one record batch
+func (r *ArrowRecorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writeBatch(); err != nil {
+		return err
+	}
+	if err := r.writeTrailer(); err != nil {
+		return err
+	}
+	if r.syncPolicy.syncs() {
+		r.unsynced = 0
+		return r.syncFile()
+	}
+	return nil
+}
+
+// Close writes remaining rows and finalizes the current file; the fsync is skipped if ctx is done
+func (r *ArrowRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finalize(ctx)
+}
+
+// rotate finalizes the current file and opens the one of t's hour when the hour changes
+func (r *ArrowRecorder) rotate(ctx context.Context, t time.Time) error {
+	key := t.Format("20060102_15")
+	if key == r.hourKey {
+		return nil
+	}
+	if err := r.finalize(ctx); err != nil {
+		return err
+	}
+	if err := r.open(t); err != nil {
+		return err
+	}
+	r.hourKey = key
+	return nil
+}
+
+// appendRow buffers one tick in the current file's batch, writing the batch when it is due
+// The row stays buffered if writing fails
+func (r *ArrowRecorder) appendRow(data *domain.PriceData) error {
+	r.columns[0].ints = append(r.columns[0].ints, data.Timestamp.UnixNano())
+	r.columns[1].ints = append(r.columns[1].ints, int64(data.Uic))
+	r.columns[2].strings = append(r.columns[2].strings, data.Ticker)
+	r.columns[3].strings = append(r.columns[3].strings, data.AssetType)
+	if r.format == domain.PriceFormatDecimal {
+		decimals := arrowDecimals(data)
+		bid, ask := domain.ToFixedPrice(data.Bid, decimals), domain.ToFixedPrice(data.Ask, decimals)
+		spread := ask - bid
+		if data.Flags.Has(domain.FlagClamped) {
+			spread = 0
+		}
+		r.columns[4].ints = append(r.columns[4].ints, int64(bid))
+		r.columns[5].ints = append(r.columns[5].ints, int64(ask))
+		r.columns[6].ints = append(r.columns[6].ints, int64(spread))
+		r.columns[len(r.fields)-1].ints = append(r.columns[len(r.fields)-1].ints, int64(decimals))
+	} else {
+		spread, precision := data.UnitSpread()
+		r.columns[4].floats = append(r.columns[4].floats, roundPrice(data.Bid, data.Decimals))
+		r.columns[5].floats = append(r.columns[5].floats, roundPrice(data.Ask, data.Decimals))
+		r.columns[6].floats = append(r.columns[6].floats, roundPrice(spread, precision))
+	}
+	r.columns[7].uints = append(r.columns[7].uints, data.Sequence)
+	r.columns[8].strings = append(r.columns[8].strings, data.SessionLabel)
+	r.columns[9].uints = append(r.columns[9].uints, uint64(data.Flags))
+	r.columns[10].strings = append(r.columns[10].strings, arrowSpreadUnit(r.format, data))
+	r.columns[11].floats = append(r.columns[11].floats, roundPrice(data.EffectiveSpread, data.Decimals+1))
+	r.columns[12].strings = append(r.columns[12].strings, data.Source)
+	r.columns[13].floats = append(r.columns[13].floats, roundPrice(data.SpreadZ, 2))
+	r.rows++
+
+	if r.syncPolicy.due(&r.unsynced, 1) {
+		if err := r.writeBatch(); err != nil {
+			return err
+		}
+		return r.syncFile()
+	}
+	if r.rows >= arrowMaxBatchRows {
+		return r.writeBatch()
+	}
+	return nil
+}
+
+// open creates the hourly file for t and writes the file header and schema
+// A file left over from an earlier run in the same hour is kept; a numbered file is created instead
+func (r *ArrowRecorder) open(t time.Time) error {
+	dirPath := filepath.Join(r.baseDir, t.Format("20060102"))
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dirPath, err)
+	}
+
+	var (
+		file *os.File
+		err  error
+	)
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("spreads_%s.arrow", t.Format("15"))
+		if n > 1 {
+			name = fmt.Sprintf("spreads_%s-%d.arrow", t.Format("15"), n)
+		}
+		file, err = os.OpenFile(filepath.Join(dirPath, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create arrow file in %s: %w", dirPath, err)
+	}
+
+	r.file = file
+	r.hour = t
+	r.offset = 0
+	r.blocks = nil
+	r.trailed = -1
+
+	err = r.write(arrowFileHeader())
+	if err == nil {
+		err = r.write(arrowSchemaMessage(r.fields))
+	}
+	if err == nil {
+		err = r.writeTrailer()
+	}
+	if err != nil {
+		// Nothing was recorded in it yet
+		file.Close()
+		os.Remove(file.Name())
+		r.file = nil
+		return err
+	}
+
+	log.Printf("ArrowRecorder: ✅ Opened %s", file.Name())
+	return nil
+}
+
+// writeBatch appends the buffered rows as a record batch, to a new file of the hour if the last
+// one was abandoned
+func (r *ArrowRecorder) writeBatch() error {
+	if r.rows == 0 {
+		return nil
+	}
+	if r.file == nil {
+		if err := r.open(r.hour); err != nil {
+			return err
+		}
+	}
+
+	message, metadataLength, bodyLength := arrowRecordBatchMessage(r.fields, r.columns, r.rows)
+	block := arrowBlock{offset: r.offset, metadataLength: metadataLength, bodyLength: bodyLength}
+	r.trailed = -1 // Overwritten by the batch
+	if err := r.write(message); err != nil {
+		return err
+	}
+
+	r.blocks = append(r.blocks, block)
+	r.resetColumns()
+	return nil
+}
+
+// finalize writes pending rows and the trailer listing all of them, then closes the file
+func (r *ArrowRecorder) finalize(ctx context.Context) error {
+	if r.file == nil && r.rows == 0 {
+		return nil
+	}
+
+	if err := r.writeBatch(); err != nil {
+		return err
+	}
+	if err := r.writeTrailer(); err != nil {
+		return err
+	}
+	if r.syncPolicy.syncs() && ctx.Err() == nil {
+		if err := r.syncFile(); err != nil {
+			return err
+		}
+	}
+
+	name := r.file.Name()
+	err := r.file.Close()
+	r.file = nil
+	r.hourKey = ""
+	if err != nil {
+		return fmt.Errorf("failed to close %s: %w", name, err)
+	}
+
+	log.Printf("ArrowRecorder: ✅ Finalized %s (%d batches)", name, len(r.blocks))
+	return nil
+}
+
+// write appends bytes to the current file and tracks the offset for footer blocks
+// What a failed write got to the file is cut off again, so the next batch and the trailer go where
+// the blocks say; if that fails too, the file is abandoned and the next batch starts a new one
+func (r *ArrowRecorder) write(p []byte) error {
+	n, err := r.file.Write(p)
+	r.written.Add(uint64(n))
+	if err == nil {
+		r.offset += int64(n)
+		return nil
+	}
+
+	err = fmt.Errorf("failed to write %s: %w", r.file.Name(), err)
+	if n > 0 {
+		if truncateErr := r.truncate(); truncateErr != nil {
+			log.Printf("ArrowRecorder: ❌ Abandoning %s after %d batches, it can't be cut back to %d bytes: %v",
+				r.file.Name(), len(r.blocks), r.offset, truncateErr)
+			r.file.Close()
+			r.file = nil
+		}
+	}
+	return err
+}
+
+// truncate cuts the current file back to the end of the last record batch
+func (r *ArrowRecorder) truncate() error {
+	if err := r.file.Truncate(r.offset); err != nil {
+		return err
+	}
+	_, err := r.file.Seek(r.offset, io.SeekStart)
+	return err
+}
+
+// writeTrailer writes the end-of-stream marker and the footer after the last record batch,
+// without moving the file position, so the next batch overwrites them
+// The trailer only grows, so no bytes of an older one are left behind
+func (r *ArrowRecorder) writeTrailer() error {
+	if r.file == nil || r.trailed == len(r.blocks) {
+		return nil
+	}
+	trailer := append(arrowEndOfStream(), arrowFileFooter(r.fields, r.blocks)...)
+	n, err := r.file.WriteAt(trailer, r.offset)
+	r.written.Add(uint64(n))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", r.file.Name(), err)
+	}
+	r.trailed = len(r.blocks)
+	return nil
+}
+
+// BytesWritten returns the bytes written to files so far
+func (r *ArrowRecorder) BytesWritten() uint64 {
+	return r.written.Load()
+}
+
+// OpenFiles returns 1 while an hourly file is open
+func (r *ArrowRecorder) OpenFiles() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file != nil {
+		return 1
+	}
+	return 0
+}
+
+// syncFile fsyncs the current file
+func (r *ArrowRecorder) syncFile() error {
+	if r.file == nil {
+		return nil
+	}
+	if err := r.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", r.file.Name(), err)
+	}
+	return nil
+}
+
+// resetColumns clears the row buffer
+func (r *ArrowRecorder) resetColumns() {
+	r.columns = make([]arrowColumn, len(r.fields))
+	r.rows = 0
+}
+
+// arrowDecimals returns the decimals of a tick's units in PriceFormatDecimal: its own, or for a tick
+// without them (0) those of its price text, so prices aren't truncated to whole units
+func arrowDecimals(data *domain.PriceData) int {
+	if data.Decimals > 0 {
+		return data.Decimals
+	}
+	return min(max(floatDecimals(data.Bid), floatDecimals(data.Ask)), arrowMaxDecimals)
+}
+
+// arrowSpreadUnit returns the unit of the spread column for a row
+func arrowSpreadUnit(format domain.PriceFormat, data *domain.PriceData) string {
+	if format == domain.PriceFormatDecimal {
+		return "" // Spread is in units of the decimals column
+	}
+	return string(data.SpreadUnit)
+}
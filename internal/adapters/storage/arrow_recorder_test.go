@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// arrowTable returns the root table of a flatbuffer
+func arrowTable(buf []byte) *flatbuffers.Table {
+	return &flatbuffers.Table{Bytes: buf, Pos: flatbuffers.GetUOffsetT(buf)}
+}
+
+// arrowSlot returns the absolute position of a table field (0 if absent)
+func arrowSlot(t *flatbuffers.Table, field int) flatbuffers.UOffsetT {
+	o := flatbuffers.UOffsetT(t.Offset(flatbuffers.VOffsetT(4 + 2*field)))
+	if o == 0 {
+		return 0
+	}
+	return t.Pos + o
+}
+
+// arrowFooter checks the magic bytes and returns the footer table
+func arrowFooter(t *testing.T, content []byte) *flatbuffers.Table {
+	t.Helper()
+	if string(content[:6]) != arrowMagic || string(content[len(content)-6:]) != arrowMagic {
+		t.Fatal("Missing Arrow magic bytes")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(content[len(content)-10:]))
+	return arrowTable(content[len(content)-10-footerLen : len(content)-10])
+}
+
+// arrowRecordBatch returns the RecordBatch table and body of the i-th batch listed in the footer
+func arrowRecordBatch(t *testing.T, content []byte, footer *flatbuffers.Table, i int) (*flatbuffers.Table, []byte) {
+	t.Helper()
+	block := footer.Vector(arrowSlot(footer, 3)-footer.Pos) + flatbuffers.UOffsetT(24*i)
+	offset := footer.GetInt64(block)
+	metadataLength := int64(footer.GetInt32(block + 8))
+
+	if binary.LittleEndian.Uint32(content[offset:]) != 0xFFFFFFFF {
+		t.Fatal("Missing continuation marker")
+	}
+	msg := arrowTable(content[offset+8 : offset+metadataLength])
+	if headerType := msg.GetByteSlot(6, 0); headerType != arrowHeaderRecordBatch {
+		t.Fatalf("Expected record batch header, got %d", headerType)
+	}
+
+	var recordBatch flatbuffers.Table
+	msg.Union(&recordBatch, arrowSlot(msg, 2)-msg.Pos)
+	return &recordBatch, content[offset+metadataLength:]
+}
+
+func TestArrowRecorder_WritesReadableFile(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	ctx := context.Background()
+
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	bids := []float64{1.08451, 1.08453, 1.08449}
+	for i, bid := range bids {
+		data := &domain.PriceData{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Uic:       21,
+			Ticker:    "EURUSD",
+			AssetType: "FxSpot",
+			Bid:       bid,
+			Ask:       bid + 0.0001,
+			Decimals:  5,
+			Sequence:  uint64(i + 1),
+		}
+		data.CalculateSpread()
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+		// Two batches: one after the first tick, one on Close
+		if i == 0 {
+			if err := recorder.Flush(ctx); err != nil {
+				t.Fatalf("Failed to flush: %v", err)
+			}
+		}
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "20251118", "spreads_14.arrow"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	footer := arrowFooter(t, content)
+	if version := footer.GetInt16Slot(4, 0); version != arrowMetadataV5 {
+		t.Errorf("Unexpected metadata version: %d", version)
+	}
+
+	batches := arrowSlot(footer, 3)
+	if batches == 0 || footer.VectorLen(batches-footer.Pos) != 2 {
+		t.Fatalf("Expected 2 record batches in footer")
+	}
+
+	// Read the bid column back from the second batch (rows 2 and 3)
+	recordBatch, body := arrowRecordBatch(t, content, footer, 1)
+	if rows := recordBatch.GetInt64Slot(4, 0); rows != 2 {
+		t.Fatalf("Expected 2 rows, got %d", rows)
+	}
+
+	// Buffers: timestamp(2), uic(2), ticker(3), asset_type(3), bid validity, bid values
+	buffers := recordBatch.Vector(arrowSlot(recordBatch, 2) - recordBatch.Pos)
+	bidValues := buffers + 11*16
+	bufferOffset := recordBatch.GetInt64(bidValues)
+
+	for i, want := range bids[1:] {
+		got := math.Float64frombits(binary.LittleEndian.Uint64(body[bufferOffset+int64(8*i):]))
+		if got != want {
+			t.Errorf("Bid %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestArrowRecorder_ReadableBeforeFinalized(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	ctx := context.Background()
+	path := filepath.Join(tempDir, "20251118", "spreads_14.arrow")
+
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	for i := range 3 {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+		if err := recorder.Flush(ctx); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		footer := arrowFooter(t, content)
+		if batches := footer.VectorLen(arrowSlot(footer, 3) - footer.Pos); batches != i+1 {
+			t.Fatalf("Expected %d record batches in the footer, got %d", i+1, batches)
+		}
+		if rows, _ := arrowRecordBatch(t, content, footer, i); rows.GetInt64Slot(4, 0) != 1 {
+			t.Errorf("Expected 1 row in batch %d, got %d", i, rows.GetInt64Slot(4, 0))
+		}
+	}
+
+	ticks := 0
+	if err := ReadArrowFile(path, func(*domain.PriceData) error { ticks++; return nil }); err != nil {
+		t.Fatalf("Failed to read open file: %v", err)
+	}
+	if ticks != 3 {
+		t.Errorf("Expected 3 ticks before Close, got %d", ticks)
+	}
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+}
+
+// shortWriteFile writes only the first half of the next write, then reports a full disk
+type shortWriteFile struct {
+	arrowFile
+	fail       bool
+	noTruncate bool // Truncate fails too
+}
+
+func (f *shortWriteFile) Truncate(size int64) error {
+	if f.noTruncate {
+		return errors.New("input/output error")
+	}
+	return f.arrowFile.Truncate(size)
+}
+
+func (f *shortWriteFile) Write(p []byte) (int, error) {
+	if !f.fail {
+		return f.arrowFile.Write(p)
+	}
+	f.fail = false
+	n, _ := f.arrowFile.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestArrowRecorder_ShortWriteIsCutOff(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	ctx := context.Background()
+	path := filepath.Join(tempDir, "20251118", "spreads_14.arrow")
+
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	record := func(i int) {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	record(0)
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	record(1)
+	recorder.file = &shortWriteFile{arrowFile: recorder.file, fail: true}
+	if err := recorder.Flush(ctx); err == nil {
+		t.Fatal("Expected the short write to fail the flush")
+	}
+	record(2)
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	var seconds []int
+	if err := ReadArrowFile(path, func(p *domain.PriceData) error { seconds = append(seconds, p.Timestamp.Second()); return nil }); err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if len(seconds) != 3 || seconds[0] != 0 || seconds[1] != 1 || seconds[2] != 2 {
+		t.Errorf("Expected the ticks of seconds 0, 1 and 2 once each, got %v", seconds)
+	}
+}
+
+func TestArrowRecorder_AbandonsFileThatCantBeCutOff(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	ctx := context.Background()
+
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	if err := recorder.Record(ctx, &domain.PriceData{Timestamp: base, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	recorder.file = &shortWriteFile{arrowFile: recorder.file, fail: true, noTruncate: true}
+	if err := recorder.Flush(ctx); err == nil {
+		t.Fatal("Expected the short write to fail the flush")
+	}
+	if recorder.OpenFiles() != 0 {
+		t.Fatal("Expected the file to be abandoned")
+	}
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	// The buffered row went to a new file of the hour
+	ticks := 0
+	if err := ReadArrowFile(filepath.Join(tempDir, "20251118", "spreads_14-2.arrow"), func(*domain.PriceData) error { ticks++; return nil }); err != nil {
+		t.Fatalf("Failed to read the new file: %v", err)
+	}
+	if ticks != 1 {
+		t.Errorf("Expected 1 tick in the new file, got %d", ticks)
+	}
+}
+
+func TestArrowRecorder_RotatesHourly(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	ctx := context.Background()
+
+	base := time.Date(2025, 11, 18, 14, 59, 59, 0, time.UTC)
+	for _, ts := range []time.Time{base, base.Add(2 * time.Second)} {
+		data := &domain.PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	for _, name := range []string{"spreads_14.arrow", "spreads_15.arrow"} {
+		if _, err := os.Stat(filepath.Join(tempDir, "20251118", name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+}
+
+func TestArrowRecorder_DecimalPrices(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatDecimal)
+
+	data := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC),
+		Ticker:    "EURUSD",
+		Bid:       1.08451,
+		Ask:       1.08471,
+		Decimals:  5,
+	}
+	data.CalculateSpread()
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "20251118", "spreads_14.arrow"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	recordBatch, body := arrowRecordBatch(t, content, arrowFooter(t, content), 0)
+
+	// Buffers: timestamp(2), uic(2), ticker(3), asset_type(3), bid(2), ask(2), spread validity, spread values
+	buffers := recordBatch.Vector(arrowSlot(recordBatch, 2) - recordBatch.Pos)
+	spreadOffset := recordBatch.GetInt64(buffers + 15*16)
+	if got := int64(binary.LittleEndian.Uint64(body[spreadOffset:])); got != 20 {
+		t.Errorf("Expected spread of 20 units, got %d", got)
+	}
+}
+
+func TestArrowRecorder_DecimalPricesWithoutDecimals(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatDecimal)
+
+	// Decimals unknown (0): scaled by the price text instead of truncated to 1
+	data := &domain.PriceData{Timestamp: time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC), Ticker: "EURUSD", Bid: 1.0845, Ask: 1.08471}
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	var got []*domain.PriceData
+	path := filepath.Join(tempDir, "20251118", "spreads_14.arrow")
+	if err := ReadArrowFile(path, func(p *domain.PriceData) error { got = append(got, p); return nil }); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if len(got) != 1 || got[0].Bid != 1.0845 || got[0].Ask != 1.08471 || got[0].Decimals != 5 {
+		t.Fatalf("Expected 1.0845/1.08471 with 5 decimals, got %+v", got)
+	}
+}
+
+func TestArrowRecorder_SyncEvery(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	recorder.SetSyncPolicy(SyncPolicy{Mode: SyncEvery, Records: 2})
+
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	for i := range 5 {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+		if err := recorder.Record(context.Background(), data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "20251118", "spreads_14.arrow"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	// Rows 1-2 and 3-4 are synced as their own batches, row 5 is written on Close
+	footer := arrowFooter(t, content)
+	if batches := footer.VectorLen(arrowSlot(footer, 3) - footer.Pos); batches != 3 {
+		t.Errorf("Expected 3 record batches, got %d", batches)
+	}
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Buckets of the state database
+var (
+	bucketMeta         = []byte("meta")
+	bucketSequences    = []byte("sequences")     // Ticker -> last sequence (uint64, big-endian)
+	bucketLastRecorded = []byte("last_recorded") // Ticker -> last flushed tick (Unix nanoseconds, big-endian)
+	bucketGaps         = []byte("gaps")          // Start (Unix nanoseconds, big-endian) -> RecordingGap JSON
+	bucketBackups      = []byte("backups")       // Backup target -> BackedUpFile JSON by DAY/FILE
+)
+
+// ErrStateStoreInUse is returned when another process, usually the running collector, holds the state database
+var ErrStateStoreInUse = errors.New("state database is in use by another process")
+
+// stateSchemaVersion is stored in the meta bucket so later layouts can migrate older databases
+const stateSchemaVersion = "1"
+
+// BoltStateStore implements StateStore in a single bbolt database file
+// Every save is one fsynced transaction, so a crash leaves either the old or the new state
+// bbolt locks the file: one collector per database
+type BoltStateStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStateStore opens (or creates) the state database at path
+// On first use, sequences are imported from legacySequenceFile (the JSON file of
+// SEQUENCE_STATE_FILE) if it exists, so numbering continues when switching stores
+func OpenBoltStateStore(path, legacySequenceFile string) (*BoltStateStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s: %w", path, ErrStateStoreInUse)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+
+	var imported map[string]uint64
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketMeta, bucketSequences, bucketLastRecorded, bucketGaps, bucketBackups} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		meta := tx.Bucket(bucketMeta)
+		if version := meta.Get([]byte("version")); version != nil && string(version) != stateSchemaVersion {
+			return fmt.Errorf("unsupported state schema version %s", version)
+		}
+		if err := meta.Put([]byte("version"), []byte(stateSchemaVersion)); err != nil {
+			return err
+		}
+
+		sequences := tx.Bucket(bucketSequences)
+		if first, _ := sequences.Cursor().First(); legacySequenceFile == "" || first != nil {
+			return nil
+		}
+		legacy, err := NewJSONSequenceStore(legacySequenceFile).Load(context.Background())
+		if err != nil {
+			return err
+		}
+		imported = legacy
+		return putUint64s(sequences, legacy)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize state database %s: %w", path, err)
+	}
+	if len(imported) > 0 {
+		log.Printf("BoltStateStore: Imported sequence numbers of %d instruments from %s", len(imported), legacySequenceFile)
+	}
+	return &BoltStateStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *BoltStateStore) Close() error {
+	return s.db.Close()
+}
+
+// Load returns the last persisted sequence per ticker (empty if none)
+func (s *BoltStateStore) Load(ctx context.Context) (map[string]uint64, error) {
+	sequences := make(map[string]uint64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSequences).ForEach(func(k, v []byte) error {
+			sequences[string(k)] = binary.BigEndian.Uint64(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sequences: %w", err)
+	}
+	return sequences, nil
+}
+
+// Save persists the last assigned sequence per ticker
+func (s *BoltStateStore) Save(ctx context.Context, sequences map[string]uint64) error {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return putUint64s(tx.Bucket(bucketSequences), sequences)
+	}); err != nil {
+		return fmt.Errorf("failed to save sequences: %w", err)
+	}
+	return nil
+}
+
+// LastRecorded returns the timestamp of the last flushed tick per ticker (empty if none)
+func (s *BoltStateStore) LastRecorded(ctx context.Context) (map[string]time.Time, error) {
+	last := make(map[string]time.Time)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketLastRecorded).ForEach(func(k, v []byte) error {
+			last[string(k)] = time.Unix(0, int64(binary.BigEndian.Uint64(v))).UTC()
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last recorded ticks: %w", err)
+	}
+	return last, nil
+}
+
+// SaveLastRecorded persists the timestamp of the last flushed tick per ticker
+func (s *BoltStateStore) SaveLastRecorded(ctx context.Context, last map[string]time.Time) error {
+	values := make(map[string]uint64, len(last))
+	for ticker, timestamp := range last {
+		values[ticker] = uint64(timestamp.UnixNano())
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return putUint64s(tx.Bucket(bucketLastRecorded), values)
+	}); err != nil {
+		return fmt.Errorf("failed to save last recorded ticks: %w", err)
+	}
+	return nil
+}
+
+// AddGap records a period without recorded ticks, keyed by its start
+func (s *BoltStateStore) AddGap(ctx context.Context, gap domain.RecordingGap) error {
+	value, err := json.Marshal(gap)
+	if err != nil {
+		return fmt.Errorf("failed to encode gap: %w", err)
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketGaps).Put(binary.BigEndian.AppendUint64(nil, uint64(gap.Start.UnixNano())), value)
+	}); err != nil {
+		return fmt.Errorf("failed to save gap: %w", err)
+	}
+	return nil
+}
+
+// Gaps returns the recorded gaps starting in [from, to), oldest first
+func (s *BoltStateStore) Gaps(ctx context.Context, from, to time.Time) ([]domain.RecordingGap, error) {
+	var gaps []domain.RecordingGap
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketGaps).Cursor()
+		end := uint64(to.UnixNano())
+		for k, v := cursor.Seek(binary.BigEndian.AppendUint64(nil, uint64(from.UnixNano()))); k != nil && binary.BigEndian.Uint64(k) < end; k, v = cursor.Next() {
+			var gap domain.RecordingGap
+			if err := json.Unmarshal(v, &gap); err != nil {
+				return fmt.Errorf("invalid gap %x: %w", k, err)
+			}
+			gaps = append(gaps, gap)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gaps: %w", err)
+	}
+	return gaps, nil
+}
+
+// BackedUp returns the files copied to target (empty if none)
+func (s *BoltStateStore) BackedUp(ctx context.Context, target string) (map[string]domain.BackedUpFile, error) {
+	files := make(map[string]domain.BackedUpFile)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(bucketBackups).Get([]byte(target)); value != nil {
+			return json.Unmarshal(value, &files)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup progress of %s: %w", target, err)
+	}
+	return files, nil
+}
+
+// SaveBackedUp replaces the files copied to target
+func (s *BoltStateStore) SaveBackedUp(ctx context.Context, target string, files map[string]domain.BackedUpFile) error {
+	value, err := json.Marshal(files)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup progress: %w", err)
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketBackups).Put([]byte(target), value)
+	}); err != nil {
+		return fmt.Errorf("failed to save backup progress of %s: %w", target, err)
+	}
+	return nil
+}
+
+// putUint64s writes big-endian values by key
+func putUint64s(bucket *bolt.Bucket, values map[string]uint64) error {
+	for key, value := range values {
+		if err := bucket.Put([]byte(key), binary.BigEndian.AppendUint64(nil, value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
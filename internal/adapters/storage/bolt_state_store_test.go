@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestBoltStateStore_ImportsLegacySequencesOnce(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	legacy := filepath.Join(dir, "sequences.json")
+	if err := NewJSONSequenceStore(legacy).Save(ctx, map[string]uint64{"EURUSD": 1042}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "state", "collector.db")
+	store, err := OpenBoltStateStore(path, legacy)
+	if err != nil {
+		t.Fatalf("OpenBoltStateStore failed: %v", err)
+	}
+	sequences, err := store.Load(ctx)
+	if err != nil || sequences["EURUSD"] != 1042 {
+		t.Fatalf("Expected imported sequence 1042, got %v, %v", sequences, err)
+	}
+	if err := store.Save(ctx, map[string]uint64{"EURUSD": 2000, "USDJPY": 7}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// bbolt locks the file against a second collector
+	if _, err := OpenBoltStateStore(path, legacy); !errors.Is(err, ErrStateStoreInUse) {
+		t.Errorf("Expected an error opening a database in use, got %v", err)
+	}
+	store.Close()
+
+	// The database, not the older legacy file, is the state from now on
+	store, err = OpenBoltStateStore(path, legacy)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer store.Close()
+	sequences, err = store.Load(ctx)
+	if err != nil || sequences["EURUSD"] != 2000 || sequences["USDJPY"] != 7 {
+		t.Errorf("Expected saved sequences after reopening, got %v, %v", sequences, err)
+	}
+}
+
+func TestBoltStateStore_LastRecordedAndGaps(t *testing.T) {
+	store, err := OpenBoltStateStore(filepath.Join(t.TempDir(), "collector.db"), "")
+	if err != nil {
+		t.Fatalf("OpenBoltStateStore failed: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	last := map[string]time.Time{"EURUSD": time.Date(2025, 11, 18, 14, 0, 0, 123456789, time.UTC)}
+	if err := store.SaveLastRecorded(ctx, last); err != nil {
+		t.Fatalf("SaveLastRecorded failed: %v", err)
+	}
+	got, err := store.LastRecorded(ctx)
+	if err != nil || !got["EURUSD"].Equal(last["EURUSD"]) {
+		t.Errorf("Expected %v, got %v, %v", last, got, err)
+	}
+
+	day := time.Date(2025, 11, 18, 0, 0, 0, 0, time.UTC)
+	gaps := []domain.RecordingGap{
+		{Start: day.Add(20 * time.Hour), End: day.Add(21 * time.Hour), Reason: domain.GapDowntime},
+		{Start: day.Add(2 * time.Hour), End: day.Add(2*time.Hour + 5*time.Minute), Reason: domain.GapNoData},
+		{Start: day.Add(-time.Hour), End: day, Reason: domain.GapDowntime},
+	}
+	for _, gap := range gaps {
+		if err := store.AddGap(ctx, gap); err != nil {
+			t.Fatalf("AddGap failed: %v", err)
+		}
+	}
+
+	found, err := store.Gaps(ctx, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Gaps failed: %v", err)
+	}
+	if len(found) != 2 || found[0].Reason != domain.GapNoData || found[1].Duration() != time.Hour {
+		t.Errorf("Expected the day's two gaps oldest first, got %+v", found)
+	}
+}
+
+func TestBoltStateStore_BackupProgress(t *testing.T) {
+	store, err := OpenBoltStateStore(filepath.Join(t.TempDir(), "collector.db"), "")
+	if err != nil {
+		t.Fatalf("OpenBoltStateStore failed: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	if files, err := store.BackedUp(ctx, "sftp://nas/fx"); err != nil || len(files) != 0 {
+		t.Fatalf("Expected no progress yet, got %v, %v", files, err)
+	}
+	files := map[string]domain.BackedUpFile{
+		"20251118/EURUSD_14.csv": {Size: 4096, ModTime: time.Date(2025, 11, 18, 15, 0, 1, 0, time.UTC)},
+	}
+	if err := store.SaveBackedUp(ctx, "sftp://nas/fx", files); err != nil {
+		t.Fatalf("SaveBackedUp failed: %v", err)
+	}
+	got, err := store.BackedUp(ctx, "sftp://nas/fx")
+	if file := got["20251118/EURUSD_14.csv"]; err != nil || file.Size != 4096 || !file.ModTime.Equal(files["20251118/EURUSD_14.csv"].ModTime) {
+		t.Errorf("Expected %v, got %v, %v", files, got, err)
+	}
+	if other, _ := store.BackedUp(ctx, "s3://bucket/fx"); len(other) != 0 {
+		t.Errorf("Expected the progress to be kept per target, got %v", other)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// minBufferSize is the smallest write buffer; csv.Writer keeps a 4 KB buffer of its own below that
+	minBufferSize = 4096
+	// maxBufferSize caps the buffer of the busiest instruments
+	maxBufferSize = 1 << 20
+)
+
+// BufferSizer sizes each instrument's write buffer to hold what it writes between two flushes
+// Busy instruments get buffers large enough that rows only reach the file on Flush; quiet ones
+// keep the minimum. Rates are measured while a file is open and smoothed over the following files,
+// so one quiet hour only halves a busy instrument's buffer
+// Not safe for concurrent use; recorders call it under their own lock
+type BufferSizer struct {
+	window time.Duration // Flush interval the buffers should cover
+	fixed  int           // Size of every buffer when not adapting
+
+	rates map[string]*byteRate
+}
+
+// byteRate tracks the bytes an instrument wrote since its buffer was last sized
+type byteRate struct {
+	bytes       int
+	first, last time.Time // First and last write of the period, so idle stretches don't count
+	perSecond   float64   // Smoothed rate, 0 until measured
+}
+
+// NewBufferSizer creates a sizer adapting buffers to the data written per window
+func NewBufferSizer(window time.Duration) *BufferSizer {
+	return &BufferSizer{window: window, rates: make(map[string]*byteRate)}
+}
+
+// ParseBufferSizer parses a buffer setting: "auto" adapts to the tick rate per window, a byte count
+// gives every instrument that buffer size
+func ParseBufferSizer(s string, window time.Duration) (*BufferSizer, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "auto" {
+		return NewBufferSizer(window), nil
+	}
+	size, err := strconv.Atoi(s)
+	if err != nil || size < minBufferSize || size > maxBufferSize {
+		return nil, fmt.Errorf("invalid buffer size %q (auto, or %d to %d bytes)", s, minBufferSize, maxBufferSize)
+	}
+	return &BufferSizer{fixed: size}, nil
+}
+
+// String returns the setting in its config form
+func (s *BufferSizer) String() string {
+	if s.fixed > 0 {
+		return strconv.Itoa(s.fixed)
+	}
+	return "auto"
+}
+
+// Written records n bytes written for ticker at now
+func (s *BufferSizer) Written(ticker string, n int, now time.Time) {
+	if s.fixed > 0 {
+		return
+	}
+	r := s.rates[ticker]
+	if r == nil {
+		r = &byteRate{}
+		s.rates[ticker] = r
+	}
+	if r.first.IsZero() {
+		r.first = now
+	}
+	r.bytes += n
+	r.last = now
+}
+
+// Size returns the buffer size for ticker's next file and starts a new measuring period
+func (s *BufferSizer) Size(ticker string) int {
+	if s.fixed > 0 {
+		return s.fixed
+	}
+	r := s.rates[ticker]
+	if r == nil {
+		return minBufferSize
+	}
+
+	if elapsed := r.last.Sub(r.first); elapsed >= time.Second {
+		rate := float64(r.bytes) / elapsed.Seconds()
+		if r.perSecond == 0 {
+			r.perSecond = rate
+		} else {
+			r.perSecond = (r.perSecond + rate) / 2
+		}
+	}
+	r.bytes, r.first, r.last = 0, time.Time{}, time.Time{}
+
+	// Round up to whole 4 KB pages
+	size := int(r.perSecond * s.window.Seconds())
+	size = (size + minBufferSize - 1) / minBufferSize * minBufferSize
+	return min(max(size, minBufferSize), maxBufferSize)
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferSizer(t *testing.T) {
+	sizer := NewBufferSizer(30 * time.Second)
+	if got := sizer.Size("EURUSD"); got != minBufferSize {
+		t.Errorf("Expected the minimum before any writes, got %d", got)
+	}
+
+	// EURUSD: 10 rows of 100 bytes per second; 30s need 30000 bytes, rounded up to 32768
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	for i := range 600 {
+		sizer.Written("EURUSD", 100, start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+	// USDTRY: one row a minute
+	for i := range 60 {
+		sizer.Written("USDTRY", 100, start.Add(time.Duration(i)*time.Minute))
+	}
+	if got := sizer.Size("EURUSD"); got != 32768 {
+		t.Errorf("Expected 32768 for EURUSD, got %d", got)
+	}
+	if got := sizer.Size("USDTRY"); got != minBufferSize {
+		t.Errorf("Expected the minimum for USDTRY, got %d", got)
+	}
+
+	// A period with a single write keeps the measured rate
+	sizer.Written("EURUSD", 100, start.Add(2*time.Hour))
+	if got := sizer.Size("EURUSD"); got != 32768 {
+		t.Errorf("Expected 32768 to be kept, got %d", got)
+	}
+}
+
+func TestParseBufferSizer(t *testing.T) {
+	fixed, err := ParseBufferSizer("65536", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed.Written("EURUSD", 1<<20, time.Now())
+	if got := fixed.Size("EURUSD"); got != 65536 || fixed.String() != "65536" {
+		t.Errorf("Expected a fixed 65536, got %d (%s)", got, fixed)
+	}
+	if auto, err := ParseBufferSizer("", time.Minute); err != nil || auto.String() != "auto" {
+		t.Errorf("Expected auto by default, got %v, %v", auto, err)
+	}
+	for _, invalid := range []string{"1024", "big", "4194304"} {
+		if _, err := ParseBufferSizer(invalid, time.Minute); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
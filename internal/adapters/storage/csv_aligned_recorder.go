@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// CSVAlignedRecorder implements AlignedQuoteWriter using one wide CSV file per hour
+// File format: data/aligned/YYYYMMDD/aligned_HH.csv (aligned_HH-N.csv when the instrument set changes)
+// Columns: timestamp, then TICKER_bid,TICKER_ask,TICKER_age_ms for every instrument
+// Fields of an instrument without a quote yet are empty
+type CSVAlignedRecorder struct {
+	baseDir string
+	mu      sync.Mutex
+
+	key    string // YYYYMMDD_HH of the open file
+	header []string
+	file   *os.File
+	buffer *bufio.Writer
+	writer *csv.Writer
+}
+
+// NewCSVAlignedRecorder creates a new CSV-based aligned quote recorder
+func NewCSVAlignedRecorder(baseDir string) *CSVAlignedRecorder {
+	return &CSVAlignedRecorder{baseDir: baseDir}
+}
+
+// alignedColumns returns the header for the instruments of row
+func alignedColumns(row domain.AlignedQuotes) []string {
+	header := make([]string, 0, 1+3*len(row.Quotes))
+	header = append(header, "timestamp")
+	for _, q := range row.Quotes {
+		header = append(header, q.Ticker+"_bid", q.Ticker+"_ask", q.Ticker+"_age_ms")
+	}
+	return header
+}
+
+// RecordAlignedQuotes writes one row and flushes it to the file
+func (r *CSVAlignedRecorder) RecordAlignedQuotes(ctx context.Context, row domain.AlignedQuotes) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openFor(row); err != nil {
+		return err
+	}
+
+	record := make([]string, 0, 1+3*len(row.Quotes))
+	record = append(record, row.Timestamp.UTC().Format(time.RFC3339Nano))
+	for _, q := range row.Quotes {
+		if !q.Valid {
+			record = append(record, "", "", "")
+			continue
+		}
+		record = append(record,
+			strconv.FormatFloat(roundPrice(q.Bid, q.Decimals), 'f', q.Decimals, 64),
+			strconv.FormatFloat(roundPrice(q.Ask, q.Decimals), 'f', q.Decimals, 64),
+			strconv.FormatInt(q.Age.Milliseconds(), 10),
+		)
+	}
+
+	if err := r.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write aligned quotes: %w", err)
+	}
+	if err := r.flush(); err != nil {
+		return fmt.Errorf("failed to flush aligned quotes: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the open file
+func (r *CSVAlignedRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.close()
+}
+
+// openFor makes sure the hourly file for row is open with matching columns
+func (r *CSVAlignedRecorder) openFor(row domain.AlignedQuotes) error {
+	timestamp := row.Timestamp.UTC()
+	dateStr := timestamp.Format("20060102")
+	hourStr := timestamp.Format("15")
+	key := dateStr + "_" + hourStr
+	header := alignedColumns(row)
+
+	if r.file != nil && r.key == key && slices.Equal(r.header, header) {
+		return nil
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf("failed to close aligned quote file: %w", err)
+	}
+
+	dirPath := filepath.Join(r.baseDir, dateStr)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dirPath, err)
+	}
+
+	file, writeHeader, err := openNumberedCSV(dirPath, "aligned_"+hourStr, AlignedSchema(header), false)
+	if err != nil {
+		return err
+	}
+
+	buffer := bufio.NewWriter(file)
+	writer := csv.NewWriter(buffer)
+	if writeHeader {
+		if err := writer.Write(header); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	r.key, r.header = key, header
+	r.file, r.buffer, r.writer = file, buffer, writer
+	return nil
+}
+
+// flush writes buffered rows to the file
+func (r *CSVAlignedRecorder) flush() error {
+	r.writer.Flush()
+	if err := r.writer.Error(); err != nil {
+		return err
+	}
+	return r.buffer.Flush()
+}
+
+// close flushes and closes the open file, if any
+func (r *CSVAlignedRecorder) close() error {
+	if r.file == nil {
+		return nil
+	}
+	file := r.file
+	r.file = nil
+
+	if err := r.flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCSVAlignedRecorder_Rows(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVAlignedRecorder(tmpDir)
+
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	row := func(at time.Time, quotes ...domain.AlignedQuote) domain.AlignedQuotes {
+		return domain.AlignedQuotes{Timestamp: at, Quotes: quotes}
+	}
+	eurusd := domain.AlignedQuote{Ticker: "EURUSD", Bid: 1.1, Ask: 1.10002, Decimals: 5, Age: 140 * time.Millisecond, Valid: true}
+	usdjpy := domain.AlignedQuote{Ticker: "USDJPY"}
+
+	ctx := context.Background()
+	if err := recorder.RecordAlignedQuotes(ctx, row(start, eurusd, usdjpy)); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	usdjpy = domain.AlignedQuote{Ticker: "USDJPY", Bid: 155.123, Ask: 155.137, Decimals: 3, Valid: true}
+	if err := recorder.RecordAlignedQuotes(ctx, row(start.Add(250*time.Millisecond), eurusd, usdjpy)); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+
+	// A different instrument set can't share the header
+	if err := recorder.RecordAlignedQuotes(ctx, row(start.Add(500*time.Millisecond), eurusd)); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "20251118", "aligned_12.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	want := []string{
+		"timestamp,EURUSD_bid,EURUSD_ask,EURUSD_age_ms,USDJPY_bid,USDJPY_ask,USDJPY_age_ms",
+		"2025-11-18T12:00:00Z,1.10000,1.10002,140,,,",
+		"2025-11-18T12:00:00.25Z,1.10000,1.10002,140,155.123,155.137,0",
+	}
+	if got := strings.Split(strings.TrimSpace(string(content)), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+
+	content, err = os.ReadFile(filepath.Join(tmpDir, "20251118", "aligned_12-2.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read numbered file: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "timestamp,EURUSD_bid,EURUSD_ask,EURUSD_age_ms\n") {
+		t.Errorf("Unexpected numbered file:\n%s", content)
+	}
+}
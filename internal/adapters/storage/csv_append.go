@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// errCSVSchemaMismatch is returned by openCSVAppend when an existing file has a different header
+var errCSVSchemaMismatch = errors.New("existing file has a different header")
+
+// csvTailChunk is how far back openCSVAppend looks for the last complete line
+const csvTailChunk = 64 * 1024
+
+// PartialSuffix marks an hourly file still being written (TICKER_HH.csv.partial); it is renamed
+// to TICKER_HH.csv once the hour is complete
+const PartialSuffix = ".partial"
+
+// openCSVAppend opens path for appending rows with the given header
+// A new or empty file needs the header (writeHeader is true). An existing file is only reused when
+// its header matches; a torn last line left by a crash or power loss is truncated first, so the
+// next row doesn't get glued onto it
+func openCSVAppend(path string, header []string) (file *os.File, writeHeader bool, err error) {
+	file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+
+	writeHeader, err = prepareCSVAppend(file, header)
+	if err != nil {
+		file.Close()
+		return nil, false, err
+	}
+	return file, writeHeader, nil
+}
+
+// openNumberedCSV opens base.csv in dirPath for appending rows with the columns of schema
+// If it was written with different columns (an older version), rows go to base-2.csv, base-3.csv, ...
+// instead, so every file keeps a single consistent header. New files are entered in the schema sidecar
+// With partial set the file is opened as base.csv.partial. A finalized file is never written to
+// again: rows arriving late for its hour go to the next numbered file, so a file downstream jobs
+// have seen under its final name doesn't change
+func openNumberedCSV(dirPath, base string, schema Schema, partial bool) (*os.File, bool, error) {
+	for n := 1; ; n++ {
+		name := base + ".csv"
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d.csv", base, n)
+		}
+
+		path := filepath.Join(dirPath, name)
+		if partial {
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+			path += PartialSuffix
+		}
+		file, writeHeader, err := openCSVAppend(path, schema.Columns)
+		if errors.Is(err, errCSVSchemaMismatch) {
+			log.Printf("CSV: %v - trying next file", err)
+			continue
+		}
+		if err == nil && writeHeader {
+			if err = writeSchema(dirPath, name, schema); err != nil {
+				file.Close()
+				file = nil
+			}
+		}
+		return file, writeHeader, err
+	}
+}
+
+// finalizePartial renames a partial file to its final name
+func finalizePartial(partialPath string) error {
+	path := strings.TrimSuffix(partialPath, PartialSuffix)
+	if err := os.Rename(partialPath, path); err != nil {
+		return fmt.Errorf("failed to finalize %s: %w", partialPath, err)
+	}
+	return nil
+}
+
+// prepareCSVAppend checks the header and trims a torn last line of an open file
+func prepareCSVAppend(file *os.File, header []string) (bool, error) {
+	size, err := completeLinesSize(file)
+	if err != nil {
+		return false, err
+	}
+	if size == 0 {
+		// New file, empty file, or only a partial header line
+		if err := file.Truncate(0); err != nil {
+			return false, fmt.Errorf("failed to truncate %s: %w", file.Name(), err)
+		}
+		return true, nil
+	}
+
+	existing, err := csv.NewReader(io.NewSectionReader(file, 0, size)).Read()
+	if err != nil {
+		return false, fmt.Errorf("failed to read header of %s: %w", file.Name(), err)
+	}
+	if !slices.Equal(existing, header) {
+		return false, fmt.Errorf("%s: %w", file.Name(), errCSVSchemaMismatch)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", file.Name(), err)
+	}
+	if info.Size() != size {
+		log.Printf("CSV: ⚠️ Truncating torn last line of %s (%d bytes)", file.Name(), info.Size()-size)
+		if err := file.Truncate(size); err != nil {
+			return false, fmt.Errorf("failed to truncate %s: %w", file.Name(), err)
+		}
+	}
+	return false, nil
+}
+
+// completeLinesSize returns the length of the file up to and including its last newline
+func completeLinesSize(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", file.Name(), err)
+	}
+
+	end := info.Size()
+	buf := make([]byte, csvTailChunk)
+	for end > 0 {
+		start := max(end-csvTailChunk, 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", file.Name(), err)
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// errStopReading ends a file early once ticks are past the requested range
+var errStopReading = errors.New("stop reading")
+
+// CSVArchive implements SpreadReader over the hourly CSV files written by CSVSpreadRecorder
+// Only flushed rows are visible, so the last SPREAD_FLUSH_INTERVAL may be missing
+type CSVArchive struct {
+	baseDir string
+}
+
+// NewCSVArchive creates a reader for the spread archive under baseDir
+func NewCSVArchive(baseDir string) *CSVArchive {
+	return &CSVArchive{baseDir: baseDir}
+}
+
+// ReadSpreads streams the ticks of ticker with from <= timestamp < to to fn, oldest first
+func (a *CSVArchive) ReadSpreads(ctx context.Context, ticker string, from, to time.Time, fn func(*domain.PriceData) error) error {
+	from, to = from.UTC(), to.UTC()
+	files, err := ListSpreadFiles(a.baseDir, ArchiveFilter{
+		From:    from.Truncate(24 * time.Hour),
+		To:      to.Add(-time.Nanosecond).Truncate(24 * time.Hour),
+		Tickers: []string{ticker},
+	})
+	if err != nil {
+		return err
+	}
+	sortSpreadFiles(files)
+
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !hourOverlaps(path, from, to) {
+			continue
+		}
+
+		err := ReadSpreadFile(path, func(data *domain.PriceData) error {
+			if data.Timestamp.Before(from) {
+				return nil
+			}
+			if !data.Timestamp.Before(to) {
+				return errStopReading
+			}
+			return fn(data)
+		})
+		if err != nil && !errors.Is(err, errStopReading) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortSpreadFiles orders hourly files by day and hour, a numbered file after the one it continues
+// (name order would put TICKER_12-2.csv before TICKER_12.csv)
+func sortSpreadFiles(files []string) {
+	slices.SortStableFunc(files, func(a, b string) int {
+		baseA, numberA := spreadFileNumber(a)
+		baseB, numberB := spreadFileNumber(b)
+		return cmp.Or(strings.Compare(baseA, baseB), cmp.Compare(numberA, numberB))
+	})
+}
+
+// spreadFileNumber splits a file path into its path without the -N.csv suffix and N (1 if absent)
+func spreadFileNumber(path string) (string, int) {
+	base := strings.TrimSuffix(strings.TrimSuffix(path, PartialSuffix), ".csv")
+	if i := strings.LastIndex(base, "-"); i > 0 {
+		if n, err := strconv.Atoi(base[i+1:]); err == nil {
+			return base[:i], n
+		}
+	}
+	return base, 1
+}
+
+// hourOverlaps reports whether the hour a file covers (from its day directory and _HH name) overlaps [from, to)
+// Files whose hour can't be parsed are read
+func hourOverlaps(path string, from, to time.Time) bool {
+	start, ok := spreadFileHour(path)
+	if !ok {
+		return true
+	}
+	return start.Before(to) && start.Add(time.Hour).After(from)
+}
+
+// spreadFileHour returns the start of the hour a file covers, from its day directory and _HH name
+func spreadFileHour(path string) (time.Time, bool) {
+	base, _ := spreadFileNumber(path)
+	day := filepath.Base(filepath.Dir(path))
+	i := strings.LastIndex(base, "_")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	start, err := time.Parse("20060102 15", day+" "+base[i+1:])
+	return start, err == nil
+}
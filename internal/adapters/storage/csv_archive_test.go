@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCSVArchive_ReadSpreads(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2025, 11, 18, 23, 50, 0, 0, time.UTC)
+
+	recordRun(t, tmpDir, base, base.Add(5*time.Minute), base.Add(75*time.Minute), base.Add(3*time.Hour))
+
+	// A numbered file continues the hour after a restart with a different schema
+	other := t.TempDir()
+	recordRun(t, other, base.Add(8*time.Minute))
+	content, err := os.ReadFile(filepath.Join(other, "20251118", "EURUSD_23.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "20251118", "EURUSD_23-2.csv"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []time.Time
+	err = NewCSVArchive(tmpDir).ReadSpreads(context.Background(), "EURUSD", base, base.Add(2*time.Hour), func(data *domain.PriceData) error {
+		got = append(got, data.Timestamp)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSpreads failed: %v", err)
+	}
+
+	want := []time.Time{base, base.Add(5 * time.Minute), base.Add(8 * time.Minute), base.Add(75 * time.Minute)}
+	if len(got) != len(want) {
+		t.Fatalf("Got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("Tick %d at %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// calendarColumns is the header of the calendar files
+var calendarColumns = []string{"time", "currency", "impact", "title", "window_start", "window_end", "tickers"}
+
+// CSVCalendarWriter implements CalendarWriter with one sidecar file per day
+// File format: data/calendar/calendar_YYYYMMDD.csv
+// Columns: time,currency,impact,title,window_start,window_end,tickers (tickers separated by ';')
+// Kept outside the spread directory so upcoming days never show up as (purgeable) day directories
+type CSVCalendarWriter struct {
+	dir string
+}
+
+// NewCSVCalendarWriter creates a calendar writer storing files in dir
+func NewCSVCalendarWriter(dir string) *CSVCalendarWriter {
+	return &CSVCalendarWriter{dir: dir}
+}
+
+// WriteCalendarDay replaces the annotations stored for day (UTC)
+// The file is replaced atomically so readers never see a partial calendar
+func (w *CSVCalendarWriter) WriteCalendarDay(ctx context.Context, day time.Time, annotations []domain.CalendarAnnotation) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	// Errors surface through writer.Error() after Flush
+	writer.Write(calendarColumns)
+	for _, a := range annotations {
+		writer.Write([]string{
+			a.Event.Time.UTC().Format(time.RFC3339),
+			a.Event.Currency,
+			a.Event.Impact.String(),
+			a.Event.Title,
+			a.WindowStart.UTC().Format(time.RFC3339),
+			a.WindowEnd.UTC().Format(time.RFC3339),
+			strings.Join(a.Tickers, ";"),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to encode calendar: %w", err)
+	}
+
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", w.dir, err)
+	}
+
+	path := filepath.Join(w.dir, fmt.Sprintf("calendar_%s.csv", day.UTC().Format("20060102")))
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write calendar file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace calendar file: %w", err)
+	}
+	return writeSchema(w.dir, filepath.Base(path), CalendarSchema)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCSVCalendarWriter_WriteCalendarDay(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewCSVCalendarWriter(dir)
+
+	at := time.Date(2025, 11, 21, 13, 30, 0, 0, time.UTC)
+	annotation := domain.CalendarAnnotation{
+		Event:       domain.CalendarEvent{Time: at, Currency: "USD", Impact: domain.ImpactHigh, Title: "Non-Farm Employment Change"},
+		WindowStart: at.Add(-15 * time.Minute),
+		WindowEnd:   at.Add(15 * time.Minute),
+		Tickers:     []string{"EURUSD", "USDJPY"},
+	}
+
+	ctx := context.Background()
+	day := at.Truncate(24 * time.Hour)
+	// Second write replaces the first
+	for range 2 {
+		if err := writer.WriteCalendarDay(ctx, day, []domain.CalendarAnnotation{annotation}); err != nil {
+			t.Fatalf("WriteCalendarDay failed: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "calendar_20251121.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read calendar file: %v", err)
+	}
+
+	expected := "time,currency,impact,title,window_start,window_end,tickers\n" +
+		"2025-11-21T13:30:00Z,USD,high,Non-Farm Employment Change,2025-11-21T13:15:00Z,2025-11-21T13:45:00Z,EURUSD;USDJPY\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+}
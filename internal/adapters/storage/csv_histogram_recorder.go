@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// CSVHistogramRecorder implements HistogramWriter using one CSV file per day
+// File format: data/histograms/YYYYMMDD/spread_histograms.csv (spread_histograms-N.csv when the buckets change)
+// Columns: hour,ticker,ticks,min_pips,avg_pips,max_pips, then le_<bound> per bucket and gt_<last bound>
+// Each row is one instrument's hour; bucket counts are per bucket, not cumulative
+type CSVHistogramRecorder struct {
+	baseDir string
+	mu      sync.Mutex
+
+	day    string // YYYYMMDD of the open file
+	header []string
+	file   *os.File
+	buffer *bufio.Writer
+	writer *csv.Writer
+}
+
+// NewCSVHistogramRecorder creates a new CSV-based spread histogram recorder
+func NewCSVHistogramRecorder(baseDir string) *CSVHistogramRecorder {
+	return &CSVHistogramRecorder{baseDir: baseDir}
+}
+
+// HistogramSchema is the schema of spread histogram files, whose columns follow the buckets
+func HistogramSchema(columns []string) Schema {
+	return Schema{Dataset: "histograms", Version: 1, Columns: columns}
+}
+
+// histogramColumns returns the header for histograms with the given bounds
+func histogramColumns(bounds []float64) []string {
+	header := []string{"hour", "ticker", "ticks", "min_pips", "avg_pips", "max_pips"}
+	for _, bound := range bounds {
+		header = append(header, "le_"+strconv.FormatFloat(bound, 'f', -1, 64))
+	}
+	if len(bounds) > 0 {
+		header = append(header, "gt_"+strconv.FormatFloat(bounds[len(bounds)-1], 'f', -1, 64))
+	}
+	return header
+}
+
+// RecordHistograms writes one row per histogram and flushes them to the file
+func (r *CSVHistogramRecorder) RecordHistograms(ctx context.Context, histograms []*domain.SpreadHistogram) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pips := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+	}
+	for _, h := range histograms {
+		if err := r.openFor(h); err != nil {
+			return err
+		}
+		record := []string{
+			h.Hour.UTC().Format(time.RFC3339), h.Ticker, strconv.Itoa(h.Ticks),
+			pips(h.MinPips), pips(h.AvgPips()), pips(h.MaxPips),
+		}
+		for _, count := range h.Counts {
+			record = append(record, strconv.Itoa(count))
+		}
+		if err := r.writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write spread histogram: %w", err)
+		}
+	}
+
+	if r.file == nil {
+		return nil
+	}
+	if err := r.flush(); err != nil {
+		return fmt.Errorf("failed to flush spread histograms: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the open file
+func (r *CSVHistogramRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.close()
+}
+
+// openFor makes sure the daily file for h is open with matching columns
+func (r *CSVHistogramRecorder) openFor(h *domain.SpreadHistogram) error {
+	day := h.Hour.UTC().Format("20060102")
+	header := histogramColumns(h.Bounds)
+	if r.file != nil && r.day == day && slices.Equal(r.header, header) {
+		return nil
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf("failed to close spread histogram file: %w", err)
+	}
+
+	dirPath := filepath.Join(r.baseDir, day)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dirPath, err)
+	}
+	file, writeHeader, err := openNumberedCSV(dirPath, "spread_histograms", HistogramSchema(header), false)
+	if err != nil {
+		return err
+	}
+
+	buffer := bufio.NewWriter(file)
+	writer := csv.NewWriter(buffer)
+	if writeHeader {
+		if err := writer.Write(header); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	r.day, r.header = day, header
+	r.file, r.buffer, r.writer = file, buffer, writer
+	return nil
+}
+
+// flush writes buffered rows to the file
+func (r *CSVHistogramRecorder) flush() error {
+	r.writer.Flush()
+	if err := r.writer.Error(); err != nil {
+		return err
+	}
+	return r.buffer.Flush()
+}
+
+// close flushes and closes the open file, if any
+func (r *CSVHistogramRecorder) close() error {
+	if r.file == nil {
+		return nil
+	}
+	file := r.file
+	r.file = nil
+
+	if err := r.flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
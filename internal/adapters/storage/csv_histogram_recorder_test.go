@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCSVHistogramRecorder_Rows(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVHistogramRecorder(tmpDir)
+
+	hour := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	eurusd := domain.NewSpreadHistogram(hour, "EURUSD", []float64{0.5, 1})
+	for _, pips := range []float64{0.2, 0.4, 0.9, 1.2} {
+		eurusd.Add(pips)
+	}
+	usdjpy := domain.NewSpreadHistogram(hour, "USDJPY", []float64{0.5, 1})
+	usdjpy.Add(1.5)
+
+	ctx := context.Background()
+	if err := recorder.RecordHistograms(ctx, []*domain.SpreadHistogram{eurusd, usdjpy}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	// Other buckets can't share the header
+	other := domain.NewSpreadHistogram(hour.Add(time.Hour), "EURUSD", []float64{1})
+	other.Add(0.5)
+	if err := recorder.RecordHistograms(ctx, []*domain.SpreadHistogram{other}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "20251118", "spread_histograms.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	want := []string{
+		"hour,ticker,ticks,min_pips,avg_pips,max_pips,le_0.5,le_1,gt_1",
+		"2025-11-18T12:00:00Z,EURUSD,4,0.2,0.675,1.2,2,1,1",
+		"2025-11-18T12:00:00Z,USDJPY,1,1.5,1.5,1.5,0,0,1",
+	}
+	if got := strings.TrimSpace(string(content)); got != strings.Join(want, "\n") {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+
+	content, err = os.ReadFile(filepath.Join(tmpDir, "20251118", "spread_histograms-2.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read numbered file: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "hour,ticker,ticks,min_pips,avg_pips,max_pips,le_1,gt_1\n") {
+		t.Errorf("Unexpected numbered file:\n%s", content)
+	}
+}
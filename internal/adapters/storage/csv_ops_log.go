@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// opsLogColumns is the header of the ops log files
+var opsLogColumns = []string{"timestamp", "event", "severity", "ticker", "message", "fields"}
+
+// CSVOpsLog implements EventRecorder using daily CSV files
+// File format: <dir>/ops_YYYYMMDD.csv
+// Columns: timestamp,event,severity,ticker,message,fields
+// Fields are encoded as sorted key=value pairs separated by ';'
+type CSVOpsLog struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewCSVOpsLog creates an ops log writing to dir
+func NewCSVOpsLog(dir string) *CSVOpsLog {
+	return &CSVOpsLog{dir: dir}
+}
+
+// RecordEvent appends a single event to the ops log
+// Files are opened per event: ops events are low volume and must survive crashes
+func (l *CSVOpsLog) RecordEvent(ctx context.Context, event domain.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", l.dir, err)
+	}
+
+	filePath := filepath.Join(l.dir, fmt.Sprintf("ops_%s.csv", event.Timestamp.UTC().Format("20060102")))
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+
+	writer := csv.NewWriter(file)
+	if !fileExists {
+		if err := writer.Write(opsLogColumns); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+		if err := writeSchema(l.dir, filepath.Base(filePath), OpsLogSchema); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	record := []string{
+		event.Timestamp.UTC().Format(time.RFC3339Nano),
+		string(event.Type),
+		string(event.Severity),
+		event.Ticker,
+		event.Message,
+		encodeFields(event.Fields),
+	}
+	if err := writer.Write(record); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to flush ops log: %w", err)
+	}
+
+	return file.Close()
+}
+
+// encodeFields renders event fields deterministically as k=v;k=v
+func encodeFields(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+fields[k])
+	}
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCSVOpsLog_RecordEvent(t *testing.T) {
+	tmpDir := t.TempDir()
+	opsLog := NewCSVOpsLog(tmpDir)
+	ctx := context.Background()
+
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	events := []domain.Event{
+		{Type: domain.EventDisconnected, Severity: domain.SeverityWarning, Timestamp: now, Message: "WebSocket disconnected"},
+		{Type: domain.EventReconnected, Severity: domain.SeverityInfo, Timestamp: now.Add(5 * time.Second), Message: "WebSocket reconnected"},
+		{
+			Type:      domain.EventHeartbeat,
+			Severity:  domain.SeverityInfo,
+			Timestamp: now.Add(time.Minute),
+			Message:   "Heartbeat",
+			Fields:    map[string]string{"ticks": "120", "connected": "true"},
+		},
+	}
+
+	for _, event := range events {
+		if err := opsLog.RecordEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to record event: %v", err)
+		}
+	}
+
+	file, err := os.Open(tmpDir + "/ops_20251118.csv")
+	if err != nil {
+		t.Fatalf("Ops log not created: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Ops log is not valid CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Expected header + 3 rows, got %d", len(rows))
+	}
+	if rows[0][0] != "timestamp" {
+		t.Errorf("Expected header row, got %v", rows[0])
+	}
+	if rows[3][1] != string(domain.EventHeartbeat) || rows[3][5] != "connected=true;ticks=120" {
+		t.Errorf("Unexpected heartbeat row: %v", rows[3])
+	}
+}
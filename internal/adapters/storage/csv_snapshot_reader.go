@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// snapshotColumns is the column order written by CSVSnapshotRecorder; version 1 files end at ticks
+var snapshotColumns = []string{"timestamp", "uic", "ticker", "asset_type", "bid", "ask", "min_spread", "max_spread", "ticks", "recorded"}
+
+// ReadSnapshotFile streams the snapshots of one hourly CSV file to fn
+func ReadSnapshotFile(path string, fn func(*domain.Snapshot) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+	if schema, ok, err := ReadSchema(path); err != nil {
+		return err
+	} else if ok && schema.Version > SnapshotSchema.Version {
+		return fmt.Errorf("%s is %s, this reader knows up to v%d: %w", path, schema, SnapshotSchema.Version, ErrNewerSchema)
+	}
+	// Version 1 files didn't count recorded ticks, so their snapshots count every tick as recorded
+	v1 := strings.Join(header, ",") == strings.Join(snapshotColumns[:len(snapshotColumns)-1], ",")
+	if !v1 && strings.Join(header, ",") != strings.Join(snapshotColumns, ",") {
+		return fmt.Errorf("%s: unexpected snapshot header %v", path, header)
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		s, err := parseSnapshotRecord(record)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if v1 {
+			s.Recorded = s.Ticks
+		} else if s.Recorded, err = strconv.Atoi(record[9]); err != nil {
+			return fmt.Errorf("%s:%d: invalid recorded: %w", path, line, err)
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+}
+
+// parseSnapshotRecord converts one CSV row into a snapshot
+func parseSnapshotRecord(record []string) (*domain.Snapshot, error) {
+	timestamp, err := time.Parse(time.RFC3339Nano, record[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	uic, err := strconv.Atoi(record[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid uic: %w", err)
+	}
+	ticks, err := strconv.Atoi(record[8])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ticks: %w", err)
+	}
+
+	var prices [4]float64
+	for i := range prices {
+		if prices[i], err = strconv.ParseFloat(record[4+i], 64); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", snapshotColumns[4+i], err)
+		}
+	}
+
+	s := &domain.Snapshot{
+		Timestamp: timestamp,
+		Uic:       uic,
+		Ticker:    record[2],
+		AssetType: record[3],
+		Bid:       prices[0],
+		Ask:       prices[1],
+		MinSpread: prices[2],
+		MaxSpread: prices[3],
+		Ticks:     ticks,
+	}
+	if i := strings.IndexByte(record[4], '.'); i >= 0 {
+		s.Decimals = len(record[4]) - i - 1
+	}
+	return s, nil
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// snapshotFile is the open hourly file of one instrument
+type snapshotFile struct {
+	key    string // YYYYMMDD_HH
+	file   *os.File
+	buffer *bufio.Writer
+	writer *csv.Writer
+}
+
+// CSVSnapshotRecorder implements SnapshotWriter using CSV files
+// File format: data/snapshots/YYYYMMDD/TICKER_HH.csv (same layout as the spread files)
+// Columns: timestamp,uic,ticker,asset_type,bid,ask,min_spread,max_spread,ticks,recorded
+// Files are flushed after every call since snapshots arrive once per interval
+type CSVSnapshotRecorder struct {
+	baseDir string
+	files   map[string]*snapshotFile // Keyed by ticker
+	mu      sync.Mutex
+}
+
+// NewCSVSnapshotRecorder creates a new CSV-based snapshot recorder
+func NewCSVSnapshotRecorder(baseDir string) *CSVSnapshotRecorder {
+	return &CSVSnapshotRecorder{
+		baseDir: baseDir,
+		files:   make(map[string]*snapshotFile),
+	}
+}
+
+// RecordSnapshots saves the snapshots of one or more completed intervals
+func (r *CSVSnapshotRecorder) RecordSnapshots(ctx context.Context, snapshots []*domain.Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	touched := make(map[string]*snapshotFile)
+	for _, s := range snapshots {
+		f, err := r.getFile(s.Ticker, s.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		record := []string{
+			s.Timestamp.Format(time.RFC3339Nano),
+			strconv.Itoa(s.Uic),
+			s.Ticker,
+			s.AssetType,
+			strconv.FormatFloat(roundPrice(s.Bid, s.Decimals), 'f', s.Decimals, 64),
+			strconv.FormatFloat(roundPrice(s.Ask, s.Decimals), 'f', s.Decimals, 64),
+			strconv.FormatFloat(roundPrice(s.MinSpread, s.Decimals), 'f', s.Decimals, 64),
+			strconv.FormatFloat(roundPrice(s.MaxSpread, s.Decimals), 'f', s.Decimals, 64),
+			strconv.Itoa(s.Ticks),
+			strconv.Itoa(s.Recorded),
+		}
+		if err := f.writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write snapshot for %s: %w", s.Ticker, err)
+		}
+		touched[s.Ticker] = f
+	}
+
+	for ticker, f := range touched {
+		if err := f.flush(); err != nil {
+			return fmt.Errorf("failed to flush snapshots for %s: %w", ticker, err)
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes all open files
+func (r *CSVSnapshotRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var firstErr error
+	for ticker, f := range r.files {
+		if err := f.close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close snapshot file for %s: %w", ticker, err)
+		}
+	}
+	r.files = make(map[string]*snapshotFile)
+	return firstErr
+}
+
+// getFile returns the hourly file for ticker at timestamp, rotating the previous hour's file
+func (r *CSVSnapshotRecorder) getFile(ticker string, timestamp time.Time) (*snapshotFile, error) {
+	dateStr := timestamp.Format("20060102")
+	hourStr := timestamp.Format("15")
+	key := dateStr + "_" + hourStr
+
+	if f, ok := r.files[ticker]; ok {
+		if f.key == key {
+			return f, nil
+		}
+		if err := f.close(); err != nil {
+			log.Printf("Warning: Error closing old snapshot file for %s: %v", ticker, err)
+		}
+		delete(r.files, ticker)
+	}
+
+	dirPath := filepath.Join(r.baseDir, dateStr)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dirPath, err)
+	}
+
+	// A file of an older version is continued in TICKER_HH-2.csv
+	file, writeHeader, err := openNumberedCSV(dirPath, fmt.Sprintf("%s_%s", ticker, hourStr), SnapshotSchema, false)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bufio.NewWriter(file)
+	f := &snapshotFile{key: key, file: file, buffer: buffer, writer: csv.NewWriter(buffer)}
+	if writeHeader {
+		if err := f.writer.Write(snapshotColumns); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	r.files[ticker] = f
+	return f, nil
+}
+
+// flush writes buffered rows to the file
+func (f *snapshotFile) flush() error {
+	f.writer.Flush()
+	if err := f.writer.Error(); err != nil {
+		return err
+	}
+	return f.buffer.Flush()
+}
+
+// close flushes and closes the file
+func (f *snapshotFile) close() error {
+	if err := f.flush(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCSVSnapshotRecorder_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSnapshotRecorder(tmpDir)
+	defer recorder.Close(context.Background())
+
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	snapshot := &domain.Snapshot{
+		Timestamp: start,
+		Uic:       21,
+		Ticker:    "EURUSD",
+		AssetType: "FxSpot",
+		Bid:       1.10000,
+		Ask:       1.10002,
+		MinSpread: 0.00002,
+		MaxSpread: 0.00005,
+		Ticks:     4,
+		Decimals:  5,
+	}
+
+	ctx := context.Background()
+	for i := range 2 {
+		if err := recorder.RecordSnapshots(ctx, []*domain.Snapshot{snapshot.CarryForward(start.Add(time.Duration(i) * time.Second))}); err != nil {
+			t.Fatalf("Failed to record snapshots: %v", err)
+		}
+	}
+
+	// Written without Close: files are flushed per call
+	content, err := os.ReadFile(filepath.Join(tmpDir, "20251118", "EURUSD_12.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header + 2 rows, got %d lines:\n%s", len(lines), content)
+	}
+	if lines[0] != "timestamp,uic,ticker,asset_type,bid,ask,min_spread,max_spread,ticks,recorded" {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if lines[2] != "2025-11-18T12:00:01Z,21,EURUSD,FxSpot,1.10000,1.10002,0.00002,0.00002,0,0" {
+		t.Errorf("Unexpected row: %s", lines[2])
+	}
+
+	var read []*domain.Snapshot
+	err = ReadSnapshotFile(filepath.Join(tmpDir, "20251118", "EURUSD_12.csv"), func(s *domain.Snapshot) error {
+		read = append(read, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSnapshotFile failed: %v", err)
+	}
+	if len(read) != 2 || read[1].MaxSpread != 0.00002 || read[1].Decimals != 5 || read[1].Ticks != 0 {
+		t.Errorf("Unexpected snapshots read back: %+v", read)
+	}
+}
+
+func TestCSVSnapshotRecorder_Version1File(t *testing.T) {
+	tmpDir := t.TempDir()
+	dayDir := filepath.Join(tmpDir, "20251118")
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "timestamp,uic,ticker,asset_type,bid,ask,min_spread,max_spread,ticks\n" +
+		"2025-11-18T12:00:00Z,21,EURUSD,FxSpot,1.10000,1.10002,0.00002,0.00005,4\n"
+	if err := os.WriteFile(filepath.Join(dayDir, "EURUSD_12.csv"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var read []*domain.Snapshot
+	err := ReadSnapshotFile(filepath.Join(dayDir, "EURUSD_12.csv"), func(s *domain.Snapshot) error {
+		read = append(read, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSnapshotFile failed: %v", err)
+	}
+	if len(read) != 1 || read[0].Ticks != 4 || read[0].Recorded != 4 {
+		t.Errorf("Expected every tick of a version 1 snapshot counted as recorded, got %+v", read)
+	}
+
+	// The same hour after an upgrade continues in a new file
+	recorder := NewCSVSnapshotRecorder(tmpDir)
+	defer recorder.Close(context.Background())
+	snapshot := &domain.Snapshot{Timestamp: time.Date(2025, 11, 18, 12, 0, 1, 0, time.UTC), Uic: 21, Ticker: "EURUSD", AssetType: "FxSpot",
+		Bid: 1.10000, Ask: 1.10002, MinSpread: 0.00002, MaxSpread: 0.00002, Ticks: 2, Recorded: 1, Decimals: 5}
+	if err := recorder.RecordSnapshots(context.Background(), []*domain.Snapshot{snapshot}); err != nil {
+		t.Fatalf("Failed to record snapshots: %v", err)
+	}
+	if old, _ := os.ReadFile(filepath.Join(dayDir, "EURUSD_12.csv")); string(old) != content {
+		t.Errorf("Expected the version 1 file unchanged, got:\n%s", old)
+	}
+	read = nil
+	err = ReadSnapshotFile(filepath.Join(dayDir, "EURUSD_12-2.csv"), func(s *domain.Snapshot) error {
+		read = append(read, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSnapshotFile failed: %v", err)
+	}
+	if len(read) != 1 || read[0].Ticks != 2 || read[0].Recorded != 1 {
+		t.Errorf("Unexpected snapshots in the new file: %+v", read)
+	}
+}
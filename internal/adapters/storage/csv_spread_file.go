@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// flushConcurrency bounds the files Flush writes at once
+const flushConcurrency = 8
+
+// spreadFile is an open hourly CSV file with its own buffer
+// Rows are encoded into pending under the recorder's lock; writing them out only takes writeMu,
+// so Flush can write outside the recorder's lock while new rows go to a fresh buffer
+type spreadFile struct {
+	file     *os.File
+	pending  *bytes.Buffer  // Rows not yet written to file
+	limit    int            // pending is written out once it reaches this size, like a full bufio buffer
+	written  *atomic.Uint64 // Bytes written by the recorder, shared by its files
+	lastUsed uint64         // Recorder's use count at the last write, for closing the least recently used file
+	hour     time.Time      // Start of the hour the file covers
+
+	writeMu   sync.Mutex // Held while rows are written to file, keeping writes in order
+	unwritten []byte     // Rows a failed write didn't get to the file, written first next time; writeMu is held
+}
+
+// newSpreadFile wraps an open file with a buffer of the given size
+func newSpreadFile(file *os.File, limit int, written *atomic.Uint64) *spreadFile {
+	return &spreadFile{file: file, pending: bytes.NewBuffer(make([]byte, 0, limit)), limit: limit, written: written}
+}
+
+// writeEncoded buffers an encoded row, writing the buffer out once full; the recorder's lock is held
+// While a flush is writing the file, rows keep buffering instead of waiting for the disk
+func (f *spreadFile) writeEncoded(row []byte) error {
+	f.pending.Write(row)
+	if f.pending.Len() >= f.limit && f.writeMu.TryLock() {
+		defer f.writeMu.Unlock()
+		err := f.writeOut(f.pending.Bytes(), false)
+		f.pending.Reset() // Reused, unlike a buffer handed to a flush
+		return err
+	}
+	return nil
+}
+
+// take swaps out the buffered rows; the recorder's lock is held
+func (f *spreadFile) take() []byte {
+	if f.pending.Len() == 0 {
+		return nil
+	}
+	data := f.pending.Bytes()
+	f.pending = bytes.NewBuffer(make([]byte, 0, f.limit))
+	return data
+}
+
+// writeOut writes rows to the file and optionally fsyncs it; writeMu is held
+// Rows a failed write left out are kept and written before data by the next call, so a retried
+// flush reports the error again until they are on disk instead of losing them
+func (f *spreadFile) writeOut(data []byte, fsync bool) error {
+	if len(f.unwritten) > 0 {
+		data = append(f.unwritten, data...)
+		f.unwritten = nil
+	}
+	if len(data) > 0 {
+		n, err := f.file.Write(data)
+		f.written.Add(uint64(n))
+		if err != nil {
+			f.unwritten = bytes.Clone(data[n:])
+			return fmt.Errorf("failed to write %s (%d bytes kept for the next flush): %w", f.file.Name(), len(f.unwritten), err)
+		}
+	}
+	if fsync {
+		if err := f.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", f.file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// pendingWrite is the rows swapped out of one file, which stays locked for writing until they are written
+type pendingWrite struct {
+	key  string
+	file *spreadFile
+	data []byte
+}
+
+// writePending writes swapped-out rows to their files concurrently and unlocks the files
+func writePending(writes []pendingWrite, fsync bool) error {
+	errs := make([]error, len(writes))
+	slots := make(chan struct{}, flushConcurrency)
+	var wg sync.WaitGroup
+	for i, w := range writes {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer w.file.writeMu.Unlock()
+			if err := w.file.writeOut(w.data, fsync); err != nil {
+				errs[i] = fmt.Errorf("failed to flush %s: %w", w.key, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
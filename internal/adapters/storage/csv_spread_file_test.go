@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSpreadFile_KeepsRowsOfFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "EURUSD_14.csv")
+	broken, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken.Close() // Writes fail like on a lost disk
+
+	var written atomic.Uint64
+	file := newSpreadFile(broken, 1024, &written)
+	file.writeEncoded([]byte("row1\n"))
+	if err := file.writeOut(file.take(), false); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	file.writeEncoded([]byte("row2\n"))
+	if err := file.writeOut(file.take(), false); err == nil {
+		t.Fatal("Expected a retried flush to report the error again")
+	}
+
+	// Once the disk is back, the kept rows go first
+	if file.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer file.file.Close()
+	file.writeEncoded([]byte("row3\n"))
+	if err := file.writeOut(file.take(), false); err != nil {
+		t.Fatalf("writeOut failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "row1\nrow2\nrow3\n" {
+		t.Errorf("Expected all rows in order, got %q", data)
+	}
+}
@@ -1,23 +1,27 @@
 package storage
 
 import (
-	"bufio"
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/bjoelf/fx-collector/internal/domain"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
 )
 
 // roundPrice rounds a float64 to the specified number of decimals
-// Used for proper FX price formatting (e.g., 4 decimals for EURUSD, 2 for USDJPY)
+// Used for proper FX price formatting (e.g., 5 decimals for EURUSD, 3 for USDJPY: one below the pip)
 func roundPrice(price float64, decimals int) float64 {
 	if decimals <= 0 {
 		return price // No rounding if decimals not specified
@@ -26,59 +30,277 @@ func roundPrice(price float64, decimals int) float64 {
 	return math.Round(price*multiplier) / multiplier
 }
 
+// appendSpreadRecord appends one tick as a CSV row, newline included, to dst
+// Prices are rounded to the instrument decimals (e.g., 5 for EURUSD, 3 for USDJPY); spread is in SpreadUnit,
+// in price units with the instrument's spread decimals
+// Encoding into a reused buffer keeps recording free of per-tick allocations; the output is what
+// csv.Writer writes for the same fields
+func appendSpreadRecord(dst []byte, data *domain.PriceData) []byte {
+	bid := roundPrice(data.Bid, data.Decimals)
+	ask := roundPrice(data.Ask, data.Decimals)
+	spread, precision := data.UnitSpread()
+	if data.SpreadUnit == "" || data.SpreadUnit == domain.SpreadUnitPrice {
+		spread = roundPrice(spread, precision)
+	}
+
+	dst = data.Timestamp.AppendFormat(dst, time.RFC3339Nano)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(data.Uic), 10)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, data.Ticker)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, data.AssetType)
+	dst = append(dst, ',')
+	dst = strconv.AppendFloat(dst, bid, 'f', data.Decimals, 64)
+	dst = append(dst, ',')
+	dst = strconv.AppendFloat(dst, ask, 'f', data.Decimals, 64)
+	dst = append(dst, ',')
+	dst = strconv.AppendFloat(dst, spread, 'f', precision, 64)
+	dst = append(dst, ',')
+	dst = strconv.AppendUint(dst, data.Sequence, 10)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, data.SessionLabel)
+	dst = append(dst, ',')
+	dst = strconv.AppendUint(dst, uint64(data.Flags), 10)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, string(data.SpreadUnit))
+	dst = append(dst, ',')
+	dst = appendEffectiveSpread(dst, data)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, data.Source)
+	dst = append(dst, ',')
+	if data.SpreadZ != 0 {
+		dst = strconv.AppendFloat(dst, data.SpreadZ, 'f', 2, 64)
+	}
+	return append(dst, '\n')
+}
+
+// appendEffectiveSpread appends the effective spread one digit finer than the prices, or nothing if not computed
+func appendEffectiveSpread(dst []byte, data *domain.PriceData) []byte {
+	if data.EffectiveSpread == 0 {
+		return dst
+	}
+	return strconv.AppendFloat(dst, roundPrice(data.EffectiveSpread, data.Decimals+1), 'f', data.Decimals+1, 64)
+}
+
+// appendCSVField appends a text field, quoted by the rules of csv.Writer
+func appendCSVField(dst []byte, field string) []byte {
+	if !csvFieldNeedsQuotes(field) {
+		return append(dst, field...)
+	}
+	dst = append(dst, '"')
+	for i := 0; i < len(field); i++ {
+		if field[i] == '"' {
+			dst = append(dst, '"')
+		}
+		dst = append(dst, field[i])
+	}
+	return append(dst, '"')
+}
+
+// csvFieldNeedsQuotes mirrors csv.Writer: separators, quotes, line breaks, a leading space or \.
+func csvFieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsAny(field, ",\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+// CSVRowSize returns the bytes a tick takes in the hourly CSV files (used for byte quotas)
+func CSVRowSize(data *domain.PriceData) int {
+	var buf [256]byte
+	return len(appendSpreadRecord(buf[:0], data))
+}
+
+// spreadColumns is the header of the hourly CSV files (see spreadColumnHistory)
+var spreadColumns = SpreadSchema.Columns
+
 // CSVSpreadRecorder implements SpreadRecorder interface using CSV files
-// File format: data/spreads/YYYYMMDD/TICKER_HH.csv (hourly files)
-// Columns: timestamp,uic,ticker,asset_type,bid,ask,spread
+// File format: data/spreads/YYYYMMDD/TICKER_HH.csv (hourly files, TICKER_HH-N.csv after a schema change)
+// Columns: timestamp,uic,ticker,asset_type,bid,ask,spread,seq,session,flags,spread_unit,effective_spread,source,spread_z
 // Using hourly files reduces file count from ~40,000/day to ~672/day (60× reduction)
+// Flush only holds the recorder's lock while swapping out the buffered rows; the disk writes run
+// concurrently per file afterwards, so a slow disk doesn't stall recording
 type CSVSpreadRecorder struct {
 	baseDir    string
-	writers    map[string]*csv.Writer
-	files      map[string]*os.File
-	buffers    map[string]*bufio.Writer
+	files      map[string]*spreadFile // Keyed by TICKER_YYYYMMDD_HH
 	mu         sync.Mutex
-	bufferSize int // Number of records to buffer before flush
+	bufferSize int    // Number of records to buffer before flush
+	scratch    []byte // Row being encoded, reused across records
+	keyScratch []byte // Key of the file being looked up
+
+	syncPolicy SyncPolicy
+	unsynced   int // Records written since the last fsync (SyncEvery)
+
+	bufferSizer  *BufferSizer
+	onBufferSize func(ticker string, size int) // Called when a file gets its buffer (optional)
+
+	written atomic.Uint64 // Bytes written to files
+
+	maxOpenFiles int    // Open files kept at most, least recently written closed first (0 = no limit)
+	uses         uint64 // Counts file lookups, ordering files by last use
+	evictions    int    // Files closed to stay within maxOpenFiles
+
+	partial bool                 // Write files as TICKER_HH.csv.partial, renamed once the hour is over
+	evicted map[string]time.Time // Partial files closed before their hour ended, by path, with their hour
+
+	onFinalize func(path string) // Called with each file renamed to its final name (optional)
+}
+
+// partialGrace is how long after its hour a partial file stays open for late ticks before it is finalized
+const partialGrace = time.Minute
+
+func init() {
+	// csv?dir=data/spreads&fsync=never&buffer=auto&max_open_files=512&partial=false
+	RegisterRecorder("csv", func(spec RecorderSpec) (ports.TickWriter, error) {
+		policy, err := ParseSyncPolicy(spec.Param("fsync", ""))
+		if err != nil {
+			return nil, err
+		}
+		// Adaptive buffers cover the sink's own flush interval if it has one
+		window, err := time.ParseDuration(spec.Param("flush", spec.Param("buffer_window", "30s")))
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid buffer window for csv recorder")
+		}
+		sizer, err := ParseBufferSizer(spec.Param("buffer", "auto"), window)
+		if err != nil {
+			return nil, err
+		}
+		maxOpen, err := strconv.Atoi(spec.Param("max_open_files", "512"))
+		if err != nil || maxOpen < 0 {
+			return nil, fmt.Errorf("invalid max_open_files %q for csv recorder", spec.Param("max_open_files", ""))
+		}
+		partial, err := strconv.ParseBool(spec.Param("partial", "false"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid partial %q for csv recorder", spec.Param("partial", ""))
+		}
+		recorder := NewCSVSpreadRecorder(spec.Param("dir", "data/spreads"))
+		recorder.SetSyncPolicy(policy)
+		recorder.SetBufferSizer(sizer)
+		recorder.SetMaxOpenFiles(maxOpen)
+		if err := recorder.SetPartialFiles(partial); err != nil {
+			return nil, err
+		}
+		return recorder, nil
+	})
 }
 
 // NewCSVSpreadRecorder creates a new CSV-based spread recorder
 func NewCSVSpreadRecorder(baseDir string) *CSVSpreadRecorder {
 	return &CSVSpreadRecorder{
 		baseDir:    baseDir,
-		writers:    make(map[string]*csv.Writer),
-		files:      make(map[string]*os.File),
-		buffers:    make(map[string]*bufio.Writer),
+		files:      make(map[string]*spreadFile),
+		evicted:    make(map[string]time.Time),
 		bufferSize: 100, // Buffer 100 records before auto-flush
+
+		bufferSizer: NewBufferSizer(30 * time.Second),
 	}
 }
 
+// SetBufferSizer sets how the write buffers of files opened from now on are sized (default adaptive, 30s window)
+func (r *CSVSpreadRecorder) SetBufferSizer(sizer *BufferSizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bufferSizer = sizer
+}
+
+// OnBufferSize sets a function called with the buffer size each new file gets, e.g. to export it as a metric
+func (r *CSVSpreadRecorder) OnBufferSize(fn func(ticker string, size int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onBufferSize = fn
+}
+
+// BytesWritten returns the bytes written to files so far
+func (r *CSVSpreadRecorder) BytesWritten() uint64 {
+	return r.written.Load()
+}
+
+// OpenFiles returns the number of hourly files currently open
+func (r *CSVSpreadRecorder) OpenFiles() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.files)
+}
+
+// SetMaxOpenFiles caps the files kept open at once (0 = no limit)
+// With more active instruments than that, the least recently written file is closed to open the next
+func (r *CSVSpreadRecorder) SetMaxOpenFiles(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxOpenFiles = n
+}
+
+// Evictions returns how many files were closed to stay within the open file limit
+func (r *CSVSpreadRecorder) Evictions() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.evictions
+}
+
+// SetPartialFiles sets whether hourly files are written under a .partial name and renamed to
+// TICKER_HH.csv once their hour is over, so batch jobs only ever see complete files (default off)
+// Enabling it picks up partial files left by an earlier run; those of ended hours are finalized by the next Flush
+func (r *CSVSpreadRecorder) SetPartialFiles(partial bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partial = partial
+	if !partial {
+		return nil
+	}
+
+	leftover, err := filepath.Glob(filepath.Join(r.baseDir, "*", "*.csv"+PartialSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to list partial files: %w", err)
+	}
+	for _, path := range leftover {
+		if hour, ok := spreadFileHour(path); ok {
+			// Finalized by the next Flush if the hour is over, or when the recorder closes
+			r.evicted[path] = hour
+		}
+	}
+	return nil
+}
+
+// OnFinalize sets a function called with the path of each file given its final name in partial mode,
+// e.g. to announce it to downstream jobs; it runs under the recorder's lock and must not block
+func (r *CSVSpreadRecorder) OnFinalize(fn func(path string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onFinalize = fn
+}
+
+// SetSyncPolicy sets when files are fsynced (default never)
+func (r *CSVSpreadRecorder) SetSyncPolicy(policy SyncPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncPolicy = policy
+}
+
 // Record saves a single price data point
 func (r *CSVSpreadRecorder) Record(ctx context.Context, data *domain.PriceData) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	writer, err := r.getWriter(data.Ticker, data.Timestamp)
+	file, err := r.getFile(data.Ticker, data.Timestamp)
 	if err != nil {
 		return fmt.Errorf("failed to get writer: %w", err)
 	}
 
-	// Round prices based on instrument decimals (e.g., 4 for EURUSD, 2 for USDJPY)
-	bid := roundPrice(data.Bid, data.Decimals)
-	ask := roundPrice(data.Ask, data.Decimals)
-	spread := roundPrice(data.Spread, data.Decimals)
-
-	record := []string{
-		data.Timestamp.Format(time.RFC3339Nano),
-		strconv.Itoa(data.Uic),
-		data.Ticker,
-		data.AssetType,
-		strconv.FormatFloat(bid, 'f', data.Decimals, 64),
-		strconv.FormatFloat(ask, 'f', data.Decimals, 64),
-		strconv.FormatFloat(spread, 'f', data.Decimals, 64),
+	r.scratch = appendSpreadRecord(r.scratch[:0], data)
+	if err := file.writeEncoded(r.scratch); err != nil {
+		// The row is kept with the rows the failed write left out
+		return ports.PartialWrite(1, fmt.Errorf("failed to write record: %w", err))
 	}
+	r.bufferSizer.Written(data.Ticker, len(r.scratch), time.Now())
 
-	if err := writer.Write(record); err != nil {
-		return fmt.Errorf("failed to write record: %w", err)
+	if r.syncPolicy.due(&r.unsynced, 1) {
+		return ports.PartialWrite(1, r.syncAll())
 	}
-
 	return nil
 }
 
@@ -87,186 +309,252 @@ func (r *CSVSpreadRecorder) RecordBatch(ctx context.Context, data []*domain.Pric
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for _, priceData := range data {
-		writer, err := r.getWriter(priceData.Ticker, priceData.Timestamp)
+	now := time.Now()
+	for i, priceData := range data {
+		file, err := r.getFile(priceData.Ticker, priceData.Timestamp)
 		if err != nil {
-			return fmt.Errorf("failed to get writer for %s: %w", priceData.Ticker, err)
-		}
-
-		// Round prices based on instrument decimals
-		bid := roundPrice(priceData.Bid, priceData.Decimals)
-		ask := roundPrice(priceData.Ask, priceData.Decimals)
-		spread := roundPrice(priceData.Spread, priceData.Decimals)
-
-		record := []string{
-			priceData.Timestamp.Format(time.RFC3339Nano),
-			strconv.Itoa(priceData.Uic),
-			priceData.Ticker,
-			priceData.AssetType,
-			strconv.FormatFloat(bid, 'f', priceData.Decimals, 64),
-			strconv.FormatFloat(ask, 'f', priceData.Decimals, 64),
-			strconv.FormatFloat(spread, 'f', priceData.Decimals, 64),
+			return ports.PartialWrite(i, fmt.Errorf("failed to get writer for %s: %w", priceData.Ticker, err))
 		}
 
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("failed to write record for %s: %w", priceData.Ticker, err)
+		r.scratch = appendSpreadRecord(r.scratch[:0], priceData)
+		if err := file.writeEncoded(r.scratch); err != nil {
+			// The row is kept with the rows the failed write left out
+			return ports.PartialWrite(i+1, fmt.Errorf("failed to write record for %s: %w", priceData.Ticker, err))
 		}
+		r.bufferSizer.Written(priceData.Ticker, len(r.scratch), now)
 	}
 
+	if r.syncPolicy.due(&r.unsynced, len(data)) {
+		return ports.PartialWrite(len(data), r.syncAll())
+	}
 	return nil
 }
 
+// keepsUnwritten marks the recorder as keeping the rows a failed write left out for the next
+// flush (unwrittenKeeper)
+func (r *CSVSpreadRecorder) keepsUnwritten() {}
+
 // Flush ensures all buffered data is written to storage
+// The recorder's lock is only held to swap out the buffered rows, so recording continues while they are written
 func (r *CSVSpreadRecorder) Flush(ctx context.Context) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	log.Printf("CSVSpreadRecorder: Flushing %d writers...", len(r.writers))
-
-	for ticker, writer := range r.writers {
-		writer.Flush()
-		if err := writer.Error(); err != nil {
-			return fmt.Errorf("failed to flush writer for %s: %w", ticker, err)
-		}
-
-		// Flush buffered writer
-		if buf, ok := r.buffers[ticker]; ok {
-			if err := buf.Flush(); err != nil {
-				return fmt.Errorf("failed to flush buffer for %s: %w", ticker, err)
-			}
-		}
-		log.Printf("CSVSpreadRecorder: ✅ Flushed %s", ticker)
+	var finalizeErr error
+	if r.partial {
+		finalizeErr = r.finalizeEnded(time.Now())
 	}
+	writes := r.takePending()
+	fsync := r.syncPolicy.syncs()
+	if fsync {
+		r.unsynced = 0
+	}
+	r.mu.Unlock()
 
+	log.Printf("CSVSpreadRecorder: Flushing %d writers...", len(writes))
+	if err := errors.Join(finalizeErr, writePending(writes, fsync)); err != nil {
+		return err
+	}
 	log.Printf("CSVSpreadRecorder: All writers flushed")
 	return nil
 }
 
+// syncAll writes all buffered rows and fsyncs every open file; r.mu is held
+func (r *CSVSpreadRecorder) syncAll() error {
+	return writePending(r.takePending(), true)
+}
+
+// takePending swaps out the buffered rows of every open file; r.mu is held
+// Each file stays locked for writing until writePending has written its rows, so rows written
+// inline meanwhile can't overtake them
+func (r *CSVSpreadRecorder) takePending() []pendingWrite {
+	writes := make([]pendingWrite, 0, len(r.files))
+	for key, file := range r.files {
+		file.writeMu.Lock()
+		writes = append(writes, pendingWrite{key: key, file: file, data: file.take()})
+	}
+	return writes
+}
+
 // Close finalizes the recording session and releases resources
-func (r *CSVSpreadRecorder) Close() error {
+// The final fsync is skipped if ctx is already done
+func (r *CSVSpreadRecorder) Close(ctx context.Context) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Flush all writers
-	for ticker, writer := range r.writers {
-		writer.Flush()
-		if err := writer.Error(); err != nil {
-			return fmt.Errorf("failed to flush writer for %s during close: %w", ticker, err)
-		}
+	if err := writePending(r.takePending(), r.syncPolicy.syncs() && ctx.Err() == nil); err != nil {
+		return fmt.Errorf("failed to flush during close: %w", err)
 	}
 
-	// Flush and close all buffers
-	for ticker, buf := range r.buffers {
-		if err := buf.Flush(); err != nil {
-			return fmt.Errorf("failed to flush buffer for %s during close: %w", ticker, err)
+	// Close all files; in partial mode those of ended hours are finalized, while those of the
+	// current hour stay partial for the next run to continue (or to finalize once the hour is over)
+	now := time.Now()
+	for key, file := range r.files {
+		if err := file.file.Close(); err != nil {
+			return fmt.Errorf("failed to close file for %s: %w", key, err)
+		}
+		if r.partial && now.Sub(file.hour) >= time.Hour {
+			if err := r.finalize(file.file.Name()); err != nil {
+				return err
+			}
 		}
 	}
+	r.files = make(map[string]*spreadFile)
 
-	// Close all files
-	for ticker, file := range r.files {
-		if err := file.Close(); err != nil {
-			return fmt.Errorf("failed to close file for %s: %w", ticker, err)
+	for path, hour := range r.evicted {
+		if now.Sub(hour) < time.Hour {
+			continue
+		}
+		if err := r.finalize(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
 		}
+		delete(r.evicted, path)
 	}
+	return nil
+}
 
-	// Clear maps
-	r.writers = make(map[string]*csv.Writer)
-	r.buffers = make(map[string]*bufio.Writer)
-	r.files = make(map[string]*os.File)
-
+// finalize renames a partial file to its final name and reports it to onFinalize; r.mu is held
+func (r *CSVSpreadRecorder) finalize(partialPath string) error {
+	if err := finalizePartial(partialPath); err != nil {
+		return err
+	}
+	if r.onFinalize != nil {
+		r.onFinalize(strings.TrimSuffix(partialPath, PartialSuffix))
+	}
 	return nil
 }
 
-// getWriter returns a CSV writer for the given ticker and timestamp
+// finalizeEnded closes and renames the partial files whose hour ended more than partialGrace ago; r.mu is held
+// Files of instruments that stopped ticking (e.g. at market close) would otherwise stay partial
+func (r *CSVSpreadRecorder) finalizeEnded(now time.Time) error {
+	var errs []error
+	for key, file := range r.files {
+		if now.Sub(file.hour) > time.Hour+partialGrace {
+			errs = append(errs, r.closeFile(key, true))
+		}
+	}
+	for path, hour := range r.evicted {
+		if now.Sub(hour) <= time.Hour+partialGrace {
+			continue
+		}
+		if err := r.finalize(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		delete(r.evicted, path)
+	}
+	return errors.Join(errs...)
+}
+
+// getFile returns the open file for the given ticker and timestamp
 // Creates directory structure and file if they don't exist
 // Uses hourly files: TICKER_HH.csv (e.g., EURUSD_14.csv for 14:00-14:59)
-// Automatically closes old hourly files to prevent resource leaks
-func (r *CSVSpreadRecorder) getWriter(ticker string, timestamp time.Time) (*csv.Writer, error) {
-	dateStr := timestamp.Format("20060102")
-	hourStr := timestamp.Format("15") // HH format (hour only)
-	key := fmt.Sprintf("%s_%s_%s", ticker, dateStr, hourStr)
-
-	// Return existing writer if available
-	if writer, ok := r.writers[key]; ok {
-		return writer, nil
+// Automatically closes files of earlier hours to prevent resource leaks
+func (r *CSVSpreadRecorder) getFile(ticker string, timestamp time.Time) (*spreadFile, error) {
+	// Key TICKER_YYYYMMDD_HH, built in a reused buffer: the lookup doesn't allocate
+	r.keyScratch = append(append(r.keyScratch[:0], ticker...), '_')
+	r.keyScratch = timestamp.AppendFormat(r.keyScratch, "20060102_15")
+
+	// Return existing file if available
+	r.uses++
+	if file, ok := r.files[string(r.keyScratch)]; ok {
+		file.lastUsed = r.uses
+		return file, nil
 	}
 
-	// Close old hourly files for this ticker to prevent resource leaks
-	// Search for keys with same ticker but different hour/date
-	for oldKey, oldWriter := range r.writers {
-		if len(oldKey) > len(ticker) && oldKey[:len(ticker)] == ticker && oldKey != key {
-			// Flush and close the old writer
-			oldWriter.Flush()
-			if err := oldWriter.Error(); err != nil {
-				log.Printf("Warning: Error flushing old writer for %s: %v", oldKey, err)
-			}
-
-			// Flush and close buffer
-			if buf, ok := r.buffers[oldKey]; ok {
-				if err := buf.Flush(); err != nil {
-					log.Printf("Warning: Error flushing old buffer for %s: %v", oldKey, err)
-				}
+	key := string(r.keyScratch)
+	dateStr := timestamp.Format("20060102")
+	hourStr := timestamp.Format("15") // HH format (hour only)
+	hour := timestamp.Truncate(time.Hour)
+
+	// Close this ticker's files of earlier hours to prevent resource leaks; a late tick for an
+	// earlier hour leaves the file of the current hour open (keys of the same length and prefix
+	// are the ticker's own)
+	for oldKey, old := range r.files {
+		if len(oldKey) == len(key) && strings.HasPrefix(oldKey, ticker+"_") && old.hour.Before(hour) {
+			if err := r.closeFile(oldKey, true); err != nil {
+				return nil, fmt.Errorf("failed to close old hourly file %s: %w", oldKey, err)
 			}
+			log.Printf("CSVSpreadRecorder: ✅ Closed old hourly file: %s", oldKey)
+		}
+	}
 
-			// Close file
-			if file, ok := r.files[oldKey]; ok {
-				if err := file.Close(); err != nil {
-					log.Printf("Warning: Error closing old file for %s: %v", oldKey, err)
-				}
+	// Stay below the handle limit by closing the file written to least recently; it is reopened
+	// for appending if its instrument ticks again within the hour
+	if r.maxOpenFiles > 0 && len(r.files) >= r.maxOpenFiles {
+		lruKey := ""
+		for openKey, open := range r.files {
+			if lruKey == "" || open.lastUsed < r.files[lruKey].lastUsed {
+				lruKey = openKey
 			}
-
-			// Remove from maps
-			delete(r.writers, oldKey)
-			delete(r.buffers, oldKey)
-			delete(r.files, oldKey)
-
-			log.Printf("CSVSpreadRecorder: ✅ Closed old hourly file: %s", oldKey)
 		}
-	} // Create directory: data/spreads/YYYYMMDD/
+		if err := r.closeFile(lruKey, false); err != nil {
+			return nil, fmt.Errorf("failed to close least recently written file %s: %w", lruKey, err)
+		}
+		r.evictions++
+		log.Printf("CSVSpreadRecorder: Closed least recently written file %s (%d files open, limit %d)", lruKey, len(r.files), r.maxOpenFiles)
+	}
+
+	// Create directory: data/spreads/YYYYMMDD/
 	dirPath := filepath.Join(r.baseDir, dateStr)
 	log.Printf("CSVSpreadRecorder: Creating directory: %s", dirPath)
 	if err := os.MkdirAll(dirPath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create directory %s: %w", dirPath, err)
 	}
 
-	// Create file: TICKER_HH.csv (hourly file)
-	filename := fmt.Sprintf("%s_%s.csv", ticker, hourStr)
-	filePath := filepath.Join(dirPath, filename)
-
-	// Check if file exists to determine if we need to write header
-	fileExists := false
-	if _, err := os.Stat(filePath); err == nil {
-		fileExists = true
-	}
-
-	log.Printf("CSVSpreadRecorder: Opening file: %s (exists=%v)", filePath, fileExists)
-
-	// Open file in append mode
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Open file: TICKER_HH.csv (hourly file), appending after a restart
+	osFile, writeHeader, err := openNumberedCSV(dirPath, fmt.Sprintf("%s_%s", ticker, hourStr), SpreadSchema, r.partial)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+		return nil, err
+	}
+	filePath := osFile.Name()
+	delete(r.evicted, filePath)
+	log.Printf("CSVSpreadRecorder: Opened file: %s (new=%v)", filePath, writeHeader)
+
+	// Buffer sized to the instrument's recent data rate
+	bufferSize := r.bufferSizer.Size(ticker)
+	file := newSpreadFile(osFile, bufferSize, &r.written)
+	file.hour = hour
+	if r.onBufferSize != nil {
+		r.onBufferSize(ticker, bufferSize)
 	}
 
-	// Create buffered writer
-	buffer := bufio.NewWriter(file)
-	writer := csv.NewWriter(buffer)
-
-	// Write header if new file
-	if !fileExists {
-		header := []string{"timestamp", "uic", "ticker", "asset_type", "bid", "ask", "spread"}
-		if err := writer.Write(header); err != nil {
-			file.Close()
+	if writeHeader {
+		if err := file.writeEncoded([]byte(strings.Join(spreadColumns, ",") + "\n")); err != nil {
+			osFile.Close()
 			return nil, fmt.Errorf("failed to write header: %w", err)
 		}
 		log.Printf("CSVSpreadRecorder: Header written to %s", filePath)
 	}
 
-	// Store references
+	file.lastUsed = r.uses
 	r.files[key] = file
-	r.buffers[key] = buffer
-	r.writers[key] = writer
+	log.Printf("CSVSpreadRecorder: ✅ Writer created for %s -> %s (%d byte buffer)", ticker, filePath, bufferSize)
 
-	log.Printf("CSVSpreadRecorder: ✅ Writer created for %s -> %s", ticker, filePath)
+	return file, nil
+}
 
-	return writer, nil
+// closeFile writes out a file's buffered rows, closes it and forgets it; r.mu is held
+// In partial mode the file is renamed to its final name if finalize is set (its hour is over),
+// otherwise remembered so it is finalized later
+// If the rows can't be written, the file stays open with them, so the next flush retries
+func (r *CSVSpreadRecorder) closeFile(key string, finalize bool) error {
+	file := r.files[key]
+	// Waits for a flush still writing the file
+	file.writeMu.Lock()
+	err := file.writeOut(file.take(), r.syncPolicy.syncs())
+	file.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+	delete(r.files, key)
+	if err := file.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", file.file.Name(), err)
+	}
+
+	if !r.partial {
+		return nil
+	}
+	if !finalize {
+		r.evicted[file.file.Name()] = file.hour
+		return nil
+	}
+	return r.finalize(file.file.Name())
 }
@@ -2,11 +2,16 @@ package storage
 
 import (
 	"context"
+	"encoding/csv"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/bjoelf/fx-collector/internal/domain"
+	"github.com/bjoelf/fx-collector/pkg/domain"
 )
 
 func TestCSVSpreadRecorder_Record(t *testing.T) {
@@ -14,7 +19,7 @@ func TestCSVSpreadRecorder_Record(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	recorder := NewCSVSpreadRecorder(tmpDir)
-	defer recorder.Close()
+	defer recorder.Close(context.Background())
 
 	// Test data
 	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
@@ -60,10 +65,189 @@ func TestCSVSpreadRecorder_Record(t *testing.T) {
 	t.Logf("File content:\n%s", lines)
 }
 
+func TestCSVRowSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+
+	priceData := &domain.PriceData{
+		Timestamp:    time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Uic:          21,
+		Ticker:       "EURUSD",
+		AssetType:    "FxSpot",
+		Bid:          1.10000,
+		Ask:          1.10002,
+		Spread:       0.00002,
+		Decimals:     5,
+		SessionLabel: "london",
+	}
+	if err := recorder.Record(context.Background(), priceData); err != nil {
+		t.Fatalf("Failed to record price: %v", err)
+	}
+	recorder.Close(context.Background())
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "20251118", "EURUSD_12.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	header := strings.Join(spreadColumns, ",") + "\n"
+	if got, want := CSVRowSize(priceData), len(content)-len(header); got != want {
+		t.Errorf("CSVRowSize = %d, file row is %d bytes", got, want)
+	}
+}
+
+func TestAppendSpreadRecord_MatchesCSVWriter(t *testing.T) {
+	priceData := &domain.PriceData{
+		Timestamp:       time.Date(2025, 11, 18, 12, 0, 0, 123456000, time.UTC),
+		Uic:             21,
+		Ticker:          "EURUSD",
+		AssetType:       "FxSpot",
+		Bid:             1.100004,
+		Ask:             1.100026,
+		Decimals:        5,
+		Sequence:        42,
+		Flags:           domain.FlagSampled,
+		SpreadUnit:      domain.SpreadUnitPips,
+		PipSize:         0.0001,
+		EffectiveSpread: 0.0000123,
+	}
+	priceData.CalculateSpread()
+
+	for _, label := range []string{"", "london", `new "york"`, "asia,late", " tokyo", "\\."} {
+		priceData.SessionLabel = label
+		fields := []string{
+			priceData.Timestamp.Format(time.RFC3339Nano), "21", "EURUSD", "FxSpot", "1.10000", "1.10003", "0.3",
+			"42", label, strconv.Itoa(int(domain.FlagSampled)), "pips", "0.000012", "", "",
+		}
+		var want strings.Builder
+		writer := csv.NewWriter(&want)
+		writer.Write(fields)
+		writer.Flush()
+
+		if got := string(appendSpreadRecord(nil, priceData)); got != want.String() {
+			t.Errorf("Session %q: expected %q, got %q", label, want.String(), got)
+		}
+	}
+}
+
+func BenchmarkAppendSpreadRecord(b *testing.B) {
+	priceData := &domain.PriceData{
+		Timestamp:    time.Date(2025, 11, 18, 12, 0, 0, 123456000, time.UTC),
+		Uic:          21,
+		Ticker:       "EURUSD",
+		AssetType:    "FxSpot",
+		Bid:          1.10000,
+		Ask:          1.10002,
+		Spread:       0.00002,
+		Decimals:     5,
+		Sequence:     42,
+		SessionLabel: "london",
+	}
+	buf := make([]byte, 0, 256)
+	b.ReportAllocs()
+	for b.Loop() {
+		buf = appendSpreadRecord(buf[:0], priceData)
+	}
+}
+
+func BenchmarkCSVSpreadRecorder_Record(b *testing.B) {
+	recorder := NewCSVSpreadRecorder(b.TempDir())
+	defer recorder.Close(context.Background())
+
+	ctx := context.Background()
+	priceData := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Uic:       21,
+		Ticker:    "EURUSD",
+		AssetType: "FxSpot",
+		Bid:       1.10000,
+		Ask:       1.10002,
+		Spread:    0.00002,
+		Decimals:  5,
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		priceData.Sequence++
+		if err := recorder.Record(ctx, priceData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCSVSpreadRecorder_SpreadUnit(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+
+	priceData := &domain.PriceData{
+		Timestamp:  time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Ticker:     "USDJPY",
+		Bid:        155.123,
+		Ask:        155.137,
+		Decimals:   3,
+		SpreadUnit: domain.SpreadUnitPips,
+		PipSize:    0.01,
+	}
+	priceData.CalculateSpread()
+
+	if err := recorder.Record(context.Background(), priceData); err != nil {
+		t.Fatalf("Failed to record price: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	content, err := os.ReadFile(tmpDir + "/20251118/USDJPY_12.csv")
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(content), ",155.123,155.137,1.4,0,,0,pips,,,\n") {
+		t.Errorf("Expected spread of 1.4 pips, got:\n%s", content)
+	}
+}
+
+func TestCSVSpreadRecorder_SpreadDecimals(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+
+	priceData := &domain.PriceData{
+		Timestamp:      time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Ticker:         "EURUSD",
+		Bid:            1.084510,
+		Ask:            1.084514,
+		Decimals:       5,
+		SpreadDecimals: 6,
+	}
+	priceData.CalculateSpread()
+
+	if err := recorder.Record(context.Background(), priceData); err != nil {
+		t.Fatalf("Failed to record price: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	path := tmpDir + "/20251118/EURUSD_12.csv"
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	// At the price decimals the spread would be 0.00000
+	if !strings.Contains(string(content), ",1.08451,1.08451,0.000004,") {
+		t.Errorf("Expected spread 0.000004, got:\n%s", content)
+	}
+
+	var read *domain.PriceData
+	if err := ReadSpreadFile(path, func(p *domain.PriceData) error { read = p; return nil }); err != nil {
+		t.Fatalf("ReadSpreadFile failed: %v", err)
+	}
+	if read.Spread != 0.000004 || read.SpreadDecimals != 6 {
+		t.Errorf("Expected spread 0.000004 at 6 decimals, got %v at %d", read.Spread, read.SpreadDecimals)
+	}
+}
+
 func TestCSVSpreadRecorder_RecordBatch(t *testing.T) {
 	tmpDir := t.TempDir()
 	recorder := NewCSVSpreadRecorder(tmpDir)
-	defer recorder.Close()
+	defer recorder.Close(context.Background())
 
 	// Test batch data
 	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
@@ -127,7 +311,7 @@ func TestCSVSpreadRecorder_RecordBatch(t *testing.T) {
 func TestCSVSpreadRecorder_MultipleFlushes(t *testing.T) {
 	tmpDir := t.TempDir()
 	recorder := NewCSVSpreadRecorder(tmpDir)
-	defer recorder.Close()
+	defer recorder.Close(context.Background())
 
 	ctx := context.Background()
 	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
@@ -162,3 +346,461 @@ func TestCSVSpreadRecorder_MultipleFlushes(t *testing.T) {
 
 	t.Logf("Final file content:\n%s", string(content))
 }
+
+func TestCSVSpreadRecorder_PurgeOldestDay(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	defer recorder.Close(context.Background())
+
+	for _, day := range []string{"20251116", "20251117", "20251118"} {
+		if err := os.MkdirAll(tmpDir+"/"+day, 0755); err != nil {
+			t.Fatalf("Failed to create day dir: %v", err)
+		}
+	}
+
+	// Oldest days go first, the most recent day is always kept
+	for _, want := range []string{"20251116", "20251117", ""} {
+		removed, err := recorder.PurgeOldestDay()
+		if err != nil {
+			t.Fatalf("Purge failed: %v", err)
+		}
+		if want == "" {
+			if removed != "" {
+				t.Fatalf("Expected nothing to purge, removed %s", removed)
+			}
+			continue
+		}
+		if removed != tmpDir+"/"+want {
+			t.Fatalf("Expected %s to be purged, got %s", want, removed)
+		}
+	}
+
+	if _, err := os.Stat(tmpDir + "/20251118"); err != nil {
+		t.Fatalf("Most recent day was removed: %v", err)
+	}
+}
+
+// recordRun records ticks with a fresh recorder and closes it, like one collector run
+func recordRun(t *testing.T, dir string, timestamps ...time.Time) {
+	t.Helper()
+	recorder := NewCSVSpreadRecorder(dir)
+	for _, ts := range timestamps {
+		data := &domain.PriceData{Timestamp: ts, Uic: 21, Ticker: "EURUSD", AssetType: "FxSpot", Bid: 1.1, Ask: 1.1002, Decimals: 4}
+		data.CalculateSpread()
+		if err := recorder.Record(context.Background(), data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+}
+
+// readSpreadLines returns the lines of a spread file and fails unless it has exactly one header
+func readSpreadLines(t *testing.T, path string) []string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if headers := strings.Count(string(content), "timestamp,uic"); headers != 1 || !strings.HasPrefix(lines[0], "timestamp,uic") {
+		t.Fatalf("Expected a single header in %s, got %d:\n%s", path, headers, content)
+	}
+	return lines
+}
+
+func TestCSVSpreadRecorder_RestartAppends(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+
+	recordRun(t, tmpDir, base)
+	recordRun(t, tmpDir, base.Add(time.Minute), base.Add(2*time.Minute))
+
+	lines := readSpreadLines(t, filepath.Join(tmpDir, "20251118", "EURUSD_14.csv"))
+	if len(lines) != 4 {
+		t.Errorf("Expected header + 3 rows, got %d lines", len(lines))
+	}
+}
+
+func TestCSVSpreadRecorder_RestartAtHourBoundary(t *testing.T) {
+	tmpDir := t.TempDir()
+	lastOfHour := time.Date(2025, 11, 18, 14, 59, 59, 0, time.UTC)
+
+	recordRun(t, tmpDir, lastOfHour)
+	// The restarted run still receives a late tick for the previous hour before the new hour starts
+	recordRun(t, tmpDir, lastOfHour.Add(500*time.Millisecond), lastOfHour.Add(time.Second))
+
+	if lines := readSpreadLines(t, filepath.Join(tmpDir, "20251118", "EURUSD_14.csv")); len(lines) != 3 {
+		t.Errorf("Expected header + 2 rows in hour 14, got %d lines", len(lines))
+	}
+	if lines := readSpreadLines(t, filepath.Join(tmpDir, "20251118", "EURUSD_15.csv")); len(lines) != 2 {
+		t.Errorf("Expected header + 1 row in hour 15, got %d lines", len(lines))
+	}
+}
+
+func TestCSVSpreadRecorder_RestartAcrossDayRollover(t *testing.T) {
+	tmpDir := t.TempDir()
+	lastOfDay := time.Date(2025, 11, 18, 23, 59, 59, 0, time.UTC)
+
+	recordRun(t, tmpDir, lastOfDay.Add(-time.Second), lastOfDay)
+	recordRun(t, tmpDir, lastOfDay.Add(time.Second), lastOfDay.Add(2*time.Second))
+
+	if lines := readSpreadLines(t, filepath.Join(tmpDir, "20251118", "EURUSD_23.csv")); len(lines) != 3 {
+		t.Errorf("Expected header + 2 rows before midnight, got %d lines", len(lines))
+	}
+	if lines := readSpreadLines(t, filepath.Join(tmpDir, "20251119", "EURUSD_00.csv")); len(lines) != 3 {
+		t.Errorf("Expected header + 2 rows after midnight, got %d lines", len(lines))
+	}
+}
+
+func TestCSVSpreadRecorder_RestartRepairsFile(t *testing.T) {
+	header := strings.Join(spreadColumns, ",") + "\n"
+	row := "2025-11-18T14:00:00Z,21,EURUSD,FxSpot,1.1000,1.1002,0.0002,1,,0,\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		rows     int
+	}{
+		{"empty file", "", 1},
+		{"torn header", header[:10], 1},
+		{"torn last row", header + row + row[:20], 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			path := filepath.Join(tmpDir, "20251118", "EURUSD_14.csv")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+
+			recordRun(t, tmpDir, time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC))
+
+			lines := readSpreadLines(t, path)
+			if len(lines) != tt.rows+1 {
+				t.Fatalf("Expected header + %d rows, got:\n%s", tt.rows, strings.Join(lines, "\n"))
+			}
+			var ticks int
+			if err := ReadSpreadFile(path, func(*domain.PriceData) error { ticks++; return nil }); err != nil || ticks != tt.rows {
+				t.Errorf("Expected %d readable ticks, got %d (%v)", tt.rows, ticks, err)
+			}
+		})
+	}
+}
+
+func TestCSVSpreadRecorder_RestartWithOldSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldPath := filepath.Join(tmpDir, "20251118", "EURUSD_14.csv")
+	old := "timestamp,uic,ticker,asset_type,bid,ask,spread\n2025-11-18T14:00:00Z,21,EURUSD,FxSpot,1.1000,1.1002,0.0002\n"
+	if err := os.MkdirAll(filepath.Dir(oldPath), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(oldPath, []byte(old), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	recordRun(t, tmpDir, base)
+	recordRun(t, tmpDir, base.Add(time.Minute))
+
+	if content, _ := os.ReadFile(oldPath); string(content) != old {
+		t.Errorf("Old-schema file was modified:\n%s", content)
+	}
+	if lines := readSpreadLines(t, filepath.Join(tmpDir, "20251118", "EURUSD_14-2.csv")); len(lines) != 3 {
+		t.Errorf("Expected header + 2 rows in the continuation file, got %d lines", len(lines))
+	}
+
+	files, err := ListSpreadFiles(tmpDir, ArchiveFilter{Tickers: []string{"EURUSD"}})
+	if err != nil || len(files) != 2 {
+		t.Errorf("Expected both files in the archive listing, got %v (%v)", files, err)
+	}
+}
+
+func TestCSVSpreadRecorder_SyncEvery(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	recorder.SetSyncPolicy(SyncPolicy{Mode: SyncEvery, Records: 2})
+	defer recorder.Close(context.Background())
+
+	path := filepath.Join(tmpDir, "20251118", "EURUSD_14.csv")
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	for i := range 2 {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4}
+		if err := recorder.Record(context.Background(), data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+
+		// Nothing reaches the file before the policy is due - rows sit in the buffer
+		content, _ := os.ReadFile(path)
+		if lines := strings.Count(string(content), "\n"); lines != 3*i {
+			t.Errorf("After record %d: expected %d lines on disk, got %d", i+1, 3*i, lines)
+		}
+	}
+}
+
+func TestCSVSpreadRecorder_CloseAfterDeadline(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	recorder.SetSyncPolicy(SyncPolicy{Mode: SyncOnFlush})
+
+	data := &domain.PriceData{Timestamp: time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4}
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+
+	// A missed deadline skips the fsync, but rows are still written and files closed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, "20251118", "EURUSD_14.csv"))
+	if err != nil || strings.Count(string(content), "\n") != 2 {
+		t.Errorf("Expected header and one row, got %q, %v", content, err)
+	}
+	if len(recorder.files) != 0 {
+		t.Errorf("Expected all files closed, got %d open", len(recorder.files))
+	}
+}
+
+func TestCSVSpreadRecorder_RecordDuringFlush(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	defer recorder.Close(context.Background())
+
+	ctx := context.Background()
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	record := func(seq int) {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(seq) * time.Millisecond), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4, Sequence: uint64(seq)}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	record(1)
+
+	// A flush has swapped out the first row but not written it yet
+	recorder.mu.Lock()
+	writes := recorder.takePending()
+	recorder.mu.Unlock()
+
+	// Recording continues past a full buffer without waiting for the disk
+	for seq := 2; seq <= 200; seq++ {
+		record(seq)
+	}
+	path := filepath.Join(tmpDir, "20251118", "EURUSD_14.csv")
+	if content, _ := os.ReadFile(path); len(content) != 0 {
+		t.Fatalf("Expected nothing written before the pending flush, got %d bytes", len(content))
+	}
+
+	if err := writePending(writes, false); err != nil {
+		t.Fatalf("Failed to write pending rows: %v", err)
+	}
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 201 {
+		t.Fatalf("Expected header and 200 rows, got %d lines", len(lines))
+	}
+	for i, line := range lines[1:] {
+		if fields := strings.Split(line, ","); fields[7] != strconv.Itoa(i+1) {
+			t.Fatalf("Row %d out of order: %s", i+1, line)
+		}
+	}
+}
+
+func TestCSVSpreadRecorder_MaxOpenFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	recorder.SetMaxOpenFiles(2)
+
+	ctx := context.Background()
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	for i, ticker := range []string{"EURUSD", "USDJPY", "EURUSD", "GBPUSD", "USDJPY"} {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: ticker, Bid: 1.1, Ask: 1.1002, Decimals: 4, Sequence: uint64(i + 1)}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+		if open := recorder.OpenFiles(); open > 2 {
+			t.Fatalf("Expected at most 2 open files, got %d", open)
+		}
+	}
+	// GBPUSD evicted USDJPY (EURUSD was written later), and USDJPY then evicted EURUSD
+	if evictions := recorder.Evictions(); evictions != 2 {
+		t.Errorf("Expected 2 evictions, got %d", evictions)
+	}
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	// The reopened file was appended to, under a single header
+	content, err := os.ReadFile(filepath.Join(tmpDir, "20251118", "USDJPY_14.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "timestamp,") || !strings.Contains(lines[2], ",5,") {
+		t.Errorf("Expected header and rows 2 and 5, got:\n%s", content)
+	}
+}
+
+func TestCSVSpreadRecorder_LateTickKeepsCurrentHourOpen(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+
+	record := func(ticker string, at time.Time) {
+		t.Helper()
+		if err := recorder.Record(ctx, &domain.PriceData{Timestamp: at, Ticker: ticker, Bid: 1.1, Ask: 1.1002, Decimals: 4}); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	base := time.Date(2025, 11, 18, 14, 0, 5, 0, time.UTC)
+	record("EURUSD", base)
+	record("EUR", base) // Its keys share EURUSD's prefix
+	current := recorder.files["EURUSD_20251118_14"]
+
+	record("EURUSD", base.Add(-10*time.Second)) // Late tick of 13:59
+	record("EURUSD", base.Add(time.Second))
+	if recorder.files["EURUSD_20251118_14"] != current {
+		t.Error("Expected the late tick to leave the current hour's file open")
+	}
+	if recorder.OpenFiles() != 3 {
+		t.Errorf("Expected the files of 13:00 and 14:00 and EUR's open, got %d", recorder.OpenFiles())
+	}
+
+	// The next hour closes both of EURUSD's earlier hours
+	record("EURUSD", base.Add(time.Hour))
+	if _, ok := recorder.files["EURUSD_20251118_13"]; ok || recorder.OpenFiles() != 2 {
+		t.Errorf("Expected the files of 13:00 and 14:00 closed, got %d open", recorder.OpenFiles())
+	}
+	if _, ok := recorder.files["EUR_20251118_14"]; !ok {
+		t.Error("Expected EUR's file to stay open")
+	}
+}
+
+func TestCSVSpreadRecorder_PartialFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	dayDir := filepath.Join(tmpDir, "20251118")
+	// Left by a crashed run; its hour is long over
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(dayDir, "GBPUSD_09.csv"+PartialSuffix)
+	if err := os.WriteFile(leftover, []byte(strings.Join(spreadColumns, ",")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	if err := recorder.SetPartialFiles(true); err != nil {
+		t.Fatalf("Failed to enable partial files: %v", err)
+	}
+	var finalized []string
+	recorder.OnFinalize(func(path string) { finalized = append(finalized, filepath.Base(path)) })
+
+	ctx := context.Background()
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dayDir, "GBPUSD_09.csv")); err != nil {
+		t.Errorf("Expected leftover partial file to be finalized: %v", err)
+	}
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dayDir, name))
+		return err == nil
+	}
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	record := func(ts time.Time) {
+		data := &domain.PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+
+	record(base)
+	if !exists("EURUSD_14.csv.partial") || exists("EURUSD_14.csv") {
+		t.Error("Expected the file being written to only exist under its partial name")
+	}
+
+	// The next hour finalizes the previous one
+	record(base.Add(time.Hour))
+	if !exists("EURUSD_14.csv") || exists("EURUSD_14.csv.partial") {
+		t.Error("Expected EURUSD_14.csv to be finalized when the hour rotated")
+	}
+	if !exists("EURUSD_15.csv.partial") {
+		t.Error("Expected EURUSD_15.csv.partial to be written")
+	}
+
+	// A late tick doesn't change the finalized file, it starts the next numbered one
+	record(base.Add(15 * time.Minute))
+	if !exists("EURUSD_14-2.csv.partial") {
+		t.Error("Expected a late tick to be written to EURUSD_14-2.csv.partial")
+	}
+
+	// Partial files are readable while being written
+	files, err := ListSpreadFiles(tmpDir, ArchiveFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Errorf("Expected 4 listed files, got %d", len(files))
+	}
+
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if !exists("EURUSD_15.csv") || exists("EURUSD_15.csv.partial") {
+		t.Error("Expected EURUSD_15.csv to be finalized on close")
+	}
+	if !exists("EURUSD_14-2.csv") {
+		t.Error("Expected EURUSD_14-2.csv to be finalized on close")
+	}
+	slices.Sort(finalized[2:])
+	if want := []string{"GBPUSD_09.csv", "EURUSD_14.csv", "EURUSD_14-2.csv", "EURUSD_15.csv"}; !slices.Equal(finalized, want) {
+		t.Errorf("Expected finalized %v, got %v", want, finalized)
+	}
+}
+
+func TestCSVSpreadRecorder_PartialFilesOfCurrentHourSurviveRestart(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	path := filepath.Join(tmpDir, now.Format("20060102"), now.Format("EURUSD_15.csv"))
+
+	for seq := uint64(1); seq <= 2; seq++ {
+		recorder := NewCSVSpreadRecorder(tmpDir)
+		if err := recorder.SetPartialFiles(true); err != nil {
+			t.Fatal(err)
+		}
+		data := &domain.PriceData{Timestamp: now, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4, Sequence: seq}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+		if err := recorder.Close(ctx); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Fatal("Expected the current hour's file not to be finalized on close")
+		}
+	}
+
+	content, err := os.ReadFile(path + PartialSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 3 {
+		t.Errorf("Expected the second run to append to the partial file, got %d lines", lines)
+	}
+}
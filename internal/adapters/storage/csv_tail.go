@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// SpreadTail follows the hourly CSV files of one instrument while the collector writes them
+// Unlike tail -F on a single file, it moves on to the next hour's file (or a -N file after a schema
+// change) as soon as it appears, after reading what was left in the previous one. Files renamed
+// from .partial to their final name are followed through the rename
+type SpreadTail struct {
+	baseDir string
+	ticker  string
+
+	file    *os.File
+	hour    time.Time // Hour and number of the file being followed
+	number  int
+	columns map[string]int // Nil until the header was read
+	pending []byte         // Incomplete last line, completed by a later read
+	buf     []byte
+}
+
+// NewSpreadTail creates a tail of ticker's files under baseDir; nothing is opened before the first Poll
+func NewSpreadTail(baseDir, ticker string) *SpreadTail {
+	return &SpreadTail{baseDir: baseDir, ticker: ticker, buf: make([]byte, 64*1024)}
+}
+
+// Poll passes the ticks written since the last call to fn, moving on to newer files if there are any
+// The first call starts at the beginning of the newest file. Files are followed in hour and number
+// order, so a -N file written for a finalized hour is read before the files of later hours
+func (t *SpreadTail) Poll(fn func(*domain.PriceData) error) error {
+	for {
+		path, hour, number, ok, err := t.next(time.Now())
+		if err != nil {
+			return err
+		}
+		if t.file != nil {
+			// Drain the current file first, so no tick is skipped at the hour boundary
+			if err := t.read(fn); err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			t.Close()
+		}
+		if !ok {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) && strings.HasSuffix(path, PartialSuffix) {
+			// Finalized since it was listed
+			path = strings.TrimSuffix(path, PartialSuffix)
+			file, err = os.Open(path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		t.file, t.hour, t.number = file, hour, number
+	}
+}
+
+// Path returns the file being followed, or "" before the first file was found
+func (t *SpreadTail) Path() string {
+	if t.file == nil {
+		return ""
+	}
+	return t.file.Name()
+}
+
+// Close closes the file being followed
+func (t *SpreadTail) Close() error {
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file, t.columns, t.pending = nil, nil, t.pending[:0]
+	return err
+}
+
+// next finds the file to follow after the current one: the oldest file of a later hour or with a
+// higher number, or before the first file the newest one. Only the day directories of now and the
+// day before are listed
+func (t *SpreadTail) next(now time.Time) (path string, hour time.Time, number int, ok bool, err error) {
+	now = now.UTC()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		matches, err := filepath.Glob(filepath.Join(t.baseDir, day.Format("20060102"), t.ticker+"_*.csv*"))
+		if err != nil {
+			return "", time.Time{}, 0, false, fmt.Errorf("failed to list files of %s: %w", t.ticker, err)
+		}
+		for _, match := range matches {
+			if ticker, isSpread := spreadFileTicker(filepath.Base(match)); !isSpread || ticker != t.ticker {
+				continue
+			}
+			matchHour, isHourly := spreadFileHour(match)
+			if !isHourly {
+				continue
+			}
+			_, matchNumber := spreadFileNumber(match)
+			if t.file == nil {
+				if !ok || fileAfter(matchHour, matchNumber, hour, number) {
+					path, hour, number, ok = match, matchHour, matchNumber, true
+				}
+				continue
+			}
+			if fileAfter(matchHour, matchNumber, t.hour, t.number) && (!ok || fileAfter(hour, number, matchHour, matchNumber)) {
+				path, hour, number, ok = match, matchHour, matchNumber, true
+			}
+		}
+	}
+	return path, hour, number, ok, nil
+}
+
+// fileAfter reports whether the file of hour and number comes after that of otherHour and otherNumber
+func fileAfter(hour time.Time, number int, otherHour time.Time, otherNumber int) bool {
+	return hour.After(otherHour) || hour.Equal(otherHour) && number > otherNumber
+}
+
+// read passes the complete rows appended to the file since the last read to fn
+func (t *SpreadTail) read(fn func(*domain.PriceData) error) error {
+	for {
+		n, err := t.file.Read(t.buf)
+		t.pending = append(t.pending, t.buf[:n]...)
+		if errors.Is(err, io.EOF) || n == 0 {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", t.file.Name(), err)
+		}
+	}
+
+	end := bytes.LastIndexByte(t.pending, '\n')
+	if end < 0 {
+		return nil
+	}
+	reader := csv.NewReader(bytes.NewReader(t.pending[:end+1]))
+	reader.FieldsPerRecord = -1
+	defer func() {
+		t.pending = t.pending[:copy(t.pending, t.pending[end+1:])]
+	}()
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", t.file.Name(), err)
+		}
+		if t.columns == nil {
+			if t.columns, err = spreadColumnIndex(t.file.Name(), record); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := parseSpreadRecord(record, t.columns)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.file.Name(), err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestSpreadTail_FollowsHourRollover(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	if err := recorder.SetPartialFiles(true); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	current := time.Now().UTC().Truncate(time.Hour)
+	record := func(ts time.Time, seq uint64) {
+		data := &domain.PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4, Sequence: seq}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+
+	tail := NewSpreadTail(tmpDir, "EURUSD")
+	defer tail.Close()
+	var seqs []uint64
+	poll := func() {
+		if err := tail.Poll(func(data *domain.PriceData) error {
+			seqs = append(seqs, data.Sequence)
+			return nil
+		}); err != nil {
+			t.Fatalf("Poll failed: %v", err)
+		}
+	}
+
+	poll() // Nothing written yet
+	record(current.Add(-time.Minute), 1)
+	record(current.Add(-time.Minute+time.Second), 2)
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	poll()
+
+	// The previous hour gets one more tick before the file rotates
+	record(current.Add(-time.Second), 3)
+	record(current, 4)
+	record(current.Add(time.Second), 5)
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	poll()
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	poll() // The current hour stays partial: nothing new, nothing twice
+
+	if len(seqs) != 5 {
+		t.Fatalf("Expected 5 ticks, got %v", seqs)
+	}
+	for i, seq := range seqs {
+		if seq != uint64(i+1) {
+			t.Errorf("Expected ticks in order, got %v", seqs)
+			break
+		}
+	}
+	if want := current.Format("_15.csv.partial"); !strings.HasSuffix(tail.Path(), want) {
+		t.Errorf("Expected to follow the current hour's file, got %s", tail.Path())
+	}
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// ReadSpreadFile streams the ticks of one hourly CSV file to fn
+// Columns are located by header name, so files written before a column was added still parse;
+// files from a newer schema version fail with ErrNewerSchema
+// Decimals is inferred from the precision the bid was written with
+func ReadSpreadFile(path string, fn func(*domain.PriceData) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && strings.HasSuffix(path, PartialSuffix) {
+		// Finalized since the directory was listed
+		path = strings.TrimSuffix(path, PartialSuffix)
+		file, err = os.Open(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+	columns, err := spreadColumnIndex(path, header)
+	if err != nil {
+		return err
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		data, err := parseSpreadRecord(record, columns)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+}
+
+// spreadColumnIndex maps the column names of an hourly file's header to their positions
+func spreadColumnIndex(path string, header []string) (map[string]int, error) {
+	if _, err := spreadSchemaOf(path, header); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"timestamp", "ticker", "bid", "ask"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s: missing column %q", path, required)
+		}
+	}
+	return columns, nil
+}
+
+// parseSpreadRecord converts one CSV row into price data
+func parseSpreadRecord(record []string, columns map[string]int) (*domain.PriceData, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, field("timestamp"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	bidStr := field("bid")
+	bid, err := strconv.ParseFloat(bidStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bid: %w", err)
+	}
+	ask, err := strconv.ParseFloat(field("ask"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ask: %w", err)
+	}
+
+	data := &domain.PriceData{
+		Timestamp:    timestamp,
+		Ticker:       field("ticker"),
+		AssetType:    field("asset_type"),
+		Bid:          bid,
+		Ask:          ask,
+		SessionLabel: field("session"),
+		Source:       field("source"),
+	}
+	if i := strings.IndexByte(bidStr, '.'); i >= 0 {
+		data.Decimals = len(bidStr) - i - 1
+	}
+	if uic := field("uic"); uic != "" {
+		if data.Uic, err = strconv.Atoi(uic); err != nil {
+			return nil, fmt.Errorf("invalid uic: %w", err)
+		}
+	}
+	if flags := field("flags"); flags != "" {
+		value, err := strconv.ParseUint(flags, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flags: %w", err)
+		}
+		data.Flags = domain.TickFlags(value)
+	}
+	if effective := field("effective_spread"); effective != "" {
+		if data.EffectiveSpread, err = strconv.ParseFloat(effective, 64); err != nil {
+			return nil, fmt.Errorf("invalid effective_spread: %w", err)
+		}
+	}
+	if z := field("spread_z"); z != "" {
+		if data.SpreadZ, err = strconv.ParseFloat(z, 64); err != nil {
+			return nil, fmt.Errorf("invalid spread_z: %w", err)
+		}
+	}
+	if seq := field("seq"); seq != "" {
+		if data.Sequence, err = strconv.ParseUint(seq, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid seq: %w", err)
+		}
+	}
+
+	// Recompute rather than parse: the stored spread is rounded like the prices
+	data.Spread = roundPrice(ask-bid, data.Decimals)
+	// unless it was written with more decimals than the prices (spreadDecimals) or clamped to zero
+	if data.Flags.Has(domain.FlagClamped) {
+		data.Spread = 0
+	}
+	if unit := field("spread_unit"); unit == "" || unit == string(domain.SpreadUnitPrice) {
+		spreadStr := field("spread")
+		if i := strings.IndexByte(spreadStr, '.'); i >= 0 && len(spreadStr)-i-1 > data.Decimals {
+			if data.Spread, err = strconv.ParseFloat(spreadStr, 64); err != nil {
+				return nil, fmt.Errorf("invalid spread: %w", err)
+			}
+			data.SpreadDecimals = len(spreadStr) - i - 1
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestReadSpreadFile_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+
+	written := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 123000000, time.UTC),
+		Uic:       42,
+		Ticker:    "USDJPY",
+		AssetType: "FxSpot",
+		Bid:       155.123,
+		Ask:       155.137,
+		Decimals:  3,
+		Sequence:  7,
+
+		SessionLabel:    "tokyo+london",
+		Flags:           domain.FlagRollover,
+		EffectiveSpread: 0.01925,
+		SpreadZ:         -1.256,
+	}
+	written.CalculateSpread()
+
+	ctx := context.Background()
+	if err := recorder.Record(ctx, written); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	var read []*domain.PriceData
+	err := ReadSpreadFile(filepath.Join(tmpDir, "20251118", "USDJPY_12.csv"), func(p *domain.PriceData) error {
+		read = append(read, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSpreadFile failed: %v", err)
+	}
+	if len(read) != 1 {
+		t.Fatalf("Expected 1 tick, got %d", len(read))
+	}
+
+	got := read[0]
+	if !got.Timestamp.Equal(written.Timestamp) || got.Uic != 42 || got.Sequence != 7 || got.Decimals != 3 {
+		t.Errorf("Unexpected tick: %+v", got)
+	}
+	if got.SessionLabel != "tokyo+london" {
+		t.Errorf("Expected session tokyo+london, got %q", got.SessionLabel)
+	}
+	if got.Flags != domain.FlagRollover {
+		t.Errorf("Expected rollover flag, got %v", got.Flags)
+	}
+	if got.Spread != 0.014 {
+		t.Errorf("Expected spread 0.014, got %v", got.Spread)
+	}
+	if got.EffectiveSpread != 0.0193 {
+		t.Errorf("Expected effective spread 0.0193 (one digit finer than prices), got %v", got.EffectiveSpread)
+	}
+	if got.SpreadZ != -1.26 {
+		t.Errorf("Expected spread z-score -1.26, got %v", got.SpreadZ)
+	}
+}
+
+func TestReadSpreadFile_LegacyHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "EURUSD_12.csv")
+	content := "timestamp,uic,ticker,asset_type,bid,ask,spread\n" +
+		"2025-11-18T12:00:00Z,21,EURUSD,FxSpot,1.08450,1.08452,0.00002\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	count := 0
+	err := ReadSpreadFile(path, func(p *domain.PriceData) error {
+		count++
+		if p.Sequence != 0 || p.Decimals != 5 {
+			t.Errorf("Unexpected tick: %+v", p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSpreadFile failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 tick, got %d", count)
+	}
+}
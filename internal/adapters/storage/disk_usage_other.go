@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package storage
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// DiskUsage is not supported on this platform
+func DiskUsage(path string) (free, total uint64, err error) {
+	return 0, 0, fmt.Errorf("disk usage not supported on %s", runtime.GOOS)
+}
+
+// OpenFileLimit is not supported on this platform
+func OpenFileLimit() (uint64, error) {
+	return 0, fmt.Errorf("open file limit not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DiskUsage returns free (available to unprivileged users) and total bytes of the filesystem holding path
+func DiskUsage(path string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &stat); err != nil {
+		return 0, 0, fmt.Errorf("failed to stat filesystem for %s: %w", path, err)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
+
+// OpenFileLimit returns the process's soft limit on open file descriptors (ulimit -n)
+func OpenFileLimit() (uint64, error) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, fmt.Errorf("failed to get open file limit: %w", err)
+	}
+	return uint64(limit.Cur), nil
+}
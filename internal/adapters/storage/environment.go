@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// EnvironmentTagFile marks the trading environment (sim or live) a data directory holds
+const EnvironmentTagFile = ".environment"
+
+// Environments are the trading environments a directory can be tagged with
+var Environments = []string{"sim", "live"}
+
+// ParseEnvironment returns the environment named by value (SAXO_ENVIRONMENT, default sim) and
+// whether it was given explicitly
+func ParseEnvironment(value string) (env string, explicit bool, err error) {
+	if value == "" {
+		return "sim", false, nil
+	}
+	env = strings.ToLower(value)
+	if !slices.Contains(Environments, env) {
+		return "", false, fmt.Errorf("invalid SAXO_ENVIRONMENT '%s': must be sim or live", value)
+	}
+	return env, true, nil
+}
+
+// TagEnvironment checks that dir may receive data of env (see CheckEnvironment) and tags it on first use
+func TagEnvironment(dir, env string, explicit bool) error {
+	if err := CheckEnvironment(dir, env, explicit); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, EnvironmentTagFile)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, []byte(env+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to tag %s: %w", dir, err)
+	}
+	return nil
+}
+
+// CheckEnvironment returns an error if dir holds data of another environment than env, without tagging it
+// A directory tagged with another environment, or with the other environment's name as a path
+// element (data/sim/spreads for live), is refused so the datasets never mix. An untagged directory
+// already holding data is only accepted if env was given explicitly, so a defaulted environment
+// never claims data recorded before directories were tagged
+func CheckEnvironment(dir, env string, explicit bool) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	for _, element := range strings.Split(filepath.ToSlash(absDir), "/") {
+		for _, other := range Environments {
+			if other != env && strings.EqualFold(element, other) {
+				return fmt.Errorf("refusing to write %s data to %s: its path names the %s environment", env, dir, other)
+			}
+		}
+	}
+
+	path := filepath.Join(dir, EnvironmentTagFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if entries, _ := os.ReadDir(dir); len(entries) > 0 && !explicit {
+			return fmt.Errorf("refusing to write %s data to %s: it holds untagged data; set SAXO_ENVIRONMENT to the environment it was recorded in", env, dir)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if tagged := strings.TrimSpace(string(data)); tagged != env {
+		return fmt.Errorf("refusing to write %s data to %s: it is tagged %s (%s)", env, dir, tagged, path)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTagEnvironment(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spreads")
+
+	if err := TagEnvironment(dir, "live", true); err != nil {
+		t.Fatalf("Failed to tag new directory: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, EnvironmentTagFile))
+	if err != nil || strings.TrimSpace(string(data)) != "live" {
+		t.Fatalf("Expected tag live, got %q (%v)", data, err)
+	}
+
+	if err := TagEnvironment(dir, "live", true); err != nil {
+		t.Errorf("Same environment should be accepted: %v", err)
+	}
+	if err := TagEnvironment(dir, "sim", true); err == nil || !strings.Contains(err.Error(), "tagged live") {
+		t.Errorf("Expected sim to be refused for a live directory, got %v", err)
+	}
+}
+
+func TestTagEnvironment_PathName(t *testing.T) {
+	base := t.TempDir()
+
+	if err := TagEnvironment(filepath.Join(base, "SIM", "spreads"), "live", true); err == nil {
+		t.Error("Expected live to be refused for a path naming sim")
+	}
+	if err := TagEnvironment(filepath.Join(base, "sim", "spreads"), "sim", true); err != nil {
+		t.Errorf("Expected sim path to be accepted for sim: %v", err)
+	}
+	if err := TagEnvironment(filepath.Join(base, "simulations"), "live", true); err != nil {
+		t.Errorf("Only whole path elements should count: %v", err)
+	}
+}
+
+func TestTagEnvironment_UntaggedData(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "20250303_EURUSD.csv"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recorded before directories were tagged: a defaulted environment must not claim it
+	if err := TagEnvironment(dir, "sim", false); err == nil || !strings.Contains(err.Error(), "untagged data") {
+		t.Errorf("Expected untagged data to be refused without an explicit environment, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, EnvironmentTagFile)); err == nil {
+		t.Error("Expected the directory to stay untagged")
+	}
+	if err := TagEnvironment(dir, "live", true); err != nil {
+		t.Fatalf("Expected an explicit environment to tag the directory: %v", err)
+	}
+	if err := TagEnvironment(dir, "live", false); err != nil {
+		t.Errorf("Expected the tagged directory to be accepted: %v", err)
+	}
+	if err := TagEnvironment(filepath.Join(t.TempDir(), "spreads"), "sim", false); err != nil {
+		t.Errorf("Expected a new directory to be tagged with the default: %v", err)
+	}
+}
@@ -0,0 +1,447 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// replayBatchSize is the number of spooled ticks written to the primary per batch on reconcile
+const replayBatchSize = 1000
+
+// spoolHandoverSize is the spool size below which reconcile replays the rest with writes held,
+// before switching back to the primary; larger spools are replayed while writes go on
+const spoolHandoverSize = 256 << 10
+
+// FallbackConfig configures a FallbackRecorder
+type FallbackConfig struct {
+	Name          string        // Primary sink name, for logs and events
+	SpoolPath     string        // NDJSON file keeping the ticks the primary missed, until they are replayed
+	ProbeInterval time.Duration // How often the primary is retried while failed over
+
+	// Events receives a storage_failover event when writes fail over and storage_recovered once
+	// the primary has caught up (optional; sends never block)
+	Events chan<- domain.Event
+}
+
+// unwrittenKeeper is implemented by sinks that hold on to the rows a failed write or flush didn't get
+// to storage and write them first on the next flush, so the ticks they accepted must not be spooled
+// or replayed again
+type unwrittenKeeper interface {
+	keepsUnwritten()
+}
+
+// FallbackRecorder writes to a primary sink and fails over to a fallback sink when it fails
+// Ticks the primary accepted are kept until its next successful Flush, unless the primary keeps them
+// itself (unwrittenKeeper); when a write or a flush fails, they go to a spool file and from then on
+// every tick goes to the fallback and the spool
+// Every ProbeInterval a background goroutine replays the spool into the primary (reconcile) and,
+// once the primary has flushed it, switches writing back. A spool left by a previous run is
+// replayed the same way
+type FallbackRecorder struct {
+	primary  ports.TickWriter
+	fallback ports.TickWriter
+	cfg      FallbackConfig
+	keeps    bool // The primary keeps the ticks it accepted through failures
+
+	mu         sync.Mutex
+	failedOver bool
+	unflushed  []*domain.PriceData // Accepted by the primary since its last successful flush
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewFallbackRecorder creates a recorder failing over from primary to fallback
+func NewFallbackRecorder(primary, fallback ports.TickWriter, cfg FallbackConfig) (*FallbackRecorder, error) {
+	if cfg.SpoolPath == "" {
+		return nil, fmt.Errorf("fallback recorder for %s needs a spool path", cfg.Name)
+	}
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &FallbackRecorder{primary: primary, fallback: fallback, cfg: cfg, cancel: cancel, done: make(chan struct{})}
+	_, r.keeps = As[unwrittenKeeper](primary)
+
+	// Ticks spooled by a previous run are replayed with the first probe
+	for _, path := range []string{r.replayPath(), cfg.SpoolPath} {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			r.failedOver = true
+			log.Printf("FallbackRecorder: %s has a spool from a previous run (%s, %d bytes), replaying with the first probe", cfg.Name, path, info.Size())
+		}
+	}
+	go r.probe(ctx)
+	return r, nil
+}
+
+// Record saves a single price data point
+func (r *FallbackRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	return r.RecordBatch(ctx, []*domain.PriceData{data})
+}
+
+// RecordBatch writes to the primary, or to the fallback and the spool while the primary is failing
+func (r *FallbackRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failedOver {
+		return r.writeFallback(ctx, data)
+	}
+	if err := r.primary.RecordBatch(ctx, data); err != nil {
+		// The ticks the primary accepted before failing are its own to write
+		var partial *ports.PartialWriteError
+		if errors.As(err, &partial) {
+			r.trackUnflushed(data[:min(partial.Written, len(data))])
+			data = data[min(partial.Written, len(data)):]
+		}
+		r.failOver(err)
+		return r.writeFallback(ctx, data)
+	}
+	r.trackUnflushed(data)
+	return nil
+}
+
+// trackUnflushed keeps ticks the primary accepted until its next successful flush, unless it keeps
+// them itself; r.mu is held
+func (r *FallbackRecorder) trackUnflushed(data []*domain.PriceData) {
+	if _, ok := r.primary.(ports.Flusher); ok && !r.keeps {
+		r.unflushed = append(r.unflushed, data...)
+	}
+}
+
+// Flush flushes both sinks; a failing primary flush fails over, since buffered sinks report errors here
+func (r *FallbackRecorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	if flusher, ok := r.primary.(ports.Flusher); ok && !r.failedOver {
+		if err := flusher.Flush(ctx); err != nil {
+			r.failOver(err)
+			errs = append(errs, fmt.Errorf("%s flush failed, failing over: %w", r.cfg.Name, err))
+		} else {
+			r.unflushed = nil
+		}
+	}
+	if flusher, ok := r.fallback.(ports.Flusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops probing and closes both sinks; the spool stays for the next run if the primary hasn't caught up
+func (r *FallbackRecorder) Close(ctx context.Context) error {
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the replay into %s: %w", r.cfg.Name, ctx.Err())
+	}
+
+	var errs []error
+	for _, sink := range []ports.TickWriter{r.primary, r.fallback} {
+		if closer, ok := sink.(ports.Closer); ok {
+			if err := closer.Close(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Members returns the primary and the fallback sink
+func (r *FallbackRecorder) Members() []ports.TickWriter {
+	return []ports.TickWriter{r.primary, r.fallback}
+}
+
+// FailedOver reports whether writes currently go to the fallback
+func (r *FallbackRecorder) FailedOver() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failedOver
+}
+
+// failOver switches writes to the fallback; r.mu is held
+// The ticks the primary hasn't confirmed with a flush are spooled, since they may be lost in its buffer
+func (r *FallbackRecorder) failOver(cause error) {
+	if r.failedOver {
+		return
+	}
+	r.failedOver = true
+	if len(r.unflushed) > 0 {
+		if err := appendSpool(r.cfg.SpoolPath, r.unflushed); err != nil {
+			log.Printf("FallbackRecorder: %d unflushed ticks of %s lost: %v", len(r.unflushed), r.cfg.Name, err)
+		}
+		r.unflushed = nil
+	}
+	msg := fmt.Sprintf("Sink %s failed, writing to its fallback until it recovers: %v", r.cfg.Name, cause)
+	log.Printf("FallbackRecorder: %s", msg)
+	r.sendEvent(domain.EventStorageFailover, domain.SeverityWarning, msg)
+}
+
+// writeFallback writes ticks to the fallback sink and the spool; r.mu is held
+func (r *FallbackRecorder) writeFallback(ctx context.Context, data []*domain.PriceData) error {
+	var errs []error
+	if err := r.fallback.RecordBatch(ctx, data); err != nil {
+		errs = append(errs, fmt.Errorf("fallback of %s failed: %w", r.cfg.Name, err))
+	}
+	if err := appendSpool(r.cfg.SpoolPath, data); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// probe reconciles every ProbeInterval while failed over, until ctx is cancelled by Close
+func (r *FallbackRecorder) probe(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.cfg.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.FailedOver() {
+				continue
+			}
+			if err := r.reconcile(ctx); err != nil {
+				log.Printf("FallbackRecorder: %s still failing: %v", r.cfg.Name, err)
+			}
+		}
+	}
+}
+
+// replayPath is where the spool is moved while it is replayed, so new ticks start a fresh spool
+func (r *FallbackRecorder) replayPath() string {
+	return r.cfg.SpoolPath + ".replay"
+}
+
+// reconcile replays the spool into the primary and switches writing back to it
+// Large spools are replayed while writes go on to the fallback and a fresh spool; the rest, once
+// small, is replayed with writes held, so the primary receives every tick in order
+func (r *FallbackRecorder) reconcile(ctx context.Context) error {
+	replayed := 0
+	for {
+		r.mu.Lock()
+		size, err := r.moveSpool()
+		if err != nil {
+			r.mu.Unlock()
+			return err
+		}
+		if size < spoolHandoverSize {
+			defer r.mu.Unlock()
+			n, err := r.replay(ctx, r.replayPath(), r.cfg.SpoolPath)
+			replayed += n
+			if err != nil {
+				return err
+			}
+			r.failedOver = false
+			msg := fmt.Sprintf("Sink %s recovered, %d missed ticks replayed from the spool", r.cfg.Name, replayed)
+			log.Printf("FallbackRecorder: %s", msg)
+			r.sendEvent(domain.EventStorageRecovered, domain.SeverityInfo, msg)
+			return nil
+		}
+		r.mu.Unlock()
+
+		n, err := r.replay(ctx, r.replayPath())
+		replayed += n
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// moveSpool moves the spool to the replay file unless one is left from an interrupted replay,
+// and returns the size of what is left to replay; r.mu is held
+func (r *FallbackRecorder) moveSpool() (int64, error) {
+	if _, err := os.Stat(r.replayPath()); err == nil {
+		return fileSize(r.replayPath()) + fileSize(r.cfg.SpoolPath), nil
+	}
+	if err := os.Rename(r.cfg.SpoolPath, r.replayPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("failed to move spool %s: %w", r.cfg.SpoolPath, err)
+	}
+	return fileSize(r.replayPath()), nil
+}
+
+// replay writes spool files into the primary in order, removing each once the primary has flushed it
+// The primary isn't written to by anyone else meanwhile; the spool itself only with r.mu held
+// On failure, the ticks the primary accepted (ports.PartialWriteError included) are cut from the file
+// once it has flushed them, or right away if it keeps them itself, so the next probe resumes after
+// them without writing them twice or losing ticks still in the primary's buffer
+func (r *FallbackRecorder) replay(ctx context.Context, paths ...string) (int, error) {
+	total := 0
+	for _, path := range paths {
+		replayed := 0
+		err := readSpool(path, replayBatchSize, func(batch []*domain.PriceData) error {
+			err := r.primary.RecordBatch(ctx, batch)
+			var partial *ports.PartialWriteError
+			if errors.As(err, &partial) {
+				replayed += min(partial.Written, len(batch))
+			} else if err == nil {
+				replayed += len(batch)
+			}
+			return err
+		})
+		// Also flushed with nothing replayed: a primary keeping ticks may still hold some
+		if flusher, ok := r.primary.(ports.Flusher); ok && (replayed > 0 || r.keeps) {
+			if flushErr := flusher.Flush(ctx); flushErr != nil {
+				if !r.keeps {
+					return total, errors.Join(err, flushErr)
+				}
+				err = errors.Join(err, flushErr)
+			}
+		}
+		total += replayed
+		if err != nil {
+			if replayed > 0 {
+				if trimErr := trimSpool(path, replayed); trimErr != nil {
+					log.Printf("FallbackRecorder: %v", trimErr)
+				}
+			}
+			return total, err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return total, fmt.Errorf("failed to remove spool %s: %w", path, err)
+		}
+	}
+	return total, nil
+}
+
+// fileSize returns the size of a file, 0 if it doesn't exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// sendEvent passes an event to the service without blocking the recording goroutine
+func (r *FallbackRecorder) sendEvent(eventType domain.EventType, severity domain.Severity, msg string) {
+	if r.cfg.Events == nil {
+		return
+	}
+	event := domain.NewEvent(eventType, severity, msg)
+	event.Fields = map[string]string{"sink": r.cfg.Name}
+	select {
+	case r.cfg.Events <- event:
+	default:
+		log.Printf("FallbackRecorder: event channel full, dropped %s event", eventType)
+	}
+}
+
+// spoolEntry is one spooled tick; PipSize and SpreadDecimals aren't part of the tick's JSON but sinks
+// need them for pip spreads and spread precision
+type spoolEntry struct {
+	*domain.PriceData
+	PipSize        float64 `json:"pip_size,omitempty"`
+	SpreadDecimals int     `json:"spread_decimals,omitempty"`
+}
+
+// appendSpool appends ticks to the spool file and syncs it, as the spool is the copy the primary gets later
+func appendSpool(path string, data []*domain.PriceData) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open spool %s: %w", path, err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, priceData := range data {
+		if err := encoder.Encode(spoolEntry{PriceData: priceData, PipSize: priceData.PipSize, SpreadDecimals: priceData.SpreadDecimals}); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write spool %s: %w", path, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write spool %s: %w", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync spool %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// readSpool passes the spooled ticks to fn in batches of up to size; a missing spool is empty
+// A line cut off by a crash is skipped
+func readSpool(path string, size int, fn func([]*domain.PriceData) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open spool %s: %w", path, err)
+	}
+	defer file.Close()
+
+	batch := make([]*domain.PriceData, 0, size)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := spoolEntry{PriceData: &domain.PriceData{}}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entry.PriceData.PipSize = entry.PipSize
+		entry.PriceData.SpreadDecimals = entry.SpreadDecimals
+		batch = append(batch, entry.PriceData)
+		if len(batch) == size {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]*domain.PriceData, 0, size)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read spool %s: %w", path, err)
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// trimSpool removes the first n ticks from the spool, rewriting it through a temporary file
+func trimSpool(path string, n int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read spool %s: %w", path, err)
+	}
+
+	// Count ticks the way readSpool does, skipping lines it skips
+	rest := data
+	for n > 0 && len(rest) > 0 {
+		line, after, _ := bytes.Cut(rest, []byte{'\n'})
+		if json.Valid(line) {
+			n--
+		}
+		rest = after
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, rest, 0644); err != nil {
+		return fmt.Errorf("failed to trim spool %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to trim spool %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// switchableRecorder fails while down is set, or once it holds failAfter ticks, and keeps what it accepted
+type switchableRecorder struct {
+	down      bool
+	failAfter int
+	ticks     []*domain.PriceData
+}
+
+func (r *switchableRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	return r.RecordBatch(ctx, []*domain.PriceData{data})
+}
+
+func (r *switchableRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	if r.down || (r.failAfter > 0 && len(r.ticks) >= r.failAfter) {
+		return errors.New("connection refused")
+	}
+	r.ticks = append(r.ticks, data...)
+	return nil
+}
+
+func TestFallbackRecorder_FailsOverAndReconciles(t *testing.T) {
+	primary, fallback := &switchableRecorder{}, &switchableRecorder{}
+	events := make(chan domain.Event, 4)
+	spool := filepath.Join(t.TempDir(), "fallback_db.ndjson")
+	recorder, err := NewFallbackRecorder(primary, fallback, FallbackConfig{Name: "db", SpoolPath: spool, ProbeInterval: time.Hour, Events: events})
+	if err != nil {
+		t.Fatalf("NewFallbackRecorder failed: %v", err)
+	}
+	ctx := context.Background()
+	tick := func(bid float64) *domain.PriceData {
+		return &domain.PriceData{Ticker: "USDJPY", Bid: bid, Ask: bid + 0.01, Spread: 0.01, PipSize: 0.01, SpreadDecimals: 3}
+	}
+
+	defer recorder.Close(ctx)
+
+	recorder.Record(ctx, tick(150.00))
+	primary.down = true
+	if err := recorder.Record(ctx, tick(150.01)); err != nil {
+		t.Fatalf("Expected the fallback to take the write, got %v", err)
+	}
+	primary.down = false
+	recorder.Record(ctx, tick(150.02)) // Not reconciled yet: still the fallback
+
+	if !recorder.FailedOver() || len(primary.ticks) != 1 || len(fallback.ticks) != 2 {
+		t.Fatalf("Expected 1 primary and 2 fallback ticks, got %d and %d", len(primary.ticks), len(fallback.ticks))
+	}
+	if event := <-events; event.Type != domain.EventStorageFailover || event.Fields["sink"] != "db" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// The next probe replays the spool, then writing switches back
+	if err := recorder.reconcile(ctx); err != nil || recorder.FailedOver() {
+		t.Fatalf("Expected the primary to be back, got %v", err)
+	}
+	recorder.Record(ctx, tick(150.03))
+	var bids []float64
+	for _, tick := range primary.ticks {
+		bids = append(bids, tick.Bid)
+	}
+	if len(bids) != 4 || bids[1] != 150.01 || bids[2] != 150.02 || bids[3] != 150.03 {
+		t.Errorf("Unexpected primary ticks %v", bids)
+	}
+	if primary.ticks[1].PipSize != 0.01 || primary.ticks[1].SpreadDecimals != 3 {
+		t.Errorf("Expected the pip size and spread decimals to survive the spool, got %+v", primary.ticks[1])
+	}
+	if _, err := os.Stat(spool); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the spool to be removed, got %v", err)
+	}
+	if event := <-events; event.Type != domain.EventStorageRecovered {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
+
+func TestFallbackRecorder_ReplaysSpoolOfPreviousRun(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "fallback_db.ndjson")
+	if err := appendSpool(spool, []*domain.PriceData{{Ticker: "EURUSD", Bid: 1.1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	primary := &switchableRecorder{}
+	recorder, _ := NewFallbackRecorder(primary, &switchableRecorder{}, FallbackConfig{Name: "db", SpoolPath: spool})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+	if !recorder.FailedOver() {
+		t.Fatal("Expected a leftover spool to start failed over")
+	}
+	recorder.Record(ctx, &domain.PriceData{Ticker: "EURUSD", Bid: 1.2})
+	if err := recorder.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if len(primary.ticks) != 2 || primary.ticks[0].Bid != 1.1 {
+		t.Errorf("Expected the spooled tick first, got %+v", primary.ticks)
+	}
+}
+
+func TestFallbackRecorder_ResumesInterruptedReplay(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "fallback_db.ndjson")
+	var spooled []*domain.PriceData
+	for i := range replayBatchSize + 500 {
+		spooled = append(spooled, &domain.PriceData{Ticker: "EURUSD", Sequence: uint64(i + 1)})
+	}
+	if err := appendSpool(spool, spooled); err != nil {
+		t.Fatal(err)
+	}
+
+	// The primary takes the first batch, then drops the connection
+	primary := &switchableRecorder{failAfter: replayBatchSize}
+	recorder, _ := NewFallbackRecorder(primary, &switchableRecorder{}, FallbackConfig{Name: "db", SpoolPath: spool})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+	recorder.Record(ctx, &domain.PriceData{Ticker: "EURUSD", Sequence: uint64(len(spooled) + 1)})
+	if err := recorder.reconcile(ctx); err == nil || !recorder.FailedOver() || len(primary.ticks) != replayBatchSize {
+		t.Fatalf("Expected the replay to stop after one batch, got %d ticks, %v", len(primary.ticks), err)
+	}
+
+	primary.failAfter = 0
+	recorder.Record(ctx, &domain.PriceData{Ticker: "EURUSD", Sequence: uint64(len(spooled) + 2)})
+	if err := recorder.reconcile(ctx); err != nil || recorder.FailedOver() {
+		t.Fatalf("Expected the primary to be back, got %v", err)
+	}
+	seen := make(map[domain.TickKey]bool)
+	for _, tick := range primary.ticks {
+		if seen[tick.Key()] {
+			t.Fatalf("Tick %d replayed twice", tick.Sequence)
+		}
+		seen[tick.Key()] = true
+	}
+	if len(primary.ticks) != len(spooled)+2 {
+		t.Errorf("Expected %d ticks, got %d", len(spooled)+2, len(primary.ticks))
+	}
+}
+
+func TestFallbackRecorder_BothFailing(t *testing.T) {
+	recorder, _ := NewFallbackRecorder(&switchableRecorder{down: true}, &switchableRecorder{down: true},
+		FallbackConfig{Name: "db", SpoolPath: filepath.Join(t.TempDir(), "spool.ndjson")})
+	if err := recorder.Record(context.Background(), &domain.PriceData{Ticker: "EURUSD"}); err == nil {
+		t.Error("Expected an error when the fallback fails too")
+	}
+}
+
+// bufferingRecorder loses its buffer when a flush fails, like a sink writing to a dropped connection
+type bufferingRecorder struct {
+	switchableRecorder
+	buffer   []*domain.PriceData
+	flushErr error
+}
+
+func (r *bufferingRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	r.buffer = append(r.buffer, data...)
+	return nil
+}
+
+func (r *bufferingRecorder) Flush(ctx context.Context) error {
+	buffer := r.buffer
+	r.buffer = nil
+	if r.flushErr != nil {
+		return r.flushErr
+	}
+	return r.switchableRecorder.RecordBatch(ctx, buffer)
+}
+
+func TestFallbackRecorder_SpoolsTicksUntilFlushed(t *testing.T) {
+	primary := &bufferingRecorder{}
+	recorder, _ := NewFallbackRecorder(primary, &switchableRecorder{},
+		FallbackConfig{Name: "db", SpoolPath: filepath.Join(t.TempDir(), "spool.ndjson"), ProbeInterval: time.Hour})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+
+	recorder.Record(ctx, &domain.PriceData{Ticker: "EURUSD", Sequence: 1})
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	recorder.Record(ctx, &domain.PriceData{Ticker: "EURUSD", Sequence: 2})
+	recorder.Record(ctx, &domain.PriceData{Ticker: "EURUSD", Sequence: 3})
+	primary.flushErr = errors.New("broken pipe")
+	if err := recorder.Flush(ctx); err == nil || !recorder.FailedOver() {
+		t.Fatalf("Expected a failed flush to fail over, got %v", err)
+	}
+
+	primary.flushErr = nil
+	if err := recorder.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if len(primary.ticks) != 3 || primary.ticks[1].Sequence != 2 || primary.ticks[2].Sequence != 3 {
+		t.Errorf("Expected the ticks lost in the buffer to be replayed, got %+v", primary.ticks)
+	}
+}
+
+func TestFallbackRecorder_KeepsUnflushedReplayInSpool(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "spool.ndjson")
+	var spooled []*domain.PriceData
+	for i := range replayBatchSize + 10 {
+		spooled = append(spooled, &domain.PriceData{Ticker: "EURUSD", Sequence: uint64(i + 1)})
+	}
+	if err := appendSpool(spool, spooled); err != nil {
+		t.Fatal(err)
+	}
+
+	// The primary buffers every batch, then loses its buffer when flushing
+	primary := &bufferingRecorder{flushErr: errors.New("broken pipe")}
+	recorder, _ := NewFallbackRecorder(primary, &switchableRecorder{}, FallbackConfig{Name: "db", SpoolPath: spool, ProbeInterval: time.Hour})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+	if err := recorder.reconcile(ctx); err == nil {
+		t.Fatal("Expected the failed flush to fail the replay")
+	}
+
+	primary.flushErr = nil
+	if err := recorder.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if len(primary.ticks) != len(spooled) {
+		t.Errorf("Expected all %d spooled ticks after the retry, got %d", len(spooled), len(primary.ticks))
+	}
+}
+
+// partialRecorder takes ticks one by one until it holds failAfter, reporting how many of a batch it took
+type partialRecorder struct {
+	switchableRecorder
+}
+
+func (r *partialRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	for i := range data {
+		if err := r.switchableRecorder.RecordBatch(ctx, data[i:i+1]); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+	}
+	return nil
+}
+
+// sequences returns the sequence numbers of ticks
+func sequences(ticks []*domain.PriceData) []uint64 {
+	var seqs []uint64
+	for _, tick := range ticks {
+		seqs = append(seqs, tick.Sequence)
+	}
+	return seqs
+}
+
+func TestFallbackRecorder_SpoolsOnlyTicksAfterPartialWrite(t *testing.T) {
+	primary, fallback := &partialRecorder{switchableRecorder{failAfter: 1}}, &switchableRecorder{}
+	recorder, _ := NewFallbackRecorder(primary, fallback,
+		FallbackConfig{Name: "db", SpoolPath: filepath.Join(t.TempDir(), "spool.ndjson"), ProbeInterval: time.Hour})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+
+	batch := []*domain.PriceData{{Ticker: "EURUSD", Sequence: 1}, {Ticker: "EURUSD", Sequence: 2}, {Ticker: "EURUSD", Sequence: 3}}
+	if err := recorder.RecordBatch(ctx, batch); err != nil {
+		t.Fatalf("Expected the fallback to take the rest, got %v", err)
+	}
+	if len(fallback.ticks) != 2 || fallback.ticks[0].Sequence != 2 {
+		t.Errorf("Expected the fallback to get ticks 2 and 3, got %v", sequences(fallback.ticks))
+	}
+
+	primary.failAfter = 0
+	if err := recorder.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if seqs := sequences(primary.ticks); len(seqs) != 3 || seqs[0] != 1 || seqs[1] != 2 || seqs[2] != 3 {
+		t.Errorf("Expected ticks 1 to 3 once each, got %v", seqs)
+	}
+}
+
+func TestFallbackRecorder_ResumesReplayAfterPartialWrite(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "spool.ndjson")
+	var spooled []*domain.PriceData
+	for i := range 5 {
+		spooled = append(spooled, &domain.PriceData{Ticker: "EURUSD", Sequence: uint64(i + 1)})
+	}
+	if err := appendSpool(spool, spooled); err != nil {
+		t.Fatal(err)
+	}
+
+	primary := &partialRecorder{switchableRecorder{failAfter: 3}}
+	recorder, _ := NewFallbackRecorder(primary, &switchableRecorder{}, FallbackConfig{Name: "db", SpoolPath: spool, ProbeInterval: time.Hour})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+	if err := recorder.reconcile(ctx); err == nil {
+		t.Fatal("Expected the partial write to fail the replay")
+	}
+
+	primary.failAfter = 0
+	if err := recorder.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if seqs := sequences(primary.ticks); len(seqs) != 5 || seqs[3] != 4 {
+		t.Errorf("Expected ticks 1 to 5 once each, got %v", seqs)
+	}
+}
+
+// keepingRecorder keeps its buffer when a flush fails, like the CSV sink
+type keepingRecorder struct {
+	bufferingRecorder
+}
+
+func (r *keepingRecorder) Flush(ctx context.Context) error {
+	if r.flushErr != nil {
+		return r.flushErr
+	}
+	return r.bufferingRecorder.Flush(ctx)
+}
+
+func (r *keepingRecorder) keepsUnwritten() {}
+
+func TestFallbackRecorder_TrimsReplayKeptByPrimary(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "spool.ndjson")
+	spooled := []*domain.PriceData{{Ticker: "EURUSD", Sequence: 1}, {Ticker: "EURUSD", Sequence: 2}}
+	if err := appendSpool(spool, spooled); err != nil {
+		t.Fatal(err)
+	}
+
+	primary := &keepingRecorder{bufferingRecorder{flushErr: errors.New("disk full")}}
+	recorder, _ := NewFallbackRecorder(NewRetryingRecorder(primary, testRetryConfig(1), nil), &switchableRecorder{},
+		FallbackConfig{Name: "csv", SpoolPath: spool, ProbeInterval: time.Hour})
+	ctx := context.Background()
+	defer recorder.Close(ctx)
+	if err := recorder.reconcile(ctx); err == nil {
+		t.Fatal("Expected the failed flush to fail the replay")
+	}
+
+	primary.flushErr = nil
+	if err := recorder.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if seqs := sequences(primary.ticks); len(seqs) != 2 {
+		t.Errorf("Expected the kept ticks written once, got %v", seqs)
+	}
+}
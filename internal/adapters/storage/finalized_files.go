@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// finalizedQueueSize bounds the files waiting to be announced; further ones go to the pending directory
+const finalizedQueueSize = 1024
+
+// publishTimeout bounds how long announcing a single file may take, unless the publisher sets its own
+const publishTimeout = 10 * time.Second
+
+// timeoutPublisher is implemented by publishers that need longer than publishTimeout per file,
+// such as those uploading the file itself
+type timeoutPublisher interface {
+	PublishTimeout() time.Duration
+}
+
+// DescribeSpreadFile reads a finalized hourly spread file and returns its row count and checksum
+func DescribeSpreadFile(path string) (domain.FinalizedFile, error) {
+	described := domain.FinalizedFile{Path: path, Dataset: SpreadSchema.Dataset, FinalizedAt: time.Now().UTC()}
+	described.Ticker, _ = spreadFileTicker(filepath.Base(path))
+	described.Hour, _ = spreadFileHour(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return described, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	buf := make([]byte, 64*1024)
+	lines := 0
+	for {
+		n, err := file.Read(buf)
+		hash.Write(buf[:n])
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		described.Bytes += int64(n)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return described, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	described.Rows = max(lines-1, 0) // Header line
+	described.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return described, nil
+}
+
+// OutboxPublisher announces finalized files by writing a JSON description of each to a directory
+// Consumers process and delete the files there; each appears atomically (written, then renamed to *.json)
+type OutboxPublisher struct {
+	dir string
+}
+
+// NewOutboxPublisher creates a publisher writing to dir, creating it if needed
+func NewOutboxPublisher(dir string) (*OutboxPublisher, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory %s: %w", dir, err)
+	}
+	return &OutboxPublisher{dir: dir}, nil
+}
+
+// PublishFile writes outbox/YYYYMMDD_TICKER_HH.json (the day and name of the finalized file)
+func (p *OutboxPublisher) PublishFile(ctx context.Context, file domain.FinalizedFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox entry: %w", err)
+	}
+
+	name := filepath.Base(filepath.Dir(file.Path)) + "_" + strings.TrimSuffix(filepath.Base(file.Path), ".csv") + ".json"
+	path := filepath.Join(p.dir, name)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return nil
+}
+
+// NamedPublisher is a publisher of FinalizedFiles; the name identifies it in pending announcements
+type NamedPublisher struct {
+	Name string
+	ports.FilePublisher
+}
+
+// Retry schedule of announcements that failed: the delay doubles from finalizedRetryBase up to finalizedRetryMax
+const (
+	finalizedRetryBase     = 30 * time.Second
+	finalizedRetryMax      = 30 * time.Minute
+	finalizedRetryInterval = 10 * time.Second // How often pending announcements are checked
+)
+
+// pendingAnnouncement is a finalized file not yet announced to every publisher, kept in the pending directory
+type pendingAnnouncement struct {
+	File        domain.FinalizedFile `json:"file"`       // Only the path until the file is described
+	Publishers  []string             `json:"publishers"` // Names of the publishers still owed the announcement
+	Attempts    int                  `json:"attempts"`
+	NextAttempt time.Time            `json:"next_attempt"`
+}
+
+// FinalizedFiles describes finalized files and hands them to the publishers in the background,
+// so checksumming a file never holds up the recorder that finalized it
+// Announcements that fail, don't fit the queue or come after Close are written to the pending
+// directory and retried with backoff, also after a restart
+type FinalizedFiles struct {
+	publishers []NamedPublisher
+	pendingDir string // "" only logs announcements that can't be made
+	queue      chan string
+	done       chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewFinalizedFiles starts announcing finalized files to the given publishers, first retrying
+// the announcements left in pendingDir
+func NewFinalizedFiles(pendingDir string, publishers ...NamedPublisher) (*FinalizedFiles, error) {
+	if pendingDir != "" {
+		if err := os.MkdirAll(pendingDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create pending announcement directory %s: %w", pendingDir, err)
+		}
+	}
+	f := &FinalizedFiles{
+		publishers: publishers,
+		pendingDir: pendingDir,
+		queue:      make(chan string, finalizedQueueSize),
+		done:       make(chan struct{}),
+	}
+	go f.run()
+	return f, nil
+}
+
+// Add queues a finalized file for announcement without blocking (e.g. from CSVSpreadRecorder.OnFinalize)
+// When the queue is full or closed, the file goes to the pending directory instead
+func (f *FinalizedFiles) Add(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		select {
+		case f.queue <- path:
+			return
+		default:
+		}
+	}
+	if f.pendingDir == "" {
+		log.Printf("Warning: Finalized file queue full or closed, not announcing %s", path)
+		return
+	}
+	entry := pendingAnnouncement{File: domain.FinalizedFile{Path: path}, Publishers: f.names(), NextAttempt: time.Now()}
+	if err := f.savePending(entry); err != nil {
+		log.Printf("Warning: Not announcing finalized file: %v", err)
+	}
+}
+
+// Close announces the files still queued, giving up when ctx is done
+// Files finalized after Close, and announcements still failing, are kept for the next start
+func (f *FinalizedFiles) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.closed {
+		f.closed = true
+		close(f.queue)
+	}
+	f.mu.Unlock()
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("finalized files not all announced: %w", ctx.Err())
+	}
+}
+
+// run announces queued files until the queue is closed, retrying pending announcements when due
+func (f *FinalizedFiles) run() {
+	defer close(f.done)
+	f.retryPending(true)
+
+	ticker := time.NewTicker(finalizedRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case path, ok := <-f.queue:
+			if !ok {
+				return
+			}
+			f.announce(pendingAnnouncement{File: domain.FinalizedFile{Path: path}, Publishers: f.names()})
+		case <-ticker.C:
+			f.retryPending(false)
+		}
+	}
+}
+
+// announce describes the file if needed and publishes it to the publishers still owed it
+// Publishers that fail are kept in the pending directory for the next attempt
+func (f *FinalizedFiles) announce(entry pendingAnnouncement) {
+	path := entry.File.Path
+	if entry.File.SHA256 == "" {
+		file, err := DescribeSpreadFile(path)
+		if err != nil {
+			log.Printf("Warning: Not announcing finalized file: %v", err)
+			f.removePending(path)
+			return
+		}
+		entry.File = file
+	}
+
+	var failed []string
+	for _, publisher := range f.publishers {
+		if !slices.Contains(entry.Publishers, publisher.Name) {
+			continue
+		}
+		timeout := publishTimeout
+		if p, ok := publisher.FilePublisher.(timeoutPublisher); ok {
+			timeout = p.PublishTimeout()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := publisher.PublishFile(ctx, entry.File); err != nil {
+			log.Printf("Warning: Failed to announce finalized file %s to %s: %v", path, publisher.Name, err)
+			failed = append(failed, publisher.Name)
+		}
+		cancel()
+	}
+	if len(failed) == 0 || f.pendingDir == "" {
+		f.removePending(path)
+		return
+	}
+
+	entry.Publishers = failed
+	entry.Attempts++
+	entry.NextAttempt = time.Now().Add(min(finalizedRetryBase<<min(entry.Attempts-1, 16), finalizedRetryMax))
+	if err := f.savePending(entry); err != nil {
+		log.Printf("Warning: Not retrying announcement of %s: %v", path, err)
+	}
+}
+
+// retryPending announces the pending files that are due, or all of them (at startup)
+func (f *FinalizedFiles) retryPending(all bool) {
+	if f.pendingDir == "" {
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(f.pendingDir, "*.json"))
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Warning: Failed to read pending announcement: %v", err)
+			continue
+		}
+		var entry pendingAnnouncement
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Printf("Warning: Removing invalid pending announcement %s: %v", path, err)
+			os.Remove(path)
+			continue
+		}
+		if all || !entry.NextAttempt.After(now) {
+			f.announce(entry)
+		}
+	}
+}
+
+// names returns the names of all publishers
+func (f *FinalizedFiles) names() []string {
+	names := make([]string, len(f.publishers))
+	for i, publisher := range f.publishers {
+		names[i] = publisher.Name
+	}
+	return names
+}
+
+// pendingPath returns the file keeping the pending announcement of a finalized file
+func (f *FinalizedFiles) pendingPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(f.pendingDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// savePending writes a pending announcement atomically
+func (f *FinalizedFiles) savePending(entry pendingAnnouncement) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending announcement: %w", err)
+	}
+	path := f.pendingPath(entry.File.Path)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write pending announcement: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write pending announcement: %w", err)
+	}
+	return nil
+}
+
+// removePending deletes the pending announcement of a file, if there is one
+func (f *FinalizedFiles) removePending(path string) {
+	if f.pendingDir == "" {
+		return
+	}
+	if err := os.Remove(f.pendingPath(path)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove pending announcement of %s: %v", path, err)
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestFinalizedFiles_Outbox(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(filepath.Join(tmpDir, "spreads"))
+	if err := recorder.SetPartialFiles(true); err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := NewOutboxPublisher(filepath.Join(tmpDir, "outbox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalized, err := NewFinalizedFiles("", NamedPublisher{Name: "outbox", FilePublisher: outbox})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.OnFinalize(finalized.Add)
+
+	ctx := context.Background()
+	base := time.Date(2025, 11, 18, 14, 30, 0, 0, time.UTC)
+	for i := range 3 {
+		data := &domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4}
+		if err := recorder.Record(ctx, data); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	if err := recorder.Close(ctx); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if err := finalized.Close(ctx); err != nil {
+		t.Fatalf("Failed to announce: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "outbox", "20251118_EURUSD_14.json"))
+	if err != nil {
+		t.Fatalf("Expected outbox entry: %v", err)
+	}
+	var file domain.FinalizedFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Ticker != "EURUSD" || file.Dataset != "spreads" || file.Rows != 3 || !file.Hour.Equal(base.Truncate(time.Hour)) {
+		t.Errorf("Unexpected outbox entry: %+v", file)
+	}
+
+	described, err := DescribeSpreadFile(file.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if described.SHA256 != file.SHA256 || described.Bytes != file.Bytes || len(file.SHA256) != 64 {
+		t.Errorf("Checksum mismatch: %+v vs %+v", described, file)
+	}
+}
+
+// flakyPublisher fails until ok is set and records the files it announced
+type flakyPublisher struct {
+	ok        bool
+	announced []string
+}
+
+func (p *flakyPublisher) PublishFile(ctx context.Context, file domain.FinalizedFile) error {
+	if !p.ok {
+		return os.ErrDeadlineExceeded
+	}
+	p.announced = append(p.announced, filepath.Base(file.Path))
+	return nil
+}
+
+func TestFinalizedFiles_KeepsFailedAnnouncementsForRetry(t *testing.T) {
+	tmpDir := t.TempDir()
+	pendingDir := filepath.Join(tmpDir, "pending")
+	ctx := context.Background()
+	var files []string
+	for _, name := range []string{"EURUSD_14.csv", "EURUSD_15.csv"} {
+		path := filepath.Join(tmpDir, "20251118", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("timestamp,bid,ask\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	healthy, failing := &flakyPublisher{ok: true}, &flakyPublisher{}
+	finalized, err := NewFinalizedFiles(pendingDir, NamedPublisher{"outbox", healthy}, NamedPublisher{"copy", failing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalized.Add(files[0])
+	if err := finalized.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	finalized.Add(files[1]) // After Close: kept for the next start
+
+	if pending, _ := filepath.Glob(filepath.Join(pendingDir, "*.json")); len(pending) != 2 {
+		t.Fatalf("Expected two pending announcements, got %v", pending)
+	}
+
+	// After a restart only the publishers still owed a file get it
+	failing.ok = true
+	finalized, err = NewFinalizedFiles(pendingDir, NamedPublisher{"outbox", healthy}, NamedPublisher{"copy", failing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := finalized.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(healthy.announced) != 2 || len(failing.announced) != 2 {
+		t.Errorf("Expected each file announced once per publisher, got %v and %v", healthy.announced, failing.announced)
+	}
+	if pending, _ := filepath.Glob(filepath.Join(pendingDir, "*")); len(pending) != 0 {
+		t.Errorf("Expected no pending announcements left, got %v", pending)
+	}
+}
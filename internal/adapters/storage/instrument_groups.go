@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// ReadInstrumentGroups reads the group tags of the instruments in an instruments file, for the
+// report tools to expand -ticker majors; a missing file has no groups
+func ReadInstrumentGroups(path string) (domain.InstrumentGroups, error) {
+	var file struct {
+		Instruments []struct {
+			Ticker string   `json:"ticker"`
+			Groups []string `json:"groups"`
+		} `json:"instruments"`
+	}
+	if err := readInstrumentsFile(path, &file); err != nil {
+		return nil, err
+	}
+
+	groups := make(domain.InstrumentGroups)
+	for _, inst := range file.Instruments {
+		for _, group := range inst.Groups {
+			groups[group] = append(groups[group], inst.Ticker)
+		}
+	}
+	return groups, nil
+}
+
+// ReadInstrumentPipSizes reads the pip size of each instrument in an instruments file that sets
+// pipDecimals or pipSize, for the report tools to convert spreads to pips; a missing file sets none
+func ReadInstrumentPipSizes(path string) (domain.PipSizes, error) {
+	var file struct {
+		Instruments []struct {
+			Ticker      string  `json:"ticker"`
+			PipDecimals int     `json:"pipDecimals"`
+			PipSize     float64 `json:"pipSize"`
+		} `json:"instruments"`
+	}
+	if err := readInstrumentsFile(path, &file); err != nil {
+		return nil, err
+	}
+
+	sizes := make(domain.PipSizes)
+	for _, inst := range file.Instruments {
+		switch {
+		case inst.PipDecimals > 0:
+			sizes[inst.Ticker] = domain.PipSizeForDecimals(inst.PipDecimals)
+		case inst.PipSize > 0:
+			sizes[inst.Ticker] = inst.PipSize
+		}
+	}
+	return sizes, nil
+}
+
+// ReadInstruments decodes the "instruments" list of an instruments file, the layout the collector
+// loads, into instruments (a pointer to a slice of the caller's instrument struct)
+// Unlike the report helpers, a missing file or an empty list is an error
+func ReadInstruments(path string, instruments any) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to read instruments file: %w", err)
+	}
+	var file struct {
+		Instruments json.RawMessage `json:"instruments"`
+	}
+	if err := readInstrumentsFile(path, &file); err != nil {
+		return err
+	}
+	if len(file.Instruments) == 0 {
+		return fmt.Errorf("no instruments found in %s", path)
+	}
+	if err := json.Unmarshal(file.Instruments, instruments); err != nil {
+		return fmt.Errorf("failed to parse instruments in %s: %w", path, err)
+	}
+	return nil
+}
+
+// readInstrumentsFile decodes an instruments file into v, leaving v untouched if the file is missing
+func readInstrumentsFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read instruments file: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse instruments file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadInstrumentGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instruments.json")
+	content := `{"instruments": [
+		{"ticker": "EURUSD", "uic": 21, "groups": ["majors"]},
+		{"ticker": "USDJPY", "uic": 42, "groups": ["majors", "jpy_crosses"]},
+		{"ticker": "EURJPY", "uic": 18, "groups": ["jpy_crosses"]},
+		{"ticker": "XAUUSD", "uic": 8176}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := ReadInstrumentGroups(path)
+	if err != nil {
+		t.Fatalf("ReadInstrumentGroups failed: %v", err)
+	}
+	if !slices.Equal(groups.Names(), []string{"jpy_crosses", "majors"}) {
+		t.Errorf("Unexpected groups %v", groups)
+	}
+	if got := groups.Expand("jpy_crosses,XAUUSD"); !slices.Equal(got, []string{"USDJPY", "EURJPY", "XAUUSD"}) {
+		t.Errorf("Unexpected expansion %v", got)
+	}
+
+	// Without an instruments file, the selection is taken as tickers
+	groups, err = ReadInstrumentGroups(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || len(groups) != 0 {
+		t.Errorf("Expected no groups for a missing file, got %v, %v", groups, err)
+	}
+}
+
+func TestReadInstrumentPipSizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instruments.json")
+	content := `{"instruments": [
+		{"ticker": "EURUSD", "uic": 21, "decimals": 5, "pipDecimals": 4},
+		{"ticker": "XAUUSD", "uic": 8176, "decimals": 2, "pipSize": 0.1},
+		{"ticker": "USDJPY", "uic": 42, "decimals": 3}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes, err := ReadInstrumentPipSizes(path)
+	if err != nil {
+		t.Fatalf("ReadInstrumentPipSizes failed: %v", err)
+	}
+	if len(sizes) != 2 || sizes["EURUSD"] != 0.0001 || sizes["XAUUSD"] != 0.1 {
+		t.Errorf("Unexpected pip sizes %v", sizes)
+	}
+	if sizes.Get("USDJPY") != 0.01 {
+		t.Errorf("Expected the default pip size for USDJPY, got %v", sizes.Get("USDJPY"))
+	}
+}
+
+func TestReadInstruments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instruments.json")
+	content := `{"defaults": {"maxSpread": 5}, "instruments": [
+		{"ticker": "EURUSD", "uic": 21, "assetType": "FxSpot", "decimals": 5},
+		{"ticker": "USDJPY", "uic": 42, "assetType": "FxSpot"}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var instruments []struct {
+		Ticker   string `json:"ticker"`
+		Uic      int    `json:"uic"`
+		Decimals int    `json:"decimals"`
+	}
+	if err := ReadInstruments(path, &instruments); err != nil {
+		t.Fatalf("ReadInstruments failed: %v", err)
+	}
+	if len(instruments) != 2 || instruments[0].Uic != 21 || instruments[0].Decimals != 5 || instruments[1].Ticker != "USDJPY" {
+		t.Errorf("Unexpected instruments %+v", instruments)
+	}
+
+	if err := ReadInstruments(filepath.Join(t.TempDir(), "missing.json"), &instruments); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	// The collector's layout is an object; a bare list is rejected instead of read as empty
+	if err := os.WriteFile(path, []byte(`[{"ticker": "EURUSD", "uic": 21}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReadInstruments(path, &instruments); err == nil {
+		t.Error("Expected an error for a file without an instruments list")
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// IntervalFlushRecorder gives one sink its own flush interval when several sinks are configured
+// CollectorService flushes it on a separate ticker instead of the global SPREAD_FLUSH_INTERVAL,
+// e.g. a database every 5s while CSV files are flushed every minute
+type IntervalFlushRecorder struct {
+	next     ports.TickWriter
+	interval time.Duration
+}
+
+// NewIntervalFlushRecorder wraps next with its own flush interval
+func NewIntervalFlushRecorder(next ports.TickWriter, interval time.Duration) *IntervalFlushRecorder {
+	return &IntervalFlushRecorder{next: next, interval: interval}
+}
+
+// FlushInterval returns how often the sink should be flushed
+func (r *IntervalFlushRecorder) FlushInterval() time.Duration {
+	return r.interval
+}
+
+// Record saves a single price data point
+func (r *IntervalFlushRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	return r.next.Record(ctx, data)
+}
+
+// RecordBatch saves multiple price data points efficiently
+func (r *IntervalFlushRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	return r.next.RecordBatch(ctx, data)
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *IntervalFlushRecorder) Flush(ctx context.Context) error {
+	if flusher, ok := r.next.(ports.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// Close finalizes the recording session and releases resources
+func (r *IntervalFlushRecorder) Close(ctx context.Context) error {
+	if closer, ok := r.next.(ports.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped recorder
+func (r *IntervalFlushRecorder) Unwrap() ports.TickWriter {
+	return r.next
+}
+
+// FlushGroups splits a recorder's buffering sinks by flush interval, for services.WithFlushGroups
+// Sinks with their own interval (IntervalFlushRecorder, also behind later decorators such as
+// retain=) get their own group; all others are under key 0, flushed at the service's interval
+func FlushGroups(recorder ports.TickWriter) map[time.Duration][]ports.Flusher {
+	sinks := []ports.TickWriter{recorder}
+	if composite, ok := recorder.(Composite); ok {
+		sinks = composite.Members()
+	}
+
+	groups := make(map[time.Duration][]ports.Flusher)
+	for _, sink := range sinks {
+		flusher, ok := sink.(ports.Flusher)
+		if !ok {
+			continue
+		}
+		var interval time.Duration
+		if scheduled, ok := As[*IntervalFlushRecorder](sink); ok && scheduled.FlushInterval() > 0 {
+			interval = scheduled.FlushInterval()
+		}
+		groups[interval] = append(groups[interval], flusher)
+	}
+	return groups
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestIntervalFlushRecorder(t *testing.T) {
+	csv := NewCSVSpreadRecorder(t.TempDir())
+	recorder := NewIntervalFlushRecorder(NewRetryingRecorder(csv, RetryConfig{}, nil), 5*time.Second)
+
+	if recorder.FlushInterval() != 5*time.Second {
+		t.Errorf("Unexpected interval %v", recorder.FlushInterval())
+	}
+	if inner, ok := As[*CSVSpreadRecorder](recorder); !ok || inner != csv {
+		t.Error("Expected As to find the wrapped CSV recorder")
+	}
+
+	data := &domain.PriceData{Timestamp: time.Date(2025, 11, 18, 14, 0, 0, 0, time.UTC), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002}
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Flush(context.Background()); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if len(csv.files) != 0 {
+		t.Error("Expected Close to reach the CSV recorder")
+	}
+}
+
+func TestFlushGroups_FindWrappedIntervals(t *testing.T) {
+	scheduled := NewIntervalFlushRecorder(NewCSVSpreadRecorder(t.TempDir()), 5*time.Second)
+	retained, err := NewRetentionRecorder(scheduled, "csv", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := FlushGroups(NewMultiRecorder(retained, NewCSVSpreadRecorder(t.TempDir())))
+	if len(groups[5*time.Second]) != 1 || len(groups[0]) != 1 {
+		t.Errorf("Expected one sink flushed every 5s and one at the default interval, got %v", groups)
+	}
+}
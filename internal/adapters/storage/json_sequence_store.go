@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONSequenceStore implements SequenceStore using a single JSON file
+// The file is replaced atomically (write temp + rename) so a crash never leaves it half-written
+type JSONSequenceStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJSONSequenceStore creates a sequence store persisted at path
+func NewJSONSequenceStore(path string) *JSONSequenceStore {
+	return &JSONSequenceStore{path: path}
+}
+
+// Load returns the last persisted sequence per ticker (empty if none)
+func (s *JSONSequenceStore) Load(ctx context.Context) (map[string]uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sequences := make(map[string]uint64)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sequences, nil
+		}
+		return nil, fmt.Errorf("failed to read sequence file %s: %w", s.path, err)
+	}
+
+	if err := json.Unmarshal(data, &sequences); err != nil {
+		return nil, fmt.Errorf("failed to parse sequence file %s: %w", s.path, err)
+	}
+	return sequences, nil
+}
+
+// Save persists the last assigned sequence per ticker
+func (s *JSONSequenceStore) Save(ctx context.Context, sequences map[string]uint64) error {
+	data, err := json.MarshalIndent(sequences, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sequences: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", s.path, err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sequence file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace sequence file: %w", err)
+	}
+	return nil
+}
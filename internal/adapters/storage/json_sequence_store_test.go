@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONSequenceStore_RoundTrip(t *testing.T) {
+	store := NewJSONSequenceStore(filepath.Join(t.TempDir(), "state", "sequences.json"))
+	ctx := context.Background()
+
+	// Missing file means no sequences yet
+	sequences, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(sequences) != 0 {
+		t.Fatalf("Expected empty sequences, got %v", sequences)
+	}
+
+	want := map[string]uint64{"EURUSD": 1042, "USDJPY": 7}
+	if err := store.Save(ctx, want); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for ticker, seq := range want {
+		if got[ticker] != seq {
+			t.Errorf("%s: expected sequence %d, got %d", ticker, seq, got[ticker])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/metrics"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// ByteCounter is implemented by sinks that count the bytes they have written
+type ByteCounter interface {
+	BytesWritten() uint64
+}
+
+// FileHolder is implemented by sinks that keep files open
+type FileHolder interface {
+	OpenFiles() int
+}
+
+// SinkMetrics are the Prometheus series of MeteredRecorder, labelled by sink
+// The zero value discards all updates, as nil metrics do
+type SinkMetrics struct {
+	Records   *metrics.CounterVec   // fx_collector_sink_records_total{sink}
+	Bytes     *metrics.CounterVec   // fx_collector_sink_bytes_total{sink}
+	Errors    *metrics.CounterVec   // fx_collector_sink_errors_total{sink,op}
+	Writes    *metrics.HistogramVec // fx_collector_sink_write_seconds{sink}
+	Flushes   *metrics.HistogramVec // fx_collector_sink_flush_seconds{sink}
+	OpenFiles *metrics.GaugeVec     // fx_collector_sink_open_files{sink}
+}
+
+// NewSinkMetrics registers the per-sink series in registry
+func NewSinkMetrics(registry *metrics.Registry) SinkMetrics {
+	return SinkMetrics{
+		Records: registry.Counter("fx_collector_sink_records_total",
+			"Ticks a sink accepted", "sink"),
+		Bytes: registry.Counter("fx_collector_sink_bytes_total",
+			"Bytes a sink wrote to its files or stream (csv, ndjson and arrow)", "sink"),
+		Errors: registry.Counter("fx_collector_sink_errors_total",
+			"Failed sink operations, retries included (op: write, flush or close)", "sink", "op"),
+		Writes: registry.Histogram("fx_collector_sink_write_seconds",
+			"Time a sink takes per write call", metrics.DurationBuckets, "sink"),
+		Flushes: registry.Histogram("fx_collector_sink_flush_seconds",
+			"Time a sink takes per flush", metrics.DurationBuckets, "sink"),
+		OpenFiles: registry.Gauge("fx_collector_sink_open_files",
+			"Files a sink holds open, as of its last flush", "sink"),
+	}
+}
+
+// SinkStats are the cumulative write statistics of one sink
+type SinkStats = domain.SinkStats
+
+// MeteredRecorder measures what one sink writes: records, bytes, write and flush latency, open
+// files and errors. It wraps the sink itself, inside any retry or fallback, so every backend of a
+// chain is measured on its own and each retry counts as a write
+type MeteredRecorder struct {
+	next    ports.TickWriter
+	name    string
+	metrics SinkMetrics
+
+	mu            sync.Mutex
+	stats         SinkStats
+	reportedBytes uint64 // Part of BytesWritten already added to metrics.Bytes
+}
+
+// NewMeteredRecorder wraps next, reporting as sink name
+func NewMeteredRecorder(next ports.TickWriter, name string, m SinkMetrics) *MeteredRecorder {
+	return &MeteredRecorder{next: next, name: name, metrics: m}
+}
+
+// Name returns the sink's label
+func (r *MeteredRecorder) Name() string {
+	return r.name
+}
+
+// Record saves a single price data point
+func (r *MeteredRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	start := time.Now()
+	err := r.next.Record(ctx, data)
+	r.observeWrite(time.Since(start), 1, err)
+	return err
+}
+
+// RecordBatch saves multiple price data points efficiently
+func (r *MeteredRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	start := time.Now()
+	err := r.next.RecordBatch(ctx, data)
+	r.observeWrite(time.Since(start), len(data), err)
+	return err
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *MeteredRecorder) Flush(ctx context.Context) error {
+	flusher, ok := r.next.(ports.Flusher)
+	if !ok {
+		r.updateGauges()
+		return nil
+	}
+
+	start := time.Now()
+	err := flusher.Flush(ctx)
+	elapsed := time.Since(start)
+	r.metrics.Flushes.Observe(elapsed.Seconds(), r.name)
+
+	r.mu.Lock()
+	r.stats.Flushes++
+	r.stats.FlushTime += elapsed
+	if err != nil {
+		r.stats.Errors++
+	}
+	r.mu.Unlock()
+	if err != nil {
+		r.metrics.Errors.Inc(r.name, "flush")
+	}
+	r.updateGauges()
+	return err
+}
+
+// Close finalizes the recording session and releases resources
+func (r *MeteredRecorder) Close(ctx context.Context) error {
+	closer, ok := r.next.(ports.Closer)
+	if !ok {
+		return nil
+	}
+	err := closer.Close(ctx)
+	if err != nil {
+		r.mu.Lock()
+		r.stats.Errors++
+		r.mu.Unlock()
+		r.metrics.Errors.Inc(r.name, "close")
+	}
+	r.updateGauges()
+	return err
+}
+
+// Unwrap returns the wrapped recorder
+func (r *MeteredRecorder) Unwrap() ports.TickWriter {
+	return r.next
+}
+
+// Stats returns the sink's statistics so far
+func (r *MeteredRecorder) Stats() SinkStats {
+	r.mu.Lock()
+	stats := r.stats
+	r.mu.Unlock()
+
+	if counter, ok := r.next.(ByteCounter); ok {
+		stats.Bytes = counter.BytesWritten()
+	}
+	if holder, ok := r.next.(FileHolder); ok {
+		stats.OpenFiles = holder.OpenFiles()
+	}
+	return stats
+}
+
+// observeWrite counts one write call of n records
+func (r *MeteredRecorder) observeWrite(elapsed time.Duration, n int, err error) {
+	r.metrics.Writes.Observe(elapsed.Seconds(), r.name)
+
+	r.mu.Lock()
+	r.stats.Writes++
+	r.stats.WriteTime += elapsed
+	if err == nil {
+		r.stats.Records += uint64(n)
+	} else {
+		r.stats.Errors++
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		r.metrics.Errors.Inc(r.name, "write")
+		return
+	}
+	r.metrics.Records.Add(uint64(n), r.name)
+}
+
+// updateGauges brings the byte counter and open file gauge up to date; called after flushes,
+// since buffering sinks only write their bytes then
+func (r *MeteredRecorder) updateGauges() {
+	stats := r.Stats()
+
+	r.mu.Lock()
+	added := stats.Bytes - r.reportedBytes
+	r.reportedBytes = stats.Bytes
+	r.mu.Unlock()
+
+	if added > 0 {
+		r.metrics.Bytes.Add(added, r.name)
+	}
+	r.metrics.OpenFiles.Set(float64(stats.OpenFiles), r.name)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/metrics"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestMeteredRecorder(t *testing.T) {
+	registry := metrics.NewRegistry()
+	sinkMetrics := NewSinkMetrics(registry)
+
+	tmpDir := t.TempDir()
+	csvSink := NewMeteredRecorder(NewCSVSpreadRecorder(tmpDir), "csv", sinkMetrics)
+	down := &switchableRecorder{down: true}
+	remote := NewMeteredRecorder(down, "db", sinkMetrics)
+	recorder := NewMultiRecorder(csvSink, NewRetryingRecorder(remote, testRetryConfig(1), nil))
+
+	ctx := context.Background()
+	ticks := []*domain.PriceData{
+		{Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4},
+		{Timestamp: time.Date(2025, 11, 18, 12, 0, 1, 0, time.UTC), Ticker: "USDJPY", Bid: 155.1, Ask: 155.12, Decimals: 2},
+	}
+	csvSink.RecordBatch(ctx, ticks)
+	remote.RecordBatch(ctx, ticks)
+	if err := csvSink.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	stats := csvSink.Stats()
+	if stats.Records != 2 || stats.Writes != 1 || stats.Flushes != 1 || stats.OpenFiles != 2 || stats.Errors != 0 {
+		t.Errorf("Unexpected csv stats %+v", stats)
+	}
+	var size int64
+	for _, name := range []string{"EURUSD_12.csv", "USDJPY_12.csv"} {
+		info, err := os.Stat(filepath.Join(tmpDir, "20251118", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	if stats.Bytes != uint64(size) {
+		t.Errorf("Expected %d bytes, got %d", size, stats.Bytes)
+	}
+	if stats := remote.Stats(); stats.Records != 0 || stats.Errors != 1 {
+		t.Errorf("Unexpected db stats %+v", stats)
+	}
+
+	var text strings.Builder
+	registry.WriteText(&text)
+	for _, want := range []string{
+		`fx_collector_sink_records_total{sink="csv"} 2`,
+		`fx_collector_sink_errors_total{sink="db",op="write"} 1`,
+		`fx_collector_sink_open_files{sink="csv"} 2`,
+		`fx_collector_sink_flush_seconds_count{sink="csv"} 1`,
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected %s in:\n%s", want, text.String())
+		}
+	}
+
+	if sinks := All[*MeteredRecorder](recorder); len(sinks) != 2 || sinks[0].Name() != "csv" || sinks[1].Name() != "db" {
+		t.Errorf("Expected both metered sinks in order, got %v", sinks)
+	}
+	recorder.Close(ctx)
+}
+
+func TestSinkStats_Sub(t *testing.T) {
+	prev := SinkStats{Records: 10, Bytes: 1000, Writes: 5, WriteTime: time.Second, OpenFiles: 3}
+	cur := SinkStats{Records: 25, Bytes: 2500, Writes: 8, WriteTime: 3 * time.Second, OpenFiles: 2}
+	got := cur.Sub(prev)
+	if got.Records != 15 || got.Bytes != 1500 || got.Writes != 3 || got.WriteTime != 2*time.Second || got.OpenFiles != 2 {
+		t.Errorf("Unexpected difference %+v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// MultiRecorder fans every tick out to several sinks
+// A failing sink doesn't stop the others; errors are joined and returned
+type MultiRecorder struct {
+	sinks []ports.TickWriter
+}
+
+// NewMultiRecorder creates a recorder writing to all sinks in order
+func NewMultiRecorder(sinks ...ports.TickWriter) *MultiRecorder {
+	return &MultiRecorder{sinks: sinks}
+}
+
+// Record saves a single price data point to every sink
+func (m *MultiRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.Record(ctx, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RecordBatch saves multiple price data points to every sink
+func (m *MultiRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.RecordBatch(ctx, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush flushes every sink that buffers writes
+func (m *MultiRecorder) Flush(ctx context.Context) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if flusher, ok := sink.(ports.Flusher); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink that holds resources
+func (m *MultiRecorder) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if closer, ok := sink.(ports.Closer); ok {
+			if err := closer.Close(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Members returns the wrapped sinks
+func (m *MultiRecorder) Members() []ports.TickWriter {
+	return m.sinks
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// MultiSnapshotWriter fans snapshots out to several writers, e.g. CSV files and a BigQuery table
+// A failing writer doesn't stop the others; errors are joined and returned
+type MultiSnapshotWriter struct {
+	writers []ports.SnapshotWriter
+}
+
+// NewMultiSnapshotWriter creates a writer writing to all writers in order
+func NewMultiSnapshotWriter(writers ...ports.SnapshotWriter) *MultiSnapshotWriter {
+	return &MultiSnapshotWriter{writers: writers}
+}
+
+// RecordSnapshots saves the snapshots to every writer
+func (m *MultiSnapshotWriter) RecordSnapshots(ctx context.Context, snapshots []*domain.Snapshot) error {
+	var errs []error
+	for _, writer := range m.writers {
+		if err := writer.RecordSnapshots(ctx, snapshots); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every writer that holds resources
+func (m *MultiSnapshotWriter) Close(ctx context.Context) error {
+	var errs []error
+	for _, writer := range m.writers {
+		if closer, ok := writer.(ports.Closer); ok {
+			if err := closer.Close(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
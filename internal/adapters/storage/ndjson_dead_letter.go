@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// NDJSONDeadLetterQueue implements DeadLetterQueue using daily newline-delimited JSON files
+// File format: <dir>/<prefix>_YYYYMMDD.ndjson (one DeadLetter per line)
+// Files are opened per write: dead letters are rare and must survive crashes
+type NDJSONDeadLetterQueue struct {
+	dir    string
+	prefix string
+	mu     sync.Mutex
+}
+
+// NewNDJSONDeadLetterQueue creates a dead-letter queue writing to dir with the given file prefix
+func NewNDJSONDeadLetterQueue(dir, prefix string) *NDJSONDeadLetterQueue {
+	return &NDJSONDeadLetterQueue{
+		dir:    dir,
+		prefix: prefix,
+	}
+}
+
+// Put stores a single dead-lettered item
+func (q *NDJSONDeadLetterQueue) Put(ctx context.Context, entry domain.DeadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+	line = append(line, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", q.dir, err)
+	}
+
+	filePath := filepath.Join(q.dir, fmt.Sprintf("%s_%s.ndjson", q.prefix, entry.Timestamp.Format("20060102")))
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write dead letter to %s: %w", filePath, err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RawCaptureRecord is one line of a raw capture file: a WebSocket frame (base64 in the file) or,
+// from a client that doesn't hand out frames, a decoded update
+type RawCaptureRecord struct {
+	ReceivedAt time.Time       `json:"received_at"`
+	Frame      []byte          `json:"frame,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// NDJSONRawCapture implements RawCapture using hourly newline-delimited JSON files
+// File format: <dir>/raw_YYYYMMDD_HH.ndjson (one RawCaptureRecord per line, hour of receipt in UTC)
+// Only the newest keep files are kept, so a capture left enabled can't fill the disk
+type NDJSONRawCapture struct {
+	dir  string
+	keep int
+
+	mu   sync.Mutex
+	file *os.File
+	hour time.Time
+}
+
+// NewNDJSONRawCapture creates a capture writing to dir, keeping the newest keep hourly files (0 keeps all)
+func NewNDJSONRawCapture(dir string, keep int) *NDJSONRawCapture {
+	return &NDJSONRawCapture{dir: dir, keep: keep}
+}
+
+// Capture appends one decoded update to the file of the hour it was received in
+func (c *NDJSONRawCapture) Capture(receivedAt time.Time, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode raw update: %w", err)
+	}
+	return c.write(RawCaptureRecord{ReceivedAt: receivedAt.UTC(), Payload: data})
+}
+
+// CaptureFrame appends one WebSocket frame to the file of the hour it was received in
+func (c *NDJSONRawCapture) CaptureFrame(receivedAt time.Time, frame []byte) error {
+	return c.write(RawCaptureRecord{ReceivedAt: receivedAt.UTC(), Frame: frame})
+}
+
+// write appends a record as one line
+func (c *NDJSONRawCapture) write(record RawCaptureRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode raw update: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hour := record.ReceivedAt.Truncate(time.Hour); c.file == nil || !hour.Equal(c.hour) {
+		if err := c.rotate(hour); err != nil {
+			return err
+		}
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write raw capture %s: %w", c.file.Name(), err)
+	}
+	return nil
+}
+
+// rotate closes the current file, opens the one for hour and removes files beyond keep
+func (c *NDJSONRawCapture) rotate(hour time.Time) error {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", c.dir, err)
+	}
+
+	path := filepath.Join(c.dir, "raw_"+hour.Format("20060102_15")+".ndjson")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	c.file, c.hour = file, hour
+
+	if c.keep > 0 {
+		files, err := ListRawCaptures(c.dir)
+		if err != nil {
+			return err
+		}
+		for _, old := range files[:max(len(files)-c.keep, 0)] {
+			if err := os.Remove(old); err != nil {
+				return fmt.Errorf("failed to remove old raw capture: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the current capture file
+func (c *NDJSONRawCapture) Close(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close raw capture: %w", err)
+	}
+	return nil
+}
+
+// ListRawCaptures returns the capture files in dir, oldest first
+func ListRawCaptures(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasPrefix(name, "raw_") && strings.HasSuffix(name, ".ndjson") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// ReadRawCapture streams the records of a capture file to fn in file order
+// A truncated last line (the capture was still being written) ends the file without an error
+func ReadRawCapture(path string, fn func(RawCaptureRecord) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil // Empty or unterminated last line
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var record RawCaptureRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, lineNumber, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNDJSONRawCapture_RotateAndRead(t *testing.T) {
+	dir := t.TempDir()
+	capture := NewNDJSONRawCapture(dir, 2)
+
+	type update struct {
+		Ticker string
+		Bid    float64
+	}
+	base := time.Date(2025, 11, 18, 14, 59, 0, 0, time.UTC)
+	for i, at := range []time.Time{base, base.Add(2 * time.Minute), base.Add(62 * time.Minute), base.Add(122 * time.Minute)} {
+		if err := capture.Capture(at, update{Ticker: "EURUSD", Bid: 1.1 + float64(i)/10000}); err != nil {
+			t.Fatalf("Failed to capture: %v", err)
+		}
+	}
+	if err := capture.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ListRawCaptures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "raw_20251118_16.ndjson"), filepath.Join(dir, "raw_20251118_17.ndjson")}
+	if len(files) != 2 || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("Expected the newest 2 hours %v, got %v", want, files)
+	}
+
+	var got []update
+	err = ReadRawCapture(files[0], func(record RawCaptureRecord) error {
+		if !record.ReceivedAt.Equal(base.Add(62 * time.Minute)) {
+			t.Errorf("Unexpected received_at %v", record.ReceivedAt)
+		}
+		var u update
+		if err := json.Unmarshal(record.Payload, &u); err != nil {
+			return err
+		}
+		got = append(got, u)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Ticker != "EURUSD" || got[0].Bid != 1.1002 {
+		t.Errorf("Unexpected records: %+v", got)
+	}
+}
+
+func TestReadRawCapture_TruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw_20251118_14.ndjson")
+	data := `{"received_at":"2025-11-18T14:00:00Z","payload":{"Ticker":"EURUSD"}}` + "\n" + `{"received_at":"2025-11-18T14:00:01Z","pay`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	if err := ReadRawCapture(path, func(RawCaptureRecord) error { count++; return nil }); err != nil {
+		t.Fatalf("Truncated last line should end the file: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 record, got %d", count)
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// NDJSONRecorder implements SpreadRecorder by writing one JSON object per tick and line
+// Intended for stdout or a named pipe: `fx-collector | jq ...`, vector, fluentbit
+// Lines are flushed after every Record/RecordBatch call so consumers see ticks immediately
+type NDJSONRecorder struct {
+	format domain.PriceFormat
+	buffer *bufio.Writer
+	closer io.Closer // nil when the underlying writer isn't owned (stdout)
+	mu     sync.Mutex
+
+	written atomic.Uint64 // Bytes handed to the output
+}
+
+// NewNDJSONRecorder creates a recorder writing to w (w is not closed by Close)
+func NewNDJSONRecorder(w io.Writer, format domain.PriceFormat) *NDJSONRecorder {
+	return &NDJSONRecorder{format: format, buffer: bufio.NewWriter(w)}
+}
+
+func init() {
+	// ndjson?output=-&format=float
+	RegisterRecorder("ndjson", func(spec RecorderSpec) (ports.TickWriter, error) {
+		format, err := spec.PriceFormat()
+		if err != nil {
+			return nil, err
+		}
+		return NewNDJSONFileRecorder(spec.Param("output", "-"), format)
+	})
+}
+
+// NewNDJSONFileRecorder creates a recorder appending to path ("-" for stdout)
+// Opening a named pipe blocks until a reader attaches
+func NewNDJSONFileRecorder(path string, format domain.PriceFormat) (*NDJSONRecorder, error) {
+	if path == "-" {
+		return NewNDJSONRecorder(os.Stdout, format), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	recorder := NewNDJSONRecorder(file, format)
+	recorder.closer = file
+	return recorder, nil
+}
+
+// Record saves a single price data point
+func (r *NDJSONRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writeLine(data); err != nil {
+		return err
+	}
+	return ports.PartialWrite(1, r.buffer.Flush())
+}
+
+// RecordBatch saves multiple price data points efficiently
+func (r *NDJSONRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, priceData := range data {
+		if err := r.writeLine(priceData); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+	}
+	return ports.PartialWrite(len(data), r.buffer.Flush())
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *NDJSONRecorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buffer.Flush()
+}
+
+// Close finalizes the recording session and releases resources
+func (r *NDJSONRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.buffer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush NDJSON output: %w", err)
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// writeLine encodes a tick as a single JSON line into the buffer
+func (r *NDJSONRecorder) writeLine(data *domain.PriceData) error {
+	line, err := data.MarshalJSONFormat(r.format)
+	if err != nil {
+		return fmt.Errorf("failed to encode tick for %s: %w", data.Ticker, err)
+	}
+	line = append(line, '\n')
+
+	n, err := r.buffer.Write(line)
+	r.written.Add(uint64(n))
+	if err != nil {
+		return fmt.Errorf("failed to write tick for %s: %w", data.Ticker, err)
+	}
+	return nil
+}
+
+// BytesWritten returns the bytes written so far
+func (r *NDJSONRecorder) BytesWritten() uint64 {
+	return r.written.Load()
+}
+
+// OpenFiles returns 1 when writing to a file of its own, 0 for stdout
+func (r *NDJSONRecorder) OpenFiles() int {
+	if r.closer != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestNDJSONRecorder_WritesOneLinePerTick(t *testing.T) {
+	var out bytes.Buffer
+	recorder := NewNDJSONRecorder(&out, domain.PriceFormatFloat)
+	ctx := context.Background()
+
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	batch := []*domain.PriceData{
+		{Timestamp: now, Uic: 21, Ticker: "EURUSD", AssetType: "FxSpot", Bid: 1.1, Ask: 1.1002, Spread: 0.0002, Sequence: 1},
+		{Timestamp: now, Uic: 42, Ticker: "USDJPY", AssetType: "FxSpot", Bid: 150.0, Ask: 150.003, Spread: 0.003, Sequence: 1},
+	}
+	if err := recorder.RecordBatch(ctx, batch); err != nil {
+		t.Fatalf("RecordBatch failed: %v", err)
+	}
+
+	// Output is visible without an explicit Flush
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), out.String())
+	}
+
+	var tick domain.PriceData
+	if err := json.Unmarshal([]byte(lines[1]), &tick); err != nil {
+		t.Fatalf("Line is not valid JSON: %v", err)
+	}
+	if tick.Ticker != "USDJPY" || tick.Uic != 42 || !tick.Timestamp.Equal(now) {
+		t.Errorf("Unexpected tick: %+v", tick)
+	}
+}
+
+func TestMultiRecorder_FansOutAndFindsCapabilities(t *testing.T) {
+	var out bytes.Buffer
+	csvRecorder := NewCSVSpreadRecorder(t.TempDir())
+	multi := NewMultiRecorder(
+		NewRetryingRecorder(NewNDJSONRecorder(&out, domain.PriceFormatFloat), testRetryConfig(1), nil),
+		NewRetryingRecorder(csvRecorder, testRetryConfig(1), nil),
+	)
+	defer multi.Close(context.Background())
+
+	data := &domain.PriceData{Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC), Ticker: "EURUSD"}
+	if err := multi.Record(context.Background(), data); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `"ticker":"EURUSD"`) {
+		t.Errorf("NDJSON sink did not receive tick: %q", out.String())
+	}
+
+	found, ok := As[*CSVSpreadRecorder](multi)
+	if !ok || found != csvRecorder {
+		t.Fatal("Expected to find CSV recorder inside multi-recorder")
+	}
+}
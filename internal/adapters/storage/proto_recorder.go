@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	"github.com/bjoelf/fx-collector/pkg/tickpb"
+)
+
+// ProtoRecorder implements SpreadRecorder by writing length-delimited protobuf PriceData messages
+// The schema is proto/fxcollector/v1/ticks.proto; consumers in other languages read the stream with
+// their generated types (parseDelimitedFrom, ParseDelimitedFromStream, ...), Go with pkg/tickpb
+// Like NDJSONRecorder the output is flushed after every Record/RecordBatch call
+type ProtoRecorder struct {
+	buffer  *bufio.Writer
+	closer  io.Closer // nil when the underlying writer isn't owned (stdout)
+	message []byte    // Reused encoding buffers
+	frame   []byte
+	mu      sync.Mutex
+
+	written atomic.Uint64 // Bytes handed to the output
+}
+
+// NewProtoRecorder creates a recorder writing to w (w is not closed by Close)
+func NewProtoRecorder(w io.Writer) *ProtoRecorder {
+	return &ProtoRecorder{buffer: bufio.NewWriter(w)}
+}
+
+func init() {
+	// proto?output=ticks.pb
+	RegisterRecorder("proto", func(spec RecorderSpec) (ports.TickWriter, error) {
+		return NewProtoFileRecorder(spec.Param("output", "-"))
+	})
+}
+
+// NewProtoFileRecorder creates a recorder appending to path ("-" for stdout)
+func NewProtoFileRecorder(path string) (*ProtoRecorder, error) {
+	if path == "-" {
+		return NewProtoRecorder(os.Stdout), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	recorder := NewProtoRecorder(file)
+	recorder.closer = file
+	return recorder, nil
+}
+
+// Record saves a single price data point
+func (r *ProtoRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writeMessage(data); err != nil {
+		return err
+	}
+	return ports.PartialWrite(1, r.buffer.Flush())
+}
+
+// RecordBatch saves multiple price data points efficiently
+func (r *ProtoRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, priceData := range data {
+		if err := r.writeMessage(priceData); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+	}
+	return ports.PartialWrite(len(data), r.buffer.Flush())
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *ProtoRecorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buffer.Flush()
+}
+
+// Close finalizes the recording session and releases resources
+func (r *ProtoRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.buffer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush protobuf output: %w", err)
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// writeMessage encodes a tick as a delimited PriceData message into the buffer
+func (r *ProtoRecorder) writeMessage(data *domain.PriceData) error {
+	r.message = tickpb.AppendPriceData(r.message[:0], data)
+	r.frame = tickpb.AppendDelimited(r.frame[:0], r.message)
+
+	n, err := r.buffer.Write(r.frame)
+	r.written.Add(uint64(n))
+	if err != nil {
+		return fmt.Errorf("failed to write tick for %s: %w", data.Ticker, err)
+	}
+	return nil
+}
+
+// BytesWritten returns the bytes written so far
+func (r *ProtoRecorder) BytesWritten() uint64 {
+	return r.written.Load()
+}
+
+// OpenFiles returns 1 when writing to a file of its own, 0 for stdout
+func (r *ProtoRecorder) OpenFiles() int {
+	if r.closer != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/tickpb"
+)
+
+func TestProtoRecorder_AppendsDelimitedMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticks.pb")
+	ctx := context.Background()
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+
+	// Two sessions appending to the same file form one stream
+	for i, ticker := range []string{"EURUSD", "USDJPY"} {
+		spec, err := ParseRecorderSpec("proto?output=" + path)
+		if err != nil {
+			t.Fatalf("ParseRecorderSpec failed: %v", err)
+		}
+		writer, err := NewRecorder(spec)
+		if err != nil {
+			t.Fatalf("NewRecorder failed: %v", err)
+		}
+		recorder, ok := writer.(*ProtoRecorder)
+		if !ok {
+			t.Fatalf("Expected a *ProtoRecorder, got %T", writer)
+		}
+		tick := &domain.PriceData{Timestamp: now, Uic: 21 + i, Ticker: ticker, Bid: 1.1, Ask: 1.1002, Spread: 0.0002, Sequence: uint64(i + 1)}
+		if err := recorder.Record(ctx, tick); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		if err := recorder.Close(ctx); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+	var ticks []*domain.PriceData
+	for {
+		msg, err := tickpb.ReadDelimited(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadDelimited failed: %v", err)
+		}
+		tick, err := tickpb.UnmarshalPriceData(msg)
+		if err != nil {
+			t.Fatalf("UnmarshalPriceData failed: %v", err)
+		}
+		ticks = append(ticks, tick)
+	}
+
+	if len(ticks) != 2 {
+		t.Fatalf("Expected 2 ticks, got %d", len(ticks))
+	}
+	if ticks[1].Ticker != "USDJPY" || ticks[1].Uic != 22 || ticks[1].Sequence != 2 || !ticks[1].Timestamp.Equal(now) {
+		t.Errorf("Unexpected tick: %+v", ticks[1])
+	}
+}
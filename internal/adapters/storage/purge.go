@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// existingParent walks up from path to the closest directory that exists
+// The spread directory may not be created until the first tick arrives
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// dayDirs returns the YYYYMMDD directories under baseDir, oldest first
+func dayDirs(baseDir string) ([]string, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse("20060102", entry.Name()); err == nil {
+			days = append(days, entry.Name())
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// PurgeOldestDay deletes the oldest day directory (data/spreads/YYYYMMDD/)
+// The most recent day is never purged since it holds the files currently being written
+// Returns the removed directory, or "" if there was nothing left to purge
+func (r *CSVSpreadRecorder) PurgeOldestDay() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	days, err := dayDirs(r.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to list %s: %w", r.baseDir, err)
+	}
+	if len(days) < 2 {
+		return "", nil
+	}
+
+	dirPath := filepath.Join(r.baseDir, days[0])
+	if err := os.RemoveAll(dirPath); err != nil {
+		return "", fmt.Errorf("failed to remove %s: %w", dirPath, err)
+	}
+
+	log.Printf("CSVSpreadRecorder: 🗑️ Purged oldest day: %s", dirPath)
+	return dirPath, nil
+}
+
+// expiredDays returns the day directories under baseDir whose whole UTC day is before cutoff,
+// except those in keep (days with open files) and the most recent day, kept like in PurgeOldestDay
+func expiredDays(baseDir string, cutoff time.Time, keep map[string]bool) ([]string, error) {
+	days, err := dayDirs(baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", baseDir, err)
+	}
+
+	var expired []string
+	for _, day := range days[:max(len(days)-1, 0)] {
+		start, _ := time.Parse("20060102", day) // Checked by dayDirs
+		if start.AddDate(0, 0, 1).After(cutoff) {
+			break
+		}
+		if !keep[day] {
+			expired = append(expired, filepath.Join(baseDir, day))
+		}
+	}
+	return expired, nil
+}
+
+// removeDays deletes day directories (unless dryRun) and returns those removed
+func removeDays(ctx context.Context, dirs []string, dryRun bool) ([]string, error) {
+	if dryRun {
+		return dirs, nil
+	}
+	for i, dirPath := range dirs {
+		if err := ctx.Err(); err != nil {
+			return dirs[:i], err
+		}
+		if err := os.RemoveAll(dirPath); err != nil {
+			return dirs[:i], fmt.Errorf("failed to remove %s: %w", dirPath, err)
+		}
+	}
+	return dirs, nil
+}
+
+// pruneDays deletes the day directories under baseDir recorded entirely before cutoff, for writers
+// that only keep files of the current hour open (see expiredDays)
+func pruneDays(ctx context.Context, baseDir string, cutoff time.Time, dryRun bool) ([]string, error) {
+	expired, err := expiredDays(baseDir, cutoff, nil)
+	if err != nil {
+		return nil, err
+	}
+	return removeDays(ctx, expired, dryRun)
+}
+
+// Prune deletes the day directories recorded entirely before cutoff (see ports.Pruner)
+// Days are chosen under the lock, skipping those with an open file (a late tick), and deleted
+// after releasing it, so recording doesn't wait for the disk
+func (r *CSVSpreadRecorder) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	r.mu.Lock()
+	open := make(map[string]bool)
+	for _, file := range r.files {
+		open[file.hour.Format("20060102")] = true
+	}
+	for _, hour := range r.evicted {
+		open[hour.Format("20060102")] = true
+	}
+	expired, err := expiredDays(r.baseDir, cutoff, open)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return removeDays(ctx, expired, dryRun)
+}
+
+// Prune deletes the day directories recorded entirely before cutoff (see ports.Pruner)
+// Like CSVSpreadRecorder.Prune, deleting happens outside the lock
+func (r *ArrowRecorder) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	r.mu.Lock()
+	open := map[string]bool{}
+	if r.hourKey != "" {
+		open[r.hourKey[:8]] = true // YYYYMMDD_HH
+	}
+	expired, err := expiredDays(r.baseDir, cutoff, open)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return removeDays(ctx, expired, dryRun)
+}
+
+// Prune deletes the snapshot day directories recorded entirely before cutoff (see ports.Pruner)
+func (r *CSVSnapshotRecorder) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	return pruneDays(ctx, r.baseDir, cutoff, dryRun)
+}
+
+// Prune deletes the aligned quote day directories recorded entirely before cutoff (see ports.Pruner)
+func (r *CSVAlignedRecorder) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	return pruneDays(ctx, r.baseDir, cutoff, dryRun)
+}
+
+// Prune deletes the histogram day directories recorded entirely before cutoff (see ports.Pruner)
+func (r *CSVHistogramRecorder) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	return pruneDays(ctx, r.baseDir, cutoff, dryRun)
+}
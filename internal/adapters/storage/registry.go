@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// RecorderSpec is a parsed sink definition: name[?key=value&...], e.g. "csv?dir=/mnt/ticks"
+// A definition can name fallback sinks after "|": "mqtt|csv?dir=/mnt/fallback"
+type RecorderSpec struct {
+	Name     string
+	Params   url.Values
+	Fallback *RecorderSpec // Sink taking the writes while this one fails (optional)
+}
+
+// ParseRecorderSpec parses a sink definition; parameter values are URL query encoded
+func ParseRecorderSpec(s string) (RecorderSpec, error) {
+	s, fallback, hasFallback := strings.Cut(s, "|")
+	spec, err := parseSingleRecorderSpec(s)
+	if err != nil || !hasFallback {
+		return spec, err
+	}
+	next, err := ParseRecorderSpec(fallback)
+	if err != nil {
+		return RecorderSpec{}, fmt.Errorf("invalid fallback of recorder %s: %w", spec.Name, err)
+	}
+	spec.Fallback = &next
+	return spec, nil
+}
+
+// parseSingleRecorderSpec parses one sink of a definition
+func parseSingleRecorderSpec(s string) (RecorderSpec, error) {
+	name, query, _ := strings.Cut(strings.TrimSpace(s), "?")
+	if name == "" {
+		return RecorderSpec{}, fmt.Errorf("recorder %q has no name", s)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return RecorderSpec{}, fmt.Errorf("invalid parameters for recorder %s: %w", name, err)
+	}
+	return RecorderSpec{Name: strings.ToLower(name), Params: params}, nil
+}
+
+// String returns the spec in its config form
+func (s RecorderSpec) String() string {
+	text := s.Name
+	if len(s.Params) > 0 {
+		text += "?" + s.Params.Encode()
+	}
+	if s.Fallback != nil {
+		text += "|" + s.Fallback.String()
+	}
+	return text
+}
+
+// Param returns a parameter or defaultValue if it isn't set
+func (s RecorderSpec) Param(key, defaultValue string) string {
+	if value := s.Params.Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// WithDefaults returns a copy with parameters missing from the spec taken from defaults
+// The fallback chain is kept as is
+func (s RecorderSpec) WithDefaults(defaults url.Values) RecorderSpec {
+	params := maps.Clone(s.Params)
+	if params == nil {
+		params = url.Values{}
+	}
+	for key, values := range defaults {
+		if _, ok := params[key]; !ok {
+			params[key] = values
+		}
+	}
+	return RecorderSpec{Name: s.Name, Params: params, Fallback: s.Fallback}
+}
+
+// PriceFormat returns the "format" parameter (default float)
+func (s RecorderSpec) PriceFormat() (domain.PriceFormat, error) {
+	return domain.ParsePriceFormat(s.Param("format", string(domain.PriceFormatFloat)))
+}
+
+// RecorderFactory creates a sink from its spec
+type RecorderFactory func(spec RecorderSpec) (ports.TickWriter, error)
+
+var (
+	recorderFactoriesMu sync.RWMutex
+	recorderFactories   = make(map[string]RecorderFactory)
+)
+
+// RegisterRecorder makes a sink available by name, typically from the adapter's init function
+// Registering the same name twice panics, as with database/sql drivers
+func RegisterRecorder(name string, factory RecorderFactory) {
+	recorderFactoriesMu.Lock()
+	defer recorderFactoriesMu.Unlock()
+
+	if factory == nil {
+		panic("storage: RegisterRecorder factory is nil")
+	}
+	if _, dup := recorderFactories[name]; dup {
+		panic("storage: RegisterRecorder called twice for " + name)
+	}
+	recorderFactories[name] = factory
+}
+
+// RecorderNames returns the registered sink names, sorted
+func RecorderNames() []string {
+	recorderFactoriesMu.RLock()
+	defer recorderFactoriesMu.RUnlock()
+	return slices.Sorted(maps.Keys(recorderFactories))
+}
+
+// NewRecorder creates the sink registered under spec.Name
+func NewRecorder(spec RecorderSpec) (ports.TickWriter, error) {
+	recorderFactoriesMu.RLock()
+	factory, ok := recorderFactories[spec.Name]
+	recorderFactoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown recorder %q (supported: %s)", spec.Name, strings.Join(RecorderNames(), ", "))
+	}
+
+	recorder, err := factory(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s recorder: %w", spec.Name, err)
+	}
+	return recorder, nil
+}
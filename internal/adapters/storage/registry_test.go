@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"net/url"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseRecorderSpec(t *testing.T) {
+	spec, err := ParseRecorderSpec(" Arrow?dir=/mnt/ticks&format=decimal ")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if spec.Name != "arrow" || spec.Param("dir", "") != "/mnt/ticks" || spec.Param("missing", "x") != "x" {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+	if got := spec.String(); got != "arrow?dir=%2Fmnt%2Fticks&format=decimal" {
+		t.Errorf("Unexpected String(): %s", got)
+	}
+
+	chain, err := ParseRecorderSpec("mqtt?qos=1|csv?dir=fallback|ndjson")
+	if err != nil {
+		t.Fatalf("Failed to parse chain: %v", err)
+	}
+	if chain.Name != "mqtt" || chain.Fallback == nil || chain.Fallback.Param("dir", "") != "fallback" ||
+		chain.Fallback.Fallback == nil || chain.Fallback.Fallback.Name != "ndjson" {
+		t.Errorf("Unexpected chain: %+v", chain)
+	}
+	if got := chain.String(); got != "mqtt?qos=1|csv?dir=fallback|ndjson" {
+		t.Errorf("Unexpected String(): %s", got)
+	}
+
+	for _, invalid := range []string{"", "?dir=x", "csv?dir=%zz", "csv|", "csv|?dir=x"} {
+		if _, err := ParseRecorderSpec(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestRecorderSpec_WithDefaults(t *testing.T) {
+	spec, _ := ParseRecorderSpec("csv?dir=custom")
+	merged := spec.WithDefaults(url.Values{"dir": {"default"}, "format": {"decimal"}})
+
+	if merged.Param("dir", "") != "custom" || merged.Param("format", "") != "decimal" {
+		t.Errorf("Unexpected merge: %+v", merged)
+	}
+	if spec.Params.Has("format") {
+		t.Error("WithDefaults modified the original spec")
+	}
+}
+
+func TestNewRecorder(t *testing.T) {
+	for _, name := range []string{"csv", "ndjson", "arrow"} {
+		if !slices.Contains(RecorderNames(), name) {
+			t.Errorf("Expected %s to be registered", name)
+		}
+	}
+
+	spec, _ := ParseRecorderSpec("csv?dir=" + url.QueryEscape(filepath.Join(t.TempDir(), "spreads")))
+	recorder, err := NewRecorder(spec)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	if _, ok := recorder.(*CSVSpreadRecorder); !ok {
+		t.Errorf("Expected *CSVSpreadRecorder, got %T", recorder)
+	}
+
+	if _, err := NewRecorder(RecorderSpec{Name: "postgres"}); err == nil {
+		t.Error("Expected error for unregistered recorder")
+	}
+	if _, err := NewRecorder(RecorderSpec{Name: "arrow", Params: url.Values{"format": {"binary"}}}); err == nil {
+		t.Error("Expected error for invalid format")
+	}
+}
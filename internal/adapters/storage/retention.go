@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// ParseRetention parses a retention period: a Go duration ("720h") or a number of days ("30d")
+func ParseRetention(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention %q (e.g. 30d or 720h)", s)
+	}
+	return d, nil
+}
+
+// RetentionRecorder attaches a retention period to a sink (retain=30d), so one central job can
+// prune every sink by its own policy: raw ticks 30 days on a local disk, two years on an archive mount
+type RetentionRecorder struct {
+	next   ports.TickWriter
+	name   string
+	retain time.Duration
+	pruner ports.Pruner
+}
+
+// NewRetentionRecorder wraps next with a retention period; next must be able to prune
+func NewRetentionRecorder(next ports.TickWriter, name string, retain time.Duration) (*RetentionRecorder, error) {
+	pruner, ok := As[ports.Pruner](next)
+	if !ok {
+		return nil, fmt.Errorf("recorder %s doesn't support retention", name)
+	}
+	return &RetentionRecorder{next: next, name: name, retain: retain, pruner: pruner}, nil
+}
+
+// Name returns the sink's name
+func (r *RetentionRecorder) Name() string {
+	return r.name
+}
+
+// Retention returns how long the sink keeps its data
+func (r *RetentionRecorder) Retention() time.Duration {
+	return r.retain
+}
+
+// Prune deletes what the sink recorded before cutoff (see ports.Pruner)
+func (r *RetentionRecorder) Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error) {
+	return r.pruner.Prune(ctx, cutoff, dryRun)
+}
+
+// Record saves a single price data point
+func (r *RetentionRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	return r.next.Record(ctx, data)
+}
+
+// RecordBatch saves multiple price data points efficiently
+func (r *RetentionRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	return r.next.RecordBatch(ctx, data)
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *RetentionRecorder) Flush(ctx context.Context) error {
+	if flusher, ok := r.next.(ports.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// Close finalizes the recording session and releases resources
+func (r *RetentionRecorder) Close(ctx context.Context) error {
+	if closer, ok := r.next.(ports.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped recorder
+func (r *RetentionRecorder) Unwrap() ports.TickWriter {
+	return r.next
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestParseRetention(t *testing.T) {
+	for input, want := range map[string]time.Duration{"30d": 30 * 24 * time.Hour, "720h": 720 * time.Hour, " 1d ": 24 * time.Hour} {
+		if got, err := ParseRetention(input); err != nil || got != want {
+			t.Errorf("ParseRetention(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "0d", "-1h", "30 days"} {
+		if _, err := ParseRetention(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestRetentionRecorder_Prune(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, day := range []string{"20251101", "20251102", "20251103", "20251104"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, day), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder, err := NewRetentionRecorder(NewCSVSpreadRecorder(tmpDir), "csv", 2*24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewRetentionRecorder failed: %v", err)
+	}
+	// Two days before noon on the 4th is noon on the 2nd: only the 1st ended before that
+	ctx := context.Background()
+	cutoff := time.Date(2025, 11, 4, 12, 0, 0, 0, time.UTC).Add(-recorder.Retention())
+	want := []string{filepath.Join(tmpDir, "20251101")}
+
+	removed, err := recorder.Prune(ctx, cutoff, true)
+	if err != nil || !slices.Equal(removed, want) {
+		t.Fatalf("Dry run: expected %v, got %v (%v)", want, removed, err)
+	}
+	if _, err := os.Stat(want[0]); err != nil {
+		t.Errorf("Expected the dry run to keep %s: %v", want[0], err)
+	}
+
+	removed, err = recorder.Prune(ctx, cutoff, false)
+	if err != nil || !slices.Equal(removed, want) {
+		t.Fatalf("Expected %v, got %v (%v)", want, removed, err)
+	}
+	days, _ := dayDirs(tmpDir)
+	if !slices.Equal(days, []string{"20251102", "20251103", "20251104"}) {
+		t.Errorf("Unexpected days left: %v", days)
+	}
+
+	// The newest day is kept whatever the retention
+	removed, _ = recorder.Prune(ctx, cutoff.AddDate(1, 0, 0), false)
+	if len(removed) != 2 {
+		t.Errorf("Expected all but the newest day removed, got %v", removed)
+	}
+
+	if _, err := NewRetentionRecorder(&flakyRecorder{}, "flaky", time.Hour); err == nil {
+		t.Error("Expected an error for a sink that can't prune")
+	}
+}
+
+func TestCSVSpreadRecorder_PruneKeepsDaysWithOpenFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+	defer recorder.Close(context.Background())
+	ctx := context.Background()
+	for _, ts := range []time.Time{
+		time.Date(2025, 11, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2025, 11, 2, 10, 0, 0, 0, time.UTC),
+		time.Date(2025, 11, 3, 10, 0, 0, 0, time.UTC),
+	} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, ts.Format("20060102")), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if ts.Day() == 2 {
+			// A late tick keeps a file of the 2nd open
+			data := &domain.PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Decimals: 4}
+			if err := recorder.Record(ctx, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	removed, err := recorder.Prune(ctx, time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC), false)
+	if err != nil || !slices.Equal(removed, []string{filepath.Join(tmpDir, "20251101")}) {
+		t.Errorf("Expected only the 1st removed, got %v, %v", removed, err)
+	}
+}
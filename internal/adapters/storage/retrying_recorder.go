@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// RetryConfig controls retry behaviour for storage writes
+type RetryConfig struct {
+	MaxAttempts    int           // Total attempts including the first (1 = no retry)
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the exponentially growing delay
+}
+
+// RetryingRecorder wraps a TickWriter, retrying failed writes with exponential backoff
+// Ticks that still fail after all attempts are written to the dead-letter queue
+type RetryingRecorder struct {
+	next       ports.TickWriter
+	config     RetryConfig
+	deadLetter ports.DeadLetterQueue
+}
+
+// NewRetryingRecorder creates a retrying decorator around next
+// deadLetter may be nil, in which case permanently failed ticks are only reported as errors
+func NewRetryingRecorder(next ports.TickWriter, config RetryConfig, deadLetter ports.DeadLetterQueue) *RetryingRecorder {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &RetryingRecorder{
+		next:       next,
+		config:     config,
+		deadLetter: deadLetter,
+	}
+}
+
+// Record saves a single price data point, retrying on failure
+// A tick the inner recorder reported as accepted (ports.PartialWriteError) is not written again
+func (r *RetryingRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	err := r.retry(ctx, func() error {
+		err := r.next.Record(ctx, data)
+		var partial *ports.PartialWriteError
+		if errors.As(err, &partial) {
+			log.Printf("RetryingRecorder: Tick accepted, writing it failed: %v", partial.Err)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		r.deadLetterTicks(ctx, []*domain.PriceData{data}, err)
+		return err
+	}
+	return nil
+}
+
+// RecordBatch saves multiple price data points, retrying on failure
+// A retry resumes after the ticks the inner recorder reported as accepted (ports.PartialWriteError);
+// once all are accepted, a failure to write them is the inner recorder's to report on Flush
+// If the retries run out, the ticks accepted over all attempts are reported the same way
+func (r *RetryingRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	remaining := data
+	err := r.retry(ctx, func() error {
+		err := r.next.RecordBatch(ctx, remaining)
+		var partial *ports.PartialWriteError
+		if errors.As(err, &partial) {
+			remaining = remaining[min(partial.Written, len(remaining)):]
+			if len(remaining) == 0 {
+				log.Printf("RetryingRecorder: Batch accepted, writing it failed: %v", partial.Err)
+				return nil
+			}
+			return partial.Err // Counted against the whole batch below
+		}
+		return err
+	})
+	if err != nil {
+		r.deadLetterTicks(ctx, remaining, err)
+		return ports.PartialWrite(len(data)-len(remaining), err)
+	}
+	return nil
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *RetryingRecorder) Flush(ctx context.Context) error {
+	if flusher, ok := r.next.(ports.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// Close finalizes the recording session and releases resources
+func (r *RetryingRecorder) Close(ctx context.Context) error {
+	if closer, ok := r.next.(ports.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped recorder
+func (r *RetryingRecorder) Unwrap() ports.TickWriter {
+	return r.next
+}
+
+// retry runs op until it succeeds, attempts are exhausted, or ctx is cancelled
+func (r *RetryingRecorder) retry(ctx context.Context, op func() error) error {
+	backoff := r.config.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = op(); err == nil {
+			return nil
+		}
+		if attempt >= r.config.MaxAttempts {
+			return fmt.Errorf("write failed after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("RetryingRecorder: Write attempt %d/%d failed, retrying in %v: %v",
+			attempt, r.config.MaxAttempts, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("write retry cancelled: %w", err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, r.config.MaxBackoff)
+	}
+}
+
+// deadLetterTicks routes permanently failed ticks to the dead-letter queue
+func (r *RetryingRecorder) deadLetterTicks(ctx context.Context, data []*domain.PriceData, cause error) {
+	if r.deadLetter == nil {
+		return
+	}
+
+	for _, priceData := range data {
+		entry := domain.DeadLetter{
+			Timestamp: time.Now().UTC(),
+			Reason:    "record_failed",
+			Error:     cause.Error(),
+			Payload:   priceData,
+		}
+		// Use a fresh context: the write may have failed because ctx was cancelled during shutdown
+		if err := r.deadLetter.Put(context.WithoutCancel(ctx), entry); err != nil {
+			log.Printf("RetryingRecorder: ❌ Failed to dead-letter tick for %s: %v", priceData.Ticker, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// flakyRecorder fails the first failures writes
+type flakyRecorder struct {
+	failures int
+	calls    int
+	recorded []*domain.PriceData
+}
+
+func (f *flakyRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("disk busy")
+	}
+	f.recorded = append(f.recorded, data)
+	return nil
+}
+
+func (f *flakyRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	for _, d := range data {
+		if err := f.Record(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *flakyRecorder) Flush(ctx context.Context) error { return nil }
+func (f *flakyRecorder) Close(ctx context.Context) error { return nil }
+
+func testRetryConfig(attempts int) RetryConfig {
+	return RetryConfig{
+		MaxAttempts:    attempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+	}
+}
+
+func TestRetryingRecorder_RecoversFromTransientError(t *testing.T) {
+	tmpDir := t.TempDir()
+	inner := &flakyRecorder{failures: 2}
+	recorder := NewRetryingRecorder(inner, testRetryConfig(3), NewNDJSONDeadLetterQueue(tmpDir, "failed_ticks"))
+
+	data := &domain.PriceData{Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC), Ticker: "EURUSD"}
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Expected retry to succeed: %v", err)
+	}
+	if len(inner.recorded) != 1 {
+		t.Fatalf("Expected 1 recorded tick, got %d", len(inner.recorded))
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Fatalf("Expected no dead-letter files, found %d", len(entries))
+	}
+}
+
+func TestRetryingRecorder_DeadLettersPermanentFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	inner := &flakyRecorder{failures: 10}
+	recorder := NewRetryingRecorder(inner, testRetryConfig(3), NewNDJSONDeadLetterQueue(tmpDir, "failed_ticks"))
+
+	data := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Ticker:    "EURUSD",
+		Bid:       1.1,
+		Ask:       1.1002,
+	}
+	if err := recorder.Record(context.Background(), data); err == nil {
+		t.Fatal("Expected error after exhausting retries")
+	}
+	if inner.calls != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", inner.calls)
+	}
+
+	files, _ := os.ReadDir(tmpDir)
+	if len(files) != 1 || !strings.HasPrefix(files[0].Name(), "failed_ticks_") {
+		t.Fatalf("Expected one dead-letter file, got %v", files)
+	}
+
+	content, err := os.ReadFile(tmpDir + "/" + files[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read dead-letter file: %v", err)
+	}
+
+	var entry struct {
+		Reason  string           `json:"reason"`
+		Error   string           `json:"error"`
+		Payload domain.PriceData `json:"payload"`
+	}
+	if err := json.Unmarshal(content, &entry); err != nil {
+		t.Fatalf("Dead-letter line is not valid JSON: %v", err)
+	}
+	if entry.Reason != "record_failed" || entry.Payload.Ticker != "EURUSD" || !strings.Contains(entry.Error, "disk busy") {
+		t.Fatalf("Unexpected dead-letter entry: %+v", entry)
+	}
+}
+
+func TestRetryingRecorder_ResumesAfterPartialWrite(t *testing.T) {
+	// The 2nd and 4th writes fail: each of the first two attempts stores one tick
+	var calls int
+	var recorded []*domain.PriceData
+	recorder := NewRetryingRecorder(recordFunc(func(d *domain.PriceData) error {
+		calls++
+		if calls == 2 || calls == 4 {
+			return errors.New("disk busy")
+		}
+		recorded = append(recorded, d)
+		return nil
+	}), testRetryConfig(3), nil)
+
+	batch := []*domain.PriceData{{Ticker: "EURUSD"}, {Ticker: "USDJPY"}, {Ticker: "GBPUSD"}}
+	if err := recorder.RecordBatch(context.Background(), batch); err != nil {
+		t.Fatalf("Expected the retries to succeed: %v", err)
+	}
+	if len(recorded) != 3 {
+		t.Fatalf("Expected every tick recorded once, got %d", len(recorded))
+	}
+	for i, d := range recorded {
+		if d != batch[i] {
+			t.Errorf("Tick %d: expected %s, got %s", i, batch[i].Ticker, d.Ticker)
+		}
+	}
+}
+
+func TestRetryingRecorder_ReportsTicksAcceptedOverAllAttempts(t *testing.T) {
+	// Every write after the 1st and 2nd fails: each of the first two attempts stores one tick
+	var calls int
+	recorder := NewRetryingRecorder(recordFunc(func(d *domain.PriceData) error {
+		if calls++; calls > 1 && calls != 3 {
+			return errors.New("disk busy")
+		}
+		return nil
+	}), testRetryConfig(3), nil)
+
+	batch := []*domain.PriceData{{Ticker: "EURUSD"}, {Ticker: "USDJPY"}, {Ticker: "GBPUSD"}}
+	err := recorder.RecordBatch(context.Background(), batch)
+	var partial *ports.PartialWriteError
+	if !errors.As(err, &partial) || partial.Written != 2 {
+		t.Fatalf("Expected 2 of the batch reported as accepted, got %v", err)
+	}
+}
+
+// recordFunc is a TickWriter reporting partial batches, recording each tick with fn
+type recordFunc func(*domain.PriceData) error
+
+func (fn recordFunc) Record(ctx context.Context, data *domain.PriceData) error {
+	return fn(data)
+}
+
+func (fn recordFunc) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	for i, d := range data {
+		if err := fn(d); err != nil {
+			return ports.PartialWrite(i, err)
+		}
+	}
+	return nil
+}
+
+func TestAs_FindsCapabilityBehindDecorator(t *testing.T) {
+	csvRecorder := NewCSVSpreadRecorder(t.TempDir())
+	defer csvRecorder.Close(context.Background())
+
+	wrapped := NewRetryingRecorder(csvRecorder, testRetryConfig(1), nil)
+	purger, ok := As[interface{ PurgeOldestDay() (string, error) }](wrapped)
+	if !ok {
+		t.Fatal("Expected to find purge capability behind retry decorator")
+	}
+	if _, err := purger.PurgeOldestDay(); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+}
+
+func TestRetryingRecorder_WritesAcceptedTickOnce(t *testing.T) {
+	dataDir, deadDir := t.TempDir(), t.TempDir()
+	csvRecorder := NewCSVSpreadRecorder(dataDir)
+	csvRecorder.SetSyncPolicy(SyncPolicy{Mode: SyncEvery, Records: 1}) // Each tick is written out by Record
+	defer csvRecorder.Close(context.Background())
+	recorder := NewRetryingRecorder(csvRecorder, testRetryConfig(3), NewNDJSONDeadLetterQueue(deadDir, "failed_ticks"))
+
+	ctx := context.Background()
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	if err := recorder.Record(ctx, &domain.PriceData{Timestamp: start, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes fail until the file is writable again
+	file := csvRecorder.files["EURUSD_20251118_12"]
+	writable := file.file
+	readOnly, err := os.Open(writable.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Close()
+	file.file = readOnly
+
+	if err := recorder.Record(ctx, &domain.PriceData{Timestamp: start.Add(time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1003}); err != nil {
+		t.Fatalf("Expected the buffered tick to count as recorded, got %v", err)
+	}
+	file.file = writable
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Expected the kept row to be written on Flush: %v", err)
+	}
+
+	content, err := os.ReadFile(writable.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(content), "2025-11-18T12:00:01Z"); n != 1 {
+		t.Errorf("Expected the tick written once, got %d times:\n%s", n, content)
+	}
+	if entries, _ := os.ReadDir(deadDir); len(entries) != 0 {
+		t.Errorf("Expected no dead-letter files, found %d", len(entries))
+	}
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// RunJournal appends run records to a JSON Lines file (one domain.RunRecord per line)
+// The file is opened and synced per write: there are two records per run, and the stop
+// record must survive the process exiting right after it
+type RunJournal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewRunJournal creates a run journal writing to path (e.g. data/runs.jsonl)
+func NewRunJournal(path string) *RunJournal {
+	return &RunJournal{path: path}
+}
+
+// Append writes one record, on a line of its own even after a crash cut off the last one
+func (j *RunJournal) Append(record domain.RunRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode run record: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", j.path, err)
+	}
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open run journal %s: %w", j.path, err)
+	}
+	terminated, err := endsWithNewline(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to read run journal %s: %w", j.path, err)
+	}
+	if !terminated {
+		line = append([]byte{'\n'}, line...)
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write run journal %s: %w", j.path, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync run journal %s: %w", j.path, err)
+	}
+	return file.Close()
+}
+
+// endsWithNewline reports whether file is empty or its last line is complete
+func endsWithNewline(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() == 0 {
+		return true, nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
+
+// ReadRunJournal reads all records of a run journal in file order
+// A missing file has no runs; a line cut off by a crash is skipped
+func ReadRunJournal(path string) ([]domain.RunRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run journal: %w", err)
+	}
+	defer file.Close()
+
+	var records []domain.RunRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record domain.RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run journal: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestRunJournal_AppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "runs.jsonl")
+	journal := NewRunJournal(path)
+
+	// Missing file means no runs yet
+	records, err := ReadRunJournal(path)
+	if err != nil {
+		t.Fatalf("ReadRunJournal failed: %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("Expected no records, got %v", records)
+	}
+
+	started := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	start := domain.RunRecord{
+		RunID:       "run-1",
+		Event:       domain.RunStarted,
+		Timestamp:   started,
+		StartedAt:   started,
+		ConfigHash:  "abc123",
+		Settings:    map[string]string{"SPREAD_FLUSH_INTERVAL": "30s"},
+		Instruments: []string{"EURUSD", "USDJPY"},
+	}
+	stop := domain.RunRecord{
+		RunID:     "run-1",
+		Event:     domain.RunStopped,
+		Timestamp: started.Add(time.Hour),
+		StartedAt: started,
+		Reason:    "signal: terminated",
+	}
+	for _, record := range []domain.RunRecord{start, stop} {
+		if err := journal.Append(record); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	records, err = ReadRunJournal(path)
+	if err != nil {
+		t.Fatalf("ReadRunJournal failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Event != domain.RunStarted || len(records[0].Instruments) != 2 || records[0].Settings["SPREAD_FLUSH_INTERVAL"] != "30s" {
+		t.Errorf("Unexpected start record: %+v", records[0])
+	}
+	if records[1].Event != domain.RunStopped || records[1].Reason != "signal: terminated" || !records[1].StartedAt.Equal(started) {
+		t.Errorf("Unexpected stop record: %+v", records[1])
+	}
+}
+
+func TestReadRunJournal_SkipsTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	if err := NewRunJournal(path).Append(domain.RunRecord{RunID: "run-1", Event: domain.RunStarted}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	// A crash mid-write leaves a partial line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"run_id":"run-2","eve`)
+	file.Close()
+
+	records, err := ReadRunJournal(path)
+	if err != nil {
+		t.Fatalf("ReadRunJournal failed: %v", err)
+	}
+	if len(records) != 1 || records[0].RunID != "run-1" {
+		t.Errorf("Expected only run-1, got %+v", records)
+	}
+
+	// The next record starts on a line of its own
+	if err := NewRunJournal(path).Append(domain.RunRecord{RunID: "run-3", Event: domain.RunStarted}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	records, err = ReadRunJournal(path)
+	if err != nil {
+		t.Fatalf("ReadRunJournal failed: %v", err)
+	}
+	if len(records) != 2 || records[1].RunID != "run-3" {
+		t.Errorf("Expected run-1 and run-3, got %+v", records)
+	}
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/buildinfo"
+)
+
+// Schema identifies the layout of a produced file: its dataset and the version of its columns
+type Schema struct {
+	Dataset string   `json:"dataset"`
+	Version int      `json:"version"`
+	Columns []string `json:"columns,omitempty"`
+
+	// Build that last wrote the file, e.g. "v1.4.0 (1560e70)"; set by writeSchema
+	WrittenBy string `json:"written_by,omitempty"`
+}
+
+// String returns dataset/vN, the form used in Arrow metadata
+func (s Schema) String() string {
+	return fmt.Sprintf("%s/v%d", s.Dataset, s.Version)
+}
+
+// SchemaSidecar is the file in every CSV output directory naming the schema of each file in it
+const SchemaSidecar = "schema.json"
+
+// ErrNewerSchema is returned for files written by a newer version than the reader knows
+var ErrNewerSchema = errors.New("written by a newer schema version")
+
+// spreadColumnHistory lists the columns each version of the hourly tick files added
+// A version only ever appends columns, so readers of older files see the missing ones as empty
+var spreadColumnHistory = [][]string{
+	{"timestamp", "uic", "ticker", "asset_type", "bid", "ask", "spread"}, // v1
+	{"seq"},              // v2
+	{"session"},          // v3
+	{"flags"},            // v4
+	{"spread_unit"},      // v5
+	{"effective_spread"}, // v6
+	{"source"},           // v7
+	{"spread_z"},         // v8
+}
+
+// spreadSchemas holds every version of the hourly tick files, oldest first
+var spreadSchemas = func() []Schema {
+	var schemas []Schema
+	var columns []string
+	for i, added := range spreadColumnHistory {
+		columns = append(slices.Clip(columns), added...)
+		schemas = append(schemas, Schema{Dataset: "spreads", Version: i + 1, Columns: columns})
+	}
+	return schemas
+}()
+
+// Current schemas of the CSV datasets
+var (
+	SpreadSchema   = spreadSchemas[len(spreadSchemas)-1]
+	SnapshotSchema = Schema{Dataset: "snapshots", Version: 2, Columns: snapshotColumns} // v2 added recorded
+	OpsLogSchema   = Schema{Dataset: "ops", Version: 1, Columns: opsLogColumns}
+	CalendarSchema = Schema{Dataset: "calendar", Version: 1, Columns: calendarColumns}
+)
+
+// ArrowSpreadSchema is the schema of the hourly Arrow files, recorded in their custom_metadata
+// The Arrow columns are the CSV columns (files before v8 stop at spread_unit, v5); PriceFormatDecimal
+// files add a decimals column
+var ArrowSpreadSchema = SpreadSchema
+
+// AlignedSchema is the schema of aligned quote files, whose columns follow the instrument set
+func AlignedSchema(columns []string) Schema {
+	return Schema{Dataset: "aligned", Version: 1, Columns: columns}
+}
+
+// sidecarMu serializes sidecar updates of all writers in the process
+var sidecarMu sync.Mutex
+
+// Tools like cmd/import and cmd/backfill write into the collector's directories, so updates are
+// also serialized between processes with a lock file next to the sidecar
+const (
+	sidecarLockTimeout = 5 * time.Second  // Wait for another process's update
+	sidecarLockStale   = 30 * time.Second // A lock this old was left by a crashed writer
+)
+
+// writeSchema records the schema of fileName in the sidecar of dirPath
+// The sidecar is replaced atomically so readers never see a partial manifest
+func writeSchema(dirPath, fileName string, schema Schema) error {
+	sidecarMu.Lock()
+	defer sidecarMu.Unlock()
+
+	path := filepath.Join(dirPath, SchemaSidecar)
+	unlock, err := lockSidecar(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return updateSidecar(path, fileName, schema)
+}
+
+// updateSidecar adds or replaces the entry of fileName; the caller holds the sidecar lock
+func updateSidecar(path, fileName string, schema Schema) error {
+	schemas, err := readSidecar(path)
+	if err != nil {
+		return err
+	}
+	schema.WrittenBy = buildinfo.Get().Short()
+	if existing, ok := schemas[fileName]; ok && existing.Version == schema.Version && existing.Dataset == schema.Dataset &&
+		slices.Equal(existing.Columns, schema.Columns) && existing.WrittenBy == schema.WrittenBy {
+		return nil
+	}
+	schemas[fileName] = schema
+
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	// A unique temporary file, so a writer that lost its lock can't publish another's content
+	tmp, err := os.CreateTemp(filepath.Dir(path), SchemaSidecar+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// lockSidecar creates the lock file of a sidecar, waiting while another process holds it
+// The returned function releases the lock
+func lockSidecar(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(sidecarLockTimeout)
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > sidecarLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock %s: held by another process for over %s", path, sidecarLockTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readSidecar reads a sidecar; a missing one is empty
+func readSidecar(path string) (map[string]Schema, error) {
+	schemas := make(map[string]Schema)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return schemas, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return schemas, nil
+}
+
+// ReadSchema returns the schema of a file from the sidecar next to it
+// ok is false for files written before sidecars existed
+func ReadSchema(path string) (schema Schema, ok bool, err error) {
+	schemas, err := readSidecar(filepath.Join(filepath.Dir(path), SchemaSidecar))
+	if err != nil {
+		return Schema{}, false, err
+	}
+	schema, ok = schemas[strings.TrimSuffix(filepath.Base(path), PartialSuffix)]
+	return schema, ok, nil
+}
+
+// spreadSchemaOf identifies the version of an hourly tick file from its sidecar entry or,
+// for files written before sidecars, from its header
+// Files from a newer version fail with ErrNewerSchema instead of silently losing columns
+func spreadSchemaOf(path string, header []string) (Schema, error) {
+	schema, ok, err := ReadSchema(path)
+	if err != nil {
+		return Schema{}, err
+	}
+	if ok {
+		if schema.Dataset != SpreadSchema.Dataset {
+			return Schema{}, fmt.Errorf("%s holds %s, not spreads", path, schema.Dataset)
+		}
+		if schema.Version > SpreadSchema.Version {
+			return Schema{}, fmt.Errorf("%s is %s, this reader knows up to v%d: %w", path, schema, SpreadSchema.Version, ErrNewerSchema)
+		}
+		return schema, nil
+	}
+
+	for _, schema := range spreadSchemas {
+		if slices.Equal(header, schema.Columns) {
+			return schema, nil
+		}
+	}
+	for _, column := range header {
+		if !slices.Contains(SpreadSchema.Columns, column) {
+			return Schema{}, fmt.Errorf("%s: unknown column %q: %w", path, column, ErrNewerSchema)
+		}
+	}
+	// Known columns in another order (e.g. rewritten by an external tool); columns are read by name
+	return Schema{Dataset: SpreadSchema.Dataset, Columns: header}, nil
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/buildinfo"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+func TestCSVSpreadRecorder_WritesSchemaSidecar(t *testing.T) {
+	tmpDir := t.TempDir()
+	recorder := NewCSVSpreadRecorder(tmpDir)
+
+	data := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Uic:       21,
+		Ticker:    "EURUSD",
+		AssetType: "FxSpot",
+		Bid:       1.10000,
+		Ask:       1.10002,
+		Decimals:  5,
+	}
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	schema, ok, err := ReadSchema(filepath.Join(tmpDir, "20251118", "EURUSD_12.csv"))
+	if err != nil || !ok {
+		t.Fatalf("Expected a sidecar entry, got ok=%v err=%v", ok, err)
+	}
+	if schema.String() != SpreadSchema.String() || !slices.Equal(schema.Columns, spreadColumns) || schema.WrittenBy == "" {
+		t.Errorf("Unexpected schema %v %v", schema, schema.Columns)
+	}
+}
+
+func TestSpreadSchemaOf_V1Header(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "EURUSD_12.csv")
+	content := "timestamp,uic,ticker,asset_type,bid,ask,spread\n" +
+		"2025-11-18T12:00:00Z,21,EURUSD,FxSpot,1.10000,1.10002,0.00002\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := spreadSchemaOf(path, []string{"timestamp", "uic", "ticker", "asset_type", "bid", "ask", "spread"})
+	if err != nil || schema.Version != 1 {
+		t.Fatalf("Expected v1, got %v (%v)", schema, err)
+	}
+
+	var ticks int
+	if err := ReadSpreadFile(path, func(*domain.PriceData) error { ticks++; return nil }); err != nil {
+		t.Fatalf("Failed to read v1 file: %v", err)
+	}
+	if ticks != 1 {
+		t.Errorf("Expected 1 tick, got %d", ticks)
+	}
+}
+
+func TestReadSpreadFile_NewerSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		sidecar *Schema
+	}{
+		{"unknown column", "timestamp,uic,ticker,asset_type,bid,ask,spread,venue", nil},
+		{"newer sidecar version", "timestamp,uic,ticker,asset_type,bid,ask,spread", &Schema{Dataset: "spreads", Version: SpreadSchema.Version + 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "EURUSD_12.csv")
+			if err := os.WriteFile(path, []byte(tt.header+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.sidecar != nil {
+				if err := writeSchema(dir, "EURUSD_12.csv", *tt.sidecar); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := ReadSpreadFile(path, func(*domain.PriceData) error { return nil })
+			if !errors.Is(err, ErrNewerSchema) {
+				t.Errorf("Expected ErrNewerSchema, got %v", err)
+			}
+		})
+	}
+}
+
+func TestArrowRecorder_SchemaMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := NewArrowRecorder(tempDir, domain.PriceFormatFloat)
+	data := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 14, 0, 0, 0, time.UTC),
+		Ticker:    "EURUSD",
+		Bid:       1.1,
+		Ask:       1.1002,
+	}
+	if err := recorder.Record(context.Background(), data); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "20251118", "spreads_14.arrow"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	footer := arrowFooter(t, content)
+	schema := &flatbuffers.Table{Bytes: footer.Bytes, Pos: footer.Indirect(arrowSlot(footer, 1))}
+
+	metadata := make(map[string]string)
+	vector := schema.Vector(arrowSlot(schema, 2) - schema.Pos)
+	for i := 0; i < schema.VectorLen(arrowSlot(schema, 2)-schema.Pos); i++ {
+		pair := &flatbuffers.Table{Bytes: schema.Bytes, Pos: schema.Indirect(vector + flatbuffers.UOffsetT(4*i))}
+		metadata[string(pair.ByteVector(arrowSlot(pair, 0)))] = string(pair.ByteVector(arrowSlot(pair, 1)))
+	}
+	if metadata["fx_collector.dataset"] != "spreads" || metadata["fx_collector.schema_version"] != strconv.Itoa(SpreadSchema.Version) ||
+		metadata["fx_collector.written_by"] != buildinfo.Get().Short() {
+		t.Errorf("Unexpected metadata %v", metadata)
+	}
+}
+
+func TestArrowSpreadFields_FollowSpreadSchema(t *testing.T) {
+	var names []string
+	for _, field := range arrowSpreadFields {
+		names = append(names, field.name)
+	}
+	if !slices.Equal(names, SpreadSchema.Columns) {
+		t.Errorf("Expected the Arrow columns %v, got %v", SpreadSchema.Columns, names)
+	}
+}
+
+func TestUpdateSidecar_ConcurrentProcesses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, SchemaSidecar)
+
+	// Each goroutine stands in for a process: only the lock file serializes them
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := lockSidecar(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock()
+			errs <- updateSidecar(path, "EURUSD_"+strconv.Itoa(i)+".csv", SpreadSchema)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to update: %v", err)
+		}
+	}
+
+	schemas, err := readSidecar(path)
+	if err != nil {
+		t.Fatalf("Failed to read sidecar: %v", err)
+	}
+	if len(schemas) != 20 {
+		t.Errorf("Expected 20 entries, got %d", len(schemas))
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.Name() != SchemaSidecar {
+			t.Errorf("Unexpected leftover %s", entry.Name())
+		}
+	}
+}
+
+func TestLockSidecar_TakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SchemaSidecar)
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * sidecarLockStale)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockSidecar(path)
+	if err != nil {
+		t.Fatalf("Expected the stale lock taken over, got %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path + ".lock"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the lock removed, got %v", err)
+	}
+}
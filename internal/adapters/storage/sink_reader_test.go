@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+func TestReadRecorded(t *testing.T) {
+	tmpDir := t.TempDir()
+	specs := []RecorderSpec{
+		{Name: "csv", Params: url.Values{"dir": {filepath.Join(tmpDir, "csv")}}},
+		{Name: "arrow", Params: url.Values{"dir": {filepath.Join(tmpDir, "arrow")}}},
+		{Name: "ndjson", Params: url.Values{"output": {filepath.Join(tmpDir, "ticks.ndjson")}, "format": {"decimal"}}},
+	}
+
+	var ticks []*domain.PriceData
+	for i, ticker := range []string{"EURUSD", "USDJPY", "EURUSD"} {
+		tick := &domain.PriceData{
+			Timestamp:  time.Date(2025, 11, 17+i, 12, 0, 0, 0, time.UTC),
+			Ticker:     ticker,
+			AssetType:  "FxSpot",
+			Bid:        1.15234,
+			Ask:        1.15248,
+			Decimals:   5,
+			Sequence:   uint64(i + 1),
+			SpreadUnit: domain.SpreadUnitPips,
+			PipSize:    0.0001,
+		}
+		tick.CalculateSpread()
+		ticks = append(ticks, tick)
+	}
+
+	ctx := context.Background()
+	for _, spec := range specs {
+		recorder, err := NewRecorder(spec)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", spec.Name, err)
+		}
+		if err := recorder.RecordBatch(ctx, ticks); err != nil {
+			t.Fatalf("Failed to record to %s: %v", spec.Name, err)
+		}
+		if err := recorder.(ports.Closer).Close(ctx); err != nil {
+			t.Fatalf("Failed to close %s: %v", spec.Name, err)
+		}
+	}
+
+	filter := ArchiveFilter{
+		From:    time.Date(2025, 11, 17, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2025, 11, 18, 0, 0, 0, 0, time.UTC),
+		Tickers: []string{"EURUSD", "USDJPY"},
+	}
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			var got []*domain.PriceData
+			if err := ReadRecorded(spec, filter, func(p *domain.PriceData) error { got = append(got, p); return nil }); err != nil {
+				t.Fatalf("ReadRecorded failed: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("Expected the 2 ticks within the days, got %d", len(got))
+			}
+			for i, tick := range got {
+				if !tick.Timestamp.Equal(ticks[i].Timestamp) || tick.Ticker != ticks[i].Ticker || tick.Sequence != ticks[i].Sequence {
+					t.Errorf("Tick %d: expected %+v, got %+v", i, ticks[i], tick)
+				}
+				if tick.Bid != 1.15234 || tick.Spread != 0.00014 {
+					t.Errorf("Tick %d: unexpected prices bid=%v spread=%v", i, tick.Bid, tick.Spread)
+				}
+			}
+		})
+	}
+}
+
+func TestReadRecorded_Unreadable(t *testing.T) {
+	noop := func(*domain.PriceData) error { return nil }
+	if err := ReadRecorded(RecorderSpec{Name: "ndjson"}, ArchiveFilter{}, noop); err == nil {
+		t.Error("Expected error for ndjson on stdout")
+	}
+	if err := ReadRecorded(RecorderSpec{Name: "mqtt"}, ArchiveFilter{}, noop); err == nil {
+		t.Error("Expected error for a write-only sink")
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyncMode selects when a recorder fsyncs its files
+type SyncMode string
+
+const (
+	SyncNever   SyncMode = "never" // Leave write-back to the OS (fastest, unbounded loss on power failure)
+	SyncOnFlush SyncMode = "flush" // fsync after every Flush (loss bounded by the flush interval)
+	SyncEvery   SyncMode = "every" // fsync every N records and on Flush (loss bounded by N)
+)
+
+// SyncPolicy trades durability against throughput for file recorders
+// The zero value is SyncNever
+type SyncPolicy struct {
+	Mode    SyncMode
+	Records int // Records between fsyncs for SyncEvery
+}
+
+// ParseSyncPolicy parses "never", "flush" or "every:N"
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	mode, count, hasCount := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	switch SyncMode(mode) {
+	case "", SyncNever:
+		return SyncPolicy{Mode: SyncNever}, nil
+	case SyncOnFlush:
+		if hasCount {
+			break
+		}
+		return SyncPolicy{Mode: SyncOnFlush}, nil
+	case SyncEvery:
+		records, err := strconv.Atoi(count)
+		if err != nil || records < 1 {
+			return SyncPolicy{}, fmt.Errorf("invalid fsync policy %q (every:N needs N >= 1)", s)
+		}
+		return SyncPolicy{Mode: SyncEvery, Records: records}, nil
+	}
+	return SyncPolicy{}, fmt.Errorf("unknown fsync policy %q (supported: never, flush, every:N)", s)
+}
+
+// String returns the policy in its config form
+func (p SyncPolicy) String() string {
+	if p.Mode == SyncEvery {
+		return fmt.Sprintf("%s:%d", p.Mode, p.Records)
+	}
+	if p.Mode == "" {
+		return string(SyncNever)
+	}
+	return string(p.Mode)
+}
+
+// syncs reports whether files are fsynced on Flush and before they are closed
+func (p SyncPolicy) syncs() bool {
+	return p.Mode == SyncOnFlush || p.Mode == SyncEvery
+}
+
+// due counts written records and reports whether an fsync is due under SyncEvery
+func (p SyncPolicy) due(pending *int, records int) bool {
+	if p.Mode != SyncEvery {
+		return false
+	}
+	*pending += records
+	if *pending < p.Records {
+		return false
+	}
+	*pending = 0
+	return true
+}
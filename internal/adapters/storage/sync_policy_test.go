@@ -0,0 +1,47 @@
+package storage
+
+import "testing"
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := []struct {
+		input string
+		want  SyncPolicy
+	}{
+		{"", SyncPolicy{Mode: SyncNever}},
+		{"never", SyncPolicy{Mode: SyncNever}},
+		{"Flush", SyncPolicy{Mode: SyncOnFlush}},
+		{"every:500", SyncPolicy{Mode: SyncEvery, Records: 500}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSyncPolicy(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSyncPolicy(%q) = %+v, %v; want %+v", tt.input, got, err, tt.want)
+		}
+		if round, _ := ParseSyncPolicy(got.String()); round != got {
+			t.Errorf("String() of %+v doesn't round-trip: %s", got, got.String())
+		}
+	}
+
+	for _, invalid := range []string{"always", "every", "every:0", "every:x", "flush:10"} {
+		if _, err := ParseSyncPolicy(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestSyncPolicy_Due(t *testing.T) {
+	policy := SyncPolicy{Mode: SyncEvery, Records: 3}
+	var pending, syncs int
+	for range 7 {
+		if policy.due(&pending, 1) {
+			syncs++
+		}
+	}
+	if syncs != 2 || pending != 1 {
+		t.Errorf("Expected 2 syncs and 1 pending record, got %d and %d", syncs, pending)
+	}
+
+	if (SyncPolicy{Mode: SyncOnFlush}).due(&pending, 100) {
+		t.Error("SyncOnFlush should never be due on records")
+	}
+}
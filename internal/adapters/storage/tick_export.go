@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Tick export formats for backtesting tools
+const (
+	// TickExportMT5 is the tab-separated layout of MetaTrader 5 "Import Ticks" (custom symbols):
+	// <DATE> <TIME> <BID> <ASK> <LAST> <VOLUME> <FLAGS>, with LAST and VOLUME empty as for FX symbols
+	TickExportMT5 = "mt5"
+
+	// TickExportDukascopy is the Dukascopy tick CSV layout: Gmt time,Ask,Bid,Ask Volume,Bid Volume
+	TickExportDukascopy = "dukascopy"
+)
+
+// mt5TickFlags marks both bid and ask as set (TICK_FLAG_BID | TICK_FLAG_ASK)
+const mt5TickFlags = "6"
+
+// TickExporter writes ticks of one instrument in a backtester's file layout
+// The collector records no traded volume, so volumes are written as 0 (Dukascopy) or left empty (MT5)
+type TickExporter struct {
+	writer *csv.Writer
+	format string
+}
+
+// NewTickExporter writes the header of format to w; times are written in UTC
+func NewTickExporter(w io.Writer, format string) (*TickExporter, error) {
+	writer := csv.NewWriter(w)
+	var header []string
+	switch format {
+	case TickExportMT5:
+		writer.Comma = '\t'
+		header = []string{"<DATE>", "<TIME>", "<BID>", "<ASK>", "<LAST>", "<VOLUME>", "<FLAGS>"}
+	case TickExportDukascopy:
+		header = []string{"Gmt time", "Ask", "Bid", "Ask Volume", "Bid Volume"}
+	default:
+		return nil, fmt.Errorf("unknown export format %q (supported: %s, %s)", format, TickExportMT5, TickExportDukascopy)
+	}
+
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	return &TickExporter{writer: writer, format: format}, nil
+}
+
+// Write appends one tick
+func (e *TickExporter) Write(p *domain.PriceData) error {
+	t := p.Timestamp.UTC()
+	bid, ask := exportPrice(p.Bid, p.Decimals), exportPrice(p.Ask, p.Decimals)
+
+	var record []string
+	switch e.format {
+	case TickExportMT5:
+		record = []string{t.Format("2006.01.02"), t.Format("15:04:05.000"), bid, ask, "", "", mt5TickFlags}
+	case TickExportDukascopy:
+		record = []string{t.Format("02.01.2006 15:04:05.000"), ask, bid, "0", "0"}
+	}
+	return e.writer.Write(record)
+}
+
+// Flush writes buffered rows to the underlying writer
+func (e *TickExporter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// exportPrice formats a price with the instrument decimals, or as short as possible without them
+func exportPrice(price float64, decimals int) string {
+	if decimals <= 0 {
+		return strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(roundPrice(price, decimals), 'f', decimals, 64)
+}
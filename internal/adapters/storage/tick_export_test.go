@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestTickExporter_Formats(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 18, 13, 0, 0, 123000000, time.FixedZone("CET", 3600)),
+		Ticker:    "EURUSD",
+		Bid:       1.084501,
+		Ask:       1.08452,
+		Decimals:  5,
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{TickExportMT5, "<DATE>\t<TIME>\t<BID>\t<ASK>\t<LAST>\t<VOLUME>\t<FLAGS>\n2025.11.18\t12:00:00.123\t1.08450\t1.08452\t\t\t6\n"},
+		{TickExportDukascopy, "Gmt time,Ask,Bid,Ask Volume,Bid Volume\n18.11.2025 12:00:00.123,1.08452,1.08450,0,0\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		exporter, err := NewTickExporter(&buf, tt.format)
+		if err != nil {
+			t.Fatalf("NewTickExporter(%s) failed: %v", tt.format, err)
+		}
+		if err := exporter.Write(tick); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err := exporter.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.format, tt.want, buf.String())
+		}
+	}
+}
+
+func TestTickExporter_UnknownFormat(t *testing.T) {
+	if _, err := NewTickExporter(&bytes.Buffer{}, "mt4"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// importFields are the tick fields a column mapping can name; ticker is optional
+var importFields = []string{"timestamp", "bid", "ask", "ticker"}
+
+// Time layouts for numeric timestamps, besides rfc3339 and Go layouts
+const (
+	TimeLayoutUnix   = "unix"    // Seconds since the epoch, fractions allowed
+	TimeLayoutUnixMs = "unix_ms" // Milliseconds since the epoch
+	TimeLayoutUnixUs = "unix_us" // Microseconds since the epoch
+)
+
+// TickCSVFormat describes the layout of a third-party tick CSV
+type TickCSVFormat struct {
+	Columns    map[string]string // Field -> header name, or 1-based column number for files without a header
+	Header     bool              // The first row names the columns
+	Comma      rune              // Field separator (default ',')
+	TimeLayout string            // rfc3339 (default), unix, unix_ms, unix_us or a Go layout like "02.01.2006 15:04:05.000"
+	Location   *time.Location    // Zone for Go layouts without one (default UTC)
+}
+
+// ParseColumnMapping parses "field=column" pairs like "timestamp=Gmt time,bid=Bid,ask=Ask"
+// timestamp, bid and ask are required; ticker is optional
+func ParseColumnMapping(s string) (map[string]string, error) {
+	columns := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, column, ok := strings.Cut(pair, "=")
+		name, column = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q (expected field=column)", pair)
+		}
+		if !slices.Contains(importFields, name) {
+			return nil, fmt.Errorf("unknown field %q in column mapping (supported: %s)", name, strings.Join(importFields, ", "))
+		}
+		columns[name] = column
+	}
+
+	for _, name := range importFields[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column mapping has no %s", name)
+		}
+	}
+	return columns, nil
+}
+
+// ReadTickCSV calls fn for every row of a third-party tick CSV
+// Decimals are taken from the bid text; Ticker is only set when the mapping has a ticker column
+func ReadTickCSV(r io.Reader, format TickCSVFormat, fn func(*domain.PriceData) error) error {
+	reader := csv.NewReader(r)
+	if format.Comma != 0 {
+		reader.Comma = format.Comma
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	var header []string
+	if format.Header {
+		record, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read header: %w", err)
+		}
+		header = slices.Clone(record)
+	}
+	columns, err := resolveColumns(format.Columns, header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		data, err := parseImportRecord(record, columns, format)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+}
+
+// resolveColumns maps each field to a column index, by header name or 1-based number
+func resolveColumns(mapping map[string]string, header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(mapping))
+	for name, column := range mapping {
+		if header != nil {
+			i := slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), column) })
+			if i < 0 {
+				return nil, fmt.Errorf("column %q for %s not in header %v", column, name, header)
+			}
+			columns[name] = i
+			continue
+		}
+
+		n, err := strconv.Atoi(column)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("column %q for %s must be a number (1 = first) in files without a header", column, name)
+		}
+		columns[name] = n - 1
+	}
+	return columns, nil
+}
+
+// parseImportRecord converts one row into price data
+func parseImportRecord(record []string, columns map[string]int, format TickCSVFormat) (*domain.PriceData, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	timestamp, err := parseImportTime(field("timestamp"), format.TimeLayout, format.Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	bidStr := field("bid")
+	bid, err := strconv.ParseFloat(bidStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bid: %w", err)
+	}
+	ask, err := strconv.ParseFloat(field("ask"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ask: %w", err)
+	}
+
+	data := &domain.PriceData{
+		Timestamp: timestamp,
+		Ticker:    strings.ToUpper(field("ticker")),
+		Bid:       bid,
+		Ask:       ask,
+	}
+	if i := strings.IndexByte(bidStr, '.'); i >= 0 {
+		data.Decimals = len(bidStr) - i - 1
+	}
+	data.Spread = roundPrice(ask-bid, data.Decimals)
+	return data, nil
+}
+
+// parseImportTime parses a timestamp in one of the TickCSVFormat layouts, returning UTC
+func parseImportTime(s, layout string, location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	var scale int64
+	switch layout {
+	case "", "rfc3339":
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t.UTC(), err
+	case TimeLayoutUnix:
+		scale = 1e9
+	case TimeLayoutUnixMs:
+		scale = 1e6
+	case TimeLayoutUnixUs:
+		scale = 1e3
+	default:
+		t, err := time.ParseInLocation(layout, s, location)
+		return t.UTC(), err
+	}
+
+	if value, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, value*scale).UTC(), nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Fractions keep microsecond precision in float64 for current epoch values
+	return time.Unix(0, int64(value*float64(scale))).UTC().Round(time.Microsecond), nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestReadTickCSV_HeaderMapping(t *testing.T) {
+	content := "Gmt time,Ask,Bid,Ask Volume,Bid Volume\n" +
+		"18.11.2025 12:00:00.123,1.08452,1.08450,1.5,2.25\n"
+	columns, err := ParseColumnMapping("timestamp=Gmt time, bid=Bid, ask=Ask")
+	if err != nil {
+		t.Fatalf("ParseColumnMapping failed: %v", err)
+	}
+	format := TickCSVFormat{Columns: columns, Header: true, TimeLayout: "02.01.2006 15:04:05.000"}
+
+	var ticks []*domain.PriceData
+	err = ReadTickCSV(strings.NewReader(content), format, func(p *domain.PriceData) error {
+		ticks = append(ticks, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadTickCSV failed: %v", err)
+	}
+	if len(ticks) != 1 {
+		t.Fatalf("Expected 1 tick, got %d", len(ticks))
+	}
+
+	got := ticks[0]
+	if !got.Timestamp.Equal(time.Date(2025, 11, 18, 12, 0, 0, 123000000, time.UTC)) {
+		t.Errorf("Unexpected timestamp %v", got.Timestamp)
+	}
+	if got.Bid != 1.0845 || got.Ask != 1.08452 || got.Decimals != 5 || got.Spread != 0.00002 {
+		t.Errorf("Unexpected tick: %+v", got)
+	}
+}
+
+func TestReadTickCSV_ColumnNumbers(t *testing.T) {
+	content := "usdjpy;1763467200123;155.123;155.137\n" +
+		"usdjpy;1763467200250;155.124;155.137\n"
+	columns, err := ParseColumnMapping("ticker=1,timestamp=2,bid=3,ask=4")
+	if err != nil {
+		t.Fatalf("ParseColumnMapping failed: %v", err)
+	}
+	format := TickCSVFormat{Columns: columns, Comma: ';', TimeLayout: TimeLayoutUnixMs}
+
+	var ticks []*domain.PriceData
+	err = ReadTickCSV(strings.NewReader(content), format, func(p *domain.PriceData) error {
+		ticks = append(ticks, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadTickCSV failed: %v", err)
+	}
+	if len(ticks) != 2 || ticks[0].Ticker != "USDJPY" || ticks[1].Spread != 0.013 {
+		t.Fatalf("Unexpected ticks: %+v", ticks)
+	}
+	if want := time.UnixMilli(1763467200123).UTC(); !ticks[0].Timestamp.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, ticks[0].Timestamp)
+	}
+}
+
+func TestReadTickCSV_Errors(t *testing.T) {
+	if _, err := ParseColumnMapping("timestamp=1,bid=2"); err == nil {
+		t.Error("Expected an error for a mapping without ask")
+	}
+	if _, err := ParseColumnMapping("timestamp=1,bid=2,ask=3,volume=4"); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+
+	columns, _ := ParseColumnMapping("timestamp=1,bid=2,ask=3")
+	content := "2025-11-18T12:00:00Z,1.0845,1.08452\nnot-a-time,1.0845,1.08452\n"
+	err := ReadTickCSV(strings.NewReader(content), TickCSVFormat{Columns: columns}, func(*domain.PriceData) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
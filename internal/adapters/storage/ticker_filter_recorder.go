@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// TickerFilterRecorder keeps the ticks of some instruments away from a sink
+// Used to route instruments to a subset of the configured sinks ("sinks" in instruments.json)
+type TickerFilterRecorder struct {
+	next ports.TickWriter
+	skip map[string]bool
+}
+
+// NewTickerFilterRecorder wraps next so ticks of the skipped tickers are dropped silently
+func NewTickerFilterRecorder(next ports.TickWriter, skip []string) *TickerFilterRecorder {
+	r := &TickerFilterRecorder{next: next, skip: make(map[string]bool, len(skip))}
+	for _, ticker := range skip {
+		r.skip[ticker] = true
+	}
+	return r
+}
+
+// Record saves a single price data point unless its ticker is skipped
+func (r *TickerFilterRecorder) Record(ctx context.Context, data *domain.PriceData) error {
+	if r.skip[data.Ticker] {
+		return nil
+	}
+	return r.next.Record(ctx, data)
+}
+
+// RecordBatch saves the price data points of tickers that aren't skipped
+func (r *TickerFilterRecorder) RecordBatch(ctx context.Context, data []*domain.PriceData) error {
+	kept := data[:0:0]
+	var positions []int // Index in data of each kept tick
+	for i, d := range data {
+		if !r.skip[d.Ticker] {
+			kept = append(kept, d)
+			positions = append(positions, i)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+
+	err := r.next.RecordBatch(ctx, kept)
+	var partial *ports.PartialWriteError
+	if !errors.As(err, &partial) {
+		return err
+	}
+	// Skipped ticks before the first one not written count as written
+	written := len(data)
+	if partial.Written < len(kept) {
+		written = positions[partial.Written]
+	}
+	return ports.PartialWrite(written, partial.Err)
+}
+
+// Flush ensures all buffered data is written to storage
+func (r *TickerFilterRecorder) Flush(ctx context.Context) error {
+	if flusher, ok := r.next.(ports.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// Close finalizes the recording session and releases resources
+func (r *TickerFilterRecorder) Close(ctx context.Context) error {
+	if closer, ok := r.next.(ports.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped recorder
+func (r *TickerFilterRecorder) Unwrap() ports.TickWriter {
+	return r.next
+}
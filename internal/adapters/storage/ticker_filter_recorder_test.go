@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestTickerFilterRecorder(t *testing.T) {
+	inner := &flakyRecorder{}
+	recorder := NewTickerFilterRecorder(inner, []string{"USDTRY"})
+	ctx := context.Background()
+
+	eurusd := &domain.PriceData{Ticker: "EURUSD"}
+	usdtry := &domain.PriceData{Ticker: "USDTRY"}
+	if err := recorder.Record(ctx, usdtry); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.RecordBatch(ctx, []*domain.PriceData{usdtry}); err != nil {
+		t.Fatalf("Failed to record batch: %v", err)
+	}
+	if inner.calls != 0 {
+		t.Errorf("Expected skipped ticks not to reach the sink, got %d calls", inner.calls)
+	}
+
+	batch := []*domain.PriceData{eurusd, usdtry, eurusd}
+	if err := recorder.RecordBatch(ctx, batch); err != nil {
+		t.Fatalf("Failed to record batch: %v", err)
+	}
+	if len(inner.recorded) != 2 || inner.recorded[0] != eurusd || inner.recorded[1] != eurusd {
+		t.Errorf("Expected both EURUSD ticks, got %v", inner.recorded)
+	}
+	if batch[1] != usdtry {
+		t.Error("Expected the caller's batch to be left as it was")
+	}
+
+	if got, ok := As[*flakyRecorder](recorder); !ok || got != inner {
+		t.Error("Expected As to find the wrapped recorder")
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import "github.com/bjoelf/fx-collector/pkg/ports"
+
+// Unwrapper is implemented by recorder decorators to expose the recorder they wrap
+type Unwrapper interface {
+	Unwrap() ports.TickWriter
+}
+
+// Composite is implemented by recorders that fan out to several sinks
+type Composite interface {
+	Members() []ports.TickWriter
+}
+
+// As walks a decorator chain (depth-first through composites) and returns the first recorder implementing T
+// Lets callers find optional capabilities (e.g. purging) behind retry/metrics wrappers
+func As[T any](recorder ports.TickWriter) (T, bool) {
+	var zero T
+	if recorder == nil {
+		return zero, false
+	}
+
+	if target, ok := recorder.(T); ok {
+		return target, true
+	}
+
+	switch wrapper := recorder.(type) {
+	case Unwrapper:
+		return As[T](wrapper.Unwrap())
+	case Composite:
+		for _, member := range wrapper.Members() {
+			if target, ok := As[T](member); ok {
+				return target, true
+			}
+		}
+	}
+	return zero, false
+}
+
+// All walks a decorator chain like As and returns every recorder implementing T, in chain order
+func All[T any](recorder ports.TickWriter) []T {
+	if recorder == nil {
+		return nil
+	}
+
+	var found []T
+	if target, ok := recorder.(T); ok {
+		found = append(found, target)
+	}
+	switch wrapper := recorder.(type) {
+	case Unwrapper:
+		found = append(found, All[T](wrapper.Unwrap())...)
+	case Composite:
+		for _, member := range wrapper.Members() {
+			found = append(found, All[T](member)...)
+		}
+	}
+	return found
+}
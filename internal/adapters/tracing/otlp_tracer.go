@@ -0,0 +1,150 @@
+// Package tracing exports spans of the tick pipeline with the OpenTelemetry SDK over OTLP/HTTP
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scopeName is the instrumentation scope of the collector's spans
+const scopeName = "fx-collector"
+
+// Config configures the OTLP exporter
+type Config struct {
+	Endpoint       string            // Collector base URL, e.g. http://localhost:4318; spans go to <Endpoint>/v1/traces
+	Headers        map[string]string // Sent with every export, e.g. an API key
+	ServiceName    string            // Resource attribute service.name
+	ExportInterval time.Duration     // How often buffered spans are sent
+	BatchSize      int               // Spans per export request; a full batch is sent immediately
+	QueueSize      int               // Spans buffered before new ones are dropped
+}
+
+// ParseHeaders parses OTEL_EXPORTER_OTLP_HEADERS style "key=value,key2=value2"
+func ParseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header '%s' (expected key=value)", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
+
+// Tracer records spans with an OpenTelemetry SDK tracer provider, whose batch span processor
+// exports them over OTLP/HTTP (protobuf) without blocking the pipeline
+// Every span is sampled: the service decides which ticks are traced (TRACE_SAMPLE_RATE)
+// A nil Tracer starts nil spans, so callers need no tracing-enabled checks
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	closed   sync.Once
+}
+
+// NewTracer creates a tracer exporting to cfg.Endpoint; Close sends what is left
+// Export failures are logged to logger
+func NewTracer(cfg Config, logger *log.Logger) (*Tracer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("OTLP endpoint is required")
+	}
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(cfg.Endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithHeaders(cfg.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Printf("Tracing: %v", err)
+	}))
+	return newTracer(cfg, exporter), nil
+}
+
+// newTracer creates a tracer exporting to exporter in batches
+func newTracer(cfg Config, exporter sdktrace.SpanExporter) *Tracer {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "fx-collector"
+	}
+	if cfg.ExportInterval <= 0 {
+		cfg.ExportInterval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.QueueSize < cfg.BatchSize {
+		cfg.QueueSize = 4 * cfg.BatchSize
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithBatchTimeout(cfg.ExportInterval),
+			sdktrace.WithMaxExportBatchSize(cfg.BatchSize),
+			sdktrace.WithMaxQueueSize(cfg.QueueSize),
+		),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	return &Tracer{provider: provider, tracer: provider.Tracer(scopeName)}
+}
+
+// Start begins a span now; parent nil starts a new trace
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	return t.StartAt(name, parent, time.Now())
+}
+
+// StartAt begins a span at start, for stages measured before the span was created
+func (t *Tracer) StartAt(name string, parent *Span, start time.Time) *Span {
+	if t == nil {
+		return nil
+	}
+	ctx := context.Background()
+	if parent != nil {
+		ctx = trace.ContextWithSpan(ctx, parent.span)
+	}
+	_, span := t.tracer.Start(ctx, name, trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindInternal))
+	return &Span{span: span}
+}
+
+// StartSpan implements ports.Tracer; parent must be a span of this tracer or nil
+func (t *Tracer) StartSpan(name string, parent ports.Span, start time.Time) ports.Span {
+	p, _ := parent.(*Span)
+	return t.StartAt(name, p, start)
+}
+
+// Flush sends all spans ended so far
+func (t *Tracer) Flush() {
+	if t == nil {
+		return
+	}
+	if err := t.provider.ForceFlush(context.Background()); err != nil {
+		otel.Handle(err)
+	}
+}
+
+// Close sends the remaining spans and shuts the exporter down
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	var err error
+	t.closed.Do(func() {
+		err = t.provider.Shutdown(context.Background())
+	})
+	return err
+}
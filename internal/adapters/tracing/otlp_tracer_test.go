@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTracer_Export(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("Unexpected request %s with key %q", r.URL.Path, r.Header.Get("X-Api-Key"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("Invalid body: %v", err)
+		}
+		requests <- req
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	tracer, err := NewTracer(Config{
+		Endpoint:       server.URL + "/",
+		Headers:        map[string]string{"X-Api-Key": "secret"},
+		ExportInterval: time.Hour,
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Close()
+
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	root := tracer.StartAt("tick", nil, start)
+	root.SetAttribute("fx.ticker", "EURUSD")
+	tracer.StartAt("map", root, start).EndAt(start.Add(time.Millisecond))
+	root.SetError(errors.New("disk full"))
+	root.EndAt(start.Add(2 * time.Millisecond))
+	tracer.Flush()
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Unexpected request shape: %v", req)
+	}
+	resource := req.ResourceSpans[0].Resource.Attributes
+	if len(resource) != 1 || resource[0].Key != "service.name" || resource[0].Value.GetStringValue() != "fx-collector" {
+		t.Errorf("Expected service.name fx-collector, got %v", resource)
+	}
+	if scope := req.ResourceSpans[0].ScopeSpans[0].Scope.Name; scope != "fx-collector" {
+		t.Errorf("Expected scope fx-collector, got %s", scope)
+	}
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	child, parent := spans[0], spans[1]
+	if !bytes.Equal(child.TraceId, parent.TraceId) || !bytes.Equal(child.ParentSpanId, parent.SpanId) || len(parent.ParentSpanId) != 0 {
+		t.Errorf("Child not linked to parent: %v / %v", child, parent)
+	}
+	if child.StartTimeUnixNano != 1763467200000000000 || child.EndTimeUnixNano != 1763467200001000000 {
+		t.Errorf("Unexpected child times %d - %d", child.StartTimeUnixNano, child.EndTimeUnixNano)
+	}
+	if parent.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || parent.Status.GetMessage() != "disk full" {
+		t.Errorf("Expected error status, got %v", parent.Status)
+	}
+	if len(parent.Attributes) != 1 || parent.Attributes[0].Key != "fx.ticker" || parent.Attributes[0].Value.GetStringValue() != "EURUSD" {
+		t.Errorf("Unexpected attributes: %v", parent.Attributes)
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("tick", nil) // Must not panic
+	span.SetAttribute("fx.ticker", "EURUSD")
+	span.SetError(errors.New("ignored"))
+	span.End()
+	tracer.Flush()
+	if err := tracer.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("x-api-key=abc, authorization=Bearer a=b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers["x-api-key"] != "abc" || headers["authorization"] != "Bearer a=b" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+	if _, err := ParseHeaders("novalue"); err == nil {
+		t.Error("Expected error for header without =")
+	}
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span is one timed stage of a trace
+// A nil Span ignores all calls; a span must not be used after End
+type Span struct {
+	span trace.Span
+}
+
+// SetAttribute attaches a string, integer, float or bool value to the span
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attributeOf(key, value))
+}
+
+// SetError marks the span as failed with err (nil is ignored)
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span now and queues it for export
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt finishes the span at end and queues it for export
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.span.End(trace.WithTimestamp(end))
+}
+
+// attributeOf converts a string, integer, float or bool value; anything else is formatted as a string
+func attributeOf(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case uint64:
+		return attribute.Int64(key, int64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,220 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// minCorrelationSamples is the number of shared intervals required for a coefficient
+const minCorrelationSamples = 30
+
+// MinCorrelationWindow returns the shortest window that can hold enough intervals of resolution
+// for a coefficient
+func MinCorrelationWindow(resolution time.Duration) time.Duration {
+	return minCorrelationSamples * resolution
+}
+
+// CorrelationMatrix holds pairwise spread correlations over one window [Start, End)
+// Values[i][j] is the Pearson correlation of Tickers[i] and Tickers[j], nil with too few samples
+type CorrelationMatrix struct {
+	Day     string       `json:"day"` // YYYY-MM-DD the window ends in (UTC)
+	Start   time.Time    `json:"start"`
+	End     time.Time    `json:"end"`
+	Tickers []string     `json:"tickers"` // Instruments with data in the window
+	Values  [][]*float64 `json:"values"`
+	Samples [][]int      `json:"samples"` // Shared intervals per pair
+}
+
+// CorrelationBuilder accumulates snapshots into spread series at a fixed resolution and correlates
+// them over a rolling window
+// Each series value is the widest spread seen in the interval, so correlated blow-outs stand out
+type CorrelationBuilder struct {
+	resolution time.Duration
+	window     time.Duration
+	step       time.Duration
+	series     map[string]map[int64]float64 // Ticker, interval start (unix nanoseconds) -> max spread
+}
+
+// NewCorrelationBuilder creates a builder resampling spreads to resolution and correlating them over
+// window, one matrix every step (windows end on multiples of step, UTC)
+// Window 0 means one UTC day; step 0 means step = window (no overlap), so the defaults give one
+// matrix per day
+func NewCorrelationBuilder(resolution, window, step time.Duration) *CorrelationBuilder {
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	if step <= 0 {
+		step = window
+	}
+	return &CorrelationBuilder{
+		resolution: resolution,
+		window:     window,
+		step:       step,
+		series:     make(map[string]map[int64]float64),
+	}
+}
+
+// Add accumulates one snapshot; carried-forward snapshots (no ticks) are skipped
+func (b *CorrelationBuilder) Add(s *domain.Snapshot) {
+	if s.Ticks == 0 {
+		return
+	}
+
+	series, ok := b.series[s.Ticker]
+	if !ok {
+		series = make(map[int64]float64)
+		b.series[s.Ticker] = series
+	}
+
+	key := s.Timestamp.UTC().Truncate(b.resolution).UnixNano()
+	if current, ok := series[key]; !ok || s.MaxSpread > current {
+		series[key] = s.MaxSpread
+	}
+}
+
+// Build computes one correlation matrix per window with data, oldest first
+func (b *CorrelationBuilder) Build() []CorrelationMatrix {
+	if len(b.series) == 0 {
+		return nil
+	}
+	first, last := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, series := range b.series {
+		for key := range series {
+			first, last = min(first, key), max(last, key)
+		}
+	}
+
+	tickers := make([]string, 0, len(b.series))
+	for ticker := range b.series {
+		tickers = append(tickers, ticker)
+	}
+	slices.Sort(tickers)
+
+	// Every window holding the first interval up to the first one ending after the last interval
+	var matrices []CorrelationMatrix
+	end := time.Unix(0, first).UTC().Truncate(b.step).Add(b.step)
+	for ; end.Add(-b.window).UnixNano() <= last; end = end.Add(b.step) {
+		if matrix, ok := b.correlate(tickers, end.Add(-b.window), end); ok {
+			matrices = append(matrices, matrix)
+		}
+	}
+	return matrices
+}
+
+// correlate computes the matrix of the window [start, end); false if no instrument has data in it
+func (b *CorrelationBuilder) correlate(allTickers []string, start, end time.Time) (CorrelationMatrix, bool) {
+	// Values per interval of the window, NaN where an instrument has none
+	intervals := int((end.Sub(start) + b.resolution - 1) / b.resolution)
+	var tickers []string
+	var values [][]float64
+	for _, ticker := range allTickers {
+		series := b.series[ticker]
+		row := make([]float64, intervals)
+		found := false
+		for i := range row {
+			v, ok := series[start.Add(time.Duration(i)*b.resolution).UnixNano()]
+			if !ok {
+				v = math.NaN()
+			}
+			row[i] = v
+			found = found || ok
+		}
+		if found {
+			tickers = append(tickers, ticker)
+			values = append(values, row)
+		}
+	}
+	if len(tickers) == 0 {
+		return CorrelationMatrix{}, false
+	}
+
+	n := len(tickers)
+	matrix := CorrelationMatrix{
+		Day:     end.Add(-1).Format("2006-01-02"),
+		Start:   start,
+		End:     end,
+		Tickers: tickers,
+		Values:  make([][]*float64, n),
+		Samples: make([][]int, n),
+	}
+	for i := range n {
+		matrix.Values[i] = make([]*float64, n)
+		matrix.Samples[i] = make([]int, n)
+	}
+
+	for i := range n {
+		for j := i; j < n; j++ {
+			r, samples := pearson(values[i], values[j])
+			matrix.Samples[i][j], matrix.Samples[j][i] = samples, samples
+			if samples >= minCorrelationSamples && !math.IsNaN(r) {
+				matrix.Values[i][j], matrix.Values[j][i] = &r, &r
+			}
+		}
+	}
+	return matrix, true
+}
+
+// pearson correlates two series over the intervals both have a value (not NaN) for
+// Returns NaN when either series is constant over the shared intervals
+func pearson(a, b []float64) (float64, int) {
+	var n, sumA, sumB, sumAA, sumBB, sumAB float64
+	for i, x := range a {
+		y := b[i]
+		if math.IsNaN(x) || math.IsNaN(y) {
+			continue
+		}
+		n++
+		sumA += x
+		sumB += y
+		sumAA += x * x
+		sumBB += y * y
+		sumAB += x * y
+	}
+	if n == 0 {
+		return math.NaN(), 0
+	}
+
+	cov := sumAB - sumA*sumB/n
+	varA := sumAA - sumA*sumA/n
+	varB := sumBB - sumB*sumB/n
+	if varA <= 0 || varB <= 0 {
+		return math.NaN(), int(n)
+	}
+	return cov / math.Sqrt(varA*varB), int(n)
+}
+
+// WriteCorrelationCSV writes one row per window and instrument pair:
+// day,window_start,window_end,ticker_a,ticker_b,correlation,samples
+// Pairs without enough samples have an empty correlation
+func WriteCorrelationCSV(w io.Writer, matrices []CorrelationMatrix) error {
+	writer := csv.NewWriter(w)
+	header := []string{"day", "window_start", "window_end", "ticker_a", "ticker_b", "correlation", "samples"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, m := range matrices {
+		start, end := m.Start.Format(time.RFC3339), m.End.Format(time.RFC3339)
+		for i := range m.Tickers {
+			for j := i + 1; j < len(m.Tickers); j++ {
+				correlation := ""
+				if v := m.Values[i][j]; v != nil {
+					correlation = strconv.FormatFloat(*v, 'f', 4, 64)
+				}
+				record := []string{m.Day, start, end, m.Tickers[i], m.Tickers[j], correlation, strconv.Itoa(m.Samples[i][j])}
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
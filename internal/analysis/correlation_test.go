@@ -0,0 +1,104 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestCorrelationBuilder_Build(t *testing.T) {
+	builder := NewCorrelationBuilder(time.Minute, 0, 0)
+	start := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+
+	add := func(ticker string, minute int, spread float64) {
+		builder.Add(&domain.Snapshot{
+			Timestamp: start.Add(time.Duration(minute) * time.Minute),
+			Ticker:    ticker,
+			MaxSpread: spread,
+			Ticks:     1,
+		})
+	}
+
+	for m := range 60 {
+		wave := math.Sin(float64(m) / 5)
+		add("EURUSD", m, 0.0001+0.00005*wave)
+		add("GBPUSD", m, 0.0002+0.00010*wave) // Widens together with EURUSD
+		add("USDJPY", m, 0.01-0.005*wave)     // Widens when EURUSD tightens
+	}
+	add("NZDUSD", 0, 0.0003) // Too few samples
+
+	matrices := builder.Build()
+	if len(matrices) != 1 {
+		t.Fatalf("Expected 1 day, got %d", len(matrices))
+	}
+	m := matrices[0]
+
+	index := make(map[string]int)
+	for i, ticker := range m.Tickers {
+		index[ticker] = i
+	}
+	value := func(a, b string) *float64 { return m.Values[index[a]][index[b]] }
+
+	if v := value("EURUSD", "GBPUSD"); v == nil || *v < 0.99 {
+		t.Errorf("Expected EURUSD/GBPUSD ≈ 1, got %v", v)
+	}
+	if v := value("EURUSD", "USDJPY"); v == nil || *v > -0.99 {
+		t.Errorf("Expected EURUSD/USDJPY ≈ -1, got %v", v)
+	}
+	if v := value("EURUSD", "NZDUSD"); v != nil {
+		t.Errorf("Expected no coefficient for NZDUSD, got %v", *v)
+	}
+	if samples := m.Samples[index["EURUSD"]][index["GBPUSD"]]; samples != 60 {
+		t.Errorf("Expected 60 shared samples, got %d", samples)
+	}
+}
+
+func TestCorrelationBuilder_RollingWindows(t *testing.T) {
+	builder := NewCorrelationBuilder(time.Minute, time.Hour, 30*time.Minute)
+	start := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+
+	// Together in the first hour, opposed in the second
+	for m := range 120 {
+		wave := math.Sin(float64(m) / 5)
+		other := wave
+		if m >= 60 {
+			other = -wave
+		}
+		at := start.Add(time.Duration(m) * time.Minute)
+		builder.Add(&domain.Snapshot{Timestamp: at, Ticker: "EURUSD", MaxSpread: 0.0001 + 0.00005*wave, Ticks: 1})
+		builder.Add(&domain.Snapshot{Timestamp: at, Ticker: "GBPUSD", MaxSpread: 0.0002 + 0.0001*other, Ticks: 1})
+	}
+
+	matrices := builder.Build()
+	// Windows ending 12:30, 13:00, 13:30, 14:00 and 14:30
+	if len(matrices) != 5 {
+		t.Fatalf("Expected 5 windows, got %d", len(matrices))
+	}
+	for i, want := range []struct {
+		end     time.Time
+		samples int
+		sign    float64 // 0: no coefficient
+	}{
+		{start.Add(30 * time.Minute), 30, 1},
+		{start.Add(time.Hour), 60, 1},
+		{start.Add(90 * time.Minute), 60, 0}, // Half and half: no clear sign
+		{start.Add(2 * time.Hour), 60, -1},
+		{start.Add(150 * time.Minute), 30, -1},
+	} {
+		m := matrices[i]
+		if !m.End.Equal(want.end) || !m.Start.Equal(want.end.Add(-time.Hour)) || m.Day != "2025-11-19" {
+			t.Errorf("Window %d: expected to end at %v, got %v-%v (%s)", i, want.end, m.Start, m.End, m.Day)
+		}
+		if m.Samples[0][1] != want.samples {
+			t.Errorf("Window %d: expected %d samples, got %d", i, want.samples, m.Samples[0][1])
+		}
+		if want.sign == 0 {
+			continue
+		}
+		if v := m.Values[0][1]; v == nil || *v*want.sign < 0.99 {
+			t.Errorf("Window %d: expected a correlation of %v, got %v", i, want.sign, v)
+		}
+	}
+}
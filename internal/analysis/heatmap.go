@@ -0,0 +1,178 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// HeatmapCell holds spread statistics for one weekday/hour slot
+type HeatmapCell struct {
+	Weekday time.Weekday `json:"weekday"` // 0 = Sunday
+	Hour    int          `json:"hour"`
+	Ticks   int          `json:"ticks"`
+	Median  float64      `json:"median"`
+	P95     float64      `json:"p95"`
+}
+
+// InstrumentHeatmap is the hour-of-day × day-of-week spread matrix of one instrument
+// Only slots with ticks are included
+type InstrumentHeatmap struct {
+	Ticker   string        `json:"ticker"`
+	Decimals int           `json:"decimals"`
+	Cells    []HeatmapCell `json:"cells"`
+}
+
+// spreadHistogram counts spreads in points (smallest price increment)
+// Spreads are small integers in points, so percentiles are exact at constant memory
+type spreadHistogram struct {
+	counts map[int64]int
+	total  int
+}
+
+// percentile returns the nearest-rank percentile (q in 0..1) in points
+func (h *spreadHistogram) percentile(q float64) int64 {
+	points := make([]int64, 0, len(h.counts))
+	for p := range h.counts {
+		points = append(points, p)
+	}
+	slices.Sort(points)
+
+	rank := max(int(math.Ceil(q*float64(h.total))), 1)
+	seen := 0
+	for _, p := range points {
+		seen += h.counts[p]
+		if seen >= rank {
+			return p
+		}
+	}
+	return points[len(points)-1]
+}
+
+// instrumentCells is the 7×24 histogram matrix of one instrument
+type instrumentCells struct {
+	decimals int
+	cells    [7][24]*spreadHistogram
+}
+
+// rescale converts every histogram to points of a finer precision
+func (inst *instrumentCells) rescale(decimals int) {
+	factor := int64(math.Round(math.Pow10(decimals - inst.decimals)))
+	for weekday := range inst.cells {
+		for _, h := range inst.cells[weekday] {
+			if h == nil {
+				continue
+			}
+			counts := make(map[int64]int, len(h.counts))
+			for points, n := range h.counts {
+				counts[points*factor] += n
+			}
+			h.counts = counts
+		}
+	}
+	inst.decimals = decimals
+}
+
+// HeatmapBuilder accumulates ticks into per-instrument weekday/hour spread histograms
+type HeatmapBuilder struct {
+	location    *time.Location
+	instruments map[string]*instrumentCells
+}
+
+// NewHeatmapBuilder creates a builder bucketing ticks by weekday and hour in location
+func NewHeatmapBuilder(location *time.Location) *HeatmapBuilder {
+	return &HeatmapBuilder{
+		location:    location,
+		instruments: make(map[string]*instrumentCells),
+	}
+}
+
+// Add accumulates one tick
+func (b *HeatmapBuilder) Add(p *domain.PriceData) {
+	inst, ok := b.instruments[p.Ticker]
+	if !ok {
+		inst = &instrumentCells{decimals: p.Decimals}
+		b.instruments[p.Ticker] = inst
+	}
+	// Precision can differ between files (e.g. trailing zero trimming); keep the finest, converting
+	// the spreads counted so far to its points
+	if p.Decimals > inst.decimals {
+		inst.rescale(p.Decimals)
+	}
+
+	t := p.Timestamp.In(b.location)
+	cell := &inst.cells[t.Weekday()][t.Hour()]
+	if *cell == nil {
+		*cell = &spreadHistogram{counts: make(map[int64]int)}
+	}
+
+	points := int64(math.Round(p.Spread * math.Pow10(inst.decimals)))
+	(*cell).counts[points]++
+	(*cell).total++
+}
+
+// Build computes median and p95 per slot, sorted by ticker
+func (b *HeatmapBuilder) Build() []InstrumentHeatmap {
+	tickers := make([]string, 0, len(b.instruments))
+	for ticker := range b.instruments {
+		tickers = append(tickers, ticker)
+	}
+	slices.Sort(tickers)
+
+	heatmaps := make([]InstrumentHeatmap, 0, len(tickers))
+	for _, ticker := range tickers {
+		inst := b.instruments[ticker]
+		scale := math.Pow10(-inst.decimals)
+
+		heatmap := InstrumentHeatmap{Ticker: ticker, Decimals: inst.decimals}
+		for weekday := range 7 {
+			for hour := range 24 {
+				h := inst.cells[weekday][hour]
+				if h == nil {
+					continue
+				}
+				heatmap.Cells = append(heatmap.Cells, HeatmapCell{
+					Weekday: time.Weekday(weekday),
+					Hour:    hour,
+					Ticks:   h.total,
+					Median:  float64(h.percentile(0.5)) * scale,
+					P95:     float64(h.percentile(0.95)) * scale,
+				})
+			}
+		}
+		heatmaps = append(heatmaps, heatmap)
+	}
+	return heatmaps
+}
+
+// WriteHeatmapCSV writes heatmaps in long format: ticker,weekday,hour,ticks,median,p95
+func WriteHeatmapCSV(w io.Writer, heatmaps []InstrumentHeatmap) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"ticker", "weekday", "hour", "ticks", "median", "p95"}); err != nil {
+		return err
+	}
+
+	for _, heatmap := range heatmaps {
+		for _, cell := range heatmap.Cells {
+			record := []string{
+				heatmap.Ticker,
+				cell.Weekday.String(),
+				strconv.Itoa(cell.Hour),
+				strconv.Itoa(cell.Ticks),
+				strconv.FormatFloat(cell.Median, 'f', heatmap.Decimals, 64),
+				strconv.FormatFloat(cell.P95, 'f', heatmap.Decimals, 64),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
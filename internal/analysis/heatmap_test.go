@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestHeatmapBuilder_Percentiles(t *testing.T) {
+	builder := NewHeatmapBuilder(time.UTC)
+
+	// Wednesday 14:xx UTC: spreads of 1..20 points
+	base := time.Date(2025, 11, 19, 14, 0, 0, 0, time.UTC)
+	for i := 1; i <= 20; i++ {
+		builder.Add(&domain.PriceData{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Ticker:    "EURUSD",
+			Spread:    float64(i) * 0.00001,
+			Decimals:  5,
+		})
+	}
+	// Thursday 03:xx UTC: one tick
+	builder.Add(&domain.PriceData{
+		Timestamp: time.Date(2025, 11, 20, 3, 0, 0, 0, time.UTC),
+		Ticker:    "EURUSD",
+		Spread:    0.00004,
+		Decimals:  5,
+	})
+
+	heatmaps := builder.Build()
+	if len(heatmaps) != 1 || len(heatmaps[0].Cells) != 2 {
+		t.Fatalf("Expected 1 instrument with 2 cells, got %+v", heatmaps)
+	}
+
+	cell := heatmaps[0].Cells[0]
+	if cell.Weekday != time.Wednesday || cell.Hour != 14 || cell.Ticks != 20 {
+		t.Errorf("Unexpected cell: %+v", cell)
+	}
+	if cell.Median != 10*0.00001 || cell.P95 != 19*0.00001 {
+		t.Errorf("Expected median 0.00010 and p95 0.00019, got %v and %v", cell.Median, cell.P95)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHeatmapCSV(&buf, heatmaps); err != nil {
+		t.Fatalf("WriteHeatmapCSV failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "EURUSD,Wednesday,14,20,0.00010,0.00019") {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestHeatmapBuilder_Location(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+
+	builder := NewHeatmapBuilder(newYork)
+	builder.Add(&domain.PriceData{
+		Timestamp: time.Date(2025, 11, 19, 3, 0, 0, 0, time.UTC), // Tuesday 22:00 in New York
+		Ticker:    "EURUSD",
+		Spread:    0.00002,
+		Decimals:  5,
+	})
+
+	cell := builder.Build()[0].Cells[0]
+	if cell.Weekday != time.Tuesday || cell.Hour != 22 {
+		t.Errorf("Expected Tuesday 22:00, got %v %d:00", cell.Weekday, cell.Hour)
+	}
+}
+
+func TestHeatmapBuilder_RescalesWhenPrecisionIncreases(t *testing.T) {
+	builder := NewHeatmapBuilder(time.UTC)
+	base := time.Date(2025, 11, 19, 14, 0, 0, 0, time.UTC)
+
+	// A file with trimmed trailing zeros first (4 decimals), then full precision
+	for i, spread := range []float64{0.0001, 0.0001, 0.0001} {
+		builder.Add(&domain.PriceData{Timestamp: base.Add(time.Duration(i) * time.Second), Ticker: "EURUSD", Spread: spread, Decimals: 4})
+	}
+	builder.Add(&domain.PriceData{Timestamp: base.Add(time.Minute), Ticker: "EURUSD", Spread: 0.00003, Decimals: 5})
+
+	cell := builder.Build()[0].Cells[0]
+	if cell.Ticks != 4 || math.Abs(cell.Median-0.0001) > 1e-12 || math.Abs(cell.P95-0.0001) > 1e-12 {
+		t.Errorf("Expected the earlier spreads kept at 0.0001, got %+v", cell)
+	}
+}
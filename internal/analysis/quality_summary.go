@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"cmp"
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// QualitySummary counts one instrument's ticks of one UTC day by quality flag
+type QualitySummary struct {
+	Day     string         `json:"day"` // YYYY-MM-DD
+	Ticker  string         `json:"ticker"`
+	Ticks   int            `json:"ticks"`
+	Clean   int            `json:"clean"`   // Ticks without any quality flag
+	Flagged map[string]int `json:"flagged"` // Ticks per quality flag name; a tick may count under several
+}
+
+// qualityFlagList lists the single quality flags in bit order
+var qualityFlagList = func() []domain.TickFlags {
+	var flags []domain.TickFlags
+	for bit := domain.TickFlags(1); bit != 0 && bit <= domain.QualityFlags; bit <<= 1 {
+		if domain.QualityFlags.Has(bit) {
+			flags = append(flags, bit)
+		}
+	}
+	return flags
+}()
+
+// QualityBuilder accumulates ticks into daily quality summaries per instrument
+type QualityBuilder struct {
+	summaries map[[2]string]*QualitySummary // By day and ticker
+}
+
+// NewQualityBuilder creates an empty builder
+func NewQualityBuilder() *QualityBuilder {
+	return &QualityBuilder{summaries: make(map[[2]string]*QualitySummary)}
+}
+
+// Add counts one tick
+func (b *QualityBuilder) Add(p *domain.PriceData) {
+	day := p.Timestamp.UTC().Format("2006-01-02")
+	key := [2]string{day, p.Ticker}
+	summary, ok := b.summaries[key]
+	if !ok {
+		summary = &QualitySummary{Day: day, Ticker: p.Ticker, Flagged: make(map[string]int)}
+		b.summaries[key] = summary
+	}
+
+	summary.Ticks++
+	quality := p.Flags.Quality()
+	if quality == 0 {
+		summary.Clean++
+		return
+	}
+	for _, flag := range qualityFlagList {
+		if quality.Has(flag) {
+			summary.Flagged[flag.String()]++
+		}
+	}
+}
+
+// Build returns the summaries ordered by day and ticker
+func (b *QualityBuilder) Build() []QualitySummary {
+	summaries := make([]QualitySummary, 0, len(b.summaries))
+	for _, summary := range b.summaries {
+		summaries = append(summaries, *summary)
+	}
+	slices.SortFunc(summaries, func(a, b QualitySummary) int {
+		return cmp.Or(cmp.Compare(a.Day, b.Day), cmp.Compare(a.Ticker, b.Ticker))
+	})
+	return summaries
+}
+
+// WriteQualityCSV writes summaries as day,ticker,ticks,clean,clean_pct and one count column per quality flag
+func WriteQualityCSV(w io.Writer, summaries []QualitySummary) error {
+	writer := csv.NewWriter(w)
+	header := []string{"day", "ticker", "ticks", "clean", "clean_pct"}
+	for _, flag := range qualityFlagList {
+		header = append(header, flag.String())
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range summaries {
+		record := []string{s.Day, s.Ticker, strconv.Itoa(s.Ticks), strconv.Itoa(s.Clean),
+			strconv.FormatFloat(100*float64(s.Clean)/float64(max(s.Ticks, 1)), 'f', 2, 64)}
+		for _, flag := range qualityFlagList {
+			record = append(record, strconv.Itoa(s.Flagged[flag.String()]))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
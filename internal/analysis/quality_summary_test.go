@@ -0,0 +1,45 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestQualityBuilder(t *testing.T) {
+	builder := NewQualityBuilder()
+	day := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+	for _, flags := range []domain.TickFlags{
+		0,
+		domain.FlagRollover, // Not a quality flag
+		domain.FlagSnapshot,
+		domain.FlagStale | domain.FlagCrossed,
+	} {
+		builder.Add(&domain.PriceData{Timestamp: day, Ticker: "EURUSD", Flags: flags})
+	}
+	builder.Add(&domain.PriceData{Timestamp: day.Add(-24 * time.Hour), Ticker: "USDJPY", Flags: domain.FlagIndicative})
+
+	summaries := builder.Build()
+	if len(summaries) != 2 || summaries[0].Day != "2025-11-18" || summaries[1].Ticker != "EURUSD" {
+		t.Fatalf("Expected USDJPY on the 18th and EURUSD on the 19th, got %+v", summaries)
+	}
+	eurusd := summaries[1]
+	if eurusd.Ticks != 4 || eurusd.Clean != 2 || eurusd.Flagged["stale"] != 1 || eurusd.Flagged["crossed"] != 1 {
+		t.Errorf("Unexpected summary %+v", eurusd)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteQualityCSV(&buf, summaries); err != nil {
+		t.Fatalf("WriteQualityCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "day,ticker,ticks,clean,clean_pct,outlier,crossed,clamped,snapshot,stale,indicative" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if lines[2] != "2025-11-19,EURUSD,4,2,50.00,0,1,0,1,1,0" {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+}
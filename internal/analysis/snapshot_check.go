@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"cmp"
+	"encoding/csv"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// SnapshotDiscrepancy is a stored snapshot that differs from the snapshot rebuilt from the recorded ticks
+type SnapshotDiscrepancy struct {
+	Ticker    string    `json:"ticker"`
+	Timestamp time.Time `json:"timestamp"` // Interval start
+	Field     string    `json:"field"`     // recorded, bid, ask, min_spread, max_spread, or missing for an unwritten snapshot
+	Stored    string    `json:"stored"`
+	Rebuilt   string    `json:"rebuilt"`
+}
+
+// snapshotKey identifies one instrument's interval
+type snapshotKey struct {
+	ticker string
+	start  int64 // Unix nanoseconds
+}
+
+// rebuiltSnapshot is a snapshot recomputed from ticks
+type rebuiltSnapshot struct {
+	snapshot *domain.Snapshot
+	last     *domain.PriceData // Tick whose quote closes the interval
+	matched  bool              // A stored snapshot was compared with it
+}
+
+// SnapshotChecker rebuilds snapshots from recorded ticks and compares them with the stored ones
+// Ticks are bucketed and filtered like the collector's snapshot writer: by their own timestamp,
+// without outliers. The closing quote is the one with the highest sequence number
+// The rebuilt tick count is compared with the ticks the sinks accepted (Snapshot.Recorded), not
+// with every tick the snapshot saw; prices only where no tick of the interval was kept out of the
+// sinks, since the stored quote and spread range include those ticks
+type SnapshotChecker struct {
+	interval time.Duration
+	rebuilt  map[snapshotKey]*rebuiltSnapshot
+}
+
+// NewSnapshotChecker creates a checker for snapshots of the given interval
+func NewSnapshotChecker(interval time.Duration) *SnapshotChecker {
+	return &SnapshotChecker{interval: interval, rebuilt: make(map[snapshotKey]*rebuiltSnapshot)}
+}
+
+// AddTick folds a recorded tick into its rebuilt snapshot
+func (c *SnapshotChecker) AddTick(p *domain.PriceData) {
+	if p.Flags.Has(domain.FlagOutlier) {
+		return
+	}
+	start := p.Timestamp.UTC().Truncate(c.interval)
+	key := snapshotKey{ticker: p.Ticker, start: start.UnixNano()}
+
+	r, ok := c.rebuilt[key]
+	if !ok {
+		c.rebuilt[key] = &rebuiltSnapshot{snapshot: domain.NewSnapshot(start, p), last: p}
+		return
+	}
+	s := r.snapshot
+	s.MinSpread = min(s.MinSpread, p.Spread)
+	s.MaxSpread = max(s.MaxSpread, p.Spread)
+	s.Ticks++
+	if closesAfter(p, r.last) {
+		s.Bid, s.Ask = p.Bid, p.Ask
+		r.last = p
+	}
+}
+
+// closesAfter reports whether p arrived after last: by sequence number, or timestamp without one
+func closesAfter(p, last *domain.PriceData) bool {
+	if p.Sequence > 0 && last.Sequence > 0 {
+		return p.Sequence > last.Sequence
+	}
+	return !p.Timestamp.Before(last.Timestamp)
+}
+
+// Check compares a stored snapshot with the one rebuilt for its interval
+// Prices match when they agree at the stored snapshot's precision
+func (c *SnapshotChecker) Check(stored *domain.Snapshot) []SnapshotDiscrepancy {
+	key := snapshotKey{ticker: stored.Ticker, start: stored.Timestamp.UnixNano()}
+	r := c.rebuilt[key]
+	discrepancy := func(field, storedValue, rebuiltValue string) SnapshotDiscrepancy {
+		return SnapshotDiscrepancy{Ticker: stored.Ticker, Timestamp: stored.Timestamp.UTC(), Field: field, Stored: storedValue, Rebuilt: rebuiltValue}
+	}
+
+	if r == nil {
+		if stored.Recorded == 0 {
+			return nil // Carried forward over an interval without ticks, or none reached the sinks
+		}
+		return []SnapshotDiscrepancy{discrepancy("recorded", strconv.Itoa(stored.Recorded), "0")}
+	}
+	r.matched = true
+	rebuilt := r.snapshot
+
+	var found []SnapshotDiscrepancy
+	if stored.Recorded != rebuilt.Ticks {
+		found = append(found, discrepancy("recorded", strconv.Itoa(stored.Recorded), strconv.Itoa(rebuilt.Ticks)))
+	}
+	if stored.Ticks == 0 || stored.Recorded != stored.Ticks {
+		return found // A carried-forward quote, or one of ticks that weren't all recorded
+	}
+
+	tolerance := math.Pow10(-stored.Decimals)/2 + 1e-12
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', stored.Decimals, 64) }
+	for _, field := range []struct {
+		name            string
+		stored, rebuilt float64
+	}{
+		{"bid", stored.Bid, rebuilt.Bid},
+		{"ask", stored.Ask, rebuilt.Ask},
+		{"min_spread", stored.MinSpread, rebuilt.MinSpread},
+		{"max_spread", stored.MaxSpread, rebuilt.MaxSpread},
+	} {
+		if math.Abs(field.stored-field.rebuilt) > tolerance {
+			found = append(found, discrepancy(field.name, format(field.stored), format(field.rebuilt)))
+		}
+	}
+	return found
+}
+
+// Missing returns the intervals with ticks that no stored snapshot was checked against, in time order
+func (c *SnapshotChecker) Missing() []SnapshotDiscrepancy {
+	var missing []SnapshotDiscrepancy
+	for key, r := range c.rebuilt {
+		if r.matched {
+			continue
+		}
+		missing = append(missing, SnapshotDiscrepancy{
+			Ticker:    key.ticker,
+			Timestamp: time.Unix(0, key.start).UTC(),
+			Field:     "missing",
+			Rebuilt:   strconv.Itoa(r.snapshot.Ticks),
+		})
+	}
+	slices.SortFunc(missing, func(a, b SnapshotDiscrepancy) int {
+		return cmp.Or(a.Timestamp.Compare(b.Timestamp), cmp.Compare(a.Ticker, b.Ticker))
+	})
+	return missing
+}
+
+// WriteSnapshotDiscrepanciesCSV writes one row per discrepancy: ticker,timestamp,field,stored,rebuilt
+func WriteSnapshotDiscrepanciesCSV(w io.Writer, discrepancies []SnapshotDiscrepancy) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"ticker", "timestamp", "field", "stored", "rebuilt"}); err != nil {
+		return err
+	}
+	for _, d := range discrepancies {
+		if err := writer.Write([]string{d.Ticker, d.Timestamp.Format(time.RFC3339Nano), d.Field, d.Stored, d.Rebuilt}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
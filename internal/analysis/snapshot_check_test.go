@@ -0,0 +1,62 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestSnapshotChecker(t *testing.T) {
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	tick := func(offset time.Duration, seq uint64, bid, ask float64, flags domain.TickFlags) *domain.PriceData {
+		p := &domain.PriceData{Timestamp: start.Add(offset), Ticker: "EURUSD", Bid: bid, Ask: ask, Decimals: 5, Sequence: seq, Flags: flags}
+		p.CalculateSpread()
+		return p
+	}
+
+	checker := NewSnapshotChecker(time.Second)
+	checker.AddTick(tick(100*time.Millisecond, 1, 1.15230, 1.15240, 0))
+	checker.AddTick(tick(300*time.Millisecond, 3, 1.15234, 1.15248, 0))
+	checker.AddTick(tick(200*time.Millisecond, 2, 1.15231, 1.15250, 0)) // Out of file order, closes nothing
+	checker.AddTick(tick(400*time.Millisecond, 4, 1.15000, 1.16000, domain.FlagOutlier))
+	checker.AddTick(tick(2500*time.Millisecond, 5, 1.15240, 1.15250, 0)) // No snapshot stored
+
+	matching := &domain.Snapshot{Timestamp: start, Ticker: "EURUSD", Bid: 1.15234, Ask: 1.15248, MinSpread: 0.00010, MaxSpread: 0.00019, Ticks: 3, Recorded: 3, Decimals: 5}
+	if found := checker.Check(matching); len(found) != 0 {
+		t.Errorf("Expected a match, got %+v", found)
+	}
+
+	wrong := *matching
+	wrong.Ticks, wrong.Recorded, wrong.MaxSpread = 4, 4, 0.01000
+	found := checker.Check(&wrong)
+	if len(found) != 2 || found[0].Field != "recorded" || found[1].Field != "max_spread" || found[1].Rebuilt != "0.00019" {
+		t.Errorf("Expected recorded and max_spread discrepancies, got %+v", found)
+	}
+
+	// Ticks kept out of the sinks (sampling, rate limits, ...) count for the snapshot only
+	gated := *matching
+	gated.Ticks, gated.MaxSpread = 7, 0.00030
+	if found := checker.Check(&gated); len(found) != 0 {
+		t.Errorf("Expected a snapshot with gated ticks to match its recorded ticks, got %+v", found)
+	}
+
+	carried := &domain.Snapshot{Timestamp: start.Add(time.Second), Ticker: "EURUSD", Bid: 1.15234, Ask: 1.15248, Decimals: 5}
+	if found := checker.Check(carried); len(found) != 0 {
+		t.Errorf("Expected a carried-forward snapshot over an empty interval to match, got %+v", found)
+	}
+
+	missing := checker.Missing()
+	if len(missing) != 1 || missing[0].Field != "missing" || !missing[0].Timestamp.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Expected the interval at 12:00:02 to be missing, got %+v", missing)
+	}
+
+	var b strings.Builder
+	if err := WriteSnapshotDiscrepanciesCSV(&b, found); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "EURUSD,2025-11-18T12:00:00Z,recorded,4,3\n") {
+		t.Errorf("Unexpected CSV:\n%s", b.String())
+	}
+}
@@ -0,0 +1,210 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// maxQuoteHold caps how long a quote is assumed to stand without a new tick,
+// so market closures and feed gaps don't weigh in with the last quote before them
+const maxQuoteHold = time.Minute
+
+// costSkipFlags mark ticks that are not a tradable quote: implausible, crossed or stale quotes,
+// quotes from a closed market and historical bar closes
+// They are skipped, so the quote before them keeps standing
+const costSkipFlags = domain.QualityFlags | domain.FlagBackfill
+
+// TradingProfile describes when and how often a strategy trades
+type TradingProfile struct {
+	Hours         domain.PauseSchedule // Trading windows, same syntax as PAUSE_SCHEDULE (e.g. "mon-fri 08:00-17:00 tz=Europe/London")
+	TradesPerHour float64              // Round trips per hour inside the windows
+	Notional      float64              // Base currency amount per trade
+}
+
+// SpreadCost is the expected spread cost of one instrument for a weekday or the whole week
+type SpreadCost struct {
+	Ticker       string  `json:"ticker"`
+	Period       string  `json:"period"` // Weekday name or "week"
+	Trades       float64 `json:"trades"`
+	CoveredHours float64 `json:"covered_hours"` // Recorded quote time inside the trading windows behind the estimate
+	AvgSpread    float64 `json:"avg_spread"`    // Time-weighted spread inside the trading windows, price units
+	AvgSpreadPip float64 `json:"avg_spread_pips"`
+	Cost         float64 `json:"cost"`     // Trades × AvgSpread × Notional
+	Currency     string  `json:"currency"` // Quote currency the cost is in
+	Decimals     int     `json:"-"`
+}
+
+// costSlot accumulates the time-weighted spread of one weekday
+type costSlot struct {
+	weighted float64 // Spread × seconds
+	seconds  float64
+}
+
+// instrumentCosts tracks one instrument's previous quote and weekday slots
+type instrumentCosts struct {
+	decimals int
+	last     *domain.PriceData
+	days     [7]costSlot
+}
+
+// SpreadCostEstimator estimates what a trading profile pays in spread, from recorded ticks
+// Each quote is weighted by how long it stood (up to maxQuoteHold), so a trade at a random
+// moment inside the windows pays the time-weighted spread
+type SpreadCostEstimator struct {
+	profile     TradingProfile
+	location    *time.Location
+	instruments map[string]*instrumentCosts
+	pipSizes    domain.PipSizes
+	lastTick    time.Time
+}
+
+// NewSpreadCostEstimator creates an estimator bucketing weekdays in location
+func NewSpreadCostEstimator(profile TradingProfile, location *time.Location) *SpreadCostEstimator {
+	return &SpreadCostEstimator{
+		profile:     profile,
+		location:    location,
+		instruments: make(map[string]*instrumentCosts),
+	}
+}
+
+// SetPipSizes sets the pip sizes avg_spread_pips is computed with (default domain.DefaultPipSize)
+func (e *SpreadCostEstimator) SetPipSizes(sizes domain.PipSizes) {
+	e.pipSizes = sizes
+}
+
+// Add accumulates one tick; an instrument's ticks must be added in time order
+// Ticks flagged with costSkipFlags are ignored
+func (e *SpreadCostEstimator) Add(p *domain.PriceData) {
+	if p.Flags&costSkipFlags != 0 {
+		return
+	}
+	inst, ok := e.instruments[p.Ticker]
+	if !ok {
+		inst = &instrumentCosts{}
+		e.instruments[p.Ticker] = inst
+	}
+	inst.decimals = max(inst.decimals, p.Decimals)
+	if p.Timestamp.After(e.lastTick) {
+		e.lastTick = p.Timestamp
+	}
+
+	if last := inst.last; last != nil && p.Timestamp.After(last.Timestamp) && e.profile.Hours.Paused(p.Ticker, last.Timestamp) {
+		hold := min(p.Timestamp.Sub(last.Timestamp), maxQuoteHold).Seconds()
+		slot := &inst.days[last.Timestamp.In(e.location).Weekday()]
+		slot.weighted += last.Spread * hold
+		slot.seconds += hold
+	}
+	inst.last = p
+}
+
+// Build returns one row per instrument and weekday with data, then the week, sorted by ticker
+// Trades per weekday come from the trading windows of the week of the last tick
+func (e *SpreadCostEstimator) Build() []SpreadCost {
+	tickers := make([]string, 0, len(e.instruments))
+	for ticker := range e.instruments {
+		tickers = append(tickers, ticker)
+	}
+	slices.Sort(tickers)
+
+	var costs []SpreadCost
+	for _, ticker := range tickers {
+		inst := e.instruments[ticker]
+		hours := e.windowHours(ticker)
+		week := SpreadCost{Ticker: ticker, Period: "week", Currency: quoteCurrency(ticker), Decimals: inst.decimals}
+
+		for day := time.Monday; day <= time.Saturday+1; day++ {
+			weekday := day % 7 // Monday first, Sunday last
+			slot := inst.days[weekday]
+			if slot.seconds == 0 {
+				continue
+			}
+			cost := e.cost(ticker, weekday.String(), hours[weekday], slot.weighted/slot.seconds, slot.seconds/3600)
+			cost.Decimals = inst.decimals
+			costs = append(costs, cost)
+
+			week.Trades += cost.Trades
+			week.CoveredHours += cost.CoveredHours
+			week.Cost += cost.Cost
+		}
+		if week.Trades > 0 {
+			week.AvgSpread = week.Cost / (week.Trades * e.profile.Notional)
+			week.AvgSpreadPip = week.AvgSpread / e.pipSizes.Get(ticker)
+			costs = append(costs, week)
+		}
+	}
+	return costs
+}
+
+// cost prices one weekday: trades at TradesPerHour through windowHours, each paying avgSpread on Notional
+func (e *SpreadCostEstimator) cost(ticker, period string, windowHours, avgSpread, coveredHours float64) SpreadCost {
+	trades := e.profile.TradesPerHour * windowHours
+	return SpreadCost{
+		Ticker:       ticker,
+		Period:       period,
+		Trades:       trades,
+		CoveredHours: coveredHours,
+		AvgSpread:    avgSpread,
+		AvgSpreadPip: avgSpread / e.pipSizes.Get(ticker),
+		Cost:         trades * avgSpread * e.profile.Notional,
+		Currency:     quoteCurrency(ticker),
+	}
+}
+
+// windowHours returns the trading window hours per weekday, scanning the week of the last tick by minute
+func (e *SpreadCostEstimator) windowHours(ticker string) [7]float64 {
+	var minutes [7]int
+	start := e.lastTick.In(e.location)
+	start = time.Date(start.Year(), start.Month(), start.Day()-int(start.Weekday()), 0, 0, 0, 0, e.location)
+	for t := start; t.Before(start.AddDate(0, 0, 7)); t = t.Add(time.Minute) {
+		if e.profile.Hours.Paused(ticker, t) {
+			minutes[t.Weekday()]++
+		}
+	}
+
+	var hours [7]float64
+	for day, m := range minutes {
+		hours[day] = float64(m) / 60
+	}
+	return hours
+}
+
+// quoteCurrency returns the last three letters of a six-letter FX ticker, or ""
+func quoteCurrency(ticker string) string {
+	if len(ticker) != 6 {
+		return ""
+	}
+	return ticker[3:]
+}
+
+// WriteSpreadCostCSV writes costs as ticker,period,trades,covered_hours,avg_spread,avg_spread_pips,cost,currency
+func WriteSpreadCostCSV(w io.Writer, costs []SpreadCost) error {
+	writer := csv.NewWriter(w)
+	header := []string{"ticker", "period", "trades", "covered_hours", "avg_spread", "avg_spread_pips", "cost", "currency"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, c := range costs {
+		record := []string{
+			c.Ticker,
+			c.Period,
+			strconv.FormatFloat(c.Trades, 'f', 1, 64),
+			strconv.FormatFloat(c.CoveredHours, 'f', 1, 64),
+			strconv.FormatFloat(c.AvgSpread, 'f', c.Decimals+1, 64),
+			strconv.FormatFloat(c.AvgSpreadPip, 'f', 2, 64),
+			strconv.FormatFloat(c.Cost, 'f', 2, 64),
+			c.Currency,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
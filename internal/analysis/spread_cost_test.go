@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestSpreadCostEstimator_TimeWeighted(t *testing.T) {
+	hours, err := domain.ParsePauseSchedule("mon-fri 08:00-10:00")
+	if err != nil {
+		t.Fatalf("ParsePauseSchedule failed: %v", err)
+	}
+	estimator := NewSpreadCostEstimator(TradingProfile{Hours: hours, TradesPerHour: 2, Notional: 100000}, time.UTC)
+
+	// Wednesday: the 07:59 quote is outside the window, the 08:01 quote counts for one minute only
+	day := time.Date(2025, 11, 19, 0, 0, 0, 0, time.UTC)
+	for _, tick := range []struct {
+		at     time.Duration
+		spread float64
+	}{
+		{7*time.Hour + 59*time.Minute, 0.0009},
+		{8 * time.Hour, 0.0001},
+		{8*time.Hour + 30*time.Second, 0.0003},
+		{8*time.Hour + time.Minute, 0.0002},
+		{12 * time.Hour, 0.0009},
+	} {
+		estimator.Add(&domain.PriceData{Timestamp: day.Add(tick.at), Ticker: "EURUSD", Spread: tick.spread, Decimals: 5})
+	}
+
+	costs := estimator.Build()
+	if len(costs) != 2 || costs[0].Period != "Wednesday" || costs[1].Period != "week" {
+		t.Fatalf("Expected a Wednesday and a week row, got %+v", costs)
+	}
+
+	wednesday := costs[0]
+	if wednesday.Trades != 4 || wednesday.Currency != "USD" {
+		t.Errorf("Expected 4 trades in USD, got %+v", wednesday)
+	}
+	if math.Abs(wednesday.AvgSpread-0.0002) > 1e-12 || math.Abs(wednesday.Cost-80) > 1e-6 {
+		t.Errorf("Expected average spread 0.0002 and cost 80, got %v and %v", wednesday.AvgSpread, wednesday.Cost)
+	}
+	if costs[1].Trades != wednesday.Trades || math.Abs(costs[1].Cost-wednesday.Cost) > 1e-6 {
+		t.Errorf("Expected the week to match the only day, got %+v", costs[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSpreadCostCSV(&buf, costs); err != nil {
+		t.Fatalf("WriteSpreadCostCSV failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "EURUSD,Wednesday,4.0,0.0,0.000200,2.00,80.00,USD") {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+
+	// A configured pip size replaces the default one
+	estimator.SetPipSizes(domain.PipSizes{"EURUSD": 0.001})
+	if costs := estimator.Build(); math.Abs(costs[0].AvgSpreadPip-0.2) > 1e-9 {
+		t.Errorf("Expected 0.2 pips of 0.001, got %v", costs[0].AvgSpreadPip)
+	}
+}
+
+func TestSpreadCostEstimator_NoDataInWindows(t *testing.T) {
+	hours, _ := domain.ParsePauseSchedule("sat 08:00-10:00")
+	estimator := NewSpreadCostEstimator(TradingProfile{Hours: hours, TradesPerHour: 1, Notional: 1000}, time.UTC)
+
+	start := time.Date(2025, 11, 19, 8, 0, 0, 0, time.UTC)
+	estimator.Add(&domain.PriceData{Timestamp: start, Ticker: "USDJPY", Spread: 0.01, Decimals: 3})
+	estimator.Add(&domain.PriceData{Timestamp: start.Add(time.Second), Ticker: "USDJPY", Spread: 0.01, Decimals: 3})
+
+	if costs := estimator.Build(); len(costs) != 0 {
+		t.Errorf("Expected no rows without quotes in the windows, got %+v", costs)
+	}
+}
+
+func TestSpreadCostEstimator_SkipsFlaggedTicks(t *testing.T) {
+	hours, _ := domain.ParsePauseSchedule("mon-fri 08:00-10:00")
+	estimator := NewSpreadCostEstimator(TradingProfile{Hours: hours, TradesPerHour: 1, Notional: 100000}, time.UTC)
+
+	// The outlier and the backfilled bar would otherwise each stand for 20 seconds
+	start := time.Date(2025, 11, 19, 8, 0, 0, 0, time.UTC)
+	for _, tick := range []*domain.PriceData{
+		{Timestamp: start, Spread: 0.0001},
+		{Timestamp: start.Add(20 * time.Second), Spread: 0.05, Flags: domain.FlagOutlier},
+		{Timestamp: start.Add(40 * time.Second), Spread: 0.0009, Flags: domain.FlagBackfill},
+		{Timestamp: start.Add(time.Minute), Spread: 0.0001},
+	} {
+		tick.Ticker = "EURUSD"
+		tick.Decimals = 5
+		estimator.Add(tick)
+	}
+
+	costs := estimator.Build()
+	if len(costs) != 2 {
+		t.Fatalf("Expected a Wednesday and a week row, got %+v", costs)
+	}
+	if math.Abs(costs[0].AvgSpread-0.0001) > 1e-12 || math.Abs(costs[0].CoveredHours-1.0/60) > 1e-9 {
+		t.Errorf("Expected the first quote to stand for the minute at 0.0001, got %v over %v hours", costs[0].AvgSpread, costs[0].CoveredHours)
+	}
+}
@@ -0,0 +1,100 @@
+// Package buildinfo identifies the build of the running binary
+// Release builds set the version, commit and date with ldflags:
+//
+//	go build -ldflags "-X github.com/bjoelf/fx-collector/internal/buildinfo.Version=v1.4.0 \
+//	  -X github.com/bjoelf/fx-collector/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/bjoelf/fx-collector/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/collector
+//
+// Values not set that way fall back to the build information the Go toolchain embeds
+package buildinfo
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Set with -ldflags "-X ..."
+var (
+	Version string
+	Commit  string
+	Date    string // RFC3339
+)
+
+// Info describes a build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build of the running binary
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, Date: Date}
+		if embedded, ok := debug.ReadBuildInfo(); ok {
+			info = merge(info, embedded)
+		}
+		if info.Version == "" {
+			info.Version = "(devel)"
+		}
+	})
+	return info
+}
+
+// merge fills the fields ldflags left empty from the toolchain's build information
+func merge(info Info, embedded *debug.BuildInfo) Info {
+	info.GoVersion = embedded.GoVersion
+	if info.Version == "" && embedded.Main.Version != "" {
+		info.Version = embedded.Main.Version
+	}
+	for _, setting := range embedded.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// Short returns the version with the abbreviated commit, e.g. "v1.4.0 (1560e70)" or "(devel) (1560e70-dirty)"
+func (i Info) Short() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	commit := i.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("%s (%s)", i.Version, commit)
+}
+
+// String returns the full description printed by --version
+func (i Info) String() string {
+	s := "fx-collector " + i.Short()
+	if i.Date != "" {
+		s += ", built " + i.Date
+	}
+	if i.GoVersion != "" {
+		s += ", " + i.GoVersion
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestMerge_LdflagsWin(t *testing.T) {
+	embedded := &debug.BuildInfo{
+		GoVersion: "go1.24.2",
+		Main:      debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "1560e70aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+			{Key: "vcs.time", Value: "2025-11-26T08:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	info := merge(Info{Version: "v1.4.0", Commit: "abcdef0123"}, embedded)
+	if info.Version != "v1.4.0" || info.Commit != "abcdef0123" {
+		t.Errorf("ldflags values overridden: %+v", info)
+	}
+	if info.Date != "2025-11-26T08:00:00Z" || !info.Modified || info.GoVersion != "go1.24.2" {
+		t.Errorf("embedded values not used: %+v", info)
+	}
+	if got, want := info.Short(), "v1.4.0 (abcdef0-dirty)"; got != want {
+		t.Errorf("Short() = %q, want %q", got, want)
+	}
+	if got, want := info.String(), "fx-collector v1.4.0 (abcdef0-dirty), built 2025-11-26T08:00:00Z, go1.24.2"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestShort_WithoutCommit(t *testing.T) {
+	if got := (Info{Version: "(devel)"}).Short(); got != "(devel)" {
+		t.Errorf("Short() = %q, want (devel)", got)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// quoteBoard keeps the latest quote of every instrument for the aligned quote stream
+type quoteBoard struct {
+	mu       sync.Mutex
+	interval time.Duration
+	writer   ports.AlignedQuoteWriter
+	latest   map[string]domain.PriceData
+}
+
+// WithAlignedQuotes writes the latest bid/ask of all instruments on a fixed clock (e.g. every 250ms)
+// Rows are written while at least one instrument's market is open
+func WithAlignedQuotes(interval time.Duration, writer ports.AlignedQuoteWriter) Option {
+	return func(cs *CollectorService) {
+		cs.aligned = &quoteBoard{
+			interval: interval,
+			writer:   writer,
+			latest:   make(map[string]domain.PriceData),
+		}
+	}
+}
+
+// observe stores a tick as its instrument's latest quote
+func (b *quoteBoard) observe(p *domain.PriceData) {
+	b.mu.Lock()
+	b.latest[p.Ticker] = *p
+	b.mu.Unlock()
+}
+
+// row returns the latest quotes of tickers at t
+func (b *quoteBoard) row(t time.Time, tickers []string) domain.AlignedQuotes {
+	row := domain.AlignedQuotes{Timestamp: t, Quotes: make([]domain.AlignedQuote, len(tickers))}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, ticker := range tickers {
+		quote := domain.AlignedQuote{Ticker: ticker}
+		if p, ok := b.latest[ticker]; ok {
+			quote.Bid, quote.Ask, quote.Decimals = p.Bid, p.Ask, p.Decimals
+			quote.Age = max(t.Sub(p.Timestamp), 0)
+			quote.Valid = true
+		}
+		row.Quotes[i] = quote
+	}
+	return row
+}
+
+// writeAlignedQuotes writes one row per interval, on timestamps that are multiples of the interval
+func (cs *CollectorService) writeAlignedQuotes() {
+	interval := cs.aligned.interval
+	tickers := cs.getAllTickers()
+	slices.Sort(tickers)
+	cs.logger.Printf("Starting aligned quote writer (every %v, %d instruments)", interval, len(tickers))
+
+	// Start on the grid so every row lands on a round timestamp
+	select {
+	case <-cs.ctx.Done():
+		return
+	case <-time.After(time.Until(time.Now().Truncate(interval).Add(interval))):
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last time.Time
+	now := time.Now()
+	for {
+		at := now.UTC().Round(interval)
+		if at.After(last) && cs.recordingAllowed() && cs.anyMarketOpen(at) {
+			if err := cs.aligned.writer.RecordAlignedQuotes(cs.ctx, cs.aligned.row(at, tickers)); err != nil {
+				cs.logger.Printf("Error recording aligned quotes: %v", err)
+				cs.countError("aligned_quotes")
+				cs.emit(domain.NewEvent(domain.EventStorageError, domain.SeverityCritical,
+					fmt.Sprintf("Failed to record aligned quotes: %v", err)))
+			}
+			last = at
+		}
+
+		select {
+		case <-cs.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
+
+// closeAlignedWriter releases the aligned quote writer's resources
+func (cs *CollectorService) closeAlignedWriter(ctx context.Context) error {
+	if cs.aligned == nil {
+		return nil
+	}
+	if closer, ok := cs.aligned.writer.(ports.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close aligned quote writer: %w", err)
+		}
+	}
+	return nil
+}
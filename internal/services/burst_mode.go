@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// BurstConfig configures sampled recording with full tick capture in burst windows; both settings
+// can be changed at runtime with Reload (Tunables.SampleInterval and BurstAnomalyWindow)
+// Bursts cover the economic calendar windows (WithEconomicCalendar) and a fixed time after each anomaly
+type BurstConfig struct {
+	SampleInterval time.Duration // Outside bursts, record at most one tick per instrument per interval (0 = only instruments with their own)
+	AnomalyWindow  time.Duration // Full capture after a locked/crossed quote or spread outlier (0 disables)
+}
+
+// Validate checks the burst mode configuration
+func (c BurstConfig) Validate() error {
+	if c.SampleInterval < 0 {
+		return fmt.Errorf("burst mode sample interval must not be negative, got %v", c.SampleInterval)
+	}
+	if c.AnomalyWindow < 0 {
+		return fmt.Errorf("burst mode anomaly window must not be negative, got %v", c.AnomalyWindow)
+	}
+	return nil
+}
+
+// burstMode tracks the burst windows and the last sampled tick per instrument; its settings
+// are kept with the tunables
+type burstMode struct {
+	// Price processor goroutine only
+	lastRecorded map[string]time.Time
+	anomalyUntil map[string]time.Time
+
+	// Replaced on every calendar refresh
+	mu     sync.RWMutex
+	events []domain.CalendarAnnotation
+}
+
+// WithBurstMode records a sample of the ticks, switching to full capture around calendar events and anomalies
+// Sampled ticks carry FlagSampled so analysis can tell them from full capture. With a zero
+// SampleInterval every tick is recorded until a reload sets one
+func WithBurstMode(cfg BurstConfig) Option {
+	return func(cs *CollectorService) {
+		cs.tunables.SampleInterval = cfg.SampleInterval
+		cs.tunables.BurstAnomalyWindow = cfg.AnomalyWindow
+		cs.burst = &burstMode{
+			lastRecorded: make(map[string]time.Time),
+			anomalyUntil: make(map[string]time.Time),
+		}
+	}
+}
+
+// setEvents replaces the calendar windows with full capture
+func (b *burstMode) setEvents(annotations []domain.CalendarAnnotation) {
+	b.mu.Lock()
+	b.events = annotations
+	b.mu.Unlock()
+}
+
+// active reports whether ticker is in a burst window at t
+func (b *burstMode) active(ticker string, t time.Time) bool {
+	if t.Before(b.anomalyUntil[ticker]) {
+		return true
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, annotation := range b.events {
+		if !t.Before(annotation.WindowStart) && t.Before(annotation.WindowEnd) && slices.Contains(annotation.Tickers, ticker) {
+			return true
+		}
+	}
+	return false
+}
+
+// triggerBurst starts or extends full capture for ticker after an anomaly at t
+// Only called from the price processor goroutine
+func (cs *CollectorService) triggerBurst(ticker string, t time.Time) {
+	b := cs.burst
+	if b == nil {
+		return
+	}
+	window := cs.currentTunables().BurstAnomalyWindow
+	if window == 0 {
+		return
+	}
+
+	until := t.Add(window)
+	if !t.Before(b.anomalyUntil[ticker]) {
+		cs.logger.Printf("Burst mode: full capture for %s until %s", ticker, until.UTC().Format(time.TimeOnly))
+	}
+	if until.After(b.anomalyUntil[ticker]) {
+		b.anomalyUntil[ticker] = until
+	}
+}
+
+// sampleTick reports whether a tick is recorded under burst mode, flagging ticks recorded outside bursts
+// Only called from the price processor goroutine
+func (cs *CollectorService) sampleTick(priceData *domain.PriceData) bool {
+	b := cs.burst
+	if b == nil {
+		return true
+	}
+
+	interval := cs.currentTunables().SampleInterval
+	if override := cs.instruments[priceData.Ticker].SampleInterval; override != 0 {
+		interval = override
+	}
+	if interval <= 0 {
+		return true // Full capture for this instrument
+	}
+
+	if !b.active(priceData.Ticker, priceData.Timestamp) {
+		last, ok := b.lastRecorded[priceData.Ticker]
+		if ok && priceData.Timestamp.Sub(last) < interval {
+			return false
+		}
+		priceData.Flags |= domain.FlagSampled
+	}
+	b.lastRecorded[priceData.Ticker] = priceData.Timestamp
+	return true
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestBurstMode_ReloadsSampleInterval(t *testing.T) {
+	cs := &CollectorService{
+		logger:          log.New(io.Discard, "", 0),
+		tunables:        Tunables{FlushInterval: time.Second, TraceSampleRate: 1},
+		tunablesChanged: make(chan struct{}),
+	}
+	WithBurstMode(BurstConfig{AnomalyWindow: time.Minute})(cs)
+
+	start := time.Date(2025, 11, 26, 14, 0, 0, 0, time.UTC)
+	recorded := func(from time.Time) int {
+		count := 0
+		for i := range 10 {
+			if cs.sampleTick(&domain.PriceData{Ticker: "EURUSD", Timestamp: from.Add(time.Duration(i) * 100 * time.Millisecond)}) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := recorded(start); got != 10 {
+		t.Fatalf("Expected every tick without a sample interval, got %d", got)
+	}
+
+	reloaded := cs.Tunables()
+	reloaded.SampleInterval = time.Second
+	if _, err := cs.Reload(reloaded); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if got := recorded(start.Add(time.Minute)); got != 1 {
+		t.Errorf("Expected one tick per second after the reload, got %d", got)
+	}
+
+	// An anomaly starts a burst of the reloaded length
+	reloaded.BurstAnomalyWindow = time.Hour
+	if _, err := cs.Reload(reloaded); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	cs.triggerBurst("EURUSD", start.Add(2*time.Minute))
+	if got := recorded(start.Add(30 * time.Minute)); got != 10 {
+		t.Errorf("Expected full capture in the burst, got %d", got)
+	}
+}
+
+func TestReload_RefusesSamplingWithoutBurstMode(t *testing.T) {
+	cs := &CollectorService{
+		logger:          log.New(io.Discard, "", 0),
+		tunables:        Tunables{FlushInterval: time.Second, TraceSampleRate: 1},
+		tunablesChanged: make(chan struct{}),
+	}
+	reloaded := cs.Tunables()
+	reloaded.SampleInterval = time.Second
+	if _, err := cs.Reload(reloaded); err == nil {
+		t.Error("Expected sampling to be refused without burst mode")
+	}
+	if cs.Tunables().SampleInterval != 0 {
+		t.Error("Expected the settings to stay unchanged")
+	}
+}
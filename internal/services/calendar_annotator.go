@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// calendarLookahead is how many days of upcoming events are annotated on each refresh
+const calendarLookahead = 2
+
+// CalendarConfig configures economic calendar annotation of the recorded data
+type CalendarConfig struct {
+	Source          ports.CalendarSource
+	Writer          ports.CalendarWriter
+	Window          time.Duration // Annotated time before and after each event
+	MinImpact       domain.Impact // Events below this impact are ignored
+	RefreshInterval time.Duration // How often the source is re-read
+}
+
+// Validate checks the refresh interval and that a source and writer are set
+func (c CalendarConfig) Validate() error {
+	if c.Source == nil || c.Writer == nil {
+		return fmt.Errorf("economic calendar needs a source and a writer")
+	}
+	if c.RefreshInterval <= 0 {
+		return fmt.Errorf("calendar refresh interval must be positive, got %v", c.RefreshInterval)
+	}
+	return nil
+}
+
+// WithEconomicCalendar writes a per-day sidecar of high-impact events and the instruments they affect
+func WithEconomicCalendar(cfg CalendarConfig) Option {
+	return func(cs *CollectorService) {
+		cs.calendar = &cfg
+	}
+}
+
+// annotateCalendar refreshes the calendar sidecars for today and the coming days
+func (cs *CollectorService) annotateCalendar() {
+	cfg := cs.calendar
+	cs.logger.Printf("Starting economic calendar annotation (window ±%v, min impact %s)", cfg.Window, cfg.MinImpact)
+
+	ticker := time.NewTicker(cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := cs.refreshCalendar(time.Now().UTC()); err != nil {
+			cs.logger.Printf("Economic calendar refresh failed: %v", err)
+		}
+
+		select {
+		case <-cs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshCalendar fetches events from today 00:00 UTC for calendarLookahead days and rewrites each day's sidecar
+func (cs *CollectorService) refreshCalendar(now time.Time) error {
+	cfg := cs.calendar
+	today := now.Truncate(24 * time.Hour)
+
+	ctx, cancel := context.WithTimeout(cs.ctx, time.Minute)
+	defer cancel()
+
+	events, err := cfg.Source.Events(ctx, today, today.AddDate(0, 0, calendarLookahead))
+	if err != nil {
+		return err
+	}
+
+	byDay := make(map[time.Time][]domain.CalendarAnnotation)
+	tickers := cs.getAllTickers()
+	slices.Sort(tickers)
+
+	var all []domain.CalendarAnnotation
+	for _, event := range events {
+		if event.Impact < cfg.MinImpact {
+			continue
+		}
+
+		annotation := domain.CalendarAnnotation{
+			Event:       event,
+			WindowStart: event.Time.Add(-cfg.Window),
+			WindowEnd:   event.Time.Add(cfg.Window),
+		}
+		for _, ticker := range tickers {
+			if event.Affects(ticker) {
+				annotation.Tickers = append(annotation.Tickers, ticker)
+			}
+		}
+		if len(annotation.Tickers) == 0 {
+			continue
+		}
+
+		day := event.Time.UTC().Truncate(24 * time.Hour)
+		byDay[day] = append(byDay[day], annotation)
+		all = append(all, annotation)
+	}
+	if cs.burst != nil {
+		cs.burst.setEvents(all)
+	}
+
+	for i := range calendarLookahead {
+		day := today.AddDate(0, 0, i)
+		annotations := byDay[day]
+		slices.SortFunc(annotations, func(a, b domain.CalendarAnnotation) int {
+			return a.Event.Time.Compare(b.Event.Time)
+		})
+		if err := cfg.Writer.WriteCalendarDay(ctx, day, annotations); err != nil {
+			return err
+		}
+	}
+
+	cs.logger.Printf("Economic calendar: annotated %d events for the next %d days", len(all), calendarLookahead)
+	return nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/calendar"
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+)
+
+func TestCalendarConfig_Validate(t *testing.T) {
+	cfg := CalendarConfig{
+		Source:          calendar.NewCSVCalendar("calendar.csv"),
+		Writer:          storage.NewCSVCalendarWriter(t.TempDir()),
+		RefreshInterval: time.Hour,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// CALENDAR_REFRESH=0 would panic in time.NewTicker once annotation starts
+	cfg.RefreshInterval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for a zero refresh interval")
+	}
+}
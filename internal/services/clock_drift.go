@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// clockCheckTimeout bounds one measurement of a clock source
+const clockCheckTimeout = 15 * time.Second
+
+// ClockDriftConfig configures the comparison of the local clock with reference clocks
+type ClockDriftConfig struct {
+	Sources   []ports.ClockSource
+	Interval  time.Duration // How often every source is measured
+	Threshold time.Duration // Drift beyond this, net of the measurement uncertainty, raises clock_drift
+}
+
+// Validate checks the interval and threshold
+func (c ClockDriftConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("clock check interval must be positive, got %v", c.Interval)
+	}
+	if c.Threshold <= 0 {
+		return fmt.Errorf("clock drift threshold must be positive, got %v", c.Threshold)
+	}
+	return nil
+}
+
+// WithClockDrift measures the local clock against cfg.Sources every cfg.Interval
+// Tick timestamps come from the local clock, so drift shows up in the data unnoticed otherwise
+func WithClockDrift(cfg ClockDriftConfig) Option {
+	return func(cs *CollectorService) {
+		if len(cfg.Sources) > 0 {
+			cs.clockDrift = &cfg
+		}
+	}
+}
+
+// monitorClockDrift measures every source periodically, warning while the clock is off
+func (cs *CollectorService) monitorClockDrift() {
+	cfg := cs.clockDrift
+	cs.logger.Printf("Starting clock drift monitor (%d sources, every %v, threshold %v)", len(cfg.Sources), cfg.Interval, cfg.Threshold)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	drifting := make(map[string]bool) // By source name
+	for {
+		for _, source := range cfg.Sources {
+			cs.checkClock(source, drifting)
+		}
+
+		select {
+		case <-cs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkClock measures one source, emitting clock_drift when the clock goes off and clock_synced when it is back
+func (cs *CollectorService) checkClock(source ports.ClockSource, drifting map[string]bool) {
+	cfg := cs.clockDrift
+	name := source.Name()
+
+	ctx, cancel := context.WithTimeout(cs.ctx, clockCheckTimeout)
+	offset, uncertainty, err := source.ClockOffset(ctx)
+	cancel()
+	if err != nil {
+		cs.logger.Printf("Clock check against %s failed: %v", name, err)
+		cs.countError("clock")
+		return
+	}
+	cs.clockOffsets.Set(offset.Seconds(), name)
+	cs.logger.Printf("Clock offset against %s: %v (±%v)", name, offset.Round(time.Millisecond), uncertainty.Round(time.Millisecond))
+
+	// Only drift the measurement can't explain counts; a precise clock stays quiet behind a slow link
+	drift := max(offset.Abs()-uncertainty, 0)
+	fields := map[string]string{
+		"source":         name,
+		"offset_ms":      fmt.Sprintf("%d", offset.Milliseconds()),
+		"uncertainty_ms": fmt.Sprintf("%d", uncertainty.Milliseconds()),
+		"threshold_ms":   fmt.Sprintf("%d", cfg.Threshold.Milliseconds()),
+	}
+	switch {
+	case drift > cfg.Threshold && !drifting[name]:
+		drifting[name] = true
+		direction := "ahead of"
+		if offset > 0 {
+			direction = "behind"
+		}
+		msg := fmt.Sprintf("Local clock is %v %s %s (±%v, threshold %v); tick timestamps are affected",
+			offset.Abs().Round(time.Millisecond), direction, name, uncertainty.Round(time.Millisecond), cfg.Threshold)
+		cs.logger.Println(msg)
+		event := domain.NewEvent(domain.EventClockDrift, domain.SeverityWarning, msg)
+		event.Fields = fields
+		cs.emit(event)
+
+	case drift <= cfg.Threshold && drifting[name]:
+		drifting[name] = false
+		msg := fmt.Sprintf("Local clock agrees with %s again (offset %v)", name, offset.Round(time.Millisecond))
+		cs.logger.Println(msg)
+		event := domain.NewEvent(domain.EventClockSynced, domain.SeverityInfo, msg)
+		event.Fields = fields
+		cs.emit(event)
+	}
+}
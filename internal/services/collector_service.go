@@ -2,21 +2,41 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/bjoelf/fx-collector/internal/domain"
-	"github.com/bjoelf/fx-collector/internal/ports"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
 	saxo "github.com/bjoelf/saxo-adapter/adapter"
 	"github.com/bjoelf/saxo-adapter/adapter/websocket"
 )
 
+// ErrInstrumentNotFound is returned when a price update's ticker isn't in the instrument map
+var ErrInstrumentNotFound = domain.ErrInstrumentNotFound
+
 type Instrument struct {
 	Ticker    string
 	Uic       int
 	AssetType string
 	Decimals  int
+	MaxSpread float64 // Sanity limit for the outlier filter (0 = none)
+
+	MaxTickRate float64 // Recorded ticks per second, token bucket (0 = unlimited)
+
+	PipSize        float64           // Pip size for pips/points spreads (e.g. 0.0001, 0.01 for JPY pairs)
+	SpreadUnit     domain.SpreadUnit // Unit sinks write the spread in
+	SpreadDecimals int               // Decimals of price-unit spreads when finer than Decimals (0 = Decimals)
+
+	// Overrides of service-wide settings (zero values use the service's setting)
+	SampleInterval time.Duration    // Burst mode sample interval; negative records every tick
+	StaleAfter     time.Duration    // Subscription watchdog threshold; negative disables the watchdog
+	Sessions       []domain.Session // Trading sessions for tick labels; non-nil and empty means no labels
 }
 
 type CollectorService struct {
@@ -24,75 +44,388 @@ type CollectorService struct {
 	brokerClient   saxo.BrokerClient
 	wsClient       saxo.WebSocketClient
 	instruments    map[string]Instrument
-	spreadRecorder ports.SpreadRecorder
+	spreadRecorder ports.TickWriter
 	logger         *log.Logger
-	flushInterval  time.Duration
-	flushTicker    *time.Ticker
+	flushStarted   bool
 	stopFlush      chan struct{}
 	ctx            context.Context
 	cancel         context.CancelFunc
+
+	// Sinks flushed on their own intervals (nil: the whole recorder at the flush interval)
+	flushGroupsByInterval map[time.Duration][]ports.Flusher
+
+	// Settings that can change while the service runs (see Reload)
+	tunablesMu      sync.RWMutex
+	tunables        Tunables
+	tunablesChanged chan struct{} // Closed and replaced by every Reload
+
+	// Operational notifications (optional)
+	notifier             ports.Notifier
+	lastTickAt           atomic.Int64  // Unix nanoseconds of the last received price update
+	dataGapCriticalAfter time.Duration // Data gaps this long are raised again as critical (0 = never)
+	adapterEvents        <-chan domain.Event
+
+	// Ops log (optional)
+	opsLog            ports.EventRecorder
+	opsQueue          *opsLogQueue
+	heartbeatInterval time.Duration
+	heartbeatTicks    atomic.Int64 // Updates received since the last heartbeat
+	wsConnected       atomic.Bool
+
+	// Per-instrument subscription health
+	ticks *tickTracker
+
+	// Snapshot, stale and indicative flags of ticks
+	quality *tickQuality
+
+	// Latest quote and spread range per instrument, attached to instrument events
+	spreads *spreadStats
+
+	// Per-instrument sequence numbers and strictly increasing timestamps
+	sequences     *sequencer
+	sequenceStore ports.SequenceStore
+	stateStore    ports.StateStore // Also the sequenceStore; nil without a state database
+	timestamps    *domain.TimestampOrder
+
+	// Unmappable price updates (optional)
+	unmappedQueue ports.DeadLetterQueue
+
+	// Primary/standby coordination (optional)
+	leaseLock       ports.LeaseLock
+	leaseTTL        time.Duration
+	isLeader        atomic.Bool
+	leaseValidUntil atomic.Int64 // Unix nanoseconds until which the leader may record
+
+	// Regular-grid snapshots (optional)
+	snapshots      *downsampler
+	snapshotWriter ports.SnapshotWriter
+	snapshotMaxAge time.Duration
+
+	// Clock-aligned latest quotes of all instruments (optional)
+	aligned *quoteBoard
+
+	// Hourly spread histograms per instrument (optional)
+	histograms *spreadHistograms
+
+	// Rolling spread z-scores per instrument (optional)
+	zscores *spreadZScores
+
+	// Broker updates as received, before mapping (optional)
+	rawCapture ports.RawCapture
+	rawQueue   *rawCaptureQueue
+
+	// Trading session definitions for tick labels (optional)
+	sessions []domain.Session
+
+	// Rollover and triple-swap tick flags (optional)
+	rollover *domain.RolloverCalendar
+
+	// Market holidays (optional, nil means FX market hours only)
+	holidays *domain.HolidayCalendar
+
+	// Spread sanity checks (optional)
+	outlierFilter *OutlierFilterConfig
+
+	// Spread recorded for crossed quotes ("" keeps the negative spread)
+	crossedQuotes domain.CrossedQuotePolicy
+
+	// Startup validation of instrument decimals against broker metadata (optional)
+	decimalsCheck      DecimalsCheck
+	instrumentMetadata ports.InstrumentMetadata
+
+	// Locked/crossed market detection (counter and log optional)
+	quoteAnomalies ports.Counter
+	anomalyQueue   ports.DeadLetterQueue
+
+	// Economic calendar annotation (optional)
+	calendar *CalendarConfig
+
+	// Comparison of the local clock with NTP and the broker (optional; offset gauge optional)
+	clockDrift   *ClockDriftConfig
+	clockOffsets ports.Gauge
+
+	// Pruning of stores with a retention period (optional)
+	retention      *RetentionConfig
+	retainedStores []RetainedStore
+
+	// Recording gates (disk emergency)
+	diskMonitor     *DiskMonitorConfig
+	recordingPaused atomic.Bool
+	sampleEvery     atomic.Int64 // Record every Nth tick (1 = all)
+	sampleCounter   int64
+
+	// Recording gates (admin API and schedule)
+	pauses        recordingPauses
+	pauseSchedule domain.PauseSchedule
+
+	// Sampled recording with full capture bursts (optional)
+	burst *burstMode
+
+	// Daily tick and byte quotas (optional)
+	quota *tickQuota
+
+	// Effective spread for a notional from the order book (optional, needs market depth)
+	effectiveNotional float64
+	depth             marketDepth
+
+	// Latest quote per instrument and window (optional)
+	conflation    *conflator
+	processorDone <-chan struct{} // Closed when the price processor has stopped for good
+
+	// Held (read) by the price processor while it handles an update; Stop takes it to keep a
+	// processor it gave up waiting for away from the sinks it closes
+	sinkGate    sync.RWMutex
+	sinksClosed bool // Set under sinkGate; the processor drops updates from then on
+
+	// Custom filter / enrich / transform stages (optional)
+	processor ports.TickProcessor
+
+	// Per-instrument tick rate limits (nil if no instrument has one)
+	rateLimiter    *tickRateLimiter
+	throttledTicks ports.Counter
+
+	// Pipeline stage timing (histogram and tracer optional)
+	stageDurations ports.Histogram
+	tracer         ports.Tracer
+	traceCounter   int
+
+	// Supervision of background goroutines
+	restartPolicy RestartPolicy
+	errorBudget   errorBudget
+
+	sinkSummaryInterval time.Duration // How often per-sink write statistics are logged (0 disables)
+	meteredSinks        []ports.MeteredSink
+	stopping            atomic.Bool // Set by the first Stop; later calls return at once
+}
+
+// Option configures optional CollectorService behaviour
+type Option func(*CollectorService)
+
+// WithNotifier sends operational events (disconnects, auth/storage failures, data gaps) to n
+func WithNotifier(n ports.Notifier) Option {
+	return func(cs *CollectorService) {
+		cs.notifier = n
+	}
+}
+
+// WithUnmappedDeadLetter writes price updates for unknown tickers to q instead of discarding them
+func WithUnmappedDeadLetter(q ports.DeadLetterQueue) Option {
+	return func(cs *CollectorService) {
+		cs.unmappedQueue = q
+	}
+}
+
+// WithOpsLog records connection events, subscription resets and periodic heartbeats to log
+// A background writer does the writing, so a slow ops log never holds up price processing
+func WithOpsLog(log ports.EventRecorder, heartbeatInterval time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.opsLog = log
+		cs.opsQueue = &opsLogQueue{events: make(chan domain.Event, opsLogQueueSize), done: make(chan struct{})}
+		cs.heartbeatInterval = heartbeatInterval
+	}
+}
+
+// WithSessions labels every tick with the trading sessions open at its timestamp, unless its instrument has its own
+func WithSessions(sessions []domain.Session) Option {
+	return func(cs *CollectorService) {
+		cs.sessions = sessions
+	}
+}
+
+// WithRolloverFlags flags ticks inside the daily rollover window and on triple-swap days
+func WithRolloverFlags(rollover *domain.RolloverCalendar) Option {
+	return func(cs *CollectorService) {
+		cs.rollover = rollover
+	}
+}
+
+// WithHolidays treats instruments as closed on their holidays (watchdog, gap detection, snapshots)
+func WithHolidays(holidays *domain.HolidayCalendar) Option {
+	return func(cs *CollectorService) {
+		cs.holidays = holidays
+	}
+}
+
+// WithDataGapThreshold sets how long without any price update counts as a data gap
+func WithDataGapThreshold(d time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.tunables.DataGapThreshold = d
+	}
+}
+
+// WithDataGapCriticalAfter re-raises a data gap as critical once it lasts d (0 = never)
+func WithDataGapCriticalAfter(d time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.dataGapCriticalAfter = d
+	}
 }
 
 func NewCollectorService(
 	authClient saxo.AuthClient,
 	brokerClient saxo.BrokerClient,
 	instruments map[string]Instrument,
-	spreadRecorder ports.SpreadRecorder,
+	spreadRecorder ports.TickWriter,
 	flushInterval time.Duration,
 	logger *log.Logger,
+	opts ...Option,
 ) (*CollectorService, error) {
 
-	// Create WebSocket client
-	wsClient := websocket.NewSaxoWebSocketClient(
-		authClient,
-		authClient.GetBaseURL(),
-		authClient.GetWebSocketURL(),
-		logger,
-	)
-
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &CollectorService{
+	cs := &CollectorService{
 		authClient:     authClient,
 		brokerClient:   brokerClient,
-		wsClient:       wsClient,
 		instruments:    instruments,
 		spreadRecorder: spreadRecorder,
 		logger:         logger,
-		flushInterval:  flushInterval,
 		stopFlush:      make(chan struct{}),
 		ctx:            ctx,
 		cancel:         cancel,
-	}, nil
+		tunables: Tunables{
+			FlushInterval:    flushInterval,
+			TraceSampleRate:  1,
+			DataGapThreshold: 5 * time.Minute,
+		},
+		tunablesChanged: make(chan struct{}),
+		ticks:           newTickTracker(),
+		quality:         newTickQuality(),
+		spreads:         newSpreadStats(),
+		sequences:       newSequencer(),
+		timestamps:      domain.NewTimestampOrder(),
+		rateLimiter:     newTickRateLimiter(instruments),
+		restartPolicy:   DefaultRestartPolicy(),
+		errorBudget:     errorBudget{interval: time.Hour},
+		snapshotMaxAge:  DefaultSnapshotMaxAge,
+	}
+
+	WithMetrics(nopMetrics{})(cs)
+	for _, opt := range opts {
+		opt(cs)
+	}
+	cs.sampleEvery.Store(1)
+
+	// Create WebSocket client unless one was injected (replay)
+	if cs.wsClient == nil {
+		cs.wsClient = websocket.NewSaxoWebSocketClient(
+			authClient,
+			authClient.GetBaseURL(),
+			authClient.GetWebSocketURL(),
+			logger,
+		)
+	}
+
+	if err := cs.restartPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if cs.retention != nil && cs.retention.CheckInterval <= 0 {
+		return nil, fmt.Errorf("retention check interval must be positive, got %v", cs.retention.CheckInterval)
+	}
+	if cs.leaseLock != nil && cs.leaseTTL < minLeaseTTL {
+		return nil, fmt.Errorf("leader lease TTL must be at least %v, got %v", minLeaseTTL, cs.leaseTTL)
+	}
+	if cs.diskMonitor != nil {
+		if err := cs.diskMonitor.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cs.calendar != nil {
+		if err := cs.calendar.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cs.clockDrift != nil {
+		if err := cs.clockDrift.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cs.outlierFilter != nil {
+		if err := cs.outlierFilter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := domain.ParseCrossedQuotePolicy(string(cs.crossedQuotes)); err != nil {
+		return nil, err
+	}
+	if cs.decimalsCheck != "" {
+		if err := cs.decimalsCheck.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cs.burst != nil {
+		if err := (BurstConfig{SampleInterval: cs.tunables.SampleInterval, AnomalyWindow: cs.tunables.BurstAnomalyWindow}).Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cs.quota != nil {
+		if err := cs.quota.cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cs.effectiveNotional < 0 {
+		return nil, fmt.Errorf("effective spread notional must be positive, got %v", cs.effectiveNotional)
+	}
+	if cs.conflation != nil && cs.conflation.window <= 0 {
+		return nil, fmt.Errorf("conflation window must be positive, got %v", cs.conflation.window)
+	}
+	if cs.quality.staleAfter < 0 {
+		return nil, fmt.Errorf("stale quote threshold must not be negative, got %v", cs.quality.staleAfter)
+	}
+	if cs.zscores != nil && cs.zscores.window <= 0 {
+		return nil, fmt.Errorf("spread z-score window must be positive, got %v", cs.zscores.window)
+	}
+
+	return cs, nil
 }
 
 func (cs *CollectorService) Start() error {
 	cs.logger.Println("Starting FX Collector Service...")
+	cs.startOpsLog()
+
+	if err := cs.loadSequences(); err != nil {
+		return err
+	}
 
 	if !cs.authClient.IsAuthenticated() {
 		cs.logger.Println("Not authenticated - attempting login...")
 		if err := cs.authClient.Login(cs.ctx); err != nil {
+			// Send synchronously - the process is about to exit
+			event := domain.NewEvent(domain.EventAuthFailure, domain.SeverityCritical,
+				fmt.Sprintf("Authentication failed: %v", err))
+			cs.recordOpsAndWait(event)
+			cs.notifyAndWait(event)
 			return fmt.Errorf("authentication failed: %w", err)
 		}
 		cs.logger.Println("Authentication successful")
 	}
 
+	if err := cs.checkInstrumentDecimals(cs.ctx); err != nil {
+		return err
+	}
+
 	wsStateChannel := make(chan bool, 1)
 	wsContextIDChannel := make(chan string, 1)
 	cs.wsClient.SetStateChannels(wsStateChannel, wsContextIDChannel)
+	cs.startRawCapture()
 
+	// Connection state is observed for notifications first, then forwarded to the token refresher
+	var tokenStateChannel chan bool
 	if saxoAuth, ok := cs.authClient.(interface {
 		StartTokenEarlyRefresh(ctx context.Context, wsConnected <-chan bool, wsContextID <-chan string)
 	}); ok {
-		go saxoAuth.StartTokenEarlyRefresh(cs.ctx, wsStateChannel, wsContextIDChannel)
+		tokenStateChannel = make(chan bool, 1)
+		go saxoAuth.StartTokenEarlyRefresh(cs.ctx, tokenStateChannel, wsContextIDChannel)
 		cs.logger.Println("Token refresh manager started")
 	}
+	cs.supervise("connection monitor", func(ctx context.Context) error {
+		return cs.monitorConnectionState(wsStateChannel, tokenStateChannel)
+	})
 
 	cs.logger.Println("Connecting to Saxo WebSocket...")
 	if err := cs.wsClient.Connect(cs.ctx); err != nil {
 		return fmt.Errorf("websocket connection failed: %w", err)
 	}
 	cs.logger.Println("WebSocket connected")
+	cs.wsConnected.Store(true)
 
 	// Register instruments with WebSocket for UIC mapping
 	// CRITICAL: This must be called before SubscribeToPrices
@@ -108,47 +441,96 @@ func (cs *CollectorService) Start() error {
 		cs.logger.Println("Warning: WebSocket client doesn't support RegisterInstruments")
 	}
 
+	cs.startMarketDepth()
+
 	tickers := cs.getAllTickers()
 	cs.logger.Printf("Subscribing to %d instruments", len(tickers))
 
-	if err := cs.wsClient.SubscribeToPrices(cs.ctx, tickers); err != nil {
+	cs.quality.expectSnapshots(tickers)
+	if err := cs.subscribeToPrices(tickers); err != nil {
 		return fmt.Errorf("price subscription failed: %w", err)
 	}
 	cs.logger.Println("Price subscriptions established")
 
-	go cs.processPriceUpdates()
+	cs.lastTickAt.Store(time.Now().UnixNano())
+	cs.processorDone = cs.supervise("price processor", cs.processPriceUpdates)
+	cs.superviseLoop("data gap monitor", cs.monitorDataGaps)
+	if cs.diskMonitor != nil {
+		cs.superviseLoop("disk monitor", cs.monitorDiskSpace)
+	}
+	if cs.retention != nil && len(cs.retainedStores) > 0 {
+		cs.superviseLoop("retention", cs.enforceRetention)
+	}
+	if cs.clockDrift != nil {
+		cs.superviseLoop("clock drift monitor", cs.monitorClockDrift)
+	}
+	if cs.tunables.SubscriptionStaleAfter > 0 || slices.ContainsFunc(slices.Collect(maps.Values(cs.instruments)),
+		func(instrument Instrument) bool { return instrument.StaleAfter > 0 }) {
+		cs.superviseLoop("subscription watchdog", cs.watchSubscriptions)
+	}
+	if cs.opsLog != nil && cs.heartbeatInterval > 0 {
+		cs.superviseLoop("heartbeat", cs.recordHeartbeats)
+	}
+	if cs.leaseLock != nil {
+		cs.superviseLoop("leader election", cs.campaignForLeadership)
+	}
+	if cs.snapshots != nil {
+		cs.superviseLoop("snapshot writer", cs.writeSnapshots)
+	}
+	if cs.aligned != nil {
+		cs.superviseLoop("aligned quote writer", cs.writeAlignedQuotes)
+	}
+	if cs.histograms != nil {
+		cs.superviseLoop("spread histogram writer", cs.writeHistograms)
+	}
+	if cs.calendar != nil {
+		cs.superviseLoop("calendar annotator", cs.annotateCalendar)
+	}
+	if cs.errorBudget.interval > 0 {
+		cs.superviseLoop("error budget report", cs.reportErrorBudget)
+	}
+	if cs.sinkSummaryInterval > 0 && len(cs.meteredSinks) > 0 {
+		cs.superviseLoop("sink summary", cs.reportSinks)
+	}
+	if cs.adapterEvents != nil {
+		cs.superviseLoop("adapter events", cs.forwardAdapterEvents)
+	}
 	cs.startPeriodicFlush()
 
 	cs.logger.Println("FX Collector Service started successfully")
 	return nil
 }
 
-func (cs *CollectorService) processPriceUpdates() {
+// processPriceUpdates records price updates until ctx is done; supervised as "price processor"
+func (cs *CollectorService) processPriceUpdates(ctx context.Context) error {
 	cs.logger.Println("Starting price update processor...")
 
 	priceChannel := cs.wsClient.GetPriceUpdateChannel()
 	updateCount := 0
 
+	var conflationTick <-chan time.Time
+	if cs.conflation != nil {
+		ticker := time.NewTicker(cs.conflation.window)
+		defer ticker.Stop()
+		conflationTick = ticker.C
+		// The context is cancelled by now; quotes still held are written before the final flush
+		defer cs.flushConflatedSafely(time.Time{})
+	}
+
 	for {
 		select {
-		case <-cs.ctx.Done():
+		case now := <-conflationTick:
+			cs.flushConflatedSafely(now)
+
+		case <-ctx.Done():
 			cs.logger.Printf("Price processor stopping (received %d updates)", updateCount)
-			return
+			return nil
 
 		case priceUpdate, ok := <-priceChannel:
 			if !ok {
-				cs.logger.Println("Price channel closed")
-				return
+				return unrecoverable(errors.New("price channel closed"))
 			}
-
-			priceData, err := cs.mapPriceUpdate(&priceUpdate)
-			if err != nil {
-				cs.logger.Printf("Error mapping price for %s: %v", priceUpdate.Ticker, err)
-				continue
-			}
-
-			if err := cs.spreadRecorder.Record(cs.ctx, priceData); err != nil {
-				cs.logger.Printf("Error recording price for %s: %v", priceUpdate.Ticker, err)
+			if !cs.handlePriceUpdate(priceUpdate) {
 				continue
 			}
 
@@ -160,10 +542,179 @@ func (cs *CollectorService) processPriceUpdates() {
 	}
 }
 
+// handlePriceUpdate processes one update; a panic loses this update only and is counted
+func (cs *CollectorService) handlePriceUpdate(priceUpdate saxo.PriceUpdate) bool {
+	if !cs.enterSinks() {
+		return false
+	}
+	defer cs.sinkGate.RUnlock()
+	defer cs.recoverPanic("price_processor", priceUpdate.Ticker)
+	return cs.processPriceUpdate(priceUpdate)
+}
+
+// flushConflatedSafely writes the conflated quotes of a window (all of them for a zero now),
+// recovering from a panic
+func (cs *CollectorService) flushConflatedSafely(now time.Time) {
+	if !cs.enterSinks() {
+		return
+	}
+	defer cs.sinkGate.RUnlock()
+	defer cs.recoverPanic("price_processor", "conflation")
+	ctx := cs.ctx
+	if now.IsZero() {
+		ctx = context.Background() // Cancelled by now
+	}
+	cs.flushConflated(ctx, now)
+}
+
+// enterSinks read-locks sinkGate for writing to the sinks; false (and unlocked) once Stop closed them
+func (cs *CollectorService) enterSinks() bool {
+	cs.sinkGate.RLock()
+	if cs.sinksClosed {
+		cs.sinkGate.RUnlock()
+		return false
+	}
+	return true
+}
+
+// closeSinkGate keeps the price processor away from the sinks after Stop gave up waiting for it
+// False if the processor is still inside an update (stuck writing to a sink) after a short wait;
+// the sinks must then be left open
+func (cs *CollectorService) closeSinkGate() bool {
+	for range 10 {
+		if cs.sinkGate.TryLock() {
+			cs.sinksClosed = true
+			cs.sinkGate.Unlock()
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// processPriceUpdate maps, checks and records (or conflates) one price update; false if it was dropped
+func (cs *CollectorService) processPriceUpdate(priceUpdate saxo.PriceUpdate) bool {
+	receivedAt := time.Now()
+	cs.ticks.touch(priceUpdate.Ticker, receivedAt)
+	cs.captureRaw(priceUpdate, receivedAt)
+	if isQuoteless(priceUpdate) {
+		return false // Counts as liveness for the watchdog, but has no quote to record
+	}
+	cs.lastTickAt.Store(receivedAt.UnixNano())
+	cs.heartbeatTicks.Add(1)
+
+	trace := cs.traceTick(priceUpdate, receivedAt)
+	defer trace.end()
+
+	priceData, err := cs.mapPriceUpdate(&priceUpdate)
+	stageStart := trace.stage("map", receivedAt)
+	if err != nil {
+		cs.logger.Printf("Error mapping price for %s: %v", priceUpdate.Ticker, err)
+		cs.countError("map")
+		if errors.Is(err, ErrInstrumentNotFound) {
+			cs.deadLetterUnmapped(priceUpdate, err)
+		}
+		trace.fail(err)
+		return false
+	}
+
+	cs.checkQuote(priceUpdate, priceData)
+
+	if !cs.filterOutlier(priceData) {
+		trace.drop("outlier")
+		return false
+	}
+
+	if priceData = cs.process(priceData, trace); priceData == nil {
+		return false
+	}
+
+	if cs.zscores != nil {
+		cs.zscores.score(priceData)
+	}
+
+	// Snapshots see every plausible tick; sampling or outliers would distort the spread range
+	if !priceData.Flags.Has(domain.FlagOutlier) {
+		cs.spreads.observe(priceData)
+	}
+	if cs.snapshots != nil && !priceData.Flags.Has(domain.FlagOutlier) {
+		cs.snapshots.observe(priceData)
+	}
+	if cs.aligned != nil && !priceData.Flags.Has(domain.FlagOutlier) {
+		cs.aligned.observe(priceData)
+	}
+	if cs.histograms != nil && !priceData.Flags.Has(domain.FlagOutlier) {
+		cs.histograms.observe(priceData)
+	}
+
+	if cs.conflation != nil {
+		cs.conflate(priceData)
+		return true
+	}
+	return cs.recordTick(cs.ctx, priceData, trace, stageStart)
+}
+
+// recordTick applies the recording gates and writes a tick; false if it wasn't recorded
+// Only called from the price processor goroutine
+func (cs *CollectorService) recordTick(ctx context.Context, priceData *domain.PriceData, trace tickTrace, stageStart time.Time) bool {
+	recorded := cs.shouldRecord(priceData)
+	stageStart = trace.stage("filter", stageStart)
+	if !recorded {
+		trace.drop("gated")
+		return false
+	}
+	priceData.Sequence = cs.nextSequence(priceData.Ticker)
+	var ordering domain.TickFlags
+	priceData.Timestamp, ordering = cs.timestamps.Next(priceData.Ticker, priceData.Timestamp)
+	priceData.Flags |= ordering
+
+	err := cs.spreadRecorder.Record(ctx, priceData)
+	trace.stage("record", stageStart)
+	if err != nil {
+		cs.logger.Printf("Error recording price for %s: %v", priceData.Ticker, err)
+		cs.countError("record")
+		event := domain.NewEvent(domain.EventStorageError, domain.SeverityCritical,
+			fmt.Sprintf("Failed to record price: %v", err))
+		event.Ticker = priceData.Ticker
+		cs.emit(event)
+		trace.fail(err)
+		return false
+	}
+	cs.sequences.recorded(priceData.Ticker, priceData.Timestamp)
+	if cs.snapshots != nil && !priceData.Flags.Has(domain.FlagOutlier) {
+		cs.snapshots.recorded(priceData)
+	}
+	return true
+}
+
+// shouldRecord applies the recording gates (pauses, disk emergency, burst sampling, rate limits, quotas) to a tick
+// Only called from the price processor goroutine
+func (cs *CollectorService) shouldRecord(priceData *domain.PriceData) bool {
+	if !cs.recordingAllowed() || cs.recordingPausedFor(priceData.Ticker, priceData.Timestamp) {
+		return false
+	}
+
+	if every := cs.sampleEvery.Load(); every > 1 {
+		cs.sampleCounter++
+		if cs.sampleCounter%every != 0 {
+			return false
+		}
+	}
+	return cs.sampleTick(priceData) && cs.limitTickRate(priceData) && cs.admitQuota(priceData)
+}
+
+// recordingAllowed reports whether this instance currently writes data (not paused, leader if HA)
+func (cs *CollectorService) recordingAllowed() bool {
+	if cs.recordingPaused.Load() {
+		return false
+	}
+	return cs.leaseLock == nil || (cs.isLeader.Load() && cs.leaseValid(time.Now()))
+}
+
 func (cs *CollectorService) mapPriceUpdate(update *saxo.PriceUpdate) (*domain.PriceData, error) {
 	instrument, ok := cs.instruments[update.Ticker]
 	if !ok {
-		return nil, fmt.Errorf("instrument not found: %s", update.Ticker)
+		return nil, fmt.Errorf("%w: %s", ErrInstrumentNotFound, update.Ticker)
 	}
 
 	priceData := &domain.PriceData{
@@ -174,12 +725,42 @@ func (cs *CollectorService) mapPriceUpdate(update *saxo.PriceUpdate) (*domain.Pr
 		Bid:       update.Bid,
 		Ask:       update.Ask,
 		Decimals:  instrument.Decimals,
+
+		SpreadUnit:     instrument.SpreadUnit,
+		PipSize:        instrument.PipSize,
+		SpreadDecimals: instrument.SpreadDecimals,
 	}
 
-	priceData.CalculateSpread()
+	priceData.CalculateSpreadWith(cs.crossedQuotes)
+	sessions := cs.sessions
+	if instrument.Sessions != nil {
+		sessions = instrument.Sessions
+	}
+	priceData.SessionLabel = domain.SessionLabel(sessions, priceData.Timestamp)
+	priceData.Flags |= cs.rollover.Flags(priceData.Timestamp)
+	cs.flagQuality(priceData)
+	cs.addEffectiveSpread(priceData)
 	return priceData, nil
 }
 
+// deadLetterUnmapped keeps the raw update so ticker renames and new symbols are recoverable
+func (cs *CollectorService) deadLetterUnmapped(update saxo.PriceUpdate, cause error) {
+	if cs.unmappedQueue == nil {
+		return
+	}
+
+	entry := domain.DeadLetter{
+		Timestamp: time.Now().UTC(),
+		Reason:    "unmapped_ticker",
+		Error:     cause.Error(),
+		Payload:   update,
+	}
+	if err := cs.unmappedQueue.Put(cs.ctx, entry); err != nil {
+		cs.logger.Printf("Failed to dead-letter unmapped update for %s: %v", update.Ticker, err)
+		cs.countError("dead_letter")
+	}
+}
+
 func (cs *CollectorService) getAllTickers() []string {
 	tickers := make([]string, 0, len(cs.instruments))
 	for ticker := range cs.instruments {
@@ -188,54 +769,249 @@ func (cs *CollectorService) getAllTickers() []string {
 	return tickers
 }
 
+// anyMarketOpen reports whether at least one instrument is expected to trade at t
+func (cs *CollectorService) anyMarketOpen(t time.Time) bool {
+	for ticker := range cs.instruments {
+		if cs.holidays.IsOpen(ticker, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// startPeriodicFlush runs one flush ticker per distinct sink flush interval
+// Last recorded ticks are saved after flushes at the default interval (or the shortest one if no sink uses it)
 func (cs *CollectorService) startPeriodicFlush() {
-	cs.flushTicker = time.NewTicker(cs.flushInterval)
+	groups := cs.flushGroups()
+	if len(groups) == 0 {
+		cs.logger.Println("Spread recorder doesn't buffer - periodic flush disabled")
+		return
+	}
 
-	go func() {
-		cs.logger.Printf("Starting periodic flush (every %v)", cs.flushInterval)
-
-		for {
-			select {
-			case <-cs.ctx.Done():
-				return
-			case <-cs.stopFlush:
-				return
-			case <-cs.flushTicker.C:
-				if err := cs.spreadRecorder.Flush(cs.ctx); err != nil {
-					cs.logger.Printf("Flush error: %v", err)
-				}
+	intervals := slices.Sorted(maps.Keys(groups)) // 0, the default interval, sorts first
+
+	cs.flushStarted = true
+	for _, interval := range intervals {
+		name := fmt.Sprintf("flush loop (%v)", interval)
+		if interval == defaultFlushGroup {
+			name = "flush loop (default)"
+		}
+		cs.supervise(name, func(ctx context.Context) error {
+			cs.runFlushTicker(interval, groups[interval], interval == intervals[0])
+			return nil
+		})
+	}
+}
+
+// runFlushTicker flushes sinks every interval until the service stops
+// The defaultFlushGroup follows the flush interval, also when it is changed by Reload
+func (cs *CollectorService) runFlushTicker(interval time.Duration, sinks []ports.Flusher, saveLastRecorded bool) {
+	var changed <-chan struct{}
+	if interval == defaultFlushGroup {
+		interval, changed = cs.currentTunables().FlushInterval, cs.tunablesUpdates()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	cs.logger.Printf("Starting periodic flush of %d sink(s) (every %v)", len(sinks), interval)
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+		case <-cs.stopFlush:
+			return
+		case <-changed:
+			changed = cs.tunablesUpdates()
+			if current := cs.currentTunables().FlushInterval; current != interval {
+				interval = current
+				ticker.Reset(interval)
 			}
+		case <-ticker.C:
+			cs.flushSinks(interval, sinks, saveLastRecorded)
 		}
-	}()
+	}
+}
+
+// flushSinks runs one periodic flush; a panic skips this round only and is counted
+func (cs *CollectorService) flushSinks(interval time.Duration, sinks []ports.Flusher, saveLastRecorded bool) {
+	defer cs.recoverPanic("flush", interval.String())
+
+	span := cs.startSpan("flush", nil, time.Now())
+	span.SetAttribute("fx.sinks", len(sinks))
+	start := time.Now()
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Flush(cs.ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+	cs.stageDurations.Observe(time.Since(start).Seconds(), "flush")
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		cs.logger.Printf("Flush error: %v", err)
+		cs.countError("flush")
+		cs.emit(domain.NewEvent(domain.EventStorageError, domain.SeverityCritical,
+			fmt.Sprintf("Flush failed: %v", err)))
+		return
+	}
+	if saveLastRecorded {
+		cs.saveLastRecorded(cs.ctx)
+	}
+}
+
+// defaultFlushGroup keys the sinks flushed at the service's flush interval in flushGroups
+const defaultFlushGroup time.Duration = 0
+
+// WithFlushGroups flushes sinks on their own intervals (see storage.FlushGroups); the sinks under
+// defaultFlushGroup follow the service's flush interval
+// Without it the whole recorder is flushed at the service's flush interval
+func WithFlushGroups(groups map[time.Duration][]ports.Flusher) Option {
+	return func(cs *CollectorService) {
+		cs.flushGroupsByInterval = groups
+	}
+}
+
+// flushGroups returns the buffering sinks by flush interval
+func (cs *CollectorService) flushGroups() map[time.Duration][]ports.Flusher {
+	if cs.flushGroupsByInterval != nil {
+		return cs.flushGroupsByInterval
+	}
+	groups := make(map[time.Duration][]ports.Flusher)
+	if flusher, ok := cs.spreadRecorder.(ports.Flusher); ok {
+		groups[defaultFlushGroup] = []ports.Flusher{flusher}
+	}
+	return groups
 }
 
-func (cs *CollectorService) Stop() error {
+// flushRecorder flushes the recorder if it buffers writes
+func (cs *CollectorService) flushRecorder(ctx context.Context) error {
+	if flusher, ok := cs.spreadRecorder.(ports.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// Stop shuts the service down within ctx's deadline: it drains conflated quotes, flushes and
+// closes the sinks, saves sequence numbers, disconnects and releases the leader lease
+// Only the first call does anything. Every step is attempted even after ctx is done (sinks then skip slow work such as fsync),
+// so resources are released; the returned error joins the failed steps and ctx's error
+// The exception is a price processor still stuck in an update at the deadline: the sinks it writes
+// to are then neither flushed nor closed, and the caller is expected to exit
+func (cs *CollectorService) Stop(ctx context.Context) error {
+	if !cs.stopping.CompareAndSwap(false, true) {
+		return nil
+	}
 	cs.logger.Println("Stopping FX Collector Service...")
 
-	if cs.flushTicker != nil {
-		cs.flushTicker.Stop()
+	if cs.flushStarted {
 		close(cs.stopFlush)
 	}
 
 	cs.cancel()
 
-	cs.logger.Println("Performing final flush...")
-	if err := cs.spreadRecorder.Flush(cs.ctx); err != nil {
-		cs.logger.Printf("Final flush error: %v", err)
+	var errs []error
+	processorErr := cs.waitForProcessor(ctx)
+	sinksFree := true
+	if processorErr != nil {
+		errs = append(errs, processorErr)
+		if sinksFree = cs.closeSinkGate(); !sinksFree {
+			errs = append(errs, errors.New("left the sinks open: the price processor is still writing to them"))
+		}
+	}
+
+	if sinksFree {
+		cs.logger.Println("Performing final flush...")
+		if err := cs.flushRecorder(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("final flush failed: %w", err))
+		} else {
+			cs.saveLastRecorded(ctx)
+		}
+	}
+	if processorErr == nil { // Otherwise the persisted reservations stay, above any number still handed out
+		cs.saveSequences(ctx)
 	}
 
 	cs.logger.Println("Closing WebSocket connection...")
-	if err := cs.wsClient.Close(); err != nil {
-		cs.logger.Printf("WebSocket close error: %v", err)
+	if err := cs.closeWebSocket(ctx); err != nil {
+		errs = append(errs, err)
 	}
 
-	cs.logger.Println("Closing spread recorder...")
-	if err := cs.spreadRecorder.Close(); err != nil {
-		cs.logger.Printf("Recorder close error: %v", err)
+	if err := cs.releaseLeadership(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cs.closeOpsLog(ctx); err != nil {
+		errs = append(errs, err)
 	}
 
+	if !sinksFree {
+		return cs.stopped(ctx, errs)
+	}
+	if closer, ok := cs.spreadRecorder.(ports.Closer); ok {
+		cs.logger.Println("Closing spread recorder...")
+		if err := closer.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close spread recorder: %w", err))
+		}
+	}
+	if err := cs.closeSnapshotWriter(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cs.closeAlignedWriter(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cs.closeHistogramWriter(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cs.closeRawCapture(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return cs.stopped(ctx, errs)
+}
+
+// stopped logs the errors of Stop and joins them, adding the deadline if it passed
+func (cs *CollectorService) stopped(ctx context.Context, errs []error) error {
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown deadline exceeded: %w", err))
+	}
+	for _, err := range errs {
+		cs.logger.Printf("Shutdown error: %v", err)
+	}
 	cs.logger.Println("FX Collector Service stopped")
-	return nil
+	return errors.Join(errs...)
+}
+
+// waitForProcessor waits until the price processor has finished the update in hand and written
+// the quotes it still held (conflation), so nothing is recorded after the final flush
+func (cs *CollectorService) waitForProcessor(ctx context.Context) error {
+	if cs.processorDone == nil {
+		return nil
+	}
+	select {
+	case <-cs.processorDone:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the price processor: %w", ctx.Err())
+	}
+}
+
+// closeWebSocket closes the broker connection, abandoning it if that outlasts ctx
+// The WebSocket client's Close takes no context, so it runs in the background
+func (cs *CollectorService) closeWebSocket(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cs.wsClient.Close()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to close WebSocket: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up closing WebSocket: %w", ctx.Err())
+	}
 }
 
 // convertToSaxoInstruments converts collector instruments to saxo.Instrument format for WebSocket registration
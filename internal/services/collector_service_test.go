@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestCollectorService_SinkGateKeepsStuckProcessorAway(t *testing.T) {
+	cs := &CollectorService{}
+	if !cs.enterSinks() {
+		t.Fatal("Expected the sinks to be open")
+	}
+	// The processor is inside an update: the sinks must not be closed under it
+	if cs.closeSinkGate() {
+		t.Fatal("Expected the gate not to close while an update is in progress")
+	}
+	cs.sinkGate.RUnlock()
+
+	if !cs.closeSinkGate() {
+		t.Fatal("Expected the gate to close between updates")
+	}
+	if cs.enterSinks() {
+		t.Error("Expected updates to be dropped once the sinks are closed")
+	}
+}
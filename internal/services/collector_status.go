@@ -0,0 +1,30 @@
+package services
+
+import (
+	"slices"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// CollectorStatus returns the connection state and the latest quote of every instrument, for monitors
+func (cs *CollectorService) CollectorStatus() domain.CollectorStatus {
+	status := domain.CollectorStatus{
+		Timestamp:   time.Now().UTC(),
+		Connected:   cs.wsConnected.Load(),
+		Instruments: []domain.InstrumentStatus{},
+	}
+	if last := cs.lastTickAt.Load(); last > 0 {
+		at := time.Unix(0, last).UTC()
+		status.LastTickAt = &at
+	}
+
+	pauses := cs.RecordingState()
+	for _, ticker := range slices.Sorted(slices.Values(cs.getAllTickers())) {
+		instrument := cs.spreads.status(ticker)
+		instrument.Paused = pauses.Paused || cs.recordingPaused.Load() ||
+			slices.Contains(pauses.Tickers, ticker) || slices.Contains(pauses.Scheduled, ticker)
+		status.Instruments = append(status.Instruments, instrument)
+	}
+	return status
+}
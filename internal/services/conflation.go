@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// conflator holds the latest quote per instrument until its window ends
+// Only touched by the price processor goroutine
+type conflator struct {
+	window  time.Duration
+	pending map[string]*conflatedQuote
+}
+
+// conflatedQuote is the latest quote of an instrument in the window starting at start
+type conflatedQuote struct {
+	data  *domain.PriceData
+	start time.Time
+}
+
+// WithConflation records only the last quote per instrument in each clock-aligned window (e.g. :00.000, :00.250)
+// Quotes that replaced earlier ones in their window carry FlagConflated. Recording gates, rate limits
+// and quotas apply to the conflated quotes; snapshots and aligned quotes still see every tick
+func WithConflation(window time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.conflation = &conflator{window: window, pending: make(map[string]*conflatedQuote)}
+	}
+}
+
+// conflate makes priceData its instrument's latest quote, recording the quote of an earlier window first
+// Only called from the price processor goroutine
+func (cs *CollectorService) conflate(priceData *domain.PriceData) {
+	c := cs.conflation
+	start := priceData.Timestamp.Truncate(c.window)
+
+	if q, ok := c.pending[priceData.Ticker]; ok {
+		if !start.After(q.start) {
+			// Same window (an out-of-order quote stays in the held one's window); a quote older
+			// than the held one is superseded by it rather than replacing it
+			if !priceData.Timestamp.Before(q.data.Timestamp) {
+				priceData.Flags |= domain.FlagConflated
+				q.data = priceData
+			} else {
+				q.data.Flags |= domain.FlagConflated
+			}
+			return
+		}
+		cs.recordTick(cs.ctx, q.data, tickTrace{cs: cs}, time.Now())
+	}
+	c.pending[priceData.Ticker] = &conflatedQuote{data: priceData, start: start}
+}
+
+// flushConflated records the held quotes whose window ended by now (all of them for a zero now)
+// Windows are judged by the local clock, so a quote arriving after its window was written
+// starts a new held quote for that window
+// Only called from the price processor goroutine
+func (cs *CollectorService) flushConflated(ctx context.Context, now time.Time) {
+	c := cs.conflation
+	var due []*domain.PriceData
+	for ticker, q := range c.pending {
+		if now.IsZero() || !now.Before(q.start.Add(c.window)) {
+			due = append(due, q.data)
+			delete(c.pending, ticker)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *domain.PriceData) int { return a.Timestamp.Compare(b.Timestamp) })
+	for _, priceData := range due {
+		cs.recordTick(ctx, priceData, tickTrace{cs: cs}, time.Now())
+	}
+}
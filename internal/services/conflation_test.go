@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// countingRecorder remembers the ticks written to it
+type countingRecorder struct {
+	mu    sync.Mutex
+	ticks []*domain.PriceData
+}
+
+func (r *countingRecorder) Record(ctx context.Context, tick *domain.PriceData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ticks = append(r.ticks, tick)
+	return nil
+}
+
+func (r *countingRecorder) RecordBatch(ctx context.Context, ticks []*domain.PriceData) error {
+	for _, tick := range ticks {
+		r.Record(ctx, tick)
+	}
+	return nil
+}
+
+func (r *countingRecorder) recorded() []*domain.PriceData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*domain.PriceData(nil), r.ticks...)
+}
+
+func newConflatingService(recorder *countingRecorder, window time.Duration) *CollectorService {
+	cs := &CollectorService{
+		ctx:            context.Background(),
+		logger:         log.New(io.Discard, "", 0),
+		spreadRecorder: recorder,
+		sequences:      newSequencer(),
+		timestamps:     domain.NewTimestampOrder(),
+	}
+	WithMetrics(nopMetrics{})(cs)
+	WithConflation(window)(cs)
+	return cs
+}
+
+func TestConflate_KeepsTheNewestQuoteOfTheWindow(t *testing.T) {
+	recorder := &countingRecorder{}
+	cs := newConflatingService(recorder, time.Second)
+	base := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+
+	cs.conflate(&domain.PriceData{Ticker: "EURUSD", Timestamp: base.Add(500 * time.Millisecond), Bid: 1.1002})
+	// Arrives later but was quoted earlier in the same window
+	cs.conflate(&domain.PriceData{Ticker: "EURUSD", Timestamp: base.Add(200 * time.Millisecond), Bid: 1.1001})
+	cs.flushConflated(cs.ctx, time.Time{})
+
+	ticks := recorder.recorded()
+	if len(ticks) != 1 {
+		t.Fatalf("Expected one conflated quote, got %d", len(ticks))
+	}
+	if ticks[0].Bid != 1.1002 || ticks[0].Flags&domain.FlagConflated == 0 {
+		t.Errorf("Expected the newest quote flagged as conflated, got bid %v flags %v", ticks[0].Bid, ticks[0].Flags)
+	}
+}
+
+func TestConflate_NothingWrittenAfterStopGaveUpWaiting(t *testing.T) {
+	recorder := &countingRecorder{}
+	cs := newConflatingService(recorder, time.Second)
+	cs.conflate(&domain.PriceData{Ticker: "EURUSD", Timestamp: time.Now(), Bid: 1.1})
+
+	// Stop timed out waiting for the processor and closed the sinks; the processor's final drain
+	// of held quotes runs afterwards and must not reach them
+	if !cs.closeSinkGate() {
+		t.Fatal("Expected the sink gate to close")
+	}
+	cs.flushConflatedSafely(time.Time{})
+	if n := len(recorder.recorded()); n != 0 {
+		t.Errorf("Expected no writes to closed sinks, got %d", n)
+	}
+}
+
+func TestConflate_DrainWaitsForStopToDecide(t *testing.T) {
+	recorder := &countingRecorder{}
+	cs := newConflatingService(recorder, time.Second)
+	cs.conflate(&domain.PriceData{Ticker: "EURUSD", Timestamp: time.Now(), Bid: 1.1})
+
+	// The processor drains while Stop tries to close the sinks: either the quote is written
+	// before they close, or not at all
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var closed bool
+	go func() {
+		defer wg.Done()
+		cs.flushConflatedSafely(time.Time{})
+	}()
+	go func() {
+		defer wg.Done()
+		closed = cs.closeSinkGate()
+	}()
+	wg.Wait()
+
+	if !closed {
+		t.Fatal("Expected the sink gate to close once the drain finished")
+	}
+	if n := len(recorder.recorded()); n > 1 {
+		t.Errorf("Expected at most the held quote, got %d writes", n)
+	}
+	cs.flushConflatedSafely(time.Time{})
+	if n := len(recorder.recorded()); n > 1 {
+		t.Errorf("Expected no writes after the sinks closed, got %d", n)
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// DiskAction is the emergency measure taken when free disk space runs low
+type DiskAction string
+
+const (
+	DiskActionNone   DiskAction = "none"   // Alert only
+	DiskActionSample DiskAction = "sample" // Record only every Nth tick
+	DiskActionPause  DiskAction = "pause"  // Stop recording until space recovers
+	DiskActionPurge  DiskAction = "purge"  // Delete oldest day directories
+)
+
+// maxPurgesPerCheck limits how many days are deleted in one check
+const maxPurgesPerCheck = 3
+
+// DiskMonitorConfig configures free-space monitoring of the spread directory
+type DiskMonitorConfig struct {
+	Path          string        // Directory whose filesystem is monitored
+	MinFreeBytes  uint64        // Threshold below which the emergency action kicks in
+	CheckInterval time.Duration // How often free space is checked
+	Action        DiskAction    // Emergency measure when below threshold
+	SampleRate    int           // Record every Nth tick when Action is sample
+
+	Usage  func(path string) (free, total uint64, err error) // Reports the filesystem's free and total bytes
+	Purger ports.DayPurger                                   // Deletes the oldest day when Action is purge (nil: purging has no effect)
+}
+
+// Validate checks the check interval, the usage probe and that the configured action is known
+func (c DiskMonitorConfig) Validate() error {
+	if c.Usage == nil {
+		return fmt.Errorf("disk monitor needs a usage probe")
+	}
+	if c.CheckInterval <= 0 {
+		return fmt.Errorf("disk check interval must be positive, got %v", c.CheckInterval)
+	}
+	switch c.Action {
+	case DiskActionNone, DiskActionSample, DiskActionPause, DiskActionPurge:
+	default:
+		return fmt.Errorf("unknown disk emergency action: %s", c.Action)
+	}
+	if c.Action == DiskActionSample && c.SampleRate < 2 {
+		return fmt.Errorf("disk sample rate must be at least 2, got %d", c.SampleRate)
+	}
+	return nil
+}
+
+// WithDiskMonitor enables free-space monitoring with the given emergency behaviour
+func WithDiskMonitor(cfg DiskMonitorConfig) Option {
+	return func(cs *CollectorService) {
+		cs.diskMonitor = &cfg
+	}
+}
+
+// monitorDiskSpace checks free space periodically and applies the emergency action
+func (cs *CollectorService) monitorDiskSpace() {
+	cfg := cs.diskMonitor
+	cs.logger.Printf("Starting disk space monitor (min free %d MB, action=%s)", cfg.MinFreeBytes/(1024*1024), cfg.Action)
+
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+
+	low := false
+	for {
+		if free, ok := cs.checkDiskSpace(); ok {
+			if free < cfg.MinFreeBytes && !low {
+				low = true
+				cs.enterDiskEmergency(free)
+			}
+			if low && cfg.Action == DiskActionPurge {
+				free = cs.purgeUntilFree(free)
+			}
+			if free >= cfg.MinFreeBytes && low {
+				low = false
+				cs.leaveDiskEmergency(free)
+			}
+		}
+
+		select {
+		case <-cs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDiskSpace returns the currently free bytes, logging failures
+func (cs *CollectorService) checkDiskSpace() (uint64, bool) {
+	free, _, err := cs.diskMonitor.Usage(cs.diskMonitor.Path)
+	if err != nil {
+		cs.logger.Printf("Disk space check failed: %v", err)
+		return 0, false
+	}
+	return free, true
+}
+
+// enterDiskEmergency alerts and applies the configured action
+func (cs *CollectorService) enterDiskEmergency(free uint64) {
+	cfg := cs.diskMonitor
+	msg := fmt.Sprintf("Low disk space on %s: %d MB free (threshold %d MB), action=%s",
+		cfg.Path, free/(1024*1024), cfg.MinFreeBytes/(1024*1024), cfg.Action)
+	cs.logger.Println(msg)
+
+	event := domain.NewEvent(domain.EventDiskSpaceLow, domain.SeverityCritical, msg)
+	event.Fields = map[string]string{
+		"path":      cfg.Path,
+		"free_mb":   fmt.Sprintf("%d", free/(1024*1024)),
+		"action":    string(cfg.Action),
+		"threshold": fmt.Sprintf("%d", cfg.MinFreeBytes/(1024*1024)),
+	}
+	cs.emit(event)
+
+	switch cfg.Action {
+	case DiskActionSample:
+		cs.sampleEvery.Store(int64(cfg.SampleRate))
+		cs.logger.Printf("Disk emergency: recording every %d ticks", cfg.SampleRate)
+	case DiskActionPause:
+		cs.recordingPaused.Store(true)
+		cs.logger.Println("Disk emergency: recording paused")
+	}
+}
+
+// leaveDiskEmergency restores normal recording once space has recovered
+func (cs *CollectorService) leaveDiskEmergency(free uint64) {
+	msg := fmt.Sprintf("Disk space recovered on %s: %d MB free", cs.diskMonitor.Path, free/(1024*1024))
+	cs.logger.Println(msg)
+	cs.emit(domain.NewEvent(domain.EventDiskSpaceRecovered, domain.SeverityInfo, msg))
+
+	switch cs.diskMonitor.Action {
+	case DiskActionSample:
+		cs.sampleEvery.Store(1)
+	case DiskActionPause:
+		cs.recordingPaused.Store(false)
+	}
+}
+
+// purgeUntilFree deletes oldest days until the threshold is met or nothing is left
+func (cs *CollectorService) purgeUntilFree(free uint64) uint64 {
+	purger := cs.diskMonitor.Purger
+	if purger == nil {
+		cs.logger.Println("Warning: spread recorder doesn't support purging - disk emergency action has no effect")
+		return free
+	}
+
+	for i := 0; i < maxPurgesPerCheck && free < cs.diskMonitor.MinFreeBytes; i++ {
+		removed, err := purger.PurgeOldestDay()
+		if err != nil {
+			cs.logger.Printf("Disk emergency purge failed: %v", err)
+			return free
+		}
+		if removed == "" {
+			cs.logger.Println("Disk emergency: nothing left to purge")
+			return free
+		}
+		cs.logger.Printf("Disk emergency: purged %s", removed)
+
+		var ok bool
+		if free, ok = cs.checkDiskSpace(); !ok {
+			return 0
+		}
+	}
+	return free
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiskMonitorConfig_Validate(t *testing.T) {
+	usage := func(string) (uint64, uint64, error) { return 1 << 30, 1 << 32, nil }
+	valid := DiskMonitorConfig{Path: "data", CheckInterval: time.Minute, Action: DiskActionNone, Usage: usage}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// A zero interval would panic in time.NewTicker once the monitor starts
+	for _, interval := range []time.Duration{0, -time.Second} {
+		invalid := valid
+		invalid.CheckInterval = interval
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected an error for interval %v", interval)
+		}
+	}
+
+	noProbe := valid
+	noProbe.Usage = nil
+	if err := noProbe.Validate(); err == nil {
+		t.Error("Expected an error without a usage probe")
+	}
+}
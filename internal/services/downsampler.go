@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// snapshotGrace is how long after an interval ends late ticks are still accepted
+const snapshotGrace = 500 * time.Millisecond
+
+// DefaultSnapshotMaxAge is how long an instrument's last quote is carried forward by default
+const DefaultSnapshotMaxAge = 5 * time.Minute
+
+// downsampler aggregates ticks into fixed-interval snapshots per instrument
+// Ticks are bucketed by their own timestamp, so snapshots line up with the raw tick files
+type downsampler struct {
+	mu           sync.Mutex
+	interval     time.Duration
+	buckets      map[time.Time]map[string]*domain.Snapshot // Open intervals by UTC start, then ticker
+	last         map[string]*domain.Snapshot               // Last emitted snapshot per ticker (for carry-forward)
+	quoted       map[string]time.Time                      // Start of the last interval with ticks per ticker
+	emittedUntil time.Time                                 // Start of the oldest interval not yet emitted
+	late         int
+}
+
+func newDownsampler(interval time.Duration) *downsampler {
+	return &downsampler{
+		interval: interval,
+		buckets:  make(map[time.Time]map[string]*domain.Snapshot),
+		last:     make(map[string]*domain.Snapshot),
+		quoted:   make(map[string]time.Time),
+	}
+}
+
+// observe folds a tick into its interval; ticks for already emitted intervals are dropped
+func (d *downsampler) observe(p *domain.PriceData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	start := p.Timestamp.UTC().Truncate(d.interval)
+	if !d.emittedUntil.IsZero() && start.Before(d.emittedUntil) {
+		d.late++
+		return
+	}
+
+	bucket, ok := d.buckets[start]
+	if !ok {
+		bucket = make(map[string]*domain.Snapshot)
+		d.buckets[start] = bucket
+	}
+	if s, ok := bucket[p.Ticker]; ok {
+		s.Add(p)
+	} else {
+		bucket[p.Ticker] = domain.NewSnapshot(start, p)
+	}
+}
+
+// recorded counts a tick the sinks accepted in its interval's snapshot
+// A tick recorded after its interval was emitted (a conflated quote held past snapshotGrace) isn't counted
+func (d *downsampler) recorded(p *domain.PriceData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s, ok := d.buckets[p.Timestamp.UTC().Truncate(d.interval)][p.Ticker]; ok {
+		s.Recorded++
+	}
+}
+
+// emit closes all intervals that ended at least snapshotGrace before now
+// Instruments without ticks in an interval get a carried-forward snapshot while the FX market is open,
+// for at most maxAge after their last tick (0 = no limit); after that they are left out until they tick
+// Returns the snapshots in time order and the number of late ticks dropped since the last call
+func (d *downsampler) emit(now time.Time, holidays *domain.HolidayCalendar, maxAge time.Duration) ([]*domain.Snapshot, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff := now.UTC().Add(-snapshotGrace).Truncate(d.interval)
+	if d.emittedUntil.IsZero() {
+		d.emittedUntil = cutoff
+		for start := range d.buckets {
+			if start.Before(d.emittedUntil) {
+				d.emittedUntil = start
+			}
+		}
+	}
+
+	var snapshots []*domain.Snapshot
+	for start := d.emittedUntil; start.Before(cutoff); start = start.Add(d.interval) {
+		bucket := d.buckets[start]
+		delete(d.buckets, start)
+
+		for ticker, s := range bucket {
+			d.last[ticker] = s
+			d.quoted[ticker] = start
+		}
+
+		tickers := make([]string, 0, len(d.last))
+		for ticker := range d.last {
+			tickers = append(tickers, ticker)
+		}
+		slices.Sort(tickers)
+
+		for _, ticker := range tickers {
+			if s, ok := bucket[ticker]; ok {
+				snapshots = append(snapshots, s)
+			} else if maxAge > 0 && start.Sub(d.quoted[ticker]) > maxAge {
+				// Too old to stand for the current price
+				delete(d.last, ticker)
+				delete(d.quoted, ticker)
+			} else if holidays.IsOpen(ticker, start) {
+				carried := d.last[ticker].CarryForward(start)
+				d.last[ticker] = carried
+				snapshots = append(snapshots, carried)
+			}
+		}
+	}
+	if cutoff.After(d.emittedUntil) {
+		d.emittedUntil = cutoff
+	}
+
+	late := d.late
+	d.late = 0
+	return snapshots, late
+}
+
+// WithSnapshots writes regular-grid snapshots (last bid/ask, min/max spread) every interval to writer
+func WithSnapshots(interval time.Duration, writer ports.SnapshotWriter) Option {
+	return func(cs *CollectorService) {
+		cs.snapshots = newDownsampler(interval)
+		cs.snapshotWriter = writer
+	}
+}
+
+// WithSnapshotMaxAge sets how long the snapshots carry an instrument's last quote forward without
+// ticks (default DefaultSnapshotMaxAge, 0 = while its market is open)
+func WithSnapshotMaxAge(maxAge time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.snapshotMaxAge = maxAge
+	}
+}
+
+// writeSnapshots emits completed intervals once per interval
+func (cs *CollectorService) writeSnapshots() {
+	interval := cs.snapshots.interval
+	cs.logger.Printf("Starting snapshot writer (every %v)", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			snapshots, late := cs.snapshots.emit(now, cs.holidays, cs.snapshotMaxAge)
+			if late > 0 {
+				cs.logger.Printf("Snapshots: %d late ticks arrived after their interval was written", late)
+			}
+			if len(snapshots) == 0 || !cs.recordingAllowed() {
+				continue
+			}
+
+			if err := cs.snapshotWriter.RecordSnapshots(cs.ctx, snapshots); err != nil {
+				cs.logger.Printf("Error recording snapshots: %v", err)
+				cs.countError("snapshots")
+				cs.emit(domain.NewEvent(domain.EventStorageError, domain.SeverityCritical,
+					fmt.Sprintf("Failed to record snapshots: %v", err)))
+			}
+		}
+	}
+}
+
+// closeSnapshotWriter releases the snapshot writer's resources
+func (cs *CollectorService) closeSnapshotWriter(ctx context.Context) error {
+	if closer, ok := cs.snapshotWriter.(ports.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close snapshot writer: %w", err)
+		}
+	}
+	return nil
+}
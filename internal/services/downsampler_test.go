@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestDownsampler_CountsRecordedTicks(t *testing.T) {
+	d := newDownsampler(time.Second)
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		tick := &domain.PriceData{Timestamp: start.Add(time.Duration(i) * 100 * time.Millisecond), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001}
+		d.observe(tick)
+		if i != 1 { // Kept out of the sinks, e.g. by sampling
+			d.recorded(tick)
+		}
+	}
+
+	snapshots, _ := d.emit(start.Add(2*time.Second+snapshotGrace), nil, 0)
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected a snapshot and a carried-forward one, got %d", len(snapshots))
+	}
+	if s := snapshots[0]; s.Ticks != 3 || s.Recorded != 2 {
+		t.Errorf("Expected 3 ticks of which 2 recorded, got %d and %d", s.Ticks, s.Recorded)
+	}
+	if s := snapshots[1]; s.Ticks != 0 || s.Recorded != 0 {
+		t.Errorf("Expected an empty carried-forward snapshot, got %d and %d", s.Ticks, s.Recorded)
+	}
+
+	// Recorded after its interval was emitted: not counted anywhere
+	d.recorded(&domain.PriceData{Timestamp: start.Add(500 * time.Millisecond), Ticker: "EURUSD"})
+	if snapshots[0].Recorded != 2 {
+		t.Errorf("Expected the emitted snapshot to stay at 2, got %d", snapshots[0].Recorded)
+	}
+}
+
+func TestDownsampler_CarriesForwardUpToMaxAge(t *testing.T) {
+	d := newDownsampler(time.Second)
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	d.observe(&domain.PriceData{Timestamp: start, Ticker: "EURUSD", Bid: 1.1, Ask: 1.1001})
+
+	snapshots, _ := d.emit(start.Add(5*time.Second+snapshotGrace), nil, 3*time.Second)
+	if len(snapshots) != 4 {
+		t.Fatalf("Expected the snapshot and 3 carried forward, got %d", len(snapshots))
+	}
+	if last := snapshots[3]; !last.Timestamp.Equal(start.Add(3*time.Second)) || last.Ticks != 0 {
+		t.Errorf("Expected the last carried snapshot at +3s, got %+v", last)
+	}
+
+	// Left out until the next tick
+	if snapshots, _ := d.emit(start.Add(10*time.Second+snapshotGrace), nil, 3*time.Second); len(snapshots) != 0 {
+		t.Errorf("Expected no snapshots for a quote older than the max age, got %d", len(snapshots))
+	}
+	d.observe(&domain.PriceData{Timestamp: start.Add(10 * time.Second), Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002})
+	if snapshots, _ := d.emit(start.Add(12*time.Second+snapshotGrace), nil, 3*time.Second); len(snapshots) != 2 || snapshots[0].Ticks != 1 {
+		t.Errorf("Expected the new quote and one carried forward, got %d", len(snapshots))
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// marketDepth is implemented by WebSocket clients that keep the order book levels of their subscriptions
+// Levels are best first; sizes are base currency amounts
+type marketDepth interface {
+	MarketDepth(ticker string) (bidPrices, bidSizes, askPrices, askSizes []float64, ok bool)
+}
+
+// WithEffectiveSpread records the spread of buying and selling notional (base currency) at once next to
+// the top-of-book spread, walking the order book levels. Needs a WebSocket client with market depth;
+// ticks without a deep enough book are recorded without it
+func WithEffectiveSpread(notional float64) Option {
+	return func(cs *CollectorService) {
+		cs.effectiveNotional = notional
+	}
+}
+
+// startMarketDepth looks up the WebSocket client's order books, disabling the effective spread without them
+func (cs *CollectorService) startMarketDepth() {
+	if cs.effectiveNotional == 0 {
+		return
+	}
+	depth, ok := cs.wsClient.(marketDepth)
+	if !ok {
+		cs.logger.Println("Warning: WebSocket client provides no market depth - effective spread disabled")
+		cs.effectiveNotional = 0
+		return
+	}
+	cs.depth = depth
+	cs.logger.Printf("Effective spread enabled (notional %.0f)", cs.effectiveNotional)
+}
+
+// addEffectiveSpread sets the effective spread of priceData from its instrument's current order book
+func (cs *CollectorService) addEffectiveSpread(priceData *domain.PriceData) {
+	if cs.depth == nil {
+		return
+	}
+	bidPrices, bidSizes, askPrices, askSizes, ok := cs.depth.MarketDepth(priceData.Ticker)
+	if !ok {
+		return
+	}
+	book := domain.OrderBook{Bids: bookLevels(bidPrices, bidSizes), Asks: bookLevels(askPrices, askSizes)}
+	if spread, ok := book.EffectiveSpread(cs.effectiveNotional); ok {
+		priceData.EffectiveSpread = spread
+	}
+}
+
+// bookLevels pairs prices with sizes, ignoring prices without a size
+func bookLevels(prices, sizes []float64) []domain.BookLevel {
+	levels := make([]domain.BookLevel, 0, min(len(prices), len(sizes)))
+	for i := range min(len(prices), len(sizes)) {
+		levels = append(levels, domain.BookLevel{Price: prices[i], Size: sizes[i]})
+	}
+	return levels
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// errorBudget counts errors per component between periodic reports
+type errorBudget struct {
+	interval time.Duration // How often the counts are logged (0 disables the report)
+
+	mu     sync.Mutex
+	counts map[string]int
+	since  time.Time
+
+	errors ports.Counter // fx_collector_errors_total{component}
+	panics ports.Counter // fx_collector_panics_total{component}
+}
+
+// WithErrorBudget logs the number of errors per component every interval (e.g. "record=3 flush=1")
+// 0 disables the report; errors and recovered panics are still counted in the metrics
+func WithErrorBudget(interval time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.errorBudget.interval = interval
+	}
+}
+
+// countError records one error of component for the error budget and metrics
+func (cs *CollectorService) countError(component string) {
+	b := &cs.errorBudget
+	b.errors.Inc(component)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.counts == nil {
+		b.counts = make(map[string]int)
+	}
+	b.counts[component]++
+}
+
+// recoverPanic is deferred around one unit of hot-path work (a price update, a flush) so that a
+// panic costs that unit only: the stack is logged, the panic counted, and the caller carries on
+func (cs *CollectorService) recoverPanic(component, detail string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	cs.logger.Printf("Recovered panic in %s (%s): %v\n%s", component, detail, r, debug.Stack())
+	cs.errorBudget.panics.Inc(component)
+	cs.countError(component)
+}
+
+// reportErrorBudget logs the error counts of the past interval until the service stops
+func (cs *CollectorService) reportErrorBudget() {
+	b := &cs.errorBudget
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	b.mu.Lock()
+	b.since = time.Now()
+	b.mu.Unlock()
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.mu.Lock()
+			counts, since := b.counts, b.since
+			b.counts, b.since = nil, now
+			b.mu.Unlock()
+
+			cs.logger.Printf("Error budget (last %v): %s", now.Sub(since).Round(time.Second), formatErrorCounts(counts))
+		}
+	}
+}
+
+// formatErrorCounts renders counts as "flush=1 record=3", sorted by component
+func formatErrorCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no errors"
+	}
+	parts := make([]string, 0, len(counts))
+	for _, component := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%s=%d", component, counts[component]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// componentLabel turns a supervisor component name like "flush loop (30s)" into a metric label
+func componentLabel(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(fields, "_")
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// notifyTimeout bounds how long a single notification may take
+const notifyTimeout = 10 * time.Second
+
+// emit records an event in the ops log and sends it to the notifier
+// Events of an instrument carry its latest quote and spread range in their fields
+func (cs *CollectorService) emit(event domain.Event) {
+	event = cs.withSpreadFields(event)
+	cs.recordOps(event)
+	cs.notify(event)
+}
+
+// WithAdapterEvents logs and notifies events raised by adapters (e.g. a sink failing over)
+// like the service's own
+func WithAdapterEvents(events <-chan domain.Event) Option {
+	return func(cs *CollectorService) {
+		cs.adapterEvents = events
+	}
+}
+
+// forwardAdapterEvents emits adapter events until the service stops
+func (cs *CollectorService) forwardAdapterEvents() {
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+		case event := <-cs.adapterEvents:
+			cs.emit(event)
+		}
+	}
+}
+
+// notify sends an event in the background so slow webhooks never block price processing
+func (cs *CollectorService) notify(event domain.Event) {
+	if cs.notifier == nil {
+		return
+	}
+	go cs.notifyAndWait(event)
+}
+
+// notifyAndWait sends an event and waits for delivery (used right before exiting)
+func (cs *CollectorService) notifyAndWait(event domain.Event) {
+	if cs.notifier == nil {
+		return
+	}
+
+	// Detached from cs.ctx so events raised during shutdown are still delivered
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	if err := cs.notifier.Notify(ctx, event); err != nil {
+		cs.logger.Printf("Notification error (%s): %v", event.Type, err)
+		cs.countError("notify")
+	}
+}
+
+// monitorConnectionState turns WebSocket state changes into disconnect/reconnect events
+// Every state is forwarded to out (token refresher) when it is non-nil
+func (cs *CollectorService) monitorConnectionState(in <-chan bool, out chan<- bool) error {
+	connected := false
+	seen := false
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return nil
+
+		case state, ok := <-in:
+			if !ok {
+				return unrecoverable(errors.New("connection state channel closed"))
+			}
+
+			if seen && state != connected {
+				if state {
+					// The client re-subscribes on reconnect; each instrument's first quote is a snapshot
+					cs.quality.expectSnapshots(cs.getAllTickers())
+					cs.logger.Println("WebSocket reconnected")
+					cs.emit(domain.NewEvent(domain.EventReconnected, domain.SeverityInfo, "WebSocket reconnected"))
+				} else {
+					cs.logger.Println("WebSocket disconnected")
+					cs.emit(domain.NewEvent(domain.EventDisconnected, domain.SeverityWarning, "WebSocket disconnected"))
+				}
+			}
+			connected = state
+			seen = true
+			cs.wsConnected.Store(state)
+
+			if out != nil {
+				select {
+				case out <- state:
+				case <-cs.ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// monitorDataGaps raises an event when no price update arrives for the data gap threshold,
+// and raises it again as critical if the gap lasts for the critical threshold
+// Time while every instrument's market is closed (weekend, holidays) does not count towards a gap
+func (cs *CollectorService) monitorDataGaps() {
+	threshold := cs.currentTunables().DataGapThreshold
+	if threshold <= 0 {
+		return
+	}
+	changed := cs.tunablesUpdates()
+
+	ticker := time.NewTicker(min(threshold/2, 30*time.Second))
+	defer ticker.Stop()
+
+	inGap, escalated := false, false
+	var reopenedAt, gapStart time.Time
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+
+		case <-changed:
+			changed = cs.tunablesUpdates()
+			if current := cs.currentTunables().DataGapThreshold; current != threshold {
+				threshold = current
+				ticker.Reset(min(threshold/2, 30*time.Second))
+			}
+
+		case now := <-ticker.C:
+			if !cs.anyMarketOpen(now) {
+				if inGap {
+					cs.emit(domain.NewEvent(domain.EventDataResumed, domain.SeverityInfo, "Data gap ended by market close"))
+					cs.addGap(domain.RecordingGap{Start: gapStart, End: now.UTC(), Reason: domain.GapNoData})
+				}
+				reopenedAt = now
+				inGap, escalated = false, false
+				continue
+			}
+
+			last := time.Unix(0, cs.lastTickAt.Load())
+			if last.Before(reopenedAt) {
+				last = reopenedAt
+			}
+			gap := now.Sub(last)
+
+			if gap >= threshold && !inGap {
+				inGap, gapStart = true, last.UTC()
+				cs.logger.Printf("Data gap: no price updates for %v", gap.Round(time.Second))
+				cs.emit(domain.NewEvent(domain.EventDataGap, domain.SeverityWarning,
+					fmt.Sprintf("No price updates for %v", gap.Round(time.Second))))
+			} else if gap < threshold && inGap {
+				inGap, escalated = false, false
+				cs.logger.Println("Price updates resumed after data gap")
+				cs.emit(domain.NewEvent(domain.EventDataResumed, domain.SeverityInfo, "Price updates resumed after data gap"))
+				cs.addGap(domain.RecordingGap{Start: gapStart, End: last.UTC(), Reason: domain.GapNoData})
+			}
+			if inGap && !escalated && cs.dataGapCriticalAfter > 0 && gap >= cs.dataGapCriticalAfter {
+				escalated = true
+				cs.logger.Printf("Data gap critical: no price updates for %v", gap.Round(time.Second))
+				cs.emit(domain.NewEvent(domain.EventDataGap, domain.SeverityCritical,
+					fmt.Sprintf("No price updates for %v", gap.Round(time.Second))))
+			}
+		}
+	}
+}
+
+// recordHeartbeats writes a periodic connection-quality row to the ops log
+// Each heartbeat carries the connection state and how many updates arrived since the last one
+func (cs *CollectorService) recordHeartbeats() {
+	ticker := time.NewTicker(cs.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			ticks := cs.heartbeatTicks.Swap(0)
+			last := time.Unix(0, cs.lastTickAt.Load())
+
+			event := domain.NewEvent(domain.EventHeartbeat, domain.SeverityInfo, "Heartbeat")
+			event.Fields = map[string]string{
+				"connected":     fmt.Sprintf("%t", cs.wsConnected.Load()),
+				"ticks":         fmt.Sprintf("%d", ticks),
+				"interval_s":    fmt.Sprintf("%.0f", cs.heartbeatInterval.Seconds()),
+				"last_tick_ago": now.Sub(last).Round(time.Millisecond).String(),
+				"market_open":   fmt.Sprintf("%t", cs.anyMarketOpen(now)),
+			}
+			cs.recordOps(event)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// DecimalsCheck controls how configured decimals are compared with broker metadata at startup
+type DecimalsCheck string
+
+const (
+	DecimalsCheckOff    DecimalsCheck = "off"    // Trust instruments.json
+	DecimalsCheckWarn   DecimalsCheck = "warn"   // Log mismatches, keep the configured value
+	DecimalsCheckStrict DecimalsCheck = "strict" // Refuse to start on any mismatch
+)
+
+// Validate checks that the check mode is known
+func (c DecimalsCheck) Validate() error {
+	switch c {
+	case DecimalsCheckOff, DecimalsCheckWarn, DecimalsCheckStrict:
+		return nil
+	default:
+		return fmt.Errorf("unknown decimals check: %s", c)
+	}
+}
+
+// WithDecimalsCheck compares each instrument's decimals with broker metadata after login
+// Instruments without configured decimals take the broker's value
+func WithDecimalsCheck(check DecimalsCheck) Option {
+	return func(cs *CollectorService) {
+		cs.decimalsCheck = check
+	}
+}
+
+// WithInstrumentMetadata sets the broker reference data source for the decimals check
+// Without one the check is skipped, and instruments must configure their decimals
+func WithInstrumentMetadata(source ports.InstrumentMetadata) Option {
+	return func(cs *CollectorService) {
+		cs.instrumentMetadata = source
+	}
+}
+
+// checkInstrumentDecimals validates configured decimals against the broker
+// A wrong decimals value silently rounds away precision in every recorded price, so an instrument
+// whose decimals are neither configured nor resolved from the broker refuses the start
+func (cs *CollectorService) checkInstrumentDecimals(ctx context.Context) error {
+	tickers := cs.getAllTickers()
+	slices.Sort(tickers)
+
+	source := cs.instrumentMetadata
+	switch {
+	case cs.decimalsCheck == "" || cs.decimalsCheck == DecimalsCheckOff:
+		source = nil
+	case source == nil:
+		cs.logger.Println("Decimals check: no instrument metadata source - skipped")
+	}
+
+	var mismatches, unresolved []string
+	checked := 0
+	for _, ticker := range tickers {
+		instrument := cs.instruments[ticker]
+		if source == nil {
+			if instrument.Decimals == 0 {
+				unresolved = append(unresolved, ticker)
+			}
+			continue
+		}
+		decimals, err := source.Decimals(ctx, instrument.Uic, instrument.AssetType)
+		if err != nil {
+			if cs.decimalsCheck == DecimalsCheckStrict {
+				return fmt.Errorf("decimals check for %s: %w", ticker, err)
+			}
+			cs.logger.Printf("Decimals check: %s: %v", ticker, err)
+			if instrument.Decimals == 0 {
+				unresolved = append(unresolved, ticker)
+			}
+			continue
+		}
+		checked++
+
+		switch {
+		case instrument.Decimals == 0:
+			instrument.Decimals = decimals
+			cs.instruments[ticker] = instrument
+			cs.logger.Printf("Decimals check: %s has no decimals configured, using broker value %d", ticker, decimals)
+		case instrument.Decimals != decimals:
+			cs.logger.Printf("Decimals check: ⚠️ %s configured with %d decimals, broker quotes %d", ticker, instrument.Decimals, decimals)
+			mismatches = append(mismatches, fmt.Sprintf("%s (%d != %d)", ticker, instrument.Decimals, decimals))
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return fmt.Errorf("no decimals for %v: set decimals in the instruments file or enable DECIMALS_CHECK", unresolved)
+	}
+	if len(mismatches) > 0 && cs.decimalsCheck == DecimalsCheckStrict {
+		return fmt.Errorf("decimals mismatch with broker metadata: %v", mismatches)
+	}
+	if source != nil && len(mismatches) == 0 && checked > 0 {
+		cs.logger.Printf("Decimals check: ✅ %d of %d instruments match broker metadata", checked, len(tickers))
+	}
+	return nil
+}
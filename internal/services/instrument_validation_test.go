@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+)
+
+// fakeMetadata answers decimals by UIC; UICs it doesn't know fail
+type fakeMetadata map[int]int
+
+func (m fakeMetadata) Decimals(ctx context.Context, uic int, assetType string) (int, error) {
+	decimals, ok := m[uic]
+	if !ok {
+		return 0, errors.New("not found")
+	}
+	return decimals, nil
+}
+
+func TestCheckInstrumentDecimals(t *testing.T) {
+	newService := func(check DecimalsCheck, source fakeMetadata) *CollectorService {
+		cs := &CollectorService{
+			logger:        log.New(io.Discard, "", 0),
+			decimalsCheck: check,
+			instruments: map[string]Instrument{
+				"EURUSD": {Ticker: "EURUSD", Uic: 21, Decimals: 5},
+				"USDJPY": {Ticker: "USDJPY", Uic: 42},
+			},
+		}
+		if source != nil {
+			cs.instrumentMetadata = source
+		}
+		return cs
+	}
+	ctx := context.Background()
+
+	cs := newService(DecimalsCheckWarn, fakeMetadata{21: 5, 42: 3})
+	if err := cs.checkInstrumentDecimals(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cs.instruments["USDJPY"].Decimals != 3 {
+		t.Errorf("Expected USDJPY to take the broker's 3 decimals, got %d", cs.instruments["USDJPY"].Decimals)
+	}
+
+	if err := newService(DecimalsCheckStrict, fakeMetadata{21: 4, 42: 3}).checkInstrumentDecimals(ctx); err == nil {
+		t.Error("Expected strict mode to refuse a mismatch")
+	}
+
+	// Decimals that are neither configured nor resolved would round every price to an integer
+	if err := newService(DecimalsCheckWarn, fakeMetadata{21: 5}).checkInstrumentDecimals(ctx); err == nil {
+		t.Error("Expected an error when the broker lookup of an instrument without decimals fails")
+	}
+	if err := newService(DecimalsCheckOff, nil).checkInstrumentDecimals(ctx); err == nil {
+		t.Error("Expected an error for an instrument without decimals when the check is off")
+	}
+	if err := newService(DecimalsCheckWarn, nil).checkInstrumentDecimals(ctx); err == nil {
+		t.Error("Expected an error for an instrument without decimals without a metadata source")
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// WithLeaderElection runs the collector in primary/standby mode
+// All instances subscribe and process prices, but only the lease holder records them
+func WithLeaderElection(lock ports.LeaseLock, ttl time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.leaseLock = lock
+		cs.leaseTTL = ttl
+	}
+}
+
+// minLeaseTTL is the shortest lease accepted; renewals and the step-down margin are fractions of it
+const minLeaseTTL = time.Second
+
+// campaignForLeadership keeps trying to acquire/renew the lease every ttl/3 until the service stops
+func (cs *CollectorService) campaignForLeadership() {
+	ticker := time.NewTicker(cs.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		cs.renewLeadership()
+		select {
+		case <-cs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewLeadership makes one attempt to acquire or renew the lease
+// A leader records only until a quarter TTL before its lease would expire (see recordingAllowed),
+// counted from the start of the last successful attempt or from the expiry the lock reports if that
+// is earlier, as when a renewal couldn't update the lease. So it has stopped before a standby can
+// take over even when the lock backend hangs or becomes unreachable
+func (cs *CollectorService) renewLeadership() {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(cs.ctx, cs.leaseTTL/3)
+	expiresAt, err := cs.leaseLock.TryAcquire(ctx)
+	cancel()
+
+	switch {
+	case err != nil:
+		cs.logger.Printf("Leader election error: %v", err)
+		cs.countError("leader_election")
+		if !cs.leaseValid(time.Now()) {
+			cs.setLeader(false)
+		}
+	case !expiresAt.IsZero():
+		until := start.Add(cs.leaseTTL)
+		if expiresAt.Before(until) {
+			until = expiresAt
+		}
+		cs.leaseValidUntil.Store(until.Add(-cs.leaseTTL / 4).UnixNano())
+		cs.setLeader(cs.leaseValid(time.Now()))
+	default:
+		cs.setLeader(false)
+	}
+}
+
+// leaseValid reports whether the lease last acquired is still safe to record under at now
+func (cs *CollectorService) leaseValid(now time.Time) bool {
+	return now.UnixNano() < cs.leaseValidUntil.Load()
+}
+
+// setLeader records a leadership transition
+func (cs *CollectorService) setLeader(leader bool) {
+	if cs.isLeader.Swap(leader) == leader {
+		return
+	}
+
+	if leader {
+		cs.logger.Println("Leader election: this instance is now PRIMARY - recording enabled")
+		cs.emit(domain.NewEvent(domain.EventLeaderElected, domain.SeverityInfo, "Became primary, recording enabled"))
+	} else {
+		cs.logger.Println("Leader election: this instance is now STANDBY - recording disabled")
+		cs.emit(domain.NewEvent(domain.EventLeaderLost, domain.SeverityWarning, "Became standby, recording disabled"))
+	}
+}
+
+// releaseLeadership hands the lease over on shutdown so the standby takes over without waiting for expiry
+func (cs *CollectorService) releaseLeadership(ctx context.Context) error {
+	if cs.leaseLock == nil || !cs.isLeader.Load() {
+		return nil
+	}
+
+	if err := cs.leaseLock.Release(ctx); err != nil {
+		return fmt.Errorf("failed to release leader lease: %w", err)
+	}
+	cs.isLeader.Store(false)
+	cs.leaseValidUntil.Store(0)
+	cs.logger.Println("Leader lease released")
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// fakeLease grants the lease while err is nil, until expiresAt if set or for a minute
+type fakeLease struct {
+	err       error
+	expiresAt time.Time
+}
+
+func (l *fakeLease) TryAcquire(ctx context.Context) (time.Time, error) {
+	if l.err != nil {
+		return time.Time{}, l.err
+	}
+	if l.expiresAt.IsZero() {
+		return time.Now().Add(time.Minute), nil
+	}
+	return l.expiresAt, nil
+}
+
+func (l *fakeLease) Release(ctx context.Context) error { return nil }
+
+func TestLeaderElection_StopsRecordingBeforeTheLeaseExpires(t *testing.T) {
+	lease := &fakeLease{}
+	cs := &CollectorService{ctx: context.Background(), logger: log.New(io.Discard, "", 0), leaseLock: lease, leaseTTL: 12 * time.Second}
+	WithMetrics(nopMetrics{})(cs)
+
+	cs.renewLeadership()
+	if !cs.isLeader.Load() || !cs.recordingAllowed() {
+		t.Fatal("Expected to record after acquiring the lease")
+	}
+	validUntil := time.Unix(0, cs.leaseValidUntil.Load())
+	if left := time.Until(validUntil); left > 9*time.Second || left < 8*time.Second {
+		t.Errorf("Expected to stop recording 9s after acquiring a 12s lease, %v left", left)
+	}
+
+	// The lock backend fails: recording stops at the margin even before the next renewal attempt
+	lease.err = errors.New("NAS unreachable")
+	cs.leaseValidUntil.Store(time.Now().Add(-time.Millisecond).UnixNano())
+	if cs.recordingAllowed() {
+		t.Error("Expected recording to stop once the lease is no longer safe")
+	}
+	cs.renewLeadership()
+	if cs.isLeader.Load() {
+		t.Error("Expected to step down after a failed renewal past the margin")
+	}
+}
+
+func TestLeaderElection_RenewalThatDidNotExtendTheLease(t *testing.T) {
+	// The lock couldn't update the lease (busy guard) and reports the expiry stored before
+	lease := &fakeLease{expiresAt: time.Now().Add(6 * time.Second)}
+	cs := &CollectorService{ctx: context.Background(), logger: log.New(io.Discard, "", 0), leaseLock: lease, leaseTTL: 12 * time.Second}
+	WithMetrics(nopMetrics{})(cs)
+
+	cs.renewLeadership()
+	if !cs.isLeader.Load() {
+		t.Fatal("Expected to stay primary under the stored lease")
+	}
+	if left := time.Until(time.Unix(0, cs.leaseValidUntil.Load())); left > 3*time.Second || left < 2*time.Second {
+		t.Errorf("Expected to stop recording 3s before the stored expiry, %v left", left)
+	}
+
+	// Within the margin already: stepped down at once
+	lease.expiresAt = time.Now().Add(2 * time.Second)
+	cs.renewLeadership()
+	if cs.isLeader.Load() || cs.recordingAllowed() {
+		t.Error("Expected to step down when the stored lease is within the margin")
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// opsLogQueueSize bounds the events waiting for the ops log writer; more are dropped
+const opsLogQueueSize = 1024
+
+// opsLogQueue hands events to the ops log writer goroutine, so the file I/O of an event raised
+// on the price processor (data quality, storage errors) doesn't hold up ticks
+type opsLogQueue struct {
+	events  chan domain.Event
+	done    chan struct{}
+	started bool
+
+	mu      sync.RWMutex // Read-held while queueing; closing the queue takes it
+	closed  bool
+	dropped atomic.Int64
+}
+
+// startOpsLog starts the ops log writer; events queued before are written first
+func (cs *CollectorService) startOpsLog() {
+	if q := cs.opsQueue; q != nil && !q.started {
+		q.started = true
+		go cs.writeOpsEvents(q)
+	}
+}
+
+// recordOps appends an event to the ops log only (no notification)
+// The event is queued for the writer; once the queue is closed on shutdown it is written directly
+func (cs *CollectorService) recordOps(event domain.Event) {
+	q := cs.opsQueue
+	if q == nil {
+		return
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		cs.writeOps(event)
+		return
+	}
+	select {
+	case q.events <- event:
+	default:
+		if dropped := q.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			cs.logger.Printf("Ops log can't keep up: %d events dropped", dropped)
+		}
+		cs.countError("ops_log")
+	}
+}
+
+// recordOpsAndWait writes an event to the ops log before returning (used right before exiting)
+func (cs *CollectorService) recordOpsAndWait(event domain.Event) {
+	if cs.opsLog != nil {
+		cs.writeOps(event)
+	}
+}
+
+// writeOps appends one event to the ops log
+func (cs *CollectorService) writeOps(event domain.Event) {
+	if err := cs.opsLog.RecordEvent(context.WithoutCancel(cs.ctx), event); err != nil {
+		cs.logger.Printf("Ops log error (%s): %v", event.Type, err)
+		cs.countError("ops_log")
+	}
+}
+
+// writeOpsEvents writes the queued events until the queue is closed
+func (cs *CollectorService) writeOpsEvents(q *opsLogQueue) {
+	defer close(q.done)
+	for event := range q.events {
+		cs.writeOps(event)
+	}
+}
+
+// closeOpsLog writes the queued events on shutdown; later events are written directly
+// Events still queued when ctx expires are lost
+func (cs *CollectorService) closeOpsLog(ctx context.Context) error {
+	q := cs.opsQueue
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+	if !q.started {
+		cs.startOpsLog()
+	}
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up writing the ops log queue: %w", ctx.Err())
+	}
+}
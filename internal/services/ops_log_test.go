@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// blockingOpsLog holds every write until release is closed
+type blockingOpsLog struct {
+	release chan struct{}
+	mu      sync.Mutex
+	events  []domain.Event
+}
+
+func (l *blockingOpsLog) RecordEvent(ctx context.Context, event domain.Event) error {
+	<-l.release
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+	return nil
+}
+
+func (l *blockingOpsLog) recorded() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.events)
+}
+
+func TestRecordOps_DoesNotWaitForTheOpsLog(t *testing.T) {
+	opsLog := &blockingOpsLog{release: make(chan struct{})}
+	cs := &CollectorService{ctx: context.Background(), logger: log.New(io.Discard, "", 0)}
+	WithMetrics(nopMetrics{})(cs)
+	WithOpsLog(opsLog, 0)(cs)
+	cs.startOpsLog()
+
+	// Returns while the ops log is stuck
+	for range 3 {
+		cs.recordOps(domain.NewEvent(domain.EventDataGap, domain.SeverityWarning, "Data gap"))
+	}
+	if n := opsLog.recorded(); n != 0 {
+		t.Fatalf("Expected no events written yet, got %d", n)
+	}
+
+	close(opsLog.release)
+	if err := cs.closeOpsLog(context.Background()); err != nil {
+		t.Fatalf("Failed to close ops log: %v", err)
+	}
+	if n := opsLog.recorded(); n != 3 {
+		t.Errorf("Expected 3 events written on close, got %d", n)
+	}
+
+	// Written directly once closed
+	cs.recordOps(domain.NewEvent(domain.EventHeartbeat, domain.SeverityInfo, "Heartbeat"))
+	if n := opsLog.recorded(); n != 4 {
+		t.Errorf("Expected the event after close to be written directly, got %d", n)
+	}
+}
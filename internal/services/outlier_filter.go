@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// OutlierAction is what happens to a tick with an implausible spread
+type OutlierAction string
+
+const (
+	OutlierActionOff    OutlierAction = "off"    // Record everything unchanged
+	OutlierActionFlag   OutlierAction = "flag"   // Record with FlagOutlier set
+	OutlierActionReject OutlierAction = "reject" // Write to the rejects queue instead of recording
+)
+
+// OutlierFilterConfig configures spread sanity checks before recording
+// Upper bounds come from each instrument's MaxSpread (0 = only reject zero/negative spreads)
+type OutlierFilterConfig struct {
+	Action  OutlierAction
+	Rejects ports.DeadLetterQueue // Receives rejected ticks (optional)
+}
+
+// Validate checks that the configured action is known
+func (c OutlierFilterConfig) Validate() error {
+	switch c.Action {
+	case OutlierActionOff, OutlierActionFlag, OutlierActionReject:
+		return nil
+	default:
+		return fmt.Errorf("unknown outlier action: %s", c.Action)
+	}
+}
+
+// WithOutlierFilter rejects or flags ticks with zero/negative spread or a spread above
+// the instrument's MaxSpread
+func WithOutlierFilter(cfg OutlierFilterConfig) Option {
+	return func(cs *CollectorService) {
+		if cfg.Action != OutlierActionOff {
+			cs.outlierFilter = &cfg
+		}
+	}
+}
+
+// filterOutlier applies the outlier filter and reports whether the tick should be kept
+func (cs *CollectorService) filterOutlier(priceData *domain.PriceData) bool {
+	cfg := cs.outlierFilter
+	if cfg == nil {
+		return true
+	}
+
+	reason := priceData.SpreadOutlier(cs.instruments[priceData.Ticker].MaxSpread)
+	if reason == "" {
+		return true
+	}
+	cs.triggerBurst(priceData.Ticker, priceData.Timestamp)
+
+	if cfg.Action == OutlierActionFlag {
+		priceData.Flags |= domain.FlagOutlier
+		return true
+	}
+
+	cs.logger.Printf("Outlier filter: rejected %s tick (%s, spread %v)", priceData.Ticker, reason, priceData.Spread)
+	if cfg.Rejects != nil {
+		entry := domain.DeadLetter{
+			Timestamp: time.Now().UTC(),
+			Reason:    reason,
+			Error:     fmt.Sprintf("spread %v (bid %v, ask %v)", priceData.Spread, priceData.Bid, priceData.Ask),
+			Payload:   priceData,
+		}
+		if err := cfg.Rejects.Put(context.WithoutCancel(cs.ctx), entry); err != nil {
+			cs.logger.Printf("Outlier filter: failed to write reject for %s: %v", priceData.Ticker, err)
+		}
+	}
+	return false
+}
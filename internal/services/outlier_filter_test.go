@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// newGatedService returns a service with the given instruments for testing the recording gates
+func newGatedService(instruments map[string]Instrument) *CollectorService {
+	cs := &CollectorService{
+		ctx:         context.Background(),
+		logger:      log.New(io.Discard, "", 0),
+		instruments: instruments,
+		spreads:     newSpreadStats(),
+	}
+	WithMetrics(nopMetrics{})(cs)
+	return cs
+}
+
+// memoryQueue keeps the dead letters put into it
+type memoryQueue struct {
+	entries []domain.DeadLetter
+}
+
+func (q *memoryQueue) Put(ctx context.Context, entry domain.DeadLetter) error {
+	q.entries = append(q.entries, entry)
+	return nil
+}
+
+func TestFilterOutlier(t *testing.T) {
+	instruments := map[string]Instrument{"EURUSD": {Ticker: "EURUSD", MaxSpread: 0.001}}
+	normal := func() *domain.PriceData {
+		return &domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1002, Spread: 0.0002}
+	}
+	wide := func() *domain.PriceData {
+		return &domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.11, Spread: 0.01}
+	}
+
+	cs := newGatedService(instruments)
+	WithOutlierFilter(OutlierFilterConfig{Action: OutlierActionFlag})(cs)
+	if tick := normal(); !cs.filterOutlier(tick) || tick.Flags.Has(domain.FlagOutlier) {
+		t.Error("Expected a normal tick to pass unflagged")
+	}
+	if tick := wide(); !cs.filterOutlier(tick) || !tick.Flags.Has(domain.FlagOutlier) {
+		t.Error("Expected a wide tick to be kept and flagged")
+	}
+
+	rejects := &memoryQueue{}
+	cs = newGatedService(instruments)
+	WithOutlierFilter(OutlierFilterConfig{Action: OutlierActionReject, Rejects: rejects})(cs)
+	if cs.filterOutlier(wide()) {
+		t.Error("Expected a wide tick to be rejected")
+	}
+	if cs.filterOutlier(&domain.PriceData{Ticker: "EURUSD", Bid: 1.1, Ask: 1.1}) {
+		t.Error("Expected a zero spread to be rejected")
+	}
+	if len(rejects.entries) != 2 || rejects.entries[0].Reason != domain.OutlierSpreadAboveMax ||
+		rejects.entries[1].Reason != domain.OutlierNonPositiveSpread {
+		t.Errorf("Expected both rejects with their reasons, got %+v", rejects.entries)
+	}
+
+	cs = newGatedService(instruments)
+	WithOutlierFilter(OutlierFilterConfig{Action: OutlierActionOff})(cs)
+	if !cs.filterOutlier(wide()) {
+		t.Error("Expected the filter to be off")
+	}
+}
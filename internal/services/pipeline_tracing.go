@@ -0,0 +1,103 @@
+package services
+
+import (
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// Pipeline stages, used as span names and as the stage label of fx_collector_pipeline_stage_seconds:
+//
+//	receive  broker quote time until the processor reads the update (network, WebSocket, channel queue)
+//	map      price update to PriceData
+//	filter   quote checks, outlier filter, processors, snapshots and recording gates
+//	record   writing to the sinks (buffered sinks only append in memory)
+//	flush    one periodic flush of the buffering sinks (its own trace)
+
+// WithTracing traces every sampleRate-th price update through the pipeline, plus every periodic flush
+func WithTracing(tracer ports.Tracer, sampleRate int) Option {
+	return func(cs *CollectorService) {
+		cs.tracer = tracer
+		cs.tunables.TraceSampleRate = max(sampleRate, 1)
+	}
+}
+
+// tickTrace times the stages of one price update; root is nil unless the update is traced
+type tickTrace struct {
+	cs   *CollectorService
+	root ports.Span
+}
+
+// traceTick times the receive stage and starts a trace if the update is sampled
+// Only called from the price processor goroutine
+func (cs *CollectorService) traceTick(update saxo.PriceUpdate, receivedAt time.Time) tickTrace {
+	trace := tickTrace{cs: cs}
+
+	// Broker and local clocks may disagree; a quote "from the future" has no receive stage
+	quotedAt := receivedAt
+	if !update.Timestamp.IsZero() && update.Timestamp.Before(receivedAt) {
+		quotedAt = update.Timestamp
+		cs.stageDurations.Observe(receivedAt.Sub(quotedAt).Seconds(), "receive")
+	}
+
+	if cs.tracer == nil {
+		return trace
+	}
+	cs.traceCounter++
+	if cs.traceCounter%cs.currentTunables().TraceSampleRate != 0 {
+		return trace
+	}
+
+	trace.root = cs.tracer.StartSpan("tick", nil, quotedAt)
+	trace.root.SetAttribute("fx.ticker", update.Ticker)
+	cs.tracer.StartSpan("receive", trace.root, quotedAt).EndAt(receivedAt)
+	return trace
+}
+
+// stage records a stage that began at start and ended now, and returns its end
+func (t tickTrace) stage(name string, start time.Time) time.Time {
+	end := time.Now()
+	t.cs.stageDurations.Observe(end.Sub(start).Seconds(), name)
+	if t.root != nil {
+		t.cs.tracer.StartSpan(name, t.root, start).EndAt(end)
+	}
+	return end
+}
+
+// drop notes why the update wasn't recorded
+func (t tickTrace) drop(reason string) {
+	if t.root != nil {
+		t.root.SetAttribute("fx.dropped", reason)
+	}
+}
+
+// fail marks the trace as failed
+func (t tickTrace) fail(err error) {
+	if t.root != nil {
+		t.root.SetError(err)
+	}
+}
+
+// end finishes the trace
+func (t tickTrace) end() {
+	if t.root != nil {
+		t.root.End()
+	}
+}
+
+// startSpan starts a span, or returns one that ignores all calls without a tracer
+func (cs *CollectorService) startSpan(name string, parent ports.Span, start time.Time) ports.Span {
+	if cs.tracer == nil {
+		return nopSpan{}
+	}
+	return cs.tracer.StartSpan(name, parent, start)
+}
+
+// nopSpan is the span of an untraced stage
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value any) {}
+func (nopSpan) SetError(err error)                 {}
+func (nopSpan) End()                               {}
+func (nopSpan) EndAt(end time.Time)                {}
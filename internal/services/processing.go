@@ -0,0 +1,34 @@
+package services
+
+import (
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// WithProcessor runs every plausible tick through p (filter, enrich, transform stages) before
+// snapshots, conflation and recording see it. Ticks p drops or fails on are not recorded
+func WithProcessor(p ports.TickProcessor) Option {
+	return func(cs *CollectorService) {
+		cs.processor = p
+	}
+}
+
+// process applies the processor to a tick; nil if it was dropped
+// Only called from the price processor goroutine
+func (cs *CollectorService) process(priceData *domain.PriceData, trace tickTrace) *domain.PriceData {
+	if cs.processor == nil {
+		return priceData
+	}
+
+	processed, err := cs.processor.Process(cs.ctx, priceData)
+	if err != nil {
+		cs.logger.Printf("Error processing price for %s: %v", priceData.Ticker, err)
+		cs.countError("processor")
+		trace.fail(err)
+		return nil
+	}
+	if processed == nil {
+		trace.drop("processor")
+	}
+	return processed
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// WithMetrics registers the collector's counters, gauges and stage histogram in registry
+func WithMetrics(registry ports.MetricsRegistry) Option {
+	return func(cs *CollectorService) {
+		cs.quoteAnomalies = registry.Counter("fx_collector_quote_anomalies_total",
+			"Price updates with a locked (bid == ask) or crossed (bid > ask) market", "ticker", "kind")
+		cs.throttledTicks = registry.Counter("fx_collector_throttled_ticks_total",
+			"Ticks not recorded because the instrument's maxTickRate was exceeded", "ticker")
+		cs.stageDurations = registry.DurationHistogram("fx_collector_pipeline_stage_seconds",
+			"Time price updates spend in each pipeline stage", "stage")
+		cs.errorBudget.errors = registry.Counter("fx_collector_errors_total",
+			"Errors by component (record, flush, map, processor, notify, ...)", "component")
+		cs.errorBudget.panics = registry.Counter("fx_collector_panics_total",
+			"Panics recovered by component; the affected tick, flush or goroutine run was lost", "component")
+		cs.clockOffsets = registry.Gauge("fx_collector_clock_offset_seconds",
+			"Offset of a reference clock from the local clock at the last check (positive: local clock behind)", "source")
+	}
+}
+
+// nopMetrics stands in for the registry until WithMetrics is given one; its metrics discard everything
+type nopMetrics struct{}
+
+func (nopMetrics) Counter(name, help string, labelNames ...string) ports.Counter { return nopMetrics{} }
+func (nopMetrics) Gauge(name, help string, labelNames ...string) ports.Gauge     { return nopMetrics{} }
+func (nopMetrics) DurationHistogram(name, help string, labelNames ...string) ports.Histogram {
+	return nopMetrics{}
+}
+func (nopMetrics) Inc(labelValues ...string)                    {}
+func (nopMetrics) Set(value float64, labelValues ...string)     {}
+func (nopMetrics) Observe(value float64, labelValues ...string) {}
+
+// WithAnomalyLog writes locked/crossed price updates with their raw payload to q
+func WithAnomalyLog(q ports.DeadLetterQueue) Option {
+	return func(cs *CollectorService) {
+		cs.anomalyQueue = q
+	}
+}
+
+// WithCrossedQuotes sets the spread recorded for crossed quotes (see domain.CrossedQuotePolicy)
+func WithCrossedQuotes(policy domain.CrossedQuotePolicy) Option {
+	return func(cs *CollectorService) {
+		cs.crossedQuotes = policy
+	}
+}
+
+// checkQuote counts locked/crossed markets and keeps the raw update for broker data-quality debugging
+// Runs before any filtering, so counts cover everything the broker sent
+func (cs *CollectorService) checkQuote(update saxo.PriceUpdate, priceData *domain.PriceData) {
+	kind := priceData.QuoteAnomaly()
+	if kind == "" {
+		return
+	}
+
+	cs.quoteAnomalies.Inc(priceData.Ticker, kind)
+	cs.triggerBurst(priceData.Ticker, priceData.Timestamp)
+	if cs.anomalyQueue == nil {
+		return
+	}
+
+	entry := domain.DeadLetter{
+		Timestamp: time.Now().UTC(),
+		Reason:    kind + "_market",
+		Error:     fmt.Sprintf("bid %v, ask %v", update.Bid, update.Ask),
+		Payload:   update,
+	}
+	if err := cs.anomalyQueue.Put(cs.ctx, entry); err != nil {
+		cs.logger.Printf("Failed to record %s market for %s: %v", kind, priceData.Ticker, err)
+		cs.countError("dead_letter")
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// rawCaptureQueueSize bounds the records waiting for the capture writer; more are dropped
+const rawCaptureQueueSize = 4096
+
+// rawFrameSource is a WebSocket client that hands out every frame as received, before parsing
+type rawFrameSource interface {
+	SetRawFrameHandler(handler func(receivedAt time.Time, frame []byte))
+}
+
+// rawRecord is one frame or, without a frame source, one decoded update waiting to be captured
+type rawRecord struct {
+	receivedAt time.Time
+	frame      []byte
+	update     saxo.PriceUpdate
+}
+
+// rawCaptureQueue hands records to the capture writer goroutine, so file I/O stays off the
+// WebSocket reader and the price processor
+type rawCaptureQueue struct {
+	frames  bool // The client hands out frames; decoded updates aren't captured
+	records chan rawRecord
+	done    chan struct{}
+
+	mu      sync.RWMutex // Read-held while queueing; closing the queue takes it
+	closed  bool
+	dropped atomic.Int64
+}
+
+// WithRawCapture persists every broker update as received, before mapping, for replay
+// With a WebSocket client that hands out frames (SetRawFrameHandler) the frames are stored byte
+// for byte; otherwise the decoded updates are
+// A failed or dropped capture is logged and counted but never holds up recording
+func WithRawCapture(capture ports.RawCapture) Option {
+	return func(cs *CollectorService) {
+		cs.rawCapture = capture
+	}
+}
+
+// WithWebSocketClient replaces the Saxo WebSocket client, e.g. with a replay of captured updates
+func WithWebSocketClient(client saxo.WebSocketClient) Option {
+	return func(cs *CollectorService) {
+		cs.wsClient = client
+	}
+}
+
+// startRawCapture starts the capture writer and, if the client offers them, subscribes to its frames
+func (cs *CollectorService) startRawCapture() {
+	if cs.rawCapture == nil || cs.rawQueue != nil {
+		return
+	}
+	q := &rawCaptureQueue{records: make(chan rawRecord, rawCaptureQueueSize), done: make(chan struct{})}
+	if source, ok := cs.wsClient.(rawFrameSource); ok {
+		q.frames = true
+		source.SetRawFrameHandler(func(receivedAt time.Time, frame []byte) {
+			cs.enqueueRaw(rawRecord{receivedAt: receivedAt, frame: frame})
+		})
+	} else {
+		cs.logger.Println("Raw capture: the WebSocket client doesn't hand out frames - capturing decoded updates")
+	}
+	cs.rawQueue = q
+	go cs.writeRawCaptures(q)
+}
+
+// captureRaw queues one update as the WebSocket client delivered it, unless frames are captured
+// Only called from the price processor goroutine
+func (cs *CollectorService) captureRaw(update saxo.PriceUpdate, receivedAt time.Time) {
+	if cs.rawQueue == nil || cs.rawQueue.frames {
+		return
+	}
+	cs.enqueueRaw(rawRecord{receivedAt: receivedAt, update: update})
+}
+
+// enqueueRaw hands a record to the writer, dropping it when the writer has fallen behind
+func (cs *CollectorService) enqueueRaw(record rawRecord) {
+	q := cs.rawQueue
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return
+	}
+	select {
+	case q.records <- record:
+	default:
+		if dropped := q.dropped.Add(1); dropped == 1 || dropped%1000 == 0 {
+			cs.logger.Printf("Raw capture can't keep up: %d records dropped", dropped)
+		}
+		cs.countError("capture")
+	}
+}
+
+// writeRawCaptures stores the queued records until the queue is closed
+func (cs *CollectorService) writeRawCaptures(q *rawCaptureQueue) {
+	defer close(q.done)
+	for record := range q.records {
+		var err error
+		if record.frame != nil {
+			err = cs.rawCapture.CaptureFrame(record.receivedAt, record.frame)
+		} else {
+			err = cs.rawCapture.Capture(record.receivedAt, record.update)
+		}
+		if err != nil {
+			cs.logger.Printf("Raw capture failed: %v", err)
+			cs.countError("capture")
+		}
+	}
+}
+
+// closeRawCapture writes the queued records and closes the capture file on shutdown
+// Records still queued when ctx expires are lost
+func (cs *CollectorService) closeRawCapture(ctx context.Context) error {
+	if q := cs.rawQueue; q != nil {
+		q.mu.Lock()
+		if !q.closed {
+			q.closed = true
+			close(q.records)
+		}
+		q.mu.Unlock()
+		select {
+		case <-q.done:
+		case <-ctx.Done():
+			return fmt.Errorf("gave up writing the raw capture queue: %w", ctx.Err())
+		}
+	}
+	if closer, ok := cs.rawCapture.(ports.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close raw capture: %w", err)
+		}
+	}
+	return nil
+}
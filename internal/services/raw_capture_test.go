@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// memoryCapture keeps what is captured
+type memoryCapture struct {
+	mu      sync.Mutex
+	updates []any
+	frames  [][]byte
+}
+
+func (c *memoryCapture) Capture(receivedAt time.Time, payload any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates = append(c.updates, payload)
+	return nil
+}
+
+func (c *memoryCapture) CaptureFrame(receivedAt time.Time, frame []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames = append(c.frames, frame)
+	return nil
+}
+
+// framingClient is a WebSocket client that hands out its frames
+type framingClient struct {
+	saxo.WebSocketClient
+	handler func(receivedAt time.Time, frame []byte)
+}
+
+func (c *framingClient) SetRawFrameHandler(handler func(receivedAt time.Time, frame []byte)) {
+	c.handler = handler
+}
+
+func newCapturingService(capture *memoryCapture, client saxo.WebSocketClient) *CollectorService {
+	cs := &CollectorService{ctx: context.Background(), logger: log.New(io.Discard, "", 0), wsClient: client}
+	WithMetrics(nopMetrics{})(cs)
+	WithRawCapture(capture)(cs)
+	return cs
+}
+
+func TestRawCapture_CapturesFramesFromTheClient(t *testing.T) {
+	capture := &memoryCapture{}
+	client := &framingClient{}
+	cs := newCapturingService(capture, client)
+	cs.startRawCapture()
+
+	client.handler(time.Now(), []byte{1, 2, 3})
+	cs.captureRaw(saxo.PriceUpdate{Ticker: "EURUSD"}, time.Now()) // Already in the frame
+	if err := cs.closeRawCapture(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(capture.frames) != 1 || len(capture.updates) != 0 {
+		t.Errorf("Expected only the frame, got %d frames and %d updates", len(capture.frames), len(capture.updates))
+	}
+
+	// A frame arriving after shutdown is ignored
+	client.handler(time.Now(), []byte{4})
+	if len(capture.frames) != 1 {
+		t.Errorf("Expected no capture after close, got %d frames", len(capture.frames))
+	}
+}
+
+func TestRawCapture_FallsBackToDecodedUpdates(t *testing.T) {
+	capture := &memoryCapture{}
+	cs := newCapturingService(capture, nil)
+	cs.startRawCapture()
+
+	for range 3 {
+		cs.captureRaw(saxo.PriceUpdate{Ticker: "EURUSD"}, time.Now())
+	}
+	if err := cs.closeRawCapture(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(capture.updates) != 3 {
+		t.Errorf("Expected the queued updates written on close, got %d", len(capture.updates))
+	}
+}
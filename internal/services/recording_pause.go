@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// recordingPauses holds pauses set through the admin API
+type recordingPauses struct {
+	mu      sync.RWMutex
+	all     bool
+	tickers map[string]bool
+}
+
+// WithPauseSchedule skips recording during recurring windows (e.g. Friday 22:00-23:00)
+// Subscriptions stay alive; only persistence is gated
+func WithPauseSchedule(schedule domain.PauseSchedule) Option {
+	return func(cs *CollectorService) {
+		cs.pauseSchedule = schedule
+	}
+}
+
+// PauseRecording stops recording ticker, or all instruments if ticker is ""
+func (cs *CollectorService) PauseRecording(ticker string) error {
+	return cs.setPaused(ticker, true)
+}
+
+// ResumeRecording resumes recording ticker, or lifts the global pause if ticker is ""
+// Resuming all instruments also clears per-instrument pauses; scheduled windows still apply
+func (cs *CollectorService) ResumeRecording(ticker string) error {
+	return cs.setPaused(ticker, false)
+}
+
+// RecordingState returns the current pauses
+func (cs *CollectorService) RecordingState() domain.RecordingState {
+	cs.pauses.mu.RLock()
+	state := domain.RecordingState{
+		Paused:    cs.pauses.all,
+		Tickers:   append([]string{}, slices.Sorted(maps.Keys(cs.pauses.tickers))...),
+		Scheduled: []string{}, // Empty lists rather than null in JSON
+	}
+	cs.pauses.mu.RUnlock()
+
+	now := time.Now()
+	for _, ticker := range cs.getAllTickers() {
+		if cs.pauseSchedule.Paused(ticker, now) {
+			state.Scheduled = append(state.Scheduled, ticker)
+		}
+	}
+	slices.Sort(state.Scheduled)
+	return state
+}
+
+// setPaused changes a manual pause and reports it as an event
+func (cs *CollectorService) setPaused(ticker string, paused bool) error {
+	if _, ok := cs.instruments[ticker]; ticker != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrInstrumentNotFound, ticker)
+	}
+
+	cs.pauses.mu.Lock()
+	switch {
+	case ticker == "":
+		cs.pauses.all = paused
+		if !paused {
+			cs.pauses.tickers = nil
+		}
+	case paused:
+		if cs.pauses.tickers == nil {
+			cs.pauses.tickers = make(map[string]bool)
+		}
+		cs.pauses.tickers[ticker] = true
+	default:
+		delete(cs.pauses.tickers, ticker)
+	}
+	cs.pauses.mu.Unlock()
+
+	scope := ticker
+	if scope == "" {
+		scope = "all instruments"
+	}
+	eventType, verb := domain.EventResumed, "resumed"
+	if paused {
+		eventType, verb = domain.EventPaused, "paused"
+	}
+	cs.logger.Printf("Recording %s for %s", verb, scope)
+
+	event := domain.NewEvent(eventType, domain.SeverityInfo, fmt.Sprintf("Recording %s for %s", verb, scope))
+	event.Ticker = ticker
+	cs.emit(event)
+	return nil
+}
+
+// recordingPausedFor reports whether a tick must not be persisted because of a pause
+func (cs *CollectorService) recordingPausedFor(ticker string, t time.Time) bool {
+	cs.pauses.mu.RLock()
+	paused := cs.pauses.all || cs.pauses.tickers[ticker]
+	cs.pauses.mu.RUnlock()
+
+	return paused || cs.pauseSchedule.Paused(ticker, t)
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordingPauses(t *testing.T) {
+	cs := newGatedService(map[string]Instrument{"EURUSD": {Ticker: "EURUSD"}, "USDJPY": {Ticker: "USDJPY"}})
+	now := time.Now()
+
+	if err := cs.PauseRecording("EURUSD"); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	if !cs.recordingPausedFor("EURUSD", now) || cs.recordingPausedFor("USDJPY", now) {
+		t.Error("Expected only EURUSD to be paused")
+	}
+
+	if err := cs.PauseRecording(""); err != nil {
+		t.Fatalf("Failed to pause all: %v", err)
+	}
+	if !cs.recordingPausedFor("USDJPY", now) {
+		t.Error("Expected USDJPY to be paused with all instruments")
+	}
+	if state := cs.RecordingState(); !state.Paused || len(state.Tickers) != 1 || state.Tickers[0] != "EURUSD" {
+		t.Errorf("Expected a global pause and EURUSD, got %+v", state)
+	}
+
+	// Resuming all also lifts the instrument's pause
+	if err := cs.ResumeRecording(""); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if cs.recordingPausedFor("EURUSD", now) || cs.recordingPausedFor("USDJPY", now) {
+		t.Error("Expected recording to be resumed for all instruments")
+	}
+
+	if err := cs.PauseRecording("GBPUSD"); !errors.Is(err, ErrInstrumentNotFound) {
+		t.Errorf("Expected ErrInstrumentNotFound for an unknown ticker, got %v", err)
+	}
+}
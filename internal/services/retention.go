@@ -0,0 +1,67 @@
+package services
+
+import (
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// RetentionConfig configures the pruning of stores that declare a retention period: sinks with
+// retain=30d, the copy target and the snapshot, aligned quote and histogram directories
+type RetentionConfig struct {
+	CheckInterval time.Duration // How often every store is pruned, starting at startup
+	DryRun        bool          // Only log what would be deleted
+}
+
+// RetainedStore is a store pruned by the retention job after its own retention period
+type RetainedStore struct {
+	Name   string
+	Retain time.Duration
+	Pruner ports.Pruner
+}
+
+// WithRetention prunes each store by its own retention period
+func WithRetention(cfg RetentionConfig, stores ...RetainedStore) Option {
+	return func(cs *CollectorService) {
+		cs.retention = &cfg
+		cs.retainedStores = stores
+	}
+}
+
+// enforceRetention prunes the stores with a retention period until the service stops
+func (cs *CollectorService) enforceRetention() {
+	mode := ""
+	if cs.retention.DryRun {
+		mode = ", dry run"
+	}
+	cs.logger.Printf("Starting retention for %d stores (every %v%s)", len(cs.retainedStores), cs.retention.CheckInterval, mode)
+
+	ticker := time.NewTicker(cs.retention.CheckInterval)
+	defer ticker.Stop()
+	for now := time.Now(); ; {
+		cs.pruneStores(now)
+		select {
+		case <-cs.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
+
+// pruneStores deletes what each store recorded before its retention period, or logs it on a dry run
+func (cs *CollectorService) pruneStores(now time.Time) {
+	for _, store := range cs.retainedStores {
+		removed, err := store.Pruner.Prune(cs.ctx, now.Add(-store.Retain), cs.retention.DryRun)
+		for _, path := range removed {
+			if cs.retention.DryRun {
+				cs.logger.Printf("Retention (dry run): %s would delete %s (older than %v)", store.Name, path, store.Retain)
+			} else {
+				cs.logger.Printf("Retention: %s deleted %s (older than %v)", store.Name, path, store.Retain)
+			}
+		}
+		if err != nil {
+			cs.logger.Printf("Retention: failed to prune %s: %v", store.Name, err)
+			cs.countError("retention")
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// sequenceBlock is how many sequence numbers are reserved per instrument at a time
+// A crash skips at most this many numbers of an instrument
+const sequenceBlock = 10000
+
+// sequencer assigns monotonically increasing per-instrument sequence numbers
+// and tracks the timestamp of the last recorded tick per instrument
+type sequencer struct {
+	mu           sync.Mutex
+	last         map[string]uint64
+	reserved     map[string]uint64 // Highest number per ticker that may be handed out before persisting a new reservation
+	lastRecorded map[string]time.Time
+}
+
+func newSequencer() *sequencer {
+	return &sequencer{last: make(map[string]uint64), reserved: make(map[string]uint64), lastRecorded: make(map[string]time.Time)}
+}
+
+// next returns the next sequence number for ticker
+// When it passes the ticker's reservation, the next block is reserved and all reservations are
+// returned: the caller persists them before using the number (see reserveSequences)
+func (s *sequencer) next(ticker string) (uint64, map[string]uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last[ticker]++
+	if s.last[ticker] <= s.reserved[ticker] {
+		return s.last[ticker], nil
+	}
+	s.reserved[ticker] = s.last[ticker] + sequenceBlock - 1
+	return s.last[ticker], maps.Clone(s.reserved)
+}
+
+// unreserve drops a reservation that couldn't be persisted, so the next number retries it
+func (s *sequencer) unreserve(ticker string, sequence uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reserved[ticker] = sequence - 1
+}
+
+// recorded notes a tick of ticker recorded at timestamp
+func (s *sequencer) recorded(ticker string, timestamp time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRecorded[ticker] = timestamp
+}
+
+// recordedSnapshot returns a copy of the last recorded timestamp per ticker
+func (s *sequencer) recordedSnapshot() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.lastRecorded)
+}
+
+// restore continues numbering after previously persisted sequences (or reservations)
+func (s *sequencer) restore(sequences map[string]uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.Copy(s.last, sequences)
+	maps.Copy(s.reserved, sequences)
+}
+
+// snapshot returns a copy of the last assigned sequence per ticker
+func (s *sequencer) snapshot() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.last)
+}
+
+// WithSequenceStore persists the last sequence per instrument so numbering continues across restarts
+func WithSequenceStore(store ports.SequenceStore) Option {
+	return func(cs *CollectorService) {
+		cs.sequenceStore = store
+	}
+}
+
+// WithStateStore keeps sequence numbers, the last recorded tick per instrument and recording gaps
+// in a state database instead of a sequence file
+func WithStateStore(store ports.StateStore) Option {
+	return func(cs *CollectorService) {
+		cs.sequenceStore = store
+		cs.stateStore = store
+	}
+}
+
+// loadSequences restores persisted sequence numbers at startup
+// With a state store, the time since the last recorded tick of the previous run is logged as downtime
+func (cs *CollectorService) loadSequences() error {
+	if cs.sequenceStore == nil {
+		return nil
+	}
+
+	sequences, err := cs.sequenceStore.Load(cs.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load sequence numbers: %w", err)
+	}
+	cs.sequences.restore(sequences)
+	cs.logger.Printf("Restored sequence numbers for %d instruments", len(sequences))
+
+	if cs.stateStore == nil {
+		return nil
+	}
+	lastRecorded, err := cs.stateStore.LastRecorded(cs.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load last recorded ticks: %w", err)
+	}
+	var latest time.Time
+	for ticker, timestamp := range lastRecorded {
+		cs.sequences.recorded(ticker, timestamp)
+		if timestamp.After(latest) {
+			latest = timestamp
+		}
+	}
+	if !latest.IsZero() {
+		now := time.Now().UTC()
+		cs.logger.Printf("Last tick of the previous run recorded at %s (%v ago)", latest.Format(time.RFC3339), now.Sub(latest).Round(time.Second))
+		for _, gap := range cs.downtimeGaps(latest, now) {
+			cs.addGap(gap)
+		}
+	}
+	return nil
+}
+
+// downtimeGaps splits the downtime from start to end into the periods in which a market was open,
+// so a weekend or holiday the collector was stopped over doesn't count as downtime
+func (cs *CollectorService) downtimeGaps(start, end time.Time) []domain.RecordingGap {
+	var gaps []domain.RecordingGap
+	open := false
+	for at := start; at.Before(end); at = at.Add(time.Minute) {
+		if !cs.anyMarketOpen(at) {
+			open = false
+			continue
+		}
+		if !open {
+			gaps = append(gaps, domain.RecordingGap{Start: at, Reason: domain.GapDowntime})
+			open = true
+		}
+		gaps[len(gaps)-1].End = at.Add(time.Minute)
+		if gaps[len(gaps)-1].End.After(end) {
+			gaps[len(gaps)-1].End = end
+		}
+	}
+	return gaps
+}
+
+// addGap records a gap in the state store, if any
+func (cs *CollectorService) addGap(gap domain.RecordingGap) {
+	if cs.stateStore == nil {
+		return
+	}
+	if err := cs.stateStore.AddGap(cs.ctx, gap); err != nil {
+		cs.logger.Printf("Failed to save recording gap: %v", err)
+		cs.countError("state")
+	}
+}
+
+// nextSequence assigns the next sequence number of ticker
+// A new reservation is persisted before its first number is used, so after a crash numbering
+// continues above every number handed out: ticks lost in the crash leave a gap, never reused numbers
+// If it can't be persisted, the number is still used and the reservation retried with the next tick
+// Only called from the price processor goroutine
+func (cs *CollectorService) nextSequence(ticker string) uint64 {
+	sequence, reservations := cs.sequences.next(ticker)
+	if reservations == nil || cs.sequenceStore == nil {
+		return sequence
+	}
+	if err := cs.sequenceStore.Save(cs.ctx, reservations); err != nil {
+		cs.sequences.unreserve(ticker, sequence)
+		cs.logger.Printf("Failed to reserve sequence numbers for %s: %v", ticker, err)
+		cs.countError("sequences")
+	}
+	return sequence
+}
+
+// saveSequences persists the last assigned sequence numbers at shutdown, once the price processor
+// has stopped, so a clean restart continues without a gap
+func (cs *CollectorService) saveSequences(ctx context.Context) {
+	if cs.sequenceStore == nil {
+		return
+	}
+	if err := cs.sequenceStore.Save(ctx, cs.sequences.snapshot()); err != nil {
+		cs.logger.Printf("Failed to save sequence numbers: %v", err)
+		cs.countError("sequences")
+	}
+}
+
+// saveLastRecorded persists the last recorded tick per instrument; called after a successful flush
+func (cs *CollectorService) saveLastRecorded(ctx context.Context) {
+	if cs.stateStore == nil {
+		return
+	}
+	if err := cs.stateStore.SaveLastRecorded(ctx, cs.sequences.recordedSnapshot()); err != nil {
+		cs.logger.Printf("Failed to save last recorded ticks: %v", err)
+		cs.countError("state")
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSequencer_ReservesBlocksAhead(t *testing.T) {
+	s := newSequencer()
+	s.restore(map[string]uint64{"EURUSD": 41})
+
+	sequence, reservations := s.next("EURUSD")
+	if sequence != 42 || reservations["EURUSD"] != 42+sequenceBlock-1 {
+		t.Fatalf("Expected 42 with a new reservation, got %d, %v", sequence, reservations)
+	}
+	for i := 1; i < sequenceBlock; i++ {
+		if _, reservations := s.next("EURUSD"); reservations != nil {
+			t.Fatalf("Unexpected reservation within the block at %d", 42+i)
+		}
+	}
+	if sequence, reservations := s.next("EURUSD"); reservations["EURUSD"] != sequence+sequenceBlock-1 {
+		t.Errorf("Expected the next block to be reserved at %d, got %v", sequence, reservations)
+	}
+}
+
+func TestSequencer_UnreserveRetries(t *testing.T) {
+	s := newSequencer()
+	sequence, _ := s.next("USDJPY")
+	s.unreserve("USDJPY", sequence)
+	if _, reservations := s.next("USDJPY"); reservations == nil {
+		t.Error("Expected a failed reservation to be retried with the next number")
+	}
+}
+
+func TestDowntimeGaps_SkipTheWeekend(t *testing.T) {
+	cs := &CollectorService{instruments: map[string]Instrument{"EURUSD": {Ticker: "EURUSD"}}}
+	// Friday 15:00 to Monday 10:00 New York time; the clocks change over the weekend
+	start := time.Date(2025, 3, 7, 20, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+
+	gaps := cs.downtimeGaps(start, end)
+	if len(gaps) != 2 {
+		t.Fatalf("Expected the downtime before and after the weekend, got %v", gaps)
+	}
+	if want := time.Date(2025, 3, 7, 22, 0, 0, 0, time.UTC); !gaps[0].End.Equal(want) {
+		t.Errorf("Expected the first gap to end at the Friday close %v, got %v", want, gaps[0].End)
+	}
+	if want := time.Date(2025, 3, 9, 21, 0, 0, 0, time.UTC); !gaps[1].Start.Equal(want) || !gaps[1].End.Equal(end) {
+		t.Errorf("Expected the second gap from the Sunday open %v to the start, got %v - %v", want, gaps[1].Start, gaps[1].End)
+	}
+}
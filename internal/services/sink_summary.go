@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// WithSinkSummary logs what each of the metered sinks wrote every interval, with its write and
+// flush latency; 0 or no sinks disables the summary
+func WithSinkSummary(interval time.Duration, sinks ...ports.MeteredSink) Option {
+	return func(cs *CollectorService) {
+		cs.sinkSummaryInterval = interval
+		cs.meteredSinks = sinks
+	}
+}
+
+// reportSinks logs one line with the statistics of every metered sink until the service stops
+func (cs *CollectorService) reportSinks() {
+	sinks := cs.meteredSinks
+	previous := make([]domain.SinkStats, len(sinks))
+	for i, sink := range sinks {
+		previous[i] = sink.Stats()
+	}
+
+	ticker := time.NewTicker(cs.sinkSummaryInterval)
+	defer ticker.Stop()
+	since := time.Now()
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+		case now := <-ticker.C:
+			parts := make([]string, 0, len(sinks))
+			for i, sink := range sinks {
+				current := sink.Stats()
+				parts = append(parts, formatSinkStats(sink.Name(), current.Sub(previous[i])))
+				previous[i] = current
+			}
+			cs.logger.Printf("Sinks (last %v): %s", now.Sub(since).Round(time.Second), strings.Join(parts, "; "))
+			since = now
+		}
+	}
+}
+
+// formatSinkStats renders a sink's statistics for a period as
+// "csv records=120 bytes=9612 write=12µs flush=3.1ms files=28 errors=0", latencies being averages
+func formatSinkStats(name string, stats domain.SinkStats) string {
+	average := func(total time.Duration, count uint64) time.Duration {
+		if count == 0 {
+			return 0
+		}
+		return (total / time.Duration(count)).Round(time.Microsecond)
+	}
+	return fmt.Sprintf("%s records=%d bytes=%d write=%v flush=%v files=%d errors=%d",
+		name, stats.Records, stats.Bytes, average(stats.WriteTime, stats.Writes),
+		average(stats.FlushTime, stats.Flushes), stats.OpenFiles, stats.Errors)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// histogramGrace is how long after an hour ends late ticks still count towards its histograms
+const histogramGrace = time.Minute
+
+// spreadHistograms counts every instrument's spreads per UTC hour in buckets of pips
+type spreadHistograms struct {
+	mu     sync.Mutex
+	bounds []float64
+	writer ports.HistogramWriter
+	open   map[time.Time]map[string]*domain.SpreadHistogram // By hour, then ticker
+}
+
+// WithSpreadHistograms writes one histogram of spreads in pips per instrument and hour to writer
+// bounds are the upper bucket bounds in pips (see domain.ParseHistogramBounds)
+func WithSpreadHistograms(bounds []float64, writer ports.HistogramWriter) Option {
+	return func(cs *CollectorService) {
+		cs.histograms = &spreadHistograms{
+			bounds: bounds,
+			writer: writer,
+			open:   make(map[time.Time]map[string]*domain.SpreadHistogram),
+		}
+	}
+}
+
+// observe counts a tick's spread in its hour's histogram
+func (h *spreadHistograms) observe(p *domain.PriceData) {
+	pipSize := p.PipSize
+	if pipSize <= 0 {
+		pipSize = domain.DefaultPipSize(p.Ticker)
+	}
+	hour := p.Timestamp.UTC().Truncate(time.Hour)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	byTicker, ok := h.open[hour]
+	if !ok {
+		byTicker = make(map[string]*domain.SpreadHistogram)
+		h.open[hour] = byTicker
+	}
+	histogram, ok := byTicker[p.Ticker]
+	if !ok {
+		histogram = domain.NewSpreadHistogram(hour, p.Ticker, h.bounds)
+		byTicker[p.Ticker] = histogram
+	}
+	histogram.Add(p.Spread / pipSize)
+}
+
+// take removes and returns the histograms of hours that ended before cutoff, by hour and ticker
+func (h *spreadHistograms) take(cutoff time.Time) []*domain.SpreadHistogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var histograms []*domain.SpreadHistogram
+	for hour, byTicker := range h.open {
+		if hour.Add(time.Hour).After(cutoff) {
+			continue
+		}
+		for _, histogram := range byTicker {
+			histograms = append(histograms, histogram)
+		}
+		delete(h.open, hour)
+	}
+	slices.SortFunc(histograms, func(a, b *domain.SpreadHistogram) int {
+		return cmp.Or(a.Hour.Compare(b.Hour), cmp.Compare(a.Ticker, b.Ticker))
+	})
+	return histograms
+}
+
+// writeHistograms writes the histograms of each hour once its grace period is over
+func (cs *CollectorService) writeHistograms() {
+	cs.logger.Printf("Starting spread histogram writer (%d buckets)", len(cs.histograms.bounds)+1)
+
+	ticker := time.NewTicker(histogramGrace)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+		case now := <-ticker.C:
+			cs.recordHistograms(cs.ctx, cs.histograms.take(now.Add(-histogramGrace)))
+		}
+	}
+}
+
+// recordHistograms writes histograms, reporting failures like the other writers
+// A standby or a collector paused for disk space drops them, as it does snapshots
+func (cs *CollectorService) recordHistograms(ctx context.Context, histograms []*domain.SpreadHistogram) {
+	if len(histograms) == 0 || !cs.recordingAllowed() {
+		return
+	}
+	if err := cs.histograms.writer.RecordHistograms(ctx, histograms); err != nil {
+		cs.logger.Printf("Error recording spread histograms: %v", err)
+		cs.countError("histograms")
+		cs.emit(domain.NewEvent(domain.EventStorageError, domain.SeverityCritical,
+			fmt.Sprintf("Failed to record spread histograms: %v", err)))
+	}
+}
+
+// closeHistogramWriter writes the histograms of the hours still open, then releases the writer
+// The current hour's histograms are written incomplete; after a restart the hour gets a second row
+func (cs *CollectorService) closeHistogramWriter(ctx context.Context) error {
+	if cs.histograms == nil {
+		return nil
+	}
+	cs.recordHistograms(ctx, cs.histograms.take(time.Now().Add(time.Hour)))
+	if closer, ok := cs.histograms.writer.(ports.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close spread histogram writer: %w", err)
+		}
+	}
+	return nil
+}
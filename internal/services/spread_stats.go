@@ -0,0 +1,127 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// spreadStats keeps the latest quote and the spread range of the current UTC hour per instrument,
+// so the events of an instrument can show the market at the time (e.g. in alert templates)
+type spreadStats struct {
+	mu     sync.Mutex
+	latest map[string]*instrumentSpread
+}
+
+// instrumentSpread is the latest quote of one instrument and its spread range this hour
+type instrumentSpread struct {
+	bid, ask, spread float64
+	decimals         int
+	pipSize          float64
+	at               time.Time
+	received         uint64 // Ticks since the service started
+
+	hour          time.Time
+	ticks         int
+	min, max, sum float64
+}
+
+func newSpreadStats() *spreadStats {
+	return &spreadStats{latest: make(map[string]*instrumentSpread)}
+}
+
+// observe records a plausible tick
+func (s *spreadStats) observe(p *domain.PriceData) {
+	hour := p.Timestamp.UTC().Truncate(time.Hour)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.latest[p.Ticker]
+	if !ok {
+		stats = &instrumentSpread{}
+		s.latest[p.Ticker] = stats
+	}
+	stats.bid, stats.ask, stats.spread = p.Bid, p.Ask, p.Spread
+	stats.decimals, stats.pipSize, stats.at = p.Decimals, p.PipSize, p.Timestamp
+	stats.received++
+
+	if !hour.Equal(stats.hour) {
+		stats.hour, stats.ticks, stats.sum = hour, 0, 0
+		stats.min, stats.max = p.Spread, p.Spread
+	}
+	stats.ticks++
+	stats.sum += p.Spread
+	stats.min = min(stats.min, p.Spread)
+	stats.max = max(stats.max, p.Spread)
+}
+
+// fields describes the latest quote of ticker as event fields; nil before its first tick
+// Spreads are in price units, spread_pips uses the instrument's pip size
+func (s *spreadStats) fields(ticker string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.latest[ticker]
+	if !ok {
+		return nil
+	}
+
+	price := func(v float64) string {
+		if stats.decimals > 0 {
+			// One more decimal than the quote so half-pip spreads stay visible
+			return strconv.FormatFloat(v, 'f', stats.decimals+1, 64)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	fields := map[string]string{
+		"bid":             price(stats.bid),
+		"ask":             price(stats.ask),
+		"spread":          price(stats.spread),
+		"quote_time":      stats.at.UTC().Format(time.RFC3339Nano),
+		"hour_spread_min": price(stats.min),
+		"hour_spread_avg": price(stats.sum / float64(stats.ticks)),
+		"hour_spread_max": price(stats.max),
+		"hour_ticks":      strconv.Itoa(stats.ticks),
+	}
+	if stats.pipSize > 0 {
+		fields["spread_pips"] = strconv.FormatFloat(stats.spread/stats.pipSize, 'f', 1, 64)
+	}
+	return fields
+}
+
+// status returns the latest quote of ticker; only the ticker is set before its first tick
+func (s *spreadStats) status(ticker string) domain.InstrumentStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := domain.InstrumentStatus{Ticker: ticker}
+	stats, ok := s.latest[ticker]
+	if !ok {
+		return status
+	}
+
+	at := stats.at
+	status.Bid, status.Ask, status.Spread = stats.bid, stats.ask, stats.spread
+	status.Decimals, status.QuoteTime, status.Ticks = stats.decimals, &at, stats.received
+	if stats.pipSize > 0 {
+		status.SpreadPips = stats.spread / stats.pipSize
+	}
+	return status
+}
+
+// withSpreadFields adds the latest quote of the event's instrument to its fields
+// Fields set by the event itself win
+func (cs *CollectorService) withSpreadFields(event domain.Event) domain.Event {
+	if event.Ticker == "" {
+		return event
+	}
+	spread := cs.spreads.fields(event.Ticker)
+	if spread == nil {
+		return event
+	}
+	for key, value := range event.Fields {
+		spread[key] = value
+	}
+	event.Fields = spread
+	return event
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// zscoreMinSamples is how many spreads the window needs before ticks get a z-score
+const zscoreMinSamples = 20
+
+// spreadZScores scores every instrument's spreads against its own trailing window
+// Only used from the price processor goroutine
+type spreadZScores struct {
+	window time.Duration
+	scores map[string]*domain.RollingZScore
+}
+
+// WithSpreadZScore records the z-score of each tick's spread against the instrument's spreads
+// over the trailing window in the spread_z column
+func WithSpreadZScore(window time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.zscores = &spreadZScores{window: window, scores: make(map[string]*domain.RollingZScore)}
+	}
+}
+
+// score sets a tick's SpreadZ; outliers are scored but kept out of the window so they don't mask each other
+func (z *spreadZScores) score(p *domain.PriceData) {
+	rolling, ok := z.scores[p.Ticker]
+	if !ok {
+		rolling = domain.NewRollingZScore(z.window, zscoreMinSamples)
+		z.scores[p.Ticker] = rolling
+	}
+
+	var score float64
+	if p.Flags.Has(domain.FlagOutlier) {
+		score, ok = rolling.Score(p.Timestamp, p.Spread)
+	} else {
+		score, ok = rolling.Add(p.Timestamp, p.Spread)
+	}
+	if !ok {
+		return
+	}
+	// Rounded to the column's precision; a zero score leaves the column empty
+	if score = math.Round(score*100) / 100; score != 0 {
+		p.SpreadZ = score
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// tickTracker remembers when each instrument last received a price update, and the reference IDs
+// its latest price subscription may have been given
+type tickTracker struct {
+	mu       sync.Mutex
+	lastTick map[string]time.Time
+	refs     map[string][]string
+}
+
+func newTickTracker() *tickTracker {
+	return &tickTracker{lastTick: make(map[string]time.Time), refs: make(map[string][]string)}
+}
+
+// touch records a tick for ticker at t
+func (t *tickTracker) touch(ticker string, at time.Time) {
+	t.mu.Lock()
+	t.lastTick[ticker] = at
+	t.mu.Unlock()
+}
+
+// last returns the time of the latest tick for ticker
+func (t *tickTracker) last(ticker string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.lastTick[ticker]
+	return at, ok
+}
+
+// subscribed records the reference IDs of a price subscription of tickers issued between from and to
+func (t *tickTracker) subscribed(tickers []string, from, to time.Time) {
+	refs := priceReferenceIDs(from, to)
+	t.mu.Lock()
+	for _, ticker := range tickers {
+		t.refs[ticker] = refs
+	}
+	t.mu.Unlock()
+}
+
+// references returns the reference IDs recorded for ticker's latest price subscription
+func (t *tickTracker) references(ticker string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.refs[ticker]
+}
+
+// priceReferenceIDs returns the reference IDs saxo-adapter may have given a price subscription issued
+// between from and to: it doesn't return the ID, which is "prices-" and the time of the request
+func priceReferenceIDs(from, to time.Time) []string {
+	var refs []string
+	for at := from.Truncate(time.Second); !at.After(to); at = at.Add(time.Second) {
+		refs = append(refs, "prices-"+at.Format("20060102-150405"))
+	}
+	return refs
+}
+
+// isQuoteless reports whether a price update lacks a side of the quote (bid or ask 0), so it can't
+// be recorded as a tick. It still shows the instrument's subscription is delivering
+func isQuoteless(update saxo.PriceUpdate) bool {
+	return update.Bid == 0 || update.Ask == 0
+}
+
+// subscribeToPrices subscribes tickers and records the subscription's reference IDs for the watchdog
+func (cs *CollectorService) subscribeToPrices(tickers []string) error {
+	from := time.Now()
+	if err := cs.wsClient.SubscribeToPrices(cs.ctx, tickers); err != nil {
+		return err
+	}
+	cs.ticks.subscribed(tickers, from, time.Now())
+	return nil
+}
+
+// lastHeard returns when ticker last showed signs of life: a price update, or a message the WebSocket
+// client recorded for its subscription (Saxo sends heartbeats there while an instrument has no new prices)
+func (cs *CollectorService) lastHeard(ticker string) time.Time {
+	last, _ := cs.ticks.last(ticker)
+	activity, ok := cs.wsClient.(ports.SubscriptionActivity)
+	if !ok {
+		return last
+	}
+	for _, ref := range cs.ticks.references(ticker) {
+		if at, ok := activity.GetLastMessageTimestamp(ref); ok && at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
+
+// WithSubscriptionWatchdog re-subscribes an instrument that received no price update or heartbeat for staleAfter
+// while its market is open (FX market hours, no holiday), independent of overall WebSocket health
+func WithSubscriptionWatchdog(staleAfter time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.tunables.SubscriptionStaleAfter = staleAfter
+	}
+}
+
+// watchSubscriptions re-issues price subscriptions for instruments that went silent
+// Price updates and subscription heartbeats (see lastHeard) both count as liveness
+func (cs *CollectorService) watchSubscriptions() {
+	staleAfter := cs.currentTunables().SubscriptionStaleAfter
+	changed := cs.tunablesUpdates()
+	cs.logger.Printf("Starting subscription watchdog (stale after %v)", staleAfter)
+
+	// Every instrument gets a full staleAfter period from start before it can be considered dead
+	startedAt := time.Now()
+	for _, ticker := range cs.getAllTickers() {
+		cs.ticks.touch(ticker, startedAt)
+	}
+
+	ticker := time.NewTicker(cs.watchdogInterval(staleAfter))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cs.ctx.Done():
+			return
+
+		case <-changed:
+			changed = cs.tunablesUpdates()
+			if current := cs.currentTunables().SubscriptionStaleAfter; current != staleAfter {
+				staleAfter = current
+				ticker.Reset(cs.watchdogInterval(staleAfter))
+			}
+
+		case now := <-ticker.C:
+			for _, instrument := range cs.getAllTickers() {
+				threshold := cs.instrumentStaleAfter(instrument, staleAfter)
+				if threshold <= 0 {
+					continue
+				}
+				if !cs.holidays.IsOpen(instrument, now) {
+					// Closed instruments get a full period after they reopen
+					cs.ticks.touch(instrument, now)
+					continue
+				}
+
+				last := cs.lastHeard(instrument)
+				if now.Sub(last) < threshold {
+					continue
+				}
+
+				cs.resubscribe(instrument, now.Sub(last))
+				// Grant the new subscription a full period before retrying
+				cs.ticks.touch(instrument, now)
+			}
+		}
+	}
+}
+
+// instrumentStaleAfter returns ticker's watchdog threshold: its own, or staleAfter (<= 0 means not watched)
+func (cs *CollectorService) instrumentStaleAfter(ticker string, staleAfter time.Duration) time.Duration {
+	if override := cs.instruments[ticker].StaleAfter; override != 0 {
+		return override
+	}
+	return staleAfter
+}
+
+// watchdogInterval checks four times per shortest threshold, at most once a second
+func (cs *CollectorService) watchdogInterval(staleAfter time.Duration) time.Duration {
+	shortest := time.Duration(0)
+	for ticker := range cs.instruments {
+		if threshold := cs.instrumentStaleAfter(ticker, staleAfter); threshold > 0 && (shortest == 0 || threshold < shortest) {
+			shortest = threshold
+		}
+	}
+	return max(shortest/4, time.Second)
+}
+
+// resubscribe re-issues the price subscription for a single instrument
+func (cs *CollectorService) resubscribe(ticker string, silentFor time.Duration) {
+	cs.logger.Printf("Subscription watchdog: no ticks for %s in %v - resubscribing", ticker, silentFor.Round(time.Second))
+
+	cs.quality.expectSnapshots([]string{ticker})
+	if err := cs.subscribeToPrices([]string{ticker}); err != nil {
+		cs.logger.Printf("Subscription watchdog: resubscribe failed for %s: %v", ticker, err)
+		return
+	}
+
+	event := domain.NewEvent(domain.EventResubscribed, domain.SeverityInfo,
+		fmt.Sprintf("Re-subscribed after %v without ticks", silentFor.Round(time.Second)))
+	event.Ticker = ticker
+	cs.emit(event)
+}
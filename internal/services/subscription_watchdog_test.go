@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+func TestProcessPriceUpdate_QuotelessIsNotRecorded(t *testing.T) {
+	for name, update := range map[string]saxo.PriceUpdate{
+		"no quote": {Ticker: "EURUSD", Timestamp: time.Now()},
+		"no bid":   {Ticker: "EURUSD", Ask: 1.1002, Timestamp: time.Now()},
+		"no ask":   {Ticker: "EURUSD", Bid: 1.1000, Timestamp: time.Now()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			recorder := &countingRecorder{}
+			cs := &CollectorService{
+				ctx:            context.Background(),
+				logger:         log.New(io.Discard, "", 0),
+				spreadRecorder: recorder,
+				ticks:          newTickTracker(),
+			}
+			WithMetrics(nopMetrics{})(cs)
+
+			if cs.processPriceUpdate(update) {
+				t.Error("Expected a quote-less update not to be processed as a tick")
+			}
+			if ticks := recorder.recorded(); len(ticks) != 0 {
+				t.Errorf("Expected no recorded ticks, got %d", len(ticks))
+			}
+			if _, ok := cs.ticks.last("EURUSD"); !ok {
+				t.Error("Expected the update to count as liveness for the watchdog")
+			}
+			if cs.lastTickAt.Load() != 0 {
+				t.Error("Expected the update not to count as a tick for the data gap monitor")
+			}
+		})
+	}
+}
+
+// heartbeatClient is a WebSocket client that records subscription heartbeats like saxo-adapter's
+type heartbeatClient struct {
+	saxo.WebSocketClient
+	lastMessage map[string]time.Time
+}
+
+func (c *heartbeatClient) SubscribeToPrices(ctx context.Context, instruments []string) error {
+	return nil
+}
+
+func (c *heartbeatClient) GetLastMessageTimestamp(referenceID string) (time.Time, bool) {
+	at, ok := c.lastMessage[referenceID]
+	return at, ok
+}
+
+func TestLastHeard_CountsSubscriptionHeartbeats(t *testing.T) {
+	client := &heartbeatClient{lastMessage: make(map[string]time.Time)}
+	cs := &CollectorService{
+		ctx:      context.Background(),
+		logger:   log.New(io.Discard, "", 0),
+		wsClient: client,
+		ticks:    newTickTracker(),
+	}
+
+	subscribedAt := time.Now()
+	if err := cs.subscribeToPrices([]string{"EURUSD", "USDJPY"}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	lastTick := subscribedAt.Add(-time.Hour)
+	cs.ticks.touch("EURUSD", lastTick)
+	cs.ticks.touch("USDJPY", lastTick)
+
+	// Saxo's "NoNewData" heartbeat for the subscription only reaches the adapter's timestamps
+	heartbeat := subscribedAt.Add(time.Minute)
+	client.lastMessage["prices-"+subscribedAt.Format("20060102-150405")] = heartbeat
+	client.lastMessage["prices-20200101-000000"] = subscribedAt.Add(time.Hour) // Another subscription
+
+	for _, ticker := range []string{"EURUSD", "USDJPY"} {
+		if got := cs.lastHeard(ticker); !got.Equal(heartbeat) {
+			t.Errorf("Expected %s last heard at the heartbeat %v, got %v", ticker, heartbeat, got)
+		}
+	}
+	if got := cs.lastHeard("GBPUSD"); !got.IsZero() {
+		t.Errorf("Expected an unsubscribed instrument never heard, got %v", got)
+	}
+}
+
+func TestLastHeard_PrefersNewerTick(t *testing.T) {
+	client := &heartbeatClient{lastMessage: make(map[string]time.Time)}
+	cs := &CollectorService{ctx: context.Background(), logger: log.New(io.Discard, "", 0), wsClient: client, ticks: newTickTracker()}
+
+	subscribedAt := time.Now()
+	if err := cs.subscribeToPrices([]string{"EURUSD"}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	client.lastMessage["prices-"+subscribedAt.Format("20060102-150405")] = subscribedAt
+	tick := subscribedAt.Add(time.Minute)
+	cs.ticks.touch("EURUSD", tick)
+
+	if got := cs.lastHeard("EURUSD"); !got.Equal(tick) {
+		t.Errorf("Expected EURUSD last heard at the tick %v, got %v", tick, got)
+	}
+}
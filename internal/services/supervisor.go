@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// giveUpShutdownTimeout bounds the shutdown before the process exits on a component that keeps failing
+const giveUpShutdownTimeout = 10 * time.Second
+
+// RestartPolicy controls how the service's background goroutines are restarted after a panic
+// or an unexpected exit, and when the supervisor gives up
+type RestartPolicy struct {
+	InitialBackoff time.Duration // Delay before the first restart, doubled per failure within Window
+	MaxBackoff     time.Duration // Upper bound for the restart delay
+	MaxRestarts    int           // Restarts allowed per component within Window before giving up
+	Window         time.Duration // Failures older than this are forgotten
+
+	// OnGiveUp is called once a component fails more than MaxRestarts times within Window
+	// nil stops the service and exits the process with status 1, so a process manager
+	// (systemd, Docker, Kubernetes) can restart it from scratch
+	OnGiveUp func(component string, err error)
+}
+
+// DefaultRestartPolicy restarts after 1s, 2s, 4s... (up to 1m) and gives up after 5 failures in 10 minutes
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		MaxRestarts:    5,
+		Window:         10 * time.Minute,
+	}
+}
+
+// Validate checks the policy for consistency
+func (p RestartPolicy) Validate() error {
+	if p.InitialBackoff <= 0 || p.MaxBackoff < p.InitialBackoff {
+		return fmt.Errorf("restart backoff must be positive with max >= initial, got %v / %v", p.InitialBackoff, p.MaxBackoff)
+	}
+	if p.MaxRestarts < 0 || p.Window <= 0 {
+		return fmt.Errorf("restart limit must be >= 0 within a positive window, got %d / %v", p.MaxRestarts, p.Window)
+	}
+	return nil
+}
+
+// WithRestartPolicy overrides DefaultRestartPolicy for the supervised goroutines
+func WithRestartPolicy(policy RestartPolicy) Option {
+	return func(cs *CollectorService) {
+		cs.restartPolicy = policy
+	}
+}
+
+// componentFunc is a supervised goroutine: it returns nil once ctx is done, or an error
+// if it can't continue; panics are recovered and treated as errors
+type componentFunc func(ctx context.Context) error
+
+// unrecoverableError marks a failure a restart can't fix, such as a closed input channel
+type unrecoverableError struct {
+	err error
+}
+
+func (e unrecoverableError) Error() string { return e.err.Error() }
+func (e unrecoverableError) Unwrap() error { return e.err }
+
+// unrecoverable makes the supervisor give up on a component at once instead of restarting it
+func unrecoverable(err error) error {
+	return unrecoverableError{err: err}
+}
+
+// supervise runs fn in a goroutine and restarts it with backoff whenever it panics or fails
+// The returned channel is closed once the component has stopped for good
+func (cs *CollectorService) supervise(name string, fn componentFunc) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var failures []time.Time
+		for {
+			err := cs.runComponent(name, fn)
+			if err == nil || cs.ctx.Err() != nil {
+				return
+			}
+
+			now := time.Now()
+			failures = append(failures, now)
+			for len(failures) > 0 && now.Sub(failures[0]) > cs.restartPolicy.Window {
+				failures = failures[1:]
+			}
+			if errors.As(err, &unrecoverableError{}) || len(failures) > cs.restartPolicy.MaxRestarts {
+				// In the background so done is closed before giveUp stops the service
+				go cs.giveUp(name, err, len(failures))
+				return
+			}
+
+			backoff := min(cs.restartPolicy.InitialBackoff<<(len(failures)-1), cs.restartPolicy.MaxBackoff)
+			if backoff <= 0 { // Shift overflow
+				backoff = cs.restartPolicy.MaxBackoff
+			}
+			cs.logger.Printf("Supervisor: %s failed (%d in %v), restarting in %v: %v",
+				name, len(failures), cs.restartPolicy.Window, backoff, err)
+			event := domain.NewEvent(domain.EventComponentRestarted, domain.SeverityWarning,
+				fmt.Sprintf("%s failed and is restarted in %v: %v", name, backoff, err))
+			event.Fields = map[string]string{"component": name}
+			cs.emit(event)
+
+			select {
+			case <-cs.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+		}
+	}()
+	return done
+}
+
+// superviseLoop supervises a goroutine that only ends when the service stops
+func (cs *CollectorService) superviseLoop(name string, fn func()) {
+	cs.supervise(name, func(ctx context.Context) error {
+		fn()
+		if ctx.Err() == nil {
+			return fmt.Errorf("%s returned unexpectedly", name)
+		}
+		return nil
+	})
+}
+
+// runComponent calls fn, turning a panic into an error
+func (cs *CollectorService) runComponent(name string, fn componentFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cs.logger.Printf("Supervisor: panic in %s: %v\n%s", name, r, debug.Stack())
+			cs.errorBudget.panics.Inc(componentLabel(name))
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil && cs.ctx.Err() == nil {
+			cs.countError(componentLabel(name))
+		}
+	}()
+	return fn(cs.ctx)
+}
+
+// giveUp escalates a component that keeps failing or can't be restarted
+func (cs *CollectorService) giveUp(name string, err error, failures int) {
+	reason := fmt.Sprintf("failed %d times within %v", failures, cs.restartPolicy.Window)
+	if errors.As(err, &unrecoverableError{}) {
+		reason = "failed and can't be restarted"
+	}
+	cs.logger.Printf("Supervisor: ❌ %s %s, giving up: %v", name, reason, err)
+	event := domain.NewEvent(domain.EventComponentFailed, domain.SeverityCritical,
+		fmt.Sprintf("%s %s and was not restarted: %v", name, reason, err))
+	event.Fields = map[string]string{"component": name}
+	// Send synchronously - the process may be about to exit
+	cs.recordOpsAndWait(event)
+	cs.notifyAndWait(event)
+
+	if cs.restartPolicy.OnGiveUp != nil {
+		cs.restartPolicy.OnGiveUp(name, err)
+		return
+	}
+
+	// Shut down as cleanly as the broken component allows, then exit
+	ctx, cancel := context.WithTimeout(context.Background(), giveUpShutdownTimeout)
+	defer cancel()
+	if err := cs.Stop(ctx); err != nil {
+		cs.logger.Printf("Supervisor: shutdown before exit failed: %v", err)
+	}
+	cs.logger.Println("Supervisor: exiting so the process manager restarts the collector")
+	os.Exit(1)
+}
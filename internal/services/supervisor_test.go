@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+// giveUps records the components the supervisor gave up on
+type giveUps struct {
+	mu    sync.Mutex
+	names []string
+	done  chan struct{}
+}
+
+func (g *giveUps) record(component string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.names = append(g.names, component)
+	close(g.done)
+}
+
+func newSupervisedService(t *testing.T, maxRestarts int) (*CollectorService, *giveUps) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	gaveUp := &giveUps{done: make(chan struct{})}
+	cs := &CollectorService{ctx: ctx, logger: log.New(io.Discard, "", 0)}
+	WithMetrics(nopMetrics{})(cs)
+	WithRestartPolicy(RestartPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+		MaxRestarts:    maxRestarts,
+		Window:         time.Minute,
+		OnGiveUp:       gaveUp.record,
+	})(cs)
+	return cs, gaveUp
+}
+
+func waitForGiveUp(t *testing.T, gaveUp *giveUps) {
+	t.Helper()
+	select {
+	case <-gaveUp.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the supervisor to give up")
+	}
+}
+
+func TestSupervise_RestartsWithBackoffAndGivesUp(t *testing.T) {
+	cs, gaveUp := newSupervisedService(t, 3)
+
+	var runs []time.Time
+	done := cs.supervise("flaky", func(ctx context.Context) error {
+		runs = append(runs, time.Now())
+		if len(runs) == 2 {
+			panic("boom")
+		}
+		return errors.New("failed")
+	})
+	<-done
+	waitForGiveUp(t, gaveUp)
+
+	// The first run and MaxRestarts restarts
+	if len(runs) != 4 {
+		t.Fatalf("Expected 4 runs, got %d", len(runs))
+	}
+	for i, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		if delay := runs[i+1].Sub(runs[i]); delay < want {
+			t.Errorf("Expected restart %d after at least %v, got %v", i+1, want, delay)
+		}
+	}
+	if len(gaveUp.names) != 1 || gaveUp.names[0] != "flaky" {
+		t.Errorf("Expected to give up on flaky once, got %v", gaveUp.names)
+	}
+}
+
+func TestSupervise_GivesUpAtOnceWhenUnrecoverable(t *testing.T) {
+	cs, gaveUp := newSupervisedService(t, 5)
+
+	runs := 0
+	done := cs.supervise("price processor", func(ctx context.Context) error {
+		runs++
+		return unrecoverable(errors.New("price channel closed"))
+	})
+	<-done
+	waitForGiveUp(t, gaveUp)
+
+	if runs != 1 {
+		t.Errorf("Expected no restart, got %d runs", runs)
+	}
+}
+
+func TestSupervise_StopsWithTheService(t *testing.T) {
+	cs, gaveUp := newSupervisedService(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cs.ctx = ctx
+
+	started := make(chan struct{})
+	done := cs.supervise("loop", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return errors.New("stopped")
+	})
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the component to stop with the service")
+	}
+	if len(gaveUp.names) != 0 {
+		t.Errorf("Expected no give-up on shutdown, got %v", gaveUp.names)
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// tickQuality sets the quality flags the broker's updates don't carry: subscription snapshots,
+// stale quotes repeated unchanged and quotes received while the market is closed
+type tickQuality struct {
+	mu       sync.Mutex
+	awaiting map[string]bool // Instruments whose next quote is a subscription snapshot
+
+	staleAfter time.Duration         // 0 disables stale flags
+	quotes     map[string]quoteSince // Only used from the price processor goroutine
+}
+
+// quoteSince is an instrument's current quote and when it was first received
+type quoteSince struct {
+	bid, ask float64
+	since    time.Time
+}
+
+func newTickQuality() *tickQuality {
+	return &tickQuality{awaiting: make(map[string]bool), quotes: make(map[string]quoteSince)}
+}
+
+// WithStaleQuotes flags ticks repeating the instrument's quote unchanged for longer than after (0 disables)
+func WithStaleQuotes(after time.Duration) Option {
+	return func(cs *CollectorService) {
+		cs.quality.staleAfter = after
+	}
+}
+
+// expectSnapshots marks the next quote of each ticker as the snapshot of a new subscription
+func (q *tickQuality) expectSnapshots(tickers []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, ticker := range tickers {
+		q.awaiting[ticker] = true
+	}
+}
+
+// snapshot reports whether a quote for ticker is the snapshot of a new subscription, once per subscription
+func (q *tickQuality) snapshot(ticker string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.awaiting[ticker] {
+		return false
+	}
+	delete(q.awaiting, ticker)
+	return true
+}
+
+// stale reports whether a tick repeats the instrument's quote from longer than staleAfter ago
+func (q *tickQuality) stale(p *domain.PriceData) bool {
+	if q.staleAfter <= 0 {
+		return false
+	}
+	previous, ok := q.quotes[p.Ticker]
+	if ok && previous.bid == p.Bid && previous.ask == p.Ask {
+		return p.Timestamp.Sub(previous.since) > q.staleAfter
+	}
+	q.quotes[p.Ticker] = quoteSince{bid: p.Bid, ask: p.Ask, since: p.Timestamp}
+	return false
+}
+
+// flagQuality sets a tick's snapshot, stale and indicative flags
+// Only called from the price processor goroutine
+func (cs *CollectorService) flagQuality(p *domain.PriceData) {
+	if cs.quality.snapshot(p.Ticker) {
+		p.Flags |= domain.FlagSnapshot
+	}
+	if cs.quality.stale(p) {
+		p.Flags |= domain.FlagStale
+	}
+	if !cs.holidays.IsOpen(p.Ticker, p.Timestamp) {
+		p.Flags |= domain.FlagIndicative
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// QuotaAction is what happens once a daily quota is used up
+type QuotaAction string
+
+const (
+	QuotaActionAlert  QuotaAction = "alert"  // Alert only, keep recording
+	QuotaActionSample QuotaAction = "sample" // Record only every Nth tick for the rest of the day
+	QuotaActionPause  QuotaAction = "pause"  // Stop recording for the rest of the day
+)
+
+// QuotaConfig caps what is recorded per UTC day, per instrument and overall (0 disables a limit)
+// Bytes are counted as RowSize reports them, e.g. as written to the CSV files
+type QuotaConfig struct {
+	InstrumentTicks int64
+	InstrumentBytes int64
+	TotalTicks      int64
+	TotalBytes      int64
+	Action          QuotaAction // Applied to the instrument, or all instruments, whose quota is used up
+	SampleRate      int         // Record every Nth tick when Action is sample
+
+	RowSize func(*domain.PriceData) int // Bytes a tick takes on disk; needed by the byte limits
+}
+
+// Enabled reports whether any limit is set
+func (c QuotaConfig) Enabled() bool {
+	return c.InstrumentTicks > 0 || c.InstrumentBytes > 0 || c.TotalTicks > 0 || c.TotalBytes > 0
+}
+
+// Validate checks that the configured action is known
+func (c QuotaConfig) Validate() error {
+	switch c.Action {
+	case QuotaActionAlert, QuotaActionSample, QuotaActionPause:
+	default:
+		return fmt.Errorf("unknown quota action: %s", c.Action)
+	}
+	if c.Action == QuotaActionSample && c.SampleRate < 2 {
+		return fmt.Errorf("quota sample rate must be at least 2, got %d", c.SampleRate)
+	}
+	if c.InstrumentTicks < 0 || c.InstrumentBytes < 0 || c.TotalTicks < 0 || c.TotalBytes < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	if (c.InstrumentBytes > 0 || c.TotalBytes > 0) && c.RowSize == nil {
+		return fmt.Errorf("byte quotas need a row size")
+	}
+	return nil
+}
+
+// quotaUsage counts what one scope (an instrument or the total) recorded today
+type quotaUsage struct {
+	ticks    int64
+	bytes    int64
+	exceeded bool
+	skipped  int64 // Ticks seen since the quota was used up (sample action)
+}
+
+// tickQuota tracks today's usage; only touched by the price processor goroutine
+type tickQuota struct {
+	cfg      QuotaConfig
+	day      time.Time
+	total    quotaUsage
+	byTicker map[string]*quotaUsage
+}
+
+// WithTickQuota limits the ticks and bytes recorded per day, so a misbehaving feed can't fill a small disk
+func WithTickQuota(cfg QuotaConfig) Option {
+	return func(cs *CollectorService) {
+		cs.quota = &tickQuota{cfg: cfg, byTicker: make(map[string]*quotaUsage)}
+	}
+}
+
+// admitQuota counts a tick against today's quotas and applies the action once one is used up
+// Ticks sampled after the quota ran out carry FlagSampled
+// Only called from the price processor goroutine
+func (cs *CollectorService) admitQuota(priceData *domain.PriceData) bool {
+	q := cs.quota
+	if q == nil {
+		return true
+	}
+
+	if day := priceData.Timestamp.UTC().Truncate(24 * time.Hour); day.After(q.day) {
+		if q.total.exceeded || q.anyExceeded() {
+			cs.logger.Printf("Quota: new day %s, quotas reset", day.Format(time.DateOnly))
+		}
+		q.day = day
+		q.total = quotaUsage{}
+		clear(q.byTicker)
+	}
+
+	usage, ok := q.byTicker[priceData.Ticker]
+	if !ok {
+		usage = &quotaUsage{}
+		q.byTicker[priceData.Ticker] = usage
+	}
+
+	if scope := q.exceededScope(usage); scope != nil {
+		switch q.cfg.Action {
+		case QuotaActionPause:
+			return false
+		case QuotaActionSample:
+			scope.skipped++
+			if scope.skipped%int64(q.cfg.SampleRate) != 0 {
+				return false
+			}
+			priceData.Flags |= domain.FlagSampled
+		}
+	}
+
+	size := int64(0)
+	if q.cfg.InstrumentBytes > 0 || q.cfg.TotalBytes > 0 {
+		size = int64(q.cfg.RowSize(priceData))
+	}
+	usage.ticks++
+	usage.bytes += size
+	q.total.ticks++
+	q.total.bytes += size
+
+	if !usage.exceeded && over(usage, q.cfg.InstrumentTicks, q.cfg.InstrumentBytes) {
+		usage.exceeded = true
+		cs.quotaExceeded(priceData.Ticker, usage, q.cfg.InstrumentTicks, q.cfg.InstrumentBytes)
+	}
+	if !q.total.exceeded && over(&q.total, q.cfg.TotalTicks, q.cfg.TotalBytes) {
+		q.total.exceeded = true
+		cs.quotaExceeded("", &q.total, q.cfg.TotalTicks, q.cfg.TotalBytes)
+	}
+	return true
+}
+
+// exceededScope returns the usage whose quota is used up, preferring the instrument's, or nil
+func (q *tickQuota) exceededScope(usage *quotaUsage) *quotaUsage {
+	switch {
+	case usage.exceeded:
+		return usage
+	case q.total.exceeded:
+		return &q.total
+	default:
+		return nil
+	}
+}
+
+// anyExceeded reports whether any instrument used up its quota today
+func (q *tickQuota) anyExceeded() bool {
+	for _, usage := range q.byTicker {
+		if usage.exceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// over reports whether usage has reached a tick or byte limit (0 disables a limit)
+func over(usage *quotaUsage, ticks, bytes int64) bool {
+	return ticks > 0 && usage.ticks >= ticks || bytes > 0 && usage.bytes >= bytes
+}
+
+// quotaExceeded reports a used-up quota; ticker "" is the total
+func (cs *CollectorService) quotaExceeded(ticker string, usage *quotaUsage, ticks, bytes int64) {
+	action := cs.quota.cfg.Action
+	scope := ticker
+	if scope == "" {
+		scope = "all instruments"
+	}
+	msg := fmt.Sprintf("Daily quota used up for %s: %d ticks, %d MB recorded, action=%s",
+		scope, usage.ticks, usage.bytes/(1024*1024), action)
+	cs.logger.Println(msg)
+
+	event := domain.NewEvent(domain.EventQuotaExceeded, domain.SeverityWarning, msg)
+	event.Ticker = ticker
+	event.Fields = map[string]string{
+		"ticks":       fmt.Sprintf("%d", usage.ticks),
+		"bytes":       fmt.Sprintf("%d", usage.bytes),
+		"tick_limit":  fmt.Sprintf("%d", ticks),
+		"bytes_limit": fmt.Sprintf("%d", bytes),
+		"action":      string(action),
+	}
+	cs.emit(event)
+}
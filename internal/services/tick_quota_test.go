@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestAdmitQuota_PausesUntilNextDay(t *testing.T) {
+	cs := newGatedService(map[string]Instrument{"EURUSD": {Ticker: "EURUSD"}, "USDJPY": {Ticker: "USDJPY"}})
+	WithTickQuota(QuotaConfig{InstrumentTicks: 3, Action: QuotaActionPause})(cs)
+
+	day := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	admitted := 0
+	for range 5 {
+		if cs.admitQuota(&domain.PriceData{Timestamp: day, Ticker: "EURUSD"}) {
+			admitted++
+		}
+	}
+	if admitted != 3 {
+		t.Errorf("Expected 3 ticks within the quota, got %d", admitted)
+	}
+	if !cs.admitQuota(&domain.PriceData{Timestamp: day, Ticker: "USDJPY"}) {
+		t.Error("Expected another instrument to keep its own quota")
+	}
+	if !cs.admitQuota(&domain.PriceData{Timestamp: day.Add(12 * time.Hour), Ticker: "EURUSD"}) {
+		t.Error("Expected the quota to reset on the next UTC day")
+	}
+}
+
+func TestAdmitQuota_SamplesTotal(t *testing.T) {
+	cs := newGatedService(map[string]Instrument{"EURUSD": {Ticker: "EURUSD"}})
+	WithTickQuota(QuotaConfig{TotalTicks: 2, Action: QuotaActionSample, SampleRate: 3})(cs)
+
+	day := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	var admitted []*domain.PriceData
+	for range 8 {
+		tick := &domain.PriceData{Timestamp: day, Ticker: "EURUSD"}
+		if cs.admitQuota(tick) {
+			admitted = append(admitted, tick)
+		}
+	}
+	// 2 within the quota, then every 3rd of the remaining 6
+	if len(admitted) != 4 {
+		t.Fatalf("Expected 4 admitted ticks, got %d", len(admitted))
+	}
+	if admitted[1].Flags.Has(domain.FlagSampled) || !admitted[2].Flags.Has(domain.FlagSampled) {
+		t.Error("Expected only ticks sampled after the quota ran out to be flagged")
+	}
+}
+
+func TestQuotaConfig_Validate(t *testing.T) {
+	for _, cfg := range []QuotaConfig{
+		{Action: "drop"},
+		{Action: QuotaActionSample, SampleRate: 1},
+		{Action: QuotaActionAlert, TotalTicks: -1},
+		{Action: QuotaActionAlert, TotalBytes: 1024}, // No row size
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", cfg)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// tickRateLimiter caps the recorded ticks per second of instruments with a MaxTickRate
+// Buckets follow tick timestamps; only touched by the price processor goroutine
+type tickRateLimiter struct {
+	buckets   map[string]*domain.TokenBucket
+	throttled map[string]bool // Ticks were dropped since the last recorded one
+}
+
+// newTickRateLimiter returns a limiter for the instruments with a MaxTickRate, or nil if there are none
+// Bursts of up to one second's worth of ticks (at least one) pass unthrottled
+func newTickRateLimiter(instruments map[string]Instrument) *tickRateLimiter {
+	limiter := &tickRateLimiter{
+		buckets:   make(map[string]*domain.TokenBucket),
+		throttled: make(map[string]bool),
+	}
+	for ticker, inst := range instruments {
+		if inst.MaxTickRate > 0 {
+			limiter.buckets[ticker] = domain.NewTokenBucket(inst.MaxTickRate, max(inst.MaxTickRate, 1))
+		}
+	}
+	if len(limiter.buckets) == 0 {
+		return nil
+	}
+	return limiter
+}
+
+// limitTickRate reports whether a tick fits its instrument's rate limit
+// The first tick recorded after throttled ones carries FlagSampled
+// Only called from the price processor goroutine
+func (cs *CollectorService) limitTickRate(priceData *domain.PriceData) bool {
+	if cs.rateLimiter == nil {
+		return true
+	}
+	bucket, ok := cs.rateLimiter.buckets[priceData.Ticker]
+	if !ok {
+		return true
+	}
+
+	if !bucket.Allow(priceData.Timestamp) {
+		cs.rateLimiter.throttled[priceData.Ticker] = true
+		cs.throttledTicks.Inc(priceData.Ticker)
+		return false
+	}
+	if cs.rateLimiter.throttled[priceData.Ticker] {
+		cs.rateLimiter.throttled[priceData.Ticker] = false
+		priceData.Flags |= domain.FlagSampled
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestLimitTickRate(t *testing.T) {
+	instruments := map[string]Instrument{
+		"EURUSD": {Ticker: "EURUSD", MaxTickRate: 2},
+		"USDJPY": {Ticker: "USDJPY"},
+	}
+	cs := newGatedService(instruments)
+	cs.rateLimiter = newTickRateLimiter(instruments)
+
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	var recorded []*domain.PriceData
+	for i := range 5 { // 5 ticks within 100ms: a burst of 2 passes
+		tick := &domain.PriceData{Timestamp: start.Add(time.Duration(i) * 20 * time.Millisecond), Ticker: "EURUSD"}
+		if cs.limitTickRate(tick) {
+			recorded = append(recorded, tick)
+		}
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("Expected 2 ticks within the burst, got %d", len(recorded))
+	}
+
+	// A second later tokens are back; the first tick after throttling is flagged
+	tick := &domain.PriceData{Timestamp: start.Add(time.Second), Ticker: "EURUSD"}
+	if !cs.limitTickRate(tick) || !tick.Flags.Has(domain.FlagSampled) {
+		t.Errorf("Expected a recorded tick flagged as sampled, got flags %v", tick.Flags)
+	}
+
+	for range 10 {
+		if !cs.limitTickRate(&domain.PriceData{Timestamp: start, Ticker: "USDJPY"}) {
+			t.Fatal("Expected an instrument without MaxTickRate not to be limited")
+		}
+	}
+}
+
+func TestNewTickRateLimiter_NoLimits(t *testing.T) {
+	if limiter := newTickRateLimiter(map[string]Instrument{"EURUSD": {Ticker: "EURUSD"}}); limiter != nil {
+		t.Error("Expected no limiter without a MaxTickRate")
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Tunables are the settings that can be changed while the service runs, without reconnecting
+// or resubscribing; everything else needs a restart
+type Tunables struct {
+	FlushInterval          time.Duration // Flush interval of sinks without their own
+	TraceSampleRate        int           // Trace every Nth price update (only if tracing is enabled)
+	DataGapThreshold       time.Duration // No price updates for this long is a data gap
+	SubscriptionStaleAfter time.Duration // Re-subscribe an instrument without ticks for this long
+	SampleInterval         time.Duration // Burst mode: record one tick per instrument per interval outside bursts (0 = all)
+	BurstAnomalyWindow     time.Duration // Burst mode: full capture for this long after an anomaly (0 disables)
+}
+
+// Tunables returns the current runtime settings
+func (cs *CollectorService) Tunables() Tunables {
+	return cs.currentTunables()
+}
+
+// Reload applies new runtime settings and returns a description of each change
+// Monitors that were disabled at start (threshold 0) can't be switched on or off, nor can sampling
+// without WithBurstMode; that and invalid values fail the whole reload, leaving every setting as it was
+func (cs *CollectorService) Reload(t Tunables) ([]string, error) {
+	cs.tunablesMu.Lock()
+	old := cs.tunables
+	if err := validateTunables(old, t, cs.burst != nil); err != nil {
+		cs.tunablesMu.Unlock()
+		return nil, err
+	}
+	cs.tunables = t
+	close(cs.tunablesChanged)
+	cs.tunablesChanged = make(chan struct{})
+	cs.tunablesMu.Unlock()
+
+	var changes []string
+	describe := func(name string, from, to any) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s %v -> %v", name, from, to))
+		}
+	}
+	describe("flush interval", old.FlushInterval, t.FlushInterval)
+	describe("trace sample rate", old.TraceSampleRate, t.TraceSampleRate)
+	describe("data gap threshold", old.DataGapThreshold, t.DataGapThreshold)
+	describe("subscription stale after", old.SubscriptionStaleAfter, t.SubscriptionStaleAfter)
+	describe("sample interval", old.SampleInterval, t.SampleInterval)
+	describe("burst anomaly window", old.BurstAnomalyWindow, t.BurstAnomalyWindow)
+
+	if len(changes) > 0 {
+		cs.logger.Printf("Settings reloaded: %v", changes)
+		event := domain.NewEvent(domain.EventConfigReloaded, domain.SeverityInfo,
+			fmt.Sprintf("Settings reloaded: %v", changes))
+		cs.recordOps(event)
+	}
+	return changes, nil
+}
+
+// validateTunables checks t as a replacement for the running settings old; burst tells whether
+// the service runs with burst mode
+func validateTunables(old, t Tunables, burst bool) error {
+	if t.FlushInterval <= 0 {
+		return fmt.Errorf("flush interval must be positive, got %v", t.FlushInterval)
+	}
+	if t.TraceSampleRate < 1 {
+		return fmt.Errorf("trace sample rate must be at least 1, got %d", t.TraceSampleRate)
+	}
+	if (old.DataGapThreshold > 0) != (t.DataGapThreshold > 0) {
+		return fmt.Errorf("data gap detection can't be switched on or off at runtime (%v -> %v), restart instead",
+			old.DataGapThreshold, t.DataGapThreshold)
+	}
+	if (old.SubscriptionStaleAfter > 0) != (t.SubscriptionStaleAfter > 0) {
+		return fmt.Errorf("the subscription watchdog can't be switched on or off at runtime (%v -> %v), restart instead",
+			old.SubscriptionStaleAfter, t.SubscriptionStaleAfter)
+	}
+	if err := (BurstConfig{SampleInterval: t.SampleInterval, AnomalyWindow: t.BurstAnomalyWindow}).Validate(); err != nil {
+		return err
+	}
+	if !burst && t.SampleInterval > 0 {
+		return fmt.Errorf("sampling can't be switched on without burst mode (sample interval %v), restart instead", t.SampleInterval)
+	}
+	return nil
+}
+
+// currentTunables returns a copy of the runtime settings
+func (cs *CollectorService) currentTunables() Tunables {
+	cs.tunablesMu.RLock()
+	defer cs.tunablesMu.RUnlock()
+	return cs.tunables
+}
+
+// tunablesUpdates returns a channel that is closed by the next Reload
+func (cs *CollectorService) tunablesUpdates() <-chan struct{} {
+	cs.tunablesMu.RLock()
+	defer cs.tunablesMu.RUnlock()
+	return cs.tunablesChanged
+}
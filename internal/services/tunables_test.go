@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	cs := newGatedService(nil)
+	cs.tunables = Tunables{FlushInterval: time.Second, TraceSampleRate: 1, DataGapThreshold: 5 * time.Minute}
+	cs.tunablesChanged = make(chan struct{})
+	updates := cs.tunablesUpdates()
+
+	changed := cs.Tunables()
+	changed.FlushInterval = 5 * time.Second
+	changes, err := cs.Reload(changed)
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if len(changes) != 1 || cs.Tunables().FlushInterval != 5*time.Second {
+		t.Errorf("Expected the flush interval change, got %v", changes)
+	}
+	select {
+	case <-updates:
+	default:
+		t.Error("Expected the reload to be announced")
+	}
+
+	// Invalid: leaves every setting as it was
+	for _, modify := range []func(*Tunables){
+		func(t *Tunables) { t.FlushInterval = 0 },
+		func(t *Tunables) { t.DataGapThreshold = 0 },                 // Can't switch the monitor off
+		func(t *Tunables) { t.SubscriptionStaleAfter = time.Minute }, // Nor the watchdog on
+	} {
+		invalid := cs.Tunables()
+		modify(&invalid)
+		if _, err := cs.Reload(invalid); err == nil {
+			t.Errorf("Expected %+v to be refused", invalid)
+		}
+	}
+	if cs.Tunables() != changed {
+		t.Errorf("Expected the settings to stay %+v, got %+v", changed, cs.Tunables())
+	}
+}
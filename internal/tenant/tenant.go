@@ -0,0 +1,61 @@
+// Package tenant separates the data of collectors running side by side for different accounts
+// (TENANT), the same way for the collector and the tools reading or writing its data
+package tenant
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bjoelf/fx-collector/internal/toolconfig"
+)
+
+// pattern matches the tenant names usable as a path segment, topic level and name suffix ("" for none)
+var pattern = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+
+// Validate checks that name can be used as a tenant
+func Validate(name string) error {
+	if !pattern.MatchString(name) {
+		return fmt.Errorf("invalid TENANT '%s': use letters, digits, '-' and '_' only", name)
+	}
+	return nil
+}
+
+// FromEnv returns the tenant set with FXC_TENANT or TENANT, empty for none
+func FromEnv() (string, error) {
+	name := toolconfig.GetEnv("TENANT", "")
+	return name, Validate(name)
+}
+
+// Path inserts the tenant directory before the last element of path (data/spreads ->
+// data/acme/spreads); empty paths and stdout ("-") are returned as they are
+func Path(name, path string) string {
+	if name == "" || path == "" || path == "-" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(path), name, filepath.Base(path))
+}
+
+// Object suffixes a database object name with the tenant (fx_ticks -> fx_ticks_acme), with '-'
+// replaced as BigQuery datasets and QuestDB tables don't allow it
+func Object(name, object string) string {
+	if name == "" || object == "" {
+		return object
+	}
+	return object + "_" + strings.ReplaceAll(name, "-", "_")
+}
+
+// SinkDefaults returns the defaults the collector gives the sinks the tools read or write, taken
+// from the same settings, with the tenant applied
+func SinkDefaults(name string) map[string]url.Values {
+	return map[string]url.Values{
+		"csv":         {"dir": {Path(name, toolconfig.GetEnv("SPREAD_RECORDING_DIR", "data/spreads"))}},
+		"ndjson":      {"output": {Path(name, toolconfig.GetEnv("NDJSON_OUTPUT", "-"))}},
+		"arrow":       {"dir": {Path(name, toolconfig.GetEnv("ARROW_DIR", "data/arrow"))}},
+		"questdb":     {"table": {Object(name, toolconfig.GetEnv("QUESTDB_TABLE", "fx_ticks"))}},
+		"bigquery":    {"dataset": {Object(name, toolconfig.GetEnv("BIGQUERY_DATASET", ""))}},
+		"remotewrite": {"tenant": {name}},
+	}
+}
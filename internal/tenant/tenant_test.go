@@ -0,0 +1,58 @@
+package tenant
+
+import "testing"
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		tenant, path, want string
+	}{
+		{"acme", "data/spreads", "data/acme/spreads"},
+		{"acme", "/var/lib/fxc/state.db", "/var/lib/fxc/acme/state.db"},
+		{"acme", "-", "-"},
+		{"acme", "", ""},
+		{"", "data/spreads", "data/spreads"},
+	}
+	for _, tt := range tests {
+		if got := Path(tt.tenant, tt.path); got != tt.want {
+			t.Errorf("Path(%q, %q) = %q, want %q", tt.tenant, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestObject(t *testing.T) {
+	if got := Object("live-main", "fx_ticks"); got != "fx_ticks_live_main" {
+		t.Errorf("Expected fx_ticks_live_main, got %q", got)
+	}
+	if got := Object("", "fx_ticks"); got != "fx_ticks" {
+		t.Errorf("Expected the name unchanged without a tenant, got %q", got)
+	}
+	if got := Object("acme", ""); got != "" {
+		t.Errorf("Expected an unset name to stay unset, got %q", got)
+	}
+}
+
+func TestSinkDefaults(t *testing.T) {
+	t.Setenv("FXC_SPREAD_RECORDING_DIR", "/srv/spreads")
+	t.Setenv("QUESTDB_TABLE", "ticks")
+	defaults := SinkDefaults("acme")
+
+	if got := defaults["csv"].Get("dir"); got != "/srv/acme/spreads" {
+		t.Errorf("Expected the tenant's CSV directory, got %q", got)
+	}
+	if got := defaults["ndjson"].Get("output"); got != "-" {
+		t.Errorf("Expected stdout left alone, got %q", got)
+	}
+	if got := defaults["questdb"].Get("table"); got != "ticks_acme" {
+		t.Errorf("Expected the tenant's table, got %q", got)
+	}
+	if got := defaults["remotewrite"].Get("tenant"); got != "acme" {
+		t.Errorf("Expected the tenant label, got %q", got)
+	}
+}
+
+func TestFromEnv_RejectsUnusableNames(t *testing.T) {
+	t.Setenv("TENANT", "acme/live")
+	if _, err := FromEnv(); err == nil {
+		t.Error("Expected a tenant with a path separator to be rejected")
+	}
+}
@@ -0,0 +1,98 @@
+// Package toolconfig holds the settings, instruments file and sink helpers the cmd tools share,
+// so each tool reads them the same way as the collector
+package toolconfig
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+)
+
+// EnvPrefix namespaces collector settings (FXC_SPREAD_RECORDERS)
+// Unprefixed names are still read; the prefixed name wins when both are set
+const EnvPrefix = "FXC_"
+
+// GetEnv gets environment variable FXC_<key> or <key> with default value
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(EnvPrefix + key); value != "" {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Instrument is the part of an instruments file entry the tools writing ticks use
+type Instrument struct {
+	Ticker    string  `json:"ticker"`
+	Uic       int     `json:"uic"`
+	AssetType string  `json:"assetType"`
+	Decimals  int     `json:"decimals"` // 0 = unknown, left to the tool to find out
+	MaxSpread float64 `json:"maxSpread,omitempty"`
+}
+
+// ReadInstruments reads the instruments file, keeping only the given comma-separated tickers if any
+func ReadInstruments(path, tickers string) ([]Instrument, error) {
+	var instruments []Instrument
+	if err := storage.ReadInstruments(path, &instruments); err != nil {
+		return nil, err
+	}
+	if tickers == "" {
+		return instruments, nil
+	}
+
+	var selected []Instrument
+	for _, ticker := range strings.Split(tickers, ",") {
+		ticker = strings.TrimSpace(ticker)
+		i := slices.IndexFunc(instruments, func(inst Instrument) bool { return strings.EqualFold(inst.Ticker, ticker) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown ticker %s in %s", ticker, path)
+		}
+		selected = append(selected, instruments[i])
+	}
+	return selected, nil
+}
+
+// CreateRecorders builds the sinks of a -recorders list with defaults (tenant.SinkDefaults),
+// refusing directories tagged with another environment
+func CreateRecorders(definitions string, defaults map[string]url.Values) (*storage.MultiRecorder, error) {
+	env, explicitEnv, err := storage.ParseEnvironment(os.Getenv("SAXO_ENVIRONMENT"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sinks []ports.TickWriter
+	for _, definition := range strings.Split(definitions, ",") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		spec, err := storage.ParseRecorderSpec(definition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -recorders: %w", err)
+		}
+		spec = spec.WithDefaults(defaults[spec.Name])
+		// Same check as the collector: never mix sim and live data
+		for s := &spec; s != nil; s = s.Fallback {
+			if dir := s.Param("dir", ""); dir != "" && (s.Name == "csv" || s.Name == "arrow") {
+				if err := storage.TagEnvironment(dir, env, explicitEnv); err != nil {
+					return nil, err
+				}
+			}
+		}
+		sink, err := storage.NewRecorder(spec)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("no recorders configured")
+	}
+	return storage.NewMultiRecorder(sinks...), nil
+}
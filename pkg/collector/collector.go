@@ -0,0 +1,318 @@
+// Package collector runs the FX spread collector inside another Go program
+// It wires the same service cmd/collector runs to a broker and sinks the caller provides:
+//
+//	c, err := collector.New(
+//		collector.WithBroker(authClient, brokerClient),
+//		collector.WithRecorder(recorder),
+//		collector.WithInstruments(collector.Instrument{Ticker: "EURUSD", Uic: 21, AssetType: "FxSpot", Decimals: 5}),
+//	)
+//	if err != nil { ... }
+//	if err := c.Start(); err != nil { ... }
+//	defer c.Stop(context.Background())
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/services"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bjoelf/fx-collector/pkg/ports"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// Instrument is an instrument to subscribe to; PipSize defaults to domain.DefaultPipSize
+type Instrument = services.Instrument
+
+// RestartPolicy controls how failed background goroutines are restarted; see WithRestartPolicy
+type RestartPolicy = services.RestartPolicy
+
+// Tunables are the settings Reload can change while the collector runs
+type Tunables = services.Tunables
+
+// DefaultFlushInterval is how often sinks are flushed unless WithFlushInterval says otherwise
+const DefaultFlushInterval = 30 * time.Second
+
+// giveUpStopTimeout bounds the shutdown when the collector stops on its own
+const giveUpStopTimeout = 10 * time.Second
+
+// config collects the options until New builds the service
+type config struct {
+	authClient    saxo.AuthClient
+	brokerClient  saxo.BrokerClient
+	recorder      ports.TickWriter
+	instruments   map[string]Instrument
+	flushInterval time.Duration
+	logger        *log.Logger
+	restartPolicy RestartPolicy
+	serviceOpts   []services.Option
+}
+
+// Option configures a Collector
+type Option func(*config)
+
+// WithBroker sets the Saxo clients ticks are streamed from (required)
+// saxo.CreateSaxoAuthClient and saxo.CreateBrokerServices create them from the usual environment
+func WithBroker(authClient saxo.AuthClient, brokerClient saxo.BrokerClient) Option {
+	return func(c *config) {
+		c.authClient = authClient
+		c.brokerClient = brokerClient
+	}
+}
+
+// WithRecorder sets the sink every recorded tick is written to (required)
+// Use several sinks by passing a writer that fans out, as cmd/collector does for RECORDERS
+func WithRecorder(recorder ports.TickWriter) Option {
+	return func(c *config) {
+		c.recorder = recorder
+	}
+}
+
+// WithInstruments adds instruments to subscribe to (at least one is required)
+func WithInstruments(instruments ...Instrument) Option {
+	return func(c *config) {
+		for _, inst := range instruments {
+			c.instruments[inst.Ticker] = inst
+		}
+	}
+}
+
+// WithFlushInterval sets how often the recorder is flushed (default 30s)
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.flushInterval = d
+	}
+}
+
+// WithLogger sets the logger (default: stdout with an [FX-COLLECTOR] prefix)
+func WithLogger(logger *log.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithRestartPolicy overrides how panicking or failing goroutines are restarted (default: 1s backoff
+// doubling to 1m, at most 5 restarts per component in 10 minutes)
+// Unlike cmd/collector, a component that keeps failing doesn't exit the process by default:
+// the collector stops and Err reports why. Set OnGiveUp to handle it yourself
+func WithRestartPolicy(policy RestartPolicy) Option {
+	return func(c *config) {
+		c.restartPolicy = policy
+	}
+}
+
+// WithProcessor runs every tick through p before it is recorded; nil results are dropped
+func WithProcessor(p ports.TickProcessor) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithProcessor(p))
+	}
+}
+
+// WithNotifier sends data-gap, disconnect and similar alerts to n
+func WithNotifier(n ports.Notifier) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithNotifier(n))
+	}
+}
+
+// WithSnapshots writes a regular-grid snapshot of the latest quotes every interval
+func WithSnapshots(interval time.Duration, writer ports.SnapshotWriter) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithSnapshots(interval, writer))
+	}
+}
+
+// WithSnapshotMaxAge sets how long the snapshots carry an instrument's last quote forward without
+// ticks (default 5m, 0 = while its market is open)
+func WithSnapshotMaxAge(maxAge time.Duration) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithSnapshotMaxAge(maxAge))
+	}
+}
+
+// WithSequenceStore keeps per-instrument sequence numbers increasing across restarts
+func WithSequenceStore(store ports.SequenceStore) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithSequenceStore(store))
+	}
+}
+
+// WithStateStore keeps sequence numbers, the last recorded tick per instrument and recording gaps in a
+// state store (replaces WithSequenceStore)
+func WithStateStore(store ports.StateStore) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithStateStore(store))
+	}
+}
+
+// WithSessions labels ticks with the trading sessions open at their timestamp
+func WithSessions(sessions []domain.Session) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithSessions(sessions))
+	}
+}
+
+// WithRolloverFlags flags ticks inside the daily rollover window
+func WithRolloverFlags(rollover *domain.RolloverCalendar) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithRolloverFlags(rollover))
+	}
+}
+
+// WithConflation records only the last quote per instrument per window
+func WithConflation(window time.Duration) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithConflation(window))
+	}
+}
+
+// WithCrossedQuotes sets the spread recorded for crossed quotes (default domain.CrossedQuoteKeep)
+func WithCrossedQuotes(policy domain.CrossedQuotePolicy) Option {
+	return func(c *config) {
+		c.serviceOpts = append(c.serviceOpts, services.WithCrossedQuotes(policy))
+	}
+}
+
+var _ ports.RecordingControl = (*Collector)(nil)
+
+// Collector is an embedded collector; Start and Stop may each be called once
+type Collector struct {
+	service *services.CollectorService
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	err     error // Why the collector stopped on its own
+}
+
+// New validates the options and creates a collector that is ready to Start
+func New(opts ...Option) (*Collector, error) {
+	c := &config{
+		instruments:   make(map[string]Instrument),
+		flushInterval: DefaultFlushInterval,
+		restartPolicy: services.DefaultRestartPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.authClient == nil || c.brokerClient == nil {
+		return nil, fmt.Errorf("a broker is required (WithBroker)")
+	}
+	if c.recorder == nil {
+		return nil, fmt.Errorf("a recorder is required (WithRecorder)")
+	}
+	if len(c.instruments) == 0 {
+		return nil, fmt.Errorf("at least one instrument is required (WithInstruments)")
+	}
+	if c.flushInterval <= 0 {
+		return nil, fmt.Errorf("flush interval must be positive, got %v", c.flushInterval)
+	}
+	if c.logger == nil {
+		c.logger = log.New(os.Stdout, "[FX-COLLECTOR] ", log.LstdFlags|log.Lmsgprefix)
+	}
+
+	for ticker, inst := range c.instruments {
+		if inst.Ticker == "" || inst.Uic == 0 {
+			return nil, fmt.Errorf("instrument %q needs a ticker and a uic", ticker)
+		}
+		if inst.PipSize <= 0 {
+			inst.PipSize = domain.DefaultPipSize(inst.Ticker)
+		}
+		if inst.SpreadUnit == "" {
+			inst.SpreadUnit = domain.SpreadUnitPrice
+		}
+		c.instruments[ticker] = inst
+	}
+
+	collector := &Collector{}
+	if c.restartPolicy.OnGiveUp == nil {
+		c.restartPolicy.OnGiveUp = collector.giveUp
+	}
+	serviceOpts := append(c.serviceOpts, services.WithRestartPolicy(c.restartPolicy))
+
+	service, err := services.NewCollectorService(
+		c.authClient,
+		c.brokerClient,
+		c.instruments,
+		c.recorder,
+		c.flushInterval,
+		c.logger,
+		serviceOpts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collector service: %w", err)
+	}
+	collector.service = service
+	return collector, nil
+}
+
+// Start authenticates, connects and subscribes; ticks are recorded in the background until Stop
+func (c *Collector) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return fmt.Errorf("collector already started")
+	}
+	c.started = true
+	return c.service.Start()
+}
+
+// Stop flushes and closes the recorder and disconnects; later calls do nothing
+// Slow steps are cut short at ctx's deadline; the error then wraps ctx's error
+func (c *Collector) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started || c.stopped {
+		return nil
+	}
+	c.stopped = true
+	return c.service.Stop(ctx)
+}
+
+// giveUp stops the collector after a component failed too often
+func (c *Collector) giveUp(component string, err error) {
+	c.mu.Lock()
+	c.err = fmt.Errorf("%s kept failing: %w", component, err)
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), giveUpStopTimeout)
+	defer cancel()
+	c.Stop(ctx)
+}
+
+// Err returns why the collector stopped on its own, or nil while it runs or after Stop
+func (c *Collector) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// PauseRecording stops recording ticker, or all instruments if ticker is "", while staying subscribed
+func (c *Collector) PauseRecording(ticker string) error {
+	return c.service.PauseRecording(ticker)
+}
+
+// ResumeRecording resumes recording ticker, or all instruments if ticker is ""
+func (c *Collector) ResumeRecording(ticker string) error {
+	return c.service.ResumeRecording(ticker)
+}
+
+// RecordingState returns the current manual and scheduled pauses
+func (c *Collector) RecordingState() domain.RecordingState {
+	return c.service.RecordingState()
+}
+
+// Tunables returns the current runtime settings
+func (c *Collector) Tunables() Tunables {
+	return c.service.Tunables()
+}
+
+// Reload changes the runtime settings without reconnecting and returns a description of each change
+func (c *Collector) Reload(t Tunables) ([]string, error) {
+	return c.service.Reload(t)
+}
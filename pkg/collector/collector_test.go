@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	saxo "github.com/bjoelf/saxo-adapter/adapter"
+)
+
+// Stand-ins that satisfy the broker interfaces; New fails before calling them
+type (
+	fakeAuth   struct{ saxo.AuthClient }
+	fakeBroker struct{ saxo.BrokerClient }
+)
+
+type discard struct{}
+
+func (discard) Record(context.Context, *domain.PriceData) error        { return nil }
+func (discard) RecordBatch(context.Context, []*domain.PriceData) error { return nil }
+
+func TestNew_RequiredOptions(t *testing.T) {
+	broker := WithBroker(fakeAuth{}, fakeBroker{})
+	recorder := WithRecorder(discard{})
+	eurusd := WithInstruments(Instrument{Ticker: "EURUSD", Uic: 21})
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"no broker", nil, "broker"},
+		{"no recorder", []Option{broker}, "recorder"},
+		{"no instruments", []Option{broker, recorder}, "instrument"},
+		{"no uic", []Option{broker, recorder, WithInstruments(Instrument{Ticker: "EURUSD"})}, "uic"},
+		{"bad flush interval", []Option{broker, recorder, eurusd, WithFlushInterval(-time.Second)}, "flush interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWithInstruments(t *testing.T) {
+	c := &config{instruments: make(map[string]Instrument)}
+	WithInstruments(Instrument{Ticker: "EURUSD", Uic: 21}, Instrument{Ticker: "USDJPY", Uic: 42})(c)
+	WithInstruments(Instrument{Ticker: "EURUSD", Uic: 21, Decimals: 5})(c)
+
+	if len(c.instruments) != 2 || c.instruments["EURUSD"].Decimals != 5 {
+		t.Errorf("Expected later instruments to replace earlier ones by ticker, got %+v", c.instruments)
+	}
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// AlignedQuotes is the latest quote of every instrument at one instant of a fixed clock
+// Rows share timestamps across instruments, unlike ticks, so pairs can be compared directly
+type AlignedQuotes struct {
+	Timestamp time.Time
+	Quotes    []AlignedQuote // One per instrument, in the same order on every row
+}
+
+// AlignedQuote is one instrument's latest quote at an AlignedQuotes timestamp
+type AlignedQuote struct {
+	Ticker   string
+	Bid      float64
+	Ask      float64
+	Decimals int
+	Age      time.Duration // Time since the quote's tick
+	Valid    bool          // False until the instrument's first tick
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// BackedUpFile records a file as it was when last copied to a backup target
+// A file whose size or modification time differs has changed since and is copied again
+type BackedUpFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Impact is the expected market impact of an economic calendar event
+type Impact int
+
+const (
+	ImpactLow Impact = iota + 1
+	ImpactMedium
+	ImpactHigh
+)
+
+// ParseImpact converts "low", "medium" or "high" (case-insensitive) to an Impact
+func ParseImpact(s string) (Impact, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return ImpactLow, nil
+	case "medium":
+		return ImpactMedium, nil
+	case "high":
+		return ImpactHigh, nil
+	}
+	return 0, fmt.Errorf("unknown impact: %q", s)
+}
+
+// String returns the lower-case impact name
+func (i Impact) String() string {
+	switch i {
+	case ImpactLow:
+		return "low"
+	case ImpactMedium:
+		return "medium"
+	case ImpactHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+// CalendarEvent is a scheduled economic release (e.g. US Non-Farm Payrolls)
+type CalendarEvent struct {
+	Time     time.Time `json:"time"`
+	Currency string    `json:"currency"` // ISO currency code, e.g. USD
+	Impact   Impact    `json:"impact"`
+	Title    string    `json:"title"`
+}
+
+// Affects reports whether the event's currency is part of an FX ticker (EUR affects EURUSD and EURJPY)
+func (e CalendarEvent) Affects(ticker string) bool {
+	return e.Currency != "" && strings.Contains(strings.ToUpper(ticker), strings.ToUpper(e.Currency))
+}
+
+// CalendarAnnotation marks the window around an event and the instruments it affects
+type CalendarAnnotation struct {
+	Event       CalendarEvent
+	WindowStart time.Time
+	WindowEnd   time.Time
+	Tickers     []string
+}
+
+// Contains reports whether t falls within the annotation window
+func (a CalendarAnnotation) Contains(t time.Time) bool {
+	return !t.Before(a.WindowStart) && !t.After(a.WindowEnd)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// CollectorStatus is the live state of a running collector, as shown by monitors
+type CollectorStatus struct {
+	Timestamp   time.Time          `json:"timestamp"`
+	Connected   bool               `json:"connected"`    // Broker WebSocket connected
+	LastTickAt  *time.Time         `json:"last_tick_at"` // Latest price update of any instrument; nil before the first
+	Instruments []InstrumentStatus `json:"instruments"`
+}
+
+// InstrumentStatus is the latest quote of one instrument
+// Rates follow from the difference in Ticks between two statuses
+type InstrumentStatus struct {
+	Ticker     string     `json:"ticker"`
+	Bid        float64    `json:"bid"`
+	Ask        float64    `json:"ask"`
+	Spread     float64    `json:"spread"`
+	SpreadPips float64    `json:"spread_pips,omitempty"` // With a pip size configured
+	Decimals   int        `json:"decimals"`
+	QuoteTime  *time.Time `json:"quote_time"` // Timestamp of the latest tick; nil before the first
+	Ticks      uint64     `json:"ticks"`      // Plausible ticks since the collector started
+	Paused     bool       `json:"paused"`     // Not recorded right now (admin API or schedule)
+}
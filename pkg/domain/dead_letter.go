@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// DeadLetter is an item that could not be processed, kept so it can be recovered later
+type DeadLetter struct {
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`          // Short machine-readable reason, e.g. "record_failed"
+	Error     string    `json:"error,omitempty"` // Last error message
+	Payload   any       `json:"payload"`         // The original item (price data, raw update, ...)
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType identifies the kind of operational event
+type EventType string
+
+const (
+	EventDisconnected  EventType = "websocket_disconnected"
+	EventReconnected   EventType = "websocket_reconnected"
+	EventAuthFailure   EventType = "auth_failure"
+	EventStorageError  EventType = "storage_error"
+	EventDataGap       EventType = "data_gap"
+	EventDiskSpaceLow  EventType = "disk_space_low"
+	EventResubscribed  EventType = "subscription_reset"
+	EventHeartbeat     EventType = "heartbeat"
+	EventLeaderElected EventType = "leader_elected"
+	EventLeaderLost    EventType = "leader_lost"
+	EventPaused        EventType = "recording_paused"
+	EventResumed       EventType = "recording_resumed"
+	EventQuotaExceeded EventType = "quota_exceeded"
+
+	EventComponentRestarted EventType = "component_restarted" // A background goroutine panicked or exited and is restarted
+	EventComponentFailed    EventType = "component_failed"    // A goroutine kept failing; the supervisor gave up
+	EventConfigReloaded     EventType = "config_reloaded"     // Runtime settings were changed (SIGHUP or admin API)
+	EventDataResumed        EventType = "data_resumed"        // Price updates arrive again after a data gap
+	EventDiskSpaceRecovered EventType = "disk_space_recovered"
+	EventStorageFailover    EventType = "storage_failover"  // A sink failed and its fallback takes the writes
+	EventStorageRecovered   EventType = "storage_recovered" // The sink caught up from its fallback spool
+	EventClockDrift         EventType = "clock_drift"       // The local clock is off from a reference clock (NTP, broker)
+	EventClockSynced        EventType = "clock_synced"      // The local clock agrees with the reference again
+)
+
+// resolvedBy maps the events that end a condition to the event that raised it
+var resolvedBy = map[EventType]EventType{
+	EventReconnected:        EventDisconnected,
+	EventDataResumed:        EventDataGap,
+	EventDiskSpaceRecovered: EventDiskSpaceLow,
+	EventLeaderElected:      EventLeaderLost,
+	EventStorageRecovered:   EventStorageFailover,
+	EventClockSynced:        EventClockDrift,
+}
+
+// Resolves returns the event type whose condition t ends (a reconnect ends a disconnect)
+func (t EventType) Resolves() (EventType, bool) {
+	raised, ok := resolvedBy[t]
+	return raised, ok
+}
+
+// Severity indicates how urgently an event needs human attention
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// AtLeast reports whether s is as urgent as min or more; unknown severities rank below info
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// ParseSeverity parses info, warning or critical
+func ParseSeverity(s string) (Severity, error) {
+	switch severity := Severity(s); severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return severity, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (info, warning or critical)", s)
+}
+
+// Event represents an operational event (connection changes, failures, warnings)
+type Event struct {
+	Type      EventType         `json:"type"`
+	Severity  Severity          `json:"severity"`
+	Timestamp time.Time         `json:"timestamp"`
+	Ticker    string            `json:"ticker,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// NewEvent creates an event stamped with the current time
+func NewEvent(eventType EventType, severity Severity, message string) Event {
+	return Event{
+		Type:      eventType,
+		Severity:  severity,
+		Timestamp: time.Now().UTC(),
+		Message:   message,
+	}
+}
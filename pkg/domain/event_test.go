@@ -0,0 +1,40 @@
+package domain
+
+import "testing"
+
+func TestSeverity_AtLeast(t *testing.T) {
+	tests := []struct {
+		severity, min Severity
+		want          bool
+	}{
+		{SeverityCritical, SeverityCritical, true},
+		{SeverityCritical, SeverityWarning, true},
+		{SeverityWarning, SeverityCritical, false},
+		{SeverityInfo, SeverityWarning, false},
+		{SeverityInfo, SeverityInfo, true},
+		{"debug", SeverityInfo, false},
+	}
+	for _, tt := range tests {
+		if got := tt.severity.AtLeast(tt.min); got != tt.want {
+			t.Errorf("%s.AtLeast(%s) = %v, want %v", tt.severity, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	if s, err := ParseSeverity("warning"); err != nil || s != SeverityWarning {
+		t.Errorf("ParseSeverity(warning) = %v, %v", s, err)
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
+
+func TestEventType_Resolves(t *testing.T) {
+	if raised, ok := EventDataResumed.Resolves(); !ok || raised != EventDataGap {
+		t.Errorf("EventDataResumed.Resolves() = %v, %v", raised, ok)
+	}
+	if _, ok := EventDataGap.Resolves(); ok {
+		t.Error("A data gap doesn't resolve anything")
+	}
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// FinalizedFile describes an hourly output file that is complete and safe for batch jobs to read
+type FinalizedFile struct {
+	Path        string    `json:"path"`
+	Dataset     string    `json:"dataset"` // e.g. "spreads"
+	Ticker      string    `json:"ticker"`
+	Hour        time.Time `json:"hour"` // Start of the hour the file covers
+	Rows        int       `json:"rows"` // Data rows, header excluded
+	Bytes       int64     `json:"bytes"`
+	SHA256      string    `json:"sha256"` // Hex checksum of the file contents
+	FinalizedAt time.Time `json:"finalized_at"`
+}
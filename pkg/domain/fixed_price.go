@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PriceFormat selects how sinks represent bid, ask and spread
+type PriceFormat string
+
+const (
+	PriceFormatFloat   PriceFormat = "float"   // float64 rounded to the instrument's decimals
+	PriceFormatDecimal PriceFormat = "decimal" // Exact fixed-point values (scaled integers or decimal text)
+)
+
+// ParsePriceFormat validates a price format name
+func ParsePriceFormat(s string) (PriceFormat, error) {
+	switch f := PriceFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case PriceFormatFloat, PriceFormatDecimal:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown price format %q (supported: float, decimal)", s)
+	}
+}
+
+// FixedPrice is a price as an integer number of the instrument's smallest increment
+// (1.08451 with 5 decimals is 108451), so spreads are exact integer differences
+type FixedPrice int64
+
+// ToFixedPrice scales price by 10^decimals, rounding away float noise
+func ToFixedPrice(price float64, decimals int) FixedPrice {
+	return FixedPrice(math.Round(price * math.Pow10(decimals)))
+}
+
+// Float64 returns the nearest float64 to the exact decimal value
+func (p FixedPrice) Float64(decimals int) float64 {
+	return float64(p) / math.Pow10(decimals)
+}
+
+// Format returns the exact decimal text with decimals places (108451, 5 -> "1.08451")
+func (p FixedPrice) Format(decimals int) string {
+	if decimals <= 0 {
+		return strconv.FormatInt(int64(p), 10)
+	}
+
+	sign := ""
+	units := int64(p)
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := strconv.FormatInt(units, 10)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	point := len(digits) - decimals
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// FixedBid returns the bid in fixed-point units of Decimals
+func (p *PriceData) FixedBid() FixedPrice {
+	return ToFixedPrice(p.Bid, p.Decimals)
+}
+
+// FixedAsk returns the ask in fixed-point units of Decimals
+func (p *PriceData) FixedAsk() FixedPrice {
+	return ToFixedPrice(p.Ask, p.Decimals)
+}
+
+// FixedSpread returns the exact spread in fixed-point units of Decimals (zero if FlagClamped)
+func (p *PriceData) FixedSpread() FixedPrice {
+	if p.Flags.Has(FlagClamped) {
+		return 0
+	}
+	return p.FixedAsk() - p.FixedBid()
+}
+
+// MarshalJSONFormat encodes the tick like json.Marshal, with spread in SpreadUnit
+// PriceFormatDecimal writes bid, ask and spread as exact decimal numbers (0.0002, never 0.00019999999)
+func (p *PriceData) MarshalJSONFormat(format PriceFormat) ([]byte, error) {
+	decimal := format == PriceFormatDecimal && p.Decimals > 0
+	unit := p.SpreadUnit != "" && p.SpreadUnit != SpreadUnitPrice
+	if !decimal && !unit {
+		return json.Marshal(p)
+	}
+
+	type plain PriceData // Same fields without this method set
+	out := struct {
+		*plain
+		Bid    any `json:"bid"`
+		Ask    any `json:"ask"`
+		Spread any `json:"spread"`
+	}{plain: (*plain)(p), Bid: p.Bid, Ask: p.Ask, Spread: p.Spread}
+
+	if decimal {
+		out.Bid = json.Number(p.FixedBid().Format(p.Decimals))
+		out.Ask = json.Number(p.FixedAsk().Format(p.Decimals))
+		out.Spread = json.Number(p.FixedSpread().Format(p.Decimals))
+		if precision := p.SpreadPrecision(); precision > p.Decimals {
+			out.Spread = json.Number(ToFixedPrice(p.Spread, precision).Format(precision))
+		}
+	}
+	if unit {
+		spread, precision := p.UnitSpread()
+		out.Spread = json.Number(strconv.FormatFloat(spread, 'f', precision, 64))
+	}
+	return json.Marshal(out)
+}
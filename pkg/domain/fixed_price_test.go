@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFixedPrice_Format(t *testing.T) {
+	tests := []struct {
+		price    FixedPrice
+		decimals int
+		want     string
+	}{
+		{108451, 5, "1.08451"},
+		{155123, 3, "155.123"},
+		{20, 5, "0.00020"},
+		{-3, 5, "-0.00003"},
+		{42, 0, "42"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.price.Format(tt.decimals); got != tt.want {
+			t.Errorf("FixedPrice(%d).Format(%d) = %q, want %q", tt.price, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestPriceData_FixedSpread(t *testing.T) {
+	p := &PriceData{Bid: 1.08451, Ask: 1.08471, Decimals: 5}
+	p.CalculateSpread()
+
+	if p.Spread == 0.0002 {
+		t.Fatalf("Expected float subtraction artifact, got exact %v", p.Spread)
+	}
+	if got := p.FixedSpread(); got != 20 {
+		t.Errorf("Expected 20 points, got %d", got)
+	}
+	if got := p.FixedSpread().Float64(p.Decimals); got != 0.0002 {
+		t.Errorf("Expected 0.0002, got %v", got)
+	}
+}
+
+func TestPriceData_MarshalJSONFormat(t *testing.T) {
+	p := &PriceData{Ticker: "EURUSD", Bid: 1.08451, Ask: 1.08471, Decimals: 5}
+	p.CalculateSpread()
+
+	out, err := p.MarshalJSONFormat(PriceFormatDecimal)
+	if err != nil {
+		t.Fatalf("MarshalJSONFormat failed: %v", err)
+	}
+	for _, want := range []string{`"bid":1.08451`, `"ask":1.08471`, `"spread":0.00020`, `"ticker":"EURUSD"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected %s in %s", want, out)
+		}
+	}
+	if strings.Count(string(out), `"spread"`) != 1 {
+		t.Errorf("Expected a single spread field in %s", out)
+	}
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// HolidayScopeAll closes every instrument
+const HolidayScopeAll = "*"
+
+// Holiday is an FX trading day on which a market is legitimately closed
+// Scope is "*" (whole market), a currency ("JPY" closes every JPY pair) or a ticker ("USDJPY")
+type Holiday struct {
+	Date  time.Time // Trading day (midnight UTC), see TradingDay
+	Scope string
+	Name  string
+}
+
+// Applies reports whether the holiday closes ticker
+func (h Holiday) Applies(ticker string) bool {
+	scope := strings.ToUpper(h.Scope)
+	ticker = strings.ToUpper(ticker)
+
+	switch {
+	case scope == HolidayScopeAll:
+		return true
+	case len(scope) == 3:
+		return strings.Contains(ticker, scope)
+	default:
+		return scope == ticker
+	}
+}
+
+// TradingDay returns the FX trading day t belongs to (midnight UTC of that date)
+// Trading days roll at 17:00 New York, so Sunday 17:00 New York already belongs to Monday
+func TradingDay(t time.Time) time.Time {
+	ny := t.In(newYork).Add(7 * time.Hour)
+	return time.Date(ny.Year(), ny.Month(), ny.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// HolidayCalendar answers whether an instrument's market is closed at a given time
+// A nil calendar has no holidays
+type HolidayCalendar struct {
+	byDay map[time.Time][]Holiday
+}
+
+// NewHolidayCalendar indexes holidays by trading day
+func NewHolidayCalendar(holidays []Holiday) *HolidayCalendar {
+	c := &HolidayCalendar{byDay: make(map[time.Time][]Holiday)}
+	for _, h := range holidays {
+		day := time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, time.UTC)
+		c.byDay[day] = append(c.byDay[day], h)
+	}
+	return c
+}
+
+// Holiday returns the holiday closing ticker at t, if any
+func (c *HolidayCalendar) Holiday(ticker string, t time.Time) (Holiday, bool) {
+	if c == nil {
+		return Holiday{}, false
+	}
+	for _, h := range c.byDay[TradingDay(t)] {
+		if h.Applies(ticker) {
+			return h, true
+		}
+	}
+	return Holiday{}, false
+}
+
+// IsOpen reports whether ticker is expected to trade at t (FX market hours and no holiday)
+func (c *HolidayCalendar) IsOpen(ticker string, t time.Time) bool {
+	if !IsFXMarketOpen(t) {
+		return false
+	}
+	_, closed := c.Holiday(ticker, t)
+	return !closed
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHolidayCalendar_IsOpen(t *testing.T) {
+	calendar := NewHolidayCalendar([]Holiday{
+		{Date: time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC), Scope: "*", Name: "Christmas Day"},
+		{Date: time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC), Scope: "JPY", Name: "Labour Thanksgiving (observed)"},
+		{Date: time.Date(2025, 11, 27, 0, 0, 0, 0, time.UTC), Scope: "USDCAD", Name: "Thanksgiving"},
+	})
+
+	tests := []struct {
+		name   string
+		ticker string
+		time   time.Time
+		want   bool
+	}{
+		{"Christmas closes everything", "EURUSD", time.Date(2025, 12, 25, 12, 0, 0, 0, time.UTC), false},
+		{"Christmas starts at previous NY close", "EURUSD", time.Date(2025, 12, 24, 22, 30, 0, 0, time.UTC), false},
+		{"Before Christmas trading day", "EURUSD", time.Date(2025, 12, 24, 21, 30, 0, 0, time.UTC), true},
+		{"Currency holiday closes pair", "USDJPY", time.Date(2025, 11, 24, 3, 0, 0, 0, time.UTC), false},
+		{"Currency holiday leaves other pairs", "EURUSD", time.Date(2025, 11, 24, 3, 0, 0, 0, time.UTC), true},
+		{"Currency holiday starts Sunday evening", "EURJPY", time.Date(2025, 11, 23, 22, 30, 0, 0, time.UTC), false},
+		{"Ticker holiday", "USDCAD", time.Date(2025, 11, 27, 15, 0, 0, 0, time.UTC), false},
+		{"Ticker holiday leaves other pairs", "USDJPY", time.Date(2025, 11, 27, 15, 0, 0, 0, time.UTC), true},
+		{"Weekend", "EURUSD", time.Date(2025, 11, 22, 12, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.IsOpen(tt.ticker, tt.time); got != tt.want {
+				t.Errorf("IsOpen(%s, %v) = %v, want %v", tt.ticker, tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHolidayCalendar_Nil(t *testing.T) {
+	var calendar *HolidayCalendar
+	if !calendar.IsOpen("EURUSD", time.Date(2025, 12, 25, 12, 0, 0, 0, time.UTC)) {
+		t.Error("Nil calendar should only apply FX market hours")
+	}
+}
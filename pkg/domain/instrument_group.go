@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+)
+
+// InstrumentGroups maps a group name (majors, jpy_crosses, metals...) to its tickers
+// Groups are tags on the instruments in instruments.json; an instrument can be in several
+type InstrumentGroups map[string][]string
+
+// Names returns the group names, sorted
+func (g InstrumentGroups) Names() []string {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Expand turns a comma-separated list of tickers and group names into tickers, in the order given
+// and without duplicates; names that aren't groups are taken as tickers
+func (g InstrumentGroups) Expand(selection string) []string {
+	var tickers []string
+	add := func(ticker string) {
+		if !slices.Contains(tickers, ticker) {
+			tickers = append(tickers, ticker)
+		}
+	}
+	for _, name := range strings.Split(selection, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if members, ok := g[name]; ok {
+			for _, ticker := range members {
+				add(ticker)
+			}
+			continue
+		}
+		add(name)
+	}
+	return tickers
+}
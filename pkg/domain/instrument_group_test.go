@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInstrumentGroups_Expand(t *testing.T) {
+	groups := InstrumentGroups{
+		"majors":      {"EURUSD", "USDJPY", "GBPUSD"},
+		"jpy_crosses": {"EURJPY", "GBPJPY", "USDJPY"},
+	}
+
+	tests := []struct {
+		selection string
+		want      []string
+	}{
+		{"", nil},
+		{"EURUSD", []string{"EURUSD"}},
+		{"majors", []string{"EURUSD", "USDJPY", "GBPUSD"}},
+		{"majors, jpy_crosses", []string{"EURUSD", "USDJPY", "GBPUSD", "EURJPY", "GBPJPY"}},
+		{"USDTRY,majors", []string{"USDTRY", "EURUSD", "USDJPY", "GBPUSD"}},
+	}
+	for _, tt := range tests {
+		if got := groups.Expand(tt.selection); !slices.Equal(got, tt.want) {
+			t.Errorf("Expand(%q) = %v, want %v", tt.selection, got, tt.want)
+		}
+	}
+
+	if got := groups.Names(); !slices.Equal(got, []string{"jpy_crosses", "majors"}) {
+		t.Errorf("Names() = %v", got)
+	}
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// newYork is the reference timezone for the FX trading week
+// Falls back to EST if tzdata is unavailable (main embeds time/tzdata)
+var newYork = func() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.FixedZone("EST", -5*60*60)
+	}
+	return loc
+}()
+
+// IsFXMarketOpen reports whether the spot FX market is open at t
+// The FX week runs from Sunday 17:00 to Friday 17:00 New York time
+func IsFXMarketOpen(t time.Time) bool {
+	ny := t.In(newYork)
+	hour := ny.Hour()
+
+	switch ny.Weekday() {
+	case time.Saturday:
+		return false
+	case time.Sunday:
+		return hour >= 17
+	case time.Friday:
+		return hour < 17
+	default:
+		return true
+	}
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsFXMarketOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		want bool
+	}{
+		{"Wednesday midday", time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC), true},
+		{"Friday before NY close", time.Date(2025, 11, 21, 21, 59, 0, 0, time.UTC), true},
+		{"Friday after NY close", time.Date(2025, 11, 21, 22, 0, 0, 0, time.UTC), false},
+		{"Saturday", time.Date(2025, 11, 22, 12, 0, 0, 0, time.UTC), false},
+		{"Sunday before open", time.Date(2025, 11, 23, 21, 59, 0, 0, time.UTC), false},
+		{"Sunday after open", time.Date(2025, 11, 23, 22, 0, 0, 0, time.UTC), true},
+		{"Friday after NY close (summer time)", time.Date(2025, 7, 18, 21, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFXMarketOpen(tt.time); got != tt.want {
+				t.Errorf("IsFXMarketOpen(%v) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package domain
+
+// BookLevel is one price level of an order book
+type BookLevel struct {
+	Price float64
+	Size  float64 // Base currency amount available at Price
+}
+
+// OrderBook holds the visible levels of an instrument, best price first on each side
+type OrderBook struct {
+	Bids []BookLevel
+	Asks []BookLevel
+}
+
+// EffectiveSpread returns the spread paid to buy and sell notional (base currency) at once:
+// the volume-weighted ask minus the volume-weighted bid, walking the levels from the top
+// ok is false if either side lacks the depth to fill notional
+func (b OrderBook) EffectiveSpread(notional float64) (spread float64, ok bool) {
+	if notional <= 0 {
+		return 0, false
+	}
+	ask, ok := fillPrice(b.Asks, notional)
+	if !ok {
+		return 0, false
+	}
+	bid, ok := fillPrice(b.Bids, notional)
+	if !ok {
+		return 0, false
+	}
+	return ask - bid, true
+}
+
+// fillPrice returns the average price of filling notional from levels in order
+func fillPrice(levels []BookLevel, notional float64) (float64, bool) {
+	remaining, cost := notional, 0.0
+	for _, level := range levels {
+		if level.Size <= 0 {
+			continue
+		}
+		filled := min(level.Size, remaining)
+		cost += filled * level.Price
+		if remaining -= filled; remaining <= 0 {
+			return cost / notional, true
+		}
+	}
+	return 0, false
+}
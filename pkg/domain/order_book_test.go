@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOrderBook_EffectiveSpread(t *testing.T) {
+	book := OrderBook{
+		Bids: []BookLevel{{1.08450, 1_000_000}, {1.08440, 4_000_000}},
+		Asks: []BookLevel{{1.08460, 1_000_000}, {1.08475, 4_000_000}},
+	}
+
+	tests := []struct {
+		notional float64
+		want     float64
+		ok       bool
+	}{
+		{1_000_000, 0.00010, true},  // Top of book
+		{2_000_000, 0.000225, true}, // Ask 1.084675, bid 1.08445
+		{5_000_000, 0.00030, true},  // Ask 1.08472, bid 1.08442
+		{6_000_000, 0, false},       // Deeper than the book
+		{0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := book.EffectiveSpread(tt.notional)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EffectiveSpread(%v) = %v, %v; want %v, %v", tt.notional, got, ok, tt.want, tt.ok)
+		}
+	}
+}
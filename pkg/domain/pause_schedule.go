@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInstrumentNotFound is returned for a ticker that isn't configured
+var ErrInstrumentNotFound = errors.New("instrument not found")
+
+// RecordingState describes which instruments are currently not recorded
+type RecordingState struct {
+	Paused    bool     `json:"paused"`    // All instruments paused through the admin API
+	Tickers   []string `json:"tickers"`   // Instruments paused through the admin API
+	Scheduled []string `json:"scheduled"` // Instruments paused by the schedule right now
+}
+
+// weekdayNames maps the three-letter day names used in schedules
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// PauseWindow is a recurring period in which ticks are not recorded
+// A window past midnight belongs to the day it starts on (fri 23:00-01:00 runs into Saturday)
+type PauseWindow struct {
+	Window  Session               // Name holds the original definition
+	Days    map[time.Weekday]bool // Start days; empty means every day
+	Tickers []string              // Empty means all instruments
+}
+
+// PauseSchedule is a list of pause windows; the zero value never pauses
+type PauseSchedule []PauseWindow
+
+// ParsePauseSchedule parses windows separated by ";", each
+// "DAYS HH:MM-HH:MM [tz=Zone] [tickers=A,B]" with DAYS like "fri", "mon-fri", "sat,sun" or "*"
+// e.g. "fri 22:00-23:00; * 16:55-17:05 tz=America/New_York tickers=EURUSD,USDJPY" (tz defaults to UTC)
+func ParsePauseSchedule(spec string) (PauseSchedule, error) {
+	var schedule PauseSchedule
+	for _, def := range strings.Split(spec, ";") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		window, err := parsePauseWindow(def)
+		if err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, window)
+	}
+	return schedule, nil
+}
+
+// parsePauseWindow parses a single window definition
+func parsePauseWindow(def string) (PauseWindow, error) {
+	fields := strings.Fields(def)
+	if len(fields) < 2 {
+		return PauseWindow{}, fmt.Errorf("invalid pause window %q (expected DAYS HH:MM-HH:MM [tz=Zone] [tickers=A,B])", def)
+	}
+
+	days, err := parseWeekdays(fields[0])
+	if err != nil {
+		return PauseWindow{}, fmt.Errorf("invalid pause window %q: %w", def, err)
+	}
+
+	zone := "UTC"
+	var tickers []string
+	for _, option := range fields[2:] {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "tz":
+			zone = value
+		case "tickers":
+			for _, ticker := range strings.Split(value, ",") {
+				if ticker = strings.TrimSpace(ticker); ticker != "" {
+					tickers = append(tickers, ticker)
+				}
+			}
+		default:
+			return PauseWindow{}, fmt.Errorf("invalid pause window %q: unknown option %q", def, option)
+		}
+	}
+
+	window, err := ParseSessionWindow(def, zone+"@"+fields[1])
+	if err != nil {
+		return PauseWindow{}, err
+	}
+	return PauseWindow{Window: window, Days: days, Tickers: tickers}, nil
+}
+
+// parseWeekdays parses "*", "fri", "mon-fri", "fri-mon" or comma-separated combinations
+func parseWeekdays(spec string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	if spec == "*" {
+		return days, nil
+	}
+
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, ok := weekdayNames[first]
+		to, ok2 := weekdayNames[last]
+		if !isRange {
+			to, ok2 = from, ok
+		}
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("unknown day %q", part)
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// Applies reports whether the window pauses ticker at t
+func (w PauseWindow) Applies(ticker string, t time.Time) bool {
+	if len(w.Tickers) > 0 && !slices.Contains(w.Tickers, ticker) {
+		return false
+	}
+	day, ok := w.Window.startDay(t)
+	return ok && (len(w.Days) == 0 || w.Days[day])
+}
+
+// Paused reports whether any window pauses ticker at t
+func (s PauseSchedule) Paused(ticker string, t time.Time) bool {
+	for _, w := range s {
+		if w.Applies(ticker, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePauseSchedule(t *testing.T) {
+	schedule, err := ParsePauseSchedule("fri 22:00-23:00; sat,sun 23:30-00:30 tickers=USDJPY; mon-fri 16:55-17:05 tz=America/New_York tickers=EURUSD,GBPUSD")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if len(schedule) != 3 {
+		t.Fatalf("Expected 3 windows, got %d", len(schedule))
+	}
+
+	// 2025-11-21 is a Friday; New York is UTC-5 in November
+	tests := []struct {
+		name   string
+		ticker string
+		t      time.Time
+		want   bool
+	}{
+		{"friday window", "AUDUSD", time.Date(2025, 11, 21, 22, 30, 0, 0, time.UTC), true},
+		{"friday window end is exclusive", "AUDUSD", time.Date(2025, 11, 21, 23, 0, 0, 0, time.UTC), false},
+		{"same hour on thursday", "AUDUSD", time.Date(2025, 11, 20, 22, 30, 0, 0, time.UTC), false},
+		{"weekend window past midnight", "USDJPY", time.Date(2025, 11, 24, 0, 15, 0, 0, time.UTC), true},
+		{"weekend window other ticker", "EURUSD", time.Date(2025, 11, 24, 0, 15, 0, 0, time.UTC), false},
+		{"new york rollover", "EURUSD", time.Date(2025, 11, 18, 22, 0, 0, 0, time.UTC), true},
+		{"new york rollover other ticker", "USDJPY", time.Date(2025, 11, 18, 22, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := schedule.Paused(tt.ticker, tt.t); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if PauseSchedule(nil).Paused("EURUSD", time.Now()) {
+		t.Error("Empty schedule should never pause")
+	}
+}
+
+func TestParsePauseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"friday 22:00-23:00",
+		"fri 22:00",
+		"fri 25:00-26:00",
+		"fri 22:00-23:00 tz=Mars/Olympus",
+		"fri 22:00-23:00 every=week",
+		"22:00-23:00",
+	} {
+		if _, err := ParsePauseSchedule(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestParseWeekdays_WrapsAround(t *testing.T) {
+	days, err := parseWeekdays("fri-mon")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	for _, d := range []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday} {
+		if !days[d] {
+			t.Errorf("Expected %v in fri-mon", d)
+		}
+	}
+	if len(days) != 4 {
+		t.Errorf("Expected 4 days, got %d", len(days))
+	}
+}
@@ -0,0 +1,141 @@
+// Package domain holds the collector's data types: ticks, snapshots, sessions, flags and schedules
+// Other Go programs can import it to consume recorded data or feed custom sinks
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PriceData represents bid/ask price data for spread analysis
+type PriceData struct {
+	Timestamp time.Time `json:"timestamp"`
+	Uic       int       `json:"uic"`
+	Ticker    string    `json:"ticker"`
+	AssetType string    `json:"asset_type"`
+	Bid       float64   `json:"bid"`
+	Ask       float64   `json:"ask"`
+	Spread    float64   `json:"spread"`
+	Decimals  int       `json:"decimals,omitempty"` // Number of decimals for price rounding
+	Sequence  uint64    `json:"seq,omitempty"`      // Per-instrument, monotonically increasing across restarts
+
+	SessionLabel string    `json:"session,omitempty"` // Open trading sessions, e.g. "london+new_york"
+	Flags        TickFlags `json:"flags,omitempty"`   // Rollover / triple-swap / outlier markers
+
+	// Cost of buying and selling a configured notional at once, in price units (0 = not computed)
+	EffectiveSpread float64 `json:"effective_spread,omitempty"`
+
+	// Standard deviations the spread is from its rolling mean (0 = not computed, e.g. too few ticks yet)
+	SpreadZ float64 `json:"spread_z,omitempty"`
+
+	Source string `json:"source,omitempty"` // Origin of imported ticks (cmd/import -source); empty for live captures
+
+	// Spread is always kept in price units; sinks write it in SpreadUnit (see UnitSpread)
+	SpreadUnit SpreadUnit `json:"spread_unit,omitempty"`
+	PipSize    float64    `json:"-"`
+
+	// Decimals of spreads in price units when finer than the prices (0 = Decimals, see SpreadPrecision)
+	SpreadDecimals int `json:"-"`
+}
+
+// CalculateSpread computes the spread from bid/ask prices
+func (p *PriceData) CalculateSpread() {
+	p.Spread = p.Ask - p.Bid
+}
+
+// CrossedQuotePolicy decides the spread recorded for a crossed quote (bid above ask)
+type CrossedQuotePolicy string
+
+const (
+	CrossedQuoteKeep    CrossedQuotePolicy = "keep"    // Record the negative spread, the default
+	CrossedQuoteClamp   CrossedQuotePolicy = "clamp"   // Record a spread of zero, flagged clamped
+	CrossedQuoteInvalid CrossedQuotePolicy = "invalid" // Keep the negative spread, flagged outlier like implausible spreads
+)
+
+// ParseCrossedQuotePolicy validates a crossed quote policy name ("" means keep)
+func ParseCrossedQuotePolicy(s string) (CrossedQuotePolicy, error) {
+	switch policy := CrossedQuotePolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case "":
+		return CrossedQuoteKeep, nil
+	case CrossedQuoteKeep, CrossedQuoteClamp, CrossedQuoteInvalid:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown crossed quote policy %q (supported: keep, clamp, invalid)", s)
+	}
+}
+
+// CalculateSpreadWith computes the spread like CalculateSpread and applies policy to a crossed quote,
+// recording the decision in Flags (FlagCrossed, plus FlagClamped or FlagOutlier)
+// Locked quotes (zero spread) are not crossed and left alone
+func (p *PriceData) CalculateSpreadWith(policy CrossedQuotePolicy) {
+	p.CalculateSpread()
+	if p.Bid <= p.Ask {
+		return
+	}
+	p.Flags |= FlagCrossed
+	switch policy {
+	case CrossedQuoteClamp:
+		p.Spread = 0
+		p.Flags |= FlagClamped
+	case CrossedQuoteInvalid:
+		p.Flags |= FlagOutlier
+	}
+}
+
+// Spread outlier reasons
+const (
+	OutlierNonPositiveSpread = "non_positive_spread"
+	OutlierSpreadAboveMax    = "spread_above_max"
+)
+
+// SpreadOutlier returns why the spread is implausible, or "" if it is plausible
+// maxSpread <= 0 disables the upper bound
+func (p *PriceData) SpreadOutlier(maxSpread float64) string {
+	switch {
+	case p.Spread <= 0:
+		return OutlierNonPositiveSpread
+	case maxSpread > 0 && p.Spread > maxSpread:
+		return OutlierSpreadAboveMax
+	default:
+		return ""
+	}
+}
+
+// Quote anomalies
+const (
+	QuoteLocked  = "locked"  // bid == ask
+	QuoteCrossed = "crossed" // bid > ask
+)
+
+// QuoteAnomaly returns QuoteLocked or QuoteCrossed for a locked/crossed market, or "" for a normal quote
+func (p *PriceData) QuoteAnomaly() string {
+	switch {
+	case p.Bid > p.Ask:
+		return QuoteCrossed
+	case p.Bid == p.Ask:
+		return QuoteLocked
+	default:
+		return ""
+	}
+}
+
+// TickKey is the natural key of a tick: one row per key in a sink makes replays idempotent
+// Ticks with a sequence number are keyed by it; imported and backfilled ticks have none and are
+// keyed by their quote instead (Bid and Ask are zero otherwise)
+type TickKey struct {
+	Ticker    string
+	Timestamp int64 // Unix nanoseconds
+	Sequence  uint64
+	Bid       float64
+	Ask       float64
+}
+
+// Key returns the natural key of the tick
+func (p *PriceData) Key() TickKey {
+	key := TickKey{Ticker: p.Ticker, Timestamp: p.Timestamp.UnixNano(), Sequence: p.Sequence}
+	if p.Sequence == 0 {
+		key.Bid, key.Ask = p.Bid, p.Ask
+	}
+	return key
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPriceData_SpreadOutlier(t *testing.T) {
+	tests := []struct {
+		name      string
+		bid, ask  float64
+		maxSpread float64
+		want      string
+	}{
+		{"Normal spread", 1.08450, 1.08452, 0.0010, ""},
+		{"Locked market", 1.08450, 1.08450, 0.0010, OutlierNonPositiveSpread},
+		{"Crossed market", 1.08452, 1.08450, 0.0010, OutlierNonPositiveSpread},
+		{"Above maximum", 1.08000, 1.08500, 0.0010, OutlierSpreadAboveMax},
+		{"No maximum configured", 1.08000, 1.08500, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PriceData{Bid: tt.bid, Ask: tt.ask}
+			p.CalculateSpread()
+			if got := p.SpreadOutlier(tt.maxSpread); got != tt.want {
+				t.Errorf("SpreadOutlier(%v) = %q, want %q", tt.maxSpread, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceData_QuoteAnomaly(t *testing.T) {
+	tests := []struct {
+		bid, ask float64
+		want     string
+	}{
+		{1.08450, 1.08452, ""},
+		{1.08450, 1.08450, QuoteLocked},
+		{1.08452, 1.08450, QuoteCrossed},
+	}
+
+	for _, tt := range tests {
+		p := &PriceData{Bid: tt.bid, Ask: tt.ask}
+		if got := p.QuoteAnomaly(); got != tt.want {
+			t.Errorf("QuoteAnomaly(bid=%v, ask=%v) = %q, want %q", tt.bid, tt.ask, got, tt.want)
+		}
+	}
+}
+
+func TestPriceData_CalculateSpreadWith(t *testing.T) {
+	tests := []struct {
+		policy     CrossedQuotePolicy
+		bid, ask   float64
+		wantSpread float64
+		wantFlags  TickFlags
+	}{
+		{CrossedQuoteKeep, 1.08450, 1.08452, 0.00002, 0},
+		{CrossedQuoteClamp, 1.08450, 1.08450, 0, 0},
+		{CrossedQuoteKeep, 1.08452, 1.08450, -0.00002, FlagCrossed},
+		{CrossedQuoteClamp, 1.08452, 1.08450, 0, FlagCrossed | FlagClamped},
+		{CrossedQuoteInvalid, 1.08452, 1.08450, -0.00002, FlagCrossed | FlagOutlier},
+	}
+
+	for _, tt := range tests {
+		p := &PriceData{Bid: tt.bid, Ask: tt.ask, Decimals: 5}
+		p.CalculateSpreadWith(tt.policy)
+		if math.Abs(p.Spread-tt.wantSpread) > 1e-12 || p.Flags != tt.wantFlags {
+			t.Errorf("%s (bid=%v, ask=%v): spread %v flags %v, want %v and %v",
+				tt.policy, tt.bid, tt.ask, p.Spread, p.Flags, tt.wantSpread, tt.wantFlags)
+		}
+		if tt.wantFlags.Has(FlagClamped) && p.FixedSpread() != 0 {
+			t.Errorf("Expected a clamped fixed-point spread of 0, got %d", p.FixedSpread())
+		}
+	}
+
+	if _, err := ParseCrossedQuotePolicy("drop"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+func TestPriceData_Key(t *testing.T) {
+	ts := time.Date(2025, 11, 18, 12, 0, 0, 123456789, time.UTC)
+	live := &PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.15234, Ask: 1.15248, Sequence: 7}
+	replayed := &PriceData{Timestamp: ts.In(time.FixedZone("CET", 3600)), Ticker: "EURUSD", Bid: 1.15234, Ask: 1.15248, Sequence: 7, Spread: 0.00014}
+	if live.Key() != replayed.Key() {
+		t.Errorf("Expected the same key for the same tick, got %+v and %+v", live.Key(), replayed.Key())
+	}
+	if key := live.Key(); key.Bid != 0 || key.Ask != 0 {
+		t.Errorf("Expected a sequenced tick to be keyed without its quote, got %+v", key)
+	}
+
+	imported := &PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.15234, Ask: 1.15248}
+	requoted := &PriceData{Timestamp: ts, Ticker: "EURUSD", Bid: 1.15235, Ask: 1.15248}
+	if imported.Key() == requoted.Key() {
+		t.Error("Expected unsequenced ticks with different quotes to have different keys")
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Recording gap reasons
+const (
+	GapDowntime = "downtime" // The collector wasn't running (stopped, crashed or restarting)
+	GapNoData   = "no_data"  // The collector ran but received no price updates (a data gap event)
+)
+
+// RecordingGap is a period in which nothing was recorded
+type RecordingGap struct {
+	Start  time.Time `json:"start"` // Last recorded tick before the gap
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason"` // GapDowntime or GapNoData
+}
+
+// Duration returns the length of the gap
+func (g RecordingGap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Run journal events
+const (
+	RunStarted = "start"
+	RunStopped = "stop"
+)
+
+// RunRecord is one line of the run journal, written when a collector run starts and again when it stops
+// A run without a stop record ended in a crash or a kill
+type RunRecord struct {
+	RunID     string    `json:"run_id"`
+	Event     string    `json:"event"` // RunStarted or RunStopped
+	Timestamp time.Time `json:"timestamp"`
+	StartedAt time.Time `json:"started_at"`
+
+	Host      string `json:"host"`
+	PID       int    `json:"pid"`
+	Version   string `json:"version"`            // Release version, "(devel)" for local builds
+	Revision  string `json:"revision,omitempty"` // VCS commit the binary was built from
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+
+	// Hash of the effective settings and instruments; equal hashes mean equally configured runs
+	ConfigHash  string            `json:"config_hash"`
+	Settings    map[string]string `json:"settings,omitempty"`    // Effective settings, secrets redacted (start only)
+	Instruments []string          `json:"instruments,omitempty"` // Subscribed tickers (start only)
+
+	Reason string `json:"reason,omitempty"` // Why the run stopped, e.g. "signal: terminated" (stop only)
+	Error  string `json:"error,omitempty"`  // Shutdown error, if any (stop only)
+}
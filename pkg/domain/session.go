@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultSessions defines the main FX trading sessions in their local time zones
+const DefaultSessions = "tokyo=Asia/Tokyo@09:00-18:00,london=Europe/London@08:00-17:00,new_york=America/New_York@08:00-17:00"
+
+// Session is a named trading session defined in local time, Monday to Friday
+// End before start means the session runs past midnight
+type Session struct {
+	Name     string
+	Location *time.Location
+	Start    time.Duration // Offset from local midnight
+	End      time.Duration
+}
+
+// ParseSessions parses definitions like "london=Europe/London@08:00-17:00,..."
+func ParseSessions(spec string) ([]Session, error) {
+	var sessions []Session
+	for _, def := range strings.Split(spec, ",") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+
+		name, window, ok := strings.Cut(def, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid session %q (expected name=Zone@HH:MM-HH:MM)", def)
+		}
+		session, err := ParseSessionWindow(name, window)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// ParseSessionWindow parses a single window like "Europe/London@08:00-17:00"
+func ParseSessionWindow(name, window string) (Session, error) {
+	zone, hours, ok := strings.Cut(window, "@")
+	start, end, ok2 := strings.Cut(hours, "-")
+	if !ok || !ok2 {
+		return Session{}, fmt.Errorf("invalid window %q for %s (expected Zone@HH:MM-HH:MM)", window, name)
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return Session{}, fmt.Errorf("invalid time zone for %s: %w", name, err)
+	}
+	startOffset, err := parseClock(start)
+	if err != nil {
+		return Session{}, fmt.Errorf("invalid start for %s: %w", name, err)
+	}
+	endOffset, err := parseClock(end)
+	if err != nil {
+		return Session{}, fmt.Errorf("invalid end for %s: %w", name, err)
+	}
+
+	return Session{Name: name, Location: location, Start: startOffset, End: endOffset}, nil
+}
+
+// parseClock converts HH:MM to an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls within the session
+func (s Session) Contains(t time.Time) bool {
+	_, ok := s.openedOn(t)
+	return ok
+}
+
+// openedOn returns the local weekday the session containing t started on
+// Sessions past midnight belong to the day they started; only Monday to Friday sessions exist
+func (s Session) openedOn(t time.Time) (time.Weekday, bool) {
+	day, ok := s.startDay(t)
+	return day, ok && isWeekday(day)
+}
+
+// startDay returns the local weekday the window containing t started on, on any day of the week
+func (s Session) startDay(t time.Time) (time.Weekday, bool) {
+	local := t.In(s.Location)
+	// Wall clock time, not the time elapsed since midnight, which is an hour off on DST change days
+	hour, minute, second := local.Clock()
+	offset := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second + time.Duration(local.Nanosecond())
+
+	day := local.Weekday()
+	switch {
+	case s.Start <= s.End:
+		if offset < s.Start || offset >= s.End {
+			return 0, false
+		}
+	case offset >= s.Start:
+	case offset < s.End:
+		day = local.AddDate(0, 0, -1).Weekday()
+	default:
+		return 0, false
+	}
+	return day, true
+}
+
+// SessionLabel returns the names of all sessions open at t joined by "+" (e.g. "london+new_york")
+// Returns "" outside all sessions
+func SessionLabel(sessions []Session, t time.Time) string {
+	var open []string
+	for _, s := range sessions {
+		if s.Contains(t) {
+			open = append(open, s.Name)
+		}
+	}
+	return strings.Join(open, "+")
+}
+
+func isWeekday(d time.Weekday) bool {
+	return d != time.Saturday && d != time.Sunday
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionLabel(t *testing.T) {
+	sessions, err := ParseSessions(DefaultSessions)
+	if err != nil {
+		t.Fatalf("ParseSessions failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{"Tokyo morning", time.Date(2025, 11, 19, 1, 0, 0, 0, time.UTC), "tokyo"},
+		{"Tokyo/London overlap", time.Date(2025, 11, 19, 8, 30, 0, 0, time.UTC), "tokyo+london"},
+		{"London/New York overlap", time.Date(2025, 11, 19, 14, 0, 0, 0, time.UTC), "london+new_york"},
+		{"New York afternoon", time.Date(2025, 11, 19, 19, 0, 0, 0, time.UTC), "new_york"},
+		{"Between sessions", time.Date(2025, 11, 19, 23, 0, 0, 0, time.UTC), ""},
+		{"Saturday", time.Date(2025, 11, 22, 14, 0, 0, 0, time.UTC), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SessionLabel(sessions, tt.time); got != tt.want {
+				t.Errorf("SessionLabel(%v) = %q, want %q", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_PastMidnight(t *testing.T) {
+	sessions, err := ParseSessions("late=America/New_York@20:00-02:00")
+	if err != nil {
+		t.Fatalf("ParseSessions failed: %v", err)
+	}
+	late := sessions[0]
+
+	tests := []struct {
+		name string
+		time time.Time
+		want bool
+	}{
+		{"Wednesday evening", time.Date(2025, 11, 20, 2, 0, 0, 0, time.UTC), true}, // Wed 21:00 New York
+		{"After midnight", time.Date(2025, 11, 20, 6, 0, 0, 0, time.UTC), true},    // Thu 01:00 New York
+		{"After end", time.Date(2025, 11, 20, 8, 0, 0, 0, time.UTC), false},        // Thu 03:00 New York
+		{"Friday's session on Saturday", time.Date(2025, 11, 22, 6, 0, 0, 0, time.UTC), true},
+		{"No Sunday session", time.Date(2025, 11, 24, 6, 0, 0, 0, time.UTC), false}, // Mon 01:00 New York
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := late.Contains(tt.time); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_DSTChangeDay(t *testing.T) {
+	// Israel moves its clocks forward on a Friday: 2025-03-28 02:00 became 03:00 (UTC+3)
+	sessions, err := ParseSessions("tel_aviv=Asia/Jerusalem@09:00-17:00")
+	if err != nil {
+		t.Fatalf("ParseSessions failed: %v", err)
+	}
+	session := sessions[0]
+
+	tests := []struct {
+		name string
+		time time.Time
+		want bool
+	}{
+		{"Before start", time.Date(2025, 3, 28, 5, 30, 0, 0, time.UTC), false}, // 08:30 local
+		{"Start", time.Date(2025, 3, 28, 6, 0, 0, 0, time.UTC), true},          // 09:00 local
+		{"Before end", time.Date(2025, 3, 28, 13, 30, 0, 0, time.UTC), true},   // 16:30 local
+		{"After end", time.Date(2025, 3, 28, 14, 30, 0, 0, time.UTC), false},   // 17:30 local
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := session.Contains(tt.time); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSessions_Invalid(t *testing.T) {
+	for _, spec := range []string{"london", "london=Europe/London", "london=Nowhere/City@08:00-17:00", "london=Europe/London@8-17"} {
+		if _, err := ParseSessions(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
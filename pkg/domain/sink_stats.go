@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// SinkStats are the cumulative write statistics of one sink
+type SinkStats struct {
+	Records   uint64
+	Bytes     uint64 // 0 for sinks that don't count their output
+	Errors    uint64
+	Writes    uint64
+	WriteTime time.Duration
+	Flushes   uint64
+	FlushTime time.Duration
+	OpenFiles int // Current, not cumulative
+}
+
+// Sub returns the statistics accumulated since prev
+func (s SinkStats) Sub(prev SinkStats) SinkStats {
+	return SinkStats{
+		Records:   s.Records - prev.Records,
+		Bytes:     s.Bytes - prev.Bytes,
+		Errors:    s.Errors - prev.Errors,
+		Writes:    s.Writes - prev.Writes,
+		WriteTime: s.WriteTime - prev.WriteTime,
+		Flushes:   s.Flushes - prev.Flushes,
+		FlushTime: s.FlushTime - prev.FlushTime,
+		OpenFiles: s.OpenFiles,
+	}
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// Snapshot summarizes one instrument's prices over a fixed interval
+// Snapshots form a regular time grid: an interval without ticks repeats the last quote with Ticks = 0
+type Snapshot struct {
+	Timestamp time.Time `json:"timestamp"` // Interval start
+	Uic       int       `json:"uic"`
+	Ticker    string    `json:"ticker"`
+	AssetType string    `json:"asset_type"`
+	Bid       float64   `json:"bid"` // Last bid at interval end
+	Ask       float64   `json:"ask"` // Last ask at interval end
+	MinSpread float64   `json:"min_spread"`
+	MaxSpread float64   `json:"max_spread"`
+	Ticks     int       `json:"ticks"`              // Price updates within the interval
+	Recorded  int       `json:"recorded"`           // Ticks of the interval the sinks accepted (after conflation, sampling, rate limits and quotas)
+	Decimals  int       `json:"decimals,omitempty"` // Number of decimals for price rounding
+}
+
+// NewSnapshot starts a snapshot for the interval beginning at start with its first tick
+func NewSnapshot(start time.Time, p *PriceData) *Snapshot {
+	return &Snapshot{
+		Timestamp: start,
+		Uic:       p.Uic,
+		Ticker:    p.Ticker,
+		AssetType: p.AssetType,
+		Bid:       p.Bid,
+		Ask:       p.Ask,
+		MinSpread: p.Spread,
+		MaxSpread: p.Spread,
+		Ticks:     1,
+		Decimals:  p.Decimals,
+	}
+}
+
+// Add folds a tick from the same interval into the snapshot
+func (s *Snapshot) Add(p *PriceData) {
+	s.Bid = p.Bid
+	s.Ask = p.Ask
+	s.MinSpread = min(s.MinSpread, p.Spread)
+	s.MaxSpread = max(s.MaxSpread, p.Spread)
+	s.Ticks++
+}
+
+// CarryForward returns the snapshot for a later interval without ticks
+// The last quote is repeated and the spread range collapses to its spread
+func (s *Snapshot) CarryForward(start time.Time) *Snapshot {
+	next := *s
+	next.Timestamp = start
+	next.MinSpread = s.Ask - s.Bid
+	next.MaxSpread = next.MinSpread
+	next.Ticks = 0
+	next.Recorded = 0
+	return &next
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshot_AddAndCarryForward(t *testing.T) {
+	start := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+	tick := func(bid, ask float64) *PriceData {
+		p := &PriceData{Timestamp: start, Ticker: "EURUSD", Bid: bid, Ask: ask}
+		p.CalculateSpread()
+		return p
+	}
+
+	s := NewSnapshot(start, tick(1.1000, 1.1002))
+	s.Add(tick(1.1001, 1.1006))
+	s.Add(tick(1.1002, 1.1003))
+
+	if s.Ticks != 3 || s.Bid != 1.1002 || s.Ask != 1.1003 {
+		t.Errorf("Unexpected last quote: %+v", s)
+	}
+	if s.MinSpread > 0.00011 || s.MaxSpread < 0.00049 {
+		t.Errorf("Unexpected spread range: min=%v max=%v", s.MinSpread, s.MaxSpread)
+	}
+
+	next := s.CarryForward(start.Add(time.Second))
+	if next.Ticks != 0 || next.Bid != s.Bid || next.MinSpread != next.MaxSpread {
+		t.Errorf("Unexpected carried-forward snapshot: %+v", next)
+	}
+	if !next.Timestamp.Equal(start.Add(time.Second)) {
+		t.Errorf("Unexpected timestamp: %v", next.Timestamp)
+	}
+}
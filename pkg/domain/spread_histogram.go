@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpreadHistogram counts one instrument's spreads within an hour in buckets of pips
+// Histograms of the same instrument and hour add up, e.g. two rows written around a restart
+type SpreadHistogram struct {
+	Hour    time.Time `json:"hour"` // Start of the hour (UTC)
+	Ticker  string    `json:"ticker"`
+	Bounds  []float64 `json:"bounds"` // Upper bucket bounds in pips, ascending
+	Counts  []int     `json:"counts"` // Ticks per bucket; the last counts spreads above every bound
+	Ticks   int       `json:"ticks"`
+	MinPips float64   `json:"min_pips"`
+	MaxPips float64   `json:"max_pips"`
+	SumPips float64   `json:"sum_pips"` // For the average; sums add up like the counts
+}
+
+// NewSpreadHistogram creates an empty histogram with the given bucket bounds
+func NewSpreadHistogram(hour time.Time, ticker string, bounds []float64) *SpreadHistogram {
+	return &SpreadHistogram{Hour: hour, Ticker: ticker, Bounds: bounds, Counts: make([]int, len(bounds)+1)}
+}
+
+// Add counts a spread in the first bucket whose bound is at least pips
+func (h *SpreadHistogram) Add(pips float64) {
+	i, _ := slices.BinarySearch(h.Bounds, pips)
+	h.Counts[i]++
+	if h.Ticks == 0 {
+		h.MinPips, h.MaxPips = pips, pips
+	}
+	h.MinPips = min(h.MinPips, pips)
+	h.MaxPips = max(h.MaxPips, pips)
+	h.SumPips += pips
+	h.Ticks++
+}
+
+// AvgPips returns the average spread in pips, 0 without ticks
+func (h *SpreadHistogram) AvgPips() float64 {
+	if h.Ticks == 0 {
+		return 0
+	}
+	return h.SumPips / float64(h.Ticks)
+}
+
+// ParseHistogramBounds parses ascending bucket bounds in pips like "0.2,0.5,1,2,5"
+func ParseHistogramBounds(s string) ([]float64, error) {
+	var bounds []float64
+	for part := range strings.SplitSeq(s, ",") {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || bound <= 0 {
+			return nil, fmt.Errorf("invalid histogram bound %q (positive pips)", part)
+		}
+		if len(bounds) > 0 && bound <= bounds[len(bounds)-1] {
+			return nil, fmt.Errorf("histogram bounds must be ascending: %v after %v", bound, bounds[len(bounds)-1])
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds, nil
+}
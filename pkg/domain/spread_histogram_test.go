@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSpreadHistogram_Add(t *testing.T) {
+	h := NewSpreadHistogram(time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC), "EURUSD", []float64{0.5, 1, 2})
+	for _, pips := range []float64{0.3, 0.5, 0.8, 1.5, 7} {
+		h.Add(pips)
+	}
+
+	// A spread on a bound belongs to that bound's bucket
+	if want := []int{2, 1, 1, 1}; !slices.Equal(h.Counts, want) {
+		t.Errorf("Expected counts %v, got %v", want, h.Counts)
+	}
+	if h.Ticks != 5 || h.MinPips != 0.3 || h.MaxPips != 7 || h.AvgPips() != 2.02 {
+		t.Errorf("Unexpected summary: %+v (avg %v)", h, h.AvgPips())
+	}
+}
+
+func TestParseHistogramBounds(t *testing.T) {
+	bounds, err := ParseHistogramBounds("0.2, 0.5,1,2")
+	if err != nil || !slices.Equal(bounds, []float64{0.2, 0.5, 1, 2}) {
+		t.Errorf("Unexpected bounds %v (%v)", bounds, err)
+	}
+	for _, invalid := range []string{"", "1,0.5", "0.5,0.5", "0,1", "abc"} {
+		if _, err := ParseHistogramBounds(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
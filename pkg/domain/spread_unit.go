@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SpreadUnit is the unit recorded spreads are expressed in
+type SpreadUnit string
+
+const (
+	SpreadUnitPrice  SpreadUnit = "price"  // Quote currency (ask - bid), the default
+	SpreadUnitPips   SpreadUnit = "pips"   // Multiples of the instrument's pip size
+	SpreadUnitPoints SpreadUnit = "points" // Tenths of a pip
+	SpreadUnitBps    SpreadUnit = "bps"    // Basis points of the mid price
+)
+
+// bpsPrecision is the number of decimals kept for basis-point spreads
+const bpsPrecision = 4
+
+// ParseSpreadUnit validates a spread unit name ("" means price)
+func ParseSpreadUnit(s string) (SpreadUnit, error) {
+	switch u := SpreadUnit(strings.ToLower(strings.TrimSpace(s))); u {
+	case "":
+		return SpreadUnitPrice, nil
+	case SpreadUnitPrice, SpreadUnitPips, SpreadUnitPoints, SpreadUnitBps:
+		return u, nil
+	default:
+		return "", fmt.Errorf("unknown spread unit %q (supported: price, pips, points, bps)", s)
+	}
+}
+
+// DefaultPipDecimals returns the conventional decimal place of a pip: 2 for JPY-quoted pairs, 4 otherwise
+// Saxo quotes one decimal more (fractional pips), so this is not the instrument's price decimals
+func DefaultPipDecimals(ticker string) int {
+	if strings.HasSuffix(strings.ToUpper(ticker), "JPY") {
+		return 2
+	}
+	return 4
+}
+
+// DefaultPipSize returns the conventional pip size: 0.01 for JPY-quoted pairs, 0.0001 otherwise
+func DefaultPipSize(ticker string) float64 {
+	return PipSizeForDecimals(DefaultPipDecimals(ticker))
+}
+
+// PipSizeForDecimals returns the pip size of a pip at the given decimal place (4 -> 0.0001)
+func PipSizeForDecimals(pipDecimals int) float64 {
+	return math.Pow10(-pipDecimals)
+}
+
+// PipSizes maps tickers to their pip size, for tools that convert recorded spreads to pips
+type PipSizes map[string]float64
+
+// Get returns a ticker's pip size, or DefaultPipSize if it has none
+func (s PipSizes) Get(ticker string) float64 {
+	if size, ok := s[ticker]; ok && size > 0 {
+		return size
+	}
+	return DefaultPipSize(ticker)
+}
+
+// UnitSpread returns the spread in SpreadUnit and the number of decimals to print it with
+// Pips and points are exact (derived from the fixed-point spread); price units are returned as is
+func (p *PriceData) UnitSpread() (float64, int) {
+	switch p.SpreadUnit {
+	case SpreadUnitPips, SpreadUnitPoints:
+		if p.PipSize <= 0 {
+			break
+		}
+		size := p.PipSize
+		if p.SpreadUnit == SpreadUnitPoints {
+			size /= 10
+		}
+		if p.Decimals <= 0 {
+			return p.Spread / size, -1
+		}
+		// The fixed-point spread counts smallest increments; rescale it to multiples of size
+		steps, sizeDecimals := splitPipSize(size)
+		decimals := p.Decimals - sizeDecimals
+		if steps == 1 {
+			return p.FixedSpread().Float64(decimals), max(decimals, 0)
+		}
+		// A pip of several steps (0.25, 0.0005): the quotient ends after the larger power of 2 or 5
+		// in steps; one with other factors repeats and is rounded to repeatingPipDecimals more
+		precision := max(decimals+quotientDecimals(steps), 0)
+		value := float64(p.FixedSpread()) / float64(steps) / math.Pow10(decimals)
+		scale := math.Pow10(precision)
+		return math.Round(value*scale) / scale, precision
+
+	case SpreadUnitBps:
+		mid := (p.Bid + p.Ask) / 2
+		if mid == 0 {
+			return 0, bpsPrecision
+		}
+		scale := math.Pow10(bpsPrecision)
+		return math.Round(p.Spread/mid*10_000*scale) / scale, bpsPrecision
+	}
+	return p.Spread, p.SpreadPrecision()
+}
+
+// repeatingPipDecimals is how many decimals a spread in a pip size with factors other than 2 and 5
+// keeps beyond the exact part
+const repeatingPipDecimals = 2
+
+// splitPipSize writes a pip size as steps × 10^-decimals with the fewest decimals (0.25 -> 25, 2)
+func splitPipSize(size float64) (steps int64, decimals int) {
+	text := strconv.FormatFloat(size, 'f', -1, 64)
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		decimals = len(text) - i - 1
+	}
+	steps = int64(math.Round(size * math.Pow10(decimals)))
+	for steps > 1 && steps%10 == 0 {
+		steps /= 10
+		decimals--
+	}
+	return steps, decimals
+}
+
+// quotientDecimals returns the decimals of units/steps for any integer units: the larger count
+// of factors 2 and 5 in steps, plus repeatingPipDecimals if steps has other factors
+func quotientDecimals(steps int64) int {
+	twos, fives := 0, 0
+	for ; steps%2 == 0; steps /= 2 {
+		twos++
+	}
+	for ; steps%5 == 0; steps /= 5 {
+		fives++
+	}
+	if steps > 1 {
+		return max(twos, fives) + repeatingPipDecimals
+	}
+	return max(twos, fives)
+}
+
+// SpreadPrecision returns the decimals of spreads in price units: SpreadDecimals, but at least Decimals
+// A spread below the last price decimal (e.g. 0.000004 on EURUSD at 5 decimals) would round to zero otherwise
+func (p *PriceData) SpreadPrecision() int {
+	return max(p.SpreadDecimals, p.Decimals)
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPriceData_UnitSpread(t *testing.T) {
+	tests := []struct {
+		name          string
+		bid, ask      float64
+		decimals      int
+		ticker        string
+		pipSize       float64 // 0 = DefaultPipSize(ticker)
+		unit          SpreadUnit
+		want          float64
+		wantPrecision int
+	}{
+		{"EURUSD price", 1.08451, 1.08471, 5, "EURUSD", 0, SpreadUnitPrice, 0.0002, 5},
+		{"EURUSD pips", 1.08451, 1.08471, 5, "EURUSD", 0, SpreadUnitPips, 2.0, 1},
+		{"EURUSD points", 1.08451, 1.08471, 5, "EURUSD", 0, SpreadUnitPoints, 20, 0},
+		{"USDJPY pips", 155.123, 155.137, 3, "USDJPY", 0, SpreadUnitPips, 1.4, 1},
+		{"USDJPY points with 2 decimals", 155.12, 155.14, 2, "USDJPY", 0, SpreadUnitPoints, 20, 0},
+		{"EURUSD bps", 1.0, 1.0002, 5, "EURUSD", 0, SpreadUnitBps, 1.9998, 4},
+		{"quarter pips", 5012.25, 5013.00, 2, "US500", 0.25, SpreadUnitPips, 3, 2},
+		{"quarter pip points", 5012.25, 5012.26, 2, "US500", 0.25, SpreadUnitPoints, 0.4, 1},
+		{"half pips", 1.08451, 1.08472, 5, "EURUSD", 0.0005, SpreadUnitPips, 0.42, 2},
+		{"pip of 10", 39012, 39032, 0, "US30", 10, SpreadUnitPips, 2, -1},
+		{"pip of 10 with decimals", 39012.5, 39032, 1, "US30", 10, SpreadUnitPips, 1.95, 2},
+		{"third pips", 1.0, 1.0001, 4, "XXXYYY", 0.0003, SpreadUnitPips, 0.33, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipSize := tt.pipSize
+			if pipSize == 0 {
+				pipSize = DefaultPipSize(tt.ticker)
+			}
+			p := &PriceData{Bid: tt.bid, Ask: tt.ask, Decimals: tt.decimals, SpreadUnit: tt.unit, PipSize: pipSize}
+			p.CalculateSpread()
+
+			got, precision := p.UnitSpread()
+			if tt.unit != SpreadUnitPrice && got != tt.want {
+				t.Errorf("UnitSpread() = %v, want %v", got, tt.want)
+			}
+			if precision != tt.wantPrecision {
+				t.Errorf("Precision = %d, want %d", precision, tt.wantPrecision)
+			}
+		})
+	}
+}
+
+func TestParseSpreadUnit(t *testing.T) {
+	if u, err := ParseSpreadUnit(""); err != nil || u != SpreadUnitPrice {
+		t.Errorf("Expected price default, got %q (%v)", u, err)
+	}
+	if _, err := ParseSpreadUnit("ticks"); err == nil {
+		t.Error("Expected error for unknown unit")
+	}
+}
+
+func TestPriceData_SpreadPrecision(t *testing.T) {
+	p := &PriceData{Bid: 1.084510, Ask: 1.084514, Decimals: 5, SpreadDecimals: 6}
+	p.CalculateSpread()
+
+	if _, precision := p.UnitSpread(); precision != 6 {
+		t.Errorf("Expected price spreads at 6 decimals, got %d", precision)
+	}
+	out, err := p.MarshalJSONFormat(PriceFormatDecimal)
+	if err != nil {
+		t.Fatalf("MarshalJSONFormat failed: %v", err)
+	}
+	if !strings.Contains(string(out), `"spread":0.000004`) {
+		t.Errorf("Expected the spread at 6 decimals, got %s", out)
+	}
+
+	// Fewer spread decimals than price decimals keep the prices' precision
+	p.SpreadDecimals = 3
+	if got := p.SpreadPrecision(); got != 5 {
+		t.Errorf("Expected precision 5, got %d", got)
+	}
+}
+
+func TestPipSizes(t *testing.T) {
+	if got := DefaultPipDecimals("USDJPY"); got != 2 {
+		t.Errorf("Expected JPY pips at 2 decimals, got %d", got)
+	}
+	// Equal to the parsed literal, so pipSize and pipDecimals can be compared exactly
+	if PipSizeForDecimals(4) != 0.0001 || PipSizeForDecimals(2) != 0.01 || PipSizeForDecimals(0) != 1 {
+		t.Errorf("Unexpected pip sizes %v, %v, %v", PipSizeForDecimals(4), PipSizeForDecimals(2), PipSizeForDecimals(0))
+	}
+
+	sizes := PipSizes{"XAUUSD": 0.1}
+	if sizes.Get("XAUUSD") != 0.1 || sizes.Get("EURJPY") != 0.01 {
+		t.Errorf("Unexpected pip sizes %v and %v", sizes.Get("XAUUSD"), sizes.Get("EURJPY"))
+	}
+	if PipSizes(nil).Get("EURUSD") != 0.0001 {
+		t.Error("Expected the default pip size without configured sizes")
+	}
+}
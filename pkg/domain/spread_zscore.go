@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// RollingZScore scores spreads against the mean and standard deviation of an instrument's spreads
+// over a trailing time window, updated online in O(1) per tick
+type RollingZScore struct {
+	window     time.Duration
+	minSamples int
+
+	samples    []zSample // Ring buffer of the spreads in the window, oldest at head
+	head, size int
+	sum, sumSq float64 // Of the spreads in the window, relative to offset for precision
+	offset     float64
+}
+
+// zSample is one spread in the window
+type zSample struct {
+	at     time.Time
+	spread float64
+}
+
+// NewRollingZScore creates a scorer over window; scores need at least minSamples spreads in the window
+func NewRollingZScore(window time.Duration, minSamples int) *RollingZScore {
+	return &RollingZScore{window: window, minSamples: max(minSamples, 2), samples: make([]zSample, 16)}
+}
+
+// Add scores a spread observed at t against the window before it, then adds it to the window
+// Timestamps should not go backwards; a spread that does is evicted late, with the ones after it
+func (z *RollingZScore) Add(t time.Time, spread float64) (score float64, ok bool) {
+	score, ok = z.Score(t, spread)
+	z.push(t, spread)
+	return score, ok
+}
+
+// Score scores a spread observed at t against the window without adding it, e.g. for outliers
+// ok is false while the window holds fewer than minSamples spreads or they are all equal
+func (z *RollingZScore) Score(t time.Time, spread float64) (score float64, ok bool) {
+	cutoff := t.Add(-z.window)
+	for z.size > 0 && !z.samples[z.head].at.After(cutoff) {
+		old := z.samples[z.head].spread - z.offset
+		z.sum -= old
+		z.sumSq -= old * old
+		z.head = (z.head + 1) % len(z.samples)
+		z.size--
+	}
+	if z.size == 0 {
+		// Restarting the sums from here also drops accumulated rounding errors
+		z.sum, z.sumSq, z.offset = 0, 0, spread
+	}
+
+	if z.size >= z.minSamples {
+		n := float64(z.size)
+		mean := z.sum / n
+		variance := (z.sumSq - n*mean*mean) / (n - 1)
+		// Below that it's rounding noise of equal spreads, which would give huge scores
+		if variance > 1e-12*z.offset*z.offset {
+			score, ok = (spread-z.offset-mean)/math.Sqrt(variance), true
+		}
+	}
+	return score, ok
+}
+
+// push adds a spread observed at t to the window
+func (z *RollingZScore) push(t time.Time, spread float64) {
+	if z.size == len(z.samples) {
+		grown := make([]zSample, 2*len(z.samples))
+		for i := range z.size {
+			grown[i] = z.samples[(z.head+i)%len(z.samples)]
+		}
+		z.samples, z.head = grown, 0
+	}
+	z.samples[(z.head+z.size)%len(z.samples)] = zSample{at: t, spread: spread}
+	z.size++
+	value := spread - z.offset
+	z.sum += value
+	z.sumSq += value * value
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRollingZScore(t *testing.T) {
+	z := NewRollingZScore(time.Minute, 3)
+	start := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+
+	// Spreads 1, 2, 3: mean 2, sample standard deviation 1
+	for i, spread := range []float64{0.0001, 0.0002, 0.0003} {
+		if _, ok := z.Add(start.Add(time.Duration(i)*time.Second), spread); ok {
+			t.Fatalf("Expected no score with %d spreads in the window", i)
+		}
+	}
+	score, ok := z.Add(start.Add(3*time.Second), 0.0005)
+	if !ok || math.Abs(score-3) > 1e-9 {
+		t.Errorf("Expected z = 3, got %v (%v)", score, ok)
+	}
+
+	// A minute later the window only holds the last spread
+	if _, ok := z.Add(start.Add(63*time.Second+time.Millisecond), 0.0001); ok {
+		t.Error("Expected no score once the window emptied")
+	}
+}
+
+func TestRollingZScore_ConstantSpread(t *testing.T) {
+	z := NewRollingZScore(time.Minute, 2)
+	start := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+	for i := range 40 {
+		if _, ok := z.Add(start.Add(time.Duration(i)*time.Second), 0.0002); ok {
+			t.Fatal("Expected no score without variance")
+		}
+	}
+}
+
+func TestRollingZScore_ScoreLeavesWindow(t *testing.T) {
+	z := NewRollingZScore(time.Minute, 2)
+	start := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+	z.Add(start, 0.0001)
+	z.Add(start.Add(time.Second), 0.0003)
+
+	first, ok := z.Score(start.Add(2*time.Second), 0.0050)
+	if !ok {
+		t.Fatal("Expected a score with 2 spreads in the window")
+	}
+	if second, _ := z.Score(start.Add(3*time.Second), 0.0050); second != first {
+		t.Errorf("Expected Score not to change the window, got %v then %v", first, second)
+	}
+}
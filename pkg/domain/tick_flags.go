@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TickFlags marks ticks recorded under special market conditions (bitfield)
+type TickFlags uint32
+
+const (
+	FlagRollover   TickFlags = 1 << iota // Within the daily rollover window
+	FlagTripleSwap                       // Rollover that books three days of swap (usually Wednesday)
+	FlagOutlier                          // Implausible spread (zero/negative or above the instrument's maximum)
+	FlagSampled                          // Recorded outside a burst window; ticks since the previous one were skipped
+	FlagBackfill                         // Close of a historical bar (cmd/backfill), not a streamed tick
+	FlagConflated                        // Last quote of a conflation window that replaced earlier ones
+	FlagImported                         // Third-party tick loaded by cmd/import; Source names its origin
+	FlagRetimed                          // Timestamp moved forward by nanoseconds to break a tie with the instrument's previous tick
+	FlagCrossed                          // Crossed quote (bid above ask); CrossedQuotePolicy decided its spread
+	FlagClamped                          // Negative spread of a crossed quote recorded as zero (CrossedQuoteClamp)
+	FlagSnapshot                         // First quote after a (re)subscription; the broker's snapshot may predate it
+	FlagStale                            // Same bid and ask as the instrument's quote from longer ago than the stale threshold
+	FlagIndicative                       // Received while the instrument's market is closed (weekend or holiday)
+	FlagOutOfOrder                       // Timestamp before the instrument's previous one: the clock was stepped back
+)
+
+// QualityFlags are the flags of ticks whose quote may not be tradable; a tick without any is clean
+const QualityFlags = FlagOutlier | FlagCrossed | FlagClamped | FlagSnapshot | FlagStale | FlagIndicative
+
+// tickFlagNames lists flag names in bit order
+var tickFlagNames = []string{"rollover", "triple_swap", "outlier", "sampled", "backfill", "conflated", "imported", "retimed", "crossed", "clamped", "snapshot", "stale", "indicative", "out_of_order"}
+
+// Has reports whether all bits of flag are set
+func (f TickFlags) Has(flag TickFlags) bool {
+	return f&flag == flag
+}
+
+// Quality returns the set quality flags (see QualityFlags)
+func (f TickFlags) Quality() TickFlags {
+	return f & QualityFlags
+}
+
+// String returns the set flags joined by "|" (e.g. "rollover|triple_swap")
+func (f TickFlags) String() string {
+	var names []string
+	for i, name := range tickFlagNames {
+		if f.Has(1 << i) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseTickFlag returns the flag with a name like "outlier" or "triple_swap"
+func ParseTickFlag(name string) (TickFlags, error) {
+	for i, n := range tickFlagNames {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return 1 << i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown tick flag %q (supported: %s)", name, strings.Join(tickFlagNames, ", "))
+}
+
+// DefaultRolloverWindow is five minutes either side of the 17:00 New York rollover
+// (21:55–22:05 UTC in winter, 20:55–21:05 UTC in summer)
+const DefaultRolloverWindow = "America/New_York@16:55-17:05"
+
+// RolloverCalendar flags ticks in the daily rollover window and on triple-swap days
+type RolloverCalendar struct {
+	Window        Session
+	TripleSwapDay time.Weekday // Weekday whose rollover books the weekend's swap
+}
+
+// ParseRolloverCalendar parses a window like "America/New_York@16:55-17:05" and a weekday name
+func ParseRolloverCalendar(window, tripleSwapDay string) (*RolloverCalendar, error) {
+	session, err := ParseSessionWindow("rollover", window)
+	if err != nil {
+		return nil, err
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), strings.TrimSpace(tripleSwapDay)) {
+			return &RolloverCalendar{Window: session, TripleSwapDay: day}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid triple swap day: %q", tripleSwapDay)
+}
+
+// Flags returns the rollover flags for a tick at t
+func (r *RolloverCalendar) Flags(t time.Time) TickFlags {
+	if r == nil {
+		return 0
+	}
+
+	day, ok := r.Window.openedOn(t)
+	if !ok {
+		return 0
+	}
+	if day == r.TripleSwapDay {
+		return FlagRollover | FlagTripleSwap
+	}
+	return FlagRollover
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRolloverCalendar_Flags(t *testing.T) {
+	calendar, err := ParseRolloverCalendar(DefaultRolloverWindow, "wednesday")
+	if err != nil {
+		t.Fatalf("ParseRolloverCalendar failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want TickFlags
+	}{
+		{"Tuesday rollover (winter)", time.Date(2025, 11, 18, 21, 58, 0, 0, time.UTC), FlagRollover},
+		{"Tuesday before rollover", time.Date(2025, 11, 18, 21, 50, 0, 0, time.UTC), 0},
+		{"Wednesday rollover", time.Date(2025, 11, 19, 22, 4, 0, 0, time.UTC), FlagRollover | FlagTripleSwap},
+		{"Wednesday rollover (summer)", time.Date(2025, 7, 16, 20, 55, 0, 0, time.UTC), FlagRollover | FlagTripleSwap},
+		{"Wednesday after rollover", time.Date(2025, 11, 19, 22, 5, 0, 0, time.UTC), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.Flags(tt.time); got != tt.want {
+				t.Errorf("Flags(%v) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTickFlags_String(t *testing.T) {
+	if got := (FlagRollover | FlagTripleSwap).String(); got != "rollover|triple_swap" {
+		t.Errorf("Unexpected string: %q", got)
+	}
+	if got := (FlagOutlier | FlagSampled).String(); got != "outlier|sampled" {
+		t.Errorf("Unexpected string: %q", got)
+	}
+	if got := (FlagBackfill | FlagConflated).String(); got != "backfill|conflated" {
+		t.Errorf("Unexpected string: %q", got)
+	}
+	if got := FlagImported.String(); got != "imported" {
+		t.Errorf("Unexpected string: %q", got)
+	}
+	if got := TickFlags(0).String(); got != "" {
+		t.Errorf("Expected empty string, got %q", got)
+	}
+	if got := (FlagSnapshot | FlagStale | FlagIndicative).String(); got != "snapshot|stale|indicative" {
+		t.Errorf("Unexpected string: %q", got)
+	}
+}
+
+func TestTickFlags_Quality(t *testing.T) {
+	flags := FlagRollover | FlagSampled | FlagStale | FlagCrossed
+	if got := flags.Quality(); got != FlagStale|FlagCrossed {
+		t.Errorf("Expected stale|crossed, got %v", got)
+	}
+	if got := (FlagRollover | FlagConflated | FlagRetimed).Quality(); got != 0 {
+		t.Errorf("Expected a clean tick, got %v", got)
+	}
+}
+
+func TestParseTickFlag(t *testing.T) {
+	if flag, err := ParseTickFlag("Triple_Swap"); err != nil || flag != FlagTripleSwap {
+		t.Errorf("ParseTickFlag(Triple_Swap) = %v, %v", flag, err)
+	}
+	if flag, err := ParseTickFlag("imported"); err != nil || flag != FlagImported {
+		t.Errorf("ParseTickFlag(imported) = %v, %v", flag, err)
+	}
+	if _, err := ParseTickFlag("halted"); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+}
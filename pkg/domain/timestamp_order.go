@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// TimestampResolution is the precision stored timestamps are normalized to
+// Finer digits of the local clock carry no information, and most time-series stores keep microseconds
+const TimestampResolution = time.Microsecond
+
+// TimestampOrder keeps the stored timestamps of each instrument increasing
+// Ticks are stamped on arrival, so two updates in the same microsecond get equal timestamps that
+// break downstream time-series indexes; those ties are broken. A clock stepped back is not hidden:
+// moving every later tick forward would falsify their timestamps until the clock caught up
+type TimestampOrder struct {
+	last map[string]time.Time // Last timestamp handed out per ticker
+}
+
+// NewTimestampOrder creates an empty ordering
+func NewTimestampOrder() *TimestampOrder {
+	return &TimestampOrder{last: make(map[string]time.Time)}
+}
+
+// Next returns t truncated to TimestampResolution and the flag to set on the tick, if any
+// A tie, t in the same microsecond as the instrument's previous timestamp, gets one nanosecond after
+// that one (FlagRetimed); the offset keeps ties in the same microsecond for stores with microsecond
+// precision, where the sequence number orders them
+// A t before the previous timestamp's microsecond (the clock was stepped back) is kept and flagged
+// FlagOutOfOrder, and the instrument's ordering continues from it
+func (o *TimestampOrder) Next(ticker string, t time.Time) (time.Time, TickFlags) {
+	next := t.Truncate(TimestampResolution)
+	last, ok := o.last[ticker]
+	var flag TickFlags
+	switch {
+	case !ok || next.After(last):
+	case next.Equal(last.Truncate(TimestampResolution)):
+		next, flag = last.Add(time.Nanosecond), FlagRetimed
+	default:
+		flag = FlagOutOfOrder
+	}
+	o.last[ticker] = next
+	return next, flag
+}
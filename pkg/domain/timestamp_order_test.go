@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampOrder_Next(t *testing.T) {
+	order := NewTimestampOrder()
+	base := time.Date(2025, 11, 18, 12, 0, 0, 123456789, time.UTC)
+	micro := time.Date(2025, 11, 18, 12, 0, 0, 123456000, time.UTC)
+
+	steps := []struct {
+		ticker string
+		t      time.Time
+		want   time.Time
+		flag   TickFlags
+	}{
+		{"EURUSD", base, micro, 0}, // Truncated
+		{"EURUSD", base.Add(100 * time.Nanosecond), micro.Add(1), FlagRetimed}, // Same microsecond
+		{"EURUSD", base.Add(200 * time.Nanosecond), micro.Add(2), FlagRetimed}, // And again
+		{"USDJPY", base, micro, 0}, // Other instruments are independent
+		{"EURUSD", base.Add(time.Microsecond), micro.Add(time.Microsecond), 0},      // Next microsecond
+		{"EURUSD", base.Add(-time.Second), micro.Add(-time.Second), FlagOutOfOrder}, // Clock stepped back: kept
+		{"EURUSD", base.Add(-time.Second + 100), micro.Add(-time.Second + 1), FlagRetimed},
+		{"EURUSD", base.Add(time.Millisecond), micro.Add(time.Millisecond), 0}, // Later tick
+	}
+	for i, step := range steps {
+		got, flag := order.Next(step.ticker, step.t)
+		if !got.Equal(step.want) || flag != step.flag {
+			t.Errorf("Step %d: expected %v (flag %v), got %v (flag %v)", i, step.want, step.flag, got, flag)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// TokenBucket admits events at a sustained rate, with bursts up to its capacity
+// Time is passed in, so a bucket can follow tick timestamps instead of the wall clock
+type TokenBucket struct {
+	rate     float64 // Tokens added per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewTokenBucket creates a full bucket refilling at rate tokens per second
+func NewTokenBucket(rate, capacity float64) *TokenBucket {
+	return &TokenBucket{rate: rate, capacity: capacity, tokens: capacity}
+}
+
+// Allow takes one token at t if available
+// Times before the previous call add nothing, so out-of-order timestamps can't mint tokens
+func (b *TokenBucket) Allow(t time.Time) bool {
+	if !b.last.IsZero() && t.After(b.last) {
+		b.tokens = min(b.capacity, b.tokens+t.Sub(b.last).Seconds()*b.rate)
+	}
+	if b.last.IsZero() || t.After(b.last) {
+		b.last = t
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
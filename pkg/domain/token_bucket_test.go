@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Allow(t *testing.T) {
+	start := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	bucket := NewTokenBucket(2, 2) // 2 per second, bursts of 2
+
+	allowed := 0
+	for i := range 10 { // 10 ticks in the first 100ms
+		if bucket.Allow(start.Add(time.Duration(i) * 10 * time.Millisecond)) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected the burst of 2, got %d", allowed)
+	}
+
+	if bucket.Allow(start.Add(400 * time.Millisecond)) {
+		t.Error("Expected no token 0.3s after the burst (0.6 refilled)")
+	}
+	if !bucket.Allow(start.Add(600 * time.Millisecond)) {
+		t.Error("Expected a token 0.5s after the burst")
+	}
+
+	// Refill is capped at capacity
+	later := start.Add(time.Minute)
+	allowed = 0
+	for range 5 {
+		if bucket.Allow(later) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected capacity 2 after a long pause, got %d", allowed)
+	}
+
+	// An earlier timestamp adds nothing
+	if bucket.Allow(start) {
+		t.Error("Expected out-of-order timestamp to be throttled")
+	}
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// AlignedQuoteWriter persists the clock-aligned quotes of all instruments
+type AlignedQuoteWriter interface {
+	// RecordAlignedQuotes saves one row of latest quotes
+	RecordAlignedQuotes(ctx context.Context, row domain.AlignedQuotes) error
+}
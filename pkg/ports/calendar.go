@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// CalendarSource provides scheduled economic calendar events
+type CalendarSource interface {
+	// Events returns the events scheduled in [from, to)
+	Events(ctx context.Context, from, to time.Time) ([]domain.CalendarEvent, error)
+}
+
+// CalendarWriter stores the calendar annotations of one day next to the recorded ticks
+type CalendarWriter interface {
+	// WriteCalendarDay replaces the annotations stored for day (UTC)
+	WriteCalendarDay(ctx context.Context, day time.Time, annotations []domain.CalendarAnnotation) error
+}
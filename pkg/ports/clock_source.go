@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// ClockSource is a reference clock the local clock is checked against (an NTP server, the broker)
+type ClockSource interface {
+	// Name identifies the source in logs, events and metrics
+	Name() string
+
+	// ClockOffset measures how far the reference clock is ahead of the local clock (negative: behind)
+	// and how far the true offset may be from the measured one
+	ClockOffset(ctx context.Context) (offset, uncertainty time.Duration, err error)
+}
@@ -0,0 +1,8 @@
+package ports
+
+// ConfigReloader re-reads the configuration and applies the settings that can change at runtime
+type ConfigReloader interface {
+	// ReloadConfig returns a description of each changed setting, e.g. "flush interval 30s -> 10s"
+	// An error leaves every setting as it was
+	ReloadConfig() ([]string, error)
+}
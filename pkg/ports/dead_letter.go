@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// DeadLetterQueue persists items that could not be processed so they are not silently lost
+type DeadLetterQueue interface {
+	// Put stores a single dead-lettered item
+	Put(ctx context.Context, entry domain.DeadLetter) error
+}
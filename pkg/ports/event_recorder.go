@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// EventRecorder persists operational events (ops log) for later correlation with data gaps
+type EventRecorder interface {
+	// RecordEvent appends a single event to the ops log
+	RecordEvent(ctx context.Context, event domain.Event) error
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// FilePublisher announces finalized output files, so downstream jobs don't have to poll for them
+type FilePublisher interface {
+	// PublishFile announces a single finalized file
+	PublishFile(ctx context.Context, file domain.FinalizedFile) error
+}
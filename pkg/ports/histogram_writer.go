@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// HistogramWriter persists hourly spread histograms
+type HistogramWriter interface {
+	// RecordHistograms saves the histograms of one or more hours
+	RecordHistograms(ctx context.Context, histograms []*domain.SpreadHistogram) error
+}
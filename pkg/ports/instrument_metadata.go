@@ -0,0 +1,9 @@
+package ports
+
+import "context"
+
+// InstrumentMetadata provides broker reference data for configured instruments
+type InstrumentMetadata interface {
+	// Decimals returns the number of decimals the broker quotes the instrument's prices with
+	Decimals(ctx context.Context, uic int, assetType string) (int, error)
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// LeaseLock is an expiring lock used to elect a single recording leader among collector instances
+type LeaseLock interface {
+	// TryAcquire acquires the lease, or renews it when already held, and returns when the lease this
+	// instance holds expires as stored (the zero time if it doesn't hold it)
+	// A renewal that can't update the lease returns the expiry stored before
+	TryAcquire(ctx context.Context) (time.Time, error)
+
+	// Release gives up the lease (if held) so a standby can take over immediately
+	Release(ctx context.Context) error
+}
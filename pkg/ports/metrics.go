@@ -0,0 +1,26 @@
+package ports
+
+// Counter is a monotonically increasing metric, one series per combination of label values
+type Counter interface {
+	Inc(labelValues ...string)
+}
+
+// Gauge is a metric that is set to its current value
+type Gauge interface {
+	Set(value float64, labelValues ...string)
+}
+
+// Histogram counts observed values in buckets
+type Histogram interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// MetricsRegistry creates the metrics the collector service reports
+type MetricsRegistry interface {
+	Counter(name, help string, labelNames ...string) Counter
+	Gauge(name, help string, labelNames ...string) Gauge
+
+	// DurationHistogram registers a histogram of durations in seconds, with buckets suited to
+	// pipeline stages
+	DurationHistogram(name, help string, labelNames ...string) Histogram
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Notifier delivers operational events to an external channel (webhook, chat, email)
+type Notifier interface {
+	// Notify sends a single event
+	Notify(ctx context.Context, event domain.Event) error
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// Pruner is implemented by stores that can delete what they hold from before a cutoff: file sinks,
+// databases, copy targets and the snapshot directories
+type Pruner interface {
+	// Prune deletes data recorded before cutoff and returns what it removed; with dryRun it only
+	// returns what it would remove
+	Prune(ctx context.Context, cutoff time.Time, dryRun bool) ([]string, error)
+}
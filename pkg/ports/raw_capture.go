@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"time"
+)
+
+// RawCapture persists broker updates as received, before mapping, so a session can be replayed
+type RawCapture interface {
+	// Capture stores one decoded update with the time it was received
+	Capture(receivedAt time.Time, payload any) error
+
+	// CaptureFrame stores one WebSocket frame, byte for byte, with the time it was received
+	CaptureFrame(receivedAt time.Time, frame []byte) error
+}
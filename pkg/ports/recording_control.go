@@ -0,0 +1,16 @@
+package ports
+
+import "github.com/bjoelf/fx-collector/pkg/domain"
+
+// RecordingControl pauses and resumes persistence of ticks at runtime
+type RecordingControl interface {
+	// PauseRecording stops recording ticker, or all instruments if ticker is ""
+	// Returns domain.ErrInstrumentNotFound for an unknown ticker
+	PauseRecording(ticker string) error
+
+	// ResumeRecording resumes recording ticker, or all instruments if ticker is ""
+	ResumeRecording(ticker string) error
+
+	// RecordingState returns the current manual and scheduled pauses
+	RecordingState() domain.RecordingState
+}
@@ -0,0 +1,12 @@
+package ports
+
+import "context"
+
+// SequenceStore persists the last assigned sequence number per instrument
+type SequenceStore interface {
+	// Load returns the last persisted sequence per ticker (empty if none)
+	Load(ctx context.Context) (map[string]uint64, error)
+
+	// Save persists the last assigned sequence per ticker
+	Save(ctx context.Context, sequences map[string]uint64) error
+}
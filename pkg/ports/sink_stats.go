@@ -0,0 +1,19 @@
+package ports
+
+import "github.com/bjoelf/fx-collector/pkg/domain"
+
+// MeteredSink reports the cumulative write statistics of one sink
+type MeteredSink interface {
+	// Name returns the sink's label
+	Name() string
+
+	// Stats returns the statistics since the sink was created
+	Stats() domain.SinkStats
+}
+
+// DayPurger deletes the oldest day of recorded data to free disk space
+type DayPurger interface {
+	// PurgeOldestDay removes the oldest day but never the current one, and returns what it removed
+	// ("" if there was nothing left to purge)
+	PurgeOldestDay() (string, error)
+}
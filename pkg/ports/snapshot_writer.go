@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// SnapshotWriter persists fixed-interval price snapshots
+type SnapshotWriter interface {
+	// RecordSnapshots saves the snapshots of one or more completed intervals
+	RecordSnapshots(ctx context.Context, snapshots []*domain.Snapshot) error
+}
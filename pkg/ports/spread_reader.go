@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// SpreadReader reads recorded ticks back from storage
+type SpreadReader interface {
+	// ReadSpreads streams the ticks of ticker with from <= timestamp < to to fn, oldest first
+	ReadSpreads(ctx context.Context, ticker string, from, to time.Time, fn func(*domain.PriceData) error) error
+}
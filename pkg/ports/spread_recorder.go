@@ -0,0 +1,68 @@
+// Package ports defines the interfaces between the collector service and its adapters
+// Implement TickWriter (and optionally Flusher and Closer) to write ticks to a custom sink
+package ports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// TickWriter writes price data points to a sink
+type TickWriter interface {
+	// Record saves a single price data point
+	Record(ctx context.Context, data *domain.PriceData) error
+
+	// RecordBatch saves multiple price data points efficiently
+	RecordBatch(ctx context.Context, data []*domain.PriceData) error
+}
+
+// PartialWriteError is returned by RecordBatch when the sink accepted the first Written ticks before
+// failing, and by Record when it accepted the tick: they are stored or buffered, and the sink reports
+// any later failure to write them on Flush
+// Retrying callers resume with the rest instead of writing the accepted ticks twice
+type PartialWriteError struct {
+	Written int
+	Err     error
+}
+
+// Error describes the failure
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("%v (after %d ticks)", e.Err, e.Written)
+}
+
+// Unwrap returns the underlying failure
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// PartialWrite returns err as a PartialWriteError after written accepted ticks, or as is if there were none
+func PartialWrite(written int, err error) error {
+	if written == 0 || err == nil {
+		return err
+	}
+	return &PartialWriteError{Written: written, Err: err}
+}
+
+// Flusher is implemented by sinks that buffer writes
+type Flusher interface {
+	// Flush ensures all buffered data is written to storage
+	Flush(ctx context.Context) error
+}
+
+// Closer is implemented by sinks that hold resources
+type Closer interface {
+	// Close finalizes the recording session and releases resources
+	// Slow steps (fsync, network round trips) are skipped or cut short once ctx is done,
+	// but resources are always released
+	Close(ctx context.Context) error
+}
+
+// SpreadRecorder handles recording of spread data to persistent storage
+// Simple sinks only need to implement TickWriter; Flusher and Closer are optional
+type SpreadRecorder interface {
+	TickWriter
+	Flusher
+	Closer
+}
@@ -0,0 +1,38 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// StateStore persists the collector's bookkeeping in one place, independent of the file layout:
+// sequence numbers, the last recorded tick per instrument, the gaps between recordings and the
+// progress of backups
+type StateStore interface {
+	SequenceStore
+	BackupProgress
+
+	// LastRecorded returns the timestamp of the last flushed tick per ticker (empty if none)
+	LastRecorded(ctx context.Context) (map[string]time.Time, error)
+
+	// SaveLastRecorded persists the timestamp of the last flushed tick per ticker
+	SaveLastRecorded(ctx context.Context, last map[string]time.Time) error
+
+	// AddGap records a period without recorded ticks
+	AddGap(ctx context.Context, gap domain.RecordingGap) error
+
+	// Gaps returns the recorded gaps starting in [from, to), oldest first
+	Gaps(ctx context.Context, from, to time.Time) ([]domain.RecordingGap, error)
+}
+
+// BackupProgress keeps which archive files were copied to a backup target, keyed by
+// DAY/FILE, so a backup only copies what changed since
+type BackupProgress interface {
+	// BackedUp returns the files copied to target (empty if none)
+	BackedUp(ctx context.Context, target string) (map[string]domain.BackedUpFile, error)
+
+	// SaveBackedUp replaces the files copied to target
+	SaveBackedUp(ctx context.Context, target string, files map[string]domain.BackedUpFile) error
+}
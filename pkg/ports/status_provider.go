@@ -0,0 +1,9 @@
+package ports
+
+import "github.com/bjoelf/fx-collector/pkg/domain"
+
+// StatusProvider reports the live state of the collector
+type StatusProvider interface {
+	// CollectorStatus returns the connection state and the latest quote of every instrument
+	CollectorStatus() domain.CollectorStatus
+}
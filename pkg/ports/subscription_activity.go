@@ -0,0 +1,10 @@
+package ports
+
+import "time"
+
+// SubscriptionActivity is implemented by WebSocket clients that record when each subscription last heard
+// from the broker. Saxo's "NoNewData" heartbeats are only recorded there, never sent as price updates
+type SubscriptionActivity interface {
+	// GetLastMessageTimestamp returns when a message (data or heartbeat) last arrived for referenceID
+	GetLastMessageTimestamp(referenceID string) (time.Time, bool)
+}
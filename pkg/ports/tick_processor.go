@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// TickProcessor is a pipeline stage between mapping a price update and recording it
+// Stages filter, enrich or transform ticks in place of patching the collector service
+type TickProcessor interface {
+	// Process returns the tick to pass on (data itself, modified, or a replacement) or nil to drop it
+	Process(ctx context.Context, data *domain.PriceData) (*domain.PriceData, error)
+}
@@ -0,0 +1,24 @@
+package ports
+
+import "time"
+
+// Tracer records spans of the tick pipeline
+type Tracer interface {
+	// StartSpan begins a span at start, as a child of parent, or of a new trace if parent is nil
+	StartSpan(name string, parent Span, start time.Time) Span
+}
+
+// Span is one timed stage of a trace; it must not be used after End
+type Span interface {
+	// SetAttribute attaches a string, integer, float or bool value
+	SetAttribute(key string, value any)
+
+	// SetError marks the span as failed with err (nil is ignored)
+	SetError(err error)
+
+	// End finishes the span now
+	End()
+
+	// EndAt finishes the span at end
+	EndAt(end time.Time)
+}
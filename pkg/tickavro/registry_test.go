@@ -0,0 +1,92 @@
+package tickavro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_RegistersAndChecksSchemas(t *testing.T) {
+	versions := map[string][]string{}
+	var compatibility string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Content-Type") != contentType {
+			t.Errorf("Unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/config/fx.ticks-value":
+			compatibility = body["compatibility"]
+			json.NewEncoder(w).Encode(body)
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/fx.ticks-value/versions":
+			if strings.Contains(body["schema"], "broken") {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"error_code":409,"message":"Schema being registered is incompatible"}`))
+				return
+			}
+			versions["fx.ticks-value"] = append(versions["fx.ticks-value"], body["schema"])
+			w.Write([]byte(`{"id":7}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/compatibility/subjects/fx.ticks-value/versions/latest":
+			if len(versions["fx.ticks-value"]) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+				return
+			}
+			if strings.Contains(body["schema"], "broken") {
+				w.Write([]byte(`{"is_compatible":false,"messages":["reader field 'x' has no default"]}`))
+				return
+			}
+			w.Write([]byte(`{"is_compatible":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registry, err := NewRegistry(strings.Replace(server.URL, "http://", "http://key:secret@", 1))
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	ctx := context.Background()
+	subject := ValueSubject("fx.ticks")
+
+	if err := registry.SetCompatibility(ctx, subject, "full_transitive"); err != nil {
+		t.Fatalf("SetCompatibility failed: %v", err)
+	}
+	if compatibility != "FULL_TRANSITIVE" {
+		t.Errorf("Expected FULL_TRANSITIVE, got %q", compatibility)
+	}
+	if err := registry.SetCompatibility(ctx, subject, "sideways"); err == nil {
+		t.Error("Expected an error for an unknown compatibility level")
+	}
+
+	// A new subject accepts any schema
+	if err := registry.CheckCompatibility(ctx, subject, Schema); err != nil {
+		t.Fatalf("CheckCompatibility failed on a new subject: %v", err)
+	}
+	id, err := registry.Register(ctx, subject, Schema)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if id != 7 {
+		t.Errorf("Expected schema ID 7, got %d", id)
+	}
+
+	err = registry.CheckCompatibility(ctx, subject, `{"broken":true}`)
+	if !errors.Is(err, ErrIncompatible) || !strings.Contains(err.Error(), "has no default") {
+		t.Errorf("Expected ErrIncompatible with the reason, got %v", err)
+	}
+	if _, err := registry.Register(ctx, subject, `{"broken":true}`); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible from Register, got %v", err)
+	}
+}
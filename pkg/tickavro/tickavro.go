@@ -0,0 +1,155 @@
+// Package tickavro encodes ticks as Avro records for Kafka topics governed by a Confluent Schema Registry
+//
+//	id, err := registry.Register(ctx, tickavro.ValueSubject(topic), tickavro.Schema)
+//	value := tickavro.AppendPriceData(tickavro.AppendHeader(nil, id), tick)
+//
+// Schema (price_data.avsc) has the fields of the protobuf PriceData. Like pkg/tickpb the binary
+// encoding is written by hand, so neither the collector nor a sink built on pkg/collector needs an
+// Avro library. The collector's kafka sink (internal/adapters/kafka) produces these records; a sink
+// built on pkg/collector brings its own Kafka producer
+package tickavro
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Schema is the Avro schema of PriceData
+// It evolves by adding fields with defaults only, so it stays BACKWARD and FORWARD compatible
+//
+//go:embed price_data.avsc
+var Schema string
+
+// magicByte starts every message in the Confluent wire format
+const magicByte = 0
+
+// AppendHeader appends the Confluent wire format header: magic byte and big-endian schema ID
+func AppendHeader(b []byte, schemaID int) []byte {
+	return binary.BigEndian.AppendUint32(append(b, magicByte), uint32(schemaID))
+}
+
+// ParseHeader splits a Confluent-framed message into its schema ID and Avro payload
+func ParseHeader(b []byte) (int, []byte, error) {
+	if len(b) < 5 || b[0] != magicByte {
+		return 0, nil, errors.New("not a schema registry framed message")
+	}
+	return int(binary.BigEndian.Uint32(b[1:5])), b[5:], nil
+}
+
+// AppendPriceData appends the Avro binary encoding of p to b, fields in schema order
+func AppendPriceData(b []byte, p *domain.PriceData) []byte {
+	b = binary.AppendVarint(b, p.Timestamp.UnixMicro())
+	b = binary.AppendVarint(b, int64(int32(p.Uic)))
+	b = appendString(b, p.Ticker)
+	b = appendString(b, p.AssetType)
+	b = appendDouble(b, p.Bid)
+	b = appendDouble(b, p.Ask)
+	b = appendDouble(b, p.Spread)
+	b = binary.AppendVarint(b, int64(int32(p.Decimals)))
+	b = binary.AppendVarint(b, int64(p.Sequence))
+	b = appendString(b, p.SessionLabel)
+	b = binary.AppendVarint(b, int64(int32(p.Flags)))
+	b = appendDouble(b, p.EffectiveSpread)
+	b = appendDouble(b, p.SpreadZ)
+	b = appendString(b, p.Source)
+	return b
+}
+
+// UnmarshalPriceData decodes a record written with Schema
+// Records written with another version of the schema need schema resolution, which this
+// package doesn't do; consumers on other versions read through their Avro library
+func UnmarshalPriceData(b []byte) (*domain.PriceData, error) {
+	d := decoder{b: b}
+	p := &domain.PriceData{
+		Timestamp: time.UnixMicro(d.long()).UTC(),
+		Uic:       int(d.int()),
+		Ticker:    d.string(),
+		AssetType: d.string(),
+		Bid:       d.double(),
+		Ask:       d.double(),
+		Spread:    d.double(),
+		Decimals:  int(d.int()),
+		Sequence:  uint64(d.long()),
+	}
+	p.SessionLabel = d.string()
+	p.Flags = domain.TickFlags(uint32(d.int()))
+	p.EffectiveSpread = d.double()
+	p.SpreadZ = d.double()
+	p.Source = d.string()
+
+	if d.err == nil && len(d.b) > 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.b))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid PriceData record: %w", d.err)
+	}
+	return p, nil
+}
+
+func appendDouble(b []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(binary.AppendVarint(b, int64(len(s))), s...)
+}
+
+// decoder reads Avro primitives in order; the first error sticks and later reads return zero values
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) long() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errors.New("truncated long")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) int() int32 {
+	v := d.long()
+	if v != int64(int32(v)) && d.err == nil {
+		d.err = fmt.Errorf("int out of range: %d", v)
+	}
+	return int32(v)
+}
+
+func (d *decoder) double() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) < 8 {
+		d.err = errors.New("truncated double")
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+	d.b = d.b[8:]
+	return v
+}
+
+func (d *decoder) string() string {
+	size := d.long()
+	if d.err != nil {
+		return ""
+	}
+	if size < 0 || size > int64(len(d.b)) {
+		d.err = errors.New("truncated string")
+		return ""
+	}
+	s := string(d.b[:size])
+	d.b = d.b[size:]
+	return s
+}
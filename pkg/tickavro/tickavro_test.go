@@ -0,0 +1,108 @@
+package tickavro
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestAppendPriceData_MatchesAvroEncoding(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp: time.UnixMicro(1),
+		Uic:       -1,
+		Ticker:    "EURUSD",
+		Bid:       1.5,
+		Sequence:  64,
+	}
+	want := []byte{
+		0x02,                               // timestamp: zigzag 1
+		0x01,                               // uic: zigzag -1
+		0x0c, 'E', 'U', 'R', 'U', 'S', 'D', // ticker: length 6
+		0x00,                                           // asset_type: empty
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f, // bid
+	}
+	want = append(want, make([]byte, 16)...) // ask, spread
+	want = append(want, 0x00)                // decimals
+	want = append(want, 0x80, 0x01)          // seq: zigzag 64
+	want = append(want, 0x00, 0x00)          // session, flags
+	want = append(want, make([]byte, 16)...) // effective_spread, spread_z
+	want = append(want, 0x00)                // source
+
+	got := AppendPriceData(nil, tick)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % x, got % x", want, got)
+	}
+}
+
+func TestPriceData_RoundTripWithHeader(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp:       time.Date(2025, 11, 18, 12, 0, 0, 123456000, time.UTC),
+		Uic:             21,
+		Ticker:          "EURUSD",
+		AssetType:       "FxSpot",
+		Bid:             1.15432,
+		Ask:             1.15441,
+		Spread:          0.00009,
+		Decimals:        5,
+		Sequence:        1 << 40,
+		SessionLabel:    "london+new_york",
+		Flags:           domain.TickFlags(1 | 4096),
+		EffectiveSpread: 0.00012,
+		SpreadZ:         -1.25,
+		Source:          "dukascopy",
+	}
+	msg := AppendPriceData(AppendHeader(nil, 42), tick)
+
+	id, payload, err := ParseHeader(msg)
+	if err != nil {
+		t.Fatalf("ParseHeader failed: %v", err)
+	}
+	if id != 42 {
+		t.Errorf("Expected schema ID 42, got %d", id)
+	}
+	decoded, err := UnmarshalPriceData(payload)
+	if err != nil {
+		t.Fatalf("UnmarshalPriceData failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, tick) {
+		t.Errorf("Expected %+v, got %+v", tick, decoded)
+	}
+
+	if _, err := UnmarshalPriceData(payload[:len(payload)-1]); err == nil {
+		t.Error("Expected an error for a truncated record")
+	}
+	if _, _, err := ParseHeader([]byte{1, 0, 0, 0, 42}); err == nil {
+		t.Error("Expected an error for a wrong magic byte")
+	}
+}
+
+func TestSchema_FieldsMatchEncoderAndHaveDefaults(t *testing.T) {
+	var schema struct {
+		Fields []struct {
+			Name    string `json:"name"`
+			Default any    `json:"default"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(Schema), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+
+	// AppendPriceData writes the fields in this order
+	order := []string{"timestamp", "uic", "ticker", "asset_type", "bid", "ask", "spread", "decimals",
+		"seq", "session", "flags", "effective_spread", "spread_z", "source"}
+	var names []string
+	for i, field := range schema.Fields {
+		names = append(names, field.Name)
+		// Fields added after the first version need a default to stay compatible
+		if i >= 7 && field.Default == nil {
+			t.Errorf("Field %s has no default", field.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, order) {
+		t.Errorf("Schema fields %v don't match the encoder order %v", names, order)
+	}
+}
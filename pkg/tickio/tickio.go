@@ -0,0 +1,188 @@
+// Package tickio reads the ticks recorded by the collector without re-implementing its file layout
+//
+//	r := tickio.Open("data/spreads")
+//	for tick, err := range r.ReadRange("EURUSD", from, to) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(tick.Timestamp, tick.Bid, tick.Ask)
+//	}
+//
+// Both the hourly CSV archive (SPREAD_RECORDING_DIR) and the hourly Arrow archive (ARROW_DIR) are
+// read, recognised by file name, including the numbered files written after a restart
+package tickio
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Tick is one recorded quote; Spread is always in price units
+type Tick = domain.PriceData
+
+// Flags marks ticks recorded under special conditions (see the README's flags table)
+type Flags = domain.TickFlags
+
+// Tick flags
+const (
+	FlagRollover   = domain.FlagRollover
+	FlagTripleSwap = domain.FlagTripleSwap
+	FlagOutlier    = domain.FlagOutlier
+	FlagSampled    = domain.FlagSampled
+	FlagBackfill   = domain.FlagBackfill
+	FlagConflated  = domain.FlagConflated
+	FlagImported   = domain.FlagImported
+	FlagRetimed    = domain.FlagRetimed
+	FlagCrossed    = domain.FlagCrossed
+	FlagClamped    = domain.FlagClamped
+	FlagSnapshot   = domain.FlagSnapshot
+	FlagStale      = domain.FlagStale
+	FlagIndicative = domain.FlagIndicative
+	FlagOutOfOrder = domain.FlagOutOfOrder
+)
+
+// ErrNewerSchema is returned for files written by a newer collector than this package knows
+var ErrNewerSchema = storage.ErrNewerSchema
+
+// Reader reads ticks from one archive directory
+type Reader struct {
+	dir string
+}
+
+// Open returns a reader of the archive in dir (e.g. data/spreads or data/arrow)
+// Point it at one archive per format: a collector writing both holds every tick twice
+func Open(dir string) *Reader {
+	return &Reader{dir: dir}
+}
+
+// ReadRange returns the ticks of ticker ("" for all) with from <= timestamp < to, in time order
+// A zero from or to leaves that end open. Files are read one hour at a time, so memory use is
+// bounded by the busiest hour; breaking out of the loop stops reading. After an error the
+// sequence ends
+func (r *Reader) ReadRange(ticker string, from, to time.Time) iter.Seq2[*Tick, error] {
+	ticker = strings.ToUpper(ticker)
+	return func(yield func(*Tick, error) bool) {
+		hours, err := r.hours(ticker, from, to)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, hour := range hours {
+			ticks, err := readHour(hour.files, ticker, from, to)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, tick := range ticks {
+				if !yield(tick, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// hourFiles are the files holding the ticks of one hour
+type hourFiles struct {
+	start time.Time
+	files []string
+}
+
+// hours lists the files of the archive that can hold ticks of ticker within [from, to), by hour
+func (r *Reader) hours(ticker string, from, to time.Time) ([]hourFiles, error) {
+	filter := storage.ArchiveFilter{From: from, To: to}
+	if !to.IsZero() {
+		filter.To = to.Add(-time.Nanosecond) // to is exclusive, the day filter inclusive
+	}
+	if ticker != "" {
+		filter.Tickers = []string{ticker}
+	}
+
+	csvFiles, err := storage.ListSpreadFiles(r.dir, filter)
+	if err != nil {
+		return nil, err
+	}
+	arrowFiles, err := storage.ListArrowFiles(r.dir, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byHour := make(map[time.Time][]string)
+	for _, path := range append(csvFiles, arrowFiles...) {
+		start, err := fileHour(path)
+		if err != nil {
+			return nil, err
+		}
+		if (!to.IsZero() && !start.Before(to)) || (!from.IsZero() && !start.Add(time.Hour).After(from)) {
+			continue
+		}
+		byHour[start] = append(byHour[start], path)
+	}
+
+	hours := make([]hourFiles, 0, len(byHour))
+	for start, files := range byHour {
+		hours = append(hours, hourFiles{start: start, files: files})
+	}
+	slices.SortFunc(hours, func(a, b hourFiles) int { return a.start.Compare(b.start) })
+	return hours, nil
+}
+
+// fileHour returns the UTC hour of an archive file from its day directory and HH name suffix
+// (TICKER_HH.csv, spreads_HH.arrow, optionally numbered like TICKER_HH-2.csv)
+func fileHour(path string) (time.Time, error) {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if i := strings.LastIndex(base, "-"); i > 0 {
+		base = base[:i]
+	}
+	day := filepath.Base(filepath.Dir(path))
+	if len(base) < 2 {
+		return time.Time{}, fmt.Errorf("unexpected archive file %s", path)
+	}
+	start, err := time.Parse("20060102 15", day+" "+base[len(base)-2:])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected archive file %s: %w", path, err)
+	}
+	return start, nil
+}
+
+// readHour reads the files of one hour and returns the matching ticks in time order
+// Ticks of different files (instruments, restarts) are merged; ties keep file order
+func readHour(files []string, ticker string, from, to time.Time) ([]*Tick, error) {
+	slices.Sort(files)
+
+	var ticks []*Tick
+	collect := func(tick *domain.PriceData) error {
+		if ticker != "" && tick.Ticker != ticker {
+			return nil
+		}
+		if (!from.IsZero() && tick.Timestamp.Before(from)) || (!to.IsZero() && !tick.Timestamp.Before(to)) {
+			return nil
+		}
+		ticks = append(ticks, tick)
+		return nil
+	}
+
+	for _, path := range files {
+		read := storage.ReadSpreadFile
+		if strings.HasSuffix(path, ".arrow") {
+			read = storage.ReadArrowFile
+		}
+		if err := read(path, collect); err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortStableFunc(ticks, func(a, b *Tick) int {
+		return cmp.Or(a.Timestamp.Compare(b.Timestamp), strings.Compare(a.Ticker, b.Ticker))
+	})
+	return ticks, nil
+}
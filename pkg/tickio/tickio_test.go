@@ -0,0 +1,142 @@
+package tickio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/internal/adapters/storage"
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// record writes ticks and closes the recorder
+func record(t *testing.T, recorder interface {
+	RecordBatch(context.Context, []*domain.PriceData) error
+	Close(context.Context) error
+}, ticks []*domain.PriceData) {
+	t.Helper()
+	if err := recorder.RecordBatch(context.Background(), ticks); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+}
+
+// testTicks returns EURUSD and USDJPY ticks every 20 minutes from 11:00 to 13:40 on 2025-11-18
+func testTicks() []*domain.PriceData {
+	base := time.Date(2025, 11, 18, 11, 0, 0, 0, time.UTC)
+	var ticks []*domain.PriceData
+	for i := range 9 {
+		for _, ticker := range []string{"EURUSD", "USDJPY"} {
+			tick := &domain.PriceData{
+				Timestamp: base.Add(time.Duration(i) * 20 * time.Minute),
+				Ticker:    ticker,
+				Bid:       1.1,
+				Ask:       1.1002,
+				Decimals:  5,
+				Sequence:  uint64(i + 1),
+			}
+			tick.CalculateSpread()
+			ticks = append(ticks, tick)
+		}
+	}
+	return ticks
+}
+
+func TestReadRange(t *testing.T) {
+	csvDir, arrowDir := t.TempDir(), t.TempDir()
+	record(t, storage.NewCSVSpreadRecorder(csvDir), testTicks())
+	record(t, storage.NewArrowRecorder(arrowDir, domain.PriceFormatFloat), testTicks())
+
+	from := time.Date(2025, 11, 18, 11, 30, 0, 0, time.UTC)
+	to := time.Date(2025, 11, 18, 13, 0, 0, 0, time.UTC)
+
+	for name, dir := range map[string]string{"csv": csvDir, "arrow": arrowDir} {
+		t.Run(name, func(t *testing.T) {
+			var seqs []uint64
+			for tick, err := range Open(dir).ReadRange("eurusd", from, to) {
+				if err != nil {
+					t.Fatalf("Failed to read: %v", err)
+				}
+				if tick.Ticker != "EURUSD" || tick.Timestamp.Before(from) || !tick.Timestamp.Before(to) {
+					t.Errorf("Unexpected tick %s %s", tick.Ticker, tick.Timestamp)
+				}
+				seqs = append(seqs, tick.Sequence)
+			}
+			// 11:40, 12:00, 12:20, 12:40
+			if len(seqs) != 4 || seqs[0] != 3 || seqs[3] != 6 {
+				t.Errorf("Expected seq 3-6, got %v", seqs)
+			}
+
+			var all int
+			var last time.Time
+			for tick, err := range Open(dir).ReadRange("", time.Time{}, time.Time{}) {
+				if err != nil {
+					t.Fatalf("Failed to read: %v", err)
+				}
+				if tick.Timestamp.Before(last) {
+					t.Errorf("Ticks out of order at %s", tick.Timestamp)
+				}
+				last = tick.Timestamp
+				all++
+			}
+			if all != 18 {
+				t.Errorf("Expected 18 ticks, got %d", all)
+			}
+		})
+	}
+}
+
+func TestReadRange_MergesRestartFiles(t *testing.T) {
+	dir := t.TempDir()
+	dayDir := filepath.Join(dir, "20251118")
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// An older version's file and the numbered file written after an upgrade in the same hour
+	older := "timestamp,uic,ticker,asset_type,bid,ask,spread\n" +
+		"2025-11-18T12:00:00Z,21,EURUSD,FxSpot,1.10000,1.10002,0.00002\n" +
+		"2025-11-18T12:30:00Z,21,EURUSD,FxSpot,1.10000,1.10003,0.00003\n"
+	newer := "timestamp,uic,ticker,asset_type,bid,ask,spread,seq\n" +
+		"2025-11-18T12:15:00Z,21,EURUSD,FxSpot,1.10000,1.10004,0.00004,9\n"
+	if err := os.WriteFile(filepath.Join(dayDir, "EURUSD_12.csv"), []byte(older), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dayDir, "EURUSD_12-2.csv"), []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var minutes []int
+	for tick, err := range Open(dir).ReadRange("EURUSD", time.Time{}, time.Time{}) {
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		minutes = append(minutes, tick.Timestamp.Minute())
+	}
+	if len(minutes) != 3 || minutes[0] != 0 || minutes[1] != 15 || minutes[2] != 30 {
+		t.Errorf("Expected ticks at :00, :15, :30, got %v", minutes)
+	}
+}
+
+func TestReadRange_StopsEarly(t *testing.T) {
+	dir := t.TempDir()
+	record(t, storage.NewCSVSpreadRecorder(dir), testTicks())
+
+	var count int
+	for _, err := range Open(dir).ReadRange("", time.Time{}, time.Time{}) {
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("Expected to stop after 3 ticks, got %d", count)
+	}
+}
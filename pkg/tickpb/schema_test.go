@@ -0,0 +1,112 @@
+package tickpb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// schemaMessage compiles proto/fxcollector/v1/ticks.proto as protoc does and returns a message of it
+func schemaMessage(t *testing.T, name protoreflect.Name) protoreflect.MessageDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{ImportPaths: []string{"../../proto"}}}
+	files, err := compiler.Compile(context.Background(), "fxcollector/v1/ticks.proto")
+	if err != nil {
+		t.Fatalf("Failed to compile the schema: %v", err)
+	}
+	message := files[0].Messages().ByName(name)
+	if message == nil {
+		t.Fatalf("Message %s not in the schema", name)
+	}
+	return message
+}
+
+// checkAgainstSchema decodes encoded with the schema's descriptor, compares every field of the
+// schema with want and checks that the schema encodes the same values to the same bytes
+func checkAgainstSchema(t *testing.T, descriptor protoreflect.MessageDescriptor, encoded []byte, want map[protoreflect.Name]any) {
+	t.Helper()
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(encoded, message); err != nil {
+		t.Fatalf("The schema can't decode the message: %v", err)
+	}
+	if unknown := message.GetUnknown(); len(unknown) > 0 {
+		t.Errorf("Fields unknown to the schema: % x", unknown)
+	}
+
+	fields := descriptor.Fields()
+	if fields.Len() != len(want) {
+		t.Errorf("The schema has %d fields, the test covers %d", fields.Len(), len(want))
+	}
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		expected, ok := want[field.Name()]
+		if !ok {
+			t.Errorf("Field %s (%d) of the schema isn't encoded", field.Name(), field.Number())
+			continue
+		}
+		if got := message.Get(field).Interface(); got != expected {
+			t.Errorf("Field %s (%d): expected %v (%T), got %v (%T)", field.Name(), field.Number(), expected, expected, got, got)
+		}
+	}
+
+	schemaEncoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Equal(schemaEncoded, encoded) {
+		t.Errorf("Expected the schema's encoding % x, got % x", schemaEncoded, encoded)
+	}
+}
+
+func TestPriceData_MatchesSchema(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp: time.Date(2025, 11, 26, 14, 30, 0, 123456789, time.UTC),
+		Uic:       21, Ticker: "EURUSD", AssetType: "FxSpot",
+		Bid: 1.15123, Ask: 1.15131, Spread: 0.00008, Decimals: -5,
+		Sequence: 42, SessionLabel: "london+new_york", Flags: domain.FlagRollover | domain.FlagOutlier,
+		EffectiveSpread: 0.0001, SpreadZ: -1.5, Source: "dukascopy",
+	}
+	checkAgainstSchema(t, schemaMessage(t, "PriceData"), AppendPriceData(nil, tick), map[protoreflect.Name]any{
+		"timestamp_unix_nano": tick.Timestamp.UnixNano(),
+		"uic":                 int32(21),
+		"ticker":              "EURUSD",
+		"asset_type":          "FxSpot",
+		"bid":                 1.15123,
+		"ask":                 1.15131,
+		"spread":              0.00008,
+		"decimals":            int32(-5),
+		"seq":                 uint64(42),
+		"session":             "london+new_york",
+		"flags":               uint32(tick.Flags),
+		"effective_spread":    0.0001,
+		"spread_z":            -1.5,
+		"source":              "dukascopy",
+	})
+}
+
+func TestBar_MatchesSchema(t *testing.T) {
+	bar := &domain.Snapshot{
+		Timestamp: time.Date(2025, 11, 26, 14, 30, 0, 0, time.UTC),
+		Uic:       21, Ticker: "EURUSD", AssetType: "FxSpot",
+		Bid: 1.15123, Ask: 1.15131, MinSpread: 0.00005, MaxSpread: 0.0002, Ticks: 314, Decimals: 5,
+	}
+	checkAgainstSchema(t, schemaMessage(t, "Bar"), AppendBar(nil, bar), map[protoreflect.Name]any{
+		"start_unix_nano": bar.Timestamp.UnixNano(),
+		"uic":             int32(21),
+		"ticker":          "EURUSD",
+		"asset_type":      "FxSpot",
+		"bid":             1.15123,
+		"ask":             1.15131,
+		"min_spread":      0.00005,
+		"max_spread":      0.0002,
+		"ticks":           uint32(314),
+		"decimals":        int32(5),
+	})
+}
@@ -0,0 +1,286 @@
+// Package tickpb encodes ticks and bars in the protocol buffer format of proto/fxcollector/v1/ticks.proto
+//
+//	buf := tickpb.AppendDelimited(nil, tickpb.AppendPriceData(nil, tick))
+//
+// The wire format is written by hand, like the Prometheus and FIX encodings, so the collector needs
+// no protobuf runtime. The tests compile the .proto and check that every field is encoded to the
+// same bytes as the protobuf runtime writes: other languages generate their types from the schema,
+// Go programs use this package. Decoding skips fields it doesn't know, so readers keep working when
+// the schema gains fields
+package tickpb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+// Wire types used by the schema
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// maxMessageSize bounds a delimited message, so a corrupt length can't allocate gigabytes
+const maxMessageSize = 1 << 20
+
+// PriceData field numbers
+const (
+	fieldTimestamp       = 1
+	fieldUic             = 2
+	fieldTicker          = 3
+	fieldAssetType       = 4
+	fieldBid             = 5
+	fieldAsk             = 6
+	fieldSpread          = 7
+	fieldDecimals        = 8
+	fieldSeq             = 9
+	fieldSession         = 10
+	fieldFlags           = 11
+	fieldEffectiveSpread = 12
+	fieldSpreadZ         = 13
+	fieldSource          = 14
+)
+
+// Bar field numbers (fields 1-6 match PriceData)
+const (
+	fieldMinSpread   = 7
+	fieldMaxSpread   = 8
+	fieldTicks       = 9
+	fieldBarDecimals = 10
+)
+
+// AppendPriceData appends the PriceData message of p to b; zero fields are omitted as in proto3
+func AppendPriceData(b []byte, p *domain.PriceData) []byte {
+	b = appendTime(b, fieldTimestamp, p.Timestamp)
+	b = appendInt(b, fieldUic, int64(p.Uic))
+	b = appendString(b, fieldTicker, p.Ticker)
+	b = appendString(b, fieldAssetType, p.AssetType)
+	b = appendDouble(b, fieldBid, p.Bid)
+	b = appendDouble(b, fieldAsk, p.Ask)
+	b = appendDouble(b, fieldSpread, p.Spread)
+	b = appendInt(b, fieldDecimals, int64(p.Decimals))
+	b = appendUint(b, fieldSeq, p.Sequence)
+	b = appendString(b, fieldSession, p.SessionLabel)
+	b = appendUint(b, fieldFlags, uint64(p.Flags))
+	b = appendDouble(b, fieldEffectiveSpread, p.EffectiveSpread)
+	b = appendDouble(b, fieldSpreadZ, p.SpreadZ)
+	b = appendString(b, fieldSource, p.Source)
+	return b
+}
+
+// UnmarshalPriceData decodes a PriceData message
+func UnmarshalPriceData(b []byte) (*domain.PriceData, error) {
+	p := &domain.PriceData{}
+	err := decodeFields(b, func(field int, value uint64, data []byte) {
+		switch field {
+		case fieldTimestamp:
+			p.Timestamp = time.Unix(0, int64(value)).UTC()
+		case fieldUic:
+			p.Uic = int(int32(value))
+		case fieldTicker:
+			p.Ticker = string(data)
+		case fieldAssetType:
+			p.AssetType = string(data)
+		case fieldBid:
+			p.Bid = math.Float64frombits(value)
+		case fieldAsk:
+			p.Ask = math.Float64frombits(value)
+		case fieldSpread:
+			p.Spread = math.Float64frombits(value)
+		case fieldDecimals:
+			p.Decimals = int(int32(value))
+		case fieldSeq:
+			p.Sequence = value
+		case fieldSession:
+			p.SessionLabel = string(data)
+		case fieldFlags:
+			p.Flags = domain.TickFlags(value)
+		case fieldEffectiveSpread:
+			p.EffectiveSpread = math.Float64frombits(value)
+		case fieldSpreadZ:
+			p.SpreadZ = math.Float64frombits(value)
+		case fieldSource:
+			p.Source = string(data)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid PriceData: %w", err)
+	}
+	return p, nil
+}
+
+// AppendBar appends the Bar message of a snapshot interval to b
+func AppendBar(b []byte, s *domain.Snapshot) []byte {
+	b = appendTime(b, fieldTimestamp, s.Timestamp)
+	b = appendInt(b, fieldUic, int64(s.Uic))
+	b = appendString(b, fieldTicker, s.Ticker)
+	b = appendString(b, fieldAssetType, s.AssetType)
+	b = appendDouble(b, fieldBid, s.Bid)
+	b = appendDouble(b, fieldAsk, s.Ask)
+	b = appendDouble(b, fieldMinSpread, s.MinSpread)
+	b = appendDouble(b, fieldMaxSpread, s.MaxSpread)
+	b = appendUint(b, fieldTicks, uint64(s.Ticks))
+	b = appendInt(b, fieldBarDecimals, int64(s.Decimals))
+	return b
+}
+
+// UnmarshalBar decodes a Bar message
+func UnmarshalBar(b []byte) (*domain.Snapshot, error) {
+	s := &domain.Snapshot{}
+	err := decodeFields(b, func(field int, value uint64, data []byte) {
+		switch field {
+		case fieldTimestamp:
+			s.Timestamp = time.Unix(0, int64(value)).UTC()
+		case fieldUic:
+			s.Uic = int(int32(value))
+		case fieldTicker:
+			s.Ticker = string(data)
+		case fieldAssetType:
+			s.AssetType = string(data)
+		case fieldBid:
+			s.Bid = math.Float64frombits(value)
+		case fieldAsk:
+			s.Ask = math.Float64frombits(value)
+		case fieldMinSpread:
+			s.MinSpread = math.Float64frombits(value)
+		case fieldMaxSpread:
+			s.MaxSpread = math.Float64frombits(value)
+		case fieldTicks:
+			s.Ticks = int(value)
+		case fieldBarDecimals:
+			s.Decimals = int(int32(value))
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Bar: %w", err)
+	}
+	return s, nil
+}
+
+// AppendDelimited appends msg preceded by its length as a varint
+func AppendDelimited(b, msg []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// ReadDelimited reads the next length-delimited message; io.EOF at the end of the stream
+// A stream that ends inside a message returns io.ErrUnexpectedEOF
+func ReadDelimited(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message length: %w", err)
+	}
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds %d", size, maxMessageSize)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// appendTag appends the key of a field
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendInt appends an int32/int64 field; negative values take ten bytes as in protobuf
+func appendInt(b []byte, field int, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), uint64(v))
+}
+
+func appendUint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+// appendTime appends a time as int64 nanoseconds since the epoch (the zero time is omitted)
+func appendTime(b []byte, field int, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	return appendInt(b, field, t.UnixNano())
+}
+
+func appendDouble(b []byte, field int, v float64) []byte {
+	if v == 0 && !math.Signbit(v) {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendTag(b, field, wireFixed64), math.Float64bits(v))
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(s)))
+	return append(b, s...)
+}
+
+// decodeFields calls fn for every field of a message with its varint or fixed value, or its bytes
+func decodeFields(b []byte, fn func(field int, value uint64, data []byte)) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("truncated field key")
+		}
+		b = b[n:]
+		field, wireType := int(key>>3), int(key&7)
+		if field == 0 {
+			return errors.New("field number 0")
+		}
+
+		switch wireType {
+		case wireVarint:
+			value, n := binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("truncated varint in field %d", field)
+			}
+			b = b[n:]
+			fn(field, value, nil)
+		case wireFixed64:
+			if len(b) < 8 {
+				return fmt.Errorf("truncated fixed64 in field %d", field)
+			}
+			fn(field, binary.LittleEndian.Uint64(b), nil)
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return fmt.Errorf("truncated bytes in field %d", field)
+			}
+			fn(field, 0, b[n:n+int(size)])
+			b = b[n+int(size):]
+		case wireFixed32:
+			if len(b) < 4 {
+				return fmt.Errorf("truncated fixed32 in field %d", field)
+			}
+			fn(field, uint64(binary.LittleEndian.Uint32(b)), nil)
+			b = b[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wireType, field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package tickpb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bjoelf/fx-collector/pkg/domain"
+)
+
+func TestAppendPriceData_MatchesProtobufEncoding(t *testing.T) {
+	// Bytes as produced by protoc-generated code for uic=21 ticker="EURUSD" bid=1.5
+	want := []byte{
+		0x10, 0x15,
+		0x1a, 0x06, 'E', 'U', 'R', 'U', 'S', 'D',
+		0x29, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f,
+	}
+	got := AppendPriceData(nil, &domain.PriceData{Uic: 21, Ticker: "EURUSD", Bid: 1.5})
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % x, got % x", want, got)
+	}
+
+	// Negative int32 values are sign-extended to ten bytes
+	got = AppendPriceData(nil, &domain.PriceData{Decimals: -1})
+	want = []byte{0x40, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % x, got % x", want, got)
+	}
+
+	if got := AppendPriceData(nil, &domain.PriceData{}); len(got) != 0 {
+		t.Errorf("Expected an empty message for a zero tick, got % x", got)
+	}
+}
+
+func TestPriceData_RoundTrip(t *testing.T) {
+	tick := &domain.PriceData{
+		Timestamp:       time.Date(2025, 11, 18, 12, 0, 0, 123456789, time.UTC),
+		Uic:             21,
+		Ticker:          "EURUSD",
+		AssetType:       "FxSpot",
+		Bid:             1.15432,
+		Ask:             1.15441,
+		Spread:          0.00009,
+		Decimals:        5,
+		Sequence:        1 << 40,
+		SessionLabel:    "london+new_york",
+		Flags:           domain.TickFlags(1 | 4096),
+		EffectiveSpread: 0.00012,
+		SpreadZ:         -1.25,
+		Source:          "dukascopy",
+	}
+	decoded, err := UnmarshalPriceData(AppendPriceData(nil, tick))
+	if err != nil {
+		t.Fatalf("UnmarshalPriceData failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, tick) {
+		t.Errorf("Expected %+v, got %+v", tick, decoded)
+	}
+}
+
+func TestBar_RoundTrip(t *testing.T) {
+	bar := &domain.Snapshot{
+		Timestamp: time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC),
+		Uic:       42,
+		Ticker:    "USDJPY",
+		AssetType: "FxSpot",
+		Bid:       150.001,
+		Ask:       150.004,
+		MinSpread: 0.002,
+		MaxSpread: 0.009,
+		Ticks:     87,
+		Decimals:  3,
+	}
+	decoded, err := UnmarshalBar(AppendBar(nil, bar))
+	if err != nil {
+		t.Fatalf("UnmarshalBar failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, bar) {
+		t.Errorf("Expected %+v, got %+v", bar, decoded)
+	}
+}
+
+func TestUnmarshalPriceData_SkipsUnknownFields(t *testing.T) {
+	msg := AppendPriceData(nil, &domain.PriceData{Ticker: "EURUSD"})
+	// Fields a newer schema might add: varint 99, bytes 100, fixed32 101, fixed64 102
+	msg = binary.AppendUvarint(binary.AppendUvarint(msg, 99<<3|wireVarint), 300)
+	msg = append(binary.AppendUvarint(msg, 100<<3|wireBytes), 3, 'a', 'b', 'c')
+	msg = append(binary.AppendUvarint(msg, 101<<3|wireFixed32), 1, 2, 3, 4)
+	msg = append(binary.AppendUvarint(msg, 102<<3|wireFixed64), 1, 2, 3, 4, 5, 6, 7, 8)
+	msg = AppendPriceData(msg, &domain.PriceData{Uic: 21})
+
+	tick, err := UnmarshalPriceData(msg)
+	if err != nil {
+		t.Fatalf("UnmarshalPriceData failed: %v", err)
+	}
+	if tick.Ticker != "EURUSD" || tick.Uic != 21 {
+		t.Errorf("Unexpected tick: %+v", tick)
+	}
+
+	if _, err := UnmarshalPriceData(msg[:len(msg)-1]); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+func TestDelimited_ReadsStreamAndDetectsTruncation(t *testing.T) {
+	var stream []byte
+	for _, ticker := range []string{"EURUSD", "USDJPY"} {
+		stream = AppendDelimited(stream, AppendPriceData(nil, &domain.PriceData{Ticker: ticker}))
+	}
+
+	r := bufio.NewReader(bytes.NewReader(stream))
+	var tickers []string
+	for {
+		msg, err := ReadDelimited(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadDelimited failed: %v", err)
+		}
+		tick, err := UnmarshalPriceData(msg)
+		if err != nil {
+			t.Fatalf("UnmarshalPriceData failed: %v", err)
+		}
+		tickers = append(tickers, tick.Ticker)
+	}
+	if !reflect.DeepEqual(tickers, []string{"EURUSD", "USDJPY"}) {
+		t.Errorf("Unexpected tickers: %v", tickers)
+	}
+
+	r = bufio.NewReader(bytes.NewReader(stream[:len(stream)-2]))
+	if _, err := ReadDelimited(r); err != nil {
+		t.Fatalf("ReadDelimited failed on the first message: %v", err)
+	}
+	if _, err := ReadDelimited(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}